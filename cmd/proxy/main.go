@@ -881,6 +881,22 @@ func init() {
 				"project_id":       projectID,
 				"duration_minutes": duration,
 			}
+			var scopes token.Scopes
+			scopes.Endpoints, _ = cmd.Flags().GetStringSlice("scope-endpoints")
+			scopes.Methods, _ = cmd.Flags().GetStringSlice("scope-methods")
+			scopes.Models, _ = cmd.Flags().GetStringSlice("scope-models")
+			if !scopes.IsEmpty() {
+				body["scopes"] = scopes
+			}
+			var restrictions token.ClientRestrictions
+			restrictions.CIDRs, _ = cmd.Flags().GetStringSlice("allowed-cidrs")
+			restrictions.Origins, _ = cmd.Flags().GetStringSlice("allowed-origins")
+			if !restrictions.IsEmpty() {
+				body["client_restrictions"] = restrictions
+			}
+			if metadata, _ := cmd.Flags().GetStringToString("metadata"); len(metadata) > 0 {
+				body["metadata"] = metadata
+			}
 			jsonBody, _ := json.Marshal(body)
 			url := manageAPIBaseURL + "/manage/tokens"
 			req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
@@ -910,8 +926,12 @@ func init() {
 			}
 
 			var result struct {
-				Token     string `json:"token"`
-				ExpiresAt string `json:"expires_at"`
+				Token     string            `json:"token"`
+				ExpiresAt string            `json:"expires_at"`
+				Scopes    *token.Scopes     `json:"scopes,omitempty"`
+				Metadata  map[string]string `json:"metadata,omitempty"`
+				// ClientRestrictions holds the token's network and origin allowlists
+				ClientRestrictions *token.ClientRestrictions `json:"client_restrictions,omitempty"`
 			}
 			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 				return fmt.Errorf("failed to parse response: %w", err)
@@ -925,6 +945,33 @@ func init() {
 				fmt.Printf("Token: %s\n", result.Token)
 				fmt.Printf("Obfuscated: %s\n", token.ObfuscateToken(result.Token))
 				fmt.Printf("Expires at: %s\n", result.ExpiresAt)
+				if result.Scopes != nil {
+					if len(result.Scopes.Endpoints) > 0 {
+						fmt.Printf("Endpoints: %s\n", strings.Join(result.Scopes.Endpoints, ", "))
+					}
+					if len(result.Scopes.Methods) > 0 {
+						fmt.Printf("Methods: %s\n", strings.Join(result.Scopes.Methods, ", "))
+					}
+					if len(result.Scopes.Models) > 0 {
+						fmt.Printf("Models: %s\n", strings.Join(result.Scopes.Models, ", "))
+					}
+				}
+				if result.ClientRestrictions != nil {
+					if len(result.ClientRestrictions.CIDRs) > 0 {
+						fmt.Printf("Allowed CIDRs: %s\n", strings.Join(result.ClientRestrictions.CIDRs, ", "))
+					}
+					if len(result.ClientRestrictions.Origins) > 0 {
+						fmt.Printf("Allowed origins: %s\n", strings.Join(result.ClientRestrictions.Origins, ", "))
+					}
+				}
+				keys := make([]string, 0, len(result.Metadata))
+				for k := range result.Metadata {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				for _, k := range keys {
+					fmt.Printf("Metadata %s: %s\n", k, result.Metadata[k])
+				}
 			}
 			return nil
 		},
@@ -934,6 +981,12 @@ func init() {
 	tokenGenerateCmd.Flags().String("project-id", "", "Project ID (required)")
 	tokenGenerateCmd.Flags().Int("duration", 1440, "Token duration in minutes (default 1440 = 24h)")
 	tokenGenerateCmd.Flags().Bool("json", false, "Output as JSON")
+	tokenGenerateCmd.Flags().StringSlice("scope-endpoints", nil, "Restrict the token to these endpoint path prefixes (e.g. /v1/chat/completions)")
+	tokenGenerateCmd.Flags().StringSlice("scope-methods", nil, "Restrict the token to these HTTP methods (e.g. POST)")
+	tokenGenerateCmd.Flags().StringSlice("scope-models", nil, "Restrict the token to these model glob patterns (e.g. gpt-4o-mini,claude-3-5-*)")
+	tokenGenerateCmd.Flags().StringSlice("allowed-cidrs", nil, "Only accept the token from these client networks (e.g. 203.0.113.0/24)")
+	tokenGenerateCmd.Flags().StringSlice("allowed-origins", nil, "Only accept the token from these browser origins (e.g. https://*.example.com)")
+	tokenGenerateCmd.Flags().StringToString("metadata", nil, "Token metadata as key=value pairs (e.g. user_id=42,client_ip=203.0.113.7)")
 
 	// Cache command and subcommands
 	var cacheCmd = &cobra.Command{
@@ -955,6 +1008,7 @@ func init() {
 			method, _ := cmd.Flags().GetString("method")
 			url, _ := cmd.Flags().GetString("url")
 			prefix, _ := cmd.Flags().GetString("prefix")
+			provider, _ := cmd.Flags().GetString("provider")
 			jsonOutput, _ := cmd.Flags().GetBool("json")
 
 			// Fallback to env vars if flags not provided
@@ -985,6 +1039,9 @@ func init() {
 			if prefix != "" {
 				reqBody["prefix"] = prefix
 			}
+			if provider != "" {
+				reqBody["provider"] = provider
+			}
 
 			jsonData, err := json.Marshal(reqBody)
 			if err != nil {
@@ -1064,6 +1121,7 @@ func init() {
 	cachePurgeCmd.Flags().String("method", "", "HTTP method (required)")
 	cachePurgeCmd.Flags().String("url", "", "URL path (required)")
 	cachePurgeCmd.Flags().String("prefix", "", "Cache key prefix for bulk purge")
+	cachePurgeCmd.Flags().String("provider", "", "API provider whose cache to purge (default provider if empty)")
 	cachePurgeCmd.Flags().Bool("json", false, "Output as JSON")
 
 	// Register cache subcommands
@@ -171,26 +171,37 @@ func encryptProjectAPIKeys(ctx context.Context, db *database.DB, encryptor *encr
 	}
 
 	for _, project := range projects {
-		// Skip if already encrypted
-		if encryption.IsEncrypted(project.APIKey) {
-			skipped++
-			continue
+		changed := false
+
+		// Encrypt the legacy API key unless empty or already encrypted
+		if project.APIKey != "" && !encryption.IsEncrypted(project.APIKey) {
+			encryptedKey, err := encryptor.Encrypt(project.APIKey)
+			if err != nil {
+				return encrypted, skipped, fmt.Errorf("failed to encrypt API key for project %s: %w", project.ID, err)
+			}
+			project.APIKey = encryptedKey
+			changed = true
 		}
 
-		// Skip empty API keys
-		if project.APIKey == "" {
-			skipped++
-			continue
+		// Encrypt each per-provider key separately
+		for provider, key := range project.APIKeys {
+			if key == "" || encryption.IsEncrypted(key) {
+				continue
+			}
+			encryptedKey, err := encryptor.Encrypt(key)
+			if err != nil {
+				return encrypted, skipped, fmt.Errorf("failed to encrypt %s API key for project %s: %w", provider, project.ID, err)
+			}
+			project.APIKeys[provider] = encryptedKey
+			changed = true
 		}
 
-		// Encrypt the API key
-		encryptedKey, err := encryptor.Encrypt(project.APIKey)
-		if err != nil {
-			return encrypted, skipped, fmt.Errorf("failed to encrypt API key for project %s: %w", project.ID, err)
+		if !changed {
+			skipped++
+			continue
 		}
 
-		// Update the project with encrypted key
-		project.APIKey = encryptedKey
+		// Update the project with encrypted keys
 		if err := db.UpdateProject(ctx, project); err != nil {
 			return encrypted, skipped, fmt.Errorf("failed to update project %s: %w", project.ID, err)
 		}
@@ -388,8 +388,11 @@ func runServerForeground() {
 						fmt.Fprintf(os.Stderr, "failed to unmarshal event: %v\n", err)
 						continue
 					}
-					// TODO: Detect provider from path or config. For now, assume OpenAI for /v1/chat/completions
+					// Use the provider-specific transformer when one exists; OpenAI otherwise
 					provider := "openai"
+					if eventtransformer.DispatchTransformer(evt.Provider) != nil {
+						provider = evt.Provider
+					}
 					transformed, err := eventtransformer.DispatchTransformer(provider).TransformEvent(m)
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "failed to transform event: %v\n", err)
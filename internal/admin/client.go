@@ -52,12 +52,16 @@ func ObfuscateToken(token string) string { return obfuscate.ObfuscateTokenGeneri
 
 // Project represents a project from the Management API
 type Project struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	APIKey    string    `json:"api_key"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	APIKey string `json:"api_key"`
+	// APIKeys holds obfuscated per-provider keys keyed by provider name
+	APIKeys map[string]string `json:"api_keys,omitempty"`
+	// AllowedModels holds the project's model allowlist patterns (empty allows every model)
+	AllowedModels []string  `json:"allowed_models,omitempty"`
+	IsActive      bool      `json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 // Token represents a token from the Management API (sanitized)
@@ -72,13 +76,26 @@ type Token struct {
 	CreatedAt     time.Time  `json:"created_at"`
 	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
 	CacheHitCount int        `json:"cache_hit_count"`
+	// Metadata holds the token's free-form key/value pairs
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// RotatedFromID is the ID of the token this token replaced by rotation
+	RotatedFromID string `json:"rotated_from_id,omitempty"`
+	// RotatedToID is the ID of the token that replaced this token by rotation
+	RotatedToID string `json:"rotated_to_id,omitempty"`
 }
 
-// TokenCreateResponse represents the response when creating a token
+// TokenCreateResponse represents the response when creating or rotating a token
 type TokenCreateResponse struct {
+	ID          string    `json:"id"`
 	Token       string    `json:"token"`
 	ExpiresAt   time.Time `json:"expires_at"`
 	MaxRequests *int      `json:"max_requests,omitempty"`
+	// Metadata holds the token's free-form key/value pairs
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// RotatedFromID is the ID of the rotated token (rotation only)
+	RotatedFromID string `json:"rotated_from_id,omitempty"`
+	// OldTokenExpiresAt is when the rotated token stops working (rotation only)
+	OldTokenExpiresAt *time.Time `json:"old_token_expires_at,omitempty"`
 }
 
 // Pagination represents pagination metadata
@@ -100,6 +117,29 @@ type DashboardData struct {
 	TotalRequests    int `json:"total_requests"`
 	RequestsToday    int `json:"requests_today"`
 	RequestsThisWeek int `json:"requests_this_week"`
+
+	// Cost accounting; empty when the Management API has no cost data
+	TotalCostUSD float64       `json:"total_cost_usd"`
+	ProjectCosts []ProjectCost `json:"project_costs"`
+}
+
+// ProjectCost is the accumulated usage and cost of a project
+type ProjectCost struct {
+	ProjectID    string  `json:"project_id"`
+	ProjectName  string  `json:"-"`
+	RequestCount int64   `json:"request_count"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// CostsResponse is the Management API response for GET /manage/costs
+type CostsResponse struct {
+	Total struct {
+		RequestCount int64   `json:"request_count"`
+		CostUSD      float64 `json:"cost_usd"`
+	} `json:"total"`
+	Projects []ProjectCost `json:"projects"`
 }
 
 // AuditEvent represents an audit log entry for the admin UI
@@ -171,9 +211,36 @@ func (c *APIClient) GetDashboardData(ctx context.Context) (*DashboardData, error
 		}
 	}
 
+	// Costs are optional: the Management API serves them only with a database
+	if costs, err := c.GetCosts(ctx); err == nil {
+		names := make(map[string]string, len(projects))
+		for _, p := range projects {
+			names[p.ID] = p.Name
+		}
+		data.TotalCostUSD = costs.Total.CostUSD
+		for _, pc := range costs.Projects {
+			pc.ProjectName = names[pc.ProjectID]
+			data.ProjectCosts = append(data.ProjectCosts, pc)
+		}
+	}
+
 	return data, nil
 }
 
+// GetCosts retrieves the accumulated cost of all projects
+func (c *APIClient) GetCosts(ctx context.Context) (*CostsResponse, error) {
+	req, err := c.newRequest(ctx, "GET", "/manage/costs", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var costs CostsResponse
+	if err := c.doRequest(req, &costs); err != nil {
+		return nil, err
+	}
+	return &costs, nil
+}
+
 // GetProjects retrieves a paginated list of projects
 func (c *APIClient) GetProjects(ctx context.Context, page, pageSize int) ([]Project, *Pagination, error) {
 	// Since the Management API doesn't currently support pagination,
@@ -276,6 +343,49 @@ func (c *APIClient) UpdateProject(ctx context.Context, id, name, apiKey string,
 	return &project, nil
 }
 
+// UpdateProjectAllowedModels replaces the model allowlist of a project.
+// An empty list allows every model.
+func (c *APIClient) UpdateProjectAllowedModels(ctx context.Context, id string, models []string) (*Project, error) {
+	if models == nil {
+		models = []string{}
+	}
+	payload := map[string]interface{}{
+		"allowed_models": models,
+	}
+
+	req, err := c.newRequest(ctx, "PATCH", fmt.Sprintf("/manage/projects/%s", id), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var project Project
+	if err := c.doRequest(req, &project); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// UpdateProjectAPIKeys adds, rotates or removes per-provider API keys of a project.
+// A nil value removes the provider's key.
+func (c *APIClient) UpdateProjectAPIKeys(ctx context.Context, id string, apiKeys map[string]*string) (*Project, error) {
+	payload := map[string]interface{}{
+		"api_keys": apiKeys,
+	}
+
+	req, err := c.newRequest(ctx, "PATCH", fmt.Sprintf("/manage/projects/%s", id), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var project Project
+	if err := c.doRequest(req, &project); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
 // DeleteProject deletes a project
 func (c *APIClient) DeleteProject(ctx context.Context, id string) error {
 	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/manage/projects/%s", id), nil)
@@ -331,7 +441,7 @@ func (c *APIClient) GetTokens(ctx context.Context, projectID string, page, pageS
 }
 
 // CreateToken creates a new token for a project with a given duration in minutes
-func (c *APIClient) CreateToken(ctx context.Context, projectID string, durationMinutes int, maxRequests *int) (*TokenCreateResponse, error) {
+func (c *APIClient) CreateToken(ctx context.Context, projectID string, durationMinutes int, maxRequests *int, metadata map[string]string) (*TokenCreateResponse, error) {
 	payload := map[string]interface{}{
 		"project_id":       projectID,
 		"duration_minutes": durationMinutes,
@@ -340,6 +450,9 @@ func (c *APIClient) CreateToken(ctx context.Context, projectID string, durationM
 	if maxRequests != nil {
 		payload["max_requests"] = *maxRequests
 	}
+	if len(metadata) > 0 {
+		payload["metadata"] = metadata
+	}
 	// Use newRequest and doRequest for consistent error handling
 	req, err := c.newRequest(ctx, "POST", "/manage/tokens", payload)
 	if err != nil {
@@ -535,6 +648,27 @@ func (c *APIClient) UpdateToken(ctx context.Context, tokenID string, isActive *b
 	return &token, nil
 }
 
+// UpdateTokenMetadata replaces a token's metadata. Nil or empty metadata
+// clears it.
+func (c *APIClient) UpdateTokenMetadata(ctx context.Context, tokenID string, metadata map[string]string) (*Token, error) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	payload := map[string]interface{}{"metadata": metadata}
+
+	req, err := c.newRequest(ctx, "PATCH", fmt.Sprintf("/manage/tokens/%s", tokenID), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := c.doRequest(req, &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
 // RevokeToken revokes a single token by setting is_active to false
 func (c *APIClient) RevokeToken(ctx context.Context, tokenID string) error {
 	req, err := c.newRequest(ctx, "DELETE", fmt.Sprintf("/manage/tokens/%s", tokenID), nil)
@@ -545,6 +679,24 @@ func (c *APIClient) RevokeToken(ctx context.Context, tokenID string) error {
 	return c.doRequest(req, nil)
 }
 
+// RotateToken issues a successor for a token. The old token stays valid for
+// gracePeriodMinutes, or the server's default grace period when nil.
+func (c *APIClient) RotateToken(ctx context.Context, tokenID string, gracePeriodMinutes *int) (*TokenCreateResponse, error) {
+	payload := map[string]interface{}{}
+	if gracePeriodMinutes != nil {
+		payload["grace_period_minutes"] = *gracePeriodMinutes
+	}
+	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/manage/tokens/%s/rotate", tokenID), payload)
+	if err != nil {
+		return nil, err
+	}
+	var result TokenCreateResponse
+	if err := c.doRequest(req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 // RevokeProjectTokens revokes all tokens for a project in bulk
 func (c *APIClient) RevokeProjectTokens(ctx context.Context, projectID string) error {
 	req, err := c.newRequest(ctx, "POST", fmt.Sprintf("/manage/projects/%s/tokens/revoke", projectID), nil)
@@ -278,6 +278,8 @@ func TestAPIClient_GetDashboardData(t *testing.T) {
 			if err := json.NewEncoder(w).Encode(tokens); err != nil {
 				t.Errorf("failed to encode tokens: %v", err)
 			}
+		case "/manage/costs":
+			_, _ = w.Write([]byte(`{"total":{"request_count":15,"cost_usd":1.25},"projects":[{"project_id":"1","request_count":15,"cost_usd":1.25}]}`))
 		default:
 			http.NotFound(w, r)
 		}
@@ -307,6 +309,12 @@ func TestAPIClient_GetDashboardData(t *testing.T) {
 	if data.TotalRequests != 15 {
 		t.Errorf("TotalRequests = %d, want 15", data.TotalRequests)
 	}
+	if data.TotalCostUSD != 1.25 {
+		t.Errorf("TotalCostUSD = %v, want 1.25", data.TotalCostUSD)
+	}
+	if len(data.ProjectCosts) != 1 || data.ProjectCosts[0].ProjectName != "Test Project" {
+		t.Errorf("ProjectCosts = %+v, want one cost for Test Project", data.ProjectCosts)
+	}
 }
 
 func TestAPIClient_GetProjects(t *testing.T) {
@@ -607,7 +615,7 @@ func TestAPIClient_CreateToken(t *testing.T) {
 			client := NewAPIClient(server.URL, "test-token")
 			ctx := context.Background()
 
-			token, err := client.CreateToken(ctx, "project-1", 24, tt.maxRequests)
+			token, err := client.CreateToken(ctx, "project-1", 24, tt.maxRequests, nil)
 			if err != nil {
 				t.Fatalf("CreateToken failed: %v", err)
 			}
@@ -720,6 +728,152 @@ func TestAPIClient_newRequest_ForwardsBrowserContext(t *testing.T) {
 	}
 }
 
+func TestAPIClient_UpdateProjectAPIKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" || r.URL.Path != "/manage/projects/1" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+
+		var req struct {
+			APIKeys map[string]*string `json:"api_keys"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if v, ok := req.APIKeys["gemini"]; !ok || v != nil {
+			http.Error(w, "expected null gemini key", http.StatusBadRequest)
+			return
+		}
+
+		project := Project{ID: "1", APIKeys: map[string]string{"anthropic": "sk-a...new"}}
+		if err := json.NewEncoder(w).Encode(project); err != nil {
+			t.Errorf("failed to encode project: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(server.URL, "test-token")
+	key := "sk-ant-new"
+	project, err := client.UpdateProjectAPIKeys(context.Background(), "1", map[string]*string{"anthropic": &key, "gemini": nil})
+	if err != nil {
+		t.Fatalf("UpdateProjectAPIKeys failed: %v", err)
+	}
+	if project.APIKeys["anthropic"] != "sk-a...new" {
+		t.Errorf("APIKeys = %v, want obfuscated anthropic key", project.APIKeys)
+	}
+}
+
+func TestAPIClient_UpdateProjectAllowedModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		var models []string
+		if err := json.Unmarshal(req["allowed_models"], &models); err != nil || models == nil {
+			http.Error(w, "expected allowed_models list", http.StatusBadRequest)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(Project{ID: "1", AllowedModels: models}); err != nil {
+			t.Errorf("failed to encode project: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(server.URL, "test-token")
+	project, err := client.UpdateProjectAllowedModels(context.Background(), "1", []string{"gpt-4o*"})
+	if err != nil {
+		t.Fatalf("UpdateProjectAllowedModels failed: %v", err)
+	}
+	if len(project.AllowedModels) != 1 || project.AllowedModels[0] != "gpt-4o*" {
+		t.Errorf("AllowedModels = %v, want [gpt-4o*]", project.AllowedModels)
+	}
+
+	// A nil list is sent as [] so the allowlist is cleared rather than left unchanged
+	if _, err := client.UpdateProjectAllowedModels(context.Background(), "1", nil); err != nil {
+		t.Fatalf("UpdateProjectAllowedModels(nil) failed: %v", err)
+	}
+}
+
+func TestAPIClient_UpdateTokenMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH, got %s", r.Method)
+		}
+		var req map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		var metadata map[string]string
+		if err := json.Unmarshal(req["metadata"], &metadata); err != nil || metadata == nil {
+			http.Error(w, "expected metadata object", http.StatusBadRequest)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(Token{ID: "tok-1", Metadata: metadata}); err != nil {
+			t.Errorf("failed to encode token: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(server.URL, "test-token")
+	token, err := client.UpdateTokenMetadata(context.Background(), "tok-1", map[string]string{"user_id": "42"})
+	if err != nil {
+		t.Fatalf("UpdateTokenMetadata failed: %v", err)
+	}
+	if token.Metadata["user_id"] != "42" {
+		t.Errorf("Metadata = %v, want user_id=42", token.Metadata)
+	}
+
+	// Nil metadata is sent as {} so it is cleared rather than left unchanged
+	if _, err := client.UpdateTokenMetadata(context.Background(), "tok-1", nil); err != nil {
+		t.Fatalf("UpdateTokenMetadata(nil) failed: %v", err)
+	}
+}
+
+func TestAPIClient_RotateToken(t *testing.T) {
+	var lastBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/manage/tokens/tok-1/rotate" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		lastBody = nil
+		if err := json.NewDecoder(r.Body).Decode(&lastBody); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(TokenCreateResponse{ID: "tok-2", Token: "sk-new", RotatedFromID: "tok-1"}); err != nil {
+			t.Errorf("failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(server.URL, "test-token")
+	grace := 30
+	resp, err := client.RotateToken(context.Background(), "tok-1", &grace)
+	if err != nil {
+		t.Fatalf("RotateToken failed: %v", err)
+	}
+	if resp.ID != "tok-2" || resp.Token != "sk-new" || resp.RotatedFromID != "tok-1" {
+		t.Errorf("RotateToken() = %+v", resp)
+	}
+	if lastBody["grace_period_minutes"] != float64(30) {
+		t.Errorf("grace_period_minutes = %v, want 30", lastBody["grace_period_minutes"])
+	}
+
+	// Without a grace period the server default applies
+	if _, err := client.RotateToken(context.Background(), "tok-1", nil); err != nil {
+		t.Fatalf("RotateToken(nil) failed: %v", err)
+	}
+	if _, ok := lastBody["grace_period_minutes"]; ok {
+		t.Errorf("did not expect grace_period_minutes in payload: %v", lastBody)
+	}
+}
+
 func TestAPIClient_UpdateProjectPartial(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var req map[string]string
@@ -1009,7 +1163,7 @@ func TestAPIClient_GetTokens_Errors(t *testing.T) {
 func TestAPIClient_CreateToken_Errors(t *testing.T) {
 	client := NewAPIClient("http://invalid-host", "token")
 	ctx := context.Background()
-	_, err := client.CreateToken(ctx, "id", 1, nil)
+	_, err := client.CreateToken(ctx, "id", 1, nil, nil)
 	if err == nil {
 		t.Error("expected network error, got nil")
 	}
@@ -1020,7 +1174,7 @@ func TestAPIClient_CreateToken_Errors(t *testing.T) {
 	}))
 	defer server.Close()
 	client2 := NewAPIClient(server.URL, "token")
-	_, err = client2.CreateToken(ctx, "id", 1, nil)
+	_, err = client2.CreateToken(ctx, "id", 1, nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "fail") {
 		t.Errorf("expected API error, got %v", err)
 	}
@@ -1033,7 +1187,7 @@ func TestAPIClient_CreateToken_Errors(t *testing.T) {
 	}))
 	defer server2.Close()
 	client3 := NewAPIClient(server2.URL, "token")
-	_, err = client3.CreateToken(ctx, "id", 1, nil)
+	_, err = client3.CreateToken(ctx, "id", 1, nil, nil)
 	if err == nil || !strings.Contains(err.Error(), "decode") {
 		t.Errorf("expected decode error, got %v", err)
 	}
@@ -48,16 +48,20 @@ type APIClientInterface interface {
 	GetDashboardData(ctx context.Context) (*DashboardData, error)
 	GetProjects(ctx context.Context, page, pageSize int) ([]Project, *Pagination, error)
 	GetTokens(ctx context.Context, projectID string, page, pageSize int) ([]Token, *Pagination, error)
-	CreateToken(ctx context.Context, projectID string, durationMinutes int, maxRequests *int) (*TokenCreateResponse, error)
+	CreateToken(ctx context.Context, projectID string, durationMinutes int, maxRequests *int, metadata map[string]string) (*TokenCreateResponse, error)
 	GetProject(ctx context.Context, projectID string) (*Project, error)
 	UpdateProject(ctx context.Context, projectID string, name string, openAIAPIKey string, isActive *bool) (*Project, error)
+	UpdateProjectAPIKeys(ctx context.Context, projectID string, apiKeys map[string]*string) (*Project, error)
+	UpdateProjectAllowedModels(ctx context.Context, projectID string, models []string) (*Project, error)
 	DeleteProject(ctx context.Context, projectID string) error
 	CreateProject(ctx context.Context, name string, openAIAPIKey string) (*Project, error)
 	GetAuditEvents(ctx context.Context, filters map[string]string, page, pageSize int) ([]AuditEvent, *Pagination, error)
 	GetAuditEvent(ctx context.Context, id string) (*AuditEvent, error)
 	GetToken(ctx context.Context, tokenID string) (*Token, error)
 	UpdateToken(ctx context.Context, tokenID string, isActive *bool, maxRequests *int) (*Token, error)
+	UpdateTokenMetadata(ctx context.Context, tokenID string, metadata map[string]string) (*Token, error)
 	RevokeToken(ctx context.Context, tokenID string) error
+	RotateToken(ctx context.Context, tokenID string, gracePeriodMinutes *int) (*TokenCreateResponse, error)
 	RevokeProjectTokens(ctx context.Context, projectID string) error
 }
 
@@ -236,6 +240,7 @@ func (s *Server) setupRoutes() {
 			tokens.POST("/:token", s.handleTokensPostOverride)
 			tokens.PUT("/:token", s.handleTokensUpdate)
 			tokens.DELETE("/:token", s.handleTokensRevoke)
+			tokens.POST("/:token/rotate", s.handleTokensRotate)
 		}
 
 		// Audit routes
@@ -392,6 +397,24 @@ func (s *Server) handleProjectsCreate(c *gin.Context) {
 		return
 	}
 
+	if apiKeys := parseProviderAPIKeysForm(c); len(apiKeys) > 0 {
+		if _, err := apiClient.UpdateProjectAPIKeys(ctx, project.ID, apiKeys); err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"error": fmt.Sprintf("Project created, but failed to save provider API key: %v", err),
+			})
+			return
+		}
+	}
+
+	if models := parseAllowedModelsForm(c); len(models) > 0 {
+		if _, err := apiClient.UpdateProjectAllowedModels(ctx, project.ID, models); err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"error": fmt.Sprintf("Project created, but failed to save allowed models: %v", err),
+			})
+			return
+		}
+	}
+
 	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/projects/%s", project.ID))
 }
 
@@ -495,9 +518,61 @@ func (s *Server) handleProjectsUpdate(c *gin.Context) {
 		return
 	}
 
+	if apiKeys := parseProviderAPIKeysForm(c); len(apiKeys) > 0 {
+		if _, err := apiClient.UpdateProjectAPIKeys(ctx, id, apiKeys); err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"error": fmt.Sprintf("Failed to update provider API keys: %v", err),
+			})
+			return
+		}
+	}
+
+	// The edit form always carries the full allowlist, so an empty field clears it
+	if _, ok := c.GetPostForm("allowed_models"); ok {
+		if _, err := apiClient.UpdateProjectAllowedModels(ctx, id, parseAllowedModelsForm(c)); err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"error": fmt.Sprintf("Failed to update allowed models: %v", err),
+			})
+			return
+		}
+	}
+
 	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/projects/%s", project.ID))
 }
 
+// parseProviderAPIKeysForm collects per-provider key changes from a project form:
+// non-empty api_keys[<provider>] fields rotate keys, remove_api_keys entries delete them,
+// and new_api_key_provider/new_api_key add a key. Removal wins over rotation.
+func parseProviderAPIKeysForm(c *gin.Context) map[string]*string {
+	changes := make(map[string]*string)
+	for provider, key := range c.PostFormMap("api_keys") {
+		if key = strings.TrimSpace(key); key != "" {
+			changes[provider] = &key
+		}
+	}
+	if provider, key := strings.TrimSpace(c.PostForm("new_api_key_provider")), strings.TrimSpace(c.PostForm("new_api_key")); provider != "" && key != "" {
+		changes[provider] = &key
+	}
+	for _, provider := range c.PostFormArray("remove_api_keys") {
+		changes[provider] = nil
+	}
+	return changes
+}
+
+// parseAllowedModelsForm reads the allowed_models field of a project form, one
+// model pattern per line or separated by commas.
+func parseAllowedModelsForm(c *gin.Context) []string {
+	var models []string
+	for _, model := range strings.FieldsFunc(c.PostForm("allowed_models"), func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ','
+	}) {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
 // handleProjectsPostOverride routes POST requests with _method overrides to the appropriate handler.
 // It ensures form submissions to /projects/:id work even though Gin resolves routes before middleware.
 func (s *Server) handleProjectsPostOverride(c *gin.Context) {
@@ -695,6 +770,7 @@ func (s *Server) handleTokensCreate(c *gin.Context) {
 			"project_id":       req.ProjectID,
 			"duration_minutes": req.DurationMinutes,
 			"max_requests":     req.MaxRequests,
+			"metadata":         c.PostForm("metadata"),
 			"error":            message,
 		})
 	}
@@ -711,7 +787,13 @@ func (s *Server) handleTokensCreate(c *gin.Context) {
 		}
 	}
 
-	token, err := apiClient.CreateToken(ctx, req.ProjectID, req.DurationMinutes, maxRequests)
+	metadata, err := parseMetadataForm(c)
+	if err != nil {
+		renderNewTokenFormError(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	token, err := apiClient.CreateToken(ctx, req.ProjectID, req.DurationMinutes, maxRequests, metadata)
 	if err != nil {
 		// forward context as well for consistency in audit logs
 		projCtx := ctx
@@ -1422,7 +1504,18 @@ func (s *Server) handleTokensUpdate(c *gin.Context) {
 		maxRequests = &parsedMaxRequests
 	}
 
-	_, err := apiClient.UpdateToken(ctx, tokenID, req.IsActive, maxRequests)
+	// The edit form always carries the full metadata, so an empty field clears it
+	_, hasMetadata := c.GetPostForm("metadata")
+	metadata, err := parseMetadataForm(c)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error":   "Invalid form data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	_, err = apiClient.UpdateToken(ctx, tokenID, req.IsActive, maxRequests)
 	if err != nil {
 		if strings.Contains(err.Error(), "404") {
 			c.HTML(http.StatusNotFound, "error.html", gin.H{
@@ -1438,9 +1531,40 @@ func (s *Server) handleTokensUpdate(c *gin.Context) {
 		return
 	}
 
+	if hasMetadata {
+		if _, err := apiClient.UpdateTokenMetadata(ctx, tokenID, metadata); err != nil {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"error":   "Failed to update token metadata",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
 	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/tokens/%s", tokenID))
 }
 
+// parseMetadataForm reads the metadata textarea, one key=value pair per line.
+func parseMetadataForm(c *gin.Context) (map[string]string, error) {
+	var metadata map[string]string
+	for _, line := range strings.FieldsFunc(c.PostForm("metadata"), func(r rune) bool {
+		return r == '\n' || r == '\r'
+	}) {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("metadata line %q must have the form key=value", line)
+		}
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return metadata, nil
+}
+
 func (s *Server) handleTokensRevoke(c *gin.Context) {
 	// Get API client from context
 	apiClient := c.MustGet("apiClient").(APIClientInterface)
@@ -1482,6 +1606,65 @@ func (s *Server) handleTokensRevoke(c *gin.Context) {
 	c.Redirect(http.StatusSeeOther, "/tokens")
 }
 
+func (s *Server) handleTokensRotate(c *gin.Context) {
+	// Get API client from context
+	apiClient := c.MustGet("apiClient").(APIClientInterface)
+
+	tokenID := c.Param("token")
+	if tokenID == "" {
+		c.HTML(http.StatusBadRequest, "error.html", gin.H{
+			"error": "Token ID is required",
+		})
+		return
+	}
+
+	// An empty grace period uses the server default
+	var gracePeriodMinutes *int
+	if raw := strings.TrimSpace(c.PostForm("grace_period_minutes")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			c.HTML(http.StatusBadRequest, "error.html", gin.H{
+				"error":   "Invalid grace period",
+				"details": "Grace period must be a non-negative number of minutes",
+			})
+			return
+		}
+		gracePeriodMinutes = &parsed
+	}
+
+	ctx := context.WithValue(c.Request.Context(), ctxKeyForwardedUA, c.Request.UserAgent())
+	if ip := c.Request.Header.Get("X-Forwarded-For"); ip != "" {
+		ctx = context.WithValue(ctx, ctxKeyForwardedIP, strings.Split(ip, ",")[0])
+	} else if ip := c.Request.Header.Get("X-Real-IP"); ip != "" {
+		ctx = context.WithValue(ctx, ctxKeyForwardedIP, ip)
+	}
+	if ref := c.Request.Referer(); ref != "" {
+		ctx = context.WithValue(ctx, ctxKeyForwardedReferer, ref)
+	}
+
+	token, err := apiClient.RotateToken(ctx, tokenID, gracePeriodMinutes)
+	if err != nil {
+		if strings.Contains(err.Error(), "404") {
+			c.HTML(http.StatusNotFound, "error.html", gin.H{
+				"error":   "Token not found",
+				"details": fmt.Sprintf("Token %s was not found", tokenID),
+			})
+		} else {
+			c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+				"error":   "Failed to rotate token",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.HTML(http.StatusOK, "tokens/created.html", gin.H{
+		"title":  "Token Rotated",
+		"active": "tokens",
+		"token":  token,
+	})
+}
+
 // handleTokensPostOverride routes POST requests with _method overrides for token actions.
 func (s *Server) handleTokensPostOverride(c *gin.Context) {
 	// Parse form to access _method
@@ -8,6 +8,7 @@ import (
 	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -275,10 +276,15 @@ func TestServer_Shutdown_NoServer(t *testing.T) {
 // Only implements methods needed for handler coverage
 
 type mockAPIClient struct {
-	DashboardData         *DashboardData
-	DashboardErr          error
-	LastCreateMaxRequests *int
-	LastUpdateMaxRequests *int
+	DashboardData            *DashboardData
+	DashboardErr             error
+	LastCreateMaxRequests    *int
+	LastUpdateMaxRequests    *int
+	LastProjectAPIKeys       map[string]*string
+	LastProjectAllowedModels []string
+	LastCreateMetadata       map[string]string
+	LastTokenMetadata        map[string]string
+	LastRotateGracePeriod    *int
 }
 
 func (m *mockAPIClient) GetDashboardData(ctx context.Context) (*DashboardData, error) {
@@ -299,11 +305,12 @@ func (m *mockAPIClient) GetTokens(ctx context.Context, projectID string, page, p
 	return []Token{{ProjectID: "1", IsActive: true}}, &Pagination{Page: page, PageSize: pageSize, TotalItems: 1, TotalPages: 1, HasNext: false, HasPrev: false}, nil
 }
 
-func (m *mockAPIClient) CreateToken(ctx context.Context, projectID string, durationMinutes int, maxRequests *int) (*TokenCreateResponse, error) {
+func (m *mockAPIClient) CreateToken(ctx context.Context, projectID string, durationMinutes int, maxRequests *int, metadata map[string]string) (*TokenCreateResponse, error) {
 	if m.DashboardErr != nil {
 		return nil, m.DashboardErr
 	}
 	m.LastCreateMaxRequests = maxRequests
+	m.LastCreateMetadata = metadata
 	return &TokenCreateResponse{Token: "tok-1234", ExpiresAt: time.Now().Add(time.Duration(durationMinutes) * time.Minute)}, nil
 }
 
@@ -325,6 +332,22 @@ func (m *mockAPIClient) UpdateProject(ctx context.Context, id, name, apiKey stri
 	return project, nil
 }
 
+func (m *mockAPIClient) UpdateProjectAPIKeys(ctx context.Context, id string, apiKeys map[string]*string) (*Project, error) {
+	if m.DashboardErr != nil {
+		return nil, m.DashboardErr
+	}
+	m.LastProjectAPIKeys = apiKeys
+	return &Project{ID: id}, nil
+}
+
+func (m *mockAPIClient) UpdateProjectAllowedModels(ctx context.Context, id string, models []string) (*Project, error) {
+	if m.DashboardErr != nil {
+		return nil, m.DashboardErr
+	}
+	m.LastProjectAllowedModels = models
+	return &Project{ID: id}, nil
+}
+
 func (m *mockAPIClient) DeleteProject(ctx context.Context, id string) error {
 	return m.DashboardErr
 }
@@ -374,10 +397,26 @@ func (m *mockAPIClient) UpdateToken(ctx context.Context, tokenID string, isActiv
 	return token, nil
 }
 
+func (m *mockAPIClient) UpdateTokenMetadata(ctx context.Context, tokenID string, metadata map[string]string) (*Token, error) {
+	if m.DashboardErr != nil {
+		return nil, m.DashboardErr
+	}
+	m.LastTokenMetadata = metadata
+	return &Token{ID: tokenID, ProjectID: "1", IsActive: true, Metadata: metadata}, nil
+}
+
 func (m *mockAPIClient) RevokeToken(ctx context.Context, tokenID string) error {
 	return m.DashboardErr
 }
 
+func (m *mockAPIClient) RotateToken(ctx context.Context, tokenID string, gracePeriodMinutes *int) (*TokenCreateResponse, error) {
+	if m.DashboardErr != nil {
+		return nil, m.DashboardErr
+	}
+	m.LastRotateGracePeriod = gracePeriodMinutes
+	return &TokenCreateResponse{ID: "tok-new", Token: "tok-5678", ExpiresAt: time.Now().Add(time.Hour), RotatedFromID: tokenID}, nil
+}
+
 func (m *mockAPIClient) RevokeProjectTokens(ctx context.Context, projectID string) error {
 	return m.DashboardErr
 }
@@ -697,6 +736,17 @@ func TestServer_HandleTokensCreate(t *testing.T) {
 	if client.LastCreateMaxRequests == nil || *client.LastCreateMaxRequests != 25 {
 		t.Fatalf("expected max_requests to be forwarded, got %v", client.LastCreateMaxRequests)
 	}
+
+	form = strings.NewReader("project_id=1&duration_minutes=1440&metadata=user_id%3D42")
+	req, _ = http.NewRequest("POST", "/tokens", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	assert.Equal(t, map[string]string{"user_id": "42"}, client.LastCreateMetadata)
 }
 
 func TestServer_HandleTokensCreate_AllowsBlankMaxRequests(t *testing.T) {
@@ -889,6 +939,82 @@ func TestServer_HandleProjectsUpdate(t *testing.T) {
 	}
 }
 
+func TestServer_HandleProjectsUpdate_ProviderAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{engine: gin.New()}
+
+	client := &mockAPIClient{}
+	s.engine.PUT("/projects/:id", func(c *gin.Context) {
+		c.Set("apiClient", client)
+		s.handleProjectsUpdate(c)
+	})
+
+	form := url.Values{}
+	form.Set("name", "Updated")
+	form.Set("api_keys[anthropic]", "sk-ant-new")
+	form.Set("api_keys[gemini]", "")
+	form.Set("api_keys[mistral]", "m-rotated")
+	form.Add("remove_api_keys", "mistral")
+	form.Set("new_api_key_provider", "cohere")
+	form.Set("new_api_key", "co-key")
+	req, _ := http.NewRequest("PUT", "/projects/1", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", w.Code)
+	}
+	got := client.LastProjectAPIKeys
+	if len(got) != 3 {
+		t.Fatalf("expected 3 provider key changes, got %v", got)
+	}
+	if got["anthropic"] == nil || *got["anthropic"] != "sk-ant-new" {
+		t.Errorf("expected anthropic rotation, got %v", got["anthropic"])
+	}
+	if got["cohere"] == nil || *got["cohere"] != "co-key" {
+		t.Errorf("expected cohere addition, got %v", got["cohere"])
+	}
+	if v, ok := got["mistral"]; !ok || v != nil {
+		t.Errorf("expected mistral removal to win over rotation, got %v", v)
+	}
+	if _, ok := got["gemini"]; ok {
+		t.Error("empty key field must keep the existing key")
+	}
+}
+
+func TestServer_HandleProjectsUpdate_AllowedModels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := &Server{engine: gin.New()}
+
+	client := &mockAPIClient{}
+	s.engine.PUT("/projects/:id", func(c *gin.Context) {
+		c.Set("apiClient", client)
+		s.handleProjectsUpdate(c)
+	})
+
+	send := func(form url.Values) {
+		t.Helper()
+		req, _ := http.NewRequest("PUT", "/projects/1", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, req)
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("expected 303, got %d", w.Code)
+		}
+	}
+
+	send(url.Values{"name": {"Updated"}, "allowed_models": {"gpt-4o-mini\r\n gpt-4.1*, \n\n"}})
+	assert.Equal(t, []string{"gpt-4o-mini", "gpt-4.1*"}, client.LastProjectAllowedModels)
+
+	send(url.Values{"name": {"Updated"}, "allowed_models": {""}})
+	assert.Empty(t, client.LastProjectAllowedModels, "an empty field clears the allowlist")
+
+	client.LastProjectAllowedModels = []string{"unchanged"}
+	send(url.Values{"name": {"Updated"}})
+	assert.Equal(t, []string{"unchanged"}, client.LastProjectAllowedModels, "forms without the field leave the allowlist alone")
+}
+
 func TestServer_HandleProjectsUpdate_Errors(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	projectsEditFile := filepath.Join(testTemplateDir(), "projects-edit.html")
@@ -1490,6 +1616,98 @@ func TestServer_HandleTokensUpdate_AllowsEmptyMaxRequests_Unlimited(t *testing.T
 	}
 }
 
+func TestServer_HandleTokensUpdate_Metadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	errTpl := filepath.Join(testTemplateDir(), "error.html")
+	if err := os.WriteFile(errTpl, []byte("<html><body>error</body></html>"), 0644); err != nil {
+		t.Fatalf("write error.html: %v", err)
+	}
+	defer func() { _ = os.Remove(errTpl) }()
+
+	s := &Server{engine: gin.New()}
+	s.engine.SetFuncMap(template.FuncMap{})
+	s.engine.LoadHTMLGlob(filepath.Join(testTemplateDir(), "*.html"))
+
+	client := &mockAPIClient{}
+	s.engine.PUT("/tokens/:token", func(c *gin.Context) {
+		c.Set("apiClient", client)
+		s.handleTokensUpdate(c)
+	})
+
+	send := func(form url.Values) int {
+		t.Helper()
+		r, _ := http.NewRequest("PUT", "/tokens/tok-1", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusSeeOther, send(url.Values{"metadata": {"user_id = 42\r\n\nclient_ip=203.0.113.7"}}))
+	assert.Equal(t, map[string]string{"user_id": "42", "client_ip": "203.0.113.7"}, client.LastTokenMetadata)
+
+	assert.Equal(t, http.StatusSeeOther, send(url.Values{"metadata": {""}}))
+	assert.Empty(t, client.LastTokenMetadata, "an empty field clears the metadata")
+
+	client.LastTokenMetadata = map[string]string{"unchanged": "1"}
+	assert.Equal(t, http.StatusSeeOther, send(url.Values{"max_requests": {"5"}}))
+	assert.Equal(t, map[string]string{"unchanged": "1"}, client.LastTokenMetadata, "forms without the field leave the metadata alone")
+
+	assert.Equal(t, http.StatusBadRequest, send(url.Values{"metadata": {"no-separator"}}))
+}
+
+func TestServer_HandleTokensRotate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokensDir := filepath.Join(testTemplateDir(), "tokens")
+	_ = os.MkdirAll(tokensDir, 0755)
+	createdTpl := filepath.Join(tokensDir, "created.html")
+	if err := os.WriteFile(createdTpl, []byte(`{{ define "tokens/created.html" }}{{ .token.Token }} replaces {{ .token.RotatedFromID }}{{ end }}`), 0644); err != nil {
+		t.Fatalf("write created.html: %v", err)
+	}
+	defer func() { _ = os.Remove(createdTpl) }()
+	errTpl := filepath.Join(testTemplateDir(), "error.html")
+	if err := os.WriteFile(errTpl, []byte("<html><body>error</body></html>"), 0644); err != nil {
+		t.Fatalf("write error.html: %v", err)
+	}
+	defer func() { _ = os.Remove(errTpl) }()
+
+	s := &Server{engine: gin.New()}
+	s.engine.SetFuncMap(template.FuncMap{})
+	s.engine.LoadHTMLGlob(filepath.Join(testTemplateDir(), "**/*.html"))
+
+	client := &mockAPIClient{}
+	s.engine.POST("/tokens/:token/rotate", func(c *gin.Context) {
+		c.Set("apiClient", client)
+		s.handleTokensRotate(c)
+	})
+
+	send := func(form url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		r, _ := http.NewRequest("POST", "/tokens/tok-1/rotate", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		s.engine.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send(url.Values{"grace_period_minutes": {"30"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "tok-5678 replaces tok-1")
+	require.NotNil(t, client.LastRotateGracePeriod)
+	assert.Equal(t, 30, *client.LastRotateGracePeriod)
+
+	w = send(url.Values{})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, client.LastRotateGracePeriod, "an empty grace period uses the server default")
+
+	w = send(url.Values{"grace_period_minutes": {"-5"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	client.DashboardErr = fmt.Errorf("API error 409: token has already been rotated")
+	w = send(url.Values{})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
 func TestServer_TokenAndProjectHandlers_MissingParams(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	// error.html template for 400s
@@ -64,6 +64,7 @@ const (
 	ActionTokenRead        = "token.read"
 	ActionTokenUpdate      = "token.update"
 	ActionTokenRevoke      = "token.revoke"
+	ActionTokenRotate      = "token.rotate"
 	ActionTokenRevokeBatch = "token.revoke_batch"
 	ActionTokenDelete      = "token.delete"
 	ActionTokenList        = "token.list"
@@ -78,7 +79,9 @@ const (
 	ActionProjectList   = "project.list"
 
 	// Proxy request actions
-	ActionProxyRequest = "proxy_request"
+	ActionProxyRequest         = "proxy_request"
+	ActionProxyFallback        = "proxy.fallback"
+	ActionProxyPolicyViolation = "proxy.policy_violation"
 
 	// Admin actions
 	ActionAdminLogin  = "admin.login"
@@ -91,6 +94,23 @@ const (
 
 	// Cache actions
 	ActionCachePurge = "cache.purge"
+
+	// Model routing actions
+	ActionModelRoutesRead   = "model_routes.read"
+	ActionModelRoutesUpdate = "model_routes.update"
+
+	// Price catalog actions
+	ActionPricesRead   = "prices.read"
+	ActionPricesUpdate = "prices.update"
+
+	// Budget actions
+	ActionBudgetRead      = "budget.read"
+	ActionBudgetUpdate    = "budget.update"
+	ActionBudgetDelete    = "budget.delete"
+	ActionBudgetThreshold = "budget.threshold"
+
+	// Circuit breaker actions
+	ActionCircuitBreakerStateChange = "circuit_breaker.state_change"
 )
 
 // Actor types for common audit actors
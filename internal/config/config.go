@@ -34,6 +34,7 @@ type Config struct {
 	DefaultAPIProvider string // Default API provider to use
 	OpenAIAPIURL       string // Base URL for OpenAI API (legacy support)
 	EnableStreaming    bool   // Whether to enable streaming responses from APIs
+	PriceCatalogPath   string // Path to a YAML model price catalog seeding cost accounting (empty to disable)
 
 	// Admin UI settings
 	AdminUIPath string        // Base path for the admin UI
@@ -70,6 +71,15 @@ type Config struct {
 	GlobalRateLimit int // Maximum requests per minute globally
 	IPRateLimit     int // Maximum requests per minute per IP
 
+	// Client IP resolution
+	TrustedProxies []string // CIDRs/IPs allowed to set X-Forwarded-For and X-Real-IP (empty ignores the headers)
+
+	// Signed child tokens
+	ChildTokenSecret     string        // HMAC secret for signing and verifying child tokens (at least 32 bytes)
+	ChildTokenPrivateKey string        // Base64 Ed25519 private key (seed or full key) for signing child tokens
+	ChildTokenPublicKey  string        // Base64 Ed25519 public key for verifying child tokens minted elsewhere
+	ChildTokenParentTTL  time.Duration // How long parent token data is cached when validating child tokens
+
 	// Distributed rate limiting
 	DistributedRateLimitEnabled   bool          // Enable Redis-backed distributed rate limiting
 	DistributedRateLimitPrefix    string        // Redis key prefix for rate limit counters
@@ -78,6 +88,21 @@ type Config struct {
 	DistributedRateLimitMax       int           // Maximum requests per window
 	DistributedRateLimitFallback  bool          // Enable fallback to in-memory when Redis unavailable
 
+	// Tokens-per-minute limits (0 disables a limit); counted in Redis when distributed rate limiting is enabled
+	TPMLimitGlobal     int64 // Maximum LLM tokens per minute across all requests
+	TPMLimitPerProject int64 // Maximum LLM tokens per minute per project
+	TPMLimitPerToken   int64 // Maximum LLM tokens per minute per token
+
+	// Concurrency limits (0 disables a limit); leases are held in Redis when distributed rate limiting is enabled
+	ConcurrencyLimitPerProject int64         // Maximum in-flight requests per project
+	ConcurrencyLimitPerToken   int64         // Maximum in-flight requests per token
+	ConcurrencyLeaseTTL        time.Duration // Lifetime of unrenewed leases, freeing slots of crashed instances
+
+	// Spend budgets
+	BudgetRedisEnabled   bool   // Count budget spend in Redis (RedisAddr/RedisDB) so all instances share it
+	BudgetRedisKeyPrefix string // Redis key prefix for budget spend counters
+	BudgetWebhookURL     string // URL receiving budget threshold alerts as JSON POSTs (empty to disable)
+
 	// Monitoring
 	EnableMetrics bool   // Whether to enable a lightweight metrics endpoint (provider-agnostic)
 	MetricsPath   string // Path for metrics endpoint
@@ -85,9 +110,12 @@ type Config struct {
 	// Cleanup
 	TokenCleanupInterval time.Duration // Interval for cleaning up expired tokens
 
+	// Token rotation
+	TokenRotationGracePeriod time.Duration // How long a rotated token stays valid by default
+
 	// Project active guard configuration
 	EnforceProjectActive bool          // Whether to enforce project active status (default: true)
-	ActiveCacheTTL       time.Duration // TTL for project active status cache (e.g., 5s)
+	ActiveCacheTTL       time.Duration // TTL for project active status and model allowlist cache (e.g., 5s)
 	ActiveCacheMax       int           // Maximum entries in project active status cache (e.g., 10000)
 
 	// API key caching (hot path: per-request upstream auth lookup)
@@ -153,6 +181,7 @@ func New() (*Config, error) {
 		DefaultAPIProvider: getEnvString("DEFAULT_API_PROVIDER", "openai"),
 		OpenAIAPIURL:       getEnvString("OPENAI_API_URL", "https://api.openai.com"),
 		EnableStreaming:    getEnvBool("ENABLE_STREAMING", true),
+		PriceCatalogPath:   getEnvString("PRICE_CATALOG_PATH", ""),
 
 		// Admin UI settings
 		AdminUIPath: getEnvString("ADMIN_UI_PATH", "/admin"),
@@ -190,6 +219,15 @@ func New() (*Config, error) {
 		GlobalRateLimit: getEnvInt("GLOBAL_RATE_LIMIT", 100),
 		IPRateLimit:     getEnvInt("IP_RATE_LIMIT", 30),
 
+		// Client IP resolution defaults
+		TrustedProxies: getEnvStringSlice("TRUSTED_PROXIES", nil),
+
+		// Signed child token defaults
+		ChildTokenSecret:     getEnvString("CHILD_TOKEN_SECRET", ""),
+		ChildTokenPrivateKey: getEnvString("CHILD_TOKEN_PRIVATE_KEY", ""),
+		ChildTokenPublicKey:  getEnvString("CHILD_TOKEN_PUBLIC_KEY", ""),
+		ChildTokenParentTTL:  getEnvDuration("CHILD_TOKEN_PARENT_CACHE_TTL", 30*time.Second),
+
 		// Distributed rate limiting defaults
 		DistributedRateLimitEnabled:   getEnvBool("DISTRIBUTED_RATE_LIMIT_ENABLED", false),
 		DistributedRateLimitPrefix:    getEnvString("DISTRIBUTED_RATE_LIMIT_PREFIX", "ratelimit:"),
@@ -198,6 +236,21 @@ func New() (*Config, error) {
 		DistributedRateLimitMax:       getEnvInt("DISTRIBUTED_RATE_LIMIT_MAX", 60),
 		DistributedRateLimitFallback:  getEnvBool("DISTRIBUTED_RATE_LIMIT_FALLBACK", true),
 
+		// Tokens-per-minute limits
+		TPMLimitGlobal:     getEnvInt64("TPM_LIMIT_GLOBAL", 0),
+		TPMLimitPerProject: getEnvInt64("TPM_LIMIT_PER_PROJECT", 0),
+		TPMLimitPerToken:   getEnvInt64("TPM_LIMIT_PER_TOKEN", 0),
+
+		// Concurrency limits
+		ConcurrencyLimitPerProject: getEnvInt64("CONCURRENCY_LIMIT_PER_PROJECT", 0),
+		ConcurrencyLimitPerToken:   getEnvInt64("CONCURRENCY_LIMIT_PER_TOKEN", 0),
+		ConcurrencyLeaseTTL:        getEnvDuration("CONCURRENCY_LEASE_TTL", 30*time.Second),
+
+		// Spend budgets
+		BudgetRedisEnabled:   getEnvBool("BUDGET_REDIS_ENABLED", false),
+		BudgetRedisKeyPrefix: getEnvString("BUDGET_REDIS_KEY_PREFIX", "llmproxy:budget:"),
+		BudgetWebhookURL:     getEnvString("BUDGET_WEBHOOK_URL", ""),
+
 		// Monitoring defaults
 		EnableMetrics: getEnvBool("ENABLE_METRICS", true),
 		MetricsPath:   getEnvString("METRICS_PATH", "/metrics"),
@@ -205,6 +258,9 @@ func New() (*Config, error) {
 		// Cleanup defaults
 		TokenCleanupInterval: getEnvDuration("TOKEN_CLEANUP_INTERVAL", time.Hour),
 
+		// Token rotation defaults
+		TokenRotationGracePeriod: getEnvDuration("TOKEN_ROTATION_GRACE_PERIOD", time.Hour),
+
 		// Project active guard defaults
 		EnforceProjectActive: getEnvBool("LLM_PROXY_ENFORCE_PROJECT_ACTIVE", true),
 		ActiveCacheTTL:       getEnvDuration("LLM_PROXY_ACTIVE_CACHE_TTL", 5*time.Second),
@@ -375,6 +431,9 @@ func DefaultConfig() *Config {
 		GlobalRateLimit: 100,
 		IPRateLimit:     30,
 
+		// Signed child token defaults
+		ChildTokenParentTTL: 30 * time.Second,
+
 		// Distributed rate limiting defaults
 		DistributedRateLimitEnabled:   false,
 		DistributedRateLimitPrefix:    "ratelimit:",
@@ -383,6 +442,21 @@ func DefaultConfig() *Config {
 		DistributedRateLimitMax:       60,
 		DistributedRateLimitFallback:  true,
 
+		// Tokens-per-minute limits
+		TPMLimitGlobal:     0,
+		TPMLimitPerProject: 0,
+		TPMLimitPerToken:   0,
+
+		// Concurrency limits
+		ConcurrencyLimitPerProject: 0,
+		ConcurrencyLimitPerToken:   0,
+		ConcurrencyLeaseTTL:        30 * time.Second,
+
+		// Spend budgets
+		BudgetRedisEnabled:   false,
+		BudgetRedisKeyPrefix: "llmproxy:budget:",
+		BudgetWebhookURL:     "",
+
 		// Monitoring defaults
 		EnableMetrics: true,
 		MetricsPath:   "/metrics",
@@ -390,6 +464,9 @@ func DefaultConfig() *Config {
 		// Cleanup defaults
 		TokenCleanupInterval: time.Hour,
 
+		// Token rotation defaults
+		TokenRotationGracePeriod: time.Hour,
+
 		// Project active guard defaults
 		EnforceProjectActive: true,
 		ActiveCacheTTL:       5 * time.Second,
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+)
+
+// ErrBudgetNotFound is returned when a project or token has no budget.
+var ErrBudgetNotFound = errors.New("budget not found")
+
+// budgetSubjectRefs returns the project_id and token_id columns of budgets
+// and spend of a subject: its ID in the column of its type, NULL in the
+// other. They reference the subject, so budgets and spend are deleted with it.
+func budgetSubjectRefs(subjectType, subjectID string) (projectID, tokenID *string) {
+	if subjectType == proxy.BudgetSubjectToken {
+		return nil, &subjectID
+	}
+	return &subjectID, nil
+}
+
+// budgetSubjectExists reports whether the project or token of a budget exists.
+func (d *DB) budgetSubjectExists(ctx context.Context, tx *sql.Tx, subjectType, subjectID string) (bool, error) {
+	query := `SELECT COUNT(*) FROM projects WHERE id = ?`
+	if subjectType == proxy.BudgetSubjectToken {
+		query = `SELECT COUNT(*) FROM tokens WHERE id = ?`
+	}
+	var n int
+	if err := tx.QueryRowContext(ctx, d.RebindQuery(query), subjectID).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// GetBudget returns the budget of a project or token, or nil when it has none.
+func (d *DB) GetBudget(ctx context.Context, subjectType, subjectID string) (*proxy.Budget, error) {
+	var (
+		budget      proxy.Budget
+		period      string
+		limitUSD    sql.NullFloat64
+		limitTokens sql.NullInt64
+	)
+	query := `SELECT period, limit_usd, limit_tokens FROM budgets WHERE subject_type = ? AND subject_id = ?`
+	err := d.QueryRowContextRebound(ctx, query, subjectType, subjectID).Scan(&period, &limitUSD, &limitTokens)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budget: %w", err)
+	}
+	budget.Period = proxy.BudgetPeriod(period)
+	if limitUSD.Valid {
+		budget.LimitUSD = &limitUSD.Float64
+	}
+	if limitTokens.Valid {
+		budget.LimitTokens = &limitTokens.Int64
+	}
+	return &budget, nil
+}
+
+// SetBudget creates or replaces the budget of a project or token.
+func (d *DB) SetBudget(ctx context.Context, subjectType, subjectID string, budget proxy.Budget) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // No-op if already committed
+	}()
+
+	now := time.Now().UTC()
+	createdAt := now
+	query := `SELECT created_at FROM budgets WHERE subject_type = ? AND subject_id = ?`
+	if err := tx.QueryRowContext(ctx, d.RebindQuery(query), subjectType, subjectID).Scan(&createdAt); err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get budget: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, d.RebindQuery(`DELETE FROM budgets WHERE subject_type = ? AND subject_id = ?`), subjectType, subjectID); err != nil {
+		return fmt.Errorf("failed to replace budget: %w", err)
+	}
+	projectID, tokenID := budgetSubjectRefs(subjectType, subjectID)
+	insert := `INSERT INTO budgets (subject_type, subject_id, project_id, token_id, period, limit_usd, limit_tokens, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, d.RebindQuery(insert), subjectType, subjectID, projectID, tokenID, string(budget.Period),
+		budget.LimitUSD, budget.LimitTokens, createdAt, now); err != nil {
+		return fmt.Errorf("failed to insert budget: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteBudget removes the budget of a project or token. Its recorded spend
+// is kept.
+func (d *DB) DeleteBudget(ctx context.Context, subjectType, subjectID string) error {
+	result, err := d.ExecContextRebound(ctx, `DELETE FROM budgets WHERE subject_type = ? AND subject_id = ?`, subjectType, subjectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// GetBudgetSpend returns the spend of a project or token from the day of
+// since on.
+func (d *DB) GetBudgetSpend(ctx context.Context, subjectType, subjectID string, since time.Time) (proxy.Spend, error) {
+	var spend proxy.Spend
+	query := `SELECT COALESCE(SUM(cost_usd), 0), COALESCE(SUM(tokens), 0) FROM budget_spend
+		WHERE subject_type = ? AND subject_id = ? AND day >= ?`
+	day := since.UTC().Format(time.DateOnly)
+	if err := d.QueryRowContextRebound(ctx, query, subjectType, subjectID, day).Scan(&spend.CostUSD, &spend.Tokens); err != nil {
+		return proxy.Spend{}, fmt.Errorf("failed to get budget spend: %w", err)
+	}
+	return spend, nil
+}
+
+// AddBudgetSpend adds deltas to the daily spend of projects and tokens in one
+// transaction. Spend of projects and tokens deleted in the meantime is dropped.
+func (d *DB) AddBudgetSpend(ctx context.Context, deltas map[proxy.SpendKey]proxy.Spend) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // No-op if already committed
+	}()
+
+	update := `UPDATE budget_spend SET cost_usd = cost_usd + ?, tokens = tokens + ?
+		WHERE subject_type = ? AND subject_id = ? AND day = ?`
+	insert := `INSERT INTO budget_spend (cost_usd, tokens, subject_type, subject_id, day, project_id, token_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	for key, delta := range deltas {
+		args := []any{delta.CostUSD, delta.Tokens, key.SubjectType, key.SubjectID, key.Day}
+		result, err := tx.ExecContext(ctx, d.RebindQuery(update), args...)
+		if err != nil {
+			return fmt.Errorf("failed to update budget spend: %w", err)
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		} else if n > 0 {
+			continue
+		}
+		if exists, err := d.budgetSubjectExists(ctx, tx, key.SubjectType, key.SubjectID); err != nil {
+			return fmt.Errorf("failed to check budget subject: %w", err)
+		} else if !exists {
+			continue
+		}
+		projectID, tokenID := budgetSubjectRefs(key.SubjectType, key.SubjectID)
+		if _, err := tx.ExecContext(ctx, d.RebindQuery(insert), append(args, projectID, tokenID)...); err != nil {
+			return fmt.Errorf("failed to insert budget spend: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createBudgetSubjects creates the projects proj-a and proj-b and the token
+// budgetTokenID of proj-a.
+func createBudgetSubjects(t *testing.T, db *DB) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, id := range []string{"proj-a", "proj-b"} {
+		require.NoError(t, db.CreateProject(ctx, proxy.Project{ID: id, Name: id, APIKey: "key", CreatedAt: now, UpdatedAt: now}))
+	}
+	require.NoError(t, db.CreateToken(ctx, Token{ID: budgetTokenID, Token: "budget-token", ProjectID: "proj-a", IsActive: true, CreatedAt: now}))
+}
+
+const budgetTokenID = "22222222-2222-2222-2222-222222222222"
+
+func TestBudgets(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	createBudgetSubjects(t, db)
+
+	budget, err := db.GetBudget(ctx, proxy.BudgetSubjectProject, "proj-a")
+	require.NoError(t, err)
+	assert.Nil(t, budget)
+
+	limitUSD := 25.0
+	require.NoError(t, db.SetBudget(ctx, proxy.BudgetSubjectProject, "proj-a", proxy.Budget{Period: proxy.BudgetPeriodMonthly, LimitUSD: &limitUSD}))
+	budget, err = db.GetBudget(ctx, proxy.BudgetSubjectProject, "proj-a")
+	require.NoError(t, err)
+	require.NotNil(t, budget)
+	assert.Equal(t, proxy.BudgetPeriodMonthly, budget.Period)
+	require.NotNil(t, budget.LimitUSD)
+	assert.Equal(t, 25.0, *budget.LimitUSD)
+	assert.Nil(t, budget.LimitTokens)
+
+	// Setting a budget replaces it
+	limitTokens := int64(1000)
+	require.NoError(t, db.SetBudget(ctx, proxy.BudgetSubjectProject, "proj-a", proxy.Budget{Period: proxy.BudgetPeriodDaily, LimitTokens: &limitTokens}))
+	budget, err = db.GetBudget(ctx, proxy.BudgetSubjectProject, "proj-a")
+	require.NoError(t, err)
+	assert.Equal(t, proxy.Budget{Period: proxy.BudgetPeriodDaily, LimitTokens: &limitTokens}, *budget)
+
+	// Budgets are per subject type
+	budget, err = db.GetBudget(ctx, proxy.BudgetSubjectToken, "proj-a")
+	require.NoError(t, err)
+	assert.Nil(t, budget)
+
+	require.NoError(t, db.DeleteBudget(ctx, proxy.BudgetSubjectProject, "proj-a"))
+	budget, err = db.GetBudget(ctx, proxy.BudgetSubjectProject, "proj-a")
+	require.NoError(t, err)
+	assert.Nil(t, budget)
+	assert.ErrorIs(t, db.DeleteBudget(ctx, proxy.BudgetSubjectProject, "proj-a"), ErrBudgetNotFound)
+
+	// Budgets are deleted with their project or token
+	require.NoError(t, db.SetBudget(ctx, proxy.BudgetSubjectToken, budgetTokenID, proxy.Budget{Period: proxy.BudgetPeriodDaily, LimitUSD: &limitUSD}))
+	require.NoError(t, db.SetBudget(ctx, proxy.BudgetSubjectProject, "proj-b", proxy.Budget{Period: proxy.BudgetPeriodDaily, LimitUSD: &limitUSD}))
+	require.NoError(t, db.DeleteToken(ctx, "budget-token"))
+	require.NoError(t, db.DeleteProject(ctx, "proj-b"))
+	budget, err = db.GetBudget(ctx, proxy.BudgetSubjectToken, budgetTokenID)
+	require.NoError(t, err)
+	assert.Nil(t, budget)
+	budget, err = db.GetBudget(ctx, proxy.BudgetSubjectProject, "proj-b")
+	require.NoError(t, err)
+	assert.Nil(t, budget)
+}
+
+func TestBudgetSpend(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	createBudgetSubjects(t, db)
+
+	// Empty deltas are a no-op
+	require.NoError(t, db.AddBudgetSpend(ctx, nil))
+
+	project := func(day string) proxy.SpendKey {
+		return proxy.SpendKey{SubjectType: proxy.BudgetSubjectProject, SubjectID: "proj-a", Day: day}
+	}
+	require.NoError(t, db.AddBudgetSpend(ctx, map[proxy.SpendKey]proxy.Spend{
+		project("2026-09-30"): {CostUSD: 5, Tokens: 500},
+		project("2026-10-01"): {CostUSD: 1, Tokens: 100},
+		project("2026-10-02"): {CostUSD: 2, Tokens: 200},
+		{SubjectType: proxy.BudgetSubjectToken, SubjectID: budgetTokenID, Day: "2026-10-02"}: {CostUSD: 9, Tokens: 900},
+		{SubjectType: proxy.BudgetSubjectProject, SubjectID: "proj-b", Day: "2026-10-02"}:    {CostUSD: 4},
+		// Spend of deleted subjects is dropped without failing the others
+		{SubjectType: proxy.BudgetSubjectToken, SubjectID: "33333333-3333-3333-3333-333333333333", Day: "2026-10-02"}: {CostUSD: 1},
+	}))
+	require.NoError(t, db.AddBudgetSpend(ctx, map[proxy.SpendKey]proxy.Spend{
+		project("2026-10-02"): {CostUSD: 0.5, Tokens: 50},
+	}))
+
+	spend, err := db.GetBudgetSpend(ctx, proxy.BudgetSubjectProject, "proj-a", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.InDelta(t, 3.5, spend.CostUSD, 1e-9)
+	assert.Equal(t, int64(350), spend.Tokens)
+
+	spend, err = db.GetBudgetSpend(ctx, proxy.BudgetSubjectProject, "proj-a", time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.InDelta(t, 2.5, spend.CostUSD, 1e-9, "spend is summed from the start of the day")
+
+	spend, err = db.GetBudgetSpend(ctx, proxy.BudgetSubjectToken, "33333333-3333-3333-3333-333333333333", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Zero(t, spend)
+
+	// Spend is deleted with its project or token
+	require.NoError(t, db.DeleteToken(ctx, "budget-token"))
+	require.NoError(t, db.DeleteProject(ctx, "proj-b"))
+	for _, key := range []proxy.SpendKey{
+		{SubjectType: proxy.BudgetSubjectToken, SubjectID: budgetTokenID},
+		{SubjectType: proxy.BudgetSubjectProject, SubjectID: "proj-b"},
+	} {
+		spend, err = db.GetBudgetSpend(ctx, key.SubjectType, key.SubjectID, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		assert.Zero(t, spend, key.SubjectID)
+	}
+}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/pricing"
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+)
+
+// costColumns are the totals columns shared by token_costs and project_costs.
+const costColumns = `request_count, input_tokens, output_tokens, cached_input_tokens, cost_usd, updated_at`
+
+// AddCosts adds deltas to the cost totals of tokens and projects in one
+// transaction. Token rows take their project from the tokens table; deltas for
+// unknown tokens are ignored.
+func (d *DB) AddCosts(ctx context.Context, tokenDeltas, projectDeltas map[string]proxy.CostDelta) error {
+	if len(tokenDeltas) == 0 && len(projectDeltas) == 0 {
+		return nil
+	}
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // No-op if already committed
+	}()
+
+	now := time.Now().UTC()
+	for tokenID, delta := range tokenDeltas {
+		insert := `INSERT INTO token_costs (` + costColumns + `, token_id, project_id)
+			SELECT ?, ?, ?, ?, ?, ?, id, project_id FROM tokens WHERE id = ?`
+		if err := d.addCostTx(ctx, tx, "token_costs", "token_id", insert, tokenID, delta, now); err != nil {
+			return err
+		}
+	}
+	for projectID, delta := range projectDeltas {
+		insert := `INSERT INTO project_costs (` + costColumns + `, project_id) VALUES (?, ?, ?, ?, ?, ?, ?)`
+		if err := d.addCostTx(ctx, tx, "project_costs", "project_id", insert, projectID, delta, now); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// addCostTx adds delta to the row of table keyed by id, creating the row with
+// insert when it does not exist yet. Both statements take the delta, the
+// update time and id as arguments.
+func (d *DB) addCostTx(ctx context.Context, tx *sql.Tx, table, keyColumn, insert, id string, delta proxy.CostDelta, now time.Time) error {
+	args := []any{delta.Requests, delta.InputTokens, delta.OutputTokens, delta.CachedInputTokens, delta.CostUSD, now, id}
+	update := fmt.Sprintf(`UPDATE %s SET request_count = request_count + ?, input_tokens = input_tokens + ?,
+		output_tokens = output_tokens + ?, cached_input_tokens = cached_input_tokens + ?, cost_usd = cost_usd + ?, updated_at = ?
+		WHERE %s = ?`, table, keyColumn)
+	result, err := tx.ExecContext(ctx, d.RebindQuery(update), args...)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", table, err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	} else if n > 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, d.RebindQuery(insert), args...); err != nil {
+		return fmt.Errorf("failed to insert into %s: %w", table, err)
+	}
+	return nil
+}
+
+// GetTotalCost returns the cost totals over all projects.
+func (d *DB) GetTotalCost(ctx context.Context) (CostTotals, error) {
+	var totals CostTotals
+	query := `SELECT COALESCE(SUM(request_count), 0), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+		COALESCE(SUM(cached_input_tokens), 0), COALESCE(SUM(cost_usd), 0) FROM project_costs`
+	err := d.QueryRowContextRebound(ctx, query).Scan(&totals.RequestCount, &totals.InputTokens, &totals.OutputTokens,
+		&totals.CachedInputTokens, &totals.CostUSD)
+	if err != nil {
+		return CostTotals{}, fmt.Errorf("failed to get total cost: %w", err)
+	}
+	return totals, nil
+}
+
+// GetProjectCost returns the cost totals of a project; projects without
+// recorded usage have zero totals.
+func (d *DB) GetProjectCost(ctx context.Context, projectID string) (CostTotals, error) {
+	query := `SELECT ` + costColumns + ` FROM project_costs WHERE project_id = ?`
+	totals, err := scanCostTotals(d.QueryRowContextRebound(ctx, query, projectID))
+	if err == sql.ErrNoRows {
+		return CostTotals{}, nil
+	}
+	if err != nil {
+		return CostTotals{}, fmt.Errorf("failed to get project cost: %w", err)
+	}
+	return totals, nil
+}
+
+// ListProjectCosts returns the cost totals of all projects with recorded usage, most expensive first.
+func (d *DB) ListProjectCosts(ctx context.Context) ([]ProjectCost, error) {
+	query := `SELECT project_id, ` + costColumns + ` FROM project_costs ORDER BY cost_usd DESC, project_id`
+	rows, err := d.QueryContextRebound(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project costs: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var costs []ProjectCost
+	for rows.Next() {
+		var c ProjectCost
+		totals, err := scanCostTotals(rows, &c.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project cost: %w", err)
+		}
+		c.CostTotals = totals
+		costs = append(costs, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project costs: %w", err)
+	}
+	return costs, nil
+}
+
+// ListTokenCosts returns the cost totals of a project's tokens with recorded usage, most expensive first.
+func (d *DB) ListTokenCosts(ctx context.Context, projectID string) ([]TokenCost, error) {
+	query := `SELECT token_id, project_id, ` + costColumns + ` FROM token_costs WHERE project_id = ? ORDER BY cost_usd DESC, token_id`
+	rows, err := d.QueryContextRebound(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list token costs: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var costs []TokenCost
+	for rows.Next() {
+		var c TokenCost
+		totals, err := scanCostTotals(rows, &c.TokenID, &c.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan token cost: %w", err)
+		}
+		c.CostTotals = totals
+		costs = append(costs, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating token costs: %w", err)
+	}
+	return costs, nil
+}
+
+// scanCostTotals scans the costColumns after the given key columns.
+func scanCostTotals(row interface{ Scan(...any) error }, keys ...any) (CostTotals, error) {
+	var (
+		totals    CostTotals
+		updatedAt time.Time
+	)
+	dest := append(keys, &totals.RequestCount, &totals.InputTokens, &totals.OutputTokens,
+		&totals.CachedInputTokens, &totals.CostUSD, &updatedAt)
+	if err := row.Scan(dest...); err != nil {
+		return CostTotals{}, err
+	}
+	totals.UpdatedAt = &updatedAt
+	return totals, nil
+}
+
+// ListModelPrices returns the model price catalog sorted by model.
+func (d *DB) ListModelPrices(ctx context.Context) ([]pricing.Price, error) {
+	query := `SELECT model, input_per_1k, output_per_1k, cached_input_per_1k FROM model_prices ORDER BY model`
+	rows, err := d.QueryContextRebound(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model prices: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var prices []pricing.Price
+	for rows.Next() {
+		var (
+			p      pricing.Price
+			cached sql.NullFloat64
+		)
+		if err := rows.Scan(&p.Model, &p.InputPer1K, &p.OutputPer1K, &cached); err != nil {
+			return nil, fmt.Errorf("failed to scan model price: %w", err)
+		}
+		if cached.Valid {
+			p.CachedInputPer1K = &cached.Float64
+		}
+		prices = append(prices, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating model prices: %w", err)
+	}
+	return prices, nil
+}
+
+// SetModelPrices replaces the model price catalog with prices.
+func (d *DB) SetModelPrices(ctx context.Context, prices []pricing.Price) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback() // No-op if already committed
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM model_prices`); err != nil {
+		return fmt.Errorf("failed to remove model prices: %w", err)
+	}
+	now := time.Now().UTC()
+	for _, p := range prices {
+		var cached sql.NullFloat64
+		if p.CachedInputPer1K != nil {
+			cached = sql.NullFloat64{Float64: *p.CachedInputPer1K, Valid: true}
+		}
+		if _, err := tx.ExecContext(ctx,
+			d.RebindQuery(`INSERT INTO model_prices (model, input_per_1k, output_per_1k, cached_input_per_1k, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`),
+			p.Model, p.InputPer1K, p.OutputPer1K, cached, now, now,
+		); err != nil {
+			return fmt.Errorf("failed to add price for model %s: %w", p.Model, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
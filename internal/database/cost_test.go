@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/pricing"
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddCosts(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, id := range []string{"proj-a", "proj-b"} {
+		require.NoError(t, db.CreateProject(ctx, proxy.Project{ID: id, Name: id, APIKey: "key", CreatedAt: now, UpdatedAt: now}))
+	}
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "11111111-1111-1111-1111-111111111111", Token: "cost-token", ProjectID: "proj-a", IsActive: true, CreatedAt: now}))
+
+	// Empty deltas are a no-op
+	require.NoError(t, db.AddCosts(ctx, nil, nil))
+
+	delta := proxy.CostDelta{Requests: 1, InputTokens: 100, OutputTokens: 50, CachedInputTokens: 10, CostUSD: 0.25}
+	require.NoError(t, db.AddCosts(ctx,
+		map[string]proxy.CostDelta{"11111111-1111-1111-1111-111111111111": delta, "unknown-token": delta},
+		map[string]proxy.CostDelta{"proj-a": delta, "proj-b": {Requests: 1, CostUSD: 1}},
+	))
+	require.NoError(t, db.AddCosts(ctx,
+		map[string]proxy.CostDelta{"11111111-1111-1111-1111-111111111111": delta},
+		map[string]proxy.CostDelta{"proj-a": delta},
+	))
+
+	projA, err := db.GetProjectCost(ctx, "proj-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), projA.RequestCount)
+	assert.Equal(t, int64(200), projA.InputTokens)
+	assert.Equal(t, int64(100), projA.OutputTokens)
+	assert.Equal(t, int64(20), projA.CachedInputTokens)
+	assert.InDelta(t, 0.5, projA.CostUSD, 1e-9)
+	require.NotNil(t, projA.UpdatedAt)
+
+	empty, err := db.GetProjectCost(ctx, "proj-none")
+	require.NoError(t, err)
+	assert.Zero(t, empty.RequestCount)
+	assert.Nil(t, empty.UpdatedAt)
+
+	total, err := db.GetTotalCost(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total.RequestCount)
+	assert.InDelta(t, 1.5, total.CostUSD, 1e-9)
+
+	projects, err := db.ListProjectCosts(ctx)
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+	assert.Equal(t, "proj-b", projects[0].ProjectID, "most expensive project first")
+	assert.Equal(t, "proj-a", projects[1].ProjectID)
+
+	tokens, err := db.ListTokenCosts(ctx, "proj-a")
+	require.NoError(t, err)
+	require.Len(t, tokens, 1, "unknown tokens are ignored")
+	assert.Equal(t, "11111111-1111-1111-1111-111111111111", tokens[0].TokenID)
+	assert.Equal(t, "proj-a", tokens[0].ProjectID)
+	assert.Equal(t, int64(2), tokens[0].RequestCount)
+	assert.InDelta(t, 0.5, tokens[0].CostUSD, 1e-9)
+
+	tokens, err = db.ListTokenCosts(ctx, "proj-b")
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
+
+func TestModelPrices(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	prices, err := db.ListModelPrices(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, prices)
+
+	cached := 0.5
+	require.NoError(t, db.SetModelPrices(ctx, []pricing.Price{
+		{Model: "gpt-4o*", InputPer1K: 2.5, OutputPer1K: 10, CachedInputPer1K: &cached},
+		{Model: "claude-*", InputPer1K: 3, OutputPer1K: 15},
+	}))
+	prices, err = db.ListModelPrices(ctx)
+	require.NoError(t, err)
+	require.Len(t, prices, 2)
+	assert.Equal(t, "claude-*", prices[0].Model)
+	assert.Nil(t, prices[0].CachedInputPer1K)
+	assert.Equal(t, "gpt-4o*", prices[1].Model)
+	require.NotNil(t, prices[1].CachedInputPer1K)
+	assert.Equal(t, 0.5, *prices[1].CachedInputPer1K)
+
+	// Setting prices replaces the catalog
+	require.NoError(t, db.SetModelPrices(ctx, []pricing.Price{{Model: "gemini-*", InputPer1K: 1}}))
+	prices, err = db.ListModelPrices(ctx)
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, "gemini-*", prices[0].Model)
+}
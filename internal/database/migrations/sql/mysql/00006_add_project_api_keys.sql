@@ -0,0 +1,17 @@
+-- +goose Up
+-- Per-provider upstream API keys for projects (MySQL)
+-- A project may hold one key per provider configured in APIConfig. The legacy
+-- projects.api_key column remains the default-provider fallback.
+
+CREATE TABLE IF NOT EXISTS project_api_keys (
+	project_id VARCHAR(191) NOT NULL,
+	provider VARCHAR(191) NOT NULL,
+	api_key TEXT NOT NULL, -- NOTE: Encrypted when ENCRYPTION_KEY is set (AES-256-GCM).
+	created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (project_id, provider),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +goose Down
+DROP TABLE IF EXISTS project_api_keys;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Per-project request policy rules (MySQL)
+-- Rules are stored as a JSON array and enforced by the proxy in addition to
+-- the provider's request_policy from APIConfig.
+
+CREATE TABLE IF NOT EXISTS project_request_policies (
+	project_id VARCHAR(191) NOT NULL,
+	rules TEXT NOT NULL,
+	created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (project_id),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +goose Down
+DROP TABLE IF EXISTS project_request_policies;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Per-project model allowlists (MySQL)
+-- Each row is a glob pattern; a project without rows may use every model the
+-- provider's param_whitelist allows.
+
+CREATE TABLE IF NOT EXISTS project_allowed_models (
+	project_id VARCHAR(191) NOT NULL,
+	model VARCHAR(191) NOT NULL,
+	created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (project_id, model),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +goose Down
+DROP TABLE IF EXISTS project_allowed_models;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Add scopes column to tokens table (MySQL)
+-- JSON object with endpoint prefixes, methods and model globs; NULL means unrestricted.
+
+ALTER TABLE tokens ADD COLUMN scopes TEXT NULL;
+
+-- +goose Down
+-- Rollback: Remove scopes column
+ALTER TABLE tokens DROP COLUMN scopes;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Add metadata column to tokens table (MySQL)
+-- JSON object of string key/value pairs, e.g. the end user a token was issued for.
+
+ALTER TABLE tokens ADD COLUMN metadata TEXT NULL;
+
+-- +goose Down
+-- Rollback: Remove metadata column
+ALTER TABLE tokens DROP COLUMN metadata;
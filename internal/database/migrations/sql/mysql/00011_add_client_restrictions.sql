@@ -0,0 +1,19 @@
+-- +goose Up
+-- Client network and origin allowlists for tokens and projects (MySQL)
+-- Tokens keep theirs as JSON in client_restrictions; projects get one row per
+-- entry, where kind is 'cidr' or 'origin'.
+
+ALTER TABLE tokens ADD COLUMN client_restrictions TEXT NULL;
+
+CREATE TABLE IF NOT EXISTS project_client_restrictions (
+	project_id VARCHAR(191) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	value VARCHAR(191) NOT NULL,
+	created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (project_id, kind, value),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +goose Down
+DROP TABLE IF EXISTS project_client_restrictions;
+ALTER TABLE tokens DROP COLUMN client_restrictions;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Link rotated tokens to their successors (MySQL)
+-- rotated_from_id is set on a successor, rotated_to_id on the token it replaced.
+
+ALTER TABLE tokens ADD COLUMN rotated_from_id VARCHAR(36) NULL;
+ALTER TABLE tokens ADD COLUMN rotated_to_id VARCHAR(36) NULL;
+
+-- +goose Down
+-- Rollback: Remove rotation columns
+ALTER TABLE tokens DROP COLUMN rotated_to_id;
+ALTER TABLE tokens DROP COLUMN rotated_from_id;
@@ -0,0 +1,45 @@
+-- +goose Up
+-- Model price catalog and cost totals (MySQL)
+-- Prices are USD per 1,000 tokens; model is an exact name or a glob pattern.
+-- A NULL cached_input_per_1k bills cached input tokens at input_per_1k.
+
+CREATE TABLE IF NOT EXISTS model_prices (
+	model VARCHAR(191) NOT NULL,
+	input_per_1k DOUBLE NOT NULL DEFAULT 0,
+	output_per_1k DOUBLE NOT NULL DEFAULT 0,
+	cached_input_per_1k DOUBLE NULL,
+	created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (model)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS token_costs (
+	token_id CHAR(36) NOT NULL,
+	project_id VARCHAR(191) NOT NULL,
+	request_count BIGINT NOT NULL DEFAULT 0,
+	input_tokens BIGINT NOT NULL DEFAULT 0,
+	output_tokens BIGINT NOT NULL DEFAULT 0,
+	cached_input_tokens BIGINT NOT NULL DEFAULT 0,
+	cost_usd DOUBLE NOT NULL DEFAULT 0,
+	updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (token_id),
+	INDEX idx_token_costs_project_id (project_id),
+	FOREIGN KEY (token_id) REFERENCES tokens(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS project_costs (
+	project_id VARCHAR(191) NOT NULL,
+	request_count BIGINT NOT NULL DEFAULT 0,
+	input_tokens BIGINT NOT NULL DEFAULT 0,
+	output_tokens BIGINT NOT NULL DEFAULT 0,
+	cached_input_tokens BIGINT NOT NULL DEFAULT 0,
+	cost_usd DOUBLE NOT NULL DEFAULT 0,
+	updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (project_id),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +goose Down
+DROP TABLE IF EXISTS project_costs;
+DROP TABLE IF EXISTS token_costs;
+DROP TABLE IF EXISTS model_prices;
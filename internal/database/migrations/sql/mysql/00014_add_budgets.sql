@@ -0,0 +1,29 @@
+-- +goose Up
+-- Spend budgets of projects and tokens (MySQL)
+-- subject_type is 'project' or 'token'; token budgets are keyed by token ID.
+-- A budget with both limits is exhausted when either is reached.
+
+CREATE TABLE IF NOT EXISTS budgets (
+	subject_type VARCHAR(16) NOT NULL,
+	subject_id VARCHAR(191) NOT NULL,
+	period VARCHAR(16) NOT NULL,
+	limit_usd DOUBLE NULL,
+	limit_tokens BIGINT NULL,
+	created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (subject_type, subject_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Daily spend per project and token (day is YYYY-MM-DD in UTC)
+CREATE TABLE IF NOT EXISTS budget_spend (
+	subject_type VARCHAR(16) NOT NULL,
+	subject_id VARCHAR(191) NOT NULL,
+	day CHAR(10) NOT NULL,
+	cost_usd DOUBLE NOT NULL DEFAULT 0,
+	tokens BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (subject_type, subject_id, day)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +goose Down
+DROP TABLE IF EXISTS budget_spend;
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Request rate limits of tokens and projects (MySQL)
+-- Tokens keep theirs as JSON in rate_limit; projects get at most one row.
+
+ALTER TABLE tokens ADD COLUMN rate_limit TEXT NULL;
+
+CREATE TABLE IF NOT EXISTS project_rate_limits (
+	project_id VARCHAR(191) NOT NULL PRIMARY KEY,
+	requests_per_window INT NOT NULL,
+	window_seconds INT NOT NULL,
+	burst INT NOT NULL DEFAULT 0,
+	created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +goose Down
+DROP TABLE IF EXISTS project_rate_limits;
+ALTER TABLE tokens DROP COLUMN rate_limit;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- Delete budgets and budget spend with their project or token (MySQL)
+-- project_id or token_id repeats subject_id, depending on subject_type,
+-- so that foreign keys can cascade deletes to the polymorphic subject.
+
+ALTER TABLE budgets
+	ADD COLUMN project_id VARCHAR(191) NULL,
+	ADD COLUMN token_id CHAR(36) NULL;
+ALTER TABLE budget_spend
+	ADD COLUMN project_id VARCHAR(191) NULL,
+	ADD COLUMN token_id CHAR(36) NULL;
+
+UPDATE budgets SET project_id = subject_id WHERE subject_type = 'project' AND subject_id IN (SELECT id FROM projects);
+UPDATE budgets SET token_id = subject_id WHERE subject_type = 'token' AND subject_id IN (SELECT id FROM tokens);
+UPDATE budget_spend SET project_id = subject_id WHERE subject_type = 'project' AND subject_id IN (SELECT id FROM projects);
+UPDATE budget_spend SET token_id = subject_id WHERE subject_type = 'token' AND subject_id IN (SELECT id FROM tokens);
+
+-- Budgets and spend of subjects deleted before
+DELETE FROM budgets WHERE project_id IS NULL AND token_id IS NULL;
+DELETE FROM budget_spend WHERE project_id IS NULL AND token_id IS NULL;
+
+ALTER TABLE budgets
+	ADD CONSTRAINT fk_budgets_project FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	ADD CONSTRAINT fk_budgets_token FOREIGN KEY (token_id) REFERENCES tokens(id) ON DELETE CASCADE;
+ALTER TABLE budget_spend
+	ADD CONSTRAINT fk_budget_spend_project FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	ADD CONSTRAINT fk_budget_spend_token FOREIGN KEY (token_id) REFERENCES tokens(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE budget_spend
+	DROP FOREIGN KEY fk_budget_spend_token,
+	DROP FOREIGN KEY fk_budget_spend_project;
+ALTER TABLE budgets
+	DROP FOREIGN KEY fk_budgets_token,
+	DROP FOREIGN KEY fk_budgets_project;
+ALTER TABLE budget_spend
+	DROP COLUMN token_id,
+	DROP COLUMN project_id;
+ALTER TABLE budgets
+	DROP COLUMN token_id,
+	DROP COLUMN project_id;
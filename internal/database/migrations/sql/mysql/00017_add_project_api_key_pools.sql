@@ -0,0 +1,19 @@
+-- +goose Up
+-- Upstream key pools for projects (MySQL)
+-- Each row is one key of a provider's pool, in selection order. A provider with
+-- pool rows uses them instead of its single key; the empty provider pools the
+-- legacy projects.api_key.
+
+CREATE TABLE IF NOT EXISTS project_api_key_pools (
+	project_id VARCHAR(191) NOT NULL,
+	provider VARCHAR(191) NOT NULL DEFAULT '',
+	position INT NOT NULL,
+	api_key TEXT NOT NULL, -- NOTE: Encrypted when ENCRYPTION_KEY is set (AES-256-GCM).
+	weight INT NOT NULL DEFAULT 1,
+	created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+	PRIMARY KEY (project_id, provider, position),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- +goose Down
+DROP TABLE IF EXISTS project_api_key_pools;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Per-provider upstream API keys for projects (PostgreSQL)
+-- A project may hold one key per provider configured in APIConfig. The legacy
+-- projects.api_key column remains the default-provider fallback.
+
+CREATE TABLE IF NOT EXISTS project_api_keys (
+	project_id TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	api_key TEXT NOT NULL, -- NOTE: Encrypted when ENCRYPTION_KEY is set (AES-256-GCM).
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (project_id, provider),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_api_keys;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Per-project request policy rules (PostgreSQL)
+-- Rules are stored as a JSON array and enforced by the proxy in addition to
+-- the provider's request_policy from APIConfig.
+
+CREATE TABLE IF NOT EXISTS project_request_policies (
+	project_id TEXT NOT NULL,
+	rules TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (project_id),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_request_policies;
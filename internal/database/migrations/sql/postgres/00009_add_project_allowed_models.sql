@@ -0,0 +1,15 @@
+-- +goose Up
+-- Per-project model allowlists (PostgreSQL)
+-- Each row is a glob pattern; a project without rows may use every model the
+-- provider's param_whitelist allows.
+
+CREATE TABLE IF NOT EXISTS project_allowed_models (
+	project_id TEXT NOT NULL,
+	model TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (project_id, model),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_allowed_models;
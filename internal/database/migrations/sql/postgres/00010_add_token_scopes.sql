@@ -0,0 +1,9 @@
+-- +goose Up
+-- Add scopes column to tokens table (PostgreSQL)
+-- JSON object with endpoint prefixes, methods and model globs; NULL means unrestricted.
+
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS scopes TEXT;
+
+-- +goose Down
+-- Rollback: Remove scopes column
+ALTER TABLE tokens DROP COLUMN IF EXISTS scopes;
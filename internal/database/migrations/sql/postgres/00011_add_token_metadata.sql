@@ -0,0 +1,9 @@
+-- +goose Up
+-- Add metadata column to tokens table (PostgreSQL)
+-- JSON object of string key/value pairs, e.g. the end user a token was issued for.
+
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS metadata TEXT;
+
+-- +goose Down
+-- Rollback: Remove metadata column
+ALTER TABLE tokens DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Client network and origin allowlists for tokens and projects (PostgreSQL)
+-- Tokens keep theirs as JSON in client_restrictions; projects get one row per
+-- entry, where kind is 'cidr' or 'origin'.
+
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS client_restrictions TEXT;
+
+CREATE TABLE IF NOT EXISTS project_client_restrictions (
+	project_id TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	value TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (project_id, kind, value),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_client_restrictions;
+ALTER TABLE tokens DROP COLUMN IF EXISTS client_restrictions;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Link rotated tokens to their successors (PostgreSQL)
+-- rotated_from_id is set on a successor, rotated_to_id on the token it replaced.
+
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS rotated_from_id TEXT;
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS rotated_to_id TEXT;
+
+-- +goose Down
+-- Rollback: Remove rotation columns
+ALTER TABLE tokens DROP COLUMN IF EXISTS rotated_to_id;
+ALTER TABLE tokens DROP COLUMN IF EXISTS rotated_from_id;
@@ -0,0 +1,43 @@
+-- +goose Up
+-- Model price catalog and cost totals (PostgreSQL)
+-- Prices are USD per 1,000 tokens; model is an exact name or a glob pattern.
+-- A NULL cached_input_per_1k bills cached input tokens at input_per_1k.
+
+CREATE TABLE IF NOT EXISTS model_prices (
+	model TEXT PRIMARY KEY,
+	input_per_1k DOUBLE PRECISION NOT NULL DEFAULT 0,
+	output_per_1k DOUBLE PRECISION NOT NULL DEFAULT 0,
+	cached_input_per_1k DOUBLE PRECISION,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS token_costs (
+	token_id UUID PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	request_count BIGINT NOT NULL DEFAULT 0,
+	input_tokens BIGINT NOT NULL DEFAULT 0,
+	output_tokens BIGINT NOT NULL DEFAULT 0,
+	cached_input_tokens BIGINT NOT NULL DEFAULT 0,
+	cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (token_id) REFERENCES tokens(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_costs_project_id ON token_costs(project_id);
+
+CREATE TABLE IF NOT EXISTS project_costs (
+	project_id TEXT PRIMARY KEY,
+	request_count BIGINT NOT NULL DEFAULT 0,
+	input_tokens BIGINT NOT NULL DEFAULT 0,
+	output_tokens BIGINT NOT NULL DEFAULT 0,
+	cached_input_tokens BIGINT NOT NULL DEFAULT 0,
+	cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_costs;
+DROP TABLE IF EXISTS token_costs;
+DROP TABLE IF EXISTS model_prices;
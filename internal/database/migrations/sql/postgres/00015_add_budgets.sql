@@ -0,0 +1,29 @@
+-- +goose Up
+-- Spend budgets of projects and tokens (PostgreSQL)
+-- subject_type is 'project' or 'token'; token budgets are keyed by token ID.
+-- A budget with both limits is exhausted when either is reached.
+
+CREATE TABLE IF NOT EXISTS budgets (
+	subject_type TEXT NOT NULL,
+	subject_id TEXT NOT NULL,
+	period TEXT NOT NULL,
+	limit_usd DOUBLE PRECISION,
+	limit_tokens BIGINT,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (subject_type, subject_id)
+);
+
+-- Daily spend per project and token (day is YYYY-MM-DD in UTC)
+CREATE TABLE IF NOT EXISTS budget_spend (
+	subject_type TEXT NOT NULL,
+	subject_id TEXT NOT NULL,
+	day CHAR(10) NOT NULL,
+	cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+	tokens BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (subject_type, subject_id, day)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS budget_spend;
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Request rate limits of tokens and projects (PostgreSQL)
+-- Tokens keep theirs as JSON in rate_limit; projects get at most one row.
+
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS rate_limit TEXT;
+
+CREATE TABLE IF NOT EXISTS project_rate_limits (
+	project_id TEXT NOT NULL PRIMARY KEY,
+	requests_per_window INTEGER NOT NULL,
+	window_seconds INTEGER NOT NULL,
+	burst INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_rate_limits;
+ALTER TABLE tokens DROP COLUMN IF EXISTS rate_limit;
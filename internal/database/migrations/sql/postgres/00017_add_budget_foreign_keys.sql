@@ -0,0 +1,33 @@
+-- +goose Up
+-- Delete budgets and budget spend with their project or token (PostgreSQL)
+-- project_id or token_id repeats subject_id, depending on subject_type,
+-- so that foreign keys can cascade deletes to the polymorphic subject.
+
+ALTER TABLE budgets
+	ADD COLUMN project_id TEXT REFERENCES projects(id) ON DELETE CASCADE,
+	ADD COLUMN token_id UUID REFERENCES tokens(id) ON DELETE CASCADE;
+ALTER TABLE budget_spend
+	ADD COLUMN project_id TEXT REFERENCES projects(id) ON DELETE CASCADE,
+	ADD COLUMN token_id UUID REFERENCES tokens(id) ON DELETE CASCADE;
+
+UPDATE budgets SET project_id = p.id FROM projects p WHERE budgets.subject_type = 'project' AND p.id = budgets.subject_id;
+UPDATE budgets SET token_id = t.id FROM tokens t WHERE budgets.subject_type = 'token' AND t.id::text = budgets.subject_id;
+UPDATE budget_spend SET project_id = p.id FROM projects p WHERE budget_spend.subject_type = 'project' AND p.id = budget_spend.subject_id;
+UPDATE budget_spend SET token_id = t.id FROM tokens t WHERE budget_spend.subject_type = 'token' AND t.id::text = budget_spend.subject_id;
+
+-- Budgets and spend of subjects deleted before
+DELETE FROM budgets WHERE project_id IS NULL AND token_id IS NULL;
+DELETE FROM budget_spend WHERE project_id IS NULL AND token_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_budgets_project_id ON budgets(project_id);
+CREATE INDEX IF NOT EXISTS idx_budgets_token_id ON budgets(token_id);
+CREATE INDEX IF NOT EXISTS idx_budget_spend_project_id ON budget_spend(project_id);
+CREATE INDEX IF NOT EXISTS idx_budget_spend_token_id ON budget_spend(token_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_budget_spend_token_id;
+DROP INDEX IF EXISTS idx_budget_spend_project_id;
+DROP INDEX IF EXISTS idx_budgets_token_id;
+DROP INDEX IF EXISTS idx_budgets_project_id;
+ALTER TABLE budget_spend DROP COLUMN IF EXISTS token_id, DROP COLUMN IF EXISTS project_id;
+ALTER TABLE budgets DROP COLUMN IF EXISTS token_id, DROP COLUMN IF EXISTS project_id;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Upstream key pools for projects (PostgreSQL)
+-- Each row is one key of a provider's pool, in selection order. A provider with
+-- pool rows uses them instead of its single key; the empty provider pools the
+-- legacy projects.api_key.
+
+CREATE TABLE IF NOT EXISTS project_api_key_pools (
+	project_id TEXT NOT NULL,
+	provider TEXT NOT NULL DEFAULT '',
+	position INTEGER NOT NULL,
+	api_key TEXT NOT NULL, -- NOTE: Encrypted when ENCRYPTION_KEY is set (AES-256-GCM).
+	weight INTEGER NOT NULL DEFAULT 1,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (project_id, provider, position),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS project_api_key_pools;
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/sofatutor/llm-proxy/internal/token"
 )
 
 // MockProjectStore is an in-memory implementation of ProjectStore for testing and development
@@ -112,8 +113,8 @@ func (m *MockProjectStore) CreateMockProject(projectID, name, apiKey string) (Pr
 	return project, err
 }
 
-// GetAPIKeyForProject retrieves the API key for a project
-func (m *MockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+// GetAPIKeyForProject retrieves the API key for a project and provider
+func (m *MockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
@@ -121,8 +122,81 @@ func (m *MockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID st
 	if !exists {
 		return "", errors.New("project not found")
 	}
+	if provider == "" {
+		return apiKey, nil
+	}
+
+	providerKey, ok := m.projects[projectID].APIKeys[provider]
+	if !ok {
+		return "", proxy.ErrProviderAPIKeyNotFound
+	}
+	return providerKey, nil
+}
+
+// GetAPIKeyPoolForProject returns the key pool of a project for provider
+func (m *MockProjectStore) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]proxy.APIKeyPoolEntry, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	project, exists := m.projects[projectID]
+	if !exists {
+		return nil, errors.New("project not found")
+	}
+	var pool []proxy.APIKeyPoolEntry
+	for _, entry := range project.APIKeyPool {
+		if entry.Provider == provider {
+			pool = append(pool, entry)
+		}
+	}
+	return pool, nil
+}
+
+// GetRequestPolicyForProject returns the request policy rules of a project
+func (m *MockProjectStore) GetRequestPolicyForProject(ctx context.Context, projectID string) ([]proxy.RequestPolicyRule, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 
-	return apiKey, nil
+	project, exists := m.projects[projectID]
+	if !exists {
+		return nil, errors.New("project not found")
+	}
+	return project.RequestPolicy, nil
+}
+
+// GetAllowedModelsForProject returns the model allowlist of a project
+func (m *MockProjectStore) GetAllowedModelsForProject(ctx context.Context, projectID string) ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	project, exists := m.projects[projectID]
+	if !exists {
+		return nil, errors.New("project not found")
+	}
+	return project.AllowedModels, nil
+}
+
+// GetClientRestrictionsForProject returns the client restrictions of a project
+func (m *MockProjectStore) GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	project, exists := m.projects[projectID]
+	if !exists {
+		return token.ClientRestrictions{}, errors.New("project not found")
+	}
+	return project.ClientRestrictions, nil
+}
+
+// GetRateLimitForProject returns the rate limit of a project
+func (m *MockProjectStore) GetRateLimitForProject(ctx context.Context, projectID string) (token.RateLimit, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	project, exists := m.projects[projectID]
+	if !exists {
+		return token.RateLimit{}, errors.New("project not found")
+	}
+	return project.RateLimit, nil
 }
 
 // --- proxy.ProjectStore interface adapters ---
@@ -89,10 +89,10 @@ func TestMockProjectStore_GetAPIKeyForProject(t *testing.T) {
 	ctx := context.Background()
 	err := store.CreateProject(ctx, proxy.Project{ID: "p1", Name: "N", APIKey: "k1"})
 	assert.NoError(t, err)
-	key, err := store.GetAPIKeyForProject(ctx, "p1")
+	key, err := store.GetAPIKeyForProject(ctx, "p1", "")
 	assert.NoError(t, err)
 	assert.Equal(t, "k1", key)
-	_, err = store.GetAPIKeyForProject(ctx, "notfound")
+	_, err = store.GetAPIKeyForProject(ctx, "notfound", "")
 	assert.Error(t, err)
 }
 
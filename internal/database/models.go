@@ -2,17 +2,32 @@ package database
 
 import (
 	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/sofatutor/llm-proxy/internal/token"
 )
 
 // Project represents a project in the database.
 type Project struct {
-	ID            string     `json:"id"`
-	Name          string     `json:"name"`
-	APIKey        string     `json:"-"` // Sensitive data, not included in JSON. Encrypted when ENCRYPTION_KEY is set.
-	IsActive      bool       `json:"is_active"`
-	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	APIKey string `json:"-"` // Sensitive data, not included in JSON. Encrypted when ENCRYPTION_KEY is set.
+	// APIKeys holds per-provider upstream keys (project_api_keys table), keyed by provider name.
+	APIKeys map[string]string `json:"-"`
+	// APIKeyPool holds pooled upstream keys (project_api_key_pools table), ordered per provider.
+	APIKeyPool []proxy.APIKeyPoolEntry `json:"-"`
+	// RequestPolicy holds project request policy rules (project_request_policies table).
+	RequestPolicy []proxy.RequestPolicyRule `json:"request_policy,omitempty"`
+	// AllowedModels holds the project's model patterns (project_allowed_models table).
+	AllowedModels []string `json:"allowed_models,omitempty"`
+	// ClientRestrictions holds the project's client allowlists (project_client_restrictions table).
+	ClientRestrictions token.ClientRestrictions `json:"client_restrictions"`
+	// RateLimit is the project's request rate limit (project_rate_limits table; zero is unlimited).
+	RateLimit     token.RateLimit `json:"rate_limit"`
+	IsActive      bool            `json:"is_active"`
+	DeactivatedAt *time.Time      `json:"deactivated_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
 }
 
 // Token represents a token in the database.
@@ -28,6 +43,18 @@ type Token struct {
 	CreatedAt     time.Time  `json:"created_at"`
 	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
 	CacheHitCount int        `json:"cache_hit_count"`
+	// Scopes restricts endpoints, methods and models (JSON in the scopes column; NULL is unrestricted).
+	Scopes token.Scopes `json:"scopes"`
+	// Metadata holds free-form key/value pairs (JSON in the metadata column).
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// ClientRestrictions limit client networks and origins (JSON in the client_restrictions column; NULL is unrestricted).
+	ClientRestrictions token.ClientRestrictions `json:"client_restrictions"`
+	// RateLimit is the token's request rate limit (JSON in the rate_limit column; NULL uses the default).
+	RateLimit token.RateLimit `json:"rate_limit"`
+	// RotatedFromID is the ID of the token this token replaced (NULL if not a rotation successor).
+	RotatedFromID string `json:"rotated_from_id,omitempty"`
+	// RotatedToID is the ID of the token that replaced this token (NULL if not rotated).
+	RotatedToID string `json:"rotated_to_id,omitempty"`
 }
 
 // AuditEvent represents an audit log entry in the database.
@@ -79,3 +106,26 @@ func (t *Token) IsDeactivated() bool {
 func (p *Project) IsDeactivated() bool {
 	return p.DeactivatedAt != nil
 }
+
+// CostTotals are the accumulated usage and cost of a token or project (token_costs and project_costs tables).
+type CostTotals struct {
+	RequestCount      int64      `json:"request_count"`
+	InputTokens       int64      `json:"input_tokens"`
+	OutputTokens      int64      `json:"output_tokens"`
+	CachedInputTokens int64      `json:"cached_input_tokens"`
+	CostUSD           float64    `json:"cost_usd"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty"`
+}
+
+// TokenCost is the cost totals of a token.
+type TokenCost struct {
+	TokenID   string `json:"token_id"`
+	ProjectID string `json:"project_id"`
+	CostTotals
+}
+
+// ProjectCost is the cost totals of a project.
+type ProjectCost struct {
+	ProjectID string `json:"project_id"`
+	CostTotals
+}
@@ -3,11 +3,13 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/sofatutor/llm-proxy/internal/token"
 )
 
 var (
@@ -47,32 +49,63 @@ func (d *DB) GetProjectByName(ctx context.Context, name string) (Project, error)
 		project.DeactivatedAt = &deactivatedAt.Time
 	}
 
+	if project.APIKeys, err = d.getProjectAPIKeys(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.APIKeyPool, err = d.getProjectAPIKeyPool(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.RequestPolicy, err = d.getProjectRequestPolicy(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.AllowedModels, err = d.getProjectAllowedModels(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.ClientRestrictions, err = d.getProjectClientRestrictions(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.RateLimit, err = d.getProjectRateLimit(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+
 	return project, nil
 }
 
 // ToProxyProject converts a database.Project to a proxy.Project
 func ToProxyProject(dbProject Project) proxy.Project {
 	return proxy.Project{
-		ID:            dbProject.ID,
-		Name:          dbProject.Name,
-		APIKey:        dbProject.APIKey,
-		IsActive:      dbProject.IsActive,
-		DeactivatedAt: dbProject.DeactivatedAt,
-		CreatedAt:     dbProject.CreatedAt,
-		UpdatedAt:     dbProject.UpdatedAt,
+		ID:                 dbProject.ID,
+		Name:               dbProject.Name,
+		APIKey:             dbProject.APIKey,
+		APIKeys:            dbProject.APIKeys,
+		APIKeyPool:         dbProject.APIKeyPool,
+		RequestPolicy:      dbProject.RequestPolicy,
+		AllowedModels:      dbProject.AllowedModels,
+		ClientRestrictions: dbProject.ClientRestrictions,
+		RateLimit:          dbProject.RateLimit,
+		IsActive:           dbProject.IsActive,
+		DeactivatedAt:      dbProject.DeactivatedAt,
+		CreatedAt:          dbProject.CreatedAt,
+		UpdatedAt:          dbProject.UpdatedAt,
 	}
 }
 
 // ToDBProject converts a proxy.Project to a database.Project
 func ToDBProject(proxyProject proxy.Project) Project {
 	return Project{
-		ID:            proxyProject.ID,
-		Name:          proxyProject.Name,
-		APIKey:        proxyProject.APIKey,
-		IsActive:      proxyProject.IsActive,
-		DeactivatedAt: proxyProject.DeactivatedAt,
-		CreatedAt:     proxyProject.CreatedAt,
-		UpdatedAt:     proxyProject.UpdatedAt,
+		ID:                 proxyProject.ID,
+		Name:               proxyProject.Name,
+		APIKey:             proxyProject.APIKey,
+		APIKeys:            proxyProject.APIKeys,
+		APIKeyPool:         proxyProject.APIKeyPool,
+		RequestPolicy:      proxyProject.RequestPolicy,
+		AllowedModels:      proxyProject.AllowedModels,
+		ClientRestrictions: proxyProject.ClientRestrictions,
+		RateLimit:          proxyProject.RateLimit,
+		IsActive:           proxyProject.IsActive,
+		DeactivatedAt:      proxyProject.DeactivatedAt,
+		CreatedAt:          proxyProject.CreatedAt,
+		UpdatedAt:          proxyProject.UpdatedAt,
 	}
 }
 
@@ -117,6 +150,39 @@ func (d *DB) DBListProjects(ctx context.Context) ([]Project, error) {
 		return nil, fmt.Errorf("error iterating projects: %w", err)
 	}
 
+	keysByProject, err := d.listProjectAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	poolsByProject, err := d.listProjectAPIKeyPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policiesByProject, err := d.listProjectRequestPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	modelsByProject, err := d.listProjectAllowedModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	restrictionsByProject, err := d.listProjectClientRestrictions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rateLimitsByProject, err := d.listProjectRateLimits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range projects {
+		projects[i].APIKeys = keysByProject[projects[i].ID]
+		projects[i].APIKeyPool = poolsByProject[projects[i].ID]
+		projects[i].RequestPolicy = policiesByProject[projects[i].ID]
+		projects[i].AllowedModels = modelsByProject[projects[i].ID]
+		projects[i].ClientRestrictions = restrictionsByProject[projects[i].ID]
+		projects[i].RateLimit = rateLimitsByProject[projects[i].ID]
+	}
+
 	return projects, nil
 }
 
@@ -126,17 +192,37 @@ func (d *DB) DBCreateProject(ctx context.Context, project Project) error {
 	VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := d.ExecContextRebound(
-		ctx,
-		query,
-		project.ID,
-		project.Name,
-		project.APIKey,
-		project.IsActive,
-		project.DeactivatedAt,
-		project.CreatedAt,
-		project.UpdatedAt,
-	)
+	err := d.Transaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(
+			ctx,
+			d.RebindQuery(query),
+			project.ID,
+			project.Name,
+			project.APIKey,
+			project.IsActive,
+			project.DeactivatedAt,
+			project.CreatedAt,
+			project.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		if err := d.syncProjectAPIKeysTx(ctx, tx, project.ID, project.APIKeys); err != nil {
+			return err
+		}
+		if err := d.syncProjectAPIKeyPoolTx(ctx, tx, project.ID, project.APIKeyPool); err != nil {
+			return err
+		}
+		if err := d.syncProjectRequestPolicyTx(ctx, tx, project.ID, project.RequestPolicy); err != nil {
+			return err
+		}
+		if err := d.syncProjectAllowedModelsTx(ctx, tx, project.ID, project.AllowedModels); err != nil {
+			return err
+		}
+		if err := d.syncProjectClientRestrictionsTx(ctx, tx, project.ID, project.ClientRestrictions); err != nil {
+			return err
+		}
+		return d.syncProjectRateLimitTx(ctx, tx, project.ID, project.RateLimit)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create project: %w", err)
 	}
@@ -173,6 +259,25 @@ func (d *DB) DBGetProjectByID(ctx context.Context, projectID string) (Project, e
 		project.DeactivatedAt = &deactivatedAt.Time
 	}
 
+	if project.APIKeys, err = d.getProjectAPIKeys(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.APIKeyPool, err = d.getProjectAPIKeyPool(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.RequestPolicy, err = d.getProjectRequestPolicy(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.AllowedModels, err = d.getProjectAllowedModels(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.ClientRestrictions, err = d.getProjectClientRestrictions(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+	if project.RateLimit, err = d.getProjectRateLimit(ctx, project.ID); err != nil {
+		return Project{}, err
+	}
+
 	return project, nil
 }
 
@@ -185,27 +290,52 @@ func (d *DB) DBUpdateProject(ctx context.Context, project Project) error {
 	WHERE id = ?
 	`
 
-	result, err := d.ExecContextRebound(
-		ctx,
-		query,
-		project.Name,
-		project.APIKey,
-		project.IsActive,
-		project.DeactivatedAt,
-		project.UpdatedAt,
-		project.ID,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update project: %w", err)
-	}
+	err := d.Transaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			d.RebindQuery(query),
+			project.Name,
+			project.APIKey,
+			project.IsActive,
+			project.DeactivatedAt,
+			project.UpdatedAt,
+			project.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update project: %w", err)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
 
-	if rowsAffected == 0 {
-		return ErrProjectNotFound
+		if rowsAffected == 0 {
+			return ErrProjectNotFound
+		}
+
+		if err := d.syncProjectAPIKeysTx(ctx, tx, project.ID, project.APIKeys); err != nil {
+			return fmt.Errorf("failed to update project: %w", err)
+		}
+		if err := d.syncProjectAPIKeyPoolTx(ctx, tx, project.ID, project.APIKeyPool); err != nil {
+			return fmt.Errorf("failed to update project: %w", err)
+		}
+		if err := d.syncProjectRequestPolicyTx(ctx, tx, project.ID, project.RequestPolicy); err != nil {
+			return fmt.Errorf("failed to update project: %w", err)
+		}
+		if err := d.syncProjectAllowedModelsTx(ctx, tx, project.ID, project.AllowedModels); err != nil {
+			return fmt.Errorf("failed to update project: %w", err)
+		}
+		if err := d.syncProjectClientRestrictionsTx(ctx, tx, project.ID, project.ClientRestrictions); err != nil {
+			return fmt.Errorf("failed to update project: %w", err)
+		}
+		if err := d.syncProjectRateLimitTx(ctx, tx, project.ID, project.RateLimit); err != nil {
+			return fmt.Errorf("failed to update project: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -267,18 +397,536 @@ func (d *DB) DeleteProject(ctx context.Context, id string) error {
 	return d.DBDeleteProject(ctx, id)
 }
 
-// GetAPIKeyForProject retrieves the API key for a project by ID
-func (d *DB) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
-	query := `SELECT api_key FROM projects WHERE id = ?`
-	var apiKey string
-	err := d.QueryRowContextRebound(ctx, query, projectID).Scan(&apiKey)
+// GetAPIKeyForProject retrieves the API key for a project by ID.
+// An empty provider returns the legacy projects.api_key; otherwise the key stored
+// in project_api_keys is returned, or proxy.ErrProviderAPIKeyNotFound if none exists.
+func (d *DB) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
+	if provider == "" {
+		query := `SELECT api_key FROM projects WHERE id = ?`
+		var apiKey string
+		err := d.QueryRowContextRebound(ctx, query, projectID).Scan(&apiKey)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return "", ErrProjectNotFound
+			}
+			return "", fmt.Errorf("failed to get API key for project: %w", err)
+		}
+		return apiKey, nil
+	}
+
+	query := `
+	SELECT p.id, k.api_key
+	FROM projects p
+	LEFT JOIN project_api_keys k ON k.project_id = p.id AND k.provider = ?
+	WHERE p.id = ?
+	`
+	var (
+		id     string
+		apiKey sql.NullString
+	)
+	err := d.QueryRowContextRebound(ctx, query, provider, projectID).Scan(&id, &apiKey)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", ErrProjectNotFound
 		}
 		return "", fmt.Errorf("failed to get API key for project: %w", err)
 	}
-	return apiKey, nil
+	if !apiKey.Valid {
+		return "", proxy.ErrProviderAPIKeyNotFound
+	}
+	return apiKey.String, nil
+}
+
+// getProjectAPIKeys returns the per-provider keys of a single project (nil if none).
+func (d *DB) getProjectAPIKeys(ctx context.Context, projectID string) (map[string]string, error) {
+	query := `SELECT provider, api_key FROM project_api_keys WHERE project_id = ?`
+	rows, err := d.QueryContextRebound(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project API keys: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var keys map[string]string
+	for rows.Next() {
+		var provider, apiKey string
+		if err := rows.Scan(&provider, &apiKey); err != nil {
+			return nil, fmt.Errorf("failed to scan project API key: %w", err)
+		}
+		if keys == nil {
+			keys = make(map[string]string)
+		}
+		keys[provider] = apiKey
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// listProjectAPIKeys returns all per-provider keys grouped by project ID.
+func (d *DB) listProjectAPIKeys(ctx context.Context) (map[string]map[string]string, error) {
+	query := `SELECT project_id, provider, api_key FROM project_api_keys`
+	rows, err := d.QueryContextRebound(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project API keys: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	out := make(map[string]map[string]string)
+	for rows.Next() {
+		var projectID, provider, apiKey string
+		if err := rows.Scan(&projectID, &provider, &apiKey); err != nil {
+			return nil, fmt.Errorf("failed to scan project API key: %w", err)
+		}
+		if out[projectID] == nil {
+			out[projectID] = make(map[string]string)
+		}
+		out[projectID][provider] = apiKey
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project API keys: %w", err)
+	}
+	return out, nil
+}
+
+// syncProjectAPIKeysTx makes project_api_keys match keys for projectID: new providers are
+// inserted, changed keys updated (keeping created_at), and providers absent from keys deleted.
+func (d *DB) syncProjectAPIKeysTx(ctx context.Context, tx *sql.Tx, projectID string, keys map[string]string) error {
+	rows, err := tx.QueryContext(ctx, d.RebindQuery(`SELECT provider, api_key FROM project_api_keys WHERE project_id = ?`), projectID)
+	if err != nil {
+		return fmt.Errorf("failed to read project API keys: %w", err)
+	}
+	existing := make(map[string]string)
+	for rows.Next() {
+		var provider, apiKey string
+		if err := rows.Scan(&provider, &apiKey); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan project API key: %w", err)
+		}
+		existing[provider] = apiKey
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return fmt.Errorf("error iterating project API keys: %w", err)
+	}
+	_ = rows.Close()
+
+	now := time.Now().UTC()
+	for provider, apiKey := range keys {
+		current, ok := existing[provider]
+		switch {
+		case !ok:
+			if _, err := tx.ExecContext(ctx,
+				d.RebindQuery(`INSERT INTO project_api_keys (project_id, provider, api_key, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`),
+				projectID, provider, apiKey, now, now,
+			); err != nil {
+				return fmt.Errorf("failed to add API key for provider %s: %w", provider, err)
+			}
+		case current != apiKey:
+			if _, err := tx.ExecContext(ctx,
+				d.RebindQuery(`UPDATE project_api_keys SET api_key = ?, updated_at = ? WHERE project_id = ? AND provider = ?`),
+				apiKey, now, projectID, provider,
+			); err != nil {
+				return fmt.Errorf("failed to rotate API key for provider %s: %w", provider, err)
+			}
+		}
+	}
+	for provider := range existing {
+		if _, ok := keys[provider]; ok {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			d.RebindQuery(`DELETE FROM project_api_keys WHERE project_id = ? AND provider = ?`),
+			projectID, provider,
+		); err != nil {
+			return fmt.Errorf("failed to remove API key for provider %s: %w", provider, err)
+		}
+	}
+	return nil
+}
+
+// GetAPIKeyPoolForProject returns the key pool of a project for provider in
+// selection order (nil if the provider has no pool).
+func (d *DB) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]proxy.APIKeyPoolEntry, error) {
+	query := `SELECT project_id, provider, api_key, weight FROM project_api_key_pools WHERE project_id = ? AND provider = ? ORDER BY position`
+	byProject, err := d.queryProjectAPIKeyPools(ctx, query, projectID, provider)
+	if err != nil {
+		return nil, err
+	}
+	return byProject[projectID], nil
+}
+
+// getProjectAPIKeyPool reads all key pools of a project (nil if none).
+func (d *DB) getProjectAPIKeyPool(ctx context.Context, projectID string) ([]proxy.APIKeyPoolEntry, error) {
+	query := `SELECT project_id, provider, api_key, weight FROM project_api_key_pools WHERE project_id = ? ORDER BY provider, position`
+	byProject, err := d.queryProjectAPIKeyPools(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return byProject[projectID], nil
+}
+
+// listProjectAPIKeyPools returns all key pools grouped by project ID.
+func (d *DB) listProjectAPIKeyPools(ctx context.Context) (map[string][]proxy.APIKeyPoolEntry, error) {
+	return d.queryProjectAPIKeyPools(ctx, `SELECT project_id, provider, api_key, weight FROM project_api_key_pools ORDER BY project_id, provider, position`)
+}
+
+func (d *DB) queryProjectAPIKeyPools(ctx context.Context, query string, args ...interface{}) (map[string][]proxy.APIKeyPoolEntry, error) {
+	rows, err := d.QueryContextRebound(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project API key pools: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	out := make(map[string][]proxy.APIKeyPoolEntry)
+	for rows.Next() {
+		var projectID string
+		var entry proxy.APIKeyPoolEntry
+		if err := rows.Scan(&projectID, &entry.Provider, &entry.APIKey, &entry.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan project API key pool entry: %w", err)
+		}
+		out[projectID] = append(out[projectID], entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project API key pools: %w", err)
+	}
+	return out, nil
+}
+
+// syncProjectAPIKeyPoolTx replaces the key pools of projectID with pool. Each
+// provider's entries keep their order.
+func (d *DB) syncProjectAPIKeyPoolTx(ctx context.Context, tx *sql.Tx, projectID string, pool []proxy.APIKeyPoolEntry) error {
+	if _, err := tx.ExecContext(ctx, d.RebindQuery(`DELETE FROM project_api_key_pools WHERE project_id = ?`), projectID); err != nil {
+		return fmt.Errorf("failed to remove API key pool: %w", err)
+	}
+	now := time.Now().UTC()
+	positions := make(map[string]int)
+	for _, entry := range pool {
+		weight := entry.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		position := positions[entry.Provider]
+		positions[entry.Provider]++
+		if _, err := tx.ExecContext(ctx,
+			d.RebindQuery(`INSERT INTO project_api_key_pools (project_id, provider, position, api_key, weight, created_at) VALUES (?, ?, ?, ?, ?, ?)`),
+			projectID, entry.Provider, position, entry.APIKey, weight, now,
+		); err != nil {
+			return fmt.Errorf("failed to add API key pool entry for provider '%s': %w", entry.Provider, err)
+		}
+	}
+	return nil
+}
+
+// GetRequestPolicyForProject returns the request policy rules of a project (nil if none).
+func (d *DB) GetRequestPolicyForProject(ctx context.Context, projectID string) ([]proxy.RequestPolicyRule, error) {
+	return d.getProjectRequestPolicy(ctx, projectID)
+}
+
+// getProjectRequestPolicy reads a project's request policy rules (nil if none).
+func (d *DB) getProjectRequestPolicy(ctx context.Context, projectID string) ([]proxy.RequestPolicyRule, error) {
+	query := `SELECT rules FROM project_request_policies WHERE project_id = ?`
+	var raw string
+	err := d.QueryRowContextRebound(ctx, query, projectID).Scan(&raw)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get project request policy: %w", err)
+	}
+	return decodeRequestPolicy(projectID, raw)
+}
+
+// listProjectRequestPolicies returns all project request policies keyed by project ID.
+func (d *DB) listProjectRequestPolicies(ctx context.Context) (map[string][]proxy.RequestPolicyRule, error) {
+	query := `SELECT project_id, rules FROM project_request_policies`
+	rows, err := d.QueryContextRebound(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project request policies: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	out := make(map[string][]proxy.RequestPolicyRule)
+	for rows.Next() {
+		var projectID, raw string
+		if err := rows.Scan(&projectID, &raw); err != nil {
+			return nil, fmt.Errorf("failed to scan project request policy: %w", err)
+		}
+		if out[projectID], err = decodeRequestPolicy(projectID, raw); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project request policies: %w", err)
+	}
+	return out, nil
+}
+
+func decodeRequestPolicy(projectID, raw string) ([]proxy.RequestPolicyRule, error) {
+	var rules []proxy.RequestPolicyRule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("invalid request policy for project %s: %w", projectID, err)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// syncProjectRequestPolicyTx stores rules as the project's request policy; an
+// empty policy removes the row.
+func (d *DB) syncProjectRequestPolicyTx(ctx context.Context, tx *sql.Tx, projectID string, rules []proxy.RequestPolicyRule) error {
+	if _, err := tx.ExecContext(ctx, d.RebindQuery(`DELETE FROM project_request_policies WHERE project_id = ?`), projectID); err != nil {
+		return fmt.Errorf("failed to remove request policy: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(rules)
+	if err != nil {
+		return fmt.Errorf("failed to encode request policy: %w", err)
+	}
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx,
+		d.RebindQuery(`INSERT INTO project_request_policies (project_id, rules, created_at, updated_at) VALUES (?, ?, ?, ?)`),
+		projectID, string(raw), now, now,
+	); err != nil {
+		return fmt.Errorf("failed to store request policy: %w", err)
+	}
+	return nil
+}
+
+// GetAllowedModelsForProject returns the model allowlist of a project (nil if unrestricted).
+func (d *DB) GetAllowedModelsForProject(ctx context.Context, projectID string) ([]string, error) {
+	return d.getProjectAllowedModels(ctx, projectID)
+}
+
+// getProjectAllowedModels reads a project's model patterns (nil if none).
+func (d *DB) getProjectAllowedModels(ctx context.Context, projectID string) ([]string, error) {
+	query := `SELECT model FROM project_allowed_models WHERE project_id = ? ORDER BY model`
+	rows, err := d.QueryContextRebound(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project allowed models: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var models []string
+	for rows.Next() {
+		var model string
+		if err := rows.Scan(&model); err != nil {
+			return nil, fmt.Errorf("failed to scan project allowed model: %w", err)
+		}
+		models = append(models, model)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project allowed models: %w", err)
+	}
+	return models, nil
+}
+
+// listProjectAllowedModels returns all model allowlists grouped by project ID.
+func (d *DB) listProjectAllowedModels(ctx context.Context) (map[string][]string, error) {
+	query := `SELECT project_id, model FROM project_allowed_models ORDER BY project_id, model`
+	rows, err := d.QueryContextRebound(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project allowed models: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	out := make(map[string][]string)
+	for rows.Next() {
+		var projectID, model string
+		if err := rows.Scan(&projectID, &model); err != nil {
+			return nil, fmt.Errorf("failed to scan project allowed model: %w", err)
+		}
+		out[projectID] = append(out[projectID], model)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project allowed models: %w", err)
+	}
+	return out, nil
+}
+
+// syncProjectAllowedModelsTx replaces the model allowlist of projectID with models.
+func (d *DB) syncProjectAllowedModelsTx(ctx context.Context, tx *sql.Tx, projectID string, models []string) error {
+	if _, err := tx.ExecContext(ctx, d.RebindQuery(`DELETE FROM project_allowed_models WHERE project_id = ?`), projectID); err != nil {
+		return fmt.Errorf("failed to remove allowed models: %w", err)
+	}
+	now := time.Now().UTC()
+	seen := make(map[string]bool, len(models))
+	for _, model := range models {
+		if seen[model] {
+			continue
+		}
+		seen[model] = true
+		if _, err := tx.ExecContext(ctx,
+			d.RebindQuery(`INSERT INTO project_allowed_models (project_id, model, created_at) VALUES (?, ?, ?)`),
+			projectID, model, now,
+		); err != nil {
+			return fmt.Errorf("failed to add allowed model %s: %w", model, err)
+		}
+	}
+	return nil
+}
+
+// Kinds of rows in the project_client_restrictions table.
+const (
+	clientRestrictionCIDR   = "cidr"
+	clientRestrictionOrigin = "origin"
+)
+
+// GetClientRestrictionsForProject returns the client allowlists of a project.
+func (d *DB) GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	return d.getProjectClientRestrictions(ctx, projectID)
+}
+
+// getProjectClientRestrictions reads a project's client allowlists.
+func (d *DB) getProjectClientRestrictions(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	query := `SELECT project_id, kind, value FROM project_client_restrictions WHERE project_id = ? ORDER BY kind, value`
+	byProject, err := d.queryProjectClientRestrictions(ctx, query, projectID)
+	if err != nil {
+		return token.ClientRestrictions{}, err
+	}
+	return byProject[projectID], nil
+}
+
+// listProjectClientRestrictions returns all client allowlists grouped by project ID.
+func (d *DB) listProjectClientRestrictions(ctx context.Context) (map[string]token.ClientRestrictions, error) {
+	return d.queryProjectClientRestrictions(ctx, `SELECT project_id, kind, value FROM project_client_restrictions ORDER BY project_id, kind, value`)
+}
+
+func (d *DB) queryProjectClientRestrictions(ctx context.Context, query string, args ...interface{}) (map[string]token.ClientRestrictions, error) {
+	rows, err := d.QueryContextRebound(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project client restrictions: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	out := make(map[string]token.ClientRestrictions)
+	for rows.Next() {
+		var projectID, kind, value string
+		if err := rows.Scan(&projectID, &kind, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan project client restriction: %w", err)
+		}
+		restrictions := out[projectID]
+		switch kind {
+		case clientRestrictionCIDR:
+			restrictions.CIDRs = append(restrictions.CIDRs, value)
+		case clientRestrictionOrigin:
+			restrictions.Origins = append(restrictions.Origins, value)
+		default:
+			continue
+		}
+		out[projectID] = restrictions
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project client restrictions: %w", err)
+	}
+	return out, nil
+}
+
+// syncProjectClientRestrictionsTx replaces the client allowlists of projectID with restrictions.
+func (d *DB) syncProjectClientRestrictionsTx(ctx context.Context, tx *sql.Tx, projectID string, restrictions token.ClientRestrictions) error {
+	if _, err := tx.ExecContext(ctx, d.RebindQuery(`DELETE FROM project_client_restrictions WHERE project_id = ?`), projectID); err != nil {
+		return fmt.Errorf("failed to remove client restrictions: %w", err)
+	}
+	now := time.Now().UTC()
+	insert := func(kind string, values []string) error {
+		seen := make(map[string]bool, len(values))
+		for _, value := range values {
+			if seen[value] {
+				continue
+			}
+			seen[value] = true
+			if _, err := tx.ExecContext(ctx,
+				d.RebindQuery(`INSERT INTO project_client_restrictions (project_id, kind, value, created_at) VALUES (?, ?, ?, ?)`),
+				projectID, kind, value, now,
+			); err != nil {
+				return fmt.Errorf("failed to add client restriction %s: %w", value, err)
+			}
+		}
+		return nil
+	}
+	if err := insert(clientRestrictionCIDR, restrictions.CIDRs); err != nil {
+		return err
+	}
+	return insert(clientRestrictionOrigin, restrictions.Origins)
+}
+
+// GetRateLimitForProject returns the request rate limit of a project.
+func (d *DB) GetRateLimitForProject(ctx context.Context, projectID string) (token.RateLimit, error) {
+	return d.getProjectRateLimit(ctx, projectID)
+}
+
+// getProjectRateLimit reads a project's rate limit; projects without one get the zero value.
+func (d *DB) getProjectRateLimit(ctx context.Context, projectID string) (token.RateLimit, error) {
+	query := `SELECT project_id, requests_per_window, window_seconds, burst FROM project_rate_limits WHERE project_id = ?`
+	byProject, err := d.queryProjectRateLimits(ctx, query, projectID)
+	if err != nil {
+		return token.RateLimit{}, err
+	}
+	return byProject[projectID], nil
+}
+
+// listProjectRateLimits returns all rate limits keyed by project ID.
+func (d *DB) listProjectRateLimits(ctx context.Context) (map[string]token.RateLimit, error) {
+	return d.queryProjectRateLimits(ctx, `SELECT project_id, requests_per_window, window_seconds, burst FROM project_rate_limits`)
+}
+
+func (d *DB) queryProjectRateLimits(ctx context.Context, query string, args ...interface{}) (map[string]token.RateLimit, error) {
+	rows, err := d.QueryContextRebound(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project rate limits: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	out := make(map[string]token.RateLimit)
+	for rows.Next() {
+		var projectID string
+		var limit token.RateLimit
+		if err := rows.Scan(&projectID, &limit.RequestsPerWindow, &limit.WindowSeconds, &limit.Burst); err != nil {
+			return nil, fmt.Errorf("failed to scan project rate limit: %w", err)
+		}
+		out[projectID] = limit
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating project rate limits: %w", err)
+	}
+	return out, nil
+}
+
+// syncProjectRateLimitTx replaces the rate limit of projectID; a zero limit removes it.
+func (d *DB) syncProjectRateLimitTx(ctx context.Context, tx *sql.Tx, projectID string, limit token.RateLimit) error {
+	if _, err := tx.ExecContext(ctx, d.RebindQuery(`DELETE FROM project_rate_limits WHERE project_id = ?`), projectID); err != nil {
+		return fmt.Errorf("failed to remove rate limit: %w", err)
+	}
+	if limit.IsZero() {
+		return nil
+	}
+	limit = limit.Normalize()
+	if _, err := tx.ExecContext(ctx,
+		d.RebindQuery(`INSERT INTO project_rate_limits (project_id, requests_per_window, window_seconds, burst, created_at) VALUES (?, ?, ?, ?, ?)`),
+		projectID, limit.RequestsPerWindow, limit.WindowSeconds, limit.Burst, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("failed to add rate limit: %w", err)
+	}
+	return nil
 }
 
 // GetProjectActive retrieves the active status for a project by ID
@@ -2,11 +2,14 @@ package database
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/sofatutor/llm-proxy/internal/token"
 	"github.com/stretchr/testify/require"
 )
 
@@ -328,7 +331,7 @@ func TestGetAPIKeyForProject(t *testing.T) {
 	}
 
 	// Happy path
-	key, err := db.GetAPIKeyForProject(ctx, "pid")
+	key, err := db.GetAPIKeyForProject(ctx, "pid", "")
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -337,12 +340,353 @@ func TestGetAPIKeyForProject(t *testing.T) {
 	}
 
 	// Error path: non-existent project
-	_, err = db.GetAPIKeyForProject(ctx, "does-not-exist")
+	_, err = db.GetAPIKeyForProject(ctx, "does-not-exist", "")
 	if err == nil {
 		t.Error("expected error for non-existent project")
 	}
 }
 
+func TestProjectAPIKeys_PerProvider(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	project := Project{
+		ID:        "pid",
+		Name:      "multi",
+		APIKey:    "sk-legacy",
+		APIKeys:   map[string]string{"openai": "sk-openai", "anthropic": "sk-ant"},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.DBCreateProject(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	key, err := db.GetAPIKeyForProject(ctx, "pid", "anthropic")
+	if err != nil || key != "sk-ant" {
+		t.Fatalf("expected anthropic key, got %q (err=%v)", key, err)
+	}
+	key, err = db.GetAPIKeyForProject(ctx, "pid", "")
+	if err != nil || key != "sk-legacy" {
+		t.Fatalf("expected legacy key, got %q (err=%v)", key, err)
+	}
+	if _, err := db.GetAPIKeyForProject(ctx, "pid", "gemini"); !errors.Is(err, proxy.ErrProviderAPIKeyNotFound) {
+		t.Fatalf("expected ErrProviderAPIKeyNotFound, got %v", err)
+	}
+	if _, err := db.GetAPIKeyForProject(ctx, "missing", "anthropic"); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound, got %v", err)
+	}
+
+	// Rotate one key, remove another, add a new one.
+	got, err := db.DBGetProjectByID(ctx, "pid")
+	if err != nil {
+		t.Fatalf("DBGetProjectByID failed: %v", err)
+	}
+	if len(got.APIKeys) != 2 {
+		t.Fatalf("expected 2 provider keys, got %v", got.APIKeys)
+	}
+	got.APIKeys = map[string]string{"anthropic": "sk-ant-rotated", "gemini": "g-key"}
+	if err := db.DBUpdateProject(ctx, got); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+
+	projects, err := db.DBListProjects(ctx)
+	if err != nil {
+		t.Fatalf("DBListProjects failed: %v", err)
+	}
+	if len(projects) != 1 {
+		t.Fatalf("expected 1 project, got %d", len(projects))
+	}
+	want := map[string]string{"anthropic": "sk-ant-rotated", "gemini": "g-key"}
+	if !reflect.DeepEqual(projects[0].APIKeys, want) {
+		t.Errorf("expected provider keys %v, got %v", want, projects[0].APIKeys)
+	}
+	if _, err := db.GetAPIKeyForProject(ctx, "pid", "openai"); !errors.Is(err, proxy.ErrProviderAPIKeyNotFound) {
+		t.Errorf("expected removed openai key to be gone, got %v", err)
+	}
+
+	// Deleting the project cascades to its provider keys.
+	if err := db.DBDeleteProject(ctx, "pid"); err != nil {
+		t.Fatalf("DBDeleteProject failed: %v", err)
+	}
+	var count int
+	if err := db.db.QueryRow("SELECT COUNT(*) FROM project_api_keys").Scan(&count); err != nil {
+		t.Fatalf("count query failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected provider keys to be deleted with project, got %d", count)
+	}
+}
+
+func TestProjectRequestPolicy(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	limit := 4000.0
+	rules := []proxy.RequestPolicyRule{
+		{Path: "$.max_tokens", Max: &limit},
+		{Path: "$.tools", Forbidden: true, Endpoints: []string{"/v1/chat/completions"}},
+		{Path: "$.store", Set: false},
+	}
+	project := Project{ID: "pid", Name: "policy", APIKey: "sk-test", RequestPolicy: rules, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.DBCreateProject(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	got, err := db.GetRequestPolicyForProject(ctx, "pid")
+	if err != nil {
+		t.Fatalf("GetRequestPolicyForProject failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, rules) {
+		t.Errorf("expected rules %+v, got %+v", rules, got)
+	}
+	fetched, err := db.DBGetProjectByID(ctx, "pid")
+	if err != nil {
+		t.Fatalf("DBGetProjectByID failed: %v", err)
+	}
+	if !reflect.DeepEqual(fetched.RequestPolicy, rules) {
+		t.Errorf("expected project rules %+v, got %+v", rules, fetched.RequestPolicy)
+	}
+
+	// Replace the policy, then remove it.
+	fetched.RequestPolicy = rules[:1]
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	projects, err := db.DBListProjects(ctx)
+	if err != nil {
+		t.Fatalf("DBListProjects failed: %v", err)
+	}
+	if len(projects) != 1 || !reflect.DeepEqual(projects[0].RequestPolicy, rules[:1]) {
+		t.Errorf("expected listed rules %+v, got %+v", rules[:1], projects)
+	}
+	fetched.RequestPolicy = nil
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	if got, err := db.GetRequestPolicyForProject(ctx, "pid"); err != nil || got != nil {
+		t.Errorf("expected no rules, got %+v (err=%v)", got, err)
+	}
+	if got, err := db.GetRequestPolicyForProject(ctx, "missing"); err != nil || got != nil {
+		t.Errorf("expected no rules for unknown project, got %+v (err=%v)", got, err)
+	}
+}
+
+func TestProjectAllowedModels(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	project := Project{
+		ID:            "pid",
+		Name:          "models",
+		APIKey:        "sk-test",
+		AllowedModels: []string{"gpt-4o-mini", "claude-3-5-*", "gpt-4o-mini"},
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if err := db.DBCreateProject(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	want := []string{"claude-3-5-*", "gpt-4o-mini"}
+	got, err := db.GetAllowedModelsForProject(ctx, "pid")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected models %v, got %v (err=%v)", want, got, err)
+	}
+	fetched, err := db.DBGetProjectByID(ctx, "pid")
+	if err != nil {
+		t.Fatalf("DBGetProjectByID failed: %v", err)
+	}
+	if !reflect.DeepEqual(fetched.AllowedModels, want) {
+		t.Errorf("expected project models %v, got %v", want, fetched.AllowedModels)
+	}
+
+	fetched.AllowedModels = []string{"o3-mini"}
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	projects, err := db.DBListProjects(ctx)
+	if err != nil {
+		t.Fatalf("DBListProjects failed: %v", err)
+	}
+	if len(projects) != 1 || !reflect.DeepEqual(projects[0].AllowedModels, []string{"o3-mini"}) {
+		t.Errorf("expected listed models [o3-mini], got %+v", projects)
+	}
+
+	fetched.AllowedModels = nil
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	if got, err := db.GetAllowedModelsForProject(ctx, "pid"); err != nil || got != nil {
+		t.Errorf("expected no models, got %v (err=%v)", got, err)
+	}
+}
+
+func TestProjectAPIKeyPool(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	project := Project{
+		ID:     "pid",
+		Name:   "pool",
+		APIKey: "sk-test",
+		APIKeyPool: []proxy.APIKeyPoolEntry{
+			{Provider: "openai", APIKey: "sk-b", Weight: 3},
+			{APIKey: "sk-legacy-a"},
+			{Provider: "openai", APIKey: "sk-a"},
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.DBCreateProject(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	want := []proxy.APIKeyPoolEntry{{Provider: "openai", APIKey: "sk-b", Weight: 3}, {Provider: "openai", APIKey: "sk-a", Weight: 1}}
+	got, err := db.GetAPIKeyPoolForProject(ctx, "pid", "openai")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected pool %v, got %v (err=%v)", want, got, err)
+	}
+	if got, err := db.GetAPIKeyPoolForProject(ctx, "pid", "anthropic"); err != nil || got != nil {
+		t.Errorf("expected no anthropic pool, got %v (err=%v)", got, err)
+	}
+	fetched, err := db.DBGetProjectByID(ctx, "pid")
+	if err != nil {
+		t.Fatalf("DBGetProjectByID failed: %v", err)
+	}
+	wantAll := append([]proxy.APIKeyPoolEntry{{APIKey: "sk-legacy-a", Weight: 1}}, want...)
+	if !reflect.DeepEqual(fetched.APIKeyPool, wantAll) {
+		t.Errorf("expected project pool %v, got %v", wantAll, fetched.APIKeyPool)
+	}
+
+	fetched.APIKeyPool = []proxy.APIKeyPoolEntry{{APIKey: "sk-legacy-b", Weight: 2}}
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	projects, err := db.DBListProjects(ctx)
+	if err != nil {
+		t.Fatalf("DBListProjects failed: %v", err)
+	}
+	if len(projects) != 1 || !reflect.DeepEqual(projects[0].APIKeyPool, fetched.APIKeyPool) {
+		t.Errorf("expected listed pool %v, got %+v", fetched.APIKeyPool, projects)
+	}
+
+	if err := db.DBDeleteProject(ctx, "pid"); err != nil {
+		t.Fatalf("DBDeleteProject failed: %v", err)
+	}
+	if got, err := db.GetAPIKeyPoolForProject(ctx, "pid", ""); err != nil || got != nil {
+		t.Errorf("expected the pool to be deleted with its project, got %v (err=%v)", got, err)
+	}
+}
+
+func TestProjectClientRestrictions(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	project := Project{
+		ID:     "pid",
+		Name:   "clients",
+		APIKey: "sk-test",
+		ClientRestrictions: token.ClientRestrictions{
+			CIDRs:   []string{"203.0.113.0/24", "10.0.0.0/8", "10.0.0.0/8"},
+			Origins: []string{"https://app.example.com"},
+		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.DBCreateProject(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	want := token.ClientRestrictions{CIDRs: []string{"10.0.0.0/8", "203.0.113.0/24"}, Origins: []string{"https://app.example.com"}}
+	got, err := db.GetClientRestrictionsForProject(ctx, "pid")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected restrictions %+v, got %+v (err=%v)", want, got, err)
+	}
+	fetched, err := db.DBGetProjectByID(ctx, "pid")
+	if err != nil {
+		t.Fatalf("DBGetProjectByID failed: %v", err)
+	}
+	if !reflect.DeepEqual(fetched.ClientRestrictions, want) {
+		t.Errorf("expected project restrictions %+v, got %+v", want, fetched.ClientRestrictions)
+	}
+
+	fetched.ClientRestrictions = token.ClientRestrictions{Origins: []string{"https://*.example.com"}}
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	projects, err := db.DBListProjects(ctx)
+	if err != nil {
+		t.Fatalf("DBListProjects failed: %v", err)
+	}
+	if len(projects) != 1 || !reflect.DeepEqual(projects[0].ClientRestrictions, fetched.ClientRestrictions) {
+		t.Errorf("expected listed restrictions %+v, got %+v", fetched.ClientRestrictions, projects)
+	}
+
+	fetched.ClientRestrictions = token.ClientRestrictions{}
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	if got, err := db.GetClientRestrictionsForProject(ctx, "pid"); err != nil || !got.IsEmpty() {
+		t.Errorf("expected no restrictions, got %+v (err=%v)", got, err)
+	}
+}
+
+func TestProjectRateLimits(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	project := Project{
+		ID:        "pid",
+		Name:      "limited",
+		APIKey:    "sk-test",
+		RateLimit: token.RateLimit{RequestsPerWindow: 100, Burst: 5},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.DBCreateProject(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	want := token.RateLimit{RequestsPerWindow: 100, WindowSeconds: 60, Burst: 5}
+	if got, err := db.GetRateLimitForProject(ctx, "pid"); err != nil || got != want {
+		t.Fatalf("expected rate limit %+v, got %+v (err=%v)", want, got, err)
+	}
+	fetched, err := db.DBGetProjectByID(ctx, "pid")
+	if err != nil {
+		t.Fatalf("DBGetProjectByID failed: %v", err)
+	}
+	if fetched.RateLimit != want {
+		t.Errorf("expected project rate limit %+v, got %+v", want, fetched.RateLimit)
+	}
+
+	fetched.RateLimit = token.RateLimit{RequestsPerWindow: 10, WindowSeconds: 3600}
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	projects, err := db.DBListProjects(ctx)
+	if err != nil {
+		t.Fatalf("DBListProjects failed: %v", err)
+	}
+	if len(projects) != 1 || projects[0].RateLimit != fetched.RateLimit {
+		t.Errorf("expected listed rate limit %+v, got %+v", fetched.RateLimit, projects)
+	}
+
+	fetched.RateLimit = token.RateLimit{}
+	if err := db.DBUpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("DBUpdateProject failed: %v", err)
+	}
+	if got, err := db.GetRateLimitForProject(ctx, "pid"); err != nil || !got.IsZero() {
+		t.Errorf("expected no rate limit, got %+v (err=%v)", got, err)
+	}
+}
+
 func TestDBDeleteProject_And_DBUpdateProject_EdgeCases(t *testing.T) {
 	db, cleanup := testDB(t)
 	defer cleanup()
@@ -486,7 +830,7 @@ func TestGetAPIKeyForProject_ClosedDB(t *testing.T) {
 	db, cleanup := testDB(t)
 	cleanup()
 	ctx := context.Background()
-	_, err := db.GetAPIKeyForProject(ctx, "test-id")
+	_, err := db.GetAPIKeyForProject(ctx, "test-id", "")
 	if err == nil {
 		t.Error("expected error for GetAPIKeyForProject on closed DB")
 	}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -29,11 +30,28 @@ func (d *DB) CreateToken(ctx context.Context, token Token) error {
 	}
 
 	query := `
-	INSERT INTO tokens (id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO tokens (id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, scopes, metadata, client_restrictions, rate_limit, rotated_from_id, rotated_to_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := d.ExecContextRebound(
+	scopes, err := encodeTokenScopes(token.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	metadata, err := encodeTokenMetadata(token.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	clientRestrictions, err := encodeTokenClientRestrictions(token.ClientRestrictions)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+	rateLimit, err := encodeTokenRateLimit(token.RateLimit)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	_, err = d.ExecContextRebound(
 		ctx,
 		query,
 		token.ID,
@@ -46,6 +64,12 @@ func (d *DB) CreateToken(ctx context.Context, token Token) error {
 		token.MaxRequests,
 		token.CreatedAt,
 		token.LastUsedAt,
+		scopes,
+		metadata,
+		clientRestrictions,
+		rateLimit,
+		nullableTokenID(token.RotatedFromID),
+		nullableTokenID(token.RotatedToID),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create token: %w", err)
@@ -57,7 +81,7 @@ func (d *DB) CreateToken(ctx context.Context, token Token) error {
 // GetTokenByID retrieves a token by its UUID.
 func (d *DB) GetTokenByID(ctx context.Context, id string) (Token, error) {
 	query := `
-	SELECT id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, cache_hit_count
+	SELECT id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, cache_hit_count, scopes, metadata, client_restrictions, rate_limit, rotated_from_id, rotated_to_id
 	FROM tokens
 	WHERE id = ?
 	`
@@ -65,6 +89,7 @@ func (d *DB) GetTokenByID(ctx context.Context, id string) (Token, error) {
 	var token Token
 	var expiresAt, lastUsedAt, deactivatedAt sql.NullTime
 	var maxRequests sql.NullInt32
+	var scopes, metadata, clientRestrictions, rateLimit, rotatedFromID, rotatedToID sql.NullString
 
 	err := d.QueryRowContextRebound(ctx, query, id).Scan(
 		&token.ID,
@@ -78,6 +103,12 @@ func (d *DB) GetTokenByID(ctx context.Context, id string) (Token, error) {
 		&token.CreatedAt,
 		&lastUsedAt,
 		&token.CacheHitCount,
+		&scopes,
+		&metadata,
+		&clientRestrictions,
+		&rateLimit,
+		&rotatedFromID,
+		&rotatedToID,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -99,6 +130,20 @@ func (d *DB) GetTokenByID(ctx context.Context, id string) (Token, error) {
 		maxReq := int(maxRequests.Int32)
 		token.MaxRequests = &maxReq
 	}
+	if token.Scopes, err = decodeTokenScopes(scopes); err != nil {
+		return Token{}, err
+	}
+	if token.Metadata, err = decodeTokenMetadata(metadata); err != nil {
+		return Token{}, err
+	}
+	if token.ClientRestrictions, err = decodeTokenClientRestrictions(clientRestrictions); err != nil {
+		return Token{}, err
+	}
+	if token.RateLimit, err = decodeTokenRateLimit(rateLimit); err != nil {
+		return Token{}, err
+	}
+	token.RotatedFromID = rotatedFromID.String
+	token.RotatedToID = rotatedToID.String
 
 	return token, nil
 }
@@ -106,7 +151,7 @@ func (d *DB) GetTokenByID(ctx context.Context, id string) (Token, error) {
 // GetTokenByToken retrieves a token by its token string (for authentication).
 func (d *DB) GetTokenByToken(ctx context.Context, tokenString string) (Token, error) {
 	query := `
-	SELECT id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, cache_hit_count
+	SELECT id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, cache_hit_count, scopes, metadata, client_restrictions, rate_limit, rotated_from_id, rotated_to_id
 	FROM tokens
 	WHERE token = ?
 	`
@@ -114,6 +159,7 @@ func (d *DB) GetTokenByToken(ctx context.Context, tokenString string) (Token, er
 	var token Token
 	var expiresAt, lastUsedAt, deactivatedAt sql.NullTime
 	var maxRequests sql.NullInt32
+	var scopes, metadata, clientRestrictions, rateLimit, rotatedFromID, rotatedToID sql.NullString
 
 	err := d.QueryRowContextRebound(ctx, query, tokenString).Scan(
 		&token.ID,
@@ -127,6 +173,12 @@ func (d *DB) GetTokenByToken(ctx context.Context, tokenString string) (Token, er
 		&token.CreatedAt,
 		&lastUsedAt,
 		&token.CacheHitCount,
+		&scopes,
+		&metadata,
+		&clientRestrictions,
+		&rateLimit,
+		&rotatedFromID,
+		&rotatedToID,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -148,6 +200,20 @@ func (d *DB) GetTokenByToken(ctx context.Context, tokenString string) (Token, er
 		maxReq := int(maxRequests.Int32)
 		token.MaxRequests = &maxReq
 	}
+	if token.Scopes, err = decodeTokenScopes(scopes); err != nil {
+		return Token{}, err
+	}
+	if token.Metadata, err = decodeTokenMetadata(metadata); err != nil {
+		return Token{}, err
+	}
+	if token.ClientRestrictions, err = decodeTokenClientRestrictions(clientRestrictions); err != nil {
+		return Token{}, err
+	}
+	if token.RateLimit, err = decodeTokenRateLimit(rateLimit); err != nil {
+		return Token{}, err
+	}
+	token.RotatedFromID = rotatedFromID.String
+	token.RotatedToID = rotatedToID.String
 
 	return token, nil
 }
@@ -162,12 +228,12 @@ func (d *DB) UpdateToken(ctx context.Context, token Token) error {
 
 	queryByID := `
 	UPDATE tokens
-	SET project_id = ?, expires_at = ?, is_active = ?, request_count = ?, max_requests = ?, last_used_at = ?
+	SET project_id = ?, expires_at = ?, is_active = ?, request_count = ?, max_requests = ?, last_used_at = ?, metadata = ?, rate_limit = ?, rotated_to_id = ?
 	WHERE id = ?
 	`
 	queryByToken := `
 	UPDATE tokens
-	SET project_id = ?, expires_at = ?, is_active = ?, request_count = ?, max_requests = ?, last_used_at = ?
+	SET project_id = ?, expires_at = ?, is_active = ?, request_count = ?, max_requests = ?, last_used_at = ?, metadata = ?, rate_limit = ?, rotated_to_id = ?
 	WHERE token = ?
 	`
 
@@ -178,6 +244,15 @@ func (d *DB) UpdateToken(ctx context.Context, token Token) error {
 		lookupValue = token.Token
 	}
 
+	metadata, err := encodeTokenMetadata(token.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+	rateLimit, err := encodeTokenRateLimit(token.RateLimit)
+	if err != nil {
+		return fmt.Errorf("failed to update token: %w", err)
+	}
+
 	result, err := d.ExecContextRebound(
 		ctx,
 		query,
@@ -187,6 +262,9 @@ func (d *DB) UpdateToken(ctx context.Context, token Token) error {
 		token.RequestCount,
 		token.MaxRequests,
 		token.LastUsedAt,
+		metadata,
+		rateLimit,
+		nullableTokenID(token.RotatedToID),
 		lookupValue,
 	)
 	if err != nil {
@@ -232,7 +310,7 @@ func (d *DB) DeleteToken(ctx context.Context, tokenID string) error {
 // ListTokens retrieves all tokens from the database.
 func (d *DB) ListTokens(ctx context.Context) ([]Token, error) {
 	query := `
-	SELECT id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, cache_hit_count
+	SELECT id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, cache_hit_count, scopes, metadata, client_restrictions, rate_limit, rotated_from_id, rotated_to_id
 	FROM tokens
 	ORDER BY created_at DESC
 	`
@@ -243,7 +321,7 @@ func (d *DB) ListTokens(ctx context.Context) ([]Token, error) {
 // GetTokensByProjectID retrieves all tokens for a project.
 func (d *DB) GetTokensByProjectID(ctx context.Context, projectID string) ([]Token, error) {
 	query := `
-	SELECT id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, cache_hit_count
+	SELECT id, token, project_id, expires_at, is_active, deactivated_at, request_count, max_requests, created_at, last_used_at, cache_hit_count, scopes, metadata, client_restrictions, rate_limit, rotated_from_id, rotated_to_id
 	FROM tokens
 	WHERE project_id = ?
 	ORDER BY created_at DESC
@@ -257,14 +335,27 @@ func (d *DB) IncrementTokenUsage(ctx context.Context, tokenID string) error {
 	if tokenID == "" {
 		return fmt.Errorf("token string is required")
 	}
+	return d.incrementTokenUsage(ctx, "token", tokenID)
+}
 
+// IncrementTokenUsageByID is IncrementTokenUsage for the token with the given ID.
+func (d *DB) IncrementTokenUsageByID(ctx context.Context, id string) error {
+	if id == "" {
+		return fmt.Errorf("token ID is required")
+	}
+	return d.incrementTokenUsage(ctx, "id", id)
+}
+
+// incrementTokenUsage increments the usage of the token whose column (token
+// or id) equals value.
+func (d *DB) incrementTokenUsage(ctx context.Context, column, value string) error {
 	now := time.Now().UTC()
 	// Enforce max_requests atomically. max_requests is treated as unlimited when NULL/<=0
 	// (the API layer should normalize 0 to NULL, but we keep DB logic defensive).
 	query := `
 	UPDATE tokens
 	SET request_count = request_count + 1, last_used_at = ?
-	WHERE token = ?
+	WHERE ` + column + ` = ?
 	  AND is_active = TRUE
 	  AND (expires_at IS NULL OR expires_at > ?)
 	  AND (
@@ -274,7 +365,7 @@ func (d *DB) IncrementTokenUsage(ctx context.Context, tokenID string) error {
 	  )
 	`
 
-	result, err := d.ExecContextRebound(ctx, query, now, tokenID, now)
+	result, err := d.ExecContextRebound(ctx, query, now, value, now)
 	if err != nil {
 		return fmt.Errorf("failed to increment token usage: %w", err)
 	}
@@ -298,13 +389,13 @@ func (d *DB) IncrementTokenUsage(ctx context.Context, tokenID string) error {
 			requestCount int
 			maxRequests  sql.NullInt32
 		)
-		checkQuery := `SELECT is_active, expires_at, request_count, max_requests FROM tokens WHERE token = ?`
-		err := d.QueryRowContextRebound(ctx, checkQuery, tokenID).Scan(&isActive, &expiresAt, &requestCount, &maxRequests)
+		checkQuery := `SELECT is_active, expires_at, request_count, max_requests FROM tokens WHERE ` + column + ` = ?`
+		err := d.QueryRowContextRebound(ctx, checkQuery, value).Scan(&isActive, &expiresAt, &requestCount, &maxRequests)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				return ErrTokenNotFound
 			}
-			return fmt.Errorf("failed to check token usage for %s: %w", obfuscate.ObfuscateTokenGeneric(tokenID), err)
+			return fmt.Errorf("failed to check token usage for %s: %w", obfuscate.ObfuscateTokenGeneric(value), err)
 		}
 		if !isActive {
 			return token.ErrTokenInactive
@@ -320,7 +411,7 @@ func (d *DB) IncrementTokenUsage(ctx context.Context, tokenID string) error {
 				return token.ErrTokenRateLimit
 			}
 		}
-		return fmt.Errorf("failed to increment token usage for %s: no rows updated", obfuscate.ObfuscateTokenGeneric(tokenID))
+		return fmt.Errorf("failed to increment token usage for %s: no rows updated", obfuscate.ObfuscateTokenGeneric(value))
 	}
 
 	return nil
@@ -406,6 +497,111 @@ func (d *DB) CleanExpiredTokens(ctx context.Context) (int64, error) {
 	return rowsAffected, nil
 }
 
+// encodeTokenScopes serializes token scopes for the scopes column; unrestricted
+// scopes are stored as NULL.
+func encodeTokenScopes(scopes token.Scopes) (sql.NullString, error) {
+	if scopes.IsEmpty() {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(scopes)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode token scopes: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// decodeTokenScopes parses the scopes column.
+func decodeTokenScopes(value sql.NullString) (token.Scopes, error) {
+	var scopes token.Scopes
+	if !value.Valid || value.String == "" {
+		return scopes, nil
+	}
+	if err := json.Unmarshal([]byte(value.String), &scopes); err != nil {
+		return token.Scopes{}, fmt.Errorf("failed to decode token scopes: %w", err)
+	}
+	return scopes, nil
+}
+
+// encodeTokenMetadata serializes token metadata for the metadata column; empty
+// metadata is stored as NULL.
+func encodeTokenMetadata(metadata map[string]string) (sql.NullString, error) {
+	if len(metadata) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode token metadata: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// decodeTokenMetadata parses the metadata column.
+func decodeTokenMetadata(value sql.NullString) (map[string]string, error) {
+	if !value.Valid || value.String == "" {
+		return nil, nil
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal([]byte(value.String), &metadata); err != nil {
+		return nil, fmt.Errorf("failed to decode token metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// encodeTokenClientRestrictions serializes client restrictions for the
+// client_restrictions column; unrestricted tokens are stored as NULL.
+func encodeTokenClientRestrictions(restrictions token.ClientRestrictions) (sql.NullString, error) {
+	if restrictions.IsEmpty() {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(restrictions)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode token client restrictions: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// decodeTokenClientRestrictions parses the client_restrictions column.
+func decodeTokenClientRestrictions(value sql.NullString) (token.ClientRestrictions, error) {
+	var restrictions token.ClientRestrictions
+	if !value.Valid || value.String == "" {
+		return restrictions, nil
+	}
+	if err := json.Unmarshal([]byte(value.String), &restrictions); err != nil {
+		return token.ClientRestrictions{}, fmt.Errorf("failed to decode token client restrictions: %w", err)
+	}
+	return restrictions, nil
+}
+
+// nullableTokenID stores an empty token ID reference as NULL.
+// encodeTokenRateLimit serializes a rate limit for the rate_limit column;
+// tokens without their own limit are stored as NULL.
+func encodeTokenRateLimit(limit token.RateLimit) (sql.NullString, error) {
+	if limit.IsZero() {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(limit)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode token rate limit: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// decodeTokenRateLimit parses the rate_limit column.
+func decodeTokenRateLimit(value sql.NullString) (token.RateLimit, error) {
+	var limit token.RateLimit
+	if !value.Valid || value.String == "" {
+		return limit, nil
+	}
+	if err := json.Unmarshal([]byte(value.String), &limit); err != nil {
+		return token.RateLimit{}, fmt.Errorf("failed to decode token rate limit: %w", err)
+	}
+	return limit, nil
+}
+
+func nullableTokenID(id string) sql.NullString {
+	return sql.NullString{String: id, Valid: id != ""}
+}
+
 // queryTokens is a helper function to query tokens.
 func (d *DB) queryTokens(ctx context.Context, query string, args ...interface{}) ([]Token, error) {
 	rows, err := d.QueryContextRebound(ctx, query, args...)
@@ -421,6 +617,7 @@ func (d *DB) queryTokens(ctx context.Context, query string, args ...interface{})
 		var token Token
 		var expiresAt, lastUsedAt, deactivatedAt sql.NullTime
 		var maxRequests sql.NullInt32
+		var scopes, metadata, clientRestrictions, rateLimit, rotatedFromID, rotatedToID sql.NullString
 
 		if err := rows.Scan(
 			&token.ID,
@@ -434,6 +631,12 @@ func (d *DB) queryTokens(ctx context.Context, query string, args ...interface{})
 			&token.CreatedAt,
 			&lastUsedAt,
 			&token.CacheHitCount,
+			&scopes,
+			&metadata,
+			&clientRestrictions,
+			&rateLimit,
+			&rotatedFromID,
+			&rotatedToID,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan token: %w", err)
 		}
@@ -451,6 +654,22 @@ func (d *DB) queryTokens(ctx context.Context, query string, args ...interface{})
 			maxReq := int(maxRequests.Int32)
 			token.MaxRequests = &maxReq
 		}
+		scopesData, err := decodeTokenScopes(scopes)
+		if err != nil {
+			return nil, err
+		}
+		token.Scopes = scopesData
+		if token.Metadata, err = decodeTokenMetadata(metadata); err != nil {
+			return nil, err
+		}
+		if token.ClientRestrictions, err = decodeTokenClientRestrictions(clientRestrictions); err != nil {
+			return nil, err
+		}
+		if token.RateLimit, err = decodeTokenRateLimit(rateLimit); err != nil {
+			return nil, err
+		}
+		token.RotatedFromID = rotatedFromID.String
+		token.RotatedToID = rotatedToID.String
 
 		tokens = append(tokens, token)
 	}
@@ -497,6 +716,10 @@ func (a *DBTokenStoreAdapter) IncrementTokenUsage(ctx context.Context, tokenID s
 	return a.db.IncrementTokenUsage(ctx, tokenID)
 }
 
+func (a *DBTokenStoreAdapter) IncrementTokenUsageByID(ctx context.Context, id string) error {
+	return a.db.IncrementTokenUsageByID(ctx, id)
+}
+
 func (a *DBTokenStoreAdapter) CreateToken(ctx context.Context, td token.TokenData) error {
 	dbToken := ImportTokenData(td)
 	return a.db.CreateToken(ctx, dbToken)
@@ -534,33 +757,45 @@ func (a *DBTokenStoreAdapter) GetTokensByProjectID(ctx context.Context, projectI
 // ImportTokenData and ExportTokenData helpers
 func ImportTokenData(td token.TokenData) Token {
 	return Token{
-		ID:            td.ID,
-		Token:         td.Token,
-		ProjectID:     td.ProjectID,
-		ExpiresAt:     td.ExpiresAt,
-		IsActive:      td.IsActive,
-		DeactivatedAt: td.DeactivatedAt,
-		RequestCount:  td.RequestCount,
-		MaxRequests:   td.MaxRequests,
-		CreatedAt:     td.CreatedAt,
-		LastUsedAt:    td.LastUsedAt,
-		CacheHitCount: td.CacheHitCount,
+		ID:                 td.ID,
+		Token:              td.Token,
+		ProjectID:          td.ProjectID,
+		ExpiresAt:          td.ExpiresAt,
+		IsActive:           td.IsActive,
+		DeactivatedAt:      td.DeactivatedAt,
+		RequestCount:       td.RequestCount,
+		MaxRequests:        td.MaxRequests,
+		CreatedAt:          td.CreatedAt,
+		LastUsedAt:         td.LastUsedAt,
+		CacheHitCount:      td.CacheHitCount,
+		Scopes:             td.Scopes,
+		Metadata:           td.Metadata,
+		ClientRestrictions: td.ClientRestrictions,
+		RateLimit:          td.RateLimit,
+		RotatedFromID:      td.RotatedFromID,
+		RotatedToID:        td.RotatedToID,
 	}
 }
 
 func ExportTokenData(t Token) token.TokenData {
 	return token.TokenData{
-		ID:            t.ID,
-		Token:         t.Token,
-		ProjectID:     t.ProjectID,
-		ExpiresAt:     t.ExpiresAt,
-		IsActive:      t.IsActive,
-		DeactivatedAt: t.DeactivatedAt,
-		RequestCount:  t.RequestCount,
-		MaxRequests:   t.MaxRequests,
-		CreatedAt:     t.CreatedAt,
-		LastUsedAt:    t.LastUsedAt,
-		CacheHitCount: t.CacheHitCount,
+		ID:                 t.ID,
+		Token:              t.Token,
+		ProjectID:          t.ProjectID,
+		ExpiresAt:          t.ExpiresAt,
+		IsActive:           t.IsActive,
+		DeactivatedAt:      t.DeactivatedAt,
+		RequestCount:       t.RequestCount,
+		MaxRequests:        t.MaxRequests,
+		CreatedAt:          t.CreatedAt,
+		LastUsedAt:         t.LastUsedAt,
+		CacheHitCount:      t.CacheHitCount,
+		Scopes:             t.Scopes,
+		Metadata:           t.Metadata,
+		ClientRestrictions: t.ClientRestrictions,
+		RateLimit:          t.RateLimit,
+		RotatedFromID:      t.RotatedFromID,
+		RotatedToID:        t.RotatedToID,
 	}
 }
 
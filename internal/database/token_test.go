@@ -470,6 +470,45 @@ func TestIncrementTokenUsage_EnforcesMaxRequests(t *testing.T) {
 	require.Equal(t, 3, got.RequestCount)
 }
 
+func TestIncrementTokenUsageByID(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	project := proxy.Project{
+		ID:        "proj-quota-2",
+		Name:      "Quota By ID Test",
+		APIKey:    "test-api-key",
+		IsActive:  true,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	require.NoError(t, db.CreateProject(ctx, project))
+
+	maxRequests := 2
+	tk := Token{
+		Token:        "token-quota-2",
+		ProjectID:    project.ID,
+		IsActive:     true,
+		RequestCount: 1,
+		MaxRequests:  &maxRequests,
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+	require.NoError(t, db.CreateToken(ctx, tk))
+	created, err := db.GetTokenByToken(ctx, tk.Token)
+	require.NoError(t, err)
+
+	require.NoError(t, db.IncrementTokenUsageByID(ctx, created.ID))
+	require.ErrorIs(t, db.IncrementTokenUsageByID(ctx, created.ID), token.ErrTokenRateLimit)
+	got, err := db.GetTokenByID(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, got.RequestCount)
+
+	require.ErrorIs(t, db.IncrementTokenUsageByID(ctx, "non-existent"), ErrTokenNotFound)
+	require.Error(t, db.IncrementTokenUsageByID(ctx, ""))
+}
+
 func TestGetTokenByID_NotFound(t *testing.T) {
 	db, cleanup := testDB(t)
 	defer cleanup()
@@ -506,6 +545,177 @@ func TestListTokens_Multiple(t *testing.T) {
 	}
 }
 
+func TestTokenScopes(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	project := proxy.Project{ID: "p", Name: "P", APIKey: "k", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.CreateProject(ctx, project))
+
+	scopes := token.Scopes{
+		Endpoints: []string{"/v1/chat/completions"},
+		Methods:   []string{"POST"},
+		Models:    []string{"gpt-4o-mini", "claude-3-5-*"},
+	}
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "scoped", Token: "tk-scoped", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now(), Scopes: scopes}))
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "plain", Token: "tk-plain", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now()}))
+
+	got, err := db.GetTokenByToken(ctx, "tk-scoped")
+	require.NoError(t, err)
+	require.Equal(t, scopes, got.Scopes)
+
+	got, err = db.GetTokenByID(ctx, "plain")
+	require.NoError(t, err)
+	require.True(t, got.Scopes.IsEmpty())
+
+	// Updates keep the scopes
+	got, err = db.GetTokenByID(ctx, "scoped")
+	require.NoError(t, err)
+	got.IsActive = false
+	require.NoError(t, db.UpdateToken(ctx, got))
+
+	tokens, err := db.GetTokensByProjectID(ctx, project.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	for _, tk := range tokens {
+		if tk.ID == "scoped" {
+			require.Equal(t, scopes, tk.Scopes)
+		}
+	}
+}
+
+func TestTokenMetadata(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	project := proxy.Project{ID: "p", Name: "P", APIKey: "k", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.CreateProject(ctx, project))
+
+	metadata := map[string]string{"user_id": "42", "client_ip": "203.0.113.7"}
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "bound", Token: "tk-bound", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now(), Metadata: metadata}))
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "plain", Token: "tk-plain", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now()}))
+
+	got, err := db.GetTokenByToken(ctx, "tk-bound")
+	require.NoError(t, err)
+	require.Equal(t, metadata, got.Metadata)
+
+	got, err = db.GetTokenByID(ctx, "plain")
+	require.NoError(t, err)
+	require.Empty(t, got.Metadata)
+
+	// Updates replace the metadata
+	got.Metadata = map[string]string{"user_id": "7"}
+	require.NoError(t, db.UpdateToken(ctx, got))
+	got, err = db.GetTokenByID(ctx, "plain")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"user_id": "7"}, got.Metadata)
+
+	tokens, err := db.ListTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	for _, tk := range tokens {
+		if tk.ID == "bound" {
+			require.Equal(t, metadata, tk.Metadata)
+		}
+	}
+}
+
+func TestTokenClientRestrictions(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	project := proxy.Project{ID: "p", Name: "P", APIKey: "k", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.CreateProject(ctx, project))
+
+	restrictions := token.ClientRestrictions{CIDRs: []string{"203.0.113.0/24"}, Origins: []string{"https://*.example.com"}}
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "restricted", Token: "tk-restricted", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now(), ClientRestrictions: restrictions}))
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "plain", Token: "tk-plain", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now()}))
+
+	got, err := db.GetTokenByToken(ctx, "tk-restricted")
+	require.NoError(t, err)
+	require.Equal(t, restrictions, got.ClientRestrictions)
+
+	got, err = db.GetTokenByID(ctx, "plain")
+	require.NoError(t, err)
+	require.True(t, got.ClientRestrictions.IsEmpty())
+
+	tokens, err := db.ListTokens(ctx)
+	require.NoError(t, err)
+	for _, tk := range tokens {
+		if tk.ID == "restricted" {
+			require.Equal(t, restrictions, tk.ClientRestrictions)
+		}
+	}
+}
+
+func TestTokenRateLimit(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	project := proxy.Project{ID: "p", Name: "P", APIKey: "k", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.CreateProject(ctx, project))
+
+	limit := token.RateLimit{RequestsPerWindow: 30, WindowSeconds: 60, Burst: 3}
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "limited", Token: "tk-limited", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now(), RateLimit: limit}))
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "plain", Token: "tk-plain", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now()}))
+
+	got, err := db.GetTokenByToken(ctx, "tk-limited")
+	require.NoError(t, err)
+	require.Equal(t, limit, got.RateLimit)
+
+	plain, err := db.GetTokenByID(ctx, "plain")
+	require.NoError(t, err)
+	require.True(t, plain.RateLimit.IsZero())
+
+	plain.RateLimit = token.RateLimit{RequestsPerWindow: 5, WindowSeconds: 1}
+	require.NoError(t, db.UpdateToken(ctx, plain))
+	tokens, err := db.GetTokensByProjectID(ctx, project.ID)
+	require.NoError(t, err)
+	for _, tk := range tokens {
+		if tk.ID == "plain" {
+			require.Equal(t, plain.RateLimit, tk.RateLimit)
+		}
+	}
+
+	got.RateLimit = token.RateLimit{}
+	require.NoError(t, db.UpdateToken(ctx, got))
+	got, err = db.GetTokenByID(ctx, "limited")
+	require.NoError(t, err)
+	require.True(t, got.RateLimit.IsZero())
+}
+
+func TestTokenRotationLinks(t *testing.T) {
+	db, cleanup := testDB(t)
+	defer cleanup()
+	ctx := context.Background()
+	project := proxy.Project{ID: "p", Name: "P", APIKey: "k", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, db.CreateProject(ctx, project))
+
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "old", Token: "tk-old", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now()}))
+	require.NoError(t, db.CreateToken(ctx, Token{ID: "new", Token: "tk-new", ProjectID: project.ID, IsActive: true, CreatedAt: time.Now(), RotatedFromID: "old"}))
+
+	old, err := db.GetTokenByID(ctx, "old")
+	require.NoError(t, err)
+	require.Empty(t, old.RotatedFromID)
+	require.Empty(t, old.RotatedToID)
+
+	old.RotatedToID = "new"
+	require.NoError(t, db.UpdateToken(ctx, old))
+	old, err = db.GetTokenByToken(ctx, "tk-old")
+	require.NoError(t, err)
+	require.Equal(t, "new", old.RotatedToID)
+
+	tokens, err := db.ListTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	for _, tk := range tokens {
+		if tk.ID == "new" {
+			require.Equal(t, "old", tk.RotatedFromID)
+			require.Empty(t, tk.RotatedToID)
+		}
+	}
+}
+
 func TestUpdateToken_InvalidInput(t *testing.T) {
 	db, cleanup := testDB(t)
 	defer cleanup()
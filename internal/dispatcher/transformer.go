@@ -189,6 +189,31 @@ func (t *DefaultEventTransformer) Transform(evt eventbus.Event) (*EventPayload,
 			"request_id":  evt.RequestID,
 		},
 	}
+	if evt.Provider != "" {
+		payload.Metadata["provider"] = evt.Provider
+	}
+	if evt.ModelRoute != "" {
+		payload.Metadata["model_route"] = evt.ModelRoute
+	}
+	if evt.UpstreamKeyID != "" {
+		payload.Metadata["upstream_key_id"] = evt.UpstreamKeyID
+	}
+	if len(evt.Attempts) > 0 {
+		attempts := make([]map[string]any, 0, len(evt.Attempts))
+		for _, a := range evt.Attempts {
+			attempt := map[string]any{
+				"provider":    a.Provider,
+				"model":       a.Model,
+				"status":      a.Status,
+				"duration_ms": a.Duration.Milliseconds(),
+			}
+			if a.Error != "" {
+				attempt["error"] = a.Error
+			}
+			attempts = append(attempts, attempt)
+		}
+		payload.Metadata["attempts"] = attempts
+	}
 
 	// Add request body as input (JSON or base64)
 	if len(evt.RequestBody) > 0 {
@@ -199,6 +224,14 @@ func (t *DefaultEventTransformer) Transform(evt eventbus.Event) (*EventPayload,
 		}
 	}
 
+	// --- Anthropic Messages output transformation (JSON or merged SSE) ---
+	if output, usage, ok := anthropicOutput(evt.ResponseBody); ok {
+		payload.Output = output
+		payload.TokensUsage = usage
+		t.addVerboseHeaders(payload, evt)
+		return payload, nil
+	}
+
 	// --- OpenAI-specific output transformation ---
 	isOpenAI := strings.HasPrefix(evt.Path, "/v1/completions") ||
 		strings.HasPrefix(evt.Path, "/v1/chat/completions") ||
@@ -238,7 +271,13 @@ func (t *DefaultEventTransformer) Transform(evt eventbus.Event) (*EventPayload,
 		}
 	}
 
-	// Add response headers to metadata only if Verbose is true
+	t.addVerboseHeaders(payload, evt)
+
+	return payload, nil
+}
+
+// addVerboseHeaders adds response headers to metadata when Verbose is true.
+func (t *DefaultEventTransformer) addVerboseHeaders(payload *EventPayload, evt eventbus.Event) {
 	if t.Verbose && evt.ResponseHeaders != nil {
 		headers := make(map[string]any)
 		for k, v := range evt.ResponseHeaders {
@@ -250,6 +289,30 @@ func (t *DefaultEventTransformer) Transform(evt eventbus.Event) (*EventPayload,
 		}
 		payload.Metadata["response_headers"] = headers
 	}
+}
 
-	return payload, nil
+// anthropicOutput recognizes Anthropic Messages API responses, merging
+// content_block_delta SSE streams into a single message, and extracts
+// usage.input_tokens/output_tokens.
+func anthropicOutput(body []byte) (json.RawMessage, *TokensUsage, bool) {
+	if len(body) == 0 {
+		return nil, nil, false
+	}
+	var msg map[string]any
+	if str := string(body); eventtransformer.IsAnthropicStreaming(str) {
+		msg, _ = eventtransformer.MergeAnthropicStreamingChunks(str)
+	} else if json.Unmarshal(body, &msg) != nil || msg["type"] != "message" {
+		return nil, nil, false
+	}
+	js, err := json.Marshal(msg)
+	if err != nil {
+		return nil, nil, false
+	}
+	var tokens *TokensUsage
+	if usage, ok := msg["usage"].(map[string]any); ok {
+		if in, out, ok := eventtransformer.AnthropicUsage(usage); ok {
+			tokens = &TokensUsage{Prompt: in, Completion: out}
+		}
+	}
+	return js, tokens, true
 }
@@ -93,3 +93,160 @@ func TestDefaultEventTransformer_Transform_VerboseHeaders(t *testing.T) {
 		t.Fatalf("expected multi-value header preserved, got %T %v", headers["X-Custom"], headers["X-Custom"])
 	}
 }
+
+func TestDefaultEventTransformer_Transform_UpstreamKeyID(t *testing.T) {
+	tr := NewDefaultEventTransformer(false)
+	evt := eventbus.Event{
+		RequestID:     "id",
+		Method:        "POST",
+		Path:          "/debug/echo",
+		Status:        200,
+		RequestBody:   []byte(`{"x":1}`),
+		ResponseBody:  []byte(`{"choices":[]}`),
+		UpstreamKeyID: "key_0123456789ab",
+	}
+	payload, err := tr.Transform(evt)
+	if err != nil {
+		t.Fatalf("Transform err: %v", err)
+	}
+	if got := payload.Metadata["upstream_key_id"]; got != "key_0123456789ab" {
+		t.Fatalf("upstream_key_id = %v", got)
+	}
+
+	evt.UpstreamKeyID = ""
+	payload, err = tr.Transform(evt)
+	if err != nil {
+		t.Fatalf("Transform err: %v", err)
+	}
+	if _, ok := payload.Metadata["upstream_key_id"]; ok {
+		t.Fatalf("upstream_key_id should be omitted when unset")
+	}
+}
+
+func TestDefaultEventTransformer_Transform_Anthropic(t *testing.T) {
+	tr := NewDefaultEventTransformer(false)
+	stream := "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":12,"output_tokens":1}}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}` + "\n\n" +
+		"event: content_block_delta\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there"}}` + "\n\n" +
+		"event: message_delta\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}` + "\n\n"
+
+	tests := []struct {
+		name       string
+		body       string
+		wantText   string
+		wantPrompt int
+		wantCompl  int
+	}{
+		{name: "streaming", body: stream, wantText: "Hi there", wantPrompt: 12, wantCompl: 7},
+		{
+			name:       "json",
+			body:       `{"id":"msg_2","type":"message","content":[{"type":"text","text":"Hello"}],"usage":{"input_tokens":3,"output_tokens":5}}`,
+			wantText:   "Hello",
+			wantPrompt: 3,
+			wantCompl:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := tr.Transform(eventbus.Event{
+				Method:       "POST",
+				Path:         "/v1/messages",
+				Status:       200,
+				Provider:     "anthropic",
+				RequestBody:  []byte(`{"model":"claude","messages":[]}`),
+				ResponseBody: []byte(tt.body),
+			})
+			if err != nil {
+				t.Fatalf("Transform err: %v", err)
+			}
+			if payload.TokensUsage == nil || payload.TokensUsage.Prompt != tt.wantPrompt || payload.TokensUsage.Completion != tt.wantCompl {
+				t.Fatalf("TokensUsage = %+v, want %d/%d", payload.TokensUsage, tt.wantPrompt, tt.wantCompl)
+			}
+			if payload.Metadata["provider"] != "anthropic" {
+				t.Fatalf("provider metadata = %v", payload.Metadata["provider"])
+			}
+			var out struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			}
+			if err := json.Unmarshal(payload.Output, &out); err != nil {
+				t.Fatalf("output not JSON: %v", err)
+			}
+			if len(out.Content) != 1 || out.Content[0].Text != tt.wantText {
+				t.Fatalf("output content = %+v, want %q", out.Content, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestDefaultEventTransformer_Transform_ModelRoute(t *testing.T) {
+	tr := NewDefaultEventTransformer(false)
+	evt := eventbus.Event{
+		RequestID:    "id",
+		Method:       "POST",
+		Path:         "/v1/chat/completions",
+		Status:       200,
+		ResponseBody: []byte(`{"choices":[]}`),
+		Provider:     "claude",
+		ModelRoute:   "claude-*",
+	}
+	payload, err := tr.Transform(evt)
+	if err != nil {
+		t.Fatalf("Transform err: %v", err)
+	}
+	if got := payload.Metadata["model_route"]; got != "claude-*" {
+		t.Fatalf("model_route = %v", got)
+	}
+
+	evt.ModelRoute = ""
+	payload, err = tr.Transform(evt)
+	if err != nil {
+		t.Fatalf("Transform err: %v", err)
+	}
+	if _, ok := payload.Metadata["model_route"]; ok {
+		t.Fatalf("model_route should be omitted when unset")
+	}
+}
+
+func TestDefaultEventTransformer_Transform_Attempts(t *testing.T) {
+	tr := NewDefaultEventTransformer(false)
+	evt := eventbus.Event{
+		RequestID:    "id",
+		Method:       "POST",
+		Path:         "/v1/chat/completions",
+		Status:       200,
+		ResponseBody: []byte(`{"choices":[]}`),
+		Attempts: []eventbus.UpstreamAttempt{
+			{Provider: "openai", Model: "gpt-4o", Status: 503, Duration: 120 * time.Millisecond},
+			{Provider: "azure", Model: "gpt-4o", Status: 200, Duration: 80 * time.Millisecond},
+		},
+	}
+	payload, err := tr.Transform(evt)
+	if err != nil {
+		t.Fatalf("Transform err: %v", err)
+	}
+	attempts, ok := payload.Metadata["attempts"].([]map[string]any)
+	if !ok || len(attempts) != 2 {
+		t.Fatalf("attempts = %#v", payload.Metadata["attempts"])
+	}
+	if attempts[0]["status"] != 503 || attempts[1]["provider"] != "azure" || attempts[0]["duration_ms"] != int64(120) {
+		t.Fatalf("attempts = %#v", attempts)
+	}
+	if _, ok := attempts[0]["error"]; ok {
+		t.Fatalf("error should be omitted when unset")
+	}
+
+	evt.Attempts = nil
+	payload, err = tr.Transform(evt)
+	if err != nil {
+		t.Fatalf("Transform err: %v", err)
+	}
+	if _, ok := payload.Metadata["attempts"]; ok {
+		t.Fatalf("attempts should be omitted when unset")
+	}
+}
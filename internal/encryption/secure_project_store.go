@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/sofatutor/llm-proxy/internal/token"
 )
 
 // SecureProjectStore wraps a ProjectStore and encrypts/decrypts API keys.
@@ -27,9 +28,9 @@ func NewSecureProjectStore(store proxy.ProjectStore, encryptor FieldEncryptor) *
 	}
 }
 
-// GetAPIKeyForProject retrieves and decrypts the API key for a project.
-func (s *SecureProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
-	encryptedKey, err := s.store.GetAPIKeyForProject(ctx, projectID)
+// GetAPIKeyForProject retrieves and decrypts the API key for a project and provider.
+func (s *SecureProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
+	encryptedKey, err := s.store.GetAPIKeyForProject(ctx, projectID, provider)
 	if err != nil {
 		return "", err
 	}
@@ -43,11 +44,40 @@ func (s *SecureProjectStore) GetAPIKeyForProject(ctx context.Context, projectID
 	return decryptedKey, nil
 }
 
+// GetAPIKeyPoolForProject retrieves and decrypts the key pool of a project for provider.
+func (s *SecureProjectStore) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]proxy.APIKeyPoolEntry, error) {
+	pool, err := proxy.GetAPIKeyPoolForProject(ctx, s.store, projectID, provider)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptAPIKeyPool(projectID, pool)
+}
+
 // GetProjectActive returns whether a project is active.
 func (s *SecureProjectStore) GetProjectActive(ctx context.Context, projectID string) (bool, error) {
 	return s.store.GetProjectActive(ctx, projectID)
 }
 
+// GetRequestPolicyForProject returns the project's request policy, which is not encrypted.
+func (s *SecureProjectStore) GetRequestPolicyForProject(ctx context.Context, projectID string) ([]proxy.RequestPolicyRule, error) {
+	return proxy.GetRequestPolicyForProject(ctx, s.store, projectID)
+}
+
+// GetAllowedModelsForProject returns the project's model allowlist, which is not encrypted.
+func (s *SecureProjectStore) GetAllowedModelsForProject(ctx context.Context, projectID string) ([]string, error) {
+	return proxy.GetAllowedModelsForProject(ctx, s.store, projectID)
+}
+
+// GetClientRestrictionsForProject returns the project's client restrictions, which are not encrypted.
+func (s *SecureProjectStore) GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	return proxy.GetClientRestrictionsForProject(ctx, s.store, projectID)
+}
+
+// GetRateLimitForProject returns the project's rate limit, which is not encrypted.
+func (s *SecureProjectStore) GetRateLimitForProject(ctx context.Context, projectID string) (token.RateLimit, error) {
+	return proxy.GetRateLimitForProject(ctx, s.store, projectID)
+}
+
 // ListProjects retrieves all projects and decrypts their API keys.
 func (s *SecureProjectStore) ListProjects(ctx context.Context) ([]proxy.Project, error) {
 	projects, err := s.store.ListProjects(ctx)
@@ -62,6 +92,9 @@ func (s *SecureProjectStore) ListProjects(ctx context.Context) ([]proxy.Project,
 			return nil, fmt.Errorf("failed to decrypt API key for project %s: %w", projects[i].ID, err)
 		}
 		projects[i].APIKey = decryptedKey
+		if err := s.decryptAPIKeys(&projects[i]); err != nil {
+			return nil, err
+		}
 	}
 
 	return projects, nil
@@ -76,6 +109,9 @@ func (s *SecureProjectStore) CreateProject(ctx context.Context, project proxy.Pr
 	}
 
 	project.APIKey = encryptedKey
+	if err := s.encryptAPIKeys(&project); err != nil {
+		return err
+	}
 	return s.store.CreateProject(ctx, project)
 }
 
@@ -93,6 +129,9 @@ func (s *SecureProjectStore) GetProjectByID(ctx context.Context, projectID strin
 	}
 
 	project.APIKey = decryptedKey
+	if err := s.decryptAPIKeys(&project); err != nil {
+		return proxy.Project{}, err
+	}
 	return project, nil
 }
 
@@ -106,6 +145,9 @@ func (s *SecureProjectStore) UpdateProject(ctx context.Context, project proxy.Pr
 		}
 		project.APIKey = encryptedKey
 	}
+	if err := s.encryptAPIKeys(&project); err != nil {
+		return err
+	}
 
 	return s.store.UpdateProject(ctx, project)
 }
@@ -115,5 +157,81 @@ func (s *SecureProjectStore) DeleteProject(ctx context.Context, projectID string
 	return s.store.DeleteProject(ctx, projectID)
 }
 
+// encryptAPIKeys encrypts each per-provider and pooled key separately, skipping
+// values that are already encrypted. The map and pool are copied so the caller's
+// project is not mutated.
+func (s *SecureProjectStore) encryptAPIKeys(project *proxy.Project) error {
+	if len(project.APIKeyPool) > 0 {
+		pool := make([]proxy.APIKeyPoolEntry, len(project.APIKeyPool))
+		for i, entry := range project.APIKeyPool {
+			if !IsEncrypted(entry.APIKey) {
+				encryptedKey, err := s.encryptor.Encrypt(entry.APIKey)
+				if err != nil {
+					return fmt.Errorf("failed to encrypt pooled API key for provider %s: %w", entry.Provider, err)
+				}
+				entry.APIKey = encryptedKey
+			}
+			pool[i] = entry
+		}
+		project.APIKeyPool = pool
+	}
+	if len(project.APIKeys) == 0 {
+		return nil
+	}
+	encrypted := make(map[string]string, len(project.APIKeys))
+	for provider, key := range project.APIKeys {
+		if IsEncrypted(key) {
+			encrypted[provider] = key
+			continue
+		}
+		encryptedKey, err := s.encryptor.Encrypt(key)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt API key for provider %s: %w", provider, err)
+		}
+		encrypted[provider] = encryptedKey
+	}
+	project.APIKeys = encrypted
+	return nil
+}
+
+// decryptAPIKeys decrypts each per-provider and pooled key into a fresh map and pool.
+func (s *SecureProjectStore) decryptAPIKeys(project *proxy.Project) error {
+	pool, err := s.decryptAPIKeyPool(project.ID, project.APIKeyPool)
+	if err != nil {
+		return err
+	}
+	project.APIKeyPool = pool
+	if len(project.APIKeys) == 0 {
+		return nil
+	}
+	decrypted := make(map[string]string, len(project.APIKeys))
+	for provider, key := range project.APIKeys {
+		decryptedKey, err := s.encryptor.Decrypt(key)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt API key for provider %s of project %s: %w", provider, project.ID, err)
+		}
+		decrypted[provider] = decryptedKey
+	}
+	project.APIKeys = decrypted
+	return nil
+}
+
+// decryptAPIKeyPool decrypts the keys of pool into a fresh slice.
+func (s *SecureProjectStore) decryptAPIKeyPool(projectID string, pool []proxy.APIKeyPoolEntry) ([]proxy.APIKeyPoolEntry, error) {
+	if len(pool) == 0 {
+		return pool, nil
+	}
+	decrypted := make([]proxy.APIKeyPoolEntry, len(pool))
+	for i, entry := range pool {
+		decryptedKey, err := s.encryptor.Decrypt(entry.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt pooled API key for provider %s of project %s: %w", entry.Provider, projectID, err)
+		}
+		entry.APIKey = decryptedKey
+		decrypted[i] = entry
+	}
+	return decrypted, nil
+}
+
 // Compile-time interface check
 var _ proxy.ProjectStore = (*SecureProjectStore)(nil)
@@ -27,7 +27,7 @@ func newMockProjectStore() *mockProjectStore {
 	}
 }
 
-func (m *mockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (m *mockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	if m.getAPIKeyError != nil {
 		return "", m.getAPIKeyError
 	}
@@ -35,9 +35,26 @@ func (m *mockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID st
 	if !ok {
 		return "", errors.New("project not found")
 	}
+	if provider != "" {
+		key, ok := p.APIKeys[provider]
+		if !ok {
+			return "", proxy.ErrProviderAPIKeyNotFound
+		}
+		return key, nil
+	}
 	return p.APIKey, nil
 }
 
+func (m *mockProjectStore) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]proxy.APIKeyPoolEntry, error) {
+	var pool []proxy.APIKeyPoolEntry
+	for _, entry := range m.projects[projectID].APIKeyPool {
+		if entry.Provider == provider {
+			pool = append(pool, entry)
+		}
+	}
+	return pool, nil
+}
+
 func (m *mockProjectStore) GetProjectActive(ctx context.Context, projectID string) (bool, error) {
 	p, ok := m.projects[projectID]
 	if !ok {
@@ -177,7 +194,7 @@ func TestSecureProjectStore_CreateAndGetProject(t *testing.T) {
 	}
 
 	// GetAPIKeyForProject should also decrypt
-	apiKey, err := store.GetAPIKeyForProject(ctx, "proj-1")
+	apiKey, err := store.GetAPIKeyForProject(ctx, "proj-1", "")
 	if err != nil {
 		t.Fatalf("GetAPIKeyForProject failed: %v", err)
 	}
@@ -347,7 +364,7 @@ func TestSecureProjectStore_ErrorHandling(t *testing.T) {
 		mock.getAPIKeyError = errors.New("db error")
 		store := NewSecureProjectStore(mock, enc)
 
-		_, err := store.GetAPIKeyForProject(ctx, "proj-1")
+		_, err := store.GetAPIKeyForProject(ctx, "proj-1", "")
 		if err == nil {
 			t.Error("expected error, got nil")
 		}
@@ -438,7 +455,7 @@ func TestSecureProjectStore_BackwardCompatibility(t *testing.T) {
 	}
 
 	// GetAPIKeyForProject should also handle unencrypted data
-	apiKey, err := store.GetAPIKeyForProject(ctx, "proj-legacy")
+	apiKey, err := store.GetAPIKeyForProject(ctx, "proj-legacy", "")
 	if err != nil {
 		t.Fatalf("GetAPIKeyForProject failed: %v", err)
 	}
@@ -497,3 +514,113 @@ func TestSecureProjectStore_UpdateAlreadyEncrypted(t *testing.T) {
 		t.Errorf("GetProjectByID returned wrong API key: got %q, want %q", retrieved.APIKey, originalAPIKey)
 	}
 }
+
+func TestSecureProjectStore_ProviderAPIKeys(t *testing.T) {
+	key, _ := GenerateKey()
+	enc, _ := NewEncryptor(key)
+	mock := newMockProjectStore()
+	store := NewSecureProjectStore(mock, enc)
+	ctx := context.Background()
+
+	project := proxy.Project{
+		ID:      "proj-1",
+		Name:    "multi",
+		APIKey:  "sk-legacy",
+		APIKeys: map[string]string{"openai": "sk-openai", "anthropic": "sk-ant"},
+	}
+	if err := store.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if project.APIKeys["anthropic"] != "sk-ant" {
+		t.Error("CreateProject must not mutate the caller's key map")
+	}
+
+	stored := mock.projects["proj-1"]
+	if stored.APIKeys["openai"] == stored.APIKeys["anthropic"] {
+		t.Error("expected distinct ciphertexts per provider")
+	}
+	for provider, v := range stored.APIKeys {
+		if !IsEncrypted(v) {
+			t.Errorf("provider %s key stored unencrypted: %q", provider, v)
+		}
+	}
+
+	got, err := store.GetAPIKeyForProject(ctx, "proj-1", "anthropic")
+	if err != nil || got != "sk-ant" {
+		t.Fatalf("GetAPIKeyForProject(anthropic) = %q, %v", got, err)
+	}
+	if _, err := store.GetAPIKeyForProject(ctx, "proj-1", "gemini"); !errors.Is(err, proxy.ErrProviderAPIKeyNotFound) {
+		t.Errorf("expected ErrProviderAPIKeyNotFound, got %v", err)
+	}
+
+	fetched, err := store.GetProjectByID(ctx, "proj-1")
+	if err != nil {
+		t.Fatalf("GetProjectByID failed: %v", err)
+	}
+	if fetched.APIKeys["openai"] != "sk-openai" || fetched.APIKeys["anthropic"] != "sk-ant" {
+		t.Errorf("unexpected decrypted keys: %v", fetched.APIKeys)
+	}
+
+	// Rotating one key leaves already-encrypted values untouched.
+	before := mock.projects["proj-1"].APIKeys["openai"]
+	fetched.APIKeys["anthropic"] = "sk-ant-rotated"
+	fetched.APIKeys["openai"] = before
+	if err := store.UpdateProject(ctx, fetched); err != nil {
+		t.Fatalf("UpdateProject failed: %v", err)
+	}
+	if mock.projects["proj-1"].APIKeys["openai"] != before {
+		t.Error("expected already-encrypted key to be stored as-is")
+	}
+	projects, err := store.ListProjects(ctx)
+	if err != nil {
+		t.Fatalf("ListProjects failed: %v", err)
+	}
+	if projects[0].APIKeys["anthropic"] != "sk-ant-rotated" {
+		t.Errorf("expected rotated key, got %q", projects[0].APIKeys["anthropic"])
+	}
+}
+
+func TestSecureProjectStore_APIKeyPool(t *testing.T) {
+	key, _ := GenerateKey()
+	enc, _ := NewEncryptor(key)
+	mock := newMockProjectStore()
+	store := NewSecureProjectStore(mock, enc)
+	ctx := context.Background()
+
+	project := proxy.Project{
+		ID:     "proj-1",
+		Name:   "pool",
+		APIKey: "sk-legacy",
+		APIKeyPool: []proxy.APIKeyPoolEntry{
+			{Provider: "openai", APIKey: "sk-a", Weight: 3},
+			{Provider: "openai", APIKey: "sk-b"},
+		},
+	}
+	if err := store.CreateProject(ctx, project); err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if project.APIKeyPool[0].APIKey != "sk-a" {
+		t.Error("CreateProject must not mutate the caller's pool")
+	}
+	for _, entry := range mock.projects["proj-1"].APIKeyPool {
+		if !IsEncrypted(entry.APIKey) {
+			t.Errorf("pooled key stored unencrypted: %q", entry.APIKey)
+		}
+	}
+
+	pool, err := store.GetAPIKeyPoolForProject(ctx, "proj-1", "openai")
+	if err != nil {
+		t.Fatalf("GetAPIKeyPoolForProject failed: %v", err)
+	}
+	if len(pool) != 2 || pool[0].APIKey != "sk-a" || pool[0].Weight != 3 || pool[1].APIKey != "sk-b" {
+		t.Errorf("unexpected decrypted pool: %+v", pool)
+	}
+
+	fetched, err := store.GetProjectByID(ctx, "proj-1")
+	if err != nil {
+		t.Fatalf("GetProjectByID failed: %v", err)
+	}
+	if fetched.APIKeyPool[1].APIKey != "sk-b" {
+		t.Errorf("unexpected decrypted project pool: %+v", fetched.APIKeyPool)
+	}
+}
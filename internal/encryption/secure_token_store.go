@@ -47,6 +47,15 @@ func (s *SecureTokenStore) IncrementTokenUsage(ctx context.Context, tokenString
 	return s.store.IncrementTokenUsage(ctx, hashedToken)
 }
 
+// IncrementTokenUsageByID increments the usage count for a token by its ID,
+// which is not hashed. Stores that cannot count by ID are left unchanged.
+func (s *SecureTokenStore) IncrementTokenUsageByID(ctx context.Context, id string) error {
+	if us, ok := s.store.(token.UsageByIDStore); ok {
+		return us.IncrementTokenUsageByID(ctx, id)
+	}
+	return nil
+}
+
 // CreateToken creates a new token in the store.
 // The token value is hashed before storage.
 func (s *SecureTokenStore) CreateToken(ctx context.Context, td token.TokenData) error {
@@ -93,7 +102,10 @@ func (s *SecureTokenStore) GetTokensByProjectID(ctx context.Context, projectID s
 }
 
 // Compile-time interface check
-var _ token.TokenStore = (*SecureTokenStore)(nil)
+var (
+	_ token.TokenStore     = (*SecureTokenStore)(nil)
+	_ token.UsageByIDStore = (*SecureTokenStore)(nil)
+)
 
 // SecureRevocationStore wraps a RevocationStore and hashes tokens before operations.
 type SecureRevocationStore struct {
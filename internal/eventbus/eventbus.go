@@ -19,6 +19,23 @@ type Event struct {
 	ResponseHeaders http.Header
 	ResponseBody    []byte
 	RequestBody     []byte
+	// Provider is the configured API provider name that handled the request.
+	Provider string
+	// ModelRoute is the model routing pattern that selected Provider, if any.
+	ModelRoute string
+	// UpstreamKeyID is the fingerprint of the upstream API key that served the request.
+	UpstreamKeyID string
+	// Attempts lists the upstream attempts when a fallback chain applied.
+	Attempts []UpstreamAttempt
+}
+
+// UpstreamAttempt describes one try of a request against an upstream target.
+type UpstreamAttempt struct {
+	Provider string
+	Model    string
+	Status   int
+	Error    string
+	Duration time.Duration
 }
 
 // EventBus is a simple interface for publishing events to subscribers.
@@ -0,0 +1,216 @@
+package eventtransformer
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// AnthropicTransformer implements Transformer for Anthropic Messages API events.
+type AnthropicTransformer struct{}
+
+// IsAnthropicStreaming detects if the response body is an Anthropic Messages
+// SSE stream (message_start ... message_stop events).
+func IsAnthropicStreaming(body string) bool {
+	return strings.Contains(body, "event: message_start") ||
+		strings.Contains(body, `"type":"message_start"`)
+}
+
+// MergeAnthropicStreamingChunks merges an Anthropic Messages SSE stream into a
+// single Messages API response object. Text from content_block_delta events is
+// concatenated per block; tool_use input is reassembled from input_json_delta
+// fragments. Usage combines input_tokens from message_start with the final
+// output_tokens from message_delta.
+func MergeAnthropicStreamingChunks(body string) (map[string]any, error) {
+	type block struct {
+		fields map[string]any
+		text   strings.Builder
+		json   strings.Builder
+	}
+	var (
+		message map[string]any
+		blocks  []*block
+		byIndex = map[int]*block{}
+		usage   = map[string]any{}
+	)
+	blockAt := func(idx int) *block {
+		b, ok := byIndex[idx]
+		if !ok {
+			b = &block{fields: map[string]any{"type": "text"}}
+			byIndex[idx] = b
+			blocks = append(blocks, b)
+		}
+		return b
+	}
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var chunk map[string]any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &chunk); err != nil {
+			continue
+		}
+		idx := 0
+		if v, ok := chunk["index"].(float64); ok {
+			idx = int(v)
+		}
+		switch chunk["type"] {
+		case "message_start":
+			if m, ok := chunk["message"].(map[string]any); ok {
+				message = m
+				if u, ok := m["usage"].(map[string]any); ok {
+					for k, v := range u {
+						usage[k] = v
+					}
+				}
+			}
+		case "content_block_start":
+			b := blockAt(idx)
+			if cb, ok := chunk["content_block"].(map[string]any); ok {
+				for k, v := range cb {
+					b.fields[k] = v
+				}
+				if t, ok := cb["text"].(string); ok {
+					b.text.WriteString(t)
+				}
+			}
+		case "content_block_delta":
+			b := blockAt(idx)
+			delta, _ := chunk["delta"].(map[string]any)
+			switch delta["type"] {
+			case "text_delta":
+				if t, ok := delta["text"].(string); ok {
+					b.text.WriteString(t)
+				}
+			case "thinking_delta":
+				if t, ok := delta["thinking"].(string); ok {
+					b.text.WriteString(t)
+				}
+			case "input_json_delta":
+				if t, ok := delta["partial_json"].(string); ok {
+					b.json.WriteString(t)
+				}
+			}
+		case "message_delta":
+			if message == nil {
+				message = map[string]any{}
+			}
+			if delta, ok := chunk["delta"].(map[string]any); ok {
+				for _, k := range []string{"stop_reason", "stop_sequence"} {
+					if v, ok := delta[k]; ok {
+						message[k] = v
+					}
+				}
+			}
+			if u, ok := chunk["usage"].(map[string]any); ok {
+				for k, v := range u {
+					usage[k] = v
+				}
+			}
+		}
+	}
+	if message == nil {
+		message = map[string]any{"type": "message", "role": "assistant"}
+	}
+	content := make([]map[string]any, 0, len(blocks))
+	for _, b := range blocks {
+		out := b.fields
+		switch out["type"] {
+		case "tool_use":
+			if raw := b.json.String(); raw != "" {
+				var input any
+				if err := json.Unmarshal([]byte(raw), &input); err == nil {
+					out["input"] = input
+				} else {
+					out["input"] = raw
+				}
+			}
+		case "thinking":
+			out["thinking"] = b.text.String()
+		default:
+			out["text"] = b.text.String()
+		}
+		content = append(content, out)
+	}
+	message["content"] = content
+	if len(usage) > 0 {
+		message["usage"] = usage
+	}
+	return message, nil
+}
+
+// AnthropicUsage returns input and output token counts from a Messages API
+// usage object; ok is false when neither is present.
+func AnthropicUsage(usage map[string]any) (input, output int, ok bool) {
+	in, hasIn := usage["input_tokens"].(float64)
+	out, hasOut := usage["output_tokens"].(float64)
+	return int(in), int(out), hasIn || hasOut
+}
+
+// TransformEvent transforms an Anthropic Messages event for logging/analytics.
+// It mirrors OpenAITransformer: OPTIONS skipping, header filtering, decoding,
+// SSE merging and usage extraction, followed by snake_case normalization.
+func (t *AnthropicTransformer) TransformEvent(evt map[string]any) (map[string]any, error) {
+	if method, _ := evt["Method"].(string); strings.ToUpper(method) == "OPTIONS" {
+		return nil, nil
+	}
+
+	hdrMap, _ := evt["ResponseHeaders"].(map[string]any)
+	if hdrMap == nil {
+		hdrMap = map[string]any{}
+	}
+	for k := range hdrMap {
+		if strings.ToLower(k) == "set-cookie" {
+			delete(hdrMap, k)
+		}
+	}
+
+	requestID, _ := evt["RequestID"].(string)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+	evt["request_id"] = requestID
+
+	if v, ok := evt["RequestBody"].(string); ok && v != "" {
+		decoded := tryBase64DecodeWithLog(v)
+		if compact, _, ok := normalizeToCompactJSON(decoded); ok {
+			evt["request_body"] = compact
+		} else if isValidUTF8(decoded) {
+			evt["request_body"] = decoded
+		} else {
+			evt["request_body"] = "[binary or undecodable data]"
+		}
+	}
+
+	if respBody, ok := evt["ResponseBody"].(string); ok && respBody != "" {
+		decoded, okDecoded := DecompressAndDecode(respBody, hdrMap)
+		if !okDecoded {
+			decoded = tryBase64DecodeWithLog(respBody)
+		}
+		var respObj map[string]any
+		if IsAnthropicStreaming(decoded) {
+			respObj, _ = MergeAnthropicStreamingChunks(decoded)
+		} else if compact, _, ok := normalizeToCompactJSON(decoded); ok {
+			_ = json.Unmarshal([]byte(compact), &respObj)
+			evt["response_body"] = compact
+		} else if isValidUTF8(decoded) {
+			evt["response_body"] = decoded
+		} else {
+			evt["response_body"] = "[binary or undecodable data]"
+		}
+		if respObj != nil {
+			if usage, ok := respObj["usage"].(map[string]any); ok {
+				evt["TokenUsage"] = usage
+				delete(respObj, "usage")
+			}
+			b, _ := json.Marshal(respObj)
+			evt["response_body"] = string(b)
+		}
+	}
+
+	delete(evt, "RequestBody")
+	delete(evt, "ResponseBody")
+	return ToSnakeCaseMap(evt), nil
+}
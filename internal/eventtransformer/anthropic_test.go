@@ -0,0 +1,149 @@
+package eventtransformer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+const anthropicStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"stop_reason":null,"usage":{"input_tokens":25,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"tu_1","name":"lookup","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"q\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"x\"}"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":15}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+
+func TestIsAnthropicStreaming(t *testing.T) {
+	if !IsAnthropicStreaming(anthropicStream) {
+		t.Error("expected Anthropic stream to be detected")
+	}
+	if IsAnthropicStreaming("data: {\"choices\":[]}\ndata: {\"choices\":[]}\n") {
+		t.Error("OpenAI chunks must not be detected as Anthropic stream")
+	}
+}
+
+func TestMergeAnthropicStreamingChunks(t *testing.T) {
+	merged, err := MergeAnthropicStreamingChunks(anthropicStream)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged["id"] != "msg_1" || merged["model"] != "claude-sonnet-4-5" {
+		t.Errorf("message fields not preserved: %v", merged)
+	}
+	if merged["stop_reason"] != "tool_use" {
+		t.Errorf("stop_reason = %v, want tool_use", merged["stop_reason"])
+	}
+	content := merged["content"].([]map[string]any)
+	if len(content) != 2 {
+		t.Fatalf("expected 2 content blocks, got %d", len(content))
+	}
+	if content[0]["text"] != "Hello world" {
+		t.Errorf("text = %q, want %q", content[0]["text"], "Hello world")
+	}
+	input, ok := content[1]["input"].(map[string]any)
+	if !ok || input["q"] != "x" {
+		t.Errorf("tool_use input = %v, want {q:x}", content[1]["input"])
+	}
+	in, out, ok := AnthropicUsage(merged["usage"].(map[string]any))
+	if !ok || in != 25 || out != 15 {
+		t.Errorf("usage = %d/%d (%v), want 25/15", in, out, ok)
+	}
+}
+
+func TestMergeAnthropicStreamingChunks_DeltasWithoutStart(t *testing.T) {
+	merged, err := MergeAnthropicStreamingChunks("data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"hi\"}}\ndata: not-json\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content := merged["content"].([]map[string]any)
+	if len(content) != 1 || content[0]["text"] != "hi" {
+		t.Errorf("unexpected content: %v", content)
+	}
+	if _, ok := merged["usage"]; ok {
+		t.Error("usage should be omitted when absent")
+	}
+}
+
+func TestAnthropicUsage(t *testing.T) {
+	if _, _, ok := AnthropicUsage(map[string]any{"prompt_tokens": 1.0}); ok {
+		t.Error("expected ok=false for non-Anthropic usage")
+	}
+	in, out, ok := AnthropicUsage(map[string]any{"input_tokens": 3.0})
+	if !ok || in != 3 || out != 0 {
+		t.Errorf("got %d/%d/%v", in, out, ok)
+	}
+}
+
+func TestAnthropicTransformer_TransformEvent(t *testing.T) {
+	tr := &AnthropicTransformer{}
+
+	out, err := tr.TransformEvent(map[string]any{"Method": "OPTIONS"})
+	if err != nil || out != nil {
+		t.Fatalf("OPTIONS should be skipped, got %v, %v", out, err)
+	}
+
+	evt := map[string]any{
+		"Method":          "POST",
+		"RequestID":       "req-1",
+		"RequestBody":     base64.StdEncoding.EncodeToString([]byte(`{"model":"claude","messages":[]}`)),
+		"ResponseBody":    base64.StdEncoding.EncodeToString([]byte(anthropicStream)),
+		"ResponseHeaders": map[string]any{"Content-Type": []any{"text/event-stream"}, "Set-Cookie": []any{"a=b"}},
+	}
+	out, err = tr.TransformEvent(evt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out["request_id"] != "req-1" {
+		t.Errorf("request_id = %v", out["request_id"])
+	}
+	usage, ok := out["token_usage"].(map[string]any)
+	if !ok || usage["input_tokens"] != 25.0 || usage["output_tokens"] != 15.0 {
+		t.Errorf("token_usage = %v", out["token_usage"])
+	}
+	var body map[string]any
+	if err := json.Unmarshal([]byte(out["response_body"].(string)), &body); err != nil {
+		t.Fatalf("response_body not JSON: %v", err)
+	}
+	if _, ok := body["usage"]; ok {
+		t.Error("usage should be moved out of response_body")
+	}
+	if hdrs := out["response_headers"].(map[string]any); hdrs["Set-Cookie"] != nil {
+		t.Error("Set-Cookie should be filtered")
+	}
+
+	// Non-streaming JSON response
+	out, err = tr.TransformEvent(map[string]any{
+		"Method":       "POST",
+		"ResponseBody": base64.StdEncoding.EncodeToString([]byte(`{"type":"message","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":4,"output_tokens":2}}`)),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if usage, ok := out["token_usage"].(map[string]any); !ok || usage["output_tokens"] != 2.0 {
+		t.Errorf("token_usage = %v", out["token_usage"])
+	}
+}
@@ -13,8 +13,8 @@ func DispatchTransformer(provider string) Transformer {
 	switch provider {
 	case "openai":
 		return &OpenAITransformer{}
-	// case "anthropic":
-	// 	return &AnthropicTransformer{}
+	case "anthropic":
+		return &AnthropicTransformer{}
 	default:
 		return nil
 	}
@@ -7,6 +7,9 @@ func TestDispatchTransformer(t *testing.T) {
 	if tr == nil {
 		t.Error("DispatchTransformer(openai) = nil, want OpenAITransformer")
 	}
+	if _, ok := DispatchTransformer("anthropic").(*AnthropicTransformer); !ok {
+		t.Error("DispatchTransformer(anthropic) is not an AnthropicTransformer")
+	}
 	tr = DispatchTransformer("unknown")
 	if tr != nil {
 		t.Error("DispatchTransformer(unknown) != nil, want nil")
@@ -0,0 +1,274 @@
+package eventtransformer
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Usage is the token usage of a single completed request.
+type Usage struct {
+	Model             string // Model named in the response, empty if it names none
+	InputTokens       int    // Prompt tokens, including CachedInputTokens
+	OutputTokens      int    // Completion tokens
+	CachedInputTokens int    // Prompt tokens served from the provider's prompt cache
+	Estimated         bool   // Counted with tiktoken because the response had no usage block
+}
+
+// ExtractUsage returns the token usage of a request from its response body,
+// which may be a JSON response or an SSE stream from the OpenAI, Anthropic or
+// Gemini APIs. Responses without a usage block are estimated with tiktoken
+// from the request's prompt and the assistant reply; ok is false when there is
+// nothing to count.
+func ExtractUsage(requestBody, responseBody []byte) (Usage, bool) {
+	if len(responseBody) == 0 {
+		return Usage{}, false
+	}
+	body := string(responseBody)
+
+	var (
+		usage map[string]any
+		model string
+		reply string
+	)
+	var resp map[string]any
+	if json.Unmarshal(responseBody, &resp) == nil {
+		usage, model = responseUsage(resp)
+		if usage == nil {
+			reply = replyText(responseBody)
+		}
+	} else {
+		usage, model = streamUsage(body)
+		if usage == nil {
+			switch {
+			case IsAnthropicStreaming(body):
+				reply = mergedReplyText(MergeAnthropicStreamingChunks(body))
+			case IsOpenAIStreaming(body):
+				reply = mergedReplyText(MergeOpenAIStreamingChunks(body))
+			}
+		}
+	}
+
+	if usage != nil {
+		if u, ok := usageCounts(usage); ok {
+			u.Model = model
+			return u, true
+		}
+	}
+	return estimateUsage(requestBody, reply, model)
+}
+
+// responseUsage returns the usage object and model of a response or stream
+// event. Anthropic message_start and OpenAI Responses API events nest them
+// under "message" and "response".
+func responseUsage(obj map[string]any) (map[string]any, string) {
+	model, _ := obj["model"].(string)
+	if model == "" {
+		model, _ = obj["modelVersion"].(string)
+	}
+	if u, ok := obj["usage"].(map[string]any); ok {
+		return u, model
+	}
+	if u, ok := obj["usageMetadata"].(map[string]any); ok {
+		return u, model
+	}
+	for _, key := range []string{"message", "response"} {
+		if nested, ok := obj[key].(map[string]any); ok {
+			if u, m := responseUsage(nested); u != nil || m != "" {
+				if model == "" {
+					model = m
+				}
+				return u, model
+			}
+		}
+	}
+	return nil, model
+}
+
+// streamUsage merges the usage objects of all events in an SSE stream. Later
+// events win, so Anthropic's final output_tokens replace the placeholder from
+// message_start.
+func streamUsage(body string) (map[string]any, string) {
+	var (
+		usage map[string]any
+		model string
+	)
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var chunk map[string]any
+		if json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &chunk) != nil {
+			continue
+		}
+		u, m := responseUsage(chunk)
+		if model == "" {
+			model = m
+		}
+		if u == nil {
+			continue
+		}
+		if usage == nil {
+			usage = map[string]any{}
+		}
+		for k, v := range u {
+			usage[k] = v
+		}
+	}
+	return usage, model
+}
+
+// usageCounts reads token counts from an OpenAI, OpenAI Responses API,
+// Anthropic or Gemini usage object.
+func usageCounts(usage map[string]any) (Usage, bool) {
+	num := func(m map[string]any, key string) (int, bool) {
+		v, ok := m[key].(float64)
+		return int(v), ok
+	}
+	nested := func(key, field string) int {
+		m, _ := usage[key].(map[string]any)
+		n, _ := num(m, field)
+		return n
+	}
+
+	var u Usage
+	if in, hasIn := num(usage, "prompt_tokens"); hasIn || usage["completion_tokens"] != nil {
+		// OpenAI chat completions, completions and embeddings
+		u.InputTokens = in
+		u.OutputTokens, _ = num(usage, "completion_tokens")
+		u.CachedInputTokens = nested("prompt_tokens_details", "cached_tokens")
+		return u, true
+	}
+	if in, hasIn := num(usage, "promptTokenCount"); hasIn || usage["candidatesTokenCount"] != nil {
+		// Gemini; thinking tokens are billed as output
+		u.InputTokens = in
+		out, _ := num(usage, "candidatesTokenCount")
+		thoughts, _ := num(usage, "thoughtsTokenCount")
+		u.OutputTokens = out + thoughts
+		u.CachedInputTokens, _ = num(usage, "cachedContentTokenCount")
+		return u, true
+	}
+	in, out, ok := AnthropicUsage(usage)
+	if !ok {
+		return Usage{}, false
+	}
+	u.OutputTokens = out
+	if _, ok := usage["input_tokens_details"]; ok {
+		// OpenAI Responses API counts cached tokens as part of input_tokens
+		u.InputTokens = in
+		u.CachedInputTokens = nested("input_tokens_details", "cached_tokens")
+		return u, true
+	}
+	// Anthropic reports cache reads and writes separately from input_tokens
+	cacheRead, _ := num(usage, "cache_read_input_tokens")
+	cacheWrite, _ := num(usage, "cache_creation_input_tokens")
+	u.InputTokens = in + cacheRead + cacheWrite
+	u.CachedInputTokens = cacheRead
+	return u, true
+}
+
+// replyText returns the assistant text of an OpenAI chat completion or an
+// Anthropic message.
+func replyText(resp []byte) string {
+	if text, _ := extractAssistantReplyContent(string(resp)); text != "" {
+		return text
+	}
+	var msg struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	if json.Unmarshal(resp, &msg) != nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, block := range msg.Content {
+		text.WriteString(block.Text)
+	}
+	return text.String()
+}
+
+// mergedReplyText returns the assistant text of a merged stream.
+func mergedReplyText(merged map[string]any, err error) string {
+	if err != nil {
+		return ""
+	}
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return ""
+	}
+	return replyText(b)
+}
+
+// estimateUsage counts the request's prompt and the reply with tiktoken.
+func estimateUsage(requestBody []byte, reply, model string) (Usage, bool) {
+	var req map[string]any
+	_ = json.Unmarshal(requestBody, &req)
+	if requestModel, ok := req["model"].(string); ok && model == "" {
+		model = requestModel
+	}
+
+	prompt := promptText(req)
+	if prompt == "" && reply == "" {
+		return Usage{}, false
+	}
+
+	u := Usage{Model: model, Estimated: true}
+	var err error
+	if prompt != "" {
+		if u.InputTokens, err = CountOpenAITokensForModel(prompt, model); err != nil {
+			return Usage{}, false
+		}
+	}
+	if reply != "" {
+		if u.OutputTokens, err = CountOpenAITokensForModel(reply, model); err != nil {
+			return Usage{}, false
+		}
+	}
+	return u, true
+}
+
+// promptText concatenates the prompt fields of a decoded request body.
+func promptText(req map[string]any) string {
+	var prompt strings.Builder
+	for _, key := range []string{"system", "instructions", "messages", "input", "prompt"} {
+		switch v := req[key].(type) {
+		case nil:
+		case string:
+			prompt.WriteString(v)
+		default:
+			if b, err := json.Marshal(v); err == nil {
+				prompt.Write(b)
+			}
+		}
+	}
+	return prompt.String()
+}
+
+// EstimateRequestTokens estimates the tokens a request may use before it is
+// sent: its prompt counted with tiktoken plus the output tokens it allows
+// (max_tokens, max_completion_tokens or max_output_tokens). Prompts are
+// estimated at four characters per token when no encoding is available.
+func EstimateRequestTokens(requestBody []byte) int {
+	var req map[string]any
+	if err := json.Unmarshal(requestBody, &req); err != nil {
+		return 0
+	}
+	model, _ := req["model"].(string)
+
+	var tokens int
+	if prompt := promptText(req); prompt != "" {
+		n, err := CountOpenAITokensForModel(prompt, model)
+		if err != nil {
+			n = (len(prompt) + 3) / 4
+		}
+		tokens = n
+	}
+	for _, key := range []string{"max_tokens", "max_completion_tokens", "max_output_tokens"} {
+		if v, ok := req[key].(float64); ok && v > 0 {
+			tokens += int(v)
+			break
+		}
+	}
+	return tokens
+}
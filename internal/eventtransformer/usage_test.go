@@ -0,0 +1,114 @@
+package eventtransformer
+
+import (
+	"testing"
+)
+
+func TestExtractUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Usage
+	}{
+		{
+			name: "openai chat completion",
+			body: `{"model":"gpt-4o-mini-2024-07-18","choices":[],"usage":{"prompt_tokens":120,"completion_tokens":30,"total_tokens":150,"prompt_tokens_details":{"cached_tokens":64}}}`,
+			want: Usage{Model: "gpt-4o-mini-2024-07-18", InputTokens: 120, OutputTokens: 30, CachedInputTokens: 64},
+		},
+		{
+			name: "openai embeddings",
+			body: `{"model":"text-embedding-3-small","data":[],"usage":{"prompt_tokens":8,"total_tokens":8}}`,
+			want: Usage{Model: "text-embedding-3-small", InputTokens: 8},
+		},
+		{
+			name: "openai stream with usage chunk",
+			body: "data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{\"content\":\"Hi\"}}],\"usage\":null}\n\n" +
+				"data: {\"model\":\"gpt-4o\",\"choices\":[],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":2}}\n\n" +
+				"data: [DONE]\n\n",
+			want: Usage{Model: "gpt-4o", InputTokens: 10, OutputTokens: 2},
+		},
+		{
+			name: "openai responses api",
+			body: `{"object":"response","model":"gpt-4.1","usage":{"input_tokens":50,"input_tokens_details":{"cached_tokens":20},"output_tokens":5}}`,
+			want: Usage{Model: "gpt-4.1", InputTokens: 50, OutputTokens: 5, CachedInputTokens: 20},
+		},
+		{
+			name: "anthropic message",
+			body: `{"type":"message","model":"claude-3-5-haiku-20241022","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":10,"cache_read_input_tokens":100,"cache_creation_input_tokens":5,"output_tokens":7}}`,
+			want: Usage{Model: "claude-3-5-haiku-20241022", InputTokens: 115, OutputTokens: 7, CachedInputTokens: 100},
+		},
+		{
+			name: "anthropic stream",
+			body: "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-sonnet-4\",\"usage\":{\"input_tokens\":25,\"cache_read_input_tokens\":10,\"output_tokens\":1}}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n" +
+				"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":15}}\n\n" +
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+			want: Usage{Model: "claude-sonnet-4", InputTokens: 35, OutputTokens: 15, CachedInputTokens: 10},
+		},
+		{
+			name: "gemini",
+			body: `{"candidates":[],"modelVersion":"gemini-2.0-flash","usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":4,"thoughtsTokenCount":3,"cachedContentTokenCount":6}}`,
+			want: Usage{Model: "gemini-2.0-flash", InputTokens: 12, OutputTokens: 7, CachedInputTokens: 6},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractUsage(nil, []byte(tt.body))
+			if !ok {
+				t.Fatal("ExtractUsage() ok = false")
+			}
+			if got != tt.want {
+				t.Errorf("ExtractUsage() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractUsage_Estimated(t *testing.T) {
+	if _, err := CountOpenAITokens("probe"); err != nil {
+		if isNetworkError(err) {
+			t.Skipf("Skipping test due to network connectivity issue: %v", err)
+		}
+		t.Fatalf("CountOpenAITokens() error = %v", err)
+	}
+
+	req := []byte(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Say hello"}]}`)
+	got, ok := ExtractUsage(req, []byte(`{"choices":[{"message":{"role":"assistant","content":"Hello there!"}}]}`))
+	if !ok {
+		t.Fatal("ExtractUsage() ok = false")
+	}
+	if !got.Estimated || got.Model != "gpt-4o-mini" || got.InputTokens == 0 || got.OutputTokens == 0 {
+		t.Errorf("ExtractUsage() = %+v, want estimated input and output tokens for gpt-4o-mini", got)
+	}
+
+	stream := "data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n" +
+		"data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{\"content\":\" there\"}}]}\n\ndata: [DONE]\n\n"
+	got, ok = ExtractUsage(req, []byte(stream))
+	if !ok || !got.Estimated || got.Model != "gpt-4o" || got.OutputTokens == 0 {
+		t.Errorf("ExtractUsage() = %+v, %v, want estimated output tokens for gpt-4o", got, ok)
+	}
+}
+
+func TestExtractUsage_NothingToCount(t *testing.T) {
+	for _, body := range []string{"", `{"object":"list","data":[]}`, "not json"} {
+		if got, ok := ExtractUsage(nil, []byte(body)); ok {
+			t.Errorf("ExtractUsage(%q) = %+v, want ok = false", body, got)
+		}
+	}
+}
+
+func TestEstimateRequestTokens(t *testing.T) {
+	if got := EstimateRequestTokens([]byte(`{"model":"gpt-4o-mini","max_tokens":100}`)); got != 100 {
+		t.Errorf("EstimateRequestTokens() = %d, want 100 for max_tokens alone", got)
+	}
+	// Counted with tiktoken, or estimated from the length without an encoding
+	got := EstimateRequestTokens([]byte(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"Say hello"}],"max_completion_tokens":50}`))
+	if got <= 50 {
+		t.Errorf("EstimateRequestTokens() = %d, want prompt tokens plus 50", got)
+	}
+	for _, body := range []string{"", "not json", `{"model":"gpt-4o-mini"}`} {
+		if got := EstimateRequestTokens([]byte(body)); got != 0 {
+			t.Errorf("EstimateRequestTokens(%q) = %d, want 0", body, got)
+		}
+	}
+}
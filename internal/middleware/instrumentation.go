@@ -9,6 +9,7 @@ import (
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sofatutor/llm-proxy/internal/eventbus"
@@ -29,6 +30,36 @@ type ObservabilityConfig struct {
 	MaxResponseBodyBytes int64
 }
 
+// eventAnnotationsKey is the context key for per-request event annotations.
+type eventAnnotationsKey struct{}
+
+// eventAnnotations collects mutations that inner handlers contribute to the
+// observability event of the current request.
+type eventAnnotations struct {
+	mu  sync.Mutex
+	fns []func(*eventbus.Event)
+}
+
+// AnnotateEvent registers fn to be applied to the observability event published
+// for the request carrying ctx. It is a no-op when observability is disabled.
+func AnnotateEvent(ctx context.Context, fn func(*eventbus.Event)) {
+	ann, ok := ctx.Value(eventAnnotationsKey{}).(*eventAnnotations)
+	if !ok || ann == nil {
+		return
+	}
+	ann.mu.Lock()
+	ann.fns = append(ann.fns, fn)
+	ann.mu.Unlock()
+}
+
+func (a *eventAnnotations) apply(evt *eventbus.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, fn := range a.fns {
+		fn(evt)
+	}
+}
+
 // ObservabilityMiddleware captures request/response data and forwards it to an event bus.
 type ObservabilityMiddleware struct {
 	cfg    ObservabilityConfig
@@ -89,7 +120,8 @@ func (m *ObservabilityMiddleware) Middleware() Middleware {
 				}
 			}
 
-			next.ServeHTTP(crw, r)
+			ann := &eventAnnotations{}
+			next.ServeHTTP(crw, r.WithContext(context.WithValue(r.Context(), eventAnnotationsKey{}, ann)))
 
 			// Resolve request ID from header, then context, then response headers
 			reqID := r.Header.Get("X-Request-ID")
@@ -118,6 +150,7 @@ func (m *ObservabilityMiddleware) Middleware() Middleware {
 				ResponseBody:    crw.body.Bytes(),
 				RequestBody:     reqBody,
 			}
+			ann.apply(&evt)
 
 			// Publish is non-blocking; avoid spawning a goroutine per request.
 			// Any heavy transformations (e.g., OpenAI metadata extraction) should happen in downstream consumers.
@@ -459,3 +459,32 @@ func TestObservabilityMiddleware_CacheHitSkipsPublish(t *testing.T) {
 		})
 	}
 }
+
+func TestObservabilityMiddleware_AnnotateEvent(t *testing.T) {
+	bus := eventbus.NewInMemoryEventBus(10)
+	mw := NewObservabilityMiddleware(ObservabilityConfig{Enabled: true, EventBus: bus}, nil)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AnnotateEvent(r.Context(), func(evt *eventbus.Event) {
+			evt.UpstreamKeyID = "key_abc"
+		})
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	rr := httptest.NewRecorder()
+	ch := bus.Subscribe()
+	mw.Middleware()(handler).ServeHTTP(rr, req)
+
+	select {
+	case evt := <-ch:
+		require.Equal(t, "key_abc", evt.UpstreamKeyID)
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	// Outside the middleware AnnotateEvent is a no-op.
+	require.NotPanics(t, func() {
+		AnnotateEvent(httptest.NewRequest(http.MethodGet, "/", nil).Context(), func(*eventbus.Event) {})
+	})
+}
@@ -0,0 +1,163 @@
+// Package pricing keeps the per-model price catalog used to put a cost on
+// proxied requests.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Price is the price of a model in USD per 1,000 tokens. Model is an exact
+// model name or a glob pattern such as "gpt-4o-mini*".
+type Price struct {
+	Model       string  `json:"model" yaml:"model"`
+	InputPer1K  float64 `json:"input_per_1k" yaml:"input_per_1k"`
+	OutputPer1K float64 `json:"output_per_1k" yaml:"output_per_1k"`
+	// CachedInputPer1K is the price of input tokens served from the provider's
+	// prompt cache; nil bills them at InputPer1K
+	CachedInputPer1K *float64 `json:"cached_input_per_1k,omitempty" yaml:"cached_input_per_1k,omitempty"`
+}
+
+// Validate checks that the price has a valid model pattern and no negative prices.
+func (p Price) Validate() error {
+	if strings.TrimSpace(p.Model) == "" {
+		return errors.New("model cannot be empty")
+	}
+	if _, err := path.Match(p.Model, ""); err != nil {
+		return fmt.Errorf("model pattern '%s' is invalid: %w", p.Model, err)
+	}
+	if p.InputPer1K < 0 || p.OutputPer1K < 0 || (p.CachedInputPer1K != nil && *p.CachedInputPer1K < 0) {
+		return fmt.Errorf("prices for model '%s' cannot be negative", p.Model)
+	}
+	return nil
+}
+
+// Cost returns the cost in USD of a request with the given token counts.
+// cachedInput tokens are part of input and billed at the cached input price.
+func (p Price) Cost(input, output, cachedInput int) float64 {
+	cachedInput = min(max(cachedInput, 0), input)
+	cachedPrice := p.InputPer1K
+	if p.CachedInputPer1K != nil {
+		cachedPrice = *p.CachedInputPer1K
+	}
+	return (float64(input-cachedInput)*p.InputPer1K + float64(cachedInput)*cachedPrice + float64(output)*p.OutputPer1K) / 1000
+}
+
+// Validate checks a list of prices, rejecting invalid entries and duplicate models.
+func Validate(prices []Price) error {
+	seen := make(map[string]bool, len(prices))
+	for _, p := range prices {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if seen[p.Model] {
+			return fmt.Errorf("duplicate price for model '%s'", p.Model)
+		}
+		seen[p.Model] = true
+	}
+	return nil
+}
+
+// catalogFile is the layout of a price catalog YAML file
+type catalogFile struct {
+	Models []Price `yaml:"models"`
+}
+
+// LoadFile reads prices from a YAML file of the form
+//
+//	models:
+//	  - model: gpt-4o-mini*
+//	    input_per_1k: 0.00015
+//	    output_per_1k: 0.0006
+//	    cached_input_per_1k: 0.000075
+func LoadFile(filePath string) ([]Price, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price catalog: %w", err)
+	}
+	var file catalogFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse price catalog: %w", err)
+	}
+	if err := Validate(file.Models); err != nil {
+		return nil, fmt.Errorf("invalid price catalog %s: %w", filePath, err)
+	}
+	return file.Models, nil
+}
+
+// Catalog is a concurrency-safe set of model prices.
+type Catalog struct {
+	mu     sync.RWMutex
+	prices map[string]Price
+}
+
+// NewCatalog creates a catalog holding prices. Prices are expected to be valid.
+func NewCatalog(prices []Price) *Catalog {
+	c := &Catalog{}
+	c.set(prices)
+	return c
+}
+
+// SetPrices validates prices and replaces the catalog with them.
+func (c *Catalog) SetPrices(prices []Price) error {
+	if err := Validate(prices); err != nil {
+		return err
+	}
+	c.set(prices)
+	return nil
+}
+
+func (c *Catalog) set(prices []Price) {
+	m := make(map[string]Price, len(prices))
+	for _, p := range prices {
+		m[p.Model] = p
+	}
+	c.mu.Lock()
+	c.prices = m
+	c.mu.Unlock()
+}
+
+// Prices returns the catalog sorted by model.
+func (c *Catalog) Prices() []Price {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	prices := make([]Price, 0, len(c.prices))
+	for _, p := range c.prices {
+		prices = append(prices, p)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Model < prices[j].Model })
+	return prices
+}
+
+// Lookup returns the price for model. An exact entry wins over glob patterns,
+// which are tried longest first.
+func (c *Catalog) Lookup(model string) (Price, bool) {
+	if model == "" {
+		return Price{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if p, ok := c.prices[model]; ok {
+		return p, true
+	}
+	var (
+		best  Price
+		found bool
+	)
+	for pattern, p := range c.prices {
+		if ok, _ := path.Match(pattern, model); !ok {
+			continue
+		}
+		if !found || len(pattern) > len(best.Model) || (len(pattern) == len(best.Model) && pattern < best.Model) {
+			best, found = p, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,132 @@
+package pricing
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func floatPtr(f float64) *float64 { return &f }
+
+func TestPrice_Cost(t *testing.T) {
+	p := Price{Model: "gpt-4o", InputPer1K: 0.0025, OutputPer1K: 0.01, CachedInputPer1K: floatPtr(0.00125)}
+	tests := []struct {
+		name                  string
+		price                 Price
+		input, output, cached int
+		want                  float64
+	}{
+		{"input and output", p, 1000, 500, 0, 0.0025 + 0.005},
+		{"cached input", p, 1000, 0, 400, 0.6*0.0025 + 0.4*0.00125},
+		{"cached input beyond input", p, 100, 0, 500, 0.1 * 0.00125},
+		{"cached at input price without cached price", Price{Model: "m", InputPer1K: 0.002}, 1000, 0, 1000, 0.002},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.price.Cost(tt.input, tt.output, tt.cached); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Cost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prices  []Price
+		wantErr bool
+	}{
+		{"valid", []Price{{Model: "gpt-4o*", InputPer1K: 1}, {Model: "claude-*", OutputPer1K: 1}}, false},
+		{"empty model", []Price{{Model: " "}}, true},
+		{"invalid pattern", []Price{{Model: "gpt-["}}, true},
+		{"negative price", []Price{{Model: "m", InputPer1K: -1}}, true},
+		{"negative cached price", []Price{{Model: "m", CachedInputPer1K: floatPtr(-1)}}, true},
+		{"duplicate model", []Price{{Model: "m"}, {Model: "m"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.prices); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCatalog_Lookup(t *testing.T) {
+	c := NewCatalog([]Price{
+		{Model: "gpt-4o*", InputPer1K: 1},
+		{Model: "gpt-4o-mini*", InputPer1K: 2},
+		{Model: "gpt-4o-mini", InputPer1K: 3},
+	})
+	tests := []struct {
+		model string
+		want  float64
+		found bool
+	}{
+		{"gpt-4o-mini", 3, true},
+		{"gpt-4o-mini-2024-07-18", 2, true},
+		{"gpt-4o-2024-08-06", 1, true},
+		{"claude-3-5-sonnet", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			p, ok := c.Lookup(tt.model)
+			if ok != tt.found || p.InputPer1K != tt.want {
+				t.Errorf("Lookup(%q) = %+v, %v, want input price %v, %v", tt.model, p, ok, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestCatalog_SetPrices(t *testing.T) {
+	c := NewCatalog([]Price{{Model: "a"}})
+	if err := c.SetPrices([]Price{{Model: "c"}, {Model: "b", InputPer1K: 1}}); err != nil {
+		t.Fatalf("SetPrices() error = %v", err)
+	}
+	prices := c.Prices()
+	if len(prices) != 2 || prices[0].Model != "b" || prices[1].Model != "c" {
+		t.Errorf("Prices() = %+v, want b and c sorted by model", prices)
+	}
+	if err := c.SetPrices([]Price{{Model: "x", InputPer1K: -1}}); err == nil {
+		t.Fatal("SetPrices() expected error for invalid prices")
+	}
+	if len(c.Prices()) != 2 {
+		t.Errorf("invalid prices replaced the catalog: %+v", c.Prices())
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "prices.yaml")
+	if err := os.WriteFile(valid, []byte(`models:
+  - model: gpt-4o-mini*
+    input_per_1k: 0.00015
+    output_per_1k: 0.0006
+    cached_input_per_1k: 0.000075
+  - model: claude-3-5-haiku*
+    input_per_1k: 0.0008
+    output_per_1k: 0.004
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	prices, err := LoadFile(valid)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+	if len(prices) != 2 || prices[0].CachedInputPer1K == nil || *prices[0].CachedInputPer1K != 0.000075 || prices[1].CachedInputPer1K != nil {
+		t.Errorf("LoadFile() = %+v", prices)
+	}
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("models:\n  - model: m\n    input_per_1k: -1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(invalid); err == nil {
+		t.Error("LoadFile() expected error for negative price")
+	}
+	if _, err := LoadFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("LoadFile() expected error for missing file")
+	}
+}
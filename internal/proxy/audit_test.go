@@ -170,22 +170,16 @@ func TestGetClientIP(t *testing.T) {
 		expectedIP string
 	}{
 		{
-			name:       "x_forwarded_for_single",
+			name:       "x_forwarded_for_ignored",
 			headers:    map[string]string{"X-Forwarded-For": "192.168.1.100"},
 			remoteAddr: "10.0.0.1:12345",
-			expectedIP: "192.168.1.100",
+			expectedIP: "10.0.0.1",
 		},
 		{
-			name:       "x_forwarded_for_multiple",
-			headers:    map[string]string{"X-Forwarded-For": "192.168.1.100, 10.0.0.1, 203.0.113.1"},
-			remoteAddr: "10.0.0.1:12345",
-			expectedIP: "192.168.1.100",
-		},
-		{
-			name:       "x_real_ip",
+			name:       "x_real_ip_ignored",
 			headers:    map[string]string{"X-Real-IP": "192.168.1.200"},
 			remoteAddr: "10.0.0.1:12345",
-			expectedIP: "192.168.1.200",
+			expectedIP: "10.0.0.1",
 		},
 		{
 			name:       "remote_addr_with_port",
@@ -199,12 +193,6 @@ func TestGetClientIP(t *testing.T) {
 			remoteAddr: "192.168.1.400",
 			expectedIP: "192.168.1.400",
 		},
-		{
-			name:       "x_forwarded_for_precedence",
-			headers:    map[string]string{"X-Forwarded-For": "192.168.1.100", "X-Real-IP": "192.168.1.200"},
-			remoteAddr: "10.0.0.1:12345",
-			expectedIP: "192.168.1.100",
-		},
 	}
 
 	for _, tt := range tests {
@@ -219,4 +207,9 @@ func TestGetClientIP(t *testing.T) {
 			assert.Equal(t, tt.expectedIP, ip)
 		})
 	}
+
+	// The IP resolved by the handler takes precedence
+	req := httptest.NewRequest("GET", "/test", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKeyClientIP, "203.0.113.9"))
+	assert.Equal(t, "203.0.113.9", getClientIP(req))
 }
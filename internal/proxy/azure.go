@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Provider modes (APIProviderConfig.Mode).
+const (
+	ProviderModeAzureOpenAI = "azure_openai"
+)
+
+// defaultAzureAPIVersion is the Azure OpenAI GA api-version used when none is configured.
+const defaultAzureAPIVersion = "2024-10-21"
+
+// azureDeploymentEndpoints are the OpenAI endpoints that Azure serves under
+// /openai/deployments/{deployment}/...; everything else maps to /openai/...
+var azureDeploymentEndpoints = map[string]bool{
+	"/chat/completions":     true,
+	"/completions":          true,
+	"/embeddings":           true,
+	"/images/generations":   true,
+	"/audio/speech":         true,
+	"/audio/transcriptions": true,
+	"/audio/translations":   true,
+}
+
+// deployment resolves the Azure deployment for model: an exact mapping wins,
+// then the first matching glob (in sorted order, for determinism), then the
+// default deployment. Without either, the model name is used as deployment
+// name, which matches Azure's common naming convention.
+func (c AzureOpenAIConfig) deployment(model string) string {
+	if model != "" {
+		if d, ok := c.Deployments[model]; ok {
+			return d
+		}
+		patterns := make([]string, 0, len(c.Deployments))
+		for p := range c.Deployments {
+			patterns = append(patterns, p)
+		}
+		sort.Strings(patterns)
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, model); ok {
+				return c.Deployments[p]
+			}
+		}
+	}
+	if c.DefaultDeployment != "" {
+		return c.DefaultDeployment
+	}
+	return model
+}
+
+func (c AzureOpenAIConfig) apiVersion() string {
+	if c.APIVersion != "" {
+		return c.APIVersion
+	}
+	return defaultAzureAPIVersion
+}
+
+// azureUpstreamPath maps an OpenAI-style request to its Azure OpenAI path. The
+// request body is left untouched so param whitelisting and caching keep using
+// the original model. ok is false when a deployment endpoint cannot be
+// resolved to a deployment.
+func (p *TransparentProxy) azureUpstreamPath(r *http.Request) (upstreamPath string, ok bool) {
+	rest, isV1 := strings.CutPrefix(r.URL.Path, "/v1")
+	if !isV1 || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		return r.URL.Path, true
+	}
+	if !azureDeploymentEndpoints[rest] {
+		return "/openai" + rest, true
+	}
+	deployment := p.config.Azure.deployment(requestModel(r))
+	if deployment == "" {
+		return "", false
+	}
+	return "/openai/deployments/" + deployment + rest, true
+}
+
+// applyAzureRewrite points the outgoing request at the Azure path resolved by
+// the handler and adds the api-version query parameter.
+func (p *TransparentProxy) applyAzureRewrite(req *http.Request) {
+	upstreamPath, ok := req.Context().Value(ctxKeyUpstreamPath).(string)
+	if !ok || upstreamPath == "" {
+		return
+	}
+	req.URL.Path = upstreamPath
+	req.URL.RawPath = ""
+	q := req.URL.Query()
+	if q.Get("api-version") == "" {
+		q.Set("api-version", p.config.Azure.apiVersion())
+		req.URL.RawQuery = q.Encode()
+	}
+}
+
+// requestModel returns the "model" field of a JSON request body, restoring the
+// body for downstream handlers.
+func requestModel(r *http.Request) string {
+	if r.Body == nil || r.Body == http.NoBody || !strings.Contains(r.Header.Get("Content-Type"), "json") {
+		return ""
+	}
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var payload struct {
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return payload.Model
+}
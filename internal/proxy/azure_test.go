@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAzureOpenAIConfig_Deployment(t *testing.T) {
+	cfg := AzureOpenAIConfig{
+		Deployments: map[string]string{
+			"gpt-4o":      "prod-4o",
+			"gpt-4o*":     "prod-4o-family",
+			"text-embed*": "embeddings",
+		},
+	}
+	assert.Equal(t, "prod-4o", cfg.deployment("gpt-4o"), "exact match wins over glob")
+	assert.Equal(t, "prod-4o-family", cfg.deployment("gpt-4o-mini"))
+	assert.Equal(t, "embeddings", cfg.deployment("text-embedding-3-small"))
+	assert.Equal(t, "o4-mini", cfg.deployment("o4-mini"), "unmapped model is used as deployment name")
+	assert.Empty(t, cfg.deployment(""))
+
+	cfg.DefaultDeployment = "fallback"
+	assert.Equal(t, "fallback", cfg.deployment("o4-mini"))
+	assert.Equal(t, "fallback", cfg.deployment(""))
+
+	assert.Equal(t, defaultAzureAPIVersion, cfg.apiVersion())
+	cfg.APIVersion = "2025-01-01-preview"
+	assert.Equal(t, "2025-01-01-preview", cfg.apiVersion())
+}
+
+func TestTransparentProxy_AzureUpstreamPath(t *testing.T) {
+	p := &TransparentProxy{config: ProxyConfig{
+		Mode:  ProviderModeAzureOpenAI,
+		Azure: AzureOpenAIConfig{Deployments: map[string]string{"gpt-4o*": "chat"}},
+	}}
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		ctype    string
+		wantPath string
+		wantOK   bool
+	}{
+		{name: "chat completions", path: "/v1/chat/completions", body: `{"model":"gpt-4o-mini"}`, ctype: "application/json", wantPath: "/openai/deployments/chat/chat/completions", wantOK: true},
+		{name: "embeddings uses model as deployment", path: "/v1/embeddings", body: `{"model":"ada"}`, ctype: "application/json", wantPath: "/openai/deployments/ada/embeddings", wantOK: true},
+		{name: "non-deployment endpoint", path: "/v1/models", wantPath: "/openai/models", wantOK: true},
+		{name: "deployment endpoint without model", path: "/v1/audio/transcriptions", ctype: "multipart/form-data", wantOK: false},
+		{name: "non-v1 path untouched", path: "/openai/deployments/x/chat/completions", wantPath: "/openai/deployments/x/chat/completions", wantOK: true},
+		{name: "v1 prefix of another segment untouched", path: "/v1beta/models", wantPath: "/v1beta/models", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.ctype != "" {
+				req.Header.Set("Content-Type", tt.ctype)
+			}
+			got, ok := p.azureUpstreamPath(req)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.wantPath, got)
+			}
+			body, _ := io.ReadAll(req.Body)
+			assert.Equal(t, tt.body, string(body), "body must be restored")
+		})
+	}
+}
+
+func TestTransparentProxy_ApplyAzureRewrite(t *testing.T) {
+	p := &TransparentProxy{config: ProxyConfig{Mode: ProviderModeAzureOpenAI}}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKeyUpstreamPath, "/openai/deployments/d/chat/completions"))
+	p.applyAzureRewrite(req)
+	assert.Equal(t, "/openai/deployments/d/chat/completions", req.URL.Path)
+	assert.Equal(t, "api-version="+defaultAzureAPIVersion, req.URL.RawQuery)
+
+	// A client-supplied api-version is kept.
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions?api-version=2024-02-01", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKeyUpstreamPath, "/openai/deployments/d/chat/completions"))
+	p.applyAzureRewrite(req)
+	assert.Equal(t, "api-version=2024-02-01", req.URL.RawQuery)
+
+	// Without a resolved path the request is left alone.
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	p.applyAzureRewrite(req)
+	assert.Equal(t, "/v1/chat/completions", req.URL.Path)
+}
+
+func TestTransparentProxy_AzureMode_EndToEnd(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []*http.Request
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Clone(context.Background()))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	apiConfig := &APIConfig{
+		DefaultAPI: "azure",
+		APIs: map[string]*APIProviderConfig{
+			"azure": {
+				BaseURL:          upstream.URL,
+				AllowedEndpoints: []string{"/v1/chat/completions"},
+				AllowedMethods:   []string{http.MethodPost},
+				ParamWhitelist:   map[string][]string{"model": {"gpt-4o*"}},
+				Mode:             ProviderModeAzureOpenAI,
+				Azure: AzureOpenAIConfig{
+					APIVersion:  "2024-10-21",
+					Deployments: map[string]string{"gpt-4o-mini": "mini-prod"},
+				},
+			},
+		},
+	}
+	require.NoError(t, validateAPIConfig(apiConfig))
+	cfg, err := apiConfig.GetProxyConfigForAPI("azure")
+	require.NoError(t, err)
+	cfg.HTTPCacheEnabled = true
+	cfg.HTTPCacheDefaultTTL = time.Minute
+	cfg.HTTPCacheMaxObjectBytes = 1 << 20
+
+	p, err := NewTransparentProxyWithLogger(*cfg, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop())
+	require.NoError(t, err)
+	h := p.Handler()
+
+	send := func(model string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"`+model+`","messages":[]}`))
+		req.Header.Set("Authorization", "Bearer tok")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Cache-Control", "public, max-age=60")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("gpt-4o-mini")
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+
+	mu.Lock()
+	require.Len(t, calls, 1)
+	seen := calls[0]
+	mu.Unlock()
+	assert.Equal(t, "/openai/deployments/mini-prod/chat/completions", seen.URL.Path)
+	assert.Equal(t, "2024-10-21", seen.URL.Query().Get("api-version"))
+	assert.Equal(t, "api-key", seen.Header.Get("api-key"))
+	assert.Empty(t, seen.Header.Get("Authorization"))
+
+	// The cache is keyed on the client request, so the repeat is a hit.
+	second := send("gpt-4o-mini")
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "hit", second.Header().Get("X-PROXY-CACHE"))
+	mu.Lock()
+	assert.Len(t, calls, 1)
+	mu.Unlock()
+
+	// Whitelisting still checks the original model.
+	rejected := send("gpt-3.5-turbo")
+	assert.Equal(t, http.StatusBadRequest, rejected.Code)
+}
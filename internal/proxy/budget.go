@@ -0,0 +1,673 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"go.uber.org/zap"
+)
+
+// BudgetPeriod is the calendar period, in UTC, after which a budget renews.
+type BudgetPeriod string
+
+const (
+	BudgetPeriodDaily   BudgetPeriod = "daily"
+	BudgetPeriodMonthly BudgetPeriod = "monthly"
+)
+
+// Start returns the start of the period containing t.
+func (p BudgetPeriod) Start(t time.Time) time.Time {
+	t = t.UTC()
+	if p == BudgetPeriodDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// End returns the end of the period containing t, when the budget renews.
+func (p BudgetPeriod) End(t time.Time) time.Time {
+	if p == BudgetPeriodDaily {
+		return p.Start(t).AddDate(0, 0, 1)
+	}
+	return p.Start(t).AddDate(0, 1, 0)
+}
+
+// budgetPeriods are the periods spend is counted for.
+var budgetPeriods = []BudgetPeriod{BudgetPeriodDaily, BudgetPeriodMonthly}
+
+// Budget subject types
+const (
+	BudgetSubjectProject = "project"
+	BudgetSubjectToken   = "token"
+)
+
+// Budget caps the spend of a project or token per period. Requests are
+// rejected once either limit is reached.
+type Budget struct {
+	Period      BudgetPeriod `json:"period"`
+	LimitUSD    *float64     `json:"limit_usd,omitempty"`    // Cost limit in USD
+	LimitTokens *int64       `json:"limit_tokens,omitempty"` // Input plus output token limit
+}
+
+// Validate checks that the budget has a known period and a positive limit.
+func (b Budget) Validate() error {
+	if b.Period != BudgetPeriodDaily && b.Period != BudgetPeriodMonthly {
+		return fmt.Errorf("period must be %q or %q", BudgetPeriodDaily, BudgetPeriodMonthly)
+	}
+	if b.LimitUSD == nil && b.LimitTokens == nil {
+		return errors.New("limit_usd or limit_tokens is required")
+	}
+	if b.LimitUSD != nil && !(*b.LimitUSD > 0) {
+		return errors.New("limit_usd must be positive")
+	}
+	if b.LimitTokens != nil && *b.LimitTokens <= 0 {
+		return errors.New("limit_tokens must be positive")
+	}
+	return nil
+}
+
+// Used returns the share of the budget that spend uses up: the larger of the
+// USD and token shares. The budget is exhausted at 1.
+func (b Budget) Used(s Spend) float64 {
+	var used float64
+	if b.LimitUSD != nil {
+		used = s.CostUSD / *b.LimitUSD
+	}
+	if b.LimitTokens != nil {
+		used = math.Max(used, float64(s.Tokens)/float64(*b.LimitTokens))
+	}
+	return used
+}
+
+// Remaining returns what is left of each limit of the budget after spend,
+// never less than zero. Limits the budget does not set are nil.
+func (b Budget) Remaining(s Spend) (usd *float64, tokens *int64) {
+	if b.LimitUSD != nil {
+		left := math.Max(*b.LimitUSD-s.CostUSD, 0)
+		usd = &left
+	}
+	if b.LimitTokens != nil {
+		left := max(*b.LimitTokens-s.Tokens, 0)
+		tokens = &left
+	}
+	return usd, tokens
+}
+
+// Spend is the usage counted against budgets.
+type Spend struct {
+	CostUSD float64 `json:"cost_usd"`
+	Tokens  int64   `json:"tokens"` // Input plus output tokens
+}
+
+// SpendKey identifies the daily spend of a project or token.
+type SpendKey struct {
+	SubjectType string
+	SubjectID   string
+	Day         string // YYYY-MM-DD in UTC
+}
+
+// BudgetStore defines the interface for budgets and their persisted spend.
+type BudgetStore interface {
+	// GetBudget returns the budget of a project or token, or nil when it has none.
+	GetBudget(ctx context.Context, subjectType, subjectID string) (*Budget, error)
+	// GetBudgetSpend returns the spend of a project or token from the day of since on.
+	GetBudgetSpend(ctx context.Context, subjectType, subjectID string, since time.Time) (Spend, error)
+	// AddBudgetSpend adds deltas to daily spend totals.
+	AddBudgetSpend(ctx context.Context, deltas map[SpendKey]Spend) error
+}
+
+// BudgetStatus is a budget with its spend in the current period.
+type BudgetStatus struct {
+	SubjectType     string    `json:"subject_type"`
+	SubjectID       string    `json:"subject_id"`
+	Budget          Budget    `json:"budget"`
+	Spend           Spend     `json:"spend"`
+	RemainingUSD    *float64  `json:"remaining_usd,omitempty"`
+	RemainingTokens *int64    `json:"remaining_tokens,omitempty"`
+	PeriodStart     time.Time `json:"period_start"`
+	ResetAt         time.Time `json:"reset_at"`
+}
+
+// BudgetAlert reports that spend crossed a threshold of a budget. It is
+// recorded as an audit event and posted to the budget webhook.
+type BudgetAlert struct {
+	SubjectType      string    `json:"subject_type"`
+	SubjectID        string    `json:"subject_id"`
+	ProjectID        string    `json:"project_id"`
+	ThresholdPercent int       `json:"threshold_percent"`
+	Budget           Budget    `json:"budget"`
+	Spend            Spend     `json:"spend"`
+	PeriodStart      time.Time `json:"period_start"`
+	ResetAt          time.Time `json:"reset_at"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+// BudgetTrackerConfig holds configuration for the budget tracker.
+type BudgetTrackerConfig struct {
+	BufferSize    int           // Size of the buffered channel (default: 1000)
+	FlushInterval time.Duration // How often to flush spend to DB (default: 5s)
+	BatchSize     int           // Max events before flush (default: 100)
+	CacheTTL      time.Duration // How long budgets are cached (default: 30s)
+	Thresholds    []int         // Alert thresholds in percent (default: 50, 80, 100)
+	WebhookURL    string        // Receives alerts as JSON POSTs when set
+}
+
+// DefaultBudgetTrackerConfig returns the default configuration.
+func DefaultBudgetTrackerConfig() BudgetTrackerConfig {
+	return BudgetTrackerConfig{
+		BufferSize:    1000,
+		FlushInterval: 5 * time.Second,
+		BatchSize:     100,
+		CacheTTL:      30 * time.Second,
+		Thresholds:    []int{50, 80, 100},
+	}
+}
+
+type spendEvent struct {
+	projectID string
+	tokenID   string
+	spend     Spend
+	at        time.Time
+}
+
+type cachedBudget struct {
+	budget    *Budget
+	expiresAt time.Time
+}
+
+// BudgetTracker enforces project and token budgets. Spend is counted in a
+// SpendCounter, which is shared between instances when backed by Redis, and
+// periodically flushed to the database as daily totals. Counters start from
+// the persisted spend; counters local to the instance start from it again
+// after each flush to pick up the spend of other instances. Like the other
+// aggregators it uses a buffered channel for non-blocking enqueue and drops
+// events when the buffer is full.
+type BudgetTracker struct {
+	config      BudgetTrackerConfig
+	store       BudgetStore
+	counter     SpendCounter
+	logger      *zap.Logger
+	auditLogger *audit.Logger
+	httpClient  *http.Client
+	eventsCh    chan spendEvent
+	stopCh      chan struct{}
+	doneCh      chan struct{}
+	mu          sync.RWMutex
+	stopped     bool
+
+	cacheMu sync.Mutex
+	cache   map[string]cachedBudget
+
+	now func() time.Time
+}
+
+// NewBudgetTracker creates a new budget tracker. A nil counter counts spend in
+// memory, per proxy instance.
+func NewBudgetTracker(config BudgetTrackerConfig, store BudgetStore, counter SpendCounter, auditLogger *audit.Logger, logger *zap.Logger) *BudgetTracker {
+	defaults := DefaultBudgetTrackerConfig()
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaults.BufferSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaults.FlushInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = defaults.CacheTTL
+	}
+	if len(config.Thresholds) == 0 {
+		config.Thresholds = defaults.Thresholds
+	}
+	if counter == nil {
+		counter = NewMemorySpendCounter()
+	}
+	if auditLogger == nil {
+		auditLogger = audit.NewNullLogger()
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &BudgetTracker{
+		config:      config,
+		store:       store,
+		counter:     counter,
+		logger:      logger,
+		auditLogger: auditLogger,
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+		eventsCh:    make(chan spendEvent, config.BufferSize),
+		stopCh:      make(chan struct{}),
+		doneCh:      make(chan struct{}),
+		cache:       make(map[string]cachedBudget),
+		now:         time.Now,
+	}
+}
+
+// Start begins the background accounting worker.
+func (t *BudgetTracker) Start() {
+	go t.run()
+}
+
+// Stop gracefully shuts down the tracker, flushing any pending spend.
+func (t *BudgetTracker) Stop(ctx context.Context) error {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return nil
+	}
+	t.stopped = true
+	t.mu.Unlock()
+
+	close(t.stopCh)
+
+	select {
+	case <-t.doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Record enqueues the spend of a request of a project and, when tokenID is
+// set, a token. This is non-blocking; if the buffer is full, the event is
+// dropped.
+func (t *BudgetTracker) Record(projectID, tokenID string, spend Spend) {
+	if projectID == "" {
+		return
+	}
+
+	t.mu.RLock()
+	stopped := t.stopped
+	t.mu.RUnlock()
+	if stopped {
+		return
+	}
+
+	select {
+	case t.eventsCh <- spendEvent{projectID: projectID, tokenID: tokenID, spend: spend, at: t.now()}:
+		// Event enqueued successfully
+	default:
+		// Buffer full, drop the event
+		t.logger.Debug("budget buffer full, dropping event", zap.String("project_id", projectID))
+	}
+}
+
+// Check returns the status of the first exhausted budget of the token or its
+// project, or nil when requests may proceed.
+func (t *BudgetTracker) Check(ctx context.Context, projectID, tokenID string) (*BudgetStatus, error) {
+	for _, subject := range budgetSubjects(projectID, tokenID) {
+		status, err := t.Status(ctx, subject[0], subject[1])
+		if err != nil {
+			return nil, err
+		}
+		if status != nil && status.Budget.Used(status.Spend) >= 1 {
+			return status, nil
+		}
+	}
+	return nil, nil
+}
+
+// Remaining returns what is left of the USD and token limits that apply to a
+// token and its project, taking the smallest of each. Limits no budget sets
+// are nil.
+func (t *BudgetTracker) Remaining(ctx context.Context, projectID, tokenID string) (usd *float64, tokens *int64, err error) {
+	for _, subject := range budgetSubjects(projectID, tokenID) {
+		status, err := t.Status(ctx, subject[0], subject[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		if status == nil {
+			continue
+		}
+		if status.RemainingUSD != nil && (usd == nil || *status.RemainingUSD < *usd) {
+			usd = status.RemainingUSD
+		}
+		if status.RemainingTokens != nil && (tokens == nil || *status.RemainingTokens < *tokens) {
+			tokens = status.RemainingTokens
+		}
+	}
+	return usd, tokens, nil
+}
+
+// Status returns the budget of a project or token with its spend in the
+// current period, or nil when it has no budget.
+func (t *BudgetTracker) Status(ctx context.Context, subjectType, subjectID string) (*BudgetStatus, error) {
+	budget, err := t.budget(ctx, subjectType, subjectID)
+	if err != nil || budget == nil {
+		return nil, err
+	}
+	now := t.now()
+	spend, err := t.spend(ctx, subjectType, subjectID, budget.Period, now)
+	if err != nil {
+		return nil, err
+	}
+	status := &BudgetStatus{
+		SubjectType: subjectType,
+		SubjectID:   subjectID,
+		Budget:      *budget,
+		Spend:       spend,
+		PeriodStart: budget.Period.Start(now),
+		ResetAt:     budget.Period.End(now),
+	}
+	status.RemainingUSD, status.RemainingTokens = budget.Remaining(spend)
+	return status, nil
+}
+
+// Invalidate drops the cached budget of a project or token after it changed.
+func (t *BudgetTracker) Invalidate(subjectType, subjectID string) {
+	t.cacheMu.Lock()
+	defer t.cacheMu.Unlock()
+	delete(t.cache, subjectType+":"+subjectID)
+}
+
+// budgetSubjects lists the token, when set, and the project a request is
+// counted against.
+func budgetSubjects(projectID, tokenID string) [][2]string {
+	subjects := make([][2]string, 0, 2)
+	if tokenID != "" {
+		subjects = append(subjects, [2]string{BudgetSubjectToken, tokenID})
+	}
+	return append(subjects, [2]string{BudgetSubjectProject, projectID})
+}
+
+// budget returns the cached budget of a subject.
+func (t *BudgetTracker) budget(ctx context.Context, subjectType, subjectID string) (*Budget, error) {
+	key := subjectType + ":" + subjectID
+	now := t.now()
+	t.cacheMu.Lock()
+	entry, ok := t.cache[key]
+	t.cacheMu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.budget, nil
+	}
+
+	budget, err := t.store.GetBudget(ctx, subjectType, subjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s budget: %w", subjectType, err)
+	}
+	t.cacheMu.Lock()
+	t.cache[key] = cachedBudget{budget: budget, expiresAt: now.Add(t.config.CacheTTL)}
+	t.cacheMu.Unlock()
+	return budget, nil
+}
+
+// spendCounterKey names the counter of a subject's spend in the period
+// starting at start.
+func spendCounterKey(subjectType, subjectID string, period BudgetPeriod, start time.Time) string {
+	return subjectType + ":" + subjectID + ":" + string(period) + ":" + start.Format(time.DateOnly)
+}
+
+// spend returns the spend of a subject in the period containing now. Counters
+// the tracker has not seen yet start from the persisted spend.
+func (t *BudgetTracker) spend(ctx context.Context, subjectType, subjectID string, period BudgetPeriod, now time.Time) (Spend, error) {
+	start := period.Start(now)
+	key := spendCounterKey(subjectType, subjectID, period, start)
+	spend, ok, err := t.counter.Get(ctx, key)
+	if err != nil {
+		return Spend{}, fmt.Errorf("failed to get spend: %w", err)
+	}
+	if ok {
+		return spend, nil
+	}
+
+	if spend, err = t.store.GetBudgetSpend(ctx, subjectType, subjectID, start); err != nil {
+		return Spend{}, fmt.Errorf("failed to get persisted spend: %w", err)
+	}
+	if err := t.counter.Seed(ctx, key, spend, t.counterTTL(period, now)); err != nil {
+		return Spend{}, fmt.Errorf("failed to seed spend: %w", err)
+	}
+	// Another instance may have seeded the counter first
+	if seeded, ok, err := t.counter.Get(ctx, key); err == nil && ok {
+		spend = seeded
+	}
+	return spend, nil
+}
+
+// counterTTL keeps counters an hour past the end of their period so late
+// events still find them.
+func (t *BudgetTracker) counterTTL(period BudgetPeriod, now time.Time) time.Duration {
+	return period.End(now).Sub(now) + time.Hour
+}
+
+// run is the main loop of the accounting worker.
+func (t *BudgetTracker) run() {
+	defer close(t.doneCh)
+
+	ticker := time.NewTicker(t.config.FlushInterval)
+	defer ticker.Stop()
+
+	deltas := make(map[SpendKey]Spend)
+	eventCount := 0
+
+	add := func(evt spendEvent) {
+		day := evt.at.UTC().Format(time.DateOnly)
+		for _, subject := range budgetSubjects(evt.projectID, evt.tokenID) {
+			key := SpendKey{SubjectType: subject[0], SubjectID: subject[1], Day: day}
+			d := deltas[key]
+			d.CostUSD += evt.spend.CostUSD
+			d.Tokens += evt.spend.Tokens
+			deltas[key] = d
+			t.count(evt, subject[0], subject[1])
+		}
+		eventCount++
+	}
+
+	// All counted spend is persisted after a flush; re-seed local counters
+	// from the database then to pick up the spend of other instances
+	resync := func() {
+		if local, ok := t.counter.(localSpendCounter); ok {
+			local.Reset()
+		}
+	}
+
+	flush := func() {
+		if eventCount == 0 {
+			resync()
+			return
+		}
+
+		// Copy and reset
+		toFlush := deltas
+		deltas = make(map[SpendKey]Spend)
+		flushedCount := eventCount
+		eventCount = 0
+
+		// Flush with a short timeout
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		if err := t.store.AddBudgetSpend(ctx, toFlush); err != nil {
+			t.logger.Warn("failed to flush budget spend",
+				zap.Error(err),
+				zap.Int("event_count", flushedCount),
+				zap.Int("subject_count", len(toFlush)))
+			// On error, we drop the spend (lossy-tolerant like the other stats aggregators)
+		} else {
+			t.logger.Debug("flushed budget spend",
+				zap.Int("event_count", flushedCount),
+				zap.Int("subject_count", len(toFlush)))
+			resync()
+		}
+	}
+
+	for {
+		select {
+		case <-t.stopCh:
+			// Drain remaining events
+			for {
+				select {
+				case evt := <-t.eventsCh:
+					add(evt)
+				default:
+					// No more events
+					flush()
+					return
+				}
+			}
+
+		case evt := <-t.eventsCh:
+			add(evt)
+			if eventCount >= t.config.BatchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// count adds the spend of evt to the counters of a subject and alerts on the
+// thresholds of its budget that the spend crossed.
+func (t *BudgetTracker) count(evt spendEvent, subjectType, subjectID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	budget, err := t.budget(ctx, subjectType, subjectID)
+	if err != nil {
+		t.logger.Warn("failed to get budget", zap.Error(err), zap.String("subject_type", subjectType), zap.String("subject_id", subjectID))
+	}
+	for _, period := range budgetPeriods {
+		// Make sure the counter starts from the persisted spend
+		if _, err := t.spend(ctx, subjectType, subjectID, period, evt.at); err != nil {
+			t.logger.Warn("failed to count budget spend", zap.Error(err), zap.String("subject_type", subjectType), zap.String("subject_id", subjectID))
+			continue
+		}
+		key := spendCounterKey(subjectType, subjectID, period, period.Start(evt.at))
+		total, err := t.counter.Add(ctx, key, evt.spend, t.counterTTL(period, evt.at))
+		if err != nil {
+			t.logger.Warn("failed to count budget spend", zap.Error(err), zap.String("subject_type", subjectType), zap.String("subject_id", subjectID))
+			continue
+		}
+		if budget == nil || budget.Period != period {
+			continue
+		}
+		previous := Spend{CostUSD: total.CostUSD - evt.spend.CostUSD, Tokens: total.Tokens - evt.spend.Tokens}
+		if threshold := t.crossedThreshold(*budget, previous, total); threshold > 0 {
+			t.alert(BudgetAlert{
+				SubjectType:      subjectType,
+				SubjectID:        subjectID,
+				ProjectID:        evt.projectID,
+				ThresholdPercent: threshold,
+				Budget:           *budget,
+				Spend:            total,
+				PeriodStart:      period.Start(evt.at),
+				ResetAt:          period.End(evt.at),
+				Timestamp:        t.now().UTC(),
+			})
+		}
+	}
+}
+
+// crossedThreshold returns the highest alert threshold, in percent, that
+// spend crossed going from previous to current, or 0 when it crossed none.
+func (t *BudgetTracker) crossedThreshold(budget Budget, previous, current Spend) int {
+	before, after := budget.Used(previous), budget.Used(current)
+	crossed := 0
+	for _, threshold := range t.config.Thresholds {
+		share := float64(threshold) / 100
+		if before < share && after >= share && threshold > crossed {
+			crossed = threshold
+		}
+	}
+	return crossed
+}
+
+// alert records a budget alert as an audit event and posts it to the webhook.
+func (t *BudgetTracker) alert(a BudgetAlert) {
+	t.logger.Info("Budget threshold reached",
+		zap.String("subject_type", a.SubjectType),
+		zap.String("subject_id", a.SubjectID),
+		zap.String("project_id", a.ProjectID),
+		zap.Int("threshold_percent", a.ThresholdPercent))
+
+	event := audit.NewEvent(audit.ActionBudgetThreshold, audit.ActorSystem, audit.ResultSuccess).
+		WithProjectID(a.ProjectID).
+		WithDetail("subject_type", a.SubjectType).
+		WithDetail("subject_id", a.SubjectID).
+		WithDetail("threshold_percent", a.ThresholdPercent).
+		WithDetail("period", a.Budget.Period).
+		WithDetail("spent_usd", a.Spend.CostUSD).
+		WithDetail("spent_tokens", a.Spend.Tokens)
+	if a.Budget.LimitUSD != nil {
+		event = event.WithDetail("limit_usd", *a.Budget.LimitUSD)
+	}
+	if a.Budget.LimitTokens != nil {
+		event = event.WithDetail("limit_tokens", *a.Budget.LimitTokens)
+	}
+	if err := t.auditLogger.Log(event); err != nil {
+		t.logger.Warn("Failed to audit budget threshold", zap.Error(err))
+	}
+
+	if t.config.WebhookURL != "" {
+		go t.postAlert(a)
+	}
+}
+
+// postAlert posts a budget alert to the webhook.
+func (t *BudgetTracker) postAlert(a BudgetAlert) {
+	body, err := json.Marshal(a)
+	if err != nil {
+		t.logger.Warn("Failed to encode budget alert", zap.Error(err))
+		return
+	}
+	resp, err := t.httpClient.Post(t.config.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.logger.Warn("Failed to post budget alert", zap.Error(err))
+		return
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		t.logger.Warn("Budget webhook rejected alert", zap.Int("status", resp.StatusCode))
+	}
+}
+
+// SetBudgetTracker sets the tracker that enforces project and token budgets.
+func (p *TransparentProxy) SetBudgetTracker(t *BudgetTracker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.budgets = t
+}
+
+// enforceBudgets rejects requests of tokens or projects whose budget is
+// exhausted with 402 Payment Required until the budget renews.
+func (p *TransparentProxy) enforceBudgets(w http.ResponseWriter, r *http.Request, projectID string) (int, ErrorResponse) {
+	if p.budgets == nil {
+		return 0, ErrorResponse{}
+	}
+	var tokenID string
+	if acct, ok := r.Context().Value(ctxKeyCostAccount).(*costAccount); ok {
+		tokenID = acct.tokenID
+	}
+	status, err := p.budgets.Check(r.Context(), projectID, tokenID)
+	if err != nil {
+		p.logger.Error("Failed to check budgets", zap.String("project_id", projectID), zap.Error(err))
+		return http.StatusServiceUnavailable, ErrorResponse{Error: "Budget unavailable", Code: "budget_unavailable"}
+	}
+	if status == nil {
+		return 0, ErrorResponse{}
+	}
+
+	p.logger.Info("Budget exhausted",
+		zap.String("project_id", projectID),
+		zap.String("subject_type", status.SubjectType),
+		zap.String("subject_id", status.SubjectID))
+	retryAfter := int(math.Ceil(time.Until(status.ResetAt).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+	return http.StatusPaymentRequired, ErrorResponse{
+		Error:       "Budget exceeded",
+		Code:        "budget_exceeded",
+		Description: fmt.Sprintf("%s %s budget exhausted; resets at %s", status.Budget.Period, status.SubjectType, status.ResetAt.Format(time.RFC3339)),
+	}
+}
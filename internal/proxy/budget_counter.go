@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SpendCounter counts the spend of budget periods for budget checks.
+type SpendCounter interface {
+	// Get returns the spend counted under key and whether the key is known.
+	Get(ctx context.Context, key string) (Spend, bool, error)
+	// Seed sets the spend of key unless it is already known.
+	Seed(ctx context.Context, key string, spend Spend, ttl time.Duration) error
+	// Add adds delta to the spend of key and returns the new total.
+	Add(ctx context.Context, key string, delta Spend, ttl time.Duration) (Spend, error)
+}
+
+type memorySpendEntry struct {
+	spend     Spend
+	expiresAt time.Time
+}
+
+// localSpendCounter is implemented by spend counters that only see the spend
+// of their own proxy instance. The budget tracker resets them after each
+// flush, so that they are seeded again from the persisted spend, which
+// includes the spend other instances flushed.
+type localSpendCounter interface {
+	Reset()
+}
+
+// MemorySpendCounter counts spend in process memory. Each proxy instance only
+// sees the spend of its own requests on top of the persisted spend it was
+// seeded with. The budget tracker re-seeds it after each flush, so the spend
+// of other instances lags behind by up to a flush interval.
+type MemorySpendCounter struct {
+	mu      sync.Mutex
+	entries map[string]memorySpendEntry
+	now     func() time.Time
+}
+
+// NewMemorySpendCounter creates an in-memory spend counter.
+func NewMemorySpendCounter() *MemorySpendCounter {
+	return &MemorySpendCounter{entries: make(map[string]memorySpendEntry), now: time.Now}
+}
+
+// Get implements SpendCounter.
+func (c *MemorySpendCounter) Get(ctx context.Context, key string) (Spend, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expiresAt) {
+		return Spend{}, false, nil
+	}
+	return entry.spend, true, nil
+}
+
+// Seed implements SpendCounter. Expired entries are swept on the way.
+func (c *MemorySpendCounter) Seed(ctx context.Context, key string, spend Spend, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = memorySpendEntry{spend: spend, expiresAt: now.Add(ttl)}
+	}
+	return nil
+}
+
+// Reset forgets all counted spend.
+func (c *MemorySpendCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// Add implements SpendCounter.
+func (c *MemorySpendCounter) Add(ctx context.Context, key string, delta Spend, ttl time.Duration) (Spend, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		entry = memorySpendEntry{}
+	}
+	entry.spend.CostUSD += delta.CostUSD
+	entry.spend.Tokens += delta.Tokens
+	entry.expiresAt = now.Add(ttl)
+	c.entries[key] = entry
+	return entry.spend, nil
+}
+
+// RedisSpendCounter counts spend in Redis hashes so that all proxy instances
+// enforce budgets against the same totals.
+type RedisSpendCounter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisSpendCounter creates a spend counter on client. Keys are prefixed
+// with keyPrefix (default: "llmproxy:budget:").
+func NewRedisSpendCounter(client *redis.Client, keyPrefix string) *RedisSpendCounter {
+	if keyPrefix == "" {
+		keyPrefix = "llmproxy:budget:"
+	}
+	return &RedisSpendCounter{client: client, prefix: keyPrefix}
+}
+
+// Get implements SpendCounter.
+func (c *RedisSpendCounter) Get(ctx context.Context, key string) (Spend, bool, error) {
+	vals, err := c.client.HMGet(ctx, c.prefix+key, "usd", "tokens").Result()
+	if err != nil {
+		return Spend{}, false, err
+	}
+	usd, ok := vals[0].(string)
+	if !ok {
+		return Spend{}, false, nil
+	}
+	var spend Spend
+	if spend.CostUSD, err = strconv.ParseFloat(usd, 64); err != nil {
+		return Spend{}, false, err
+	}
+	if tokens, ok := vals[1].(string); ok {
+		if spend.Tokens, err = strconv.ParseInt(tokens, 10, 64); err != nil {
+			return Spend{}, false, err
+		}
+	}
+	return spend, true, nil
+}
+
+// Seed implements SpendCounter.
+func (c *RedisSpendCounter) Seed(ctx context.Context, key string, spend Spend, ttl time.Duration) error {
+	k := c.prefix + key
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSetNX(ctx, k, "usd", strconv.FormatFloat(spend.CostUSD, 'f', -1, 64))
+		pipe.HSetNX(ctx, k, "tokens", spend.Tokens)
+		pipe.PExpire(ctx, k, ttl)
+		return nil
+	})
+	return err
+}
+
+// Add implements SpendCounter.
+func (c *RedisSpendCounter) Add(ctx context.Context, key string, delta Spend, ttl time.Duration) (Spend, error) {
+	k := c.prefix + key
+	var (
+		usd    *redis.FloatCmd
+		tokens *redis.IntCmd
+	)
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		usd = pipe.HIncrByFloat(ctx, k, "usd", delta.CostUSD)
+		tokens = pipe.HIncrBy(ctx, k, "tokens", delta.Tokens)
+		pipe.PExpire(ctx, k, ttl)
+		return nil
+	})
+	if err != nil {
+		return Spend{}, err
+	}
+	return Spend{CostUSD: usd.Val(), Tokens: tokens.Val()}, nil
+}
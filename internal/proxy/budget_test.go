@@ -0,0 +1,356 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockBudgetStore keeps budgets and daily spend in memory.
+type mockBudgetStore struct {
+	mu      sync.Mutex
+	budgets map[string]Budget
+	spend   map[SpendKey]Spend
+	err     error
+}
+
+func (m *mockBudgetStore) set(subjectType, subjectID string, b Budget) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.budgets == nil {
+		m.budgets = map[string]Budget{}
+	}
+	m.budgets[subjectType+":"+subjectID] = b
+}
+
+func (m *mockBudgetStore) GetBudget(ctx context.Context, subjectType, subjectID string) (*Budget, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	b, ok := m.budgets[subjectType+":"+subjectID]
+	if !ok {
+		return nil, nil
+	}
+	return &b, nil
+}
+
+func (m *mockBudgetStore) GetBudgetSpend(ctx context.Context, subjectType, subjectID string, since time.Time) (Spend, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var total Spend
+	for key, s := range m.spend {
+		if key.SubjectType == subjectType && key.SubjectID == subjectID && key.Day >= since.Format(time.DateOnly) {
+			total.CostUSD += s.CostUSD
+			total.Tokens += s.Tokens
+		}
+	}
+	return total, nil
+}
+
+func (m *mockBudgetStore) AddBudgetSpend(ctx context.Context, deltas map[SpendKey]Spend) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.spend == nil {
+		m.spend = map[SpendKey]Spend{}
+	}
+	for key, d := range deltas {
+		s := m.spend[key]
+		s.CostUSD += d.CostUSD
+		s.Tokens += d.Tokens
+		m.spend[key] = s
+	}
+	return nil
+}
+
+func (m *mockBudgetStore) daily(subjectType, subjectID, day string) Spend {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.spend[SpendKey{SubjectType: subjectType, SubjectID: subjectID, Day: day}]
+}
+
+func usd(v float64) *float64 { return &v }
+
+func TestBudgetPeriod(t *testing.T) {
+	at := time.Date(2026, 12, 31, 15, 4, 5, 0, time.FixedZone("CET", 3600))
+	assert.Equal(t, time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), BudgetPeriodDaily.Start(at))
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), BudgetPeriodDaily.End(at))
+	assert.Equal(t, time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), BudgetPeriodMonthly.Start(at))
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), BudgetPeriodMonthly.End(at))
+}
+
+func TestBudget_Validate(t *testing.T) {
+	tokens := int64(100)
+	zero := int64(0)
+	assert.NoError(t, Budget{Period: BudgetPeriodDaily, LimitUSD: usd(1)}.Validate())
+	assert.NoError(t, Budget{Period: BudgetPeriodMonthly, LimitTokens: &tokens}.Validate())
+	assert.Error(t, Budget{Period: "weekly", LimitUSD: usd(1)}.Validate())
+	assert.Error(t, Budget{Period: BudgetPeriodDaily}.Validate())
+	assert.Error(t, Budget{Period: BudgetPeriodDaily, LimitUSD: usd(0)}.Validate())
+	assert.Error(t, Budget{Period: BudgetPeriodDaily, LimitTokens: &zero}.Validate())
+}
+
+func TestBudget_UsedAndRemaining(t *testing.T) {
+	tokens := int64(1000)
+	b := Budget{Period: BudgetPeriodDaily, LimitUSD: usd(10), LimitTokens: &tokens}
+	assert.InDelta(t, 0.8, b.Used(Spend{CostUSD: 2, Tokens: 800}), 1e-9, "the larger share counts")
+	assert.InDelta(t, 0.5, b.Used(Spend{CostUSD: 5, Tokens: 100}), 1e-9)
+
+	leftUSD, leftTokens := b.Remaining(Spend{CostUSD: 12, Tokens: 100})
+	assert.Equal(t, 0.0, *leftUSD)
+	assert.Equal(t, int64(900), *leftTokens)
+}
+
+func TestBudgetTracker_CheckAndRecord(t *testing.T) {
+	store := &mockBudgetStore{}
+	store.set(BudgetSubjectProject, "proj-1", Budget{Period: BudgetPeriodMonthly, LimitUSD: usd(10)})
+	tokens := int64(100)
+	store.set(BudgetSubjectToken, "tok-1", Budget{Period: BudgetPeriodDaily, LimitTokens: &tokens})
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	// Spend persisted earlier this month and last month
+	require.NoError(t, store.AddBudgetSpend(context.Background(), map[SpendKey]Spend{
+		{SubjectType: BudgetSubjectProject, SubjectID: "proj-1", Day: "2026-10-01"}: {CostUSD: 6},
+		{SubjectType: BudgetSubjectProject, SubjectID: "proj-1", Day: "2026-09-30"}: {CostUSD: 100},
+	}))
+
+	tracker := NewBudgetTracker(BudgetTrackerConfig{FlushInterval: time.Hour}, store, nil, nil, nil)
+	tracker.now = func() time.Time { return now }
+	tracker.Start()
+	ctx := context.Background()
+
+	status, err := tracker.Check(ctx, "proj-1", "tok-1")
+	require.NoError(t, err)
+	assert.Nil(t, status)
+
+	left, leftTokens, err := tracker.Remaining(ctx, "proj-1", "tok-1")
+	require.NoError(t, err)
+	assert.InDelta(t, 4.0, *left, 1e-9, "monthly spend is seeded from the database")
+	assert.Equal(t, int64(100), *leftTokens)
+
+	tracker.Record("proj-1", "tok-1", Spend{CostUSD: 1, Tokens: 100})
+	tracker.Record("proj-1", "tok-2", Spend{CostUSD: 3})
+	require.Eventually(t, func() bool {
+		status, err := tracker.Check(ctx, "proj-1", "tok-2")
+		return err == nil && status != nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	status, err = tracker.Check(ctx, "proj-1", "tok-1")
+	require.NoError(t, err)
+	require.NotNil(t, status)
+	assert.Equal(t, BudgetSubjectToken, status.SubjectType, "token budgets are checked first")
+	assert.Equal(t, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), status.ResetAt)
+
+	status, err = tracker.Check(ctx, "proj-1", "tok-2")
+	require.NoError(t, err)
+	assert.Equal(t, BudgetSubjectProject, status.SubjectType)
+	assert.InDelta(t, 10.0, status.Spend.CostUSD, 1e-9)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), status.ResetAt)
+
+	// Spend is flushed as daily totals
+	require.NoError(t, tracker.Stop(ctx))
+	assert.Equal(t, Spend{CostUSD: 4, Tokens: 100}, store.daily(BudgetSubjectProject, "proj-1", "2026-10-16"))
+	assert.Equal(t, Spend{CostUSD: 1, Tokens: 100}, store.daily(BudgetSubjectToken, "tok-1", "2026-10-16"))
+	assert.Equal(t, Spend{CostUSD: 3}, store.daily(BudgetSubjectToken, "tok-2", "2026-10-16"))
+
+	// Budgets renew with their period
+	tracker.now = func() time.Time { return now.AddDate(0, 0, 1) }
+	status, err = tracker.Check(ctx, "", "tok-1")
+	require.NoError(t, err)
+	assert.Nil(t, status)
+
+	// Changed budgets apply once invalidated
+	store.set(BudgetSubjectToken, "tok-1", Budget{Period: BudgetPeriodMonthly, LimitTokens: &tokens})
+	tracker.Invalidate(BudgetSubjectToken, "tok-1")
+	status, err = tracker.Check(ctx, "", "tok-1")
+	require.NoError(t, err)
+	require.NotNil(t, status)
+
+	store.mu.Lock()
+	store.err = errors.New("db down")
+	store.mu.Unlock()
+	tracker.Invalidate(BudgetSubjectToken, "tok-1")
+	_, err = tracker.Check(ctx, "", "tok-1")
+	assert.Error(t, err)
+}
+
+func TestBudgetTracker_ResyncsInstances(t *testing.T) {
+	store := &mockBudgetStore{}
+	store.set(BudgetSubjectProject, "proj-1", Budget{Period: BudgetPeriodMonthly, LimitUSD: usd(10)})
+	ctx := context.Background()
+
+	// Two proxy instances counting spend in memory
+	instances := make([]*BudgetTracker, 2)
+	for i := range instances {
+		instances[i] = NewBudgetTracker(BudgetTrackerConfig{FlushInterval: 10 * time.Millisecond}, store, nil, nil, nil)
+		instances[i].Start()
+		defer func(tracker *BudgetTracker) { _ = tracker.Stop(ctx) }(instances[i])
+		status, err := instances[i].Check(ctx, "proj-1", "")
+		require.NoError(t, err)
+		require.Nil(t, status)
+	}
+
+	// Each instance picks up the spend the other one flushed
+	instances[0].Record("proj-1", "", Spend{CostUSD: 6})
+	require.Eventually(t, func() bool {
+		left, _, err := instances[1].Remaining(ctx, "proj-1", "")
+		return err == nil && *left < 4.5
+	}, 2*time.Second, 10*time.Millisecond)
+
+	instances[1].Record("proj-1", "", Spend{CostUSD: 5})
+	require.Eventually(t, func() bool {
+		status, err := instances[0].Check(ctx, "proj-1", "")
+		return err == nil && status != nil
+	}, 2*time.Second, 10*time.Millisecond, "the budget is exhausted on both instances")
+}
+
+func TestBudgetTracker_ThresholdAlerts(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		alerts []BudgetAlert
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a BudgetAlert
+		if err := json.NewDecoder(r.Body).Decode(&a); err == nil {
+			mu.Lock()
+			alerts = append(alerts, a)
+			mu.Unlock()
+		}
+	}))
+	defer webhook.Close()
+
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	auditLogger, err := audit.NewLogger(audit.LoggerConfig{FilePath: auditPath})
+	require.NoError(t, err)
+	defer func() { _ = auditLogger.Close() }()
+
+	store := &mockBudgetStore{}
+	store.set(BudgetSubjectProject, "proj-1", Budget{Period: BudgetPeriodDaily, LimitUSD: usd(10)})
+	tracker := NewBudgetTracker(BudgetTrackerConfig{FlushInterval: time.Hour, WebhookURL: webhook.URL}, store, nil, auditLogger, nil)
+	tracker.Start()
+	defer func() { _ = tracker.Stop(context.Background()) }()
+
+	tracker.Record("proj-1", "", Spend{CostUSD: 4})  // 40%
+	tracker.Record("proj-1", "", Spend{CostUSD: 2})  // 60%: crosses 50%
+	tracker.Record("proj-1", "", Spend{CostUSD: 1})  // 70%
+	tracker.Record("proj-1", "", Spend{CostUSD: 5})  // 120%: crosses 80% and 100%
+	tracker.Record("proj-1", "", Spend{CostUSD: 10}) // Already exhausted
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(alerts) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	got := map[int]BudgetAlert{}
+	for _, a := range alerts {
+		got[a.ThresholdPercent] = a
+	}
+	assert.Len(t, alerts, 2, "one alert per crossing, for the highest threshold crossed")
+	mu.Unlock()
+	require.Contains(t, got, 50)
+	require.Contains(t, got, 100)
+	assert.Equal(t, "proj-1", got[100].ProjectID)
+	assert.InDelta(t, 12.0, got[100].Spend.CostUSD, 1e-9)
+
+	logged, err := os.ReadFile(auditPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(logged), audit.ActionBudgetThreshold))
+}
+
+func TestRedisSpendCounter(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+
+	counter := NewRedisSpendCounter(client, "")
+	ctx := context.Background()
+
+	_, ok, err := counter.Get(ctx, "project:p:daily:2026-10-16")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, counter.Seed(ctx, "project:p:daily:2026-10-16", Spend{CostUSD: 1.5, Tokens: 10}, time.Hour))
+	require.NoError(t, counter.Seed(ctx, "project:p:daily:2026-10-16", Spend{CostUSD: 99}, time.Hour), "seeding a known key is a no-op")
+	total, err := counter.Add(ctx, "project:p:daily:2026-10-16", Spend{CostUSD: 0.25, Tokens: 5}, time.Hour)
+	require.NoError(t, err)
+	assert.InDelta(t, 1.75, total.CostUSD, 1e-9)
+	assert.Equal(t, int64(15), total.Tokens)
+
+	spend, ok, err := counter.Get(ctx, "project:p:daily:2026-10-16")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, total, spend)
+	assert.True(t, mr.Exists("llmproxy:budget:project:p:daily:2026-10-16"))
+
+	mr.FastForward(2 * time.Hour)
+	_, ok, err = counter.Get(ctx, "project:p:daily:2026-10-16")
+	require.NoError(t, err)
+	assert.False(t, ok, "counters expire")
+}
+
+func TestTransparentProxy_EnforcesBudgets(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"gpt-4o-mini","choices":[],"usage":{"prompt_tokens":1000,"completion_tokens":0}}`))
+	}))
+	defer upstream.Close()
+
+	store := &mockBudgetStore{}
+	store.set(BudgetSubjectToken, "tok-id", Budget{Period: BudgetPeriodDaily, LimitUSD: usd(1.5)})
+	tracker := NewBudgetTracker(BudgetTrackerConfig{FlushInterval: time.Hour}, store, nil, nil, nil)
+	tracker.Start()
+	defer func() { _ = tracker.Stop(context.Background()) }()
+	agg := NewCostAggregator(CostAggregatorConfig{FlushInterval: time.Hour}, &mockCostStore{}, testPriceCatalog(), nil)
+	agg.SetBudgetTracker(tracker)
+	agg.Start()
+	defer func() { _ = agg.Stop(context.Background()) }()
+
+	p := newTestProxy(t, upstream.URL, withTokenValidator(&limitedTokenValidator{}))
+	p.SetCostAggregator(agg)
+	p.SetBudgetTracker(tracker)
+	h := p.Handler()
+
+	// Each request costs $1: the second is admitted, the third rejected
+	require.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+	require.Eventually(t, func() bool {
+		status, _ := tracker.Status(context.Background(), BudgetSubjectToken, "tok-id")
+		return status != nil && status.Spend.CostUSD > 0
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+	var w *httptest.ResponseRecorder
+	require.Eventually(t, func() bool {
+		w = sendChat(h, "gpt-4o-mini", nil)
+		return w.Code == http.StatusPaymentRequired
+	}, 2*time.Second, 10*time.Millisecond)
+
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "budget_exceeded", resp.Code)
+	assert.Contains(t, resp.Description, "daily token budget exhausted")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	store.mu.Lock()
+	store.err = errors.New("db down")
+	store.mu.Unlock()
+	tracker.Invalidate(BudgetSubjectToken, "tok-id")
+	assert.Equal(t, http.StatusServiceUnavailable, sendChat(h, "gpt-4o-mini", nil).Code)
+}
@@ -269,6 +269,10 @@ func cloneHeadersForCache(h http.Header) http.Header {
 		"X-Proxy-Final-Response-At": {},
 		"X-Proxy-First-Response-At": {},
 		"Date":                      {},
+		// Token limits belong to the requesting token
+		"X-Ratelimit-Limit":     {},
+		"X-Ratelimit-Remaining": {},
+		"X-Ratelimit-Reset":     {},
 		// Cookies are user-specific, not cacheable for shared cache
 		"Set-Cookie": {},
 	}
@@ -1,72 +1,468 @@
 package proxy
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"go.uber.org/zap"
 )
 
-// Define a custom context key type for circuit breaker cooldown override
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
 
-type cbContextKey string
+// Circuit breaker defaults used when APIProviderConfig.CircuitBreaker leaves a field unset
+const (
+	defaultCircuitFailureThreshold = 5
+	defaultCircuitCooldown         = 30 * time.Second
+)
 
-const cbCooldownOverrideKey cbContextKey = "circuitbreaker_cooldown_override"
+// defaultCircuitFailureStatuses are the upstream statuses counted as failures.
+var defaultCircuitFailureStatuses = []int{
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
 
-// CircuitBreakerMiddleware returns a middleware that opens the circuit after N consecutive failures.
-// While open, it returns 503 immediately. After a cooldown, it closes and allows requests again.
-func CircuitBreakerMiddleware(failureThreshold int, cooldown time.Duration, isTransient func(status int) bool) Middleware {
-	cb := &circuitBreaker{
-		failureThreshold: failureThreshold,
-		cooldown:         cooldown,
-		isTransient:      isTransient,
-	}
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			cb.mu.Lock()
-			if cb.open {
-				// Allow test override of cooldown via context
-				if override, ok := r.Context().Value(cbCooldownOverrideKey).(time.Duration); ok {
-					cb.cooldown = override
-				}
-				if time.Since(cb.openedAt) < cb.cooldown {
-					cb.mu.Unlock()
-					w.Header().Set("Content-Type", "application/json")
-					w.WriteHeader(http.StatusServiceUnavailable)
-					_, _ = w.Write([]byte("{\"error\":\"Upstream unavailable (circuit breaker open)\"}")) // Ignore error: nothing we can do if write fails
-					return
-				}
-				// Cooldown expired, close circuit
-				cb.open = false
-				cb.failureCount = 0
-			}
-			cb.mu.Unlock()
-
-			rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
-			next.ServeHTTP(rec, r)
-
-			if cb.isTransient(rec.statusCode) {
-				cb.mu.Lock()
-				cb.failureCount++
-				if cb.failureCount >= cb.failureThreshold {
-					cb.open = true
-					cb.openedAt = time.Now()
-				}
-				cb.mu.Unlock()
-			} else {
-				cb.mu.Lock()
-				cb.failureCount = 0
-				cb.mu.Unlock()
-			}
-		})
+// errCircuitOpen is returned when a circuit breaker rejects an upstream request.
+var errCircuitOpen = errors.New("upstream unavailable (circuit breaker open)")
+
+// validateCircuitBreakerConfig checks the circuit breaker settings of a provider.
+func validateCircuitBreakerConfig(cfg CircuitBreakerConfig) error {
+	if cfg.FailureThreshold < 0 || cfg.HalfOpenRequests < 0 || cfg.SuccessThreshold < 0 {
+		return errors.New("thresholds must not be negative")
+	}
+	if cfg.Cooldown < 0 {
+		return errors.New("cooldown must not be negative")
 	}
+	for _, status := range cfg.FailureStatuses {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("failure_statuses has invalid status code %d", status)
+		}
+	}
+	return nil
+}
+
+// CircuitBreakerStatus is a snapshot of one circuit breaker.
+type CircuitBreakerStatus struct {
+	Provider string `json:"provider"`
+	// KeyID is set for per-key breakers
+	KeyID               string `json:"key_id,omitempty"`
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Opens               int64  `json:"opens"`
+	// OpenedAt is when the circuit last opened
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
 }
 
+// circuitTransition describes a state change reported to the breaker's owner.
+type circuitTransition struct {
+	status CircuitBreakerStatus
+	from   string
+}
+
+// circuitBreaker implements the closed -> open -> half-open cycle for one
+// upstream or upstream key.
 type circuitBreaker struct {
-	mu               sync.Mutex
-	open             bool
+	provider string
+	keyID    string
+
 	failureThreshold int
 	cooldown         time.Duration
-	isTransient      func(status int) bool
-	openedAt         time.Time
-	failureCount     int
+	halfOpenRequests int
+	successThreshold int
+	onTransition     func(circuitTransition)
+	now              func() time.Time
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	successes int
+	probes    int
+	opens     int64
+	openedAt  time.Time
+	changedAt time.Time
+}
+
+func newCircuitBreaker(provider, keyID string, cfg CircuitBreakerConfig, onTransition func(circuitTransition)) *circuitBreaker {
+	cb := &circuitBreaker{
+		provider:         provider,
+		keyID:            keyID,
+		failureThreshold: cfg.FailureThreshold,
+		cooldown:         cfg.Cooldown,
+		halfOpenRequests: cfg.HalfOpenRequests,
+		successThreshold: cfg.SuccessThreshold,
+		onTransition:     onTransition,
+		now:              time.Now,
+		state:            CircuitClosed,
+	}
+	if cb.failureThreshold <= 0 {
+		cb.failureThreshold = defaultCircuitFailureThreshold
+	}
+	if cb.cooldown <= 0 {
+		cb.cooldown = defaultCircuitCooldown
+	}
+	if cb.halfOpenRequests <= 0 {
+		cb.halfOpenRequests = 1
+	}
+	if cb.successThreshold <= 0 {
+		cb.successThreshold = 1
+	}
+	return cb
+}
+
+// allow reports whether a request may go upstream. In the half-open state it
+// reserves one of the probe slots, which record or release gives back.
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	now := cb.now()
+	var transition *circuitTransition
+	if cb.state == CircuitOpen && now.Sub(cb.openedAt) >= cb.cooldown {
+		transition = cb.setStateLocked(CircuitHalfOpen, now)
+	}
+	allowed := true
+	switch cb.state {
+	case CircuitOpen:
+		allowed = false
+	case CircuitHalfOpen:
+		// Probes whose outcome never arrived must not wedge the breaker
+		if cb.probes >= cb.halfOpenRequests && now.Sub(cb.changedAt) >= cb.cooldown {
+			cb.probes = 0
+			cb.changedAt = now
+		}
+		if cb.probes < cb.halfOpenRequests {
+			cb.probes++
+		} else {
+			allowed = false
+		}
+	}
+	cb.mu.Unlock()
+	cb.notify(transition)
+	return allowed
+}
+
+// available reports whether allow would admit a request, without reserving a probe.
+func (cb *circuitBreaker) available() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case CircuitOpen:
+		return cb.now().Sub(cb.openedAt) >= cb.cooldown
+	case CircuitHalfOpen:
+		return cb.probes < cb.halfOpenRequests
+	}
+	return true
+}
+
+// release returns a probe slot reserved by allow for a request that was not sent.
+func (cb *circuitBreaker) release() {
+	cb.mu.Lock()
+	if cb.state == CircuitHalfOpen && cb.probes > 0 {
+		cb.probes--
+	}
+	cb.mu.Unlock()
+}
+
+// record reports the outcome of an admitted request.
+func (cb *circuitBreaker) record(failure bool) {
+	cb.mu.Lock()
+	now := cb.now()
+	var transition *circuitTransition
+	switch cb.state {
+	case CircuitClosed:
+		if !failure {
+			cb.failures = 0
+			break
+		}
+		cb.failures++
+		if cb.failures >= cb.failureThreshold {
+			transition = cb.setStateLocked(CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		if cb.probes > 0 {
+			cb.probes--
+		}
+		if failure {
+			cb.failures++
+			transition = cb.setStateLocked(CircuitOpen, now)
+			break
+		}
+		cb.successes++
+		if cb.successes >= cb.successThreshold {
+			transition = cb.setStateLocked(CircuitClosed, now)
+		}
+	}
+	cb.mu.Unlock()
+	cb.notify(transition)
+}
+
+// retryAfter returns how long until an open circuit starts probing again.
+func (cb *circuitBreaker) retryAfter() time.Duration {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state != CircuitOpen {
+		return 0
+	}
+	return cb.cooldown - cb.now().Sub(cb.openedAt)
+}
+
+func (cb *circuitBreaker) status() CircuitBreakerStatus {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.statusLocked()
+}
+
+func (cb *circuitBreaker) statusLocked() CircuitBreakerStatus {
+	status := CircuitBreakerStatus{
+		Provider:            cb.provider,
+		KeyID:               cb.keyID,
+		State:               cb.state,
+		ConsecutiveFailures: cb.failures,
+		Opens:               cb.opens,
+	}
+	if !cb.openedAt.IsZero() {
+		openedAt := cb.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+func (cb *circuitBreaker) setStateLocked(state string, now time.Time) *circuitTransition {
+	from := cb.state
+	cb.state = state
+	cb.changedAt = now
+	cb.probes = 0
+	cb.successes = 0
+	switch state {
+	case CircuitOpen:
+		cb.opens++
+		cb.openedAt = now
+	case CircuitClosed:
+		cb.failures = 0
+	}
+	return &circuitTransition{status: cb.statusLocked(), from: from}
+}
+
+func (cb *circuitBreaker) notify(t *circuitTransition) {
+	if t != nil && cb.onTransition != nil {
+		cb.onTransition(*t)
+	}
+}
+
+// upstreamBreakers holds a provider's circuit breaker and, when enabled, one
+// breaker per pooled upstream key. A nil *upstreamBreakers admits everything.
+type upstreamBreakers struct {
+	provider     string
+	cfg          CircuitBreakerConfig
+	failures     map[int]bool
+	upstream     *circuitBreaker
+	onTransition func(circuitTransition)
+
+	mu   sync.Mutex
+	keys map[string]*circuitBreaker
+}
+
+func newUpstreamBreakers(provider string, cfg CircuitBreakerConfig, onTransition func(circuitTransition)) *upstreamBreakers {
+	if cfg.Disabled {
+		return nil
+	}
+	b := &upstreamBreakers{
+		provider:     provider,
+		cfg:          cfg,
+		failures:     make(map[int]bool),
+		upstream:     newCircuitBreaker(provider, "", cfg, onTransition),
+		onTransition: onTransition,
+	}
+	statuses := cfg.FailureStatuses
+	if len(statuses) == 0 {
+		statuses = defaultCircuitFailureStatuses
+	}
+	for _, status := range statuses {
+		b.failures[status] = true
+	}
+	if cfg.PerKey {
+		b.keys = make(map[string]*circuitBreaker)
+	}
+	return b
+}
+
+// key returns the breaker for keyID, or nil when per-key breakers are off.
+func (b *upstreamBreakers) key(keyID string) *circuitBreaker {
+	if b.keys == nil || keyID == "" {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cb, ok := b.keys[keyID]
+	if !ok {
+		cb = newCircuitBreaker(b.provider, keyID, b.cfg, b.onTransition)
+		b.keys[keyID] = cb
+	}
+	return cb
+}
+
+// admit reserves passage through the provider's breaker and the breaker of keyID.
+func (b *upstreamBreakers) admit(keyID string) bool {
+	if b == nil {
+		return true
+	}
+	if !b.upstream.allow() {
+		return false
+	}
+	if cb := b.key(keyID); cb != nil && !cb.allow() {
+		b.upstream.release()
+		return false
+	}
+	return true
+}
+
+// record reports the status of an admitted request. Status 0 means the request
+// never got a response and gives back its admission.
+func (b *upstreamBreakers) record(keyID string, status int) {
+	if b == nil {
+		return
+	}
+	cb := b.key(keyID)
+	if status == 0 {
+		b.upstream.release()
+		if cb != nil {
+			cb.release()
+		}
+		return
+	}
+	failure := b.failures[status]
+	b.upstream.record(failure)
+	if cb != nil {
+		cb.record(failure)
+	}
+}
+
+// availableKeys drops keys whose breaker is open. All keys are returned when
+// none is available, so admission decides.
+func (b *upstreamBreakers) availableKeys(keys []pooledKey) []pooledKey {
+	if b == nil || b.keys == nil || len(keys) < 2 {
+		return keys
+	}
+	available := make([]pooledKey, 0, len(keys))
+	for _, k := range keys {
+		if b.key(k.id).available() {
+			available = append(available, k)
+		}
+	}
+	if len(available) == 0 {
+		return keys
+	}
+	return available
+}
+
+// retryAfter returns how long the provider breaker stays open.
+func (b *upstreamBreakers) retryAfter() time.Duration {
+	if b == nil {
+		return 0
+	}
+	return b.upstream.retryAfter()
+}
+
+// statuses returns the provider breaker followed by per-key breakers.
+func (b *upstreamBreakers) statuses() []CircuitBreakerStatus {
+	if b == nil {
+		return nil
+	}
+	out := []CircuitBreakerStatus{b.upstream.status()}
+	b.mu.Lock()
+	keys := make([]*circuitBreaker, 0, len(b.keys))
+	for _, cb := range b.keys {
+		keys = append(keys, cb)
+	}
+	b.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].keyID < keys[j].keyID })
+	for _, cb := range keys {
+		out = append(out, cb.status())
+	}
+	return out
+}
+
+// CircuitBreakers returns the state of the proxy's circuit breakers, or nil
+// when they are disabled.
+func (p *TransparentProxy) CircuitBreakers() []CircuitBreakerStatus {
+	return p.breakers.statuses()
+}
+
+// forwardUpstream sends r through the reverse proxy if the circuit breakers
+// admit it and records the upstream's answer. When a breaker rejects the
+// request nothing is written and errCircuitOpen is returned.
+func (p *TransparentProxy) forwardUpstream(w http.ResponseWriter, r *http.Request) error {
+	if p.breakers == nil {
+		p.proxy.ServeHTTP(w, r)
+		return nil
+	}
+	var keyID string
+	if sel, ok := r.Context().Value(ctxKeyUpstreamKey).(*upstreamKeySelection); ok && sel != nil {
+		keyID = sel.keyID
+	}
+	if !p.breakers.admit(keyID) {
+		return errCircuitOpen
+	}
+	rec := &responseRecorder{ResponseWriter: w}
+	p.proxy.ServeHTTP(rec, r)
+	status := rec.statusCode
+	// A request the client abandoned says nothing about the upstream
+	if r.Context().Err() != nil {
+		status = 0
+	}
+	p.breakers.record(keyID, status)
+	return nil
+}
+
+// circuitStateChanged logs and audits a circuit breaker state change.
+func (p *TransparentProxy) circuitStateChanged(t circuitTransition) {
+	p.logger.Warn("Circuit breaker state changed",
+		zap.String("provider", t.status.Provider),
+		zap.String("upstream_key_id", t.status.KeyID),
+		zap.String("from", t.from),
+		zap.String("to", t.status.State),
+		zap.Int("consecutive_failures", t.status.ConsecutiveFailures),
+	)
+	if p.auditLogger == nil {
+		return
+	}
+	result := audit.ResultFailure
+	if t.status.State == CircuitClosed {
+		result = audit.ResultSuccess
+	}
+	auditEvent := audit.NewEvent(audit.ActionCircuitBreakerStateChange, audit.ActorSystem, result).
+		WithDetail("provider", t.status.Provider).
+		WithDetail("from", t.from).
+		WithDetail("to", t.status.State).
+		WithDetail("consecutive_failures", t.status.ConsecutiveFailures)
+	if t.status.KeyID != "" {
+		auditEvent = auditEvent.WithDetail("key_id", t.status.KeyID)
+	}
+	if err := p.auditLogger.Log(auditEvent); err != nil {
+		p.logger.Warn("Failed to audit circuit breaker state change", zap.Error(err))
+	}
+}
+
+// writeCircuitOpen answers a request rejected by an open circuit.
+func (p *TransparentProxy) writeCircuitOpen(w http.ResponseWriter, r *http.Request) {
+	// Nothing was sent upstream, so the request uses no tokens
+	if acct, ok := r.Context().Value(ctxKeyCostAccount).(*costAccount); ok {
+		releaseTPM(acct)
+	}
+	if wait := p.breakers.retryAfter(); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+	}
+	writeErrorResponseForRequest(w, r, http.StatusServiceUnavailable, ErrorResponse{
+		Error: "Upstream unavailable (circuit breaker open)",
+		Code:  "circuit_open",
+	})
 }
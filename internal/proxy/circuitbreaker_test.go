@@ -0,0 +1,270 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeClock is a settable time source for circuit breakers.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestValidateCircuitBreakerConfig(t *testing.T) {
+	assert.NoError(t, validateCircuitBreakerConfig(CircuitBreakerConfig{}))
+	assert.NoError(t, validateCircuitBreakerConfig(CircuitBreakerConfig{FailureThreshold: 3, Cooldown: time.Second, FailureStatuses: []int{500, 429}}))
+	assert.Error(t, validateCircuitBreakerConfig(CircuitBreakerConfig{FailureThreshold: -1}))
+	assert.Error(t, validateCircuitBreakerConfig(CircuitBreakerConfig{HalfOpenRequests: -1}))
+	assert.Error(t, validateCircuitBreakerConfig(CircuitBreakerConfig{Cooldown: -time.Second}))
+	assert.Error(t, validateCircuitBreakerConfig(CircuitBreakerConfig{FailureStatuses: []int{42}}))
+}
+
+func TestCircuitBreaker_StateMachine(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	var transitions []string
+	cb := newCircuitBreaker("openai", "", CircuitBreakerConfig{FailureThreshold: 2, Cooldown: time.Minute, SuccessThreshold: 2}, func(tr circuitTransition) {
+		transitions = append(transitions, tr.from+"->"+tr.status.State)
+	})
+	cb.now = clock.Now
+
+	// A success resets the consecutive failure count
+	require.True(t, cb.allow())
+	cb.record(true)
+	require.True(t, cb.allow())
+	cb.record(false)
+	require.True(t, cb.allow())
+	cb.record(true)
+	assert.Equal(t, CircuitClosed, cb.status().State)
+
+	require.True(t, cb.allow())
+	cb.record(true)
+	assert.Equal(t, CircuitOpen, cb.status().State)
+	assert.False(t, cb.allow())
+	assert.Equal(t, time.Minute, cb.retryAfter())
+
+	// After the cooldown a single probe is admitted
+	clock.Advance(time.Minute)
+	assert.True(t, cb.available())
+	require.True(t, cb.allow())
+	assert.Equal(t, CircuitHalfOpen, cb.status().State)
+	assert.False(t, cb.allow(), "only one probe at a time")
+
+	// A failed probe reopens the circuit
+	cb.record(true)
+	assert.Equal(t, CircuitOpen, cb.status().State)
+	assert.Equal(t, int64(2), cb.status().Opens)
+
+	// Two successful probes close it
+	clock.Advance(time.Minute)
+	require.True(t, cb.allow())
+	cb.record(false)
+	assert.Equal(t, CircuitHalfOpen, cb.status().State)
+	require.True(t, cb.allow())
+	cb.record(false)
+	assert.Equal(t, CircuitClosed, cb.status().State)
+	assert.Zero(t, cb.status().ConsecutiveFailures)
+
+	assert.Equal(t, []string{
+		"closed->open", "open->half_open", "half_open->open",
+		"open->half_open", "half_open->closed",
+	}, transitions)
+}
+
+func TestCircuitBreaker_HalfOpenProbes(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cb := newCircuitBreaker("openai", "", CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Second, HalfOpenRequests: 2}, nil)
+	cb.now = clock.Now
+
+	require.True(t, cb.allow())
+	cb.record(true)
+	clock.Advance(time.Second)
+
+	assert.True(t, cb.allow())
+	assert.True(t, cb.allow())
+	assert.False(t, cb.allow(), "probe limit reached")
+	assert.False(t, cb.available())
+
+	// A probe that was never sent frees its slot
+	cb.release()
+	assert.True(t, cb.allow())
+
+	// Probes that never report back do not wedge the circuit
+	clock.Advance(time.Second)
+	assert.True(t, cb.allow())
+	assert.Equal(t, CircuitHalfOpen, cb.status().State)
+}
+
+func TestUpstreamBreakers_Disabled(t *testing.T) {
+	var b *upstreamBreakers
+	assert.Nil(t, newUpstreamBreakers("openai", CircuitBreakerConfig{Disabled: true}, nil))
+	assert.True(t, b.admit("key"))
+	b.record("key", http.StatusBadGateway)
+	assert.Nil(t, b.statuses())
+	keys := testKeyPool("sk-a", "sk-b")
+	assert.Equal(t, keys, b.availableKeys(keys))
+}
+
+func TestUpstreamBreakers_PerKey(t *testing.T) {
+	b := newUpstreamBreakers("openai", CircuitBreakerConfig{FailureThreshold: 1, FailureStatuses: []int{http.StatusInternalServerError}, PerKey: true}, nil)
+	keys := testKeyPool("sk-a", "sk-b")
+
+	require.True(t, b.admit(keys[0].id))
+	b.record(keys[0].id, http.StatusBadGateway)
+	assert.Equal(t, keys, b.availableKeys(keys), "502 is not a configured failure")
+
+	require.True(t, b.admit(keys[0].id))
+	b.record(keys[0].id, http.StatusInternalServerError)
+	assert.Equal(t, keys[1:], b.availableKeys(keys))
+	assert.False(t, b.admit(keys[0].id))
+
+	statuses := b.statuses()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "", statuses[0].KeyID)
+	for _, s := range statuses[1:] {
+		if s.KeyID == keys[0].id {
+			assert.Equal(t, CircuitOpen, s.State)
+		} else {
+			assert.Equal(t, CircuitClosed, s.State)
+		}
+	}
+}
+
+func TestTransparentProxy_CircuitBreaker_PerKey(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		seen = append(seen, auth)
+		mu.Unlock()
+		if auth == "Bearer sk-a" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	p, err := NewTransparentProxyWithLogger(ProxyConfig{
+		TargetBaseURL:    upstream.URL,
+		Provider:         "openai",
+		AllowedEndpoints: []string{"/v1/test"},
+		AllowedMethods:   []string{http.MethodGet},
+		CircuitBreaker:   CircuitBreakerConfig{FailureThreshold: 2, PerKey: true},
+	}, &stubTokenValidator{}, &poolKeyStore{keys: []string{"sk-a", "sk-b"}}, zap.NewNop())
+	require.NoError(t, err)
+	h := p.Handler()
+
+	for i := 0; i < 6; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/test", nil)
+		req.Header.Set("Authorization", "Bearer tok")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	mu.Lock()
+	assert.Equal(t, []string{"Bearer sk-a", "Bearer sk-b", "Bearer sk-a", "Bearer sk-b", "Bearer sk-b", "Bearer sk-b"}, seen)
+	mu.Unlock()
+
+	statuses := p.CircuitBreakers()
+	require.Len(t, statuses, 3)
+	assert.Equal(t, CircuitClosed, statuses[0].State, "the provider keeps serving through the healthy key")
+}
+
+func TestTransparentProxy_CircuitBreaker_OpenResponseAndAudit(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	collector := &SimpleAuditCollector{}
+	p, err := NewTransparentProxyWithAudit(ProxyConfig{
+		TargetBaseURL:    upstream.URL,
+		Provider:         "openai",
+		AllowedEndpoints: []string{"/v1/test"},
+		AllowedMethods:   []string{http.MethodGet},
+		CircuitBreaker:   CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute},
+	}, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop(), collector, middleware.ObservabilityConfig{})
+	require.NoError(t, err)
+	h := p.Handler()
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/test", nil)
+		req.Header.Set("Authorization", "Bearer tok")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusBadGateway, send().Code)
+
+	w := send()
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "circuit_open")
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	var changes []*audit.Event
+	for _, evt := range collector.GetEvents() {
+		if evt.Action == audit.ActionCircuitBreakerStateChange {
+			changes = append(changes, evt)
+		}
+	}
+	require.Len(t, changes, 1)
+	assert.Equal(t, audit.ResultFailure, changes[0].Result)
+	assert.Equal(t, "openai", changes[0].Details["provider"])
+	assert.Equal(t, CircuitClosed, changes[0].Details["from"])
+	assert.Equal(t, CircuitOpen, changes[0].Details["to"])
+
+	statuses := p.CircuitBreakers()
+	require.Len(t, statuses, 1)
+	assert.Equal(t, CircuitOpen, statuses[0].State)
+	assert.Equal(t, int64(1), statuses[0].Opens)
+}
+
+func TestTransparentProxy_CircuitBreaker_FallbackSkipsOpenPrimary(t *testing.T) {
+	primary := &modelUpstream{status: map[string]int{"gpt-4o": http.StatusServiceUnavailable}}
+	secondary := &modelUpstream{}
+	primarySrv := httptest.NewServer(primary)
+	defer primarySrv.Close()
+	secondarySrv := httptest.NewServer(secondary)
+	defer secondarySrv.Close()
+
+	policy := FallbackPolicy{
+		On:     []string{FallbackOn5xx},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "azure", Model: "gpt-4o"}}},
+	}
+	setup := newFallbackTestSetup(t, primarySrv.URL, secondarySrv.URL, policy, withConfig(func(cfg *ProxyConfig) {
+		cfg.CircuitBreaker = CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}
+	}))
+
+	for i := 0; i < 3; i++ {
+		w := sendChat(setup.handler, "gpt-4o", nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "azure", w.Header().Get("X-Proxy-Provider"))
+		waitForEvent(t, setup.events)
+	}
+	assert.Len(t, primary.seen(), 1, "the open circuit keeps requests off the primary")
+	assert.Len(t, secondary.seen(), 3)
+}
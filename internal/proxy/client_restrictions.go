@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"go.uber.org/zap"
+)
+
+// ClientRestrictionsStore is implemented by project stores that keep
+// per-project client allowlists. Stores without it leave projects unrestricted.
+type ClientRestrictionsStore interface {
+	// GetClientRestrictionsForProject returns the project's client allowlists
+	GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error)
+}
+
+// GetClientRestrictionsForProject returns the client allowlists of a project
+// from store, or no restrictions when store does not keep them.
+func GetClientRestrictionsForProject(ctx context.Context, store ProjectStore, projectID string) (token.ClientRestrictions, error) {
+	if cs, ok := store.(ClientRestrictionsStore); ok {
+		return cs.GetClientRestrictionsForProject(ctx, projectID)
+	}
+	return token.ClientRestrictions{}, nil
+}
+
+// enforceProjectClientRestrictions rejects requests from networks or origins
+// outside the project's allowlists.
+func (p *TransparentProxy) enforceProjectClientRestrictions(r *http.Request, projectID string) (int, ErrorResponse) {
+	restrictions, err := GetClientRestrictionsForProject(r.Context(), p.projectStore, projectID)
+	if err != nil {
+		p.logger.Error("Failed to load project client restrictions", zap.String("project_id", projectID), zap.Error(err))
+		return http.StatusServiceUnavailable, ErrorResponse{Error: "Client restrictions unavailable", Code: "policy_unavailable"}
+	}
+	return p.enforceClientRestrictions(r, restrictions, token.TokenData{}, projectID, "project")
+}
+
+// enforceClientRestrictions rejects requests whose client IP or origin is not
+// allowed by restrictions. scope names the owner of the restrictions ("project"
+// or "token") in the response and the audit event.
+func (p *TransparentProxy) enforceClientRestrictions(r *http.Request, restrictions token.ClientRestrictions, td token.TokenData, projectID, scope string) (int, ErrorResponse) {
+	if restrictions.IsEmpty() {
+		return 0, ErrorResponse{}
+	}
+
+	var reason, description string
+	switch {
+	case !restrictions.AllowsIP(getClientIP(r)):
+		reason = "ip_not_allowed"
+		description = fmt.Sprintf("client IP is not allowed for this %s", scope)
+	case !restrictions.AllowsOrigin(r.Header.Get("Origin"), r.Header.Get("Referer")):
+		reason = "origin_not_allowed"
+		description = fmt.Sprintf("origin is not allowed for this %s", scope)
+	default:
+		return 0, ErrorResponse{}
+	}
+
+	p.recordClientRestrictionDenial(reason)
+	p.auditTokenDenial(r, td, projectID, "client_restrictions", reason, map[string]any{"scope": scope})
+	return http.StatusForbidden, ErrorResponse{
+		Error:       "Client not allowed",
+		Code:        "client_not_allowed",
+		Description: description,
+	}
+}
+
+// recordClientRestrictionDenial counts a rejected request in the proxy metrics.
+func (p *TransparentProxy) recordClientRestrictionDenial(reason string) {
+	p.metrics.mu.Lock()
+	defer p.metrics.mu.Unlock()
+	if reason == "ip_not_allowed" {
+		p.metrics.ClientIPDenials++
+	} else {
+		p.metrics.OriginDenials++
+	}
+}
+
+// resolveClientIP returns the client IP of a request to the proxy.
+func (p *TransparentProxy) resolveClientIP(r *http.Request) string {
+	return ClientIP(r, p.trustedProxies)
+}
+
+// ClientIP returns the client IP, trusting X-Forwarded-For and X-Real-IP only
+// when the connection comes from one of trustedProxies. Forwarded hops are
+// read right to left, skipping trusted proxies, so a client cannot spoof its
+// address by prepending entries.
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !isTrustedProxy(trustedProxies, remote) {
+		return remote
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if i == 0 || !isTrustedProxy(trustedProxies, hop) {
+				return hop
+			}
+		}
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+	return remote
+}
+
+// isTrustedProxy reports whether ip belongs to one of trustedProxies.
+func isTrustedProxy(trustedProxies []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(strings.Trim(ip, "[]"))
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
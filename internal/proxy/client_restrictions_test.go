@@ -0,0 +1,197 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/middleware"
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// restrictedTokenValidator is a stubTokenValidator whose tokens carry client restrictions.
+type restrictedTokenValidator struct {
+	stubTokenValidator
+	restrictions token.ClientRestrictions
+}
+
+func (v *restrictedTokenValidator) GetTokenData(ctx context.Context, tokenString string) (token.TokenData, error) {
+	return token.TokenData{ID: "tok-id", Token: tokenString, ProjectID: "test-project-id", IsActive: true, ClientRestrictions: v.restrictions}, nil
+}
+
+// clientsProjectStore is a stubProjectStore with client restrictions.
+type clientsProjectStore struct {
+	stubProjectStore
+	restrictions token.ClientRestrictions
+	err          error
+}
+
+func (s *clientsProjectStore) GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	return s.restrictions, s.err
+}
+
+// withTrustedProxies trusts X-Forwarded-For from the given CIDRs.
+func withTrustedProxies(cidrs ...string) testProxyOption {
+	return withConfig(func(cfg *ProxyConfig) { cfg.TrustedProxies = cidrs })
+}
+
+func TestTransparentProxy_TokenClientRestrictions(t *testing.T) {
+	upstream := &modelUpstream{}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+	collector := &SimpleAuditCollector{}
+	validator := &restrictedTokenValidator{restrictions: token.ClientRestrictions{
+		CIDRs:   []string{"203.0.113.0/24"},
+		Origins: []string{"https://app.example.com"},
+	}}
+	// httptest requests come from 192.0.2.1
+	p := newTestProxy(t, srv.URL, withTokenValidator(validator), withAuditLogger(collector), withTrustedProxies("192.0.2.0/24"))
+	h := p.Handler()
+
+	ok := map[string]string{"X-Forwarded-For": "203.0.113.5", "Origin": "https://app.example.com"}
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", ok).Code)
+
+	w := sendChat(h, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "198.51.100.1", "Origin": "https://app.example.com"})
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "client_not_allowed")
+	assert.Contains(t, w.Body.String(), "client IP is not allowed for this token")
+
+	w = sendChat(h, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "203.0.113.5", "Referer": "https://evil.example.org/page"})
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "origin is not allowed for this token")
+
+	assert.Len(t, upstream.seen(), 1)
+	m := p.Metrics()
+	assert.Equal(t, int64(1), m.ClientIPDenials)
+	assert.Equal(t, int64(1), m.OriginDenials)
+
+	events := policyViolations(collector)
+	require.Len(t, events, 2)
+	assert.Equal(t, "client_restrictions", events[0].Details["policy"])
+	assert.Equal(t, "token", events[0].Details["scope"])
+	assert.Equal(t, "ip_not_allowed", events[0].Details["reason"])
+	assert.Equal(t, "198.51.100.1", events[0].ClientIP)
+	assert.Equal(t, "origin_not_allowed", events[1].Details["reason"])
+}
+
+func TestTransparentProxy_ClientRestrictionsIgnoreSpoofedHeaders(t *testing.T) {
+	upstream := &modelUpstream{}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+	validator := &restrictedTokenValidator{restrictions: token.ClientRestrictions{CIDRs: []string{"203.0.113.0/24"}}}
+	// Without trusted proxies the client IP is the peer address, 192.0.2.1
+	h := newTestProxy(t, srv.URL, withTokenValidator(validator)).Handler()
+
+	w := sendChat(h, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "203.0.113.5"})
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "client IP is not allowed for this token")
+	w = sendChat(h, "gpt-4o-mini", map[string]string{"X-Real-IP": "203.0.113.5"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, upstream.seen())
+
+	// Behind trusted load balancers, X-Forwarded-For is walked right to left
+	trusted := newTestProxy(t, srv.URL, withTokenValidator(validator), withTrustedProxies("192.0.2.0/24")).Handler()
+	assert.Equal(t, http.StatusOK, sendChat(trusted, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.5, 192.0.2.7"}).Code)
+	assert.Equal(t, http.StatusForbidden, sendChat(trusted, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "203.0.113.5, 198.51.100.1, 192.0.2.7"}).Code)
+}
+
+func TestTransparentProxy_ProjectClientRestrictions(t *testing.T) {
+	upstream := &modelUpstream{}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+	collector := &SimpleAuditCollector{}
+	store := &clientsProjectStore{restrictions: token.ClientRestrictions{CIDRs: []string{"203.0.113.0/24"}}}
+	// httptest requests come from 192.0.2.1
+	h := newTestProxy(t, srv.URL, withProjectStore(store), withAuditLogger(collector), withTrustedProxies("192.0.2.0/24")).Handler()
+
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "203.0.113.5"}).Code)
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "203.0.113.5, 192.0.2.9"}).Code,
+		"trusted hops are skipped")
+
+	w := sendChat(h, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "203.0.113.5, 198.51.100.1"})
+	require.Equal(t, http.StatusForbidden, w.Code, "a client cannot prepend an allowed address")
+	assert.Contains(t, w.Body.String(), "client IP is not allowed for this project")
+
+	// Forwarded headers from untrusted peers are ignored
+	untrusted := newTestProxy(t, srv.URL, withProjectStore(store), withAuditLogger(collector), withTrustedProxies("10.0.0.0/8")).Handler()
+	assert.Equal(t, http.StatusForbidden, sendChat(untrusted, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "203.0.113.5"}).Code)
+
+	events := policyViolations(collector)
+	require.Len(t, events, 2)
+	assert.Equal(t, "project", events[0].Details["scope"])
+	assert.Nil(t, events[0].Details["token_id"])
+	assert.Equal(t, "192.0.2.1", events[1].ClientIP)
+
+	store.err = errors.New("db down")
+	assert.Equal(t, http.StatusServiceUnavailable, sendChat(h, "gpt-4o-mini", nil).Code)
+}
+
+func TestTransparentProxy_InvalidTrustedProxies(t *testing.T) {
+	_, err := NewTransparentProxyWithAudit(ProxyConfig{
+		TargetBaseURL:  "http://upstream.invalid",
+		TrustedProxies: []string{"lb.internal"},
+	}, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop(), nil, middleware.ObservabilityConfig{})
+	assert.Error(t, err)
+}
+
+func TestResolveClientIP(t *testing.T) {
+	p := newTestProxy(t, "http://upstream.invalid", withTrustedProxies("10.0.0.0/8"))
+	tests := []struct {
+		name, remote, xff, xri, want string
+	}{
+		{"untrusted peer", "198.51.100.1:4000", "203.0.113.5", "", "198.51.100.1"},
+		{"trusted peer", "10.0.0.2:4000", "203.0.113.5", "", "203.0.113.5"},
+		{"rightmost untrusted hop", "10.0.0.2:4000", "1.1.1.1, 203.0.113.5, 10.0.0.3", "", "203.0.113.5"},
+		{"all hops trusted", "10.0.0.2:4000", "10.0.0.4, 10.0.0.3", "", "10.0.0.4"},
+		{"x-real-ip", "10.0.0.2:4000", "", "203.0.113.5", "203.0.113.5"},
+		{"no headers", "10.0.0.2:4000", "", "", "10.0.0.2"},
+		{"ipv6 peer", "[2001:db8::1]:4000", "203.0.113.5", "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xri != "" {
+				r.Header.Set("X-Real-IP", tt.xri)
+			}
+			assert.Equal(t, tt.want, p.resolveClientIP(r))
+		})
+	}
+}
+
+// clientsCountingProjectStore counts client restriction lookups.
+type clientsCountingProjectStore struct {
+	countingProjectStore
+	n int
+}
+
+func (s *clientsCountingProjectStore) GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	s.n++
+	return token.ClientRestrictions{CIDRs: []string{"10.0.0.0/8"}}, nil
+}
+
+func TestCachedProjectActiveStore_GetClientRestrictionsForProject(t *testing.T) {
+	under := &clientsCountingProjectStore{}
+	c := NewCachedProjectActiveStore(under, CachedProjectActiveStoreConfig{TTL: time.Minute, Max: 10})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		restrictions, err := GetClientRestrictionsForProject(ctx, c, "p1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/8"}, restrictions.CIDRs)
+	}
+	assert.Equal(t, 1, under.n)
+
+	require.NoError(t, c.UpdateProject(ctx, Project{ID: "p1"}))
+	_, _ = GetClientRestrictionsForProject(ctx, c, "p1")
+	assert.Equal(t, 2, under.n, "expected update to purge the cached restrictions")
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"go.uber.org/zap"
+)
+
+// SetConcurrencyLimiter sets the limiter that caps in-flight requests per
+// project and per token.
+func (p *TransparentProxy) SetConcurrencyLimiter(l *token.ConcurrencyLimiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.concurrency = l
+}
+
+// acquireConcurrency takes a slot for the request under the concurrency
+// limits of its project and token. The slot is held until the returned lease
+// is released, which for streams is when the stream ends. It writes a 429 and
+// returns false when a limit is exhausted.
+func (p *TransparentProxy) acquireConcurrency(w http.ResponseWriter, r *http.Request) (*token.ConcurrencyLease, bool) {
+	if p.concurrency == nil {
+		return nil, true
+	}
+	acct, ok := r.Context().Value(ctxKeyCostAccount).(*costAccount)
+	if !ok {
+		return nil, true
+	}
+	lease, err := p.concurrency.Acquire(r.Context(), acct.projectID, acct.tokenID)
+	var limitErr *token.ConcurrencyLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		p.logger.Info("Concurrency limit exceeded",
+			zap.String("project_id", acct.projectID),
+			zap.String("scope", limitErr.Scope),
+			zap.Int64("limit", limitErr.Limit))
+		retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		writeErrorResponseForRequest(w, r, http.StatusTooManyRequests, ErrorResponse{
+			Error:       "Rate limit exceeded",
+			Code:        "concurrency_limit_exceeded",
+			Description: "concurrency limit of the " + limitErr.Scope + " exhausted",
+		})
+		return nil, false
+	case err != nil:
+		p.logger.Error("Failed to acquire concurrency slot", zap.String("project_id", acct.projectID), zap.Error(err))
+		writeErrorResponseForRequest(w, r, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "Rate limit unavailable",
+			Code:  "rate_limit_unavailable",
+		})
+		return nil, false
+	}
+	return lease, true
+}
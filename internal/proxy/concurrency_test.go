@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransparentProxy_EnforcesConcurrencyLimits(t *testing.T) {
+	started := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("data: {\"choices\":[]}\n\n"))
+		w.(http.Flusher).Flush()
+		started <- struct{}{}
+		<-unblock
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+	defer close(unblock)
+
+	limiter := token.NewConcurrencyLimiter(token.NewMemoryConcurrencyStore(), token.ConcurrencyLimiterConfig{Limits: token.ConcurrencyLimits{PerToken: 1}})
+	p := newTestProxy(t, upstream.URL, withTokenValidator(&limitedTokenValidator{}))
+	p.SetConcurrencyLimiter(limiter)
+	h := p.Handler()
+
+	send := func() *httptest.ResponseRecorder {
+		body := `{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}],"stream":true}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	// The open stream holds the token's only slot
+	streamDone := make(chan *httptest.ResponseRecorder, 1)
+	go func() { streamDone <- send() }()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not start")
+	}
+
+	w := send()
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "concurrency_limit_exceeded", resp.Code)
+	assert.Contains(t, resp.Description, "token")
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// Ending the stream frees the slot
+	unblock <- struct{}{}
+	select {
+	case w := <-streamDone:
+		require.Equal(t, http.StatusOK, w.Code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not finish")
+	}
+	go func() { <-started; unblock <- struct{}{} }()
+	assert.Equal(t, http.StatusOK, send().Code)
+}
@@ -15,6 +15,8 @@ type APIConfig struct {
 	APIs map[string]*APIProviderConfig `yaml:"apis"`
 	// DefaultAPI is the default API provider to use if not specified
 	DefaultAPI string `yaml:"default_api"`
+	// ModelRoutes dispatch /v1/ requests to providers by the request's model
+	ModelRoutes []ModelRoute `yaml:"model_routes"`
 }
 
 // APIProviderConfig represents the configuration for a specific API provider
@@ -33,6 +35,158 @@ type APIProviderConfig struct {
 	ParamWhitelist  map[string][]string `yaml:"param_whitelist"`
 	AllowedOrigins  []string            `yaml:"allowed_origins"`
 	RequiredHeaders []string            `yaml:"required_headers"`
+	// KeyPool controls how a key is picked when a project stores several upstream keys
+	KeyPool KeyPoolConfig `yaml:"key_pool"`
+	// Auth controls how the upstream credential is injected into requests
+	Auth UpstreamAuthConfig `yaml:"auth"`
+	// Mode selects provider-specific request mapping: "" (passthrough), azure_openai,
+	// or anthropic/gemini to serve OpenAI chat completions from those APIs
+	Mode string `yaml:"mode"`
+	// Azure configures deployment mapping for the azure_openai mode
+	Azure AzureOpenAIConfig `yaml:"azure"`
+	// Fallback configures the models and providers retried when the upstream fails
+	Fallback FallbackConfig `yaml:"fallback"`
+	// Retry configures retries of the same upstream request after transient failures
+	Retry RetryConfig `yaml:"retry"`
+	// CircuitBreaker configures the breaker that stops traffic to a failing upstream
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"`
+	// Hedge configures duplicate requests sent when the upstream is slow
+	Hedge HedgeConfig `yaml:"hedge"`
+	// ResponseTransforms rewrite upstream responses before they reach the client
+	ResponseTransforms []ResponseTransformConfig `yaml:"response_transforms"`
+	// RequestPolicy checks and rewrites JSON request bodies before they are proxied
+	RequestPolicy []RequestPolicyRule `yaml:"request_policy"`
+	// TokenBindings require requests to match values stored in token metadata
+	TokenBindings TokenBindingConfig `yaml:"token_bindings"`
+}
+
+// TokenBindingConfig binds requests to token metadata. A binding only applies
+// to tokens whose metadata contains its key.
+type TokenBindingConfig struct {
+	// Headers maps request header names to metadata keys, e.g. X-Proxy-User-Id: user_id
+	Headers map[string]string `yaml:"headers"`
+	// ClientIP is the metadata key holding the client IP a token is bound to
+	ClientIP string `yaml:"client_ip"`
+}
+
+// RetryConfig controls retries of failed upstream requests for a provider
+type RetryConfig struct {
+	// MaxAttempts is the total number of tries including the first (0 or 1 disables retries)
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the wait before the first retry, doubled for each further one (default 200ms)
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	// MaxBackoff caps backoff and Retry-After waits (default 5s)
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// RetryOn lists the upstream status codes that are retried (default 429, 502, 503, 504)
+	RetryOn []int `yaml:"retry_on"`
+	// IgnoreRetryAfter uses the backoff even when the upstream sends Retry-After
+	IgnoreRetryAfter bool `yaml:"ignore_retry_after"`
+}
+
+// CircuitBreakerConfig controls the circuit breaker of a provider
+type CircuitBreakerConfig struct {
+	// Disabled turns the circuit breaker off
+	Disabled bool `yaml:"disabled"`
+	// FailureThreshold is the number of consecutive failures that opens the circuit (default 5)
+	FailureThreshold int `yaml:"failure_threshold"`
+	// Cooldown is how long the circuit stays open before probing (default 30s)
+	Cooldown time.Duration `yaml:"cooldown"`
+	// HalfOpenRequests is the number of concurrent probes allowed while half-open (default 1)
+	HalfOpenRequests int `yaml:"half_open_requests"`
+	// SuccessThreshold is the number of successful probes that closes the circuit (default 1)
+	SuccessThreshold int `yaml:"success_threshold"`
+	// FailureStatuses lists the upstream status codes counted as failures (default 502, 503, 504)
+	FailureStatuses []int `yaml:"failure_statuses"`
+	// PerKey adds a breaker per pooled upstream key; keys with an open circuit are skipped
+	PerKey bool `yaml:"per_key"`
+}
+
+// HedgeConfig controls hedged requests for a provider. Non-streaming requests
+// that get no response within Delay are sent a second time; the first response wins.
+type HedgeConfig struct {
+	// Delay is how long to wait before sending the duplicate (0 disables hedging)
+	Delay time.Duration `yaml:"delay"`
+	// Endpoints limits hedging to these endpoint prefixes (default all allowed endpoints)
+	Endpoints []string `yaml:"endpoints"`
+	// BaseURL sends the duplicate to another upstream with the same API (default base_url)
+	BaseURL string `yaml:"base_url"`
+}
+
+// ResponseTransformConfig is one step of a provider's response transform
+// pipeline. String values may use the ${project_id}, ${request_id},
+// ${provider} and ${token_hash} placeholders.
+type ResponseTransformConfig struct {
+	// Op is json_delete, json_set, header_add or header_remove
+	Op string `yaml:"op"`
+	// Path is the JSONPath of the json_delete/json_set target, e.g. $.choices[*].logprobs
+	Path string `yaml:"path"`
+	// Header is the header name for header_add/header_remove
+	Header string `yaml:"header"`
+	// Value is the JSON value for json_set or the string value for header_add
+	Value any `yaml:"value"`
+}
+
+// RequestPolicyRule constrains or rewrites one field of JSON request bodies.
+// The client's value is checked first, then Default or Set is applied. String
+// values may use the same placeholders as response transforms.
+type RequestPolicyRule struct {
+	// Path is the JSONPath of the field, e.g. $.max_tokens or $.response_format.type
+	Path string `yaml:"path" json:"path"`
+	// Endpoints limits the rule to these endpoint prefixes (default all endpoints)
+	Endpoints []string `yaml:"endpoints" json:"endpoints,omitempty"`
+	// Forbidden rejects requests that set the field
+	Forbidden bool `yaml:"forbidden" json:"forbidden,omitempty"`
+	// Min is the smallest allowed numeric value
+	Min *float64 `yaml:"min" json:"min,omitempty"`
+	// Max is the largest allowed numeric value
+	Max *float64 `yaml:"max" json:"max,omitempty"`
+	// Allowed lists glob patterns the value must match
+	Allowed []string `yaml:"allowed" json:"allowed,omitempty"`
+	// Default is set when the client omits the field
+	Default any `yaml:"default" json:"default,omitempty"`
+	// Set replaces the client's value (or adds the field)
+	Set any `yaml:"set" json:"set,omitempty"`
+}
+
+// FallbackConfig defines per-model fallback chains for a provider
+type FallbackConfig struct {
+	// On lists the failures that trigger a fallback: 5xx, 429 and timeout (default all)
+	On []string `yaml:"on"`
+	// Chains maps model names or globs to the targets tried in order after a failure.
+	// Targets are a model on the same provider or "provider/model".
+	Chains map[string][]string `yaml:"chains"`
+}
+
+// AzureOpenAIConfig maps OpenAI model names to Azure OpenAI deployments
+type AzureOpenAIConfig struct {
+	// APIVersion is sent as the api-version query parameter (default 2024-10-21)
+	APIVersion string `yaml:"api_version"`
+	// Deployments maps model names or globs (e.g. gpt-4o*) to deployment names
+	Deployments map[string]string `yaml:"deployments"`
+	// DefaultDeployment is used when no mapping matches and the request has no model
+	DefaultDeployment string `yaml:"default_deployment"`
+}
+
+// UpstreamAuthConfig describes how a provider expects its API key
+type UpstreamAuthConfig struct {
+	// Style is one of bearer (default), x-api-key, query-param or header
+	Style string `yaml:"style"`
+	// Header is the header name for the x-api-key (default "x-api-key") and header styles
+	Header string `yaml:"header"`
+	// Prefix is prepended to the key in the credential header (e.g. "Token ")
+	Prefix string `yaml:"prefix"`
+	// QueryParam is the parameter name for the query-param style (default "key")
+	QueryParam string `yaml:"query_param"`
+	// Headers are set on upstream requests that do not already carry them (e.g. anthropic-version)
+	Headers map[string]string `yaml:"headers"`
+}
+
+// KeyPoolConfig contains upstream key pool settings for a provider
+type KeyPoolConfig struct {
+	// Strategy is one of round_robin (default), least_recently_limited or weighted
+	Strategy string `yaml:"strategy"`
+	// BenchDuration is how long a key is skipped after a 429/401 (default 30s)
+	BenchDuration time.Duration `yaml:"bench_duration"`
 }
 
 // TimeoutConfig contains timeout settings for the proxy
@@ -99,6 +253,57 @@ func validateAPIConfig(config *APIConfig) error {
 		if len(api.AllowedMethods) == 0 {
 			return fmt.Errorf("API '%s' has no allowed_methods", name)
 		}
+
+		switch api.KeyPool.Strategy {
+		case "", KeyPoolRoundRobin, KeyPoolLeastRecentlyLimited, KeyPoolWeighted:
+		default:
+			return fmt.Errorf("API '%s' has unknown key_pool strategy '%s'", name, api.KeyPool.Strategy)
+		}
+
+		if err := validateUpstreamAuth(api.Auth); err != nil {
+			return fmt.Errorf("API '%s' has invalid auth: %w", name, err)
+		}
+
+		switch api.Mode {
+		case "", ProviderModeAzureOpenAI, ProviderModeAnthropic, ProviderModeGemini:
+		default:
+			return fmt.Errorf("API '%s' has unknown mode '%s'", name, api.Mode)
+		}
+
+		if err := validateRetryConfig(api.Retry); err != nil {
+			return fmt.Errorf("API '%s' has invalid retry: %w", name, err)
+		}
+
+		if err := validateCircuitBreakerConfig(api.CircuitBreaker); err != nil {
+			return fmt.Errorf("API '%s' has invalid circuit_breaker: %w", name, err)
+		}
+
+		if err := validateHedgeConfig(api.Hedge); err != nil {
+			return fmt.Errorf("API '%s' has invalid hedge: %w", name, err)
+		}
+
+		if _, err := newResponseTransformer(api.ResponseTransforms, name); err != nil {
+			return fmt.Errorf("API '%s' has invalid response_transforms: %w", name, err)
+		}
+
+		if err := ValidateRequestPolicy(api.RequestPolicy); err != nil {
+			return fmt.Errorf("API '%s' has invalid request_policy: %w", name, err)
+		}
+
+		if err := validateTokenBindings(api.TokenBindings); err != nil {
+			return fmt.Errorf("API '%s' has invalid token_bindings: %w", name, err)
+		}
+
+		if _, err := resolveFallbackConfig(api.Fallback, name, config.APIs); err != nil {
+			return fmt.Errorf("API '%s' has invalid fallback: %w", name, err)
+		}
+	}
+
+	if err := validateModelRoutes(config.ModelRoutes, func(name string) bool {
+		_, ok := config.APIs[name]
+		return ok
+	}); err != nil {
+		return err
 	}
 
 	return nil
@@ -120,6 +325,8 @@ func (c *APIConfig) GetProxyConfigForAPI(apiName string) (*ProxyConfig, error) {
 	// Create the proxy configuration
 	proxyConfig := ProxyConfig{
 		TargetBaseURL:         apiConfig.BaseURL,
+		Provider:              apiName,
+		ProviderKeyFallback:   apiName == c.DefaultAPI,
 		AllowedEndpoints:      apiConfig.AllowedEndpoints,
 		AllowedMethods:        apiConfig.AllowedMethods,
 		RequestTimeout:        apiConfig.Timeouts.Request,
@@ -131,6 +338,47 @@ func (c *APIConfig) GetProxyConfigForAPI(apiName string) (*ProxyConfig, error) {
 		ParamWhitelist:        apiConfig.ParamWhitelist,
 		AllowedOrigins:        apiConfig.AllowedOrigins,
 		RequiredHeaders:       apiConfig.RequiredHeaders,
+		KeyPoolStrategy:       apiConfig.KeyPool.Strategy,
+		KeyPoolBenchDuration:  apiConfig.KeyPool.BenchDuration,
+		UpstreamAuth:          apiConfig.Auth,
+		Mode:                  apiConfig.Mode,
+		Azure:                 apiConfig.Azure,
+		Retry:                 apiConfig.Retry,
+		CircuitBreaker:        apiConfig.CircuitBreaker,
+		Hedge:                 apiConfig.Hedge,
+		ResponseTransforms:    apiConfig.ResponseTransforms,
+		RequestPolicy:         apiConfig.RequestPolicy,
+		TokenBindings:         apiConfig.TokenBindings,
+	}
+
+	fallback, err := resolveFallbackConfig(apiConfig.Fallback, apiName, c.APIs)
+	if err != nil {
+		return nil, fmt.Errorf("API '%s' has invalid fallback: %w", apiName, err)
+	}
+	proxyConfig.Fallback = fallback
+
+	// Azure OpenAI authenticates with an api-key header unless configured otherwise
+	if proxyConfig.Mode == ProviderModeAzureOpenAI && proxyConfig.UpstreamAuth.Style == "" {
+		proxyConfig.UpstreamAuth.Style = AuthStyleHeader
+		proxyConfig.UpstreamAuth.Header = "api-key"
+	}
+
+	// Translating modes talk to the native APIs, which use their own auth headers
+	if proxyConfig.UpstreamAuth.Style == "" {
+		switch proxyConfig.Mode {
+		case ProviderModeAnthropic:
+			proxyConfig.UpstreamAuth.Style = AuthStyleXAPIKey
+			if _, ok := proxyConfig.UpstreamAuth.Headers["anthropic-version"]; !ok {
+				headers := map[string]string{"anthropic-version": defaultAnthropicVersion}
+				for k, v := range proxyConfig.UpstreamAuth.Headers {
+					headers[k] = v
+				}
+				proxyConfig.UpstreamAuth.Headers = headers
+			}
+		case ProviderModeGemini:
+			proxyConfig.UpstreamAuth.Style = AuthStyleHeader
+			proxyConfig.UpstreamAuth.Header = "x-goog-api-key"
+		}
 	}
 
 	return &proxyConfig, nil
@@ -3,6 +3,7 @@ package proxy
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -195,6 +196,344 @@ func TestValidateAPIConfig(t *testing.T) {
 		},
 	}
 	assert.Error(t, validateAPIConfig(missingMethodsConfig), "Config with no allowed methods should return error")
+
+	// Test unknown key pool strategy
+	badKeyPoolConfig := &APIConfig{
+		DefaultAPI: "api1",
+		APIs: map[string]*APIProviderConfig{
+			"api1": {
+				BaseURL:          "https://api.example.com",
+				AllowedEndpoints: []string{"/v1/test"},
+				AllowedMethods:   []string{"GET"},
+				KeyPool:          KeyPoolConfig{Strategy: "random"},
+			},
+		},
+	}
+	assert.Error(t, validateAPIConfig(badKeyPoolConfig), "Config with unknown key pool strategy should return error")
+
+	// Test unknown auth style
+	badAuthConfig := &APIConfig{
+		DefaultAPI: "api1",
+		APIs: map[string]*APIProviderConfig{
+			"api1": {
+				BaseURL:          "https://api.example.com",
+				AllowedEndpoints: []string{"/v1/test"},
+				AllowedMethods:   []string{"GET"},
+				Auth:             UpstreamAuthConfig{Style: "basic"},
+			},
+		},
+	}
+	assert.Error(t, validateAPIConfig(badAuthConfig), "Config with unknown auth style should return error")
+
+	// Test unknown provider mode
+	badModeConfig := &APIConfig{
+		DefaultAPI: "api1",
+		APIs: map[string]*APIProviderConfig{
+			"api1": {
+				BaseURL:          "https://api.example.com",
+				AllowedEndpoints: []string{"/v1/test"},
+				AllowedMethods:   []string{"GET"},
+				Mode:             "vertex",
+			},
+		},
+	}
+	assert.Error(t, validateAPIConfig(badModeConfig), "Config with unknown mode should return error")
+}
+
+func TestLoadAPIConfigFromFile_UpstreamAuth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	content := `
+default_api: anthropic
+apis:
+  anthropic:
+    base_url: https://api.anthropic.com
+    allowed_endpoints: ["/v1/messages"]
+    allowed_methods: ["POST"]
+    auth:
+      style: x-api-key
+      headers:
+        anthropic-version: "2023-06-01"
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg, err := LoadAPIConfigFromFile(path)
+	require.NoError(t, err)
+	proxyCfg, err := cfg.GetProxyConfigForAPI("anthropic")
+	require.NoError(t, err)
+	assert.Equal(t, AuthStyleXAPIKey, proxyCfg.UpstreamAuth.Style)
+	assert.Equal(t, "2023-06-01", proxyCfg.UpstreamAuth.Headers["anthropic-version"])
+}
+
+func TestLoadAPIConfigFromFile_Fallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	content := `
+default_api: openai
+apis:
+  openai:
+    base_url: https://api.openai.com
+    allowed_endpoints: ["/v1/chat/completions"]
+    allowed_methods: ["POST"]
+    fallback:
+      on: ["5xx", "timeout"]
+      chains:
+        gpt-4o: ["gpt-4o-mini", "azure/gpt-4o"]
+  azure:
+    base_url: https://example.openai.azure.com
+    allowed_endpoints: ["/v1/chat/completions"]
+    allowed_methods: ["POST"]
+    mode: azure_openai
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg, err := LoadAPIConfigFromFile(path)
+	require.NoError(t, err)
+	proxyCfg, err := cfg.GetProxyConfigForAPI("openai")
+	require.NoError(t, err)
+	assert.Equal(t, []string{FallbackOn5xx, FallbackOnTimeout}, proxyCfg.Fallback.On)
+	assert.Equal(t, []FallbackTarget{
+		{Provider: "openai", Model: "gpt-4o-mini"},
+		{Provider: "azure", Model: "gpt-4o"},
+	}, proxyCfg.Fallback.Chains["gpt-4o"])
+
+	invalid := strings.Replace(content, `on: ["5xx", "timeout"]`, `on: ["4xx"]`, 1)
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	_, err = LoadAPIConfigFromFile(path)
+	assert.ErrorContains(t, err, "API 'openai' has invalid fallback")
+}
+
+func TestLoadAPIConfigFromFile_Retry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	content := `
+apis:
+  openai:
+    base_url: https://api.openai.com
+    allowed_endpoints: ["/v1/chat/completions"]
+    allowed_methods: ["POST"]
+    retry:
+      max_attempts: 3
+      initial_backoff: 100ms
+      max_backoff: 2s
+      retry_on: [500, 503]
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg, err := LoadAPIConfigFromFile(path)
+	require.NoError(t, err)
+	proxyCfg, err := cfg.GetProxyConfigForAPI("openai")
+	require.NoError(t, err)
+	assert.Equal(t, RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		RetryOn:        []int{500, 503},
+	}, proxyCfg.Retry)
+
+	invalid := strings.Replace(content, "retry_on: [500, 503]", "retry_on: [42]", 1)
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	_, err = LoadAPIConfigFromFile(path)
+	assert.ErrorContains(t, err, "API 'openai' has invalid retry")
+}
+
+func TestLoadAPIConfigFromFile_CircuitBreaker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	content := `
+apis:
+  openai:
+    base_url: https://api.openai.com
+    allowed_endpoints: ["/v1/chat/completions"]
+    allowed_methods: ["POST"]
+    circuit_breaker:
+      failure_threshold: 3
+      cooldown: 10s
+      half_open_requests: 2
+      success_threshold: 2
+      failure_statuses: [500, 502]
+      per_key: true
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg, err := LoadAPIConfigFromFile(path)
+	require.NoError(t, err)
+	proxyCfg, err := cfg.GetProxyConfigForAPI("openai")
+	require.NoError(t, err)
+	assert.Equal(t, CircuitBreakerConfig{
+		FailureThreshold: 3,
+		Cooldown:         10 * time.Second,
+		HalfOpenRequests: 2,
+		SuccessThreshold: 2,
+		FailureStatuses:  []int{500, 502},
+		PerKey:           true,
+	}, proxyCfg.CircuitBreaker)
+
+	invalid := strings.Replace(content, "failure_threshold: 3", "failure_threshold: -3", 1)
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	_, err = LoadAPIConfigFromFile(path)
+	assert.ErrorContains(t, err, "API 'openai' has invalid circuit_breaker")
+}
+
+func TestLoadAPIConfigFromFile_Hedge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	content := `
+apis:
+  openai:
+    base_url: https://api.openai.com
+    allowed_endpoints: ["/v1/embeddings"]
+    allowed_methods: ["POST"]
+    hedge:
+      delay: 800ms
+      endpoints: ["/v1/embeddings"]
+      base_url: https://eu.api.openai.com
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg, err := LoadAPIConfigFromFile(path)
+	require.NoError(t, err)
+	proxyCfg, err := cfg.GetProxyConfigForAPI("openai")
+	require.NoError(t, err)
+	assert.Equal(t, HedgeConfig{
+		Delay:     800 * time.Millisecond,
+		Endpoints: []string{"/v1/embeddings"},
+		BaseURL:   "https://eu.api.openai.com",
+	}, proxyCfg.Hedge)
+
+	invalid := strings.Replace(content, "https://eu.api.openai.com", "eu.api.openai.com", 1)
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	_, err = LoadAPIConfigFromFile(path)
+	assert.ErrorContains(t, err, "API 'openai' has invalid hedge")
+}
+
+func TestLoadAPIConfigFromFile_ResponseTransforms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	content := `
+apis:
+  openai:
+    base_url: https://api.openai.com
+    allowed_endpoints: ["/v1/chat/completions"]
+    allowed_methods: ["POST"]
+    response_transforms:
+      - op: json_delete
+        path: $.system_fingerprint
+      - op: json_set
+        path: $.x_usage
+        value:
+          project: ${project_id}
+          weight: 2
+      - op: header_remove
+        header: openai-organization
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg, err := LoadAPIConfigFromFile(path)
+	require.NoError(t, err)
+	proxyCfg, err := cfg.GetProxyConfigForAPI("openai")
+	require.NoError(t, err)
+	assert.Equal(t, []ResponseTransformConfig{
+		{Op: TransformJSONDelete, Path: "$.system_fingerprint"},
+		{Op: TransformJSONSet, Path: "$.x_usage", Value: map[string]any{"project": "${project_id}", "weight": 2}},
+		{Op: TransformHeaderRemove, Header: "openai-organization"},
+	}, proxyCfg.ResponseTransforms)
+
+	invalid := strings.Replace(content, "path: $.system_fingerprint", "path: system_fingerprint", 1)
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	_, err = LoadAPIConfigFromFile(path)
+	assert.ErrorContains(t, err, "API 'openai' has invalid response_transforms")
+}
+
+func TestLoadAPIConfigFromFile_RequestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	content := `
+apis:
+  openai:
+    base_url: https://api.openai.com
+    allowed_endpoints: ["/v1/chat/completions"]
+    allowed_methods: ["POST"]
+    request_policy:
+      - path: $.max_tokens
+        max: 4000
+      - path: $.tools
+        forbidden: true
+        endpoints: ["/v1/chat/completions"]
+      - path: $.store
+        set: false
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	cfg, err := LoadAPIConfigFromFile(path)
+	require.NoError(t, err)
+	proxyCfg, err := cfg.GetProxyConfigForAPI("openai")
+	require.NoError(t, err)
+	limit := 4000.0
+	assert.Equal(t, []RequestPolicyRule{
+		{Path: "$.max_tokens", Max: &limit},
+		{Path: "$.tools", Forbidden: true, Endpoints: []string{"/v1/chat/completions"}},
+		{Path: "$.store", Set: false},
+	}, proxyCfg.RequestPolicy)
+
+	invalid := strings.Replace(content, "forbidden: true", "forbidden: true\n        max: 1", 1)
+	require.NoError(t, os.WriteFile(path, []byte(invalid), 0o600))
+	_, err = LoadAPIConfigFromFile(path)
+	assert.ErrorContains(t, err, "API 'openai' has invalid request_policy")
+}
+
+func TestGetProxyConfigForAPI_KeyPool(t *testing.T) {
+	apiConfig := &APIConfig{
+		DefaultAPI: "api1",
+		APIs: map[string]*APIProviderConfig{
+			"api1": {
+				BaseURL:          "https://api.example.com",
+				AllowedEndpoints: []string{"/v1/test"},
+				AllowedMethods:   []string{"GET"},
+				KeyPool:          KeyPoolConfig{Strategy: KeyPoolWeighted, BenchDuration: time.Minute},
+			},
+		},
+	}
+	cfg, err := apiConfig.GetProxyConfigForAPI("api1")
+	assert.NoError(t, err)
+	assert.Equal(t, KeyPoolWeighted, cfg.KeyPoolStrategy)
+	assert.Equal(t, time.Minute, cfg.KeyPoolBenchDuration)
+}
+
+func TestGetProxyConfigForAPI_TranslatingModeAuthDefaults(t *testing.T) {
+	apiConfig := &APIConfig{
+		DefaultAPI: "claude",
+		APIs: map[string]*APIProviderConfig{
+			"claude": {
+				BaseURL:          "https://api.anthropic.com",
+				AllowedEndpoints: []string{"/v1/chat/completions"},
+				AllowedMethods:   []string{"POST"},
+				Mode:             ProviderModeAnthropic,
+			},
+			"gemini": {
+				BaseURL:          "https://generativelanguage.googleapis.com",
+				AllowedEndpoints: []string{"/v1/chat/completions"},
+				AllowedMethods:   []string{"POST"},
+				Mode:             ProviderModeGemini,
+			},
+			"gemini-query": {
+				BaseURL:          "https://generativelanguage.googleapis.com",
+				AllowedEndpoints: []string{"/v1/chat/completions"},
+				AllowedMethods:   []string{"POST"},
+				Mode:             ProviderModeGemini,
+				Auth:             UpstreamAuthConfig{Style: AuthStyleQueryParam},
+			},
+		},
+	}
+	require.NoError(t, validateAPIConfig(apiConfig))
+
+	cfg, err := apiConfig.GetProxyConfigForAPI("claude")
+	require.NoError(t, err)
+	assert.Equal(t, AuthStyleXAPIKey, cfg.UpstreamAuth.Style)
+	assert.Equal(t, defaultAnthropicVersion, cfg.UpstreamAuth.Headers["anthropic-version"])
+
+	cfg, err = apiConfig.GetProxyConfigForAPI("gemini")
+	require.NoError(t, err)
+	assert.Equal(t, AuthStyleHeader, cfg.UpstreamAuth.Style)
+	assert.Equal(t, "x-goog-api-key", cfg.UpstreamAuth.Header)
+
+	// Explicit auth settings are kept.
+	cfg, err = apiConfig.GetProxyConfigForAPI("gemini-query")
+	require.NoError(t, err)
+	assert.Equal(t, AuthStyleQueryParam, cfg.UpstreamAuth.Style)
 }
 
 func TestLoadAPIConfigFromFile_Valid(t *testing.T) {
@@ -0,0 +1,395 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/eventtransformer"
+	"github.com/sofatutor/llm-proxy/internal/pricing"
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"go.uber.org/zap"
+)
+
+// maxCostCaptureBytes caps how much of a response is kept to read its usage.
+// Usage in longer responses is estimated from what was captured.
+const maxCostCaptureBytes = 8 * 1024 * 1024
+
+// maxCostRequestBytes caps how much of a request body is kept for usage
+// estimates. Longer bodies are streamed upstream without a copy.
+const maxCostRequestBytes = 8 * 1024 * 1024
+
+// CostDelta is an increment to the cost totals of a token or project.
+type CostDelta struct {
+	Requests          int64
+	InputTokens       int64
+	OutputTokens      int64
+	CachedInputTokens int64
+	CostUSD           float64
+}
+
+func (d *CostDelta) add(o CostDelta) {
+	d.Requests += o.Requests
+	d.InputTokens += o.InputTokens
+	d.OutputTokens += o.OutputTokens
+	d.CachedInputTokens += o.CachedInputTokens
+	d.CostUSD += o.CostUSD
+}
+
+// CostStore defines the interface for persisting cost totals.
+type CostStore interface {
+	// AddCosts adds deltas to the cost totals of tokens (keyed by token ID)
+	// and projects (keyed by project ID).
+	AddCosts(ctx context.Context, tokenDeltas, projectDeltas map[string]CostDelta) error
+}
+
+// CostAggregatorConfig holds configuration for the cost aggregator.
+type CostAggregatorConfig struct {
+	BufferSize    int           // Size of the buffered channel (default: 1000)
+	FlushInterval time.Duration // How often to flush costs to DB (default: 5s)
+	BatchSize     int           // Max events before flush (default: 100)
+}
+
+// DefaultCostAggregatorConfig returns the default configuration.
+func DefaultCostAggregatorConfig() CostAggregatorConfig {
+	return CostAggregatorConfig{
+		BufferSize:    1000,
+		FlushInterval: 5 * time.Second,
+		BatchSize:     100,
+	}
+}
+
+type costEvent struct {
+	tokenID   string
+	projectID string
+	delta     CostDelta
+}
+
+// CostAggregator prices the usage of completed requests with a price catalog
+// and periodically flushes per-token and per-project totals to the database.
+// It uses a buffered channel for non-blocking enqueue and drops events when
+// the buffer is full.
+type CostAggregator struct {
+	config   CostAggregatorConfig
+	store    CostStore
+	prices   *pricing.Catalog
+	budgets  *BudgetTracker
+	logger   *zap.Logger
+	eventsCh chan costEvent
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	mu       sync.RWMutex
+	stopped  bool
+}
+
+// NewCostAggregator creates a new aggregator pricing usage with prices.
+func NewCostAggregator(config CostAggregatorConfig, store CostStore, prices *pricing.Catalog, logger *zap.Logger) *CostAggregator {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1000
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if prices == nil {
+		prices = pricing.NewCatalog(nil)
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &CostAggregator{
+		config:   config,
+		store:    store,
+		prices:   prices,
+		logger:   logger,
+		eventsCh: make(chan costEvent, config.BufferSize),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start begins the background aggregation worker.
+func (a *CostAggregator) Start() {
+	go a.run()
+}
+
+// Stop gracefully shuts down the aggregator, flushing any pending costs.
+func (a *CostAggregator) Stop(ctx context.Context) error {
+	a.mu.Lock()
+	if a.stopped {
+		a.mu.Unlock()
+		return nil
+	}
+	a.stopped = true
+	a.mu.Unlock()
+
+	close(a.stopCh)
+
+	select {
+	case <-a.doneCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetBudgetTracker sets the tracker that priced usage is counted against.
+// It must be called before Start.
+func (a *CostAggregator) SetBudgetTracker(t *BudgetTracker) {
+	a.budgets = t
+}
+
+// RecordResponse extracts the usage of a completed request from its response
+// body and records it. The request body is used to estimate usage when the
+// response has no usage block.
+func (a *CostAggregator) RecordResponse(tokenID, projectID string, requestBody, responseBody []byte) {
+	if usage, ok := responseUsage(requestBody, responseBody); ok {
+		a.RecordUsage(tokenID, projectID, usage)
+	}
+}
+
+// responseUsage extracts the usage of a completed request, taking the model
+// from the request when the response does not name it.
+func responseUsage(requestBody, responseBody []byte) (eventtransformer.Usage, bool) {
+	usage, ok := eventtransformer.ExtractUsage(requestBody, responseBody)
+	if ok && usage.Model == "" {
+		usage.Model = requestModelFromBody(requestBody)
+	}
+	return usage, ok
+}
+
+// RecordUsage prices usage and enqueues it for the token and project. Usage
+// of models without a price is counted at no cost. This is non-blocking; if
+// the buffer is full, the event is dropped.
+func (a *CostAggregator) RecordUsage(tokenID, projectID string, usage eventtransformer.Usage) {
+	if projectID == "" {
+		return
+	}
+
+	a.mu.RLock()
+	stopped := a.stopped
+	a.mu.RUnlock()
+	if stopped {
+		return
+	}
+
+	delta := CostDelta{
+		Requests:          1,
+		InputTokens:       int64(usage.InputTokens),
+		OutputTokens:      int64(usage.OutputTokens),
+		CachedInputTokens: int64(usage.CachedInputTokens),
+	}
+	if price, ok := a.prices.Lookup(usage.Model); ok {
+		delta.CostUSD = price.Cost(usage.InputTokens, usage.OutputTokens, usage.CachedInputTokens)
+	} else {
+		a.logger.Debug("no price for model, recording usage without cost", zap.String("model", usage.Model))
+	}
+	if a.budgets != nil {
+		a.budgets.Record(projectID, tokenID, Spend{CostUSD: delta.CostUSD, Tokens: delta.InputTokens + delta.OutputTokens})
+	}
+
+	select {
+	case a.eventsCh <- costEvent{tokenID: tokenID, projectID: projectID, delta: delta}:
+		// Event enqueued successfully
+	default:
+		// Buffer full, drop the event
+		a.logger.Debug("cost buffer full, dropping event", zap.String("project_id", projectID))
+	}
+}
+
+// run is the main loop of the aggregator worker.
+func (a *CostAggregator) run() {
+	defer close(a.doneCh)
+
+	ticker := time.NewTicker(a.config.FlushInterval)
+	defer ticker.Stop()
+
+	tokenDeltas := make(map[string]CostDelta)
+	projectDeltas := make(map[string]CostDelta)
+	eventCount := 0
+
+	add := func(evt costEvent) {
+		if evt.tokenID != "" {
+			d := tokenDeltas[evt.tokenID]
+			d.add(evt.delta)
+			tokenDeltas[evt.tokenID] = d
+		}
+		d := projectDeltas[evt.projectID]
+		d.add(evt.delta)
+		projectDeltas[evt.projectID] = d
+		eventCount++
+	}
+
+	flush := func() {
+		if eventCount == 0 {
+			return
+		}
+
+		// Copy and reset
+		tokensToFlush, projectsToFlush := tokenDeltas, projectDeltas
+		tokenDeltas = make(map[string]CostDelta)
+		projectDeltas = make(map[string]CostDelta)
+		flushedCount := eventCount
+		eventCount = 0
+
+		// Flush with a short timeout
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		if err := a.store.AddCosts(ctx, tokensToFlush, projectsToFlush); err != nil {
+			a.logger.Warn("failed to flush costs",
+				zap.Error(err),
+				zap.Int("event_count", flushedCount),
+				zap.Int("project_count", len(projectsToFlush)))
+			// On error, we drop the costs (lossy-tolerant like the other stats aggregators)
+		} else {
+			a.logger.Debug("flushed costs",
+				zap.Int("event_count", flushedCount),
+				zap.Int("token_count", len(tokensToFlush)),
+				zap.Int("project_count", len(projectsToFlush)))
+		}
+	}
+
+	for {
+		select {
+		case <-a.stopCh:
+			// Drain remaining events
+			for {
+				select {
+				case evt := <-a.eventsCh:
+					add(evt)
+				default:
+					// No more events
+					flush()
+					return
+				}
+			}
+
+		case evt := <-a.eventsCh:
+			add(evt)
+			if eventCount >= a.config.BatchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// SetCostAggregator sets the aggregator that records the cost of proxied requests.
+func (p *TransparentProxy) SetCostAggregator(agg *CostAggregator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.costAggregator = agg
+}
+
+// costAccount is who a proxied request is billed to. The token ID is filled
+// in once token data is loaded and stays empty for validators without it.
+type costAccount struct {
+	tokenID     string
+	projectID   string
+	requestBody []byte
+	tpm         *token.TPMReservation // Tokens reserved against TPM limits, if any
+}
+
+// withCostAccount stores the costAccount for a validated request in its
+// context, keeping a copy of JSON request bodies of up to maxCostRequestBytes
+// for usage estimates. Budget, TPM and concurrency limits read the token ID
+// from it.
+func (p *TransparentProxy) withCostAccount(r *http.Request, projectID string) *http.Request {
+	if p.costAggregator == nil && p.budgets == nil && p.tpm == nil && p.concurrency == nil {
+		return r
+	}
+	acct := &costAccount{projectID: projectID}
+	if r.Body != nil && r.Body != http.NoBody && strings.Contains(r.Header.Get("Content-Type"), "json") {
+		if body, buffered, err := bufferRequestBody(r, maxCostRequestBytes); err == nil && buffered {
+			acct.requestBody = body
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), ctxKeyCostAccount, acct))
+}
+
+// setCostAccountToken bills the request to td. Child tokens are billed to the
+// stored token they were derived from.
+func setCostAccountToken(r *http.Request, td token.TokenData) {
+	acct, ok := r.Context().Value(ctxKeyCostAccount).(*costAccount)
+	if !ok {
+		return
+	}
+	acct.tokenID = td.ID
+	if td.ParentID != "" {
+		acct.tokenID = td.ParentID
+	}
+}
+
+// captureCost wraps the body of a successful JSON or SSE response so its
+// usage is recorded, and its TPM reservation reconciled, once the client has
+// read it. Usage is extracted off the response path. Failed requests release
+// their TPM reservation.
+func (p *TransparentProxy) captureCost(res *http.Response) {
+	if res.Request == nil {
+		return
+	}
+	acct, ok := res.Request.Context().Value(ctxKeyCostAccount).(*costAccount)
+	if !ok {
+		return
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		releaseTPM(acct)
+		return
+	}
+	if p.costAggregator == nil && acct.tpm == nil {
+		return
+	}
+	contentType := res.Header.Get("Content-Type")
+	if !strings.Contains(contentType, "json") && !strings.Contains(contentType, "event-stream") {
+		return
+	}
+	agg := p.costAggregator
+	gzipped := strings.EqualFold(res.Header.Get("Content-Encoding"), "gzip")
+	tokenID, projectID := acct.tokenID, acct.projectID
+	res.Body = newStreamingCapture(res.Body, maxCostCaptureBytes, func(body []byte) {
+		if len(body) == 0 {
+			return
+		}
+		go func() {
+			if gzipped {
+				zr, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					return
+				}
+				// A truncated capture still yields the part that was read
+				body, _ = io.ReadAll(zr)
+			}
+			usage, ok := responseUsage(acct.requestBody, body)
+			if !ok {
+				return
+			}
+			if acct.tpm != nil {
+				reconcileTPM(acct.tpm, int64(usage.InputTokens+usage.OutputTokens))
+			}
+			if agg != nil {
+				agg.RecordUsage(tokenID, projectID, usage)
+			}
+		}()
+	})
+}
+
+// requestModelFromBody returns the "model" field of a JSON request body.
+func requestModelFromBody(body []byte) string {
+	var payload struct {
+		Model string `json:"model"`
+	}
+	_ = json.Unmarshal(body, &payload)
+	return payload.Model
+}
@@ -0,0 +1,161 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/eventtransformer"
+	"github.com/sofatutor/llm-proxy/internal/pricing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCostStore sums the deltas it is given.
+type mockCostStore struct {
+	mu       sync.Mutex
+	tokens   map[string]CostDelta
+	projects map[string]CostDelta
+}
+
+func (m *mockCostStore) AddCosts(ctx context.Context, tokenDeltas, projectDeltas map[string]CostDelta) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tokens == nil {
+		m.tokens = map[string]CostDelta{}
+		m.projects = map[string]CostDelta{}
+	}
+	for id, d := range tokenDeltas {
+		total := m.tokens[id]
+		total.add(d)
+		m.tokens[id] = total
+	}
+	for id, d := range projectDeltas {
+		total := m.projects[id]
+		total.add(d)
+		m.projects[id] = total
+	}
+	return nil
+}
+
+func (m *mockCostStore) project(id string) CostDelta {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.projects[id]
+}
+
+func (m *mockCostStore) token(id string) CostDelta {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tokens[id]
+}
+
+func testPriceCatalog() *pricing.Catalog {
+	cached := 0.5
+	return pricing.NewCatalog([]pricing.Price{{Model: "gpt-4o-mini*", InputPer1K: 1, OutputPer1K: 2, CachedInputPer1K: &cached}})
+}
+
+func TestCostAggregator_RecordUsage(t *testing.T) {
+	store := &mockCostStore{}
+	agg := NewCostAggregator(CostAggregatorConfig{FlushInterval: time.Hour}, store, testPriceCatalog(), nil)
+	agg.Start()
+
+	agg.RecordUsage("tok-1", "proj-1", eventtransformer.Usage{Model: "gpt-4o-mini-2024-07-18", InputTokens: 1000, OutputTokens: 500, CachedInputTokens: 200})
+	agg.RecordUsage("tok-2", "proj-1", eventtransformer.Usage{Model: "gpt-4o-mini", InputTokens: 1000})
+	agg.RecordUsage("", "proj-1", eventtransformer.Usage{Model: "unpriced", InputTokens: 10, OutputTokens: 5})
+	agg.RecordUsage("tok-3", "", eventtransformer.Usage{Model: "gpt-4o-mini", InputTokens: 1000})
+	require.NoError(t, agg.Stop(context.Background()))
+
+	tok1 := store.token("tok-1")
+	assert.Equal(t, int64(1), tok1.Requests)
+	assert.Equal(t, int64(200), tok1.CachedInputTokens)
+	assert.InDelta(t, 0.8+0.1+1.0, tok1.CostUSD, 1e-9)
+	assert.InDelta(t, 1.0, store.token("tok-2").CostUSD, 1e-9)
+	assert.Zero(t, store.token("tok-3").Requests, "usage without a project is not recorded")
+
+	proj := store.project("proj-1")
+	assert.Equal(t, int64(3), proj.Requests)
+	assert.Equal(t, int64(2010), proj.InputTokens)
+	assert.Equal(t, int64(505), proj.OutputTokens)
+	assert.InDelta(t, 2.9, proj.CostUSD, 1e-9, "unpriced models are counted at no cost")
+
+	// Stopped aggregators drop usage
+	agg.RecordUsage("tok-1", "proj-1", eventtransformer.Usage{Model: "gpt-4o-mini", InputTokens: 1000})
+	assert.Equal(t, int64(3), store.project("proj-1").Requests)
+}
+
+func TestTransparentProxy_RecordsCost(t *testing.T) {
+	var (
+		status = http.StatusOK
+		mu     sync.Mutex
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		code := status
+		mu.Unlock()
+		if r.Header.Get("X-Stream") != "" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte("data: {\"model\":\"gpt-4o-mini\",\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+				"data: {\"model\":\"gpt-4o-mini\",\"choices\":[],\"usage\":{\"prompt_tokens\":1000,\"completion_tokens\":1000}}\n\ndata: [DONE]\n\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_, _ = w.Write([]byte(`{"model":"gpt-4o-mini-2024-07-18","choices":[],"usage":{"prompt_tokens":2000,"completion_tokens":500,"prompt_tokens_details":{"cached_tokens":1000}}}`))
+	}))
+	defer srv.Close()
+
+	store := &mockCostStore{}
+	agg := NewCostAggregator(CostAggregatorConfig{FlushInterval: 10 * time.Millisecond}, store, testPriceCatalog(), nil)
+	agg.Start()
+	defer func() { _ = agg.Stop(context.Background()) }()
+
+	p := newTestProxy(t, srv.URL, withTokenValidator(&limitedTokenValidator{}))
+	p.SetCostAggregator(agg)
+	h := p.Handler()
+
+	require.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+	require.Eventually(t, func() bool { return store.token("tok-id").Requests == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.InDelta(t, 1.0+0.5+1.0, store.token("tok-id").CostUSD, 1e-9)
+
+	require.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", map[string]string{"X-Stream": "1"}).Code)
+	require.Eventually(t, func() bool { return store.project("test-project-id").Requests == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.InDelta(t, 2.5+3.0, store.project("test-project-id").CostUSD, 1e-9, "streamed usage is priced")
+
+	// Failed upstream requests cost nothing
+	mu.Lock()
+	status = http.StatusBadRequest
+	mu.Unlock()
+	require.Equal(t, http.StatusBadRequest, sendChat(h, "gpt-4o-mini", nil).Code)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int64(2), store.project("test-project-id").Requests)
+}
+
+func TestWithCostAccount_BodyCap(t *testing.T) {
+	p := &TransparentProxy{costAggregator: &CostAggregator{}}
+	account := func(body []byte) (*costAccount, []byte) {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r = p.withCostAccount(r, "proj-1")
+		forwarded, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		return r.Context().Value(ctxKeyCostAccount).(*costAccount), forwarded
+	}
+
+	small := []byte(`{"model":"gpt-4o"}`)
+	acct, forwarded := account(small)
+	assert.Equal(t, small, acct.requestBody)
+	assert.Equal(t, small, forwarded)
+
+	// Oversized bodies are streamed on without keeping a copy
+	large := bytes.Repeat([]byte("a"), maxCostRequestBytes+1)
+	acct, forwarded = account(large)
+	assert.Nil(t, acct.requestBody)
+	assert.Equal(t, len(large), len(forwarded))
+}
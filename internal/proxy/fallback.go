@@ -0,0 +1,488 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/eventbus"
+	"github.com/sofatutor/llm-proxy/internal/logging"
+	"github.com/sofatutor/llm-proxy/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// Upstream failures that can trigger a fallback
+const (
+	FallbackOn5xx     = "5xx"
+	FallbackOn429     = "429"
+	FallbackOnTimeout = "timeout"
+)
+
+// maxDiscardedBodyBytes caps how much of a failed attempt's response is kept
+// in case it has to be replayed to the client.
+const maxDiscardedBodyBytes = 1 << 20
+
+// FallbackTarget is a model on a provider tried after an upstream failure.
+type FallbackTarget struct {
+	Provider string
+	Model    string
+}
+
+// FallbackPolicy is a provider's resolved fallback configuration.
+type FallbackPolicy struct {
+	// On lists the failures that trigger a fallback
+	On []string
+	// Chains maps model names or globs to the targets tried in order
+	Chains map[string][]FallbackTarget
+}
+
+// resolveFallbackConfig validates cfg for provider and resolves its chain
+// entries. An entry is "provider/model" when the part before the first "/"
+// names a configured provider, and a model on the same provider otherwise.
+func resolveFallbackConfig(cfg FallbackConfig, provider string, apis map[string]*APIProviderConfig) (FallbackPolicy, error) {
+	if len(cfg.Chains) == 0 {
+		return FallbackPolicy{}, nil
+	}
+	policy := FallbackPolicy{
+		On:     []string{FallbackOn5xx, FallbackOn429, FallbackOnTimeout},
+		Chains: make(map[string][]FallbackTarget, len(cfg.Chains)),
+	}
+	if len(cfg.On) > 0 {
+		for _, cond := range cfg.On {
+			switch cond {
+			case FallbackOn5xx, FallbackOn429, FallbackOnTimeout:
+			default:
+				return FallbackPolicy{}, fmt.Errorf("unknown condition '%s' (want 5xx, 429 or timeout)", cond)
+			}
+		}
+		policy.On = append([]string{}, cfg.On...)
+	}
+	for model, entries := range cfg.Chains {
+		if model == "" {
+			return FallbackPolicy{}, errors.New("chain has no model pattern")
+		}
+		if _, err := path.Match(model, ""); err != nil {
+			return FallbackPolicy{}, fmt.Errorf("chain has invalid pattern '%s': %w", model, err)
+		}
+		if len(entries) == 0 {
+			return FallbackPolicy{}, fmt.Errorf("chain '%s' has no targets", model)
+		}
+		targets := make([]FallbackTarget, 0, len(entries))
+		for _, entry := range entries {
+			target := FallbackTarget{Provider: provider, Model: entry}
+			if name, m, ok := strings.Cut(entry, "/"); ok {
+				if _, exists := apis[name]; exists {
+					target = FallbackTarget{Provider: name, Model: m}
+				}
+			}
+			if target.Model == "" {
+				return FallbackPolicy{}, fmt.Errorf("chain '%s' has target '%s' without a model", model, entry)
+			}
+			targets = append(targets, target)
+		}
+		policy.Chains[model] = targets
+	}
+	return policy, nil
+}
+
+// chainFor returns the fallback targets for model. An exact chain key wins
+// over glob patterns, which are tried longest first.
+func (f FallbackPolicy) chainFor(model string) []FallbackTarget {
+	if model == "" || len(f.Chains) == 0 {
+		return nil
+	}
+	if targets, ok := f.Chains[model]; ok {
+		return targets
+	}
+	patterns := make([]string, 0, len(f.Chains))
+	for pattern := range f.Chains {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, model); ok {
+			return f.Chains[pattern]
+		}
+	}
+	return nil
+}
+
+// triggers reports whether an attempt that ended with status (and err, for
+// transport failures) should fall through to the next target.
+func (f FallbackPolicy) triggers(status int, err error) bool {
+	var cond string
+	switch {
+	case isTimeoutError(err):
+		cond = FallbackOnTimeout
+	case status == http.StatusTooManyRequests:
+		cond = FallbackOn429
+	case status >= 500:
+		cond = FallbackOn5xx
+	default:
+		return false
+	}
+	for _, on := range f.On {
+		if on == cond {
+			return true
+		}
+	}
+	return false
+}
+
+func isTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// SetFallbackProviders registers the proxies of other providers so fallback
+// chains can cross providers. It must be called before the proxy serves requests.
+func (p *TransparentProxy) SetFallbackProviders(providers map[string]*TransparentProxy) {
+	p.fallbackProviders = providers
+}
+
+// fallbackAttempt is stored in the request context of each attempt so the
+// error handler can report transport failures and later attempts skip caching.
+type fallbackAttempt struct {
+	index int
+	err   error
+}
+
+// fallbackRun is one resolved step of a fallback chain.
+type fallbackRun struct {
+	proxy *TransparentProxy
+	model string
+}
+
+// serveUpstream proxies r upstream, walking the fallback chain configured for
+// the request's model if there is one. The request holds a concurrency slot
+// until the response has been copied.
+func (p *TransparentProxy) serveUpstream(w http.ResponseWriter, r *http.Request) {
+	lease, ok := p.acquireConcurrency(w, r)
+	if !ok {
+		return
+	}
+	defer lease.Release()
+	if !p.reserveTPM(w, r) {
+		return
+	}
+	if len(p.config.Fallback.Chains) > 0 {
+		model := requestModel(r)
+		if chain := p.config.Fallback.chainFor(model); len(chain) > 0 {
+			p.serveWithFallback(w, r, model, chain)
+			return
+		}
+	}
+	if err := p.forwardUpstream(w, r); errors.Is(err, errCircuitOpen) {
+		p.writeCircuitOpen(w, r)
+	}
+}
+
+// serveWithFallback replays the buffered request against each target in turn
+// until one answers without a fallback-triggering failure. Failed attempts are
+// held back, so nothing reaches the client until an attempt is committed.
+func (p *TransparentProxy) serveWithFallback(w http.ResponseWriter, r *http.Request, model string, chain []FallbackTarget) {
+	body, _, err := bufferRequestBody(r, 0)
+	if err != nil {
+		writeErrorResponseForRequest(w, r, http.StatusBadRequest, ErrorResponse{
+			Error: "Failed to read request body",
+			Code:  "invalid_request",
+		})
+		return
+	}
+
+	allowedModels, _ := r.Context().Value(ctxKeyAllowedModels).([]string)
+	tokenModels, _ := r.Context().Value(ctxKeyTokenModels).([]string)
+	runs := []fallbackRun{{proxy: p, model: model}}
+	for _, target := range chain {
+		// Fallbacks stay within the project's model allowlist and the token's scopes
+		if !modelAllowed(allowedModels, target.Model) || !modelAllowed(tokenModels, target.Model) {
+			continue
+		}
+		tp := p.fallbackProviders[target.Provider]
+		if target.Provider == p.config.Provider {
+			tp = p
+		}
+		// Skip targets that could never serve this request
+		if tp == nil || !tp.isMethodAllowed(r.Method) || !tp.isEndpointAllowed(r.URL.Path) {
+			continue
+		}
+		runs = append(runs, fallbackRun{proxy: tp, model: target.Model})
+	}
+
+	var (
+		attempts  []eventbus.UpstreamAttempt
+		held      *fallbackResponseWriter
+		served    int
+		committed bool
+	)
+	for i, run := range runs {
+		attempt := &fallbackAttempt{index: i}
+		req := r.WithContext(context.WithValue(r.Context(), ctxKeyFallbackAttempt, attempt))
+		setRequestBody(req, withRequestModel(body, model, run.model))
+
+		fw := &fallbackResponseWriter{
+			w:       w,
+			header:  w.Header().Clone(),
+			policy:  p.config.Fallback,
+			attempt: attempt,
+			ctx:     r.Context(),
+			final:   i == len(runs)-1,
+		}
+		if i > 0 {
+			fw.header.Set("X-Proxy-Provider", run.proxy.config.Provider)
+			fw.header.Set("X-Proxy-Model", run.model)
+			fw.header.Set("X-Proxy-Fallback-Attempts", strconv.Itoa(i+1))
+		}
+
+		start := time.Now()
+		var err error
+		if i == 0 {
+			err = p.forwardUpstream(fw, req)
+		} else {
+			err = run.proxy.serveFallbackTarget(fw, req)
+		}
+		if err != nil {
+			attempt.err = err
+		}
+		result := eventbus.UpstreamAttempt{
+			Provider: run.proxy.config.Provider,
+			Model:    run.model,
+			Status:   fw.status,
+			Duration: time.Since(start),
+		}
+		if attempt.err != nil {
+			result.Error = attempt.err.Error()
+		}
+		attempts = append(attempts, result)
+
+		if fw.status == 0 {
+			continue
+		}
+		served = i
+		if !fw.discarded {
+			held = nil
+			committed = true
+			break
+		}
+		held = fw
+	}
+	switch {
+	case held != nil:
+		// The remaining targets could not be tried; give the client the last failure.
+		held.header.Set("X-Proxy-Fallback-Attempts", strconv.Itoa(len(attempts)))
+		held.replay()
+	case !committed:
+		// Only an open circuit keeps the primary from answering
+		p.writeCircuitOpen(w, r)
+	}
+
+	p.recordFallbackAttempts(r, attempts, served)
+}
+
+// serveFallbackTarget proxies a fallback attempt through p, redoing the
+// provider-specific checks and preparation the handler did for the original
+// provider. A request p would reject is not sent, and the attempt is skipped.
+// The token was already validated, so its usage is not tracked again.
+func (p *TransparentProxy) serveFallbackTarget(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	projectID, _ := ctx.Value(ctxKeyProjectID).(string)
+	if status, er := p.checkParamWhitelist(r); status != 0 {
+		return errorResponseError(er)
+	}
+	if status, er := p.enforceRequestPolicy(r, projectID); status != 0 {
+		return errorResponseError(er)
+	}
+	selected, err := p.selectUpstreamKey(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to get API key: %w", err)
+	}
+	ctx = context.WithValue(ctx, ctxKeyUpstreamKey, &upstreamKeySelection{projectID: projectID, keyID: selected.id, key: selected.key})
+	// Drop what the previous provider resolved for the request
+	ctx = context.WithValue(ctx, ctxKeyUpstreamPath, nil)
+	ctx = context.WithValue(ctx, ctxKeyChatTranslation, nil)
+
+	req, status, er := p.prepareUpstreamRequest(r.WithContext(ctx))
+	if status != 0 {
+		return errorResponseError(er)
+	}
+	if selected.id != "" {
+		middleware.AnnotateEvent(ctx, func(evt *eventbus.Event) {
+			evt.UpstreamKeyID = selected.id
+		})
+	}
+	return p.forwardUpstream(w, req)
+}
+
+// errorResponseError turns the error response for a fallback attempt that was
+// not sent into the attempt's error.
+func errorResponseError(er ErrorResponse) error {
+	if er.Description != "" {
+		return fmt.Errorf("%s: %s", er.Error, er.Description)
+	}
+	return errors.New(er.Error)
+}
+
+// recordFallbackAttempts adds the attempts to the observability event and
+// audits requests that needed a fallback. served indexes the attempt whose
+// response reached the client.
+func (p *TransparentProxy) recordFallbackAttempts(r *http.Request, attempts []eventbus.UpstreamAttempt, served int) {
+	provider := attempts[served].Provider
+	middleware.AnnotateEvent(r.Context(), func(evt *eventbus.Event) {
+		evt.Attempts = attempts
+		evt.Provider = provider
+	})
+	if len(attempts) < 2 || p.auditLogger == nil {
+		return
+	}
+
+	result := audit.ResultSuccess
+	if status := attempts[served].Status; status == 0 || status >= 400 {
+		result = audit.ResultFailure
+	}
+	projectID, _ := r.Context().Value(ctxKeyProjectID).(string)
+	requestID, _ := logging.GetRequestID(r.Context())
+	details := make([]map[string]interface{}, 0, len(attempts))
+	for _, a := range attempts {
+		detail := map[string]interface{}{
+			"provider":    a.Provider,
+			"model":       a.Model,
+			"status":      a.Status,
+			"duration_ms": a.Duration.Milliseconds(),
+		}
+		if a.Error != "" {
+			detail["error"] = a.Error
+		}
+		details = append(details, detail)
+	}
+	auditEvent := audit.NewEvent(audit.ActionProxyFallback, audit.ActorSystem, result).
+		WithProjectID(projectID).
+		WithRequestID(requestID).
+		WithClientIP(getClientIP(r)).
+		WithUserAgent(r.UserAgent()).
+		WithHTTPMethod(r.Method).
+		WithEndpoint(r.URL.Path).
+		WithDetail("attempts", details)
+	if err := p.auditLogger.Log(auditEvent); err != nil {
+		p.logger.Warn("Failed to audit fallback", zap.String("request_id", requestID), zap.Error(err))
+	}
+}
+
+// withRequestModel returns body with its JSON "model" replaced by to.
+func withRequestModel(body []byte, from, to string) []byte {
+	if from == to {
+		return body
+	}
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+	model, err := json.Marshal(to)
+	if err != nil {
+		return body
+	}
+	payload["model"] = model
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+func setRequestBody(r *http.Request, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
+// fallbackResponseWriter holds back the response of an attempt that failed in
+// a way that triggers a fallback; any other response is passed through.
+type fallbackResponseWriter struct {
+	w         http.ResponseWriter
+	header    http.Header
+	policy    FallbackPolicy
+	attempt   *fallbackAttempt
+	ctx       context.Context
+	final     bool
+	status    int
+	discarded bool
+	body      bytes.Buffer
+}
+
+func (f *fallbackResponseWriter) Header() http.Header {
+	return f.header
+}
+
+func (f *fallbackResponseWriter) WriteHeader(status int) {
+	if f.status != 0 {
+		return
+	}
+	f.status = status
+	// Once the client has gone away there is no point in trying further targets
+	if !f.final && f.ctx.Err() == nil && f.policy.triggers(status, f.attempt.err) {
+		f.discarded = true
+		return
+	}
+	f.commitHeader()
+}
+
+func (f *fallbackResponseWriter) Write(b []byte) (int, error) {
+	if f.status == 0 {
+		f.WriteHeader(http.StatusOK)
+	}
+	if f.discarded {
+		if room := maxDiscardedBodyBytes - f.body.Len(); room > 0 {
+			f.body.Write(b[:min(len(b), room)])
+		}
+		return len(b), nil
+	}
+	return f.w.Write(b)
+}
+
+func (f *fallbackResponseWriter) Flush() {
+	if f.status == 0 || f.discarded {
+		return
+	}
+	if fl, ok := f.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+}
+
+func (f *fallbackResponseWriter) commitHeader() {
+	dst := f.w.Header()
+	for k, v := range f.header {
+		dst[k] = v
+	}
+	f.w.WriteHeader(f.status)
+}
+
+// replay writes a held-back response to the client.
+func (f *fallbackResponseWriter) replay() {
+	// The kept body may have been truncated
+	f.header.Del("Content-Length")
+	f.commitHeader()
+	_, _ = f.w.Write(f.body.Bytes())
+}
@@ -0,0 +1,429 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/eventbus"
+	"github.com/sofatutor/llm-proxy/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// modelUpstream answers with a per-model status and records the models it saw.
+type modelUpstream struct {
+	mu     sync.Mutex
+	models []string
+	status map[string]int
+	delay  map[string]time.Duration
+}
+
+func (u *modelUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Model string `json:"model"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(body, &payload)
+	u.mu.Lock()
+	u.models = append(u.models, payload.Model)
+	status := u.status[payload.Model]
+	delay := u.delay[payload.Model]
+	u.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(`{"model":"` + payload.Model + `","status":"` + http.StatusText(status) + `"}`))
+}
+
+func (u *modelUpstream) seen() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string{}, u.models...)
+}
+
+type fallbackTestSetup struct {
+	handler http.Handler
+	events  <-chan eventbus.Event
+	audit   *SimpleAuditCollector
+}
+
+// newFallbackTestSetup builds an "openai" proxy with the given fallback policy,
+// adjusted by opts, and an "azure" proxy it can fall back to.
+func newFallbackTestSetup(t *testing.T, primaryURL, secondaryURL string, policy FallbackPolicy, opts ...testProxyOption) fallbackTestSetup {
+	t.Helper()
+	bus := eventbus.NewInMemoryEventBus(10)
+	t.Cleanup(bus.Stop)
+	collector := &SimpleAuditCollector{}
+	obsCfg := middleware.ObservabilityConfig{Enabled: true, EventBus: bus}
+
+	primary := newTestProxy(t, primaryURL, append([]testProxyOption{
+		withAuditLogger(collector),
+		withObservability(obsCfg),
+		withConfig(func(cfg *ProxyConfig) { cfg.Fallback = policy }),
+	}, opts...)...)
+	secondary := newTestProxy(t, secondaryURL,
+		withAuditLogger(collector),
+		withObservability(obsCfg),
+		withConfig(func(cfg *ProxyConfig) { cfg.Provider = "azure" }),
+	)
+
+	providers := map[string]*TransparentProxy{"openai": primary, "azure": secondary}
+	primary.SetFallbackProviders(providers)
+	secondary.SetFallbackProviders(providers)
+	return fallbackTestSetup{handler: primary.Handler(), events: bus.Subscribe(), audit: collector}
+}
+
+func sendChat(h http.Handler, model string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"`+model+`","messages":[]}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func waitForEvent(t *testing.T, events <-chan eventbus.Event) eventbus.Event {
+	t.Helper()
+	select {
+	case evt := <-events:
+		return evt
+	case <-time.After(2 * time.Second):
+		t.Fatal("no observability event published")
+		return eventbus.Event{}
+	}
+}
+
+func TestResolveFallbackConfig(t *testing.T) {
+	apis := map[string]*APIProviderConfig{"openai": {}, "azure": {}}
+
+	policy, err := resolveFallbackConfig(FallbackConfig{
+		Chains: map[string][]string{"gpt-4o": {"gpt-4o-mini", "azure/gpt-4o", "ft:org/custom"}},
+	}, "openai", apis)
+	require.NoError(t, err)
+	assert.Equal(t, []string{FallbackOn5xx, FallbackOn429, FallbackOnTimeout}, policy.On, "all conditions by default")
+	assert.Equal(t, []FallbackTarget{
+		{Provider: "openai", Model: "gpt-4o-mini"},
+		{Provider: "azure", Model: "gpt-4o"},
+		{Provider: "openai", Model: "ft:org/custom"},
+	}, policy.Chains["gpt-4o"])
+
+	policy, err = resolveFallbackConfig(FallbackConfig{}, "openai", apis)
+	require.NoError(t, err)
+	assert.Empty(t, policy.Chains)
+
+	invalid := []FallbackConfig{
+		{On: []string{"4xx"}, Chains: map[string][]string{"gpt-4o": {"gpt-4o-mini"}}},
+		{Chains: map[string][]string{"gpt-[": {"gpt-4o-mini"}}},
+		{Chains: map[string][]string{"gpt-4o": {}}},
+		{Chains: map[string][]string{"gpt-4o": {"azure/"}}},
+	}
+	for _, cfg := range invalid {
+		_, err := resolveFallbackConfig(cfg, "openai", apis)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
+
+func TestFallbackPolicy_ChainFor(t *testing.T) {
+	policy := FallbackPolicy{Chains: map[string][]FallbackTarget{
+		"gpt-4o":  {{Provider: "openai", Model: "exact"}},
+		"gpt-4o*": {{Provider: "openai", Model: "glob-long"}},
+		"gpt-*":   {{Provider: "openai", Model: "glob-short"}},
+	}}
+	assert.Equal(t, "exact", policy.chainFor("gpt-4o")[0].Model)
+	assert.Equal(t, "glob-long", policy.chainFor("gpt-4o-mini")[0].Model, "longer globs are more specific")
+	assert.Equal(t, "glob-short", policy.chainFor("gpt-3.5")[0].Model)
+	assert.Nil(t, policy.chainFor("claude-3"))
+	assert.Nil(t, policy.chainFor(""))
+}
+
+func TestFallbackPolicy_Triggers(t *testing.T) {
+	all := FallbackPolicy{On: []string{FallbackOn5xx, FallbackOn429, FallbackOnTimeout}}
+	assert.True(t, all.triggers(http.StatusServiceUnavailable, nil))
+	assert.True(t, all.triggers(http.StatusTooManyRequests, nil))
+	assert.True(t, all.triggers(http.StatusGatewayTimeout, errors.Join(errors.New("dial"), errTimeoutForTest{})))
+	assert.False(t, all.triggers(http.StatusBadRequest, nil))
+	assert.False(t, all.triggers(http.StatusOK, nil))
+
+	timeoutOnly := FallbackPolicy{On: []string{FallbackOnTimeout}}
+	assert.False(t, timeoutOnly.triggers(http.StatusBadGateway, errors.New("connection refused")))
+	assert.True(t, timeoutOnly.triggers(http.StatusBadGateway, errTimeoutForTest{}))
+}
+
+type errTimeoutForTest struct{}
+
+func (errTimeoutForTest) Error() string   { return "i/o timeout" }
+func (errTimeoutForTest) Timeout() bool   { return true }
+func (errTimeoutForTest) Temporary() bool { return true }
+
+func TestTransparentProxy_Fallback_SameProviderModel(t *testing.T) {
+	primary := &modelUpstream{status: map[string]int{"gpt-4o": http.StatusServiceUnavailable}}
+	upstream := httptest.NewServer(primary)
+	defer upstream.Close()
+
+	setup := newFallbackTestSetup(t, upstream.URL, upstream.URL, FallbackPolicy{
+		On:     []string{FallbackOn5xx},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "openai", Model: "gpt-4o-mini"}}},
+	})
+
+	w := sendChat(setup.handler, "gpt-4o", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"model":"gpt-4o-mini"`)
+	assert.NotContains(t, w.Body.String(), "Service Unavailable", "failed attempt is never written")
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, primary.seen())
+	assert.Equal(t, "2", w.Header().Get("X-Proxy-Fallback-Attempts"))
+	assert.Equal(t, "gpt-4o-mini", w.Header().Get("X-Proxy-Model"))
+	assert.Equal(t, "openai", w.Header().Get("X-Proxy-Provider"))
+
+	evt := waitForEvent(t, setup.events)
+	require.Len(t, evt.Attempts, 2)
+	assert.Equal(t, http.StatusServiceUnavailable, evt.Attempts[0].Status)
+	assert.Equal(t, "gpt-4o", evt.Attempts[0].Model)
+	assert.Equal(t, http.StatusOK, evt.Attempts[1].Status)
+	assert.Equal(t, "gpt-4o-mini", evt.Attempts[1].Model)
+
+	events := setup.audit.GetEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.ActionProxyFallback, events[0].Action)
+	assert.Equal(t, audit.ResultSuccess, events[0].Result)
+	assert.Len(t, events[0].Details["attempts"], 2)
+}
+
+func TestTransparentProxy_Fallback_CrossProvider(t *testing.T) {
+	openai := &modelUpstream{status: map[string]int{"gpt-4o": http.StatusTooManyRequests, "gpt-4o-mini": http.StatusTooManyRequests}}
+	openaiSrv := httptest.NewServer(openai)
+	defer openaiSrv.Close()
+	azure := &modelUpstream{}
+	azureSrv := httptest.NewServer(azure)
+	defer azureSrv.Close()
+
+	setup := newFallbackTestSetup(t, openaiSrv.URL, azureSrv.URL, FallbackPolicy{
+		On: []string{FallbackOn429},
+		Chains: map[string][]FallbackTarget{"gpt-4o*": {
+			{Provider: "openai", Model: "gpt-4o-mini"},
+			{Provider: "azure", Model: "gpt-4o"},
+		}},
+	})
+
+	w := sendChat(setup.handler, "gpt-4o", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, openai.seen())
+	assert.Equal(t, []string{"gpt-4o"}, azure.seen())
+	assert.Equal(t, "azure", w.Header().Get("X-Proxy-Provider"))
+	assert.Equal(t, "3", w.Header().Get("X-Proxy-Fallback-Attempts"))
+
+	evt := waitForEvent(t, setup.events)
+	assert.Equal(t, "azure", evt.Provider)
+	require.Len(t, evt.Attempts, 3)
+	assert.Equal(t, "azure", evt.Attempts[2].Provider)
+}
+
+func TestTransparentProxy_Fallback_SkipsTargetsThatRejectTheRequest(t *testing.T) {
+	openai := &modelUpstream{status: map[string]int{"gpt-4o": http.StatusServiceUnavailable}}
+	azure := &modelUpstream{}
+	primary := newTestProxy(t, startUpstream(t, openai.ServeHTTP), withConfig(func(cfg *ProxyConfig) {
+		cfg.Fallback = FallbackPolicy{
+			On: []string{FallbackOn5xx},
+			Chains: map[string][]FallbackTarget{"gpt-4o": {
+				{Provider: "azure", Model: "gpt-4o"},
+				{Provider: "openai", Model: "gpt-4o-mini"},
+			}},
+		}
+	}))
+	secondary := newTestProxy(t, startUpstream(t, azure.ServeHTTP), withRequestPolicy(RequestPolicyRule{Path: "$.tools", Forbidden: true}),
+		withConfig(func(cfg *ProxyConfig) {
+			cfg.Provider = "azure"
+			cfg.ParamWhitelist = map[string][]string{"temperature": {"0", "0.*"}}
+		}))
+	providers := map[string]*TransparentProxy{"openai": primary, "azure": secondary}
+	primary.SetFallbackProviders(providers)
+	secondary.SetFallbackProviders(providers)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		primary.Handler().ServeHTTP(w, req)
+		return w
+	}
+
+	// The primary allows tools and any temperature, the fallback provider does not
+	for _, body := range []string{`{"model":"gpt-4o","tools":[]}`, `{"model":"gpt-4o","temperature":1.5}`} {
+		w := send(body)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "openai", w.Header().Get("X-Proxy-Provider"))
+		assert.Equal(t, "gpt-4o-mini", w.Header().Get("X-Proxy-Model"))
+	}
+	assert.Empty(t, azure.seen(), "requests the fallback provider rejects are not sent to it")
+
+	w := send(`{"model":"gpt-4o","temperature":0.2}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "azure", w.Header().Get("X-Proxy-Provider"))
+	assert.Equal(t, []string{"gpt-4o"}, azure.seen())
+}
+
+func TestTransparentProxy_Fallback_NonRetryablePassesThrough(t *testing.T) {
+	primary := &modelUpstream{status: map[string]int{"gpt-4o": http.StatusBadRequest}}
+	upstream := httptest.NewServer(primary)
+	defer upstream.Close()
+
+	setup := newFallbackTestSetup(t, upstream.URL, upstream.URL, FallbackPolicy{
+		On:     []string{FallbackOn5xx, FallbackOn429},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "azure", Model: "gpt-4o"}}},
+	})
+
+	w := sendChat(setup.handler, "gpt-4o", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{"gpt-4o"}, primary.seen())
+	assert.Empty(t, w.Header().Get("X-Proxy-Fallback-Attempts"))
+	assert.Empty(t, setup.audit.GetEvents(), "no fallback happened")
+
+	evt := waitForEvent(t, setup.events)
+	require.Len(t, evt.Attempts, 1)
+	assert.Equal(t, "openai", evt.Provider)
+
+	// Models without a chain are proxied directly
+	w = sendChat(setup.handler, "gpt-3.5-turbo", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	evt = waitForEvent(t, setup.events)
+	assert.Empty(t, evt.Attempts)
+}
+
+func TestTransparentProxy_Fallback_ChainExhausted(t *testing.T) {
+	primary := &modelUpstream{status: map[string]int{"gpt-4o": http.StatusInternalServerError, "gpt-4o-mini": http.StatusBadGateway}}
+	upstream := httptest.NewServer(primary)
+	defer upstream.Close()
+
+	setup := newFallbackTestSetup(t, upstream.URL, upstream.URL, FallbackPolicy{
+		On:     []string{FallbackOn5xx},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "openai", Model: "gpt-4o-mini"}}},
+	})
+
+	w := sendChat(setup.handler, "gpt-4o", nil)
+	assert.Equal(t, http.StatusBadGateway, w.Code, "the last attempt's response is returned")
+	assert.Contains(t, w.Body.String(), `"model":"gpt-4o-mini"`)
+	assert.Equal(t, "2", w.Header().Get("X-Proxy-Fallback-Attempts"))
+
+	events := setup.audit.GetEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.ResultFailure, events[0].Result)
+}
+
+func TestTransparentProxy_Fallback_UnavailableTargetReplaysFailure(t *testing.T) {
+	primary := &modelUpstream{status: map[string]int{"gpt-4o": http.StatusServiceUnavailable}}
+	upstream := httptest.NewServer(primary)
+	defer upstream.Close()
+
+	// "bedrock" has no proxy, so the primary is the only usable step and its
+	// failure is returned as-is.
+	setup := newFallbackTestSetup(t, upstream.URL, upstream.URL, FallbackPolicy{
+		On:     []string{FallbackOn5xx},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "bedrock", Model: "gpt-4o"}}},
+	})
+
+	w := sendChat(setup.handler, "gpt-4o", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, []string{"gpt-4o"}, primary.seen())
+}
+
+func TestTransparentProxy_Fallback_Timeout(t *testing.T) {
+	primary := &modelUpstream{delay: map[string]time.Duration{"gpt-4o": 500 * time.Millisecond}}
+	primarySrv := httptest.NewServer(primary)
+	defer primarySrv.Close()
+	azure := &modelUpstream{}
+	azureSrv := httptest.NewServer(azure)
+	defer azureSrv.Close()
+
+	setup := newFallbackTestSetup(t, primarySrv.URL, azureSrv.URL, FallbackPolicy{
+		On:     []string{FallbackOnTimeout},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "azure", Model: "gpt-4o"}}},
+	}, withConfig(func(cfg *ProxyConfig) {
+		cfg.ResponseHeaderTimeout = 50 * time.Millisecond
+	}))
+
+	w := sendChat(setup.handler, "gpt-4o", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "azure", w.Header().Get("X-Proxy-Provider"))
+
+	evt := waitForEvent(t, setup.events)
+	require.Len(t, evt.Attempts, 2)
+	assert.NotEmpty(t, evt.Attempts[0].Error)
+
+	// Connection errors are not timeouts
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+	setup = newFallbackTestSetup(t, closedURL, azureSrv.URL, FallbackPolicy{
+		On:     []string{FallbackOnTimeout},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "azure", Model: "gpt-4o"}}},
+	})
+	w = sendChat(setup.handler, "gpt-4o", nil)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
+
+func TestTransparentProxy_Fallback_StreamingPassesThrough(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[]}\n\n"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+
+	setup := newFallbackTestSetup(t, upstream.URL, upstream.URL, FallbackPolicy{
+		On:     []string{FallbackOn5xx},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "azure", Model: "gpt-4o"}}},
+	})
+
+	w := sendChat(setup.handler, "gpt-4o", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "data: {\"choices\":[]}\n\ndata: [DONE]\n\n", w.Body.String())
+}
+
+func TestTransparentProxy_Fallback_ResponsesNotCached(t *testing.T) {
+	primary := &modelUpstream{status: map[string]int{"gpt-4o": http.StatusServiceUnavailable}}
+	upstream := httptest.NewServer(primary)
+	defer upstream.Close()
+
+	setup := newFallbackTestSetup(t, upstream.URL, upstream.URL, FallbackPolicy{
+		On:     []string{FallbackOn5xx},
+		Chains: map[string][]FallbackTarget{"gpt-4o": {{Provider: "openai", Model: "gpt-4o-mini"}}},
+	}, withConfig(func(cfg *ProxyConfig) {
+		cfg.HTTPCacheEnabled = true
+		cfg.HTTPCacheDefaultTTL = time.Minute
+	}))
+
+	optIn := map[string]string{"Cache-Control": "public, max-age=60"}
+	for i := 0; i < 2; i++ {
+		w := sendChat(setup.handler, "gpt-4o", optIn)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, "stored", w.Header().Get("X-PROXY-CACHE"))
+		assert.NotEqual(t, "hit", w.Header().Get("X-PROXY-CACHE"))
+	}
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini", "gpt-4o", "gpt-4o-mini"}, primary.seen())
+}
+
+func TestWithRequestModel(t *testing.T) {
+	body := []byte(`{"model":"gpt-4o","stream":true}`)
+	assert.Equal(t, body, withRequestModel(body, "gpt-4o", "gpt-4o"))
+	assert.JSONEq(t, `{"model":"gpt-4o-mini","stream":true}`, string(withRequestModel(body, "gpt-4o", "gpt-4o-mini")))
+	assert.Equal(t, []byte("not json"), withRequestModel([]byte("not json"), "a", "b"))
+}
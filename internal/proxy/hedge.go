@@ -0,0 +1,257 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// validateHedgeConfig checks the hedging settings of a provider.
+func validateHedgeConfig(cfg HedgeConfig) error {
+	if cfg.Delay < 0 {
+		return errors.New("delay must not be negative")
+	}
+	if cfg.BaseURL == "" {
+		return nil
+	}
+	if cfg.Delay == 0 {
+		return errors.New("base_url requires a delay")
+	}
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return fmt.Errorf("base_url is invalid: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("base_url '%s' needs a scheme and host", cfg.BaseURL)
+	}
+	return nil
+}
+
+// hedgeTransport sends a duplicate of a slow non-streaming request after a
+// delay and returns whichever response arrives first; the other request is
+// canceled. Each copy goes through next, so retries apply to both.
+type hedgeTransport struct {
+	next      http.RoundTripper
+	delay     time.Duration
+	endpoints []string
+	target    *url.URL
+	// report is called once per hedged request with whether the hedge won
+	report func(won bool)
+	logger *zap.Logger
+}
+
+// newHedgeTransport wraps next with cfg's hedging policy. It returns next when
+// hedging is disabled.
+func newHedgeTransport(next http.RoundTripper, cfg HedgeConfig, report func(won bool), logger *zap.Logger) http.RoundTripper {
+	if cfg.Delay <= 0 {
+		return next
+	}
+	t := &hedgeTransport{
+		next:      next,
+		delay:     cfg.Delay,
+		endpoints: cfg.Endpoints,
+		report:    report,
+		logger:    logger,
+	}
+	if cfg.BaseURL != "" {
+		// Validated with the provider configuration
+		t.target, _ = url.Parse(cfg.BaseURL)
+	}
+	return t
+}
+
+// hedgeResult is the outcome of one copy of a hedged request.
+type hedgeResult struct {
+	res   *http.Response
+	err   error
+	hedge bool
+}
+
+// usable reports whether the result can be returned without waiting for the
+// other copy.
+func (r hedgeResult) usable() bool {
+	return r.err == nil && r.res.StatusCode < http.StatusInternalServerError
+}
+
+func (t *hedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.hedgeable(req) {
+		return t.next.RoundTrip(req)
+	}
+	body, buffered, err := bufferRequestBody(req, maxRetryBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !buffered || requestsStream(body) {
+		return t.next.RoundTrip(req)
+	}
+
+	results := make(chan hedgeResult, 2)
+	cancels := make(map[bool]context.CancelFunc, 2)
+	launch := func(hedge bool) {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels[hedge] = cancel
+		legReq := req.WithContext(ctx)
+		if hedge {
+			legReq = t.hedgeRequest(ctx, req, body)
+		}
+		go func() {
+			res, err := t.next.RoundTrip(legReq)
+			results <- hedgeResult{res: res, err: err, hedge: hedge}
+		}()
+	}
+	launch(false)
+	timer := time.NewTimer(t.delay)
+	defer timer.Stop()
+
+	var (
+		winner  *hedgeResult
+		failed  *hedgeResult
+		pending = 1
+		hedged  bool
+	)
+	for pending > 0 && winner == nil {
+		select {
+		case <-timer.C:
+			launch(true)
+			pending++
+			hedged = true
+		case r := <-results:
+			pending--
+			switch {
+			case r.usable():
+				winner = &r
+			case failed == nil:
+				// Keep the first failure in case the other copy fails as well
+				failed = &r
+			default:
+				closeResponse(r.res)
+			}
+		}
+	}
+	if winner == nil {
+		winner = failed
+	} else if failed != nil {
+		closeResponse(failed.res)
+	}
+
+	// Cancel the loser and dispose of its response once it arrives
+	for hedge, cancel := range cancels {
+		if hedge != winner.hedge {
+			cancel()
+		}
+	}
+	if pending > 0 {
+		go func(n int) {
+			for i := 0; i < n; i++ {
+				closeResponse((<-results).res)
+			}
+		}(pending)
+	}
+
+	cancel := cancels[winner.hedge]
+	if winner.err != nil {
+		cancel()
+	} else {
+		// The request context must outlive the round trip until the body is read
+		winner.res.Body = &cancelOnClose{ReadCloser: winner.res.Body, cancel: cancel}
+	}
+
+	if hedged {
+		won := winner.hedge && winner.err == nil
+		if t.report != nil {
+			t.report(won)
+		}
+		requestID, _ := req.Context().Value(ctxKeyRequestID).(string)
+		t.logger.Debug("Hedged upstream request",
+			zap.String("request_id", requestID),
+			zap.Duration("delay", t.delay),
+			zap.Bool("hedge_won", won),
+		)
+	}
+	return winner.res, winner.err
+}
+
+// hedgeable reports whether req is eligible for hedging before its body is read.
+func (t *hedgeTransport) hedgeable(req *http.Request) bool {
+	if isStreamingRequest(req) {
+		return false
+	}
+	if len(t.endpoints) == 0 {
+		return true
+	}
+	path, ok := req.Context().Value(ctxKeyOriginalPath).(string)
+	if !ok {
+		path = req.URL.Path
+	}
+	for _, endpoint := range t.endpoints {
+		if strings.HasPrefix(path, endpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// hedgeRequest builds the duplicate of req, sent to the hedge target if one is set.
+func (t *hedgeTransport) hedgeRequest(ctx context.Context, req *http.Request, body []byte) *http.Request {
+	hr := req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		setRequestBody(hr, body)
+	}
+	if t.target != nil {
+		hr.URL.Scheme = t.target.Scheme
+		hr.URL.Host = t.target.Host
+		hr.Host = t.target.Host
+	}
+	return hr
+}
+
+// requestsStream reports whether a JSON request body asks for a streamed response.
+func requestsStream(body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	var payload struct {
+		Stream bool `json:"stream"`
+	}
+	return json.Unmarshal(body, &payload) == nil && payload.Stream
+}
+
+// closeResponse drains and closes res so its connection can be reused.
+func closeResponse(res *http.Response) {
+	if res == nil || res.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	_ = res.Body.Close()
+}
+
+// cancelOnClose cancels a request context when the response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// recordHedge counts a hedged request in the proxy metrics.
+func (p *TransparentProxy) recordHedge(won bool) {
+	p.metrics.mu.Lock()
+	defer p.metrics.mu.Unlock()
+
+	p.metrics.HedgedRequests++
+	if won {
+		p.metrics.HedgeWins++
+	}
+}
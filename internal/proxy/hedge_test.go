@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// hedgeReports collects the outcomes reported by a hedge transport.
+type hedgeReports struct {
+	mu  sync.Mutex
+	won []bool
+}
+
+func (h *hedgeReports) report(won bool) {
+	h.mu.Lock()
+	h.won = append(h.won, won)
+	h.mu.Unlock()
+}
+
+func (h *hedgeReports) get() []bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]bool{}, h.won...)
+}
+
+func TestValidateHedgeConfig(t *testing.T) {
+	assert.NoError(t, validateHedgeConfig(HedgeConfig{}))
+	assert.NoError(t, validateHedgeConfig(HedgeConfig{Delay: time.Second, BaseURL: "https://eu.example.com"}))
+	assert.Error(t, validateHedgeConfig(HedgeConfig{Delay: -time.Second}))
+	assert.Error(t, validateHedgeConfig(HedgeConfig{BaseURL: "https://eu.example.com"}), "base_url without delay")
+	assert.Error(t, validateHedgeConfig(HedgeConfig{Delay: time.Second, BaseURL: "eu.example.com"}))
+}
+
+func TestNewHedgeTransport_Disabled(t *testing.T) {
+	base := http.DefaultTransport
+	assert.Equal(t, base, newHedgeTransport(base, HedgeConfig{}, nil, zap.NewNop()))
+}
+
+func TestHedgeTransport_HedgeWins(t *testing.T) {
+	var calls atomic.Int32
+	primaryCanceled := make(chan struct{})
+	var bodies sync.Map
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		n := calls.Add(1)
+		b, _ := io.ReadAll(r.Body)
+		bodies.Store(n, string(b))
+		if n == 1 {
+			<-r.Context().Done()
+			close(primaryCanceled)
+			return nil, r.Context().Err()
+		}
+		return statusResponse(http.StatusOK, http.Header{"X-Host": []string{r.URL.Host}}), nil
+	})
+	reports := &hedgeReports{}
+	rt := newHedgeTransport(next, HedgeConfig{Delay: 10 * time.Millisecond, BaseURL: "https://eu.example.com"}, reports.report, zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodPost, "http://upstream/v1/embeddings", strings.NewReader(`{"input":"hi"}`))
+	res, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "eu.example.com", res.Header.Get("X-Host"), "the hedge goes to the configured upstream")
+
+	select {
+	case <-primaryCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("the slow request was not canceled")
+	}
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, []bool{true}, reports.get())
+	for _, n := range []int32{1, 2} {
+		body, _ := bodies.Load(n)
+		assert.Equal(t, `{"input":"hi"}`, body, "both copies carry the body")
+	}
+}
+
+func TestHedgeTransport_FastPrimaryIsNotHedged(t *testing.T) {
+	var calls atomic.Int32
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return statusResponse(http.StatusOK, nil), nil
+	})
+	reports := &hedgeReports{}
+	rt := newHedgeTransport(next, HedgeConfig{Delay: time.Second}, reports.report, zap.NewNop())
+
+	res, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Empty(t, reports.get())
+}
+
+func TestHedgeTransport_FailuresWaitForTheOtherCopy(t *testing.T) {
+	var calls atomic.Int32
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			// Fail only after the hedge was sent
+			time.Sleep(30 * time.Millisecond)
+			return statusResponse(http.StatusBadGateway, nil), nil
+		}
+		time.Sleep(60 * time.Millisecond)
+		return statusResponse(http.StatusOK, nil), nil
+	})
+	reports := &hedgeReports{}
+	rt := newHedgeTransport(next, HedgeConfig{Delay: 10 * time.Millisecond}, reports.report, zap.NewNop())
+
+	res, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode, "a 5xx does not beat a later success")
+	assert.Equal(t, []bool{true}, reports.get())
+
+	// When both copies fail the first failure is returned
+	failing := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		time.Sleep(20 * time.Millisecond)
+		return nil, errors.New("connection refused")
+	})
+	rt = newHedgeTransport(failing, HedgeConfig{Delay: 5 * time.Millisecond}, reports.report, zap.NewNop())
+	_, err = rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, []bool{true, false}, reports.get())
+}
+
+func TestHedgeTransport_Eligibility(t *testing.T) {
+	var calls atomic.Int32
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		return statusResponse(http.StatusOK, nil), nil
+	})
+	rt := newHedgeTransport(next, HedgeConfig{Delay: 5 * time.Millisecond, Endpoints: []string{"/v1/embeddings"}}, nil, zap.NewNop())
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "http://upstream/v1/chat/completions", strings.NewReader(`{}`)),
+		httptest.NewRequest(http.MethodPost, "http://upstream/v1/embeddings", strings.NewReader(`{"stream":true}`)),
+		httptest.NewRequest(http.MethodPost, "http://upstream/v1/embeddings?stream=true", strings.NewReader(`{}`)),
+	}
+	// Endpoints match the path the client used, not the rewritten upstream path
+	rewritten := httptest.NewRequest(http.MethodPost, "http://upstream/openai/deployments/emb/embeddings", strings.NewReader(`{}`))
+	requests = append(requests, rewritten.WithContext(context.WithValue(rewritten.Context(), ctxKeyOriginalPath, "/v1/chat/completions")))
+
+	for _, req := range requests {
+		calls.Store(0)
+		res, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, int32(1), calls.Load(), req.URL.String())
+	}
+
+	calls.Store(0)
+	res, err := rt.RoundTrip(httptest.NewRequest(http.MethodPost, "http://upstream/v1/embeddings", strings.NewReader(`{}`)))
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestTransparentProxy_Hedge(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Reading the body lets the server notice the canceled request
+		_, _ = io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+		_, _ = w.Write([]byte(`{"from":"slow"}`))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"from":"fast","auth":"` + r.Header.Get("Authorization") + `"}`))
+	}))
+	defer fast.Close()
+
+	p, err := NewTransparentProxyWithLogger(ProxyConfig{
+		TargetBaseURL:    slow.URL,
+		AllowedEndpoints: []string{"/v1/embeddings"},
+		AllowedMethods:   []string{http.MethodPost},
+		Hedge:            HedgeConfig{Delay: 20 * time.Millisecond, BaseURL: fast.URL},
+	}, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"model":"text-embedding-3-small","input":"hi"}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	start := time.Now()
+	p.Handler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"from":"fast","auth":"Bearer api-key"}`, w.Body.String())
+	assert.Less(t, time.Since(start), time.Second)
+	m := p.Metrics()
+	assert.Equal(t, int64(1), m.HedgedRequests)
+	assert.Equal(t, int64(1), m.HedgeWins)
+}
@@ -24,7 +24,7 @@ func (benchTokenValidator) ValidateTokenWithTracking(ctx context.Context, tokenI
 
 type benchProjectStore struct{}
 
-func (benchProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (benchProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	return "sk-test", nil
 }
 func (benchProjectStore) GetProjectActive(ctx context.Context, projectID string) (bool, error) {
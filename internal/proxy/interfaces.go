@@ -7,8 +7,13 @@ import (
 	"time"
 
 	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/token"
 )
 
+// ErrProviderAPIKeyNotFound is returned by ProjectStore.GetAPIKeyForProject when the
+// project exists but has no API key stored for the requested provider.
+var ErrProviderAPIKeyNotFound = errors.New("no API key configured for provider")
+
 // TokenValidator defines the interface for token validation
 type TokenValidator interface {
 	// ValidateToken validates a token and returns the associated project ID
@@ -21,8 +26,10 @@ type TokenValidator interface {
 // ProjectStore defines the interface for retrieving and managing project information
 // (extended for management API)
 type ProjectStore interface {
-	// GetAPIKeyForProject retrieves the API key for a project
-	GetAPIKeyForProject(ctx context.Context, projectID string) (string, error)
+	// GetAPIKeyForProject retrieves the API key for a project and provider.
+	// An empty provider returns the project's legacy provider-agnostic key; a named
+	// provider returns the per-provider key or ErrProviderAPIKeyNotFound.
+	GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error)
 	// GetProjectActive checks if a project is active
 	GetProjectActive(ctx context.Context, projectID string) (bool, error)
 	// Management API CRUD
@@ -59,6 +66,13 @@ type ProxyConfig struct {
 	// TargetBaseURL is the base URL of the API to proxy to
 	TargetBaseURL string
 
+	// Provider is the APIConfig provider name served by this proxy. It selects the
+	// project's per-provider upstream key. Empty means the legacy project key.
+	Provider string
+	// ProviderKeyFallback allows falling back to the legacy project key when the
+	// project has no key stored for Provider (set for the default provider only).
+	ProviderKeyFallback bool
+
 	// AllowedEndpoints is a whitelist of endpoints that can be accessed
 	AllowedEndpoints []string
 
@@ -102,9 +116,44 @@ type ProxyConfig struct {
 	// RequiredHeaders is a list of required request headers (case-insensitive)
 	RequiredHeaders []string
 
+	// KeyPoolStrategy selects among a project's pooled upstream keys
+	// (round_robin, least_recently_limited, weighted; default round_robin)
+	KeyPoolStrategy string
+	// KeyPoolBenchDuration is how long a pooled key is skipped after a 429/401
+	KeyPoolBenchDuration time.Duration
+
+	// UpstreamAuth controls how the upstream credential is sent (bearer by default)
+	UpstreamAuth UpstreamAuthConfig
+
+	// Mode selects provider-specific request mapping ("" for passthrough, azure_openai,
+	// anthropic or gemini)
+	Mode string
+	// Azure holds the deployment mapping used when Mode is azure_openai
+	Azure AzureOpenAIConfig
+
+	// Fallback holds the resolved fallback chains tried when the upstream fails
+	Fallback FallbackPolicy
+	// Retry controls retries of transient upstream failures
+	Retry RetryConfig
+	// CircuitBreaker controls the breaker that stops traffic to a failing upstream
+	CircuitBreaker CircuitBreakerConfig
+	// Hedge controls duplicate requests sent when the upstream is slow
+	Hedge HedgeConfig
+	// ResponseTransforms rewrite upstream response headers and JSON bodies
+	ResponseTransforms []ResponseTransformConfig
+	// RequestPolicy checks and rewrites JSON request bodies; project policies apply first
+	RequestPolicy []RequestPolicyRule
+	// TokenBindings require request headers and client IPs to match token metadata
+	TokenBindings TokenBindingConfig
+
 	// Project active guard configuration
 	EnforceProjectActive bool // Whether to enforce project active status
 
+	// TrustedProxies lists the networks (CIDRs or IPs) allowed to report the client IP via
+	// X-Forwarded-For and X-Real-IP. When empty, those headers are ignored and the
+	// client IP is the connection's peer address.
+	TrustedProxies []string
+
 	// --- HTTP cache (global, opt-in; set programmatically, not via YAML) ---
 	// HTTPCacheEnabled toggles the proxy cache for GET/HEAD based on HTTP semantics
 	HTTPCacheEnabled bool
@@ -170,6 +219,14 @@ const (
 	ctxKeyLogger contextKey = "logger"
 	// ctxKeyOriginalPath stores the original request path before proxy rewriting
 	ctxKeyOriginalPath contextKey = "original_path"
+	// ctxKeyOriginalQuery stores the original raw query before proxy rewriting
+	ctxKeyOriginalQuery contextKey = "original_query"
+	// ctxKeyUpstreamPath holds a provider-specific upstream path for the request
+	ctxKeyUpstreamPath contextKey = "upstream_path"
+	// ctxKeyModelRoute holds the model route pattern that selected the provider
+	ctxKeyModelRoute contextKey = "model_route"
+	// ctxKeyChatTranslation holds the *chatTranslation for translated chat completions
+	ctxKeyChatTranslation contextKey = "chat_translation"
 	// ctxKeyValidationError carries token validation error (if any)
 	ctxKeyValidationError contextKey = "validation_error"
 	// Timing keys for observability
@@ -179,16 +236,45 @@ const (
 	ctxKeyProxyFinalRespAt   contextKey = "proxy_final_resp_at"
 	// ctxKeyRequestStart marks the time when a handler started processing
 	ctxKeyRequestStart contextKey = "request_start"
+	// ctxKeyUpstreamKey holds the *upstreamKeySelection for the request
+	ctxKeyUpstreamKey contextKey = "upstream_key"
+	// ctxKeyFallbackAttempt holds the *fallbackAttempt when a fallback chain applies
+	ctxKeyFallbackAttempt contextKey = "fallback_attempt"
+	// ctxKeyAllowedModels holds the project's model allowlist, if it has one
+	ctxKeyAllowedModels contextKey = "allowed_models"
+	// ctxKeyTokenModels holds the model scopes of the request's token, if it has any
+	ctxKeyTokenModels contextKey = "token_models"
+	// ctxKeyClientIP holds the client IP, resolved through the trusted proxies if the peer is one
+	ctxKeyClientIP contextKey = "client_ip"
+	// ctxKeyTokenLimits holds the tokenLimits reported in the X-RateLimit-* response headers
+	ctxKeyTokenLimits contextKey = "token_limits"
+	// ctxKeyCostAccount holds the *costAccount the request's cost is recorded for
+	ctxKeyCostAccount contextKey = "cost_account"
+	// ctxKeyTokenRateLimit holds the tokenRateLimit of the request's token
+	ctxKeyTokenRateLimit contextKey = "token_rate_limit"
 )
 
 // Project represents a project for the management API and proxy
 // (copied from database/models.go)
 type Project struct {
-	ID            string     `json:"id"`
-	Name          string     `json:"name"`
-	APIKey        string     `json:"api_key"` // Provider-agnostic API key. Encrypted when ENCRYPTION_KEY is set.
-	IsActive      bool       `json:"is_active"`
-	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	APIKey string `json:"api_key"` // Provider-agnostic API key. Encrypted when ENCRYPTION_KEY is set.
+	// APIKeys holds per-provider upstream keys keyed by provider name (see APIConfig).
+	// Each value is encrypted separately when ENCRYPTION_KEY is set.
+	APIKeys map[string]string `json:"api_keys,omitempty"`
+	// APIKeyPool holds pooled upstream keys that replace the single key of their provider (see APIKeyPoolStore)
+	APIKeyPool []APIKeyPoolEntry `json:"api_key_pool,omitempty"`
+	// RequestPolicy holds project-specific request policy rules (see RequestPolicyStore)
+	RequestPolicy []RequestPolicyRule `json:"request_policy,omitempty"`
+	// AllowedModels restricts the models the project may request (see AllowedModelsStore)
+	AllowedModels []string `json:"allowed_models,omitempty"`
+	// ClientRestrictions limit the networks and origins the project's tokens may be used from (see ClientRestrictionsStore)
+	ClientRestrictions token.ClientRestrictions `json:"client_restrictions"`
+	// RateLimit limits the project's request rate (see RateLimitStore)
+	RateLimit     token.RateLimit `json:"rate_limit"`
+	IsActive      bool            `json:"is_active"`
+	DeactivatedAt *time.Time      `json:"deactivated_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
 }
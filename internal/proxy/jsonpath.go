@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// placeholderVars returns the replacer for the placeholders allowed in
+// configured values: ${project_id}, ${request_id}, ${provider} and
+// ${token_hash}, a stable digest of the client token that never reveals it.
+func placeholderVars(r *http.Request, provider string) *strings.Replacer {
+	var projectID, requestID, tokenHash string
+	if r != nil {
+		projectID, _ = r.Context().Value(ctxKeyProjectID).(string)
+		requestID, _ = r.Context().Value(ctxKeyRequestID).(string)
+		if token, _ := r.Context().Value(ctxKeyTokenID).(string); token != "" {
+			sum := sha256.Sum256([]byte(token))
+			tokenHash = hex.EncodeToString(sum[:16])
+		}
+	}
+	return strings.NewReplacer(
+		"${project_id}", projectID,
+		"${request_id}", requestID,
+		"${provider}", provider,
+		"${token_hash}", tokenHash,
+	)
+}
+
+// expandValue returns a copy of v with placeholders in its strings replaced.
+func expandValue(v any, vars *strings.Replacer) any {
+	switch val := v.(type) {
+	case string:
+		return vars.Replace(val)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = expandValue(item, vars)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = expandValue(item, vars)
+		}
+		return out
+	}
+	return v
+}
+
+// pathSegment is one step of a JSONPath: an object key, an array index or a
+// wildcard over either.
+type pathSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the supported JSONPath subset: $.key, $['key'], [n]
+// and [*] / .* segments, e.g. $.choices[*].message.refusal.
+func parseJSONPath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path '%s' must start with $", path)
+	}
+	var segs []pathSegment
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("path '%s' has an empty key", path)
+			}
+			if name == "*" {
+				segs = append(segs, pathSegment{wildcard: true})
+			} else {
+				segs = append(segs, pathSegment{key: name})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path '%s' has an unclosed [", path)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				segs = append(segs, pathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segs = append(segs, pathSegment{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("path '%s' has invalid index '%s'", path, inner)
+				}
+				segs = append(segs, pathSegment{index: n, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("path '%s' is invalid at '%s'", path, rest)
+		}
+	}
+	if len(segs) == 0 {
+		return nil, errors.New("path must select a field below $")
+	}
+	return segs, nil
+}
+
+// deletePath removes the values selected by segs from node.
+func deletePath(node any, segs []pathSegment) any {
+	seg, last := segs[0], len(segs) == 1
+	switch n := node.(type) {
+	case map[string]any:
+		switch {
+		case seg.wildcard && last:
+			clear(n)
+		case seg.wildcard:
+			for k, v := range n {
+				n[k] = deletePath(v, segs[1:])
+			}
+		case seg.isIndex:
+		case last:
+			delete(n, seg.key)
+		default:
+			if child, ok := n[seg.key]; ok {
+				n[seg.key] = deletePath(child, segs[1:])
+			}
+		}
+		return n
+	case []any:
+		switch {
+		case seg.wildcard && last:
+			return []any{}
+		case seg.wildcard:
+			for i, v := range n {
+				n[i] = deletePath(v, segs[1:])
+			}
+		case !seg.isIndex || seg.index >= len(n):
+		case last:
+			return append(n[:seg.index:seg.index], n[seg.index+1:]...)
+		default:
+			n[seg.index] = deletePath(n[seg.index], segs[1:])
+		}
+		return n
+	}
+	return node
+}
+
+// lookupPath returns the values selected by segs in node.
+func lookupPath(node any, segs []pathSegment) []any {
+	if len(segs) == 0 {
+		return []any{node}
+	}
+	seg := segs[0]
+	var out []any
+	switch n := node.(type) {
+	case map[string]any:
+		switch {
+		case seg.wildcard:
+			for _, v := range n {
+				out = append(out, lookupPath(v, segs[1:])...)
+			}
+		case !seg.isIndex:
+			if v, ok := n[seg.key]; ok {
+				out = lookupPath(v, segs[1:])
+			}
+		}
+	case []any:
+		switch {
+		case seg.wildcard:
+			for _, v := range n {
+				out = append(out, lookupPath(v, segs[1:])...)
+			}
+		case seg.isIndex && seg.index < len(n):
+			out = lookupPath(n[seg.index], segs[1:])
+		}
+	}
+	return out
+}
+
+// setPath sets the values selected by segs in node, creating missing objects
+// along key segments.
+func setPath(node any, segs []pathSegment, value func() any) any {
+	seg, last := segs[0], len(segs) == 1
+	next := func(child any) any {
+		if last {
+			return value()
+		}
+		return setPath(child, segs[1:], value)
+	}
+	switch n := node.(type) {
+	case map[string]any:
+		switch {
+		case seg.wildcard:
+			for k, v := range n {
+				n[k] = next(v)
+			}
+		case !seg.isIndex:
+			n[seg.key] = next(n[seg.key])
+		}
+		return n
+	case []any:
+		switch {
+		case seg.wildcard:
+			for i, v := range n {
+				n[i] = next(v)
+			}
+		case seg.isIndex && seg.index < len(n):
+			n[seg.index] = next(n[seg.index])
+		}
+		return n
+	case nil:
+		if !seg.isIndex && !seg.wildcard {
+			return map[string]any{seg.key: next(nil)}
+		}
+	}
+	return node
+}
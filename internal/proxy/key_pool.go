@@ -0,0 +1,280 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key pool selection strategies (APIProviderConfig.KeyPool.Strategy).
+const (
+	KeyPoolRoundRobin           = "round_robin"
+	KeyPoolLeastRecentlyLimited = "least_recently_limited"
+	KeyPoolWeighted             = "weighted"
+)
+
+// defaultKeyBenchDuration is how long a key that returned 429/401 is skipped when
+// KeyPoolBenchDuration is not configured.
+const defaultKeyBenchDuration = 30 * time.Second
+
+// APIKeyPoolEntry is one upstream key of a project's key pool. Entries with the
+// same Provider form that provider's pool; an empty Provider pools the legacy
+// project key.
+type APIKeyPoolEntry struct {
+	Provider string `json:"provider,omitempty"`
+	APIKey   string `json:"api_key"` // Encrypted when ENCRYPTION_KEY is set.
+	// Weight is the key's share under the weighted strategy (default 1)
+	Weight int `json:"weight,omitempty"`
+}
+
+// APIKeyPoolStore is implemented by project stores that keep upstream key pools.
+// Stores without it serve a single key per provider.
+type APIKeyPoolStore interface {
+	// GetAPIKeyPoolForProject returns the project's pool for provider in order (nil if none)
+	GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]APIKeyPoolEntry, error)
+}
+
+// GetAPIKeyPoolForProject returns the key pool of a project for provider from
+// store, or nil when store does not keep pools.
+func GetAPIKeyPoolForProject(ctx context.Context, store ProjectStore, projectID, provider string) ([]APIKeyPoolEntry, error) {
+	if ps, ok := store.(APIKeyPoolStore); ok {
+		return ps.GetAPIKeyPoolForProject(ctx, projectID, provider)
+	}
+	return nil, nil
+}
+
+// ValidateAPIKeyPool checks the entries of a project's key pool.
+func ValidateAPIKeyPool(pool []APIKeyPoolEntry) error {
+	seen := make(map[APIKeyPoolEntry]bool, len(pool))
+	for _, entry := range pool {
+		if strings.TrimSpace(entry.APIKey) == "" {
+			return errors.New("api_key cannot be empty")
+		}
+		if entry.Weight < 0 {
+			return errors.New("weight must not be negative")
+		}
+		key := APIKeyPoolEntry{Provider: entry.Provider, APIKey: entry.APIKey}
+		if seen[key] {
+			return fmt.Errorf("duplicate key in the pool of provider '%s'", entry.Provider)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// pooledKey is one upstream credential of a project's key pool.
+type pooledKey struct {
+	key    string
+	weight int
+	id     string // stable, non-reversible fingerprint safe for logs and events
+}
+
+// newPooledKey wraps a single upstream key as a pool member.
+func newPooledKey(key string, weight int) pooledKey {
+	if weight <= 0 {
+		weight = 1
+	}
+	return pooledKey{key: key, weight: weight, id: apiKeyFingerprint(key)}
+}
+
+// pooledKeys converts stored pool entries into pool members.
+func pooledKeys(pool []APIKeyPoolEntry) []pooledKey {
+	keys := make([]pooledKey, 0, len(pool))
+	for _, entry := range pool {
+		keys = append(keys, newPooledKey(entry.APIKey, entry.Weight))
+	}
+	return keys
+}
+
+// apiKeyFingerprint returns a short identifier for key that does not reveal it.
+func apiKeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key_" + hex.EncodeToString(sum[:6])
+}
+
+// keyPoolState tracks selection and benching state for one project's pool.
+type keyPoolState struct {
+	next          uint64
+	benchedUntil  map[string]time.Time
+	lastLimitedAt map[string]time.Time
+	currentWeight map[string]int
+}
+
+// keyPool selects an upstream key per request and benches keys that the
+// upstream rejects with 429 or 401. State is in-memory and per proxy instance.
+type keyPool struct {
+	strategy string
+	bench    time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	projects map[string]*keyPoolState
+}
+
+func newKeyPool(strategy string, bench time.Duration) *keyPool {
+	if strategy == "" {
+		strategy = KeyPoolRoundRobin
+	}
+	if bench <= 0 {
+		bench = defaultKeyBenchDuration
+	}
+	return &keyPool{
+		strategy: strategy,
+		bench:    bench,
+		now:      time.Now,
+		projects: make(map[string]*keyPoolState),
+	}
+}
+
+// Select picks a key from keys for projectID. Benched keys are skipped; if every
+// key is benched, the one whose bench expires first is used (fail open).
+func (kp *keyPool) Select(projectID string, keys []pooledKey) pooledKey {
+	if len(keys) == 1 {
+		return keys[0]
+	}
+	now := kp.now()
+
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	st := kp.stateLocked(projectID, keys)
+
+	available := make([]pooledKey, 0, len(keys))
+	for _, k := range keys {
+		if until, ok := st.benchedUntil[k.id]; !ok || !now.Before(until) {
+			available = append(available, k)
+		}
+	}
+	if len(available) == 0 {
+		best := keys[0]
+		for _, k := range keys[1:] {
+			if st.benchedUntil[k.id].Before(st.benchedUntil[best.id]) {
+				best = k
+			}
+		}
+		return best
+	}
+
+	switch kp.strategy {
+	case KeyPoolWeighted:
+		// Smooth weighted round-robin: deterministic and evenly interleaved.
+		total := 0
+		var best *pooledKey
+		for i := range available {
+			k := &available[i]
+			total += k.weight
+			st.currentWeight[k.id] += k.weight
+			if best == nil || st.currentWeight[k.id] > st.currentWeight[best.id] {
+				best = k
+			}
+		}
+		st.currentWeight[best.id] -= total
+		return *best
+	case KeyPoolLeastRecentlyLimited:
+		// Prefer the key rate-limited longest ago (never limited wins); rotate
+		// the starting point so ties are spread round-robin.
+		start := int(st.next % uint64(len(available)))
+		st.next++
+		best := available[start]
+		for i := 1; i < len(available); i++ {
+			k := available[(start+i)%len(available)]
+			if st.lastLimitedAt[k.id].Before(st.lastLimitedAt[best.id]) {
+				best = k
+			}
+		}
+		return best
+	default:
+		k := available[st.next%uint64(len(available))]
+		st.next++
+		return k
+	}
+}
+
+// Report records the upstream status for the key identified by keyID. A 429 or
+// 401 benches the key for the configured duration, or longer if the upstream
+// asked for it via Retry-After. It returns the bench duration (0 if not benched).
+func (kp *keyPool) Report(projectID, keyID string, status int, retryAfter time.Duration) time.Duration {
+	if status != http.StatusTooManyRequests && status != http.StatusUnauthorized {
+		return 0
+	}
+	bench := kp.bench
+	if retryAfter > bench {
+		bench = retryAfter
+	}
+	now := kp.now()
+
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+	st := kp.projects[projectID]
+	if st == nil {
+		return 0
+	}
+	st.benchedUntil[keyID] = now.Add(bench)
+	if status == http.StatusTooManyRequests {
+		st.lastLimitedAt[keyID] = now
+	}
+	return bench
+}
+
+// stateLocked returns the pool state for projectID, dropping state for keys that
+// are no longer part of the pool (e.g. after rotation).
+func (kp *keyPool) stateLocked(projectID string, keys []pooledKey) *keyPoolState {
+	st := kp.projects[projectID]
+	if st == nil {
+		st = &keyPoolState{
+			benchedUntil:  make(map[string]time.Time),
+			lastLimitedAt: make(map[string]time.Time),
+			currentWeight: make(map[string]int),
+		}
+		kp.projects[projectID] = st
+		return st
+	}
+	if len(st.benchedUntil)+len(st.lastLimitedAt)+len(st.currentWeight) > 0 {
+		current := make(map[string]struct{}, len(keys))
+		for _, k := range keys {
+			current[k.id] = struct{}{}
+		}
+		for _, m := range []map[string]time.Time{st.benchedUntil, st.lastLimitedAt} {
+			for id := range m {
+				if _, ok := current[id]; !ok {
+					delete(m, id)
+				}
+			}
+		}
+		for id := range st.currentWeight {
+			if _, ok := current[id]; !ok {
+				delete(st.currentWeight, id)
+			}
+		}
+	}
+	return st
+}
+
+// upstreamKeySelection records which upstream key serves a request: the director
+// injects key, and the response path reports the outcome for keyID.
+type upstreamKeySelection struct {
+	projectID string
+	keyID     string
+	key       string
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
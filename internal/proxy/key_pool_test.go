@@ -0,0 +1,274 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testKeyPool builds a pool of keys with weight 1.
+func testKeyPool(keys ...string) []pooledKey {
+	pool := make([]APIKeyPoolEntry, 0, len(keys))
+	for _, key := range keys {
+		pool = append(pool, APIKeyPoolEntry{APIKey: key})
+	}
+	return pooledKeys(pool)
+}
+
+func TestPooledKeys(t *testing.T) {
+	keys := pooledKeys([]APIKeyPoolEntry{{APIKey: "sk-a", Weight: 3}, {APIKey: "sk-b"}, {APIKey: "sk-c", Weight: -1}})
+	require.Len(t, keys, 3)
+	for i, want := range []struct {
+		key    string
+		weight int
+	}{{"sk-a", 3}, {"sk-b", 1}, {"sk-c", 1}} {
+		assert.Equal(t, want.key, keys[i].key)
+		assert.Equal(t, want.weight, keys[i].weight)
+		assert.Equal(t, apiKeyFingerprint(want.key), keys[i].id)
+	}
+}
+
+func TestValidateAPIKeyPool(t *testing.T) {
+	assert.NoError(t, ValidateAPIKeyPool(nil))
+	assert.NoError(t, ValidateAPIKeyPool([]APIKeyPoolEntry{{APIKey: "sk-a", Weight: 3}, {APIKey: "sk-b"}, {Provider: "anthropic", APIKey: "sk-a"}}))
+	assert.Error(t, ValidateAPIKeyPool([]APIKeyPoolEntry{{APIKey: " "}}))
+	assert.Error(t, ValidateAPIKeyPool([]APIKeyPoolEntry{{APIKey: "sk-a", Weight: -1}}))
+	assert.Error(t, ValidateAPIKeyPool([]APIKeyPoolEntry{{APIKey: "sk-a"}, {APIKey: "sk-a", Weight: 2}}))
+}
+
+func TestAPIKeyFingerprint(t *testing.T) {
+	id := apiKeyFingerprint("sk-secret")
+	assert.True(t, strings.HasPrefix(id, "key_"))
+	assert.Len(t, id, len("key_")+12)
+	assert.NotContains(t, id, "secret")
+	assert.Equal(t, id, apiKeyFingerprint("sk-secret"))
+	assert.NotEqual(t, id, apiKeyFingerprint("sk-other"))
+}
+
+func TestKeyPool_RoundRobin(t *testing.T) {
+	kp := newKeyPool("", 0)
+	keys := testKeyPool("sk-a", "sk-b", "sk-c")
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, kp.Select("p1", keys).key)
+	}
+	assert.Equal(t, []string{"sk-a", "sk-b", "sk-c", "sk-a", "sk-b", "sk-c"}, got)
+
+	// Projects rotate independently.
+	assert.Equal(t, "sk-a", kp.Select("p2", keys).key)
+}
+
+func TestKeyPool_Weighted(t *testing.T) {
+	kp := newKeyPool(KeyPoolWeighted, 0)
+	keys := pooledKeys([]APIKeyPoolEntry{{APIKey: "sk-a", Weight: 3}, {APIKey: "sk-b", Weight: 1}})
+
+	counts := map[string]int{}
+	for i := 0; i < 40; i++ {
+		counts[kp.Select("p1", keys).key]++
+	}
+	assert.Equal(t, 30, counts["sk-a"])
+	assert.Equal(t, 10, counts["sk-b"])
+}
+
+func TestKeyPool_LeastRecentlyLimited(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	kp := newKeyPool(KeyPoolLeastRecentlyLimited, time.Second)
+	kp.now = func() time.Time { return now }
+	keys := testKeyPool("sk-a", "sk-b", "sk-c")
+
+	// Seed state, then limit a and b at different times.
+	kp.Select("p1", keys)
+	kp.Report("p1", keys[0].id, http.StatusTooManyRequests, 0)
+	now = now.Add(time.Second)
+	kp.Report("p1", keys[1].id, http.StatusTooManyRequests, 0)
+
+	// After the benches expire, the never-limited key wins, then the one limited longest ago.
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "sk-c", kp.Select("p1", keys).key)
+	}
+	kp.Report("p1", keys[2].id, http.StatusTooManyRequests, 0)
+	now = now.Add(time.Minute)
+	assert.Equal(t, "sk-a", kp.Select("p1", keys).key)
+}
+
+func TestKeyPool_BenchAndFailOpen(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	kp := newKeyPool(KeyPoolRoundRobin, 10*time.Second)
+	kp.now = func() time.Time { return now }
+	keys := testKeyPool("sk-a", "sk-b")
+
+	require.Equal(t, "sk-a", kp.Select("p1", keys).key)
+
+	// Non-limiting statuses never bench.
+	assert.Zero(t, kp.Report("p1", keys[0].id, http.StatusInternalServerError, 0))
+	assert.Zero(t, kp.Report("p1", keys[0].id, http.StatusOK, 0))
+
+	// 401 benches for the configured duration; Retry-After extends it.
+	assert.Equal(t, 10*time.Second, kp.Report("p1", keys[0].id, http.StatusUnauthorized, 0))
+	assert.Equal(t, time.Minute, kp.Report("p1", keys[1].id, http.StatusTooManyRequests, time.Minute))
+
+	// Both benched: fail open to the key released first.
+	assert.Equal(t, "sk-a", kp.Select("p1", keys).key)
+
+	// Once a's bench expires it is served exclusively until b returns.
+	now = now.Add(11 * time.Second)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "sk-a", kp.Select("p1", keys).key)
+	}
+	now = now.Add(time.Minute)
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		seen[kp.Select("p1", keys).key] = true
+	}
+	assert.Len(t, seen, 2)
+}
+
+func TestKeyPool_ReportUnknownProjectAndRotation(t *testing.T) {
+	kp := newKeyPool("", 0)
+	assert.Zero(t, kp.Report("unknown", "key_x", http.StatusTooManyRequests, 0))
+
+	old := testKeyPool("sk-a", "sk-b")
+	kp.Select("p1", old)
+	kp.Report("p1", old[0].id, http.StatusTooManyRequests, 0)
+
+	// Rotating the pool drops state for keys that are gone.
+	kp.Select("p1", testKeyPool("sk-b", "sk-c"))
+	kp.mu.Lock()
+	_, stale := kp.projects["p1"].benchedUntil[old[0].id]
+	kp.mu.Unlock()
+	assert.False(t, stale)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+	assert.Zero(t, parseRetryAfter("-5", now))
+	assert.Equal(t, 20*time.Second, parseRetryAfter(" 20 ", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Zero(t, parseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+}
+
+// poolKeyStore serves a fixed key pool for every project and provider.
+type poolKeyStore struct {
+	stubProjectStore
+	keys []string
+}
+
+func (s *poolKeyStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
+	return "sk-single", nil
+}
+
+func (s *poolKeyStore) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]APIKeyPoolEntry, error) {
+	pool := make([]APIKeyPoolEntry, 0, len(s.keys))
+	for _, key := range s.keys {
+		pool = append(pool, APIKeyPoolEntry{Provider: provider, APIKey: key})
+	}
+	return pool, nil
+}
+
+func TestTransparentProxy_KeyPool_BenchesRateLimitedKey(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		seen = append(seen, auth)
+		mu.Unlock()
+		if auth == "Bearer sk-a" {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	p, err := NewTransparentProxyWithLogger(ProxyConfig{
+		TargetBaseURL:    upstream.URL,
+		AllowedEndpoints: []string{"/v1/test"},
+		AllowedMethods:   []string{"GET"},
+	}, &stubTokenValidator{}, &poolKeyStore{keys: []string{"sk-a", "sk-b"}}, zap.NewNop())
+	require.NoError(t, err)
+	h := p.Handler()
+
+	codes := make([]int, 0, 4)
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/test", nil)
+		req.Header.Set("Authorization", "Bearer tok")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusTooManyRequests, http.StatusOK, http.StatusOK, http.StatusOK}, codes)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"Bearer sk-a", "Bearer sk-b", "Bearer sk-b", "Bearer sk-b"}, seen)
+}
+
+// providerPoolStore serves per-provider keys and key pools.
+type providerPoolStore struct {
+	providerKeyStore
+	pools map[string][]APIKeyPoolEntry
+}
+
+func (s *providerPoolStore) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]APIKeyPoolEntry, error) {
+	return s.pools[provider], nil
+}
+
+func TestTransparentProxy_SelectUpstreamKey(t *testing.T) {
+	store := &providerPoolStore{
+		providerKeyStore: providerKeyStore{legacy: "sk-legacy", keys: map[string]string{"anthropic": "sk-ant"}},
+		pools: map[string][]APIKeyPoolEntry{
+			"":       {{APIKey: "sk-pool-a"}, {APIKey: "sk-pool-b"}},
+			"gemini": {{Provider: "gemini", APIKey: "sk-gem-a"}},
+		},
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		provider string
+		fallback bool
+		want     []string
+	}{
+		{name: "legacy pool replaces legacy key", want: []string{"sk-pool-a", "sk-pool-b", "sk-pool-a"}},
+		{name: "provider without pool uses its key", provider: "anthropic", want: []string{"sk-ant", "sk-ant"}},
+		{name: "fallback to legacy key uses legacy pool", provider: "openai", fallback: true, want: []string{"sk-pool-a", "sk-pool-b"}},
+		{name: "pool needs no provider key", provider: "gemini", want: []string{"sk-gem-a", "sk-gem-a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &TransparentProxy{
+				config:       ProxyConfig{Provider: tt.provider, ProviderKeyFallback: tt.fallback},
+				projectStore: store,
+				keyPool:      newKeyPool("", 0),
+			}
+			for _, want := range tt.want {
+				selected, err := p.selectUpstreamKey(ctx, "p1")
+				require.NoError(t, err)
+				assert.Equal(t, want, selected.key)
+				assert.Equal(t, apiKeyFingerprint(want), selected.id)
+			}
+		})
+	}
+
+	// Providers with neither a pool nor a key of their own have no key
+	p := &TransparentProxy{config: ProxyConfig{Provider: "mistral"}, projectStore: store}
+	_, err := p.selectUpstreamKey(ctx, "p1")
+	assert.ErrorIs(t, err, ErrProviderAPIKeyNotFound)
+}
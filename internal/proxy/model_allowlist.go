@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/logging"
+	"go.uber.org/zap"
+)
+
+// AllowedModelsStore is implemented by project stores that keep per-project
+// model allowlists. Stores without it allow every model.
+type AllowedModelsStore interface {
+	// GetAllowedModelsForProject returns the project's model patterns (nil if unrestricted)
+	GetAllowedModelsForProject(ctx context.Context, projectID string) ([]string, error)
+}
+
+// GetAllowedModelsForProject returns the model allowlist of a project from
+// store, or nil when store does not keep allowlists.
+func GetAllowedModelsForProject(ctx context.Context, store ProjectStore, projectID string) ([]string, error) {
+	if ms, ok := store.(AllowedModelsStore); ok {
+		return ms.GetAllowedModelsForProject(ctx, projectID)
+	}
+	return nil, nil
+}
+
+// ValidateAllowedModels checks a project's model patterns.
+func ValidateAllowedModels(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.TrimSpace(pattern) == "" {
+			return errors.New("model pattern cannot be empty")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("model pattern '%s' is invalid: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// modelAllowed reports whether model matches one of patterns. An empty
+// allowlist allows every model.
+func modelAllowed(patterns []string, model string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceAllowedModels rejects requests for models outside the project's
+// allowlist. The allowlist narrows the provider's param_whitelist, which is
+// checked earlier. The allowlist is stored in the request context so fallback
+// chains skip models the project may not use.
+func (p *TransparentProxy) enforceAllowedModels(r *http.Request, projectID string) (*http.Request, int, ErrorResponse) {
+	patterns, err := GetAllowedModelsForProject(r.Context(), p.projectStore, projectID)
+	if err != nil {
+		p.logger.Error("Failed to load project model allowlist", zap.String("project_id", projectID), zap.Error(err))
+		return r, http.StatusServiceUnavailable, ErrorResponse{Error: "Model allowlist unavailable", Code: "policy_unavailable"}
+	}
+	if len(patterns) == 0 {
+		return r, 0, ErrorResponse{}
+	}
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeyAllowedModels, patterns))
+
+	model := requestModel(r)
+	if model == "" || modelAllowed(patterns, model) {
+		return r, 0, ErrorResponse{}
+	}
+
+	requestID, _ := logging.GetRequestID(r.Context())
+	p.logger.Warn("Model not allowed for project",
+		zap.String("request_id", requestID),
+		zap.String("project_id", projectID),
+		zap.String("model", model),
+	)
+	if p.auditLogger != nil {
+		auditEvent := audit.NewEvent(audit.ActionProxyPolicyViolation, audit.ActorSystem, audit.ResultDenied).
+			WithProjectID(projectID).
+			WithRequestID(requestID).
+			WithClientIP(getClientIP(r)).
+			WithUserAgent(r.UserAgent()).
+			WithHTTPMethod(r.Method).
+			WithEndpoint(r.URL.Path).
+			WithReason("model_not_allowed").
+			WithDetail("provider", p.providerName()).
+			WithDetail("policy", "allowed_models").
+			WithDetail("model", model)
+		if err := p.auditLogger.Log(auditEvent); err != nil {
+			p.logger.Warn("Failed to audit policy violation", zap.String("request_id", requestID), zap.Error(err))
+		}
+	}
+	return r, http.StatusForbidden, ErrorResponse{
+		Error:       "Model not allowed",
+		Code:        "model_not_allowed",
+		Description: fmt.Sprintf("model '%s' is not allowed for this project (allowed: %s)", model, strings.Join(patterns, ", ")),
+	}
+}
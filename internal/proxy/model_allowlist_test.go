@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAllowedModels(t *testing.T) {
+	assert.NoError(t, ValidateAllowedModels(nil))
+	assert.NoError(t, ValidateAllowedModels([]string{"gpt-4o-mini", "claude-3-5-*"}))
+	assert.Error(t, ValidateAllowedModels([]string{" "}))
+	assert.Error(t, ValidateAllowedModels([]string{"gpt-[4"}))
+}
+
+func TestModelAllowed(t *testing.T) {
+	assert.True(t, modelAllowed(nil, "gpt-4o"), "an empty allowlist allows every model")
+	patterns := []string{"gpt-4o-mini", "claude-3-5-*"}
+	assert.True(t, modelAllowed(patterns, "gpt-4o-mini"))
+	assert.True(t, modelAllowed(patterns, "claude-3-5-haiku-latest"))
+	assert.False(t, modelAllowed(patterns, "gpt-4o"))
+}
+
+// modelsProjectStore is a stubProjectStore with a model allowlist.
+type modelsProjectStore struct {
+	stubProjectStore
+	models []string
+	err    error
+}
+
+func (s *modelsProjectStore) GetAllowedModelsForProject(ctx context.Context, projectID string) ([]string, error) {
+	return s.models, s.err
+}
+
+func TestTransparentProxy_AllowedModels(t *testing.T) {
+	upstream := &modelUpstream{}
+	collector := &SimpleAuditCollector{}
+	store := &modelsProjectStore{models: []string{"gpt-4o-mini", "o3-*"}}
+	h := newTestProxy(t, startUpstream(t, upstream.ServeHTTP), withProjectStore(store), withAuditLogger(collector)).Handler()
+
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+	assert.Equal(t, http.StatusOK, sendChat(h, "o3-mini", nil).Code)
+
+	w := sendChat(h, "gpt-4o", nil)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "model_not_allowed")
+	assert.Contains(t, w.Body.String(), "model 'gpt-4o' is not allowed for this project")
+	assert.Equal(t, []string{"gpt-4o-mini", "o3-mini"}, upstream.seen())
+
+	events := policyViolations(collector)
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.ResultDenied, events[0].Result)
+	assert.Equal(t, "model_not_allowed", events[0].Details["reason"])
+	assert.Equal(t, "gpt-4o", events[0].Details["model"])
+
+	// Requests without a model are not restricted
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	store.err = errors.New("db down")
+	assert.Equal(t, http.StatusServiceUnavailable, sendChat(h, "gpt-4o-mini", nil).Code)
+}
+
+func TestTransparentProxy_AllowedModels_FallbackSkipsDisallowedModels(t *testing.T) {
+	upstream := &modelUpstream{status: map[string]int{"gpt-4o-mini": http.StatusServiceUnavailable}}
+	store := &modelsProjectStore{models: []string{"gpt-4o-mini", "gpt-4.1-mini"}}
+	h := newTestProxy(t, startUpstream(t, upstream.ServeHTTP), withProjectStore(store), withConfig(func(cfg *ProxyConfig) {
+		cfg.Fallback = FallbackPolicy{
+			On: []string{FallbackOn5xx},
+			Chains: map[string][]FallbackTarget{"gpt-4o-mini": {
+				{Provider: "openai", Model: "gpt-4o"},
+				{Provider: "openai", Model: "gpt-4.1-mini"},
+			}},
+		}
+	})).Handler()
+
+	w := sendChat(h, "gpt-4o-mini", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"gpt-4o-mini", "gpt-4.1-mini"}, upstream.seen())
+}
+
+func TestCachedProjectActiveStore_GetAllowedModelsForProject(t *testing.T) {
+	under := &modelsCountingProjectStore{models: []string{"gpt-4o-mini"}}
+	c := NewCachedProjectActiveStore(under, CachedProjectActiveStoreConfig{TTL: time.Minute, Max: 10})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		models, err := GetAllowedModelsForProject(ctx, c, "p1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"gpt-4o-mini"}, models)
+	}
+	assert.Equal(t, 1, under.modelsN)
+
+	require.NoError(t, c.UpdateProject(ctx, Project{ID: "p1"}))
+	_, _ = GetAllowedModelsForProject(ctx, c, "p1")
+	assert.Equal(t, 2, under.modelsN, "expected update to purge the cached allowlist")
+
+	under.err = errors.New("db down")
+	_, err := GetAllowedModelsForProject(ctx, c, "p2")
+	assert.Error(t, err)
+	under.err = nil
+	_, _ = GetAllowedModelsForProject(ctx, c, "p2")
+	assert.Equal(t, 4, under.modelsN, "errors are not cached")
+}
+
+// modelsCountingProjectStore counts model allowlist lookups.
+type modelsCountingProjectStore struct {
+	countingProjectStore
+	modelsN int
+	models  []string
+	err     error
+}
+
+func (s *modelsCountingProjectStore) GetAllowedModelsForProject(ctx context.Context, projectID string) ([]string, error) {
+	s.modelsN++
+	return s.models, s.err
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"sync"
+)
+
+// ModelRoute maps requests whose JSON body names a matching model to a provider.
+type ModelRoute struct {
+	// Model is a model name or glob pattern (e.g. "claude-*")
+	Model string `yaml:"model" json:"model"`
+	// Provider is the name of an entry in APIConfig.APIs
+	Provider string `yaml:"provider" json:"provider"`
+}
+
+// ModelRouter dispatches requests on the shared /v1/ prefix to provider
+// handlers based on the request's model. Routes are evaluated in order and the
+// first match wins; requests without a matching route go to the default
+// provider. The routing table can be replaced at runtime.
+type ModelRouter struct {
+	mu              sync.RWMutex
+	routes          []ModelRoute
+	handlers        map[string]http.Handler
+	defaultProvider string
+}
+
+// NewModelRouter creates a router over the given provider handlers.
+func NewModelRouter(handlers map[string]http.Handler, defaultProvider string, routes []ModelRoute) (*ModelRouter, error) {
+	if _, ok := handlers[defaultProvider]; !ok {
+		return nil, fmt.Errorf("default provider '%s' has no handler", defaultProvider)
+	}
+	r := &ModelRouter{handlers: handlers, defaultProvider: defaultProvider}
+	if err := r.SetRoutes(routes); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DefaultProvider returns the provider used when no route matches.
+func (r *ModelRouter) DefaultProvider() string {
+	return r.defaultProvider
+}
+
+// Routes returns a copy of the current routing table.
+func (r *ModelRouter) Routes() []ModelRoute {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]ModelRoute{}, r.routes...)
+}
+
+// SetRoutes validates and atomically replaces the routing table.
+func (r *ModelRouter) SetRoutes(routes []ModelRoute) error {
+	if err := validateModelRoutes(routes, func(name string) bool {
+		_, ok := r.handlers[name]
+		return ok
+	}); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.routes = append([]ModelRoute{}, routes...)
+	r.mu.Unlock()
+	return nil
+}
+
+// Match returns the first route whose pattern matches model.
+func (r *ModelRouter) Match(model string) (ModelRoute, bool) {
+	if model == "" {
+		return ModelRoute{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, route := range r.routes {
+		if ok, _ := path.Match(route.Model, model); ok {
+			return route, true
+		}
+	}
+	return ModelRoute{}, false
+}
+
+// ServeHTTP routes the request and records the decision in the X-Proxy-Provider
+// and X-Proxy-Model-Route response headers.
+func (r *ModelRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	provider := r.defaultProvider
+	// Only buffer the body for the model when there is something to match.
+	if r.hasRoutes() {
+		if route, ok := r.Match(requestModel(req)); ok {
+			provider = route.Provider
+			w.Header().Set("X-Proxy-Model-Route", route.Model)
+			req = req.WithContext(context.WithValue(req.Context(), ctxKeyModelRoute, route.Model))
+		}
+	}
+	w.Header().Set("X-Proxy-Provider", provider)
+	r.handlers[provider].ServeHTTP(w, req)
+}
+
+func (r *ModelRouter) hasRoutes() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.routes) > 0
+}
+
+// validateModelRoutes checks route patterns and that each provider exists.
+func validateModelRoutes(routes []ModelRoute, providerExists func(string) bool) error {
+	for i, route := range routes {
+		if route.Model == "" {
+			return fmt.Errorf("model route %d has no model pattern", i)
+		}
+		if _, err := path.Match(route.Model, ""); err != nil {
+			return fmt.Errorf("model route %d has invalid pattern '%s': %w", i, route.Model, err)
+		}
+		if !providerExists(route.Provider) {
+			return fmt.Errorf("model route '%s' references unknown provider '%s'", route.Model, route.Provider)
+		}
+	}
+	return nil
+}
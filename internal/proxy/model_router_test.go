@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/eventbus"
+	"github.com/sofatutor/llm-proxy/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// recordingHandler records the requests it serves.
+type recordingHandler struct {
+	calls int
+	body  string
+	route string
+}
+
+func (h *recordingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	b, _ := io.ReadAll(r.Body)
+	h.body = string(b)
+	h.route, _ = r.Context().Value(ctxKeyModelRoute).(string)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestModelRouter_Match(t *testing.T) {
+	handlers := map[string]http.Handler{"openai": &recordingHandler{}, "claude": &recordingHandler{}, "gemini": &recordingHandler{}}
+	r, err := NewModelRouter(handlers, "openai", []ModelRoute{
+		{Model: "claude-3-haiku", Provider: "openai"},
+		{Model: "claude-*", Provider: "claude"},
+		{Model: "gemini-*", Provider: "gemini"},
+	})
+	require.NoError(t, err)
+
+	route, ok := r.Match("claude-3-haiku")
+	require.True(t, ok)
+	assert.Equal(t, "openai", route.Provider, "first matching route wins")
+
+	route, ok = r.Match("claude-sonnet-4")
+	require.True(t, ok)
+	assert.Equal(t, ModelRoute{Model: "claude-*", Provider: "claude"}, route)
+
+	_, ok = r.Match("gpt-4o")
+	assert.False(t, ok)
+	_, ok = r.Match("")
+	assert.False(t, ok)
+}
+
+func TestModelRouter_SetRoutes(t *testing.T) {
+	handlers := map[string]http.Handler{"openai": &recordingHandler{}, "claude": &recordingHandler{}}
+	_, err := NewModelRouter(handlers, "missing", nil)
+	assert.Error(t, err, "default provider must have a handler")
+
+	r, err := NewModelRouter(handlers, "openai", nil)
+	require.NoError(t, err)
+	assert.Empty(t, r.Routes())
+	assert.Equal(t, "openai", r.DefaultProvider())
+
+	assert.Error(t, r.SetRoutes([]ModelRoute{{Model: "claude-*", Provider: "anthropic"}}), "unknown provider")
+	assert.Error(t, r.SetRoutes([]ModelRoute{{Model: "", Provider: "claude"}}), "empty pattern")
+	assert.Error(t, r.SetRoutes([]ModelRoute{{Model: "claude-[", Provider: "claude"}}), "invalid glob")
+	assert.Empty(t, r.Routes(), "failed updates leave the table unchanged")
+
+	routes := []ModelRoute{{Model: "claude-*", Provider: "claude"}}
+	require.NoError(t, r.SetRoutes(routes))
+	got := r.Routes()
+	assert.Equal(t, routes, got)
+	got[0].Provider = "openai"
+	assert.Equal(t, "claude", r.Routes()[0].Provider, "Routes returns a copy")
+}
+
+func TestModelRouter_ServeHTTP(t *testing.T) {
+	openai := &recordingHandler{}
+	claude := &recordingHandler{}
+	r, err := NewModelRouter(map[string]http.Handler{"openai": openai, "claude": claude}, "openai", []ModelRoute{
+		{Model: "claude-*", Provider: "claude"},
+	})
+	require.NoError(t, err)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	body := `{"model":"claude-sonnet-4","messages":[]}`
+	w := send(body)
+	assert.Equal(t, 1, claude.calls)
+	assert.Equal(t, body, claude.body, "body is restored for the provider")
+	assert.Equal(t, "claude-*", claude.route)
+	assert.Equal(t, "claude", w.Header().Get("X-Proxy-Provider"))
+	assert.Equal(t, "claude-*", w.Header().Get("X-Proxy-Model-Route"))
+
+	w = send(`{"model":"gpt-4o"}`)
+	assert.Equal(t, 1, openai.calls)
+	assert.Empty(t, openai.route)
+	assert.Equal(t, "openai", w.Header().Get("X-Proxy-Provider"))
+	assert.Empty(t, w.Header().Get("X-Proxy-Model-Route"))
+
+	// Requests without a JSON model go to the default provider.
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 2, openai.calls)
+	assert.Equal(t, "openai", w.Header().Get("X-Proxy-Provider"))
+}
+
+func TestModelRouter_AnnotatesEvent(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	bus := eventbus.NewInMemoryEventBus(10)
+	defer bus.Stop()
+	events := bus.Subscribe()
+
+	p, err := NewTransparentProxyWithLoggerAndObservability(ProxyConfig{
+		TargetBaseURL:    upstream.URL,
+		Provider:         "claude",
+		AllowedEndpoints: []string{"/v1/chat/completions"},
+		AllowedMethods:   []string{http.MethodPost},
+	}, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop(), middleware.ObservabilityConfig{Enabled: true, EventBus: bus})
+	require.NoError(t, err)
+
+	r, err := NewModelRouter(map[string]http.Handler{"claude": p.Handler()}, "claude", []ModelRoute{{Model: "claude-*", Provider: "claude"}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"claude-opus"}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "claude", w.Header().Get("X-Proxy-Provider"))
+
+	select {
+	case evt := <-events:
+		assert.Equal(t, "claude", evt.Provider)
+		assert.Equal(t, "claude-*", evt.ModelRoute)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no observability event published")
+	}
+}
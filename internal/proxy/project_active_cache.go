@@ -5,15 +5,24 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
 )
 
-// CachedProjectActiveStore wraps a ProjectStore with an in-memory TTL+LRU cache for GetProjectActive.
+// CachedProjectActiveStore wraps a ProjectStore with an in-memory TTL+LRU cache for GetProjectActive,
+// GetAllowedModelsForProject, GetClientRestrictionsForProject and GetRateLimitForProject.
 //
-// Rationale: GetProjectActive is on the hot path when EnforceProjectActive is enabled and can be a DB lookup.
-// Caching avoids per-request DB round-trips in steady state.
+// Rationale: these lookups are on the hot path (active status when EnforceProjectActive is enabled, model
+// allowlists, client restrictions and rate limits on every request) and can be DB lookups. Caching avoids per-request DB round-trips in steady state.
 type CachedProjectActiveStore struct {
 	underlying ProjectStore
 	cache      *projectActiveCache
+	// models caches model allowlists by project ID, including unrestricted projects
+	models *projectCache[[]string]
+	// clients caches client restrictions by project ID, including unrestricted projects
+	clients *projectCache[token.ClientRestrictions]
+	// rateLimits caches rate limits by project ID, including unlimited projects
+	rateLimits *projectCache[token.RateLimit]
 }
 
 type CachedProjectActiveStoreConfig struct {
@@ -31,6 +40,9 @@ func NewCachedProjectActiveStore(underlying ProjectStore, cfg CachedProjectActiv
 	return &CachedProjectActiveStore{
 		underlying: underlying,
 		cache:      newProjectActiveCache(cfg.TTL, cfg.Max),
+		models:     newProjectCache[[]string](cfg.TTL, cfg.Max),
+		clients:    newProjectCache[token.ClientRestrictions](cfg.TTL, cfg.Max),
+		rateLimits: newProjectCache[token.RateLimit](cfg.TTL, cfg.Max),
 	}
 }
 
@@ -53,8 +65,63 @@ func (s *CachedProjectActiveStore) GetProjectActive(ctx context.Context, project
 	return active, nil
 }
 
-func (s *CachedProjectActiveStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
-	return s.underlying.GetAPIKeyForProject(ctx, projectID)
+// GetAllowedModelsForProject returns the project's model allowlist, cached like active status.
+func (s *CachedProjectActiveStore) GetAllowedModelsForProject(ctx context.Context, projectID string) ([]string, error) {
+	if v, ok := s.models.Get(projectID); ok {
+		return v, nil
+	}
+	models, err := GetAllowedModelsForProject(ctx, s.underlying, projectID)
+	if err != nil {
+		return nil, err
+	}
+	s.models.Set(projectID, models)
+	return models, nil
+}
+
+// GetClientRestrictionsForProject returns the project's client restrictions, cached like active status.
+func (s *CachedProjectActiveStore) GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	if v, ok := s.clients.Get(projectID); ok {
+		return v, nil
+	}
+	restrictions, err := GetClientRestrictionsForProject(ctx, s.underlying, projectID)
+	if err != nil {
+		return token.ClientRestrictions{}, err
+	}
+	s.clients.Set(projectID, restrictions)
+	return restrictions, nil
+}
+
+// GetRateLimitForProject returns the project's rate limit, cached like active status.
+func (s *CachedProjectActiveStore) GetRateLimitForProject(ctx context.Context, projectID string) (token.RateLimit, error) {
+	if v, ok := s.rateLimits.Get(projectID); ok {
+		return v, nil
+	}
+	limit, err := GetRateLimitForProject(ctx, s.underlying, projectID)
+	if err != nil {
+		return token.RateLimit{}, err
+	}
+	s.rateLimits.Set(projectID, limit)
+	return limit, nil
+}
+
+// purge drops everything cached for projectID.
+func (s *CachedProjectActiveStore) purge(projectID string) {
+	s.cache.Purge(projectID)
+	s.models.Purge(projectID)
+	s.clients.Purge(projectID)
+	s.rateLimits.Purge(projectID)
+}
+
+func (s *CachedProjectActiveStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
+	return s.underlying.GetAPIKeyForProject(ctx, projectID, provider)
+}
+
+func (s *CachedProjectActiveStore) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]APIKeyPoolEntry, error) {
+	return GetAPIKeyPoolForProject(ctx, s.underlying, projectID, provider)
+}
+
+func (s *CachedProjectActiveStore) GetRequestPolicyForProject(ctx context.Context, projectID string) ([]RequestPolicyRule, error) {
+	return GetRequestPolicyForProject(ctx, s.underlying, projectID)
 }
 
 func (s *CachedProjectActiveStore) ListProjects(ctx context.Context) ([]Project, error) {
@@ -66,7 +133,7 @@ func (s *CachedProjectActiveStore) CreateProject(ctx context.Context, project Pr
 		return err
 	}
 	if project.ID != "" {
-		s.cache.Purge(project.ID)
+		s.purge(project.ID)
 	}
 	return nil
 }
@@ -80,7 +147,7 @@ func (s *CachedProjectActiveStore) UpdateProject(ctx context.Context, project Pr
 		return err
 	}
 	if project.ID != "" {
-		s.cache.Purge(project.ID)
+		s.purge(project.ID)
 	}
 	return nil
 }
@@ -90,7 +157,7 @@ func (s *CachedProjectActiveStore) DeleteProject(ctx context.Context, projectID
 		return err
 	}
 	if projectID != "" {
-		s.cache.Purge(projectID)
+		s.purge(projectID)
 	}
 	return nil
 }
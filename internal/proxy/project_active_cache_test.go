@@ -20,7 +20,7 @@ type countingActiveStore struct {
 	err     error
 }
 
-func (s *countingActiveStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (s *countingActiveStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	return "sk-test", nil
 }
 func (s *countingActiveStore) GetProjectActive(ctx context.Context, projectID string) (bool, error) {
@@ -123,7 +123,7 @@ func applyCORSResponseHeaders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Access-Control-Allow-Origin", origin)
-	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Proxy-ID, X-LLM-Proxy-Remote-Duration, X-LLM-Proxy-Remote-Duration-Ms")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Proxy-ID, X-Proxy-Provider, X-Proxy-Model-Route, X-Proxy-Model, X-Proxy-Fallback-Attempts, X-LLM-Proxy-Remote-Duration, X-LLM-Proxy-Remote-Duration-Ms")
 	w.Header().Add("Vary", "Origin")
 }
 
@@ -157,20 +157,12 @@ func getLoggerFromContext(ctx context.Context) *zap.Logger {
 }
 
 // getClientIP extracts the client IP address from the request
-// It checks X-Forwarded-For, X-Real-IP headers and falls back to RemoteAddr
+// It returns the IP resolved by the proxy handler, which honours forwarded
+// headers only from trusted proxies, and falls back to RemoteAddr. The
+// client-controlled X-Forwarded-For and X-Real-IP headers are never read here.
 func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (comma-separated list)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP from the list
-		if idx := strings.Index(xff, ","); idx >= 0 {
-			return strings.TrimSpace(xff[:idx])
-		}
-		return strings.TrimSpace(xff)
-	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return strings.TrimSpace(xri)
+	if ip, ok := r.Context().Value(ctxKeyClientIP).(string); ok {
+		return ip
 	}
 
 	// Fallback to RemoteAddr (remove port if present)
@@ -3,11 +3,15 @@ package proxy
 import (
 	"container/list"
 	"context"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
 )
 
 // CachedProjectStore wraps a ProjectStore with an in-memory TTL+LRU cache for GetAPIKeyForProject.
+// Entries are keyed by project and provider, so each provider key is cached (and expires) separately.
 //
 // Rationale: GetAPIKeyForProject is on the hot path for cache misses and currently performs a DB query.
 // Caching avoids per-request DB round-trips in steady state.
@@ -16,7 +20,11 @@ import (
 // not change persistence characteristics and is scoped to the process lifetime.
 type CachedProjectStore struct {
 	underlying ProjectStore
-	cache      *apiKeyCache
+	cache      *projectCache[string]
+	// policies caches request policies by project ID, including projects without one
+	policies *projectCache[[]RequestPolicyRule]
+	// pools caches key pools by project and provider, including empty pools
+	pools *projectCache[[]APIKeyPoolEntry]
 }
 
 type CachedProjectStoreConfig struct {
@@ -33,29 +41,84 @@ func NewCachedProjectStore(underlying ProjectStore, cfg CachedProjectStoreConfig
 	}
 	return &CachedProjectStore{
 		underlying: underlying,
-		cache:      newAPIKeyCache(cfg.TTL, cfg.Max),
+		cache:      newProjectCache[string](cfg.TTL, cfg.Max),
+		policies:   newProjectCache[[]RequestPolicyRule](cfg.TTL, cfg.Max),
+		pools:      newProjectCache[[]APIKeyPoolEntry](cfg.TTL, cfg.Max),
 	}
 }
 
-func (s *CachedProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (s *CachedProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
+	cacheKey := apiKeyCacheKey(projectID, provider)
 	if projectID != "" {
-		if v, ok := s.cache.Get(projectID); ok {
+		if v, ok := s.cache.Get(cacheKey); ok {
 			return v, nil
 		}
 	}
 
-	apiKey, err := s.underlying.GetAPIKeyForProject(ctx, projectID)
+	apiKey, err := s.underlying.GetAPIKeyForProject(ctx, projectID, provider)
 	if err != nil {
 		return "", err
 	}
 	if projectID != "" && apiKey != "" {
 		// Intentionally do not cache empty API keys: an empty key typically indicates misconfiguration
 		// and we prefer not to "stick" that state in cache while an operator fixes the project.
-		s.cache.Set(projectID, apiKey)
+		s.cache.Set(cacheKey, apiKey)
 	}
 	return apiKey, nil
 }
 
+// GetAPIKeyPoolForProject returns the project's key pool for provider, cached like API keys.
+func (s *CachedProjectStore) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]APIKeyPoolEntry, error) {
+	cacheKey := apiKeyCacheKey(projectID, provider)
+	if v, ok := s.pools.Get(cacheKey); ok {
+		return v, nil
+	}
+	pool, err := GetAPIKeyPoolForProject(ctx, s.underlying, projectID, provider)
+	if err != nil {
+		return nil, err
+	}
+	s.pools.Set(cacheKey, pool)
+	return pool, nil
+}
+
+// GetRequestPolicyForProject returns the project's request policy, cached like API keys.
+func (s *CachedProjectStore) GetRequestPolicyForProject(ctx context.Context, projectID string) ([]RequestPolicyRule, error) {
+	if v, ok := s.policies.Get(projectID); ok {
+		return v, nil
+	}
+	rules, err := GetRequestPolicyForProject(ctx, s.underlying, projectID)
+	if err != nil {
+		return nil, err
+	}
+	s.policies.Set(projectID, rules)
+	return rules, nil
+}
+
+func (s *CachedProjectStore) GetAllowedModelsForProject(ctx context.Context, projectID string) ([]string, error) {
+	return GetAllowedModelsForProject(ctx, s.underlying, projectID)
+}
+
+func (s *CachedProjectStore) GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	return GetClientRestrictionsForProject(ctx, s.underlying, projectID)
+}
+
+func (s *CachedProjectStore) GetRateLimitForProject(ctx context.Context, projectID string) (token.RateLimit, error) {
+	return GetRateLimitForProject(ctx, s.underlying, projectID)
+}
+
+// purge drops everything cached for projectID.
+func (s *CachedProjectStore) purge(projectID string) {
+	s.cache.PurgeProject(projectID)
+	s.pools.PurgeProject(projectID)
+	s.policies.Purge(projectID)
+}
+
+// apiKeyCacheKey builds the cache key for a project's provider key. The NUL separator
+// cannot appear in project IDs, which keeps per-project prefix purges unambiguous.
+func apiKeyCacheKey(projectID, provider string) string {
+	return projectID + "\x00" + provider
+}
+
 func (s *CachedProjectStore) GetProjectActive(ctx context.Context, projectID string) (bool, error) {
 	return s.underlying.GetProjectActive(ctx, projectID)
 }
@@ -71,7 +134,7 @@ func (s *CachedProjectStore) CreateProject(ctx context.Context, project Project)
 	if project.ID != "" {
 		// Defensive purge: ensures we never serve a stale API key for a re-created project ID
 		// (e.g., delete+recreate with same ID, or out-of-band DB changes).
-		s.cache.Purge(project.ID)
+		s.purge(project.ID)
 	}
 	return nil
 }
@@ -85,7 +148,7 @@ func (s *CachedProjectStore) UpdateProject(ctx context.Context, project Project)
 		return err
 	}
 	if project.ID != "" {
-		s.cache.Purge(project.ID)
+		s.purge(project.ID)
 	}
 	return nil
 }
@@ -95,54 +158,56 @@ func (s *CachedProjectStore) DeleteProject(ctx context.Context, projectID string
 		return err
 	}
 	if projectID != "" {
-		s.cache.Purge(projectID)
+		s.purge(projectID)
 	}
 	return nil
 }
 
-type apiKeyCacheEntry struct {
+type projectCacheEntry[V any] struct {
 	key       string
-	value     string
+	value     V
 	expiresAt time.Time
 	elem      *list.Element
 }
 
-type apiKeyCache struct {
+// projectCache is a TTL+LRU cache for per-project lookups.
+type projectCache[V any] struct {
 	mu  sync.Mutex
 	ll  *list.List
-	m   map[string]*apiKeyCacheEntry
+	m   map[string]*projectCacheEntry[V]
 	ttl time.Duration
 	max int
 }
 
-func newAPIKeyCache(ttl time.Duration, max int) *apiKeyCache {
-	return &apiKeyCache{
+func newProjectCache[V any](ttl time.Duration, max int) *projectCache[V] {
+	return &projectCache[V]{
 		ll:  list.New(),
-		m:   make(map[string]*apiKeyCacheEntry, max),
+		m:   make(map[string]*projectCacheEntry[V], max),
 		ttl: ttl,
 		max: max,
 	}
 }
 
-func (c *apiKeyCache) Get(key string) (string, bool) {
+func (c *projectCache[V]) Get(key string) (V, bool) {
+	var zero V
 	now := time.Now()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	ent := c.m[key]
 	if ent == nil {
-		return "", false
+		return zero, false
 	}
 	if now.After(ent.expiresAt) {
 		c.removeLocked(ent)
-		return "", false
+		return zero, false
 	}
 
 	c.ll.MoveToFront(ent.elem)
 	return ent.value, true
 }
 
-func (c *apiKeyCache) Set(key, value string) {
+func (c *projectCache[V]) Set(key string, value V) {
 	if key == "" {
 		return
 	}
@@ -160,7 +225,7 @@ func (c *apiKeyCache) Set(key, value string) {
 	}
 
 	elem := c.ll.PushFront(key)
-	ent := &apiKeyCacheEntry{key: key, value: value, expiresAt: exp, elem: elem}
+	ent := &projectCacheEntry[V]{key: key, value: value, expiresAt: exp, elem: elem}
 	c.m[key] = ent
 
 	if c.max > 0 && c.ll.Len() > c.max {
@@ -168,7 +233,7 @@ func (c *apiKeyCache) Set(key, value string) {
 	}
 }
 
-func (c *apiKeyCache) Purge(key string) {
+func (c *projectCache[V]) Purge(key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if ent := c.m[key]; ent != nil {
@@ -176,7 +241,19 @@ func (c *apiKeyCache) Purge(key string) {
 	}
 }
 
-func (c *apiKeyCache) evictOldestLocked() {
+// PurgeProject removes every cached provider key for projectID.
+func (c *projectCache[V]) PurgeProject(projectID string) {
+	prefix := apiKeyCacheKey(projectID, "")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, ent := range c.m {
+		if strings.HasPrefix(key, prefix) {
+			c.removeLocked(ent)
+		}
+	}
+}
+
+func (c *projectCache[V]) evictOldestLocked() {
 	elem := c.ll.Back()
 	if elem == nil {
 		return
@@ -196,7 +273,7 @@ func (c *apiKeyCache) evictOldestLocked() {
 	c.ll.Remove(elem)
 }
 
-func (c *apiKeyCache) removeLocked(ent *apiKeyCacheEntry) {
+func (c *projectCache[V]) removeLocked(ent *projectCacheEntry[V]) {
 	delete(c.m, ent.key)
 	if ent.elem != nil {
 		c.ll.Remove(ent.elem)
@@ -24,7 +24,7 @@ type countingProjectStore struct {
 	active bool
 }
 
-func (s *countingProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (s *countingProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	s.mu.Lock()
 	s.apiKeyN++
 	s.mu.Unlock()
@@ -78,9 +78,9 @@ func TestCachedProjectStore_GetAPIKeyForProject_Caches(t *testing.T) {
 	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 10})
 
 	ctx := context.Background()
-	v1, err := c.GetAPIKeyForProject(ctx, "p1")
+	v1, err := c.GetAPIKeyForProject(ctx, "p1", "")
 	require.NoError(t, err)
-	v2, err := c.GetAPIKeyForProject(ctx, "p1")
+	v2, err := c.GetAPIKeyForProject(ctx, "p1", "")
 	require.NoError(t, err)
 
 	require.Equal(t, "sk-test", v1)
@@ -96,9 +96,9 @@ func TestCachedProjectStore_GetAPIKeyForProject_TTLExpires(t *testing.T) {
 	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: 20 * time.Millisecond, Max: 10})
 
 	ctx := context.Background()
-	_, _ = c.GetAPIKeyForProject(ctx, "p1")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "")
 	time.Sleep(30 * time.Millisecond)
-	_, _ = c.GetAPIKeyForProject(ctx, "p1")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "")
 
 	under.mu.Lock()
 	defer under.mu.Unlock()
@@ -110,9 +110,9 @@ func TestCachedProjectStore_UpdateProject_Invalidate(t *testing.T) {
 	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 10})
 
 	ctx := context.Background()
-	_, _ = c.GetAPIKeyForProject(ctx, "p1")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "")
 	require.NoError(t, c.UpdateProject(ctx, Project{ID: "p1"}))
-	_, _ = c.GetAPIKeyForProject(ctx, "p1")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "")
 
 	under.mu.Lock()
 	defer under.mu.Unlock()
@@ -125,9 +125,9 @@ func TestCachedProjectStore_DeleteProject_Invalidate(t *testing.T) {
 	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 10})
 
 	ctx := context.Background()
-	_, _ = c.GetAPIKeyForProject(ctx, "p1")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "")
 	require.NoError(t, c.DeleteProject(ctx, "p1"))
-	_, _ = c.GetAPIKeyForProject(ctx, "p1")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "")
 
 	under.mu.Lock()
 	defer under.mu.Unlock()
@@ -140,9 +140,9 @@ func TestCachedProjectStore_CreateProject_Invalidate(t *testing.T) {
 	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 10})
 
 	ctx := context.Background()
-	_, _ = c.GetAPIKeyForProject(ctx, "p1")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "")
 	require.NoError(t, c.CreateProject(ctx, Project{ID: "p1"}))
-	_, _ = c.GetAPIKeyForProject(ctx, "p1")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "")
 
 	under.mu.Lock()
 	defer under.mu.Unlock()
@@ -155,12 +155,12 @@ func TestCachedProjectStore_GetAPIKeyForProject_LRUEviction(t *testing.T) {
 	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 2})
 
 	ctx := context.Background()
-	_, _ = c.GetAPIKeyForProject(ctx, "p1") // miss
-	_, _ = c.GetAPIKeyForProject(ctx, "p2") // miss
-	_, _ = c.GetAPIKeyForProject(ctx, "p1") // hit => p1 is MRU, p2 is LRU
-	_, _ = c.GetAPIKeyForProject(ctx, "p3") // miss => should evict p2
-	_, _ = c.GetAPIKeyForProject(ctx, "p1") // still hit (p1 should not have been evicted by inserting p3)
-	_, _ = c.GetAPIKeyForProject(ctx, "p2") // miss if evicted (note: this will reinsert p2 and may evict another key)
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "") // miss
+	_, _ = c.GetAPIKeyForProject(ctx, "p2", "") // miss
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "") // hit => p1 is MRU, p2 is LRU
+	_, _ = c.GetAPIKeyForProject(ctx, "p3", "") // miss => should evict p2
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "") // still hit (p1 should not have been evicted by inserting p3)
+	_, _ = c.GetAPIKeyForProject(ctx, "p2", "") // miss if evicted (note: this will reinsert p2 and may evict another key)
 
 	under.mu.Lock()
 	defer under.mu.Unlock()
@@ -175,7 +175,7 @@ type errorProjectStore struct {
 	mu  sync.Mutex
 }
 
-func (s *errorProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (s *errorProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	s.mu.Lock()
 	s.n++
 	s.mu.Unlock()
@@ -199,8 +199,8 @@ func TestCachedProjectStore_GetAPIKeyForProject_DoesNotCacheErrors(t *testing.T)
 	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 10})
 
 	ctx := context.Background()
-	_, err1 := c.GetAPIKeyForProject(ctx, "p1")
-	_, err2 := c.GetAPIKeyForProject(ctx, "p1")
+	_, err1 := c.GetAPIKeyForProject(ctx, "p1", "")
+	_, err2 := c.GetAPIKeyForProject(ctx, "p1", "")
 	require.Error(t, err1)
 	require.Error(t, err2)
 
@@ -224,7 +224,7 @@ func TestCachedProjectStore_ConcurrentAccess(t *testing.T) {
 			defer wg.Done()
 			pid := string(rune('a' + (idx % 10)))
 			for i := 0; i < iterations; i++ {
-				v, err := c.GetAPIKeyForProject(ctx, pid)
+				v, err := c.GetAPIKeyForProject(ctx, pid, "")
 				require.NoError(t, err)
 				require.Equal(t, "sk-test", v)
 				if i%10 == 0 {
@@ -277,3 +277,99 @@ func TestCachedProjectStore_PassThroughMethods_AreNotCached(t *testing.T) {
 	require.Equal(t, 2, under.getByIDN)
 	require.Equal(t, 2, under.listN)
 }
+
+func TestCachedProjectStore_CachesPerProviderAndPurgesProject(t *testing.T) {
+	under := &countingProjectStore{apiKey: "sk-test"}
+	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 10})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, _ = c.GetAPIKeyForProject(ctx, "p1", "openai")
+		_, _ = c.GetAPIKeyForProject(ctx, "p1", "anthropic")
+		_, _ = c.GetAPIKeyForProject(ctx, "p2", "openai")
+	}
+	under.mu.Lock()
+	require.Equal(t, 3, under.apiKeyN, "expected one underlying lookup per project/provider pair")
+	under.mu.Unlock()
+
+	require.NoError(t, c.UpdateProject(ctx, Project{ID: "p1"}))
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "openai")
+	_, _ = c.GetAPIKeyForProject(ctx, "p1", "anthropic")
+	_, _ = c.GetAPIKeyForProject(ctx, "p2", "openai")
+
+	under.mu.Lock()
+	defer under.mu.Unlock()
+	require.Equal(t, 5, under.apiKeyN, "expected update to purge every provider key of p1 only")
+}
+
+// policyCountingProjectStore is a countingProjectStore with request policies.
+type policyCountingProjectStore struct {
+	countingProjectStore
+	policyN int
+	rules   []RequestPolicyRule
+}
+
+func (s *policyCountingProjectStore) GetRequestPolicyForProject(ctx context.Context, projectID string) ([]RequestPolicyRule, error) {
+	s.mu.Lock()
+	s.policyN++
+	s.mu.Unlock()
+	return s.rules, nil
+}
+
+func TestCachedProjectStore_GetRequestPolicyForProject(t *testing.T) {
+	under := &policyCountingProjectStore{rules: []RequestPolicyRule{{Path: "$.tools", Forbidden: true}}}
+	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 10})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		rules, err := GetRequestPolicyForProject(ctx, c, "p1")
+		require.NoError(t, err)
+		require.Equal(t, under.rules, rules)
+	}
+	require.Equal(t, 1, under.policyN)
+
+	require.NoError(t, c.UpdateProject(ctx, Project{ID: "p1"}))
+	_, _ = GetRequestPolicyForProject(ctx, c, "p1")
+	require.Equal(t, 2, under.policyN, "expected update to purge the cached policy")
+
+	// Stores without request policies have none
+	rules, err := GetRequestPolicyForProject(ctx, NewCachedProjectStore(&countingProjectStore{}, CachedProjectStoreConfig{TTL: time.Minute, Max: 10}), "p1")
+	require.NoError(t, err)
+	require.Nil(t, rules)
+}
+
+// poolCountingProjectStore is a countingProjectStore with key pools.
+type poolCountingProjectStore struct {
+	countingProjectStore
+	poolN int
+	pools map[string][]APIKeyPoolEntry
+}
+
+func (s *poolCountingProjectStore) GetAPIKeyPoolForProject(ctx context.Context, projectID, provider string) ([]APIKeyPoolEntry, error) {
+	s.mu.Lock()
+	s.poolN++
+	s.mu.Unlock()
+	return s.pools[provider], nil
+}
+
+func TestCachedProjectStore_GetAPIKeyPoolForProject(t *testing.T) {
+	under := &poolCountingProjectStore{pools: map[string][]APIKeyPoolEntry{
+		"openai": {{Provider: "openai", APIKey: "sk-a"}, {Provider: "openai", APIKey: "sk-b", Weight: 2}},
+	}}
+	c := NewCachedProjectStore(under, CachedProjectStoreConfig{TTL: time.Minute, Max: 10})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		pool, err := GetAPIKeyPoolForProject(ctx, c, "p1", "openai")
+		require.NoError(t, err)
+		require.Equal(t, under.pools["openai"], pool)
+		pool, err = GetAPIKeyPoolForProject(ctx, c, "p1", "anthropic")
+		require.NoError(t, err)
+		require.Empty(t, pool)
+	}
+	require.Equal(t, 2, under.poolN, "expected one lookup per provider")
+
+	require.NoError(t, c.UpdateProject(ctx, Project{ID: "p1"}))
+	_, _ = GetAPIKeyPoolForProject(ctx, c, "p1", "openai")
+	require.Equal(t, 3, under.poolN, "expected update to purge the cached pools")
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/sofatutor/llm-proxy/internal/eventbus"
 	"github.com/sofatutor/llm-proxy/internal/logging"
 	"github.com/sofatutor/llm-proxy/internal/middleware"
 	"github.com/sofatutor/llm-proxy/internal/token"
@@ -46,6 +47,17 @@ type TransparentProxy struct {
 	obsMiddleware        *middleware.ObservabilityMiddleware
 	cache                httpCache
 	cacheStatsAggregator *CacheStatsAggregator
+	costAggregator       *CostAggregator
+	budgets              *BudgetTracker
+	tpm                  *token.TPMLimiter
+	rateLimiter          *token.RedisRateLimiter
+	concurrency          *token.ConcurrencyLimiter
+	keyPool              *keyPool
+	fallbackProviders    map[string]*TransparentProxy
+	breakers             *upstreamBreakers
+	transforms           *responseTransformer
+	requestPolicy        requestPolicy
+	trustedProxies       []*net.IPNet
 }
 
 // ProxyMetrics tracks proxy usage statistics
@@ -58,7 +70,13 @@ type ProxyMetrics struct {
 	CacheMisses int64 // Cache misses (responses fetched from upstream)
 	CacheBypass int64 // Cache bypassed (e.g., due to authorization)
 	CacheStores int64 // Cache stores (responses stored in cache)
-	mu          sync.Mutex
+	// Hedging metrics
+	HedgedRequests int64 // Requests for which a hedge was sent
+	HedgeWins      int64 // Hedged requests answered by the hedge
+	// Client restriction metrics
+	ClientIPDenials int64 // Requests rejected because the client IP is not allowed
+	OriginDenials   int64 // Requests rejected because the origin is not allowed
+	mu              sync.Mutex
 }
 
 // CacheMetricType represents the kind of cache metric to increment.
@@ -89,6 +107,10 @@ func (p *TransparentProxy) Metrics() ProxyMetrics {
 		CacheMisses:       p.metrics.CacheMisses,
 		CacheBypass:       p.metrics.CacheBypass,
 		CacheStores:       p.metrics.CacheStores,
+		HedgedRequests:    p.metrics.HedgedRequests,
+		HedgeWins:         p.metrics.HedgeWins,
+		ClientIPDenials:   p.metrics.ClientIPDenials,
+		OriginDenials:     p.metrics.OriginDenials,
 	}
 }
 
@@ -207,6 +229,20 @@ func NewTransparentProxyWithLogger(config ProxyConfig, validator TokenValidator,
 		metrics:              &ProxyMetrics{},
 		allowedMethodsHeader: allowedMethodsHeader,
 		targetURL:            targetURL,
+		keyPool:              newKeyPool(config.KeyPoolStrategy, config.KeyPoolBenchDuration),
+	}
+	proxy.breakers = newUpstreamBreakers(proxy.providerName(), config.CircuitBreaker, proxy.circuitStateChanged)
+	proxy.transforms, err = newResponseTransformer(config.ResponseTransforms, proxy.providerName())
+	if err != nil {
+		return nil, fmt.Errorf("invalid response transforms: %w", err)
+	}
+	proxy.requestPolicy, err = compileRequestPolicy(config.RequestPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request policy: %w", err)
+	}
+	proxy.trustedProxies, err = token.ParseNetworks(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
 	}
 
 	// Initialize HTTP cache (enabled only when HTTPCacheEnabled is true)
@@ -281,7 +317,7 @@ func NewTransparentProxyWithLogger(config ProxyConfig, validator TokenValidator,
 		Director:       proxy.director,
 		ModifyResponse: proxy.modifyResponse,
 		ErrorHandler:   proxy.errorHandler,
-		Transport:      proxy.createTransport(),
+		Transport:      newHedgeTransport(newRetryTransport(proxy.createTransport(), config.Retry, logger), config.Hedge, proxy.recordHedge, logger),
 		FlushInterval:  config.FlushInterval,
 	}
 
@@ -312,8 +348,10 @@ func NewTransparentProxyWithAudit(config ProxyConfig, validator TokenValidator,
 
 // director is the Director function for the reverse proxy
 func (p *TransparentProxy) director(req *http.Request) {
-	// Store original path in context for logging
-	*req = *req.WithContext(context.WithValue(req.Context(), ctxKeyOriginalPath, req.URL.Path))
+	// Store original path and query in context for logging and cache keys
+	ctx := context.WithValue(req.Context(), ctxKeyOriginalPath, req.URL.Path)
+	ctx = context.WithValue(ctx, ctxKeyOriginalQuery, req.URL.RawQuery)
+	*req = *req.WithContext(ctx)
 
 	// Update request URL
 	req.URL.Scheme = p.targetURL.Scheme
@@ -330,6 +368,25 @@ func (p *TransparentProxy) director(req *http.Request) {
 	// Preserve or strip certain headers
 	p.processRequestHeaders(req)
 
+	// JSON transforms need a decoded body: let the transport negotiate (and
+	// transparently decode) compression rather than passing the client's
+	// Accept-Encoding upstream.
+	if p.transforms != nil && p.transforms.jsonOps {
+		req.Header.Del("Accept-Encoding")
+	}
+
+	if p.config.Mode == ProviderModeAzureOpenAI {
+		p.applyAzureRewrite(req)
+	}
+	if t, ok := req.Context().Value(ctxKeyChatTranslation).(*chatTranslation); ok {
+		applyChatTranslation(req, t)
+	}
+
+	// Swap the client's proxy token for the upstream credential
+	if sel, ok := req.Context().Value(ctxKeyUpstreamKey).(*upstreamKeySelection); ok && sel.key != "" {
+		p.config.UpstreamAuth.applyUpstreamCredential(req, sel.key)
+	}
+
 	// --- PATCH: Add X-UPSTREAM-REQUEST-START header ---
 	upstreamStart := time.Now().UnixNano()
 	req.Header.Set("X-UPSTREAM-REQUEST-START", strconv.FormatInt(upstreamStart, 10))
@@ -354,7 +411,7 @@ func (p *TransparentProxy) director(req *http.Request) {
 	p.logger.Debug("Upstream request",
 		zap.String("request_id", requestID),
 		zap.String("method", req.Method),
-		zap.String("url", req.URL.String()),
+		zap.String("url", p.config.UpstreamAuth.redactedURL(req.URL)),
 		zap.Any("headers", headers),
 	)
 }
@@ -427,7 +484,16 @@ func calculateCacheTTL(res *http.Response, req *http.Request, defaultTTL time.Du
 	return 0, false
 }
 
-func (p *TransparentProxy) modifyResponse(res *http.Response) error {
+func (p *TransparentProxy) modifyResponse(res *http.Response) (err error) {
+	// Transforms run last so cached entries keep the upstream response
+	if p.transforms != nil {
+		defer func() {
+			if err == nil {
+				err = p.transformResponse(res)
+			}
+		}()
+	}
+
 	// Set proxy headers (always)
 	res.Header.Set("X-Proxy", "llm-proxy")
 
@@ -444,14 +510,29 @@ func (p *TransparentProxy) modifyResponse(res *http.Response) error {
 		if requestID, ok := res.Request.Context().Value(ctxKeyRequestID).(string); ok && requestID != "" {
 			res.Header.Set("X-Request-ID", requestID)
 		}
+		setRateLimitHeaders(res.Request.Context(), res.Header)
 
 		if origin := res.Request.Header.Get("Origin"); origin != "" {
 			res.Header.Set("Access-Control-Allow-Origin", origin)
-			res.Header.Set("Access-Control-Expose-Headers", "X-Request-ID, X-Proxy-ID, X-LLM-Proxy-Remote-Duration, X-LLM-Proxy-Remote-Duration-Ms")
+			res.Header.Set("Access-Control-Expose-Headers", "X-Request-ID, X-Proxy-ID, X-Proxy-Provider, X-Proxy-Model-Route, X-Proxy-Model, X-Proxy-Fallback-Attempts, X-LLM-Proxy-Remote-Duration, X-LLM-Proxy-Remote-Duration-Ms, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 			res.Header.Add("Vary", "Origin")
 		}
 	}
 
+	p.reportUpstreamKeyOutcome(res)
+	restoreClientURL(res.Request)
+
+	// Convert translated backends' responses back into OpenAI chat completions
+	if res.Request != nil {
+		if t, ok := res.Request.Context().Value(ctxKeyChatTranslation).(*chatTranslation); ok {
+			if err := t.translateResponse(res); err != nil {
+				return err
+			}
+		}
+	}
+
+	p.captureCost(res)
+
 	// --- PATCH: Add X-UPSTREAM-REQUEST-STOP header ---
 	upstreamStop := time.Now().UnixNano()
 	res.Header.Set("X-UPSTREAM-REQUEST-STOP", strconv.FormatInt(upstreamStop, 10))
@@ -480,8 +561,9 @@ func (p *TransparentProxy) modifyResponse(res *http.Response) error {
 		}
 	}
 
-	// Store in cache when enabled and request is cacheable
-	if p.cache != nil && res.Request != nil {
+	// Store in cache when enabled and request is cacheable. Fallback responses
+	// come from a different model than the one requested and are never stored.
+	if p.cache != nil && res.Request != nil && !isFallbackResponse(res.Request) {
 		req := res.Request
 		if req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodPost {
 			// Only cache successful responses
@@ -621,6 +703,14 @@ func (p *TransparentProxy) errorHandler(w http.ResponseWriter, r *http.Request,
 		// Use default values
 	}
 
+	// Let a fallback chain see why the attempt failed
+	if attempt, ok := r.Context().Value(ctxKeyFallbackAttempt).(*fallbackAttempt); ok {
+		attempt.err = err
+	}
+	if acct, ok := r.Context().Value(ctxKeyCostAccount).(*costAccount); ok {
+		releaseTPM(acct)
+	}
+
 	writeErrorResponseForRequest(w, r, statusCode, errorResponse)
 }
 
@@ -747,6 +837,138 @@ func prepareBodyHashForCaching(r *http.Request, maxBytes int64, logger *zap.Logg
 	return true
 }
 
+// upstreamAPIKey resolves the upstream key for projectID on this proxy's provider
+// and returns it with the provider it is stored under ("" for the legacy key).
+// The legacy project key is only used when no provider is configured or when
+// ProviderKeyFallback is set, so a default-provider key never leaks to other vendors.
+func (p *TransparentProxy) upstreamAPIKey(ctx context.Context, projectID string) (string, string, error) {
+	if p.config.Provider == "" {
+		apiKey, err := p.projectStore.GetAPIKeyForProject(ctx, projectID, "")
+		return apiKey, "", err
+	}
+	apiKey, err := p.projectStore.GetAPIKeyForProject(ctx, projectID, p.config.Provider)
+	if errors.Is(err, ErrProviderAPIKeyNotFound) && p.config.ProviderKeyFallback {
+		apiKey, err = p.projectStore.GetAPIKeyForProject(ctx, projectID, "")
+		return apiKey, "", err
+	}
+	return apiKey, p.config.Provider, err
+}
+
+// restoreClientURL resets the outgoing request's path and query to what the
+// client sent once the response arrives, so cache storage keys match the
+// handler's lookup keys and upstream credentials in the query are dropped.
+func restoreClientURL(req *http.Request) {
+	if req == nil || req.URL == nil {
+		return
+	}
+	if origPath, ok := req.Context().Value(ctxKeyOriginalPath).(string); ok {
+		req.URL.Path = origPath
+		req.URL.RawPath = ""
+	}
+	if origQuery, ok := req.Context().Value(ctxKeyOriginalQuery).(string); ok {
+		req.URL.RawQuery = origQuery
+	}
+}
+
+// selectUpstreamKey picks the upstream key for a request of projectID. When the
+// project has a key pool for the provider, the pool replaces the provider's
+// single key, which it then does not need. The single key is looked up only
+// without a pool, and when it falls back to the legacy key, the legacy pool
+// replaces that one in turn.
+func (p *TransparentProxy) selectUpstreamKey(ctx context.Context, projectID string) (pooledKey, error) {
+	pool, err := GetAPIKeyPoolForProject(ctx, p.projectStore, projectID, p.config.Provider)
+	if err != nil {
+		return pooledKey{}, err
+	}
+	if len(pool) == 0 {
+		apiKey, provider, err := p.upstreamAPIKey(ctx, projectID)
+		if err != nil {
+			return pooledKey{}, err
+		}
+		if provider != p.config.Provider {
+			if pool, err = GetAPIKeyPoolForProject(ctx, p.projectStore, projectID, provider); err != nil {
+				return pooledKey{}, err
+			}
+		}
+		if len(pool) == 0 {
+			return newPooledKey(apiKey, 1), nil
+		}
+	}
+	// Keys whose circuit is open are skipped while others are available
+	keys := p.breakers.availableKeys(pooledKeys(pool))
+	if p.keyPool == nil {
+		return keys[0], nil
+	}
+	return p.keyPool.Select(projectID, keys), nil
+}
+
+// reportUpstreamKeyOutcome benches the pooled key that served res when the
+// upstream rejected it with 429 or 401.
+func (p *TransparentProxy) reportUpstreamKeyOutcome(res *http.Response) {
+	if p.keyPool == nil || res.Request == nil {
+		return
+	}
+	sel, ok := res.Request.Context().Value(ctxKeyUpstreamKey).(*upstreamKeySelection)
+	if !ok || sel == nil || sel.keyID == "" {
+		return
+	}
+	retryAfter := parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+	if bench := p.keyPool.Report(sel.projectID, sel.keyID, res.StatusCode, retryAfter); bench > 0 {
+		p.logger.Warn("Benching upstream API key",
+			zap.String("project_id", sel.projectID),
+			zap.String("upstream_key_id", sel.keyID),
+			zap.Int("status", res.StatusCode),
+			zap.Duration("bench", bench),
+		)
+	}
+}
+
+// prepareUpstreamRequest resolves the provider-specific parts of a request
+// (Azure deployment path, chat translation) into its context. A non-zero
+// status means the request cannot be served by this provider.
+func (p *TransparentProxy) prepareUpstreamRequest(r *http.Request) (*http.Request, int, ErrorResponse) {
+	if p.config.Mode == ProviderModeAzureOpenAI {
+		upstreamPath, ok := p.azureUpstreamPath(r)
+		if !ok {
+			return r, http.StatusBadRequest, ErrorResponse{
+				Error: "No Azure deployment configured for this request",
+				Code:  "deployment_not_found",
+			}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeyUpstreamPath, upstreamPath))
+	}
+
+	if isTranslatingMode(p.config.Mode) {
+		translation, err := p.prepareChatTranslation(r)
+		if err != nil {
+			return r, http.StatusBadRequest, ErrorResponse{
+				Error:       "Request cannot be translated for this provider",
+				Code:        "invalid_request",
+				Description: err.Error(),
+			}
+		}
+		if translation != nil {
+			r = r.WithContext(context.WithValue(r.Context(), ctxKeyChatTranslation, translation))
+		}
+	}
+	return r, 0, ErrorResponse{}
+}
+
+// providerName names the upstream in metrics, logs and placeholders: the
+// configured provider, or the target host when there is none.
+func (p *TransparentProxy) providerName() string {
+	if p.config.Provider != "" {
+		return p.config.Provider
+	}
+	return p.targetURL.Host
+}
+
+// isFallbackResponse reports whether req is a fallback attempt after the first.
+func isFallbackResponse(req *http.Request) bool {
+	attempt, ok := req.Context().Value(ctxKeyFallbackAttempt).(*fallbackAttempt)
+	return ok && attempt.index > 0
+}
+
 // Handler returns the HTTP handler for the proxy
 func (p *TransparentProxy) Handler() http.Handler {
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -761,7 +983,7 @@ func (p *TransparentProxy) Handler() http.Handler {
 				} else {
 					w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-Requested-With")
 				}
-				w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Proxy-ID, X-LLM-Proxy-Remote-Duration, X-LLM-Proxy-Remote-Duration-Ms")
+				w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Proxy-ID, X-Proxy-Provider, X-Proxy-Model-Route, X-Proxy-Model, X-Proxy-Fallback-Attempts, X-LLM-Proxy-Remote-Duration, X-LLM-Proxy-Remote-Duration-Ms, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 				w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 			}
 			w.WriteHeader(http.StatusNoContent)
@@ -782,6 +1004,20 @@ func (p *TransparentProxy) Handler() http.Handler {
 		receivedAt := time.Now().UTC()
 		ctx = context.WithValue(ctx, ctxKeyProxyReceivedAt, receivedAt)
 		r = r.WithContext(ctx)
+		ctx = context.WithValue(ctx, ctxKeyClientIP, p.resolveClientIP(r))
+		r = r.WithContext(ctx)
+
+		// Providers authenticated via query parameter (e.g. Gemini's ?key=) let
+		// clients pass the proxy token the same way; drop it from the URL early.
+		queryToken := p.config.UpstreamAuth.takeQueryCredential(r)
+
+		if p.config.Provider != "" {
+			modelRoute, _ := ctx.Value(ctxKeyModelRoute).(string)
+			middleware.AnnotateEvent(ctx, func(evt *eventbus.Event) {
+				evt.Provider = p.config.Provider
+				evt.ModelRoute = modelRoute
+			})
+		}
 
 		// Pre-check cache so we can avoid token usage tracking / upstream auth lookup
 		// on true cache hits. We still enforce auth and project status before serving.
@@ -815,8 +1051,7 @@ func (p *TransparentProxy) Handler() http.Handler {
 	skipPreCache:
 
 		// --- Token extraction and validation (moved from director) ---
-		authHeader := r.Header.Get("Authorization")
-		tokenStr := extractTokenFromHeader(authHeader)
+		tokenStr := p.config.UpstreamAuth.clientToken(r, queryToken)
 		if tokenStr == "" {
 			p.handleValidationError(w, r, errors.New("missing or invalid authorization header"))
 			return
@@ -835,23 +1070,32 @@ func (p *TransparentProxy) Handler() http.Handler {
 			p.handleValidationError(w, r, err)
 			return
 		}
+		keySelection := &upstreamKeySelection{projectID: projectID}
 		ctx = context.WithValue(r.Context(), ctxKeyProjectID, projectID)
 		ctx = context.WithValue(ctx, ctxKeyTokenID, tokenStr)
+		ctx = context.WithValue(ctx, ctxKeyUpstreamKey, keySelection)
 		r = r.WithContext(ctx)
+		r = p.withCostAccount(r, projectID)
 		// Defer upstream API key lookup until we actually need to proxy upstream.
 		// This keeps cache-hit latency low under concurrency.
 		var (
-			upstreamAPIKey     string
 			upstreamAPIKeyErr  error
 			upstreamAPIKeyOnce sync.Once
 		)
 		ensureUpstreamAuthorization := func(reqToAuthorize *http.Request) bool {
 			upstreamAPIKeyOnce.Do(func() {
-				upstreamAPIKey, upstreamAPIKeyErr = p.projectStore.GetAPIKeyForProject(reqToAuthorize.Context(), projectID)
-				if upstreamAPIKeyErr != nil {
-					upstreamAPIKeyErr = fmt.Errorf("failed to get API key: %w", upstreamAPIKeyErr)
+				selected, err := p.selectUpstreamKey(reqToAuthorize.Context(), projectID)
+				if err != nil {
+					upstreamAPIKeyErr = fmt.Errorf("failed to get API key: %w", err)
 					return
 				}
+				keySelection.key = selected.key
+				if selected.id != "" {
+					keySelection.keyID = selected.id
+					middleware.AnnotateEvent(reqToAuthorize.Context(), func(evt *eventbus.Event) {
+						evt.UpstreamKeyID = selected.id
+					})
+				}
 			})
 			if upstreamAPIKeyErr != nil {
 				requestID, _ := reqToAuthorize.Context().Value(ctxKeyRequestID).(string)
@@ -868,7 +1112,6 @@ func (p *TransparentProxy) Handler() http.Handler {
 				})
 				return false
 			}
-			reqToAuthorize.Header.Set("Authorization", fmt.Sprintf("Bearer %s", upstreamAPIKey))
 			return true
 		}
 
@@ -878,6 +1121,60 @@ func (p *TransparentProxy) Handler() http.Handler {
 			return
 		}
 
+		if status, er := p.enforceProjectClientRestrictions(r, projectID); status != 0 {
+			writeErrorResponseForRequest(w, r, status, er)
+			return
+		}
+
+		r, status, er := p.enforceTokenRestrictions(r, tokenStr, projectID)
+		if status != 0 {
+			writeErrorResponseForRequest(w, r, status, er)
+			return
+		}
+
+		r, status, er = p.enforceAllowedModels(r, projectID)
+		if status != 0 {
+			writeErrorResponseForRequest(w, r, status, er)
+			return
+		}
+
+		if status, er := p.enforceRequestPolicy(r, projectID); status != 0 {
+			writeErrorResponseForRequest(w, r, status, er)
+			return
+		}
+		// Cache keys must hash the body sent upstream, not the one the policy rewrote
+		if hash := r.Header.Get("X-Body-Hash"); hash != "" {
+			r.Header.Del("X-Body-Hash")
+			rehashed := prepareBodyHashForCaching(r, p.getMaxBodyHashBytes(), p.logger)
+			if preCacheOK && (!rehashed || r.Header.Get("X-Body-Hash") != hash) {
+				// The pre-check hit belongs to another body, so this request is not
+				// a cache hit after all and its token usage must be tracked
+				preCacheOK = false
+				if _, err := p.tokenValidator.ValidateTokenWithTracking(r.Context(), tokenStr); err != nil {
+					p.handleValidationError(w, r, err)
+					return
+				}
+			}
+		}
+
+		if status, er := p.enforceBudgets(w, r, projectID); status != 0 {
+			writeErrorResponseForRequest(w, r, status, er)
+			return
+		}
+
+		// Counted last, so requests rejected by the checks above keep their quota
+		r, status, er = p.enforceRateLimits(w, r, projectID)
+		if status != 0 {
+			writeErrorResponseForRequest(w, r, status, er)
+			return
+		}
+
+		r, status, er = p.prepareUpstreamRequest(r)
+		if status != 0 {
+			writeErrorResponseForRequest(w, r, status, er)
+			return
+		}
+
 		// Wrap the ResponseWriter to allow us to set headers at first/last byte
 		rw := &timingResponseWriter{ResponseWriter: w}
 
@@ -902,7 +1199,7 @@ func (p *TransparentProxy) Handler() http.Handler {
 				if !ensureUpstreamAuthorization(r) {
 					return
 				}
-				p.proxy.ServeHTTP(rw, r)
+				p.serveUpstream(rw, r)
 				return
 			}
 			key := CacheKeyFromRequest(r)
@@ -925,7 +1222,7 @@ func (p *TransparentProxy) Handler() http.Handler {
 						return
 					}
 					// Note: don't set miss status here; let modifyResponse handle cache status
-					p.proxy.ServeHTTP(rw, r)
+					p.serveUpstream(rw, r)
 					return
 				}
 
@@ -938,7 +1235,7 @@ func (p *TransparentProxy) Handler() http.Handler {
 					if !ensureUpstreamAuthorization(r) {
 						return
 					}
-					p.proxy.ServeHTTP(rw, r)
+					p.serveUpstream(rw, r)
 					return
 				}
 				// Origin revalidation path: if client requests revalidation (no-cache/max-age=0),
@@ -956,7 +1253,7 @@ func (p *TransparentProxy) Handler() http.Handler {
 					}
 					// Forward conditionally to upstream; let modifyResponse handle store/refresh
 					// Don't increment miss here since this is a conditional revalidation
-					p.proxy.ServeHTTP(rw, condReq)
+					p.serveUpstream(rw, condReq)
 					return
 				}
 				// If the client provided conditionals, respond 304 when validators match
@@ -969,6 +1266,7 @@ func (p *TransparentProxy) Handler() http.Handler {
 						}
 						// Set fresh timing headers for conditional cache hit
 						setFreshCacheTimingHeaders(w, time.Now())
+						setRateLimitHeaders(r.Context(), w.Header())
 						w.Header().Set("Cache-Status", "llm-proxy; conditional-hit")
 						w.Header().Set("X-PROXY-CACHE", "conditional-hit")
 						w.Header().Set("X-PROXY-CACHE-KEY", key)
@@ -984,13 +1282,18 @@ func (p *TransparentProxy) Handler() http.Handler {
 				}
 				// Set fresh timing headers for cache hit
 				setFreshCacheTimingHeaders(w, time.Now())
+				setRateLimitHeaders(r.Context(), w.Header())
 				w.Header().Set("Cache-Status", "llm-proxy; hit")
 				w.Header().Set("X-PROXY-CACHE", "hit")
 				w.Header().Set("X-PROXY-CACHE-KEY", key)
 				p.recordCacheHit(r)
+				body := cr.body
+				if p.transforms != nil {
+					body = p.transformCachedResponse(r, w.Header(), cr.statusCode, body)
+				}
 				w.WriteHeader(cr.statusCode)
 				if r.Method != http.MethodHead {
-					_, _ = w.Write(cr.body)
+					_, _ = w.Write(body)
 				}
 				return
 			}
@@ -1006,7 +1309,7 @@ func (p *TransparentProxy) Handler() http.Handler {
 		if !ensureUpstreamAuthorization(r) {
 			return
 		}
-		p.proxy.ServeHTTP(rw, r)
+		p.serveUpstream(rw, r)
 	})
 
 	var handler http.Handler = baseHandler
@@ -1015,10 +1318,6 @@ func (p *TransparentProxy) Handler() http.Handler {
 	if p.obsMiddleware != nil {
 		handler = p.obsMiddleware.Middleware()(handler)
 	}
-	handler = CircuitBreakerMiddleware(5, 30*time.Second, func(status int) bool {
-		return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
-	})(handler)
-
 	return handler
 }
 
@@ -1054,9 +1353,10 @@ func (p *TransparentProxy) recordCacheMiss() {
 // recordCacheHit records a cache hit for metrics and per-token tracking.
 func (p *TransparentProxy) recordCacheHit(r *http.Request) {
 	p.incrementCacheMetric(CacheMetricHit)
-	// Record per-token cache hit if aggregator is configured
+	// Record per-token cache hit if aggregator is configured. Child tokens
+	// have no stored row to count on.
 	if p.cacheStatsAggregator != nil {
-		if tokenID, ok := r.Context().Value(ctxKeyTokenID).(string); ok && tokenID != "" {
+		if tokenID, ok := r.Context().Value(ctxKeyTokenID).(string); ok && tokenID != "" && !token.IsChildToken(tokenID) {
 			p.cacheStatsAggregator.RecordCacheHit(tokenID)
 		}
 	}
@@ -1147,6 +1447,45 @@ func (p *TransparentProxy) LoggingMiddleware() Middleware {
 	}
 }
 
+// checkParamWhitelist rejects JSON POST bodies whose parameters do not match
+// the provider's param_whitelist patterns.
+func (p *TransparentProxy) checkParamWhitelist(r *http.Request) (int, ErrorResponse) {
+	if r.Method != http.MethodPost || len(p.config.ParamWhitelist) == 0 || r.Header.Get("Content-Type") != "application/json" {
+		return 0, ErrorResponse{}
+	}
+	// Read and buffer the body for validation and later proxying
+	bodyBytes, _, _ := bufferRequestBody(r, 0)
+	if len(bodyBytes) == 0 {
+		return 0, ErrorResponse{}
+	}
+	var bodyMap map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &bodyMap); err != nil {
+		return 0, ErrorResponse{}
+	}
+	for param, allowed := range p.config.ParamWhitelist {
+		val, ok := bodyMap[param]
+		if !ok {
+			continue
+		}
+		valStr := fmt.Sprintf("%v", val)
+		found := false
+		// Support glob expressions in allowed values
+		for _, allowedVal := range allowed {
+			if ok, _ := path.Match(allowedVal, valStr); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return http.StatusBadRequest, ErrorResponse{
+				Error: fmt.Sprintf("Parameter '%s' value '%s' is not allowed. Allowed patterns: %v", param, valStr, allowed),
+				Code:  "param_not_allowed",
+			}
+		}
+	}
+	return 0, ErrorResponse{}
+}
+
 // ValidateRequestMiddleware validates the incoming request against allowed endpoints and methods
 func (p *TransparentProxy) ValidateRequestMiddleware() Middleware {
 	return func(next http.Handler) http.Handler {
@@ -1197,47 +1536,9 @@ func (p *TransparentProxy) ValidateRequestMiddleware() Middleware {
 			// --- End of validation scope ---
 
 			// --- Begin param whitelist validation ---
-			if r.Method == http.MethodPost && len(p.config.ParamWhitelist) > 0 && r.Header.Get("Content-Type") == "application/json" {
-				// Read and buffer the body for validation and later proxying
-				var bodyBytes []byte
-				if r.Body != nil {
-					bodyBytes, _ = io.ReadAll(r.Body)
-				}
-				if len(bodyBytes) > 0 {
-					var bodyMap map[string]interface{}
-					if err := json.Unmarshal(bodyBytes, &bodyMap); err == nil {
-						for param, allowed := range p.config.ParamWhitelist {
-							if val, ok := bodyMap[param]; ok {
-								valStr := ""
-								switch v := val.(type) {
-								case string:
-									valStr = v
-								case float64:
-									valStr = fmt.Sprintf("%v", v)
-								default:
-									valStr = fmt.Sprintf("%v", v)
-								}
-								found := false
-								// Support glob expressions in allowed values
-								for _, allowedVal := range allowed {
-									if ok, _ := path.Match(allowedVal, valStr); ok {
-										found = true
-										break
-									}
-								}
-								if !found {
-									writeErrorResponseForRequest(w, r, http.StatusBadRequest, ErrorResponse{
-										Error: fmt.Sprintf("Parameter '%s' value '%s' is not allowed. Allowed patterns: %v", param, valStr, allowed),
-										Code:  "param_not_allowed",
-									})
-									return
-								}
-							}
-						}
-					}
-					// Restore the body for downstream handlers
-					r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-				}
+			if status, er := p.checkParamWhitelist(r); status != 0 {
+				writeErrorResponseForRequest(w, r, status, er)
+				return
 			}
 			// --- End param whitelist validation ---
 
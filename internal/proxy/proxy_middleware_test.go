@@ -343,54 +343,3 @@ func TestChainMiddleware(t *testing.T) {
 		"middleware1-after",
 	}, calls)
 }
-
-// --- CIRCUIT BREAKER MIDDLEWARE TESTS ---
-func TestCircuitBreakerMiddleware_OpensOnFailures(t *testing.T) {
-	var callCount int
-	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		callCount++
-		w.WriteHeader(http.StatusBadGateway) // 502
-	})
-	cb := CircuitBreakerMiddleware(2, 10*time.Millisecond, func(status int) bool {
-		return status == http.StatusBadGateway
-	})
-	for i := 0; i < 3; i++ {
-		rec := httptest.NewRecorder()
-		cb(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
-	}
-	// After 2 failures, circuit should open and return 503
-	rec := httptest.NewRecorder()
-	cb(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
-	if rec.Code != http.StatusServiceUnavailable {
-		t.Errorf("expected 503 from open circuit, got %d", rec.Code)
-	}
-}
-
-func TestCircuitBreakerMiddleware_ClosesOnSuccess(t *testing.T) {
-	var callCount int
-	fail := true
-	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		callCount++
-		if fail {
-			w.WriteHeader(http.StatusBadGateway)
-		} else {
-			w.WriteHeader(http.StatusOK)
-		}
-	})
-	cb := CircuitBreakerMiddleware(2, 10*time.Millisecond, func(status int) bool {
-		return status == http.StatusBadGateway
-	})
-	// Trip the circuit
-	for i := 0; i < 3; i++ {
-		rec := httptest.NewRecorder()
-		cb(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
-	}
-	// Wait for cooldown
-	time.Sleep(15 * time.Millisecond)
-	fail = false
-	rec := httptest.NewRecorder()
-	cb(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
-	if rec.Code != http.StatusOK {
-		t.Errorf("expected 200 after circuit closes, got %d", rec.Code)
-	}
-}
@@ -44,7 +44,7 @@ type MockProjectStore struct {
 	mock.Mock
 }
 
-func (m *MockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (m *MockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	args := m.Called(ctx, projectID)
 	return args.String(0), args.Error(1)
 }
@@ -1232,21 +1232,21 @@ func TestTransparentProxy_CircuitBreaker_ClosesOnRecovery(t *testing.T) {
 	}))
 	defer server.Close()
 
+	// Use a short cooldown for test speed
+	cooldown := 100 * time.Millisecond
+
 	p := newTestProxyWithConfig(ProxyConfig{
 		TargetBaseURL:    server.URL,
 		AllowedEndpoints: []string{"/test"},
 		AllowedMethods:   []string{"GET"},
+		CircuitBreaker:   CircuitBreakerConfig{Cooldown: cooldown},
 	})
 
 	handler := p.Handler()
 
-	// Use a short cooldown for test speed
-	cooldown := 100 * time.Millisecond
-
 	// Trip the circuit breaker (threshold is 5)
 	for i := 0; i < 5; i++ {
 		req := httptest.NewRequest("GET", "/test", nil)
-		req = req.WithContext(context.WithValue(req.Context(), cbCooldownOverrideKey, cooldown))
 		req.Header.Set("Authorization", "Bearer valid-token")
 		w := httptest.NewRecorder()
 		handler.ServeHTTP(w, req)
@@ -1254,7 +1254,6 @@ func TestTransparentProxy_CircuitBreaker_ClosesOnRecovery(t *testing.T) {
 
 	// Next request should get 503 from circuit breaker
 	req := httptest.NewRequest("GET", "/test", nil)
-	req = req.WithContext(context.WithValue(req.Context(), cbCooldownOverrideKey, cooldown))
 	req.Header.Set("Authorization", "Bearer valid-token")
 	w := httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
@@ -1273,7 +1272,6 @@ func TestTransparentProxy_CircuitBreaker_ClosesOnRecovery(t *testing.T) {
 
 	// Now the circuit breaker should close and allow traffic again
 	req = httptest.NewRequest("GET", "/test", nil)
-	req = req.WithContext(context.WithValue(req.Context(), cbCooldownOverrideKey, cooldown))
 	req.Header.Set("Authorization", "Bearer valid-token")
 	w = httptest.NewRecorder()
 	handler.ServeHTTP(w, req)
@@ -1356,6 +1354,69 @@ func newTestProxyWithConfig(cfg ProxyConfig) *TransparentProxy {
 	return p
 }
 
+// testProxyDeps holds what newTestProxy builds a proxy from.
+type testProxyDeps struct {
+	config    ProxyConfig
+	validator TokenValidator
+	store     ProjectStore
+	audit     AuditLogger
+	obs       middleware.ObservabilityConfig
+}
+
+// testProxyOption customizes a proxy built by newTestProxy.
+type testProxyOption func(*testProxyDeps)
+
+// withConfig applies feature-specific settings to the proxy config.
+func withConfig(fn func(*ProxyConfig)) testProxyOption {
+	return func(d *testProxyDeps) { fn(&d.config) }
+}
+
+func withTokenValidator(v TokenValidator) testProxyOption {
+	return func(d *testProxyDeps) { d.validator = v }
+}
+
+func withProjectStore(s ProjectStore) testProxyOption {
+	return func(d *testProxyDeps) { d.store = s }
+}
+
+func withAuditLogger(a AuditLogger) testProxyOption {
+	return func(d *testProxyDeps) { d.audit = a }
+}
+
+func withObservability(cfg middleware.ObservabilityConfig) testProxyOption {
+	return func(d *testProxyDeps) { d.obs = cfg }
+}
+
+// startUpstream serves handler as a test upstream and returns its URL.
+func startUpstream(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+	return upstream.URL
+}
+
+// newTestProxy creates an openai TransparentProxy for upstreamURL that serves
+// GET and POST under /v1/ with stub dependencies, adjusted by opts.
+func newTestProxy(t *testing.T, upstreamURL string, opts ...testProxyOption) *TransparentProxy {
+	t.Helper()
+	d := testProxyDeps{
+		config: ProxyConfig{
+			TargetBaseURL:    upstreamURL,
+			Provider:         "openai",
+			AllowedEndpoints: []string{"/v1/"},
+			AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		},
+		validator: &stubTokenValidator{},
+		store:     &stubProjectStore{},
+	}
+	for _, opt := range opts {
+		opt(&d)
+	}
+	p, err := NewTransparentProxyWithAudit(d.config, d.validator, d.store, zap.NewNop(), d.audit, d.obs)
+	require.NoError(t, err)
+	return p
+}
+
 type stubTokenValidator struct{}
 
 func (s *stubTokenValidator) ValidateTokenWithTracking(ctx context.Context, token string) (string, error) {
@@ -1367,7 +1428,7 @@ func (s *stubTokenValidator) ValidateToken(ctx context.Context, token string) (s
 
 type stubProjectStore struct{}
 
-func (s *stubProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (s *stubProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	return "api-key", nil
 }
 
@@ -1998,3 +2059,55 @@ func TestTransparentProxyWithAudit_InactiveProject(t *testing.T) {
 	validator.AssertExpectations(t)
 	store.AssertExpectations(t)
 }
+
+// providerKeyStore serves per-provider keys and a legacy key per project.
+type providerKeyStore struct {
+	stubProjectStore
+	legacy string
+	keys   map[string]string
+}
+
+func (s *providerKeyStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
+	if provider == "" {
+		return s.legacy, nil
+	}
+	if k, ok := s.keys[provider]; ok {
+		return k, nil
+	}
+	return "", ErrProviderAPIKeyNotFound
+}
+
+func TestTransparentProxy_UpstreamAPIKey_ProviderSelection(t *testing.T) {
+	store := &providerKeyStore{legacy: "sk-legacy", keys: map[string]string{"anthropic": "sk-ant"}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		provider     string
+		fallback     bool
+		wantKey      string
+		wantProvider string
+		wantErr      error
+	}{
+		{name: "no provider uses legacy key", provider: "", wantKey: "sk-legacy"},
+		{name: "provider key", provider: "anthropic", wantKey: "sk-ant", wantProvider: "anthropic"},
+		{name: "default provider falls back to legacy key", provider: "openai", fallback: true, wantKey: "sk-legacy"},
+		{name: "non-default provider never uses legacy key", provider: "gemini", wantErr: ErrProviderAPIKeyNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &TransparentProxy{
+				config:       ProxyConfig{Provider: tt.provider, ProviderKeyFallback: tt.fallback},
+				projectStore: store,
+			}
+			key, provider, err := p.upstreamAPIKey(ctx, "p1")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKey, key)
+			assert.Equal(t, tt.wantProvider, provider)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"go.uber.org/zap"
+)
+
+// RateLimitStore is implemented by project stores that keep per-project
+// request rate limits. Stores without it leave projects unlimited.
+type RateLimitStore interface {
+	// GetRateLimitForProject returns the project's rate limit
+	GetRateLimitForProject(ctx context.Context, projectID string) (token.RateLimit, error)
+}
+
+// GetRateLimitForProject returns the rate limit of a project from store, or
+// no limit when store does not keep them.
+func GetRateLimitForProject(ctx context.Context, store ProjectStore, projectID string) (token.RateLimit, error) {
+	if rs, ok := store.(RateLimitStore); ok {
+		return rs.GetRateLimitForProject(ctx, projectID)
+	}
+	return token.RateLimit{}, nil
+}
+
+// tokenRateLimit is the rate limit of the request's token. Child tokens are
+// counted under the stored token they were derived from.
+type tokenRateLimit struct {
+	tokenID string
+	limit   token.RateLimit
+}
+
+// withTokenRateLimit stores the rate limit of td in the request context.
+func withTokenRateLimit(r *http.Request, td token.TokenData) *http.Request {
+	tokenID := td.ID
+	if td.ParentID != "" {
+		tokenID = td.ParentID
+	}
+	if tokenID == "" {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), ctxKeyTokenRateLimit, tokenRateLimit{tokenID: tokenID, limit: td.RateLimit}))
+}
+
+// SetRateLimiter sets the limiter that enforces per-project and per-token
+// request rate limits.
+func (p *TransparentProxy) SetRateLimiter(l *token.RedisRateLimiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rateLimiter = l
+}
+
+// enforceRateLimits counts the request against the rate limits of its token
+// and project. The stored limits are passed to the limiter on every request,
+// which only updates them when they changed, so changes apply without a
+// restart and every replica enforces the same limits. Tokens without their
+// own limit get the limiter's default. The token is checked first, so a token
+// over its own limit does not use up its project's quota with rejected
+// retries. The state of each limit is stored in the returned request for the
+// X-RateLimit-* headers.
+func (p *TransparentProxy) enforceRateLimits(w http.ResponseWriter, r *http.Request, projectID string) (*http.Request, int, ErrorResponse) {
+	if p.rateLimiter == nil {
+		return r, 0, ErrorResponse{}
+	}
+	ctx := r.Context()
+
+	limit, err := GetRateLimitForProject(ctx, p.projectStore, projectID)
+	if err != nil {
+		p.logger.Error("Failed to load project rate limit", zap.String("project_id", projectID), zap.Error(err))
+		return r, http.StatusServiceUnavailable, ErrorResponse{Error: "Rate limit unavailable", Code: "rate_limit_unavailable"}
+	}
+
+	if tl, ok := ctx.Value(ctxKeyTokenRateLimit).(tokenRateLimit); ok {
+		if tl.limit.IsZero() {
+			p.rateLimiter.RemoveTokenLimit(tl.tokenID)
+		} else {
+			p.rateLimiter.SetTokenRateLimit(tl.tokenID, tl.limit.TokenRateLimit())
+		}
+		retryAfter, rateStatus, err := p.rateLimiter.CheckTokenStatus(ctx, tl.tokenID)
+		r = withRateLimitStatus(r, rateStatus)
+		if status, er := p.rateLimitResult(w, r, projectID, "token", retryAfter, err); status != 0 {
+			return r, status, er
+		}
+	}
+
+	if limit.IsZero() {
+		p.rateLimiter.RemoveProjectLimit(projectID)
+	} else {
+		p.rateLimiter.SetProjectLimit(projectID, limit.TokenRateLimit())
+	}
+	retryAfter, rateStatus, err := p.rateLimiter.CheckProjectStatus(ctx, projectID)
+	r = withRateLimitStatus(r, rateStatus)
+	status, er := p.rateLimitResult(w, r, projectID, "project", retryAfter, err)
+	return r, status, er
+}
+
+// rateLimitResult turns the outcome of a rate limit check into an error
+// response, setting Retry-After and the X-RateLimit-* headers when the limit
+// is exhausted.
+func (p *TransparentProxy) rateLimitResult(w http.ResponseWriter, r *http.Request, projectID, scope string, retryAfter time.Duration, err error) (int, ErrorResponse) {
+	if err != nil {
+		p.logger.Error("Failed to check rate limit", zap.String("project_id", projectID), zap.String("scope", scope), zap.Error(err))
+		return http.StatusServiceUnavailable, ErrorResponse{Error: "Rate limit unavailable", Code: "rate_limit_unavailable"}
+	}
+	if retryAfter <= 0 {
+		return 0, ErrorResponse{}
+	}
+	p.logger.Info("Rate limit exceeded", zap.String("project_id", projectID), zap.String("scope", scope))
+	setRateLimitHeaders(r.Context(), w.Header())
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(retryAfter.Seconds())), 1)))
+	return http.StatusTooManyRequests, ErrorResponse{
+		Error:       "Rate limit exceeded",
+		Code:        "rate_limit_exceeded",
+		Description: "request rate limit of the " + scope + " exhausted",
+	}
+}
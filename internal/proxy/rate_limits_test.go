@@ -0,0 +1,189 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rateLimitedTokenValidator is a stubTokenValidator whose tokens carry a rate limit.
+type rateLimitedTokenValidator struct {
+	stubTokenValidator
+	limit token.RateLimit
+}
+
+func (v *rateLimitedTokenValidator) GetTokenData(ctx context.Context, tokenString string) (token.TokenData, error) {
+	return token.TokenData{ID: "tok-id", Token: tokenString, ProjectID: "test-project-id", IsActive: true, RateLimit: v.limit}, nil
+}
+
+// rateLimitProjectStore is a stubProjectStore with a project rate limit.
+type rateLimitProjectStore struct {
+	stubProjectStore
+	limit token.RateLimit
+	err   error
+}
+
+func (s *rateLimitProjectStore) GetRateLimitForProject(ctx context.Context, projectID string) (token.RateLimit, error) {
+	return s.limit, s.err
+}
+
+func TestTransparentProxy_EnforcesRateLimits(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name         string
+		tokenLimit   token.RateLimit
+		projectLimit token.RateLimit
+		allowed      int
+		wantScope    string
+	}{
+		{name: "unlimited", allowed: 5},
+		{name: "token limit", tokenLimit: token.RateLimit{RequestsPerWindow: 2, WindowSeconds: 3600}, allowed: 2, wantScope: "token"},
+		{name: "project limit", tokenLimit: token.RateLimit{RequestsPerWindow: 5, WindowSeconds: 3600}, projectLimit: token.RateLimit{RequestsPerWindow: 1, WindowSeconds: 3600}, allowed: 1, wantScope: "project"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := token.NewRedisRateLimiter(token.NewMemoryRateLimitClient(), token.RedisRateLimiterConfig{DefaultWindowDuration: time.Minute})
+			p := newTestProxy(t, upstream.URL, withTokenValidator(&rateLimitedTokenValidator{limit: tt.tokenLimit}), withProjectStore(&rateLimitProjectStore{limit: tt.projectLimit}))
+			p.SetRateLimiter(limiter)
+			h := p.Handler()
+
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o-mini"}`))
+				req.Header.Set("Authorization", "Bearer tok")
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+				return w
+			}
+			for i := 0; i < tt.allowed; i++ {
+				require.Equal(t, http.StatusOK, send().Code, "request %d", i+1)
+			}
+			if tt.wantScope == "" {
+				return
+			}
+
+			w := send()
+			require.Equal(t, http.StatusTooManyRequests, w.Code)
+			var resp ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "rate_limit_exceeded", resp.Code)
+			assert.Contains(t, resp.Description, tt.wantScope)
+			assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+// rateLimitModelsProjectStore is a project store with both a rate limit and a model allowlist.
+type rateLimitModelsProjectStore struct {
+	modelsProjectStore
+	limit token.RateLimit
+}
+
+func (s *rateLimitModelsProjectStore) GetRateLimitForProject(ctx context.Context, projectID string) (token.RateLimit, error) {
+	return s.limit, nil
+}
+
+func TestTransparentProxy_RejectedRequestsKeepRateLimitQuota(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	limiter := token.NewRedisRateLimiter(token.NewMemoryRateLimitClient(), token.RedisRateLimiterConfig{DefaultWindowDuration: time.Minute})
+	store := &rateLimitModelsProjectStore{
+		modelsProjectStore: modelsProjectStore{models: []string{"gpt-4o-mini"}},
+		limit:              token.RateLimit{RequestsPerWindow: 1, WindowSeconds: 3600},
+	}
+	p := newTestProxy(t, upstream.URL, withTokenValidator(&rateLimitedTokenValidator{}), withProjectStore(store))
+	p.SetRateLimiter(limiter)
+	h := p.Handler()
+
+	// Requests for a model outside the allowlist are rejected before they are counted
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusForbidden, sendChat(h, "gpt-4o", nil).Code)
+	}
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendChat(h, "gpt-4o-mini", nil).Code)
+}
+
+func TestTransparentProxy_TokenRateLimitRejectionsKeepProjectQuota(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	limiter := token.NewRedisRateLimiter(token.NewMemoryRateLimitClient(), token.RedisRateLimiterConfig{DefaultWindowDuration: time.Minute})
+	validator := &rateLimitedTokenValidator{limit: token.RateLimit{RequestsPerWindow: 2, WindowSeconds: 3600}}
+	store := &rateLimitProjectStore{limit: token.RateLimit{RequestsPerWindow: 3, WindowSeconds: 3600}}
+	p := newTestProxy(t, upstream.URL, withTokenValidator(validator), withProjectStore(store))
+	p.SetRateLimiter(limiter)
+	h := p.Handler()
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+	}
+	for i := 0; i < 3; i++ {
+		w := sendChat(h, "gpt-4o-mini", nil)
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Contains(t, w.Body.String(), "token exhausted")
+	}
+
+	// The token's rejected requests were not counted against the project
+	validator.limit = token.RateLimit{RequestsPerWindow: 10, WindowSeconds: 3600}
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+	w := sendChat(h, "gpt-4o-mini", nil)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "project exhausted")
+}
+
+func TestTransparentProxy_RateLimitStoreError(t *testing.T) {
+	limiter := token.NewRedisRateLimiter(token.NewMemoryRateLimitClient(), token.RedisRateLimiterConfig{DefaultWindowDuration: time.Minute})
+	p := newTestProxy(t, "http://127.0.0.1:0", withTokenValidator(&rateLimitedTokenValidator{}), withProjectStore(&rateLimitProjectStore{err: errors.New("db down")}))
+	p.SetRateLimiter(limiter)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "rate_limit_unavailable", resp.Code)
+}
+
+func TestCachedProjectActiveStore_GetRateLimitForProject(t *testing.T) {
+	underlying := &rateLimitProjectStore{limit: token.RateLimit{RequestsPerWindow: 10, WindowSeconds: 60}}
+	store := NewCachedProjectActiveStore(underlying, CachedProjectActiveStoreConfig{TTL: time.Minute})
+	ctx := context.Background()
+
+	got, err := store.GetRateLimitForProject(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, underlying.limit, got)
+
+	underlying.limit = token.RateLimit{}
+	got, err = store.GetRateLimitForProject(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, 10, got.RequestsPerWindow, "served from cache")
+
+	require.NoError(t, store.UpdateProject(ctx, Project{ID: "p1"}))
+	got, err = store.GetRateLimitForProject(ctx, "p1")
+	require.NoError(t, err)
+	assert.True(t, got.IsZero(), "updates purge the cache")
+}
@@ -0,0 +1,295 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/logging"
+	"go.uber.org/zap"
+)
+
+// RequestPolicyStore is implemented by project stores that keep per-project
+// request policies. Stores without it have no project policies.
+type RequestPolicyStore interface {
+	// GetRequestPolicyForProject returns the project's request policy rules (nil if none)
+	GetRequestPolicyForProject(ctx context.Context, projectID string) ([]RequestPolicyRule, error)
+}
+
+// GetRequestPolicyForProject returns the request policy of a project from
+// store, or nil when store does not keep request policies.
+func GetRequestPolicyForProject(ctx context.Context, store ProjectStore, projectID string) ([]RequestPolicyRule, error) {
+	if ps, ok := store.(RequestPolicyStore); ok {
+		return ps.GetRequestPolicyForProject(ctx, projectID)
+	}
+	return nil, nil
+}
+
+// ValidateRequestPolicy checks request policy rules and reports the first problem.
+func ValidateRequestPolicy(rules []RequestPolicyRule) error {
+	_, err := compileRequestPolicy(rules)
+	return err
+}
+
+// requestPolicy is a compiled list of request policy rules.
+type requestPolicy []policyRule
+
+type policyRule struct {
+	RequestPolicyRule
+	path []pathSegment
+}
+
+func compileRequestPolicy(rules []RequestPolicyRule) (requestPolicy, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	out := make(requestPolicy, 0, len(rules))
+	for i, rule := range rules {
+		segs, err := parseJSONPath(rule.Path)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if err := validatePolicyRule(rule, segs); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, rule.Path, err)
+		}
+		out = append(out, policyRule{RequestPolicyRule: rule, path: segs})
+	}
+	return out, nil
+}
+
+func validatePolicyRule(rule RequestPolicyRule, segs []pathSegment) error {
+	checks := rule.Min != nil || rule.Max != nil || len(rule.Allowed) > 0
+	switch {
+	case !rule.Forbidden && !checks && rule.Default == nil && rule.Set == nil:
+		return errors.New("rule has no check or value")
+	case rule.Forbidden && (checks || rule.Default != nil || rule.Set != nil):
+		return errors.New("forbidden cannot be combined with other settings")
+	case rule.Set != nil && (checks || rule.Default != nil):
+		return errors.New("set cannot be combined with checks or a default")
+	case rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max:
+		return errors.New("min is greater than max")
+	}
+	if rule.Default != nil {
+		for _, seg := range segs {
+			if seg.wildcard {
+				return errors.New("default cannot be used with wildcards")
+			}
+		}
+	}
+	for _, pattern := range rule.Allowed {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("allowed pattern '%s' is invalid: %w", pattern, err)
+		}
+	}
+	for _, endpoint := range rule.Endpoints {
+		if !strings.HasPrefix(endpoint, "/") {
+			return fmt.Errorf("endpoint '%s' must start with /", endpoint)
+		}
+	}
+	return nil
+}
+
+// policyViolation is a request rejected by a policy rule.
+type policyViolation struct {
+	rule    RequestPolicyRule
+	message string
+}
+
+// apply enforces the policy on a decoded JSON body sent to endpoint. It returns
+// the body, whether it was rewritten and the first violation found.
+func (rp requestPolicy) apply(doc any, endpoint string, vars *strings.Replacer) (any, bool, *policyViolation) {
+	changed := false
+	for _, rule := range rp {
+		if !rule.appliesTo(endpoint) {
+			continue
+		}
+		present := false
+		for _, v := range lookupPath(doc, rule.path) {
+			if v == nil {
+				continue
+			}
+			present = true
+			if msg := rule.check(v); msg != "" {
+				return doc, changed, &policyViolation{rule: rule.RequestPolicyRule, message: rule.field() + " " + msg}
+			}
+		}
+		switch {
+		case rule.Set != nil:
+			doc = setPath(doc, rule.path, func() any { return expandValue(rule.Set, vars) })
+			changed = true
+		case rule.Default != nil && !present:
+			doc = setPath(doc, rule.path, func() any { return expandValue(rule.Default, vars) })
+			changed = true
+		}
+	}
+	return doc, changed, nil
+}
+
+func (r policyRule) appliesTo(endpoint string) bool {
+	if len(r.Endpoints) == 0 {
+		return true
+	}
+	for _, prefix := range r.Endpoints {
+		if strings.HasPrefix(endpoint, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// field is the rule's path as shown in error messages.
+func (r policyRule) field() string {
+	return strings.TrimPrefix(strings.TrimPrefix(r.Path, "$"), ".")
+}
+
+// check returns why v violates the rule, or "" if it does not.
+func (r policyRule) check(v any) string {
+	if r.Forbidden {
+		return "is not allowed"
+	}
+	if r.Min != nil || r.Max != nil {
+		n, ok := v.(json.Number)
+		if !ok {
+			return "must be a number"
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return "must be a number"
+		}
+		if r.Min != nil && f < *r.Min {
+			return "must be at least " + strconv.FormatFloat(*r.Min, 'f', -1, 64)
+		}
+		if r.Max != nil && f > *r.Max {
+			return "must be at most " + strconv.FormatFloat(*r.Max, 'f', -1, 64)
+		}
+	}
+	if len(r.Allowed) > 0 {
+		var s string
+		switch val := v.(type) {
+		case string:
+			s = val
+		case json.Number:
+			s = val.String()
+		case bool:
+			s = strconv.FormatBool(val)
+		default:
+			return "must be one of " + strings.Join(r.Allowed, ", ")
+		}
+		for _, pattern := range r.Allowed {
+			if ok, _ := path.Match(pattern, s); ok {
+				return ""
+			}
+		}
+		return fmt.Sprintf("value '%s' is not allowed (allowed: %s)", s, strings.Join(r.Allowed, ", "))
+	}
+	return ""
+}
+
+// enforceRequestPolicy applies the project and provider request policies to a
+// JSON request body, in that order. It returns a non-zero status when the
+// request must be rejected.
+func (p *TransparentProxy) enforceRequestPolicy(r *http.Request, projectID string) (int, ErrorResponse) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return 0, ErrorResponse{}
+	}
+	rules, err := GetRequestPolicyForProject(r.Context(), p.projectStore, projectID)
+	if err != nil {
+		p.logger.Error("Failed to load project request policy", zap.String("project_id", projectID), zap.Error(err))
+		return http.StatusServiceUnavailable, ErrorResponse{Error: "Request policy unavailable", Code: "policy_unavailable"}
+	}
+	if len(rules) == 0 && len(p.requestPolicy) == 0 {
+		return 0, ErrorResponse{}
+	}
+	projectPolicy, err := compileRequestPolicy(rules)
+	if err != nil {
+		p.logger.Error("Invalid project request policy", zap.String("project_id", projectID), zap.Error(err))
+		return http.StatusServiceUnavailable, ErrorResponse{Error: "Request policy unavailable", Code: "policy_unavailable"}
+	}
+
+	body, _, err := bufferRequestBody(r, 0)
+	if err != nil {
+		return http.StatusBadRequest, ErrorResponse{Error: "Failed to read request body", Code: "invalid_request"}
+	}
+	// Only JSON objects are subject to policies, whatever the Content-Type says
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if dec.Decode(&doc) != nil {
+		return 0, ErrorResponse{}
+	}
+	if _, ok := doc.(map[string]any); !ok {
+		return 0, ErrorResponse{}
+	}
+
+	endpoint, ok := r.Context().Value(ctxKeyOriginalPath).(string)
+	if !ok {
+		endpoint = r.URL.Path
+	}
+	vars := placeholderVars(r, p.providerName())
+	rewritten := false
+	for _, policy := range []struct {
+		source string
+		rules  requestPolicy
+	}{{"project", projectPolicy}, {"provider", p.requestPolicy}} {
+		var changed bool
+		var violation *policyViolation
+		doc, changed, violation = policy.rules.apply(doc, endpoint, vars)
+		rewritten = rewritten || changed
+		if violation != nil {
+			p.recordPolicyViolation(r, projectID, endpoint, policy.source, violation)
+			return http.StatusBadRequest, ErrorResponse{
+				Error:       "Request violates policy",
+				Code:        "policy_violation",
+				Description: violation.message,
+			}
+		}
+	}
+
+	if rewritten {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(doc); err != nil {
+			return http.StatusInternalServerError, ErrorResponse{Error: "Failed to apply request policy", Code: "internal_error"}
+		}
+		setRequestBody(r, bytes.TrimRight(buf.Bytes(), "\n"))
+	}
+	return 0, ErrorResponse{}
+}
+
+// recordPolicyViolation logs and audits a request rejected by a policy rule.
+func (p *TransparentProxy) recordPolicyViolation(r *http.Request, projectID, endpoint, source string, v *policyViolation) {
+	requestID, _ := logging.GetRequestID(r.Context())
+	p.logger.Warn("Request rejected by policy",
+		zap.String("request_id", requestID),
+		zap.String("project_id", projectID),
+		zap.String("policy", source),
+		zap.String("path", v.rule.Path),
+		zap.String("violation", v.message),
+	)
+	if p.auditLogger == nil {
+		return
+	}
+	auditEvent := audit.NewEvent(audit.ActionProxyPolicyViolation, audit.ActorSystem, audit.ResultDenied).
+		WithProjectID(projectID).
+		WithRequestID(requestID).
+		WithClientIP(getClientIP(r)).
+		WithUserAgent(r.UserAgent()).
+		WithHTTPMethod(r.Method).
+		WithEndpoint(endpoint).
+		WithReason("policy_violation").
+		WithDetail("provider", p.providerName()).
+		WithDetail("policy", source).
+		WithDetail("path", v.rule.Path).
+		WithDetail("violation", v.message)
+	if err := p.auditLogger.Log(auditEvent); err != nil {
+		p.logger.Warn("Failed to audit policy violation", zap.String("request_id", requestID), zap.Error(err))
+	}
+}
@@ -0,0 +1,293 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func float(v float64) *float64 { return &v }
+
+func TestValidateRequestPolicy(t *testing.T) {
+	assert.NoError(t, ValidateRequestPolicy(nil))
+	assert.NoError(t, ValidateRequestPolicy([]RequestPolicyRule{
+		{Path: "$.max_tokens", Max: float(4000), Default: 1024},
+		{Path: "$.temperature", Min: float(0), Max: float(1.2)},
+		{Path: "$.tools", Forbidden: true, Endpoints: []string{"/v1/chat/completions"}},
+		{Path: "$.messages[*].role", Allowed: []string{"system", "user", "assistant"}},
+		{Path: "$.store", Set: false},
+	}))
+
+	invalid := []RequestPolicyRule{
+		{Path: "max_tokens", Max: float(1)},
+		{Path: "$.max_tokens"},
+		{Path: "$.tools", Forbidden: true, Max: float(1)},
+		{Path: "$.store", Set: false, Default: true},
+		{Path: "$.store", Set: false, Allowed: []string{"false"}},
+		{Path: "$.temperature", Min: float(2), Max: float(1)},
+		{Path: "$.messages[*].name", Default: "x"},
+		{Path: "$.model", Allowed: []string{"gpt-[4"}},
+		{Path: "$.model", Allowed: []string{"gpt-4o"}, Endpoints: []string{"v1/chat"}},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, ValidateRequestPolicy([]RequestPolicyRule{rule}), "%+v", rule)
+	}
+}
+
+// applyPolicy runs rules on body sent to endpoint and returns the resulting
+// body and violation message.
+func applyPolicy(t *testing.T, rules []RequestPolicyRule, endpoint, body string) (string, string) {
+	t.Helper()
+	policy, err := compileRequestPolicy(rules)
+	require.NoError(t, err)
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	require.NoError(t, dec.Decode(&doc))
+	ctx := context.WithValue(context.Background(), ctxKeyProjectID, "proj-1")
+	ctx = context.WithValue(ctx, ctxKeyTokenID, "tok")
+	vars := placeholderVars(httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx), "openai")
+	doc, _, violation := policy.apply(doc, endpoint, vars)
+	out, err := json.Marshal(doc)
+	require.NoError(t, err)
+	if violation != nil {
+		return string(out), violation.message
+	}
+	return string(out), ""
+}
+
+func TestRequestPolicy_Checks(t *testing.T) {
+	rules := []RequestPolicyRule{
+		{Path: "$.max_tokens", Max: float(4000)},
+		{Path: "$.temperature", Min: float(0), Max: float(1.2)},
+		{Path: "$.logit_bias", Forbidden: true},
+		{Path: "$.tools", Forbidden: true, Endpoints: []string{"/v1/chat/completions"}},
+		{Path: "$.response_format.type", Allowed: []string{"text", "json_object"}},
+		{Path: "$.model", Allowed: []string{"gpt-4o*"}},
+		{Path: "$.messages[*].role", Allowed: []string{"system", "user", "assistant"}},
+	}
+	tests := []struct {
+		name     string
+		endpoint string
+		body     string
+		want     string
+	}{
+		{"valid", "/v1/chat/completions", `{"model":"gpt-4o-mini","max_tokens":4000,"temperature":0.7,"response_format":{"type":"json_object"}}`, ""},
+		{"absent and null fields are not checked", "/v1/chat/completions", `{"logit_bias":null,"messages":[]}`, ""},
+		{"max", "/v1/chat/completions", `{"max_tokens":4001}`, "max_tokens must be at most 4000"},
+		{"min", "/v1/chat/completions", `{"temperature":-0.5}`, "temperature must be at least 0"},
+		{"not a number", "/v1/chat/completions", `{"max_tokens":"4000"}`, "max_tokens must be a number"},
+		{"forbidden", "/v1/chat/completions", `{"logit_bias":{"50256":-100}}`, "logit_bias is not allowed"},
+		{"forbidden on endpoint", "/v1/chat/completions", `{"tools":[]}`, "tools is not allowed"},
+		{"other endpoint", "/v1/responses", `{"tools":[]}`, ""},
+		{"nested", "/v1/chat/completions", `{"response_format":{"type":"json_schema"}}`, "response_format.type value 'json_schema' is not allowed (allowed: text, json_object)"},
+		{"glob", "/v1/chat/completions", `{"model":"gpt-3.5-turbo"}`, "model value 'gpt-3.5-turbo' is not allowed (allowed: gpt-4o*)"},
+		{"wildcard", "/v1/chat/completions", `{"messages":[{"role":"user"},{"role":"tool"}]}`, "messages[*].role value 'tool' is not allowed (allowed: system, user, assistant)"},
+		{"not a scalar", "/v1/chat/completions", `{"model":["gpt-4o"]}`, "model must be one of gpt-4o*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, msg := applyPolicy(t, rules, tt.endpoint, tt.body)
+			assert.Equal(t, tt.want, msg)
+		})
+	}
+}
+
+func TestRequestPolicy_DefaultAndSet(t *testing.T) {
+	rules := []RequestPolicyRule{
+		{Path: "$.max_tokens", Max: float(4000), Default: 1024},
+		{Path: "$.store", Set: false},
+		{Path: "$.user", Default: "${token_hash}"},
+		{Path: "$.metadata.project", Set: "${project_id}"},
+	}
+
+	out, msg := applyPolicy(t, rules, "/v1/chat/completions", `{"model":"gpt-4o","store":true}`)
+	assert.Empty(t, msg)
+	assert.JSONEq(t, `{
+		"model": "gpt-4o",
+		"max_tokens": 1024,
+		"store": false,
+		"user": "`+tokenHash("tok")+`",
+		"metadata": {"project": "proj-1"}
+	}`, out)
+
+	out, msg = applyPolicy(t, rules, "/v1/chat/completions", `{"max_tokens":200,"user":"end-user-1"}`)
+	assert.Empty(t, msg)
+	assert.JSONEq(t, `{"max_tokens":200,"store":false,"user":"end-user-1","metadata":{"project":"proj-1"}}`, out, "defaults do not replace values")
+}
+
+// tokenHash returns the ${token_hash} value for token.
+func tokenHash(token string) string {
+	ctx := context.WithValue(context.Background(), ctxKeyTokenID, token)
+	return placeholderVars(httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx), "").Replace("${token_hash}")
+}
+
+// policyProjectStore is a stubProjectStore with per-project request policies.
+type policyProjectStore struct {
+	stubProjectStore
+	rules []RequestPolicyRule
+	err   error
+}
+
+func (s *policyProjectStore) GetRequestPolicyForProject(ctx context.Context, projectID string) ([]RequestPolicyRule, error) {
+	return s.rules, s.err
+}
+
+// bodyRecordingUpstream starts an upstream that records the bodies it receives.
+func bodyRecordingUpstream(t *testing.T) (string, *[]string) {
+	var bodies []string
+	url := startUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1"}`))
+	})
+	return url, &bodies
+}
+
+// withRequestPolicy configures the provider's request policy.
+func withRequestPolicy(rules ...RequestPolicyRule) testProxyOption {
+	return withConfig(func(cfg *ProxyConfig) { cfg.RequestPolicy = rules })
+}
+
+func policyViolations(c *SimpleAuditCollector) []*audit.Event {
+	var events []*audit.Event
+	for _, evt := range c.GetEvents() {
+		if evt.Action == audit.ActionProxyPolicyViolation {
+			events = append(events, evt)
+		}
+	}
+	return events
+}
+
+func postChat(p *TransparentProxy, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer tok")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestTransparentProxy_RequestPolicy(t *testing.T) {
+	collector := &SimpleAuditCollector{}
+	upstreamURL, bodies := bodyRecordingUpstream(t)
+	p := newTestProxy(t, upstreamURL, withAuditLogger(collector), withRequestPolicy(
+		RequestPolicyRule{Path: "$.max_tokens", Max: float(4000)},
+		RequestPolicyRule{Path: "$.store", Set: false},
+	))
+
+	w := postChat(p, `{"model":"gpt-4o","max_tokens":100,"store":true}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, *bodies, 1)
+	assert.JSONEq(t, `{"model":"gpt-4o","max_tokens":100,"store":false}`, (*bodies)[0])
+
+	w = postChat(p, `{"model":"gpt-4o","max_tokens":8000}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var resp ErrorResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "policy_violation", resp.Code)
+	assert.Equal(t, "max_tokens must be at most 4000", resp.Description)
+	assert.Len(t, *bodies, 1, "rejected requests are not sent upstream")
+
+	events := policyViolations(collector)
+	require.Len(t, events, 1)
+	ev := events[0]
+	assert.Equal(t, audit.ResultDenied, ev.Result)
+	assert.Equal(t, "test-project-id", ev.ProjectID)
+	assert.Equal(t, "/v1/chat/completions", ev.Details["endpoint"])
+	assert.Equal(t, "provider", ev.Details["policy"])
+	assert.Equal(t, "$.max_tokens", ev.Details["path"])
+	assert.Equal(t, "max_tokens must be at most 4000", ev.Details["violation"])
+
+	// Bodies that are not JSON objects are not subject to policies
+	w = postChat(p, `not json`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "not json", (*bodies)[1])
+}
+
+func TestTransparentProxy_ProjectRequestPolicy(t *testing.T) {
+	store := &policyProjectStore{rules: []RequestPolicyRule{
+		{Path: "$.tools", Forbidden: true},
+		{Path: "$.store", Set: true},
+	}}
+	collector := &SimpleAuditCollector{}
+	upstreamURL, bodies := bodyRecordingUpstream(t)
+	p := newTestProxy(t, upstreamURL, withProjectStore(store), withAuditLogger(collector),
+		withRequestPolicy(RequestPolicyRule{Path: "$.store", Set: false}))
+
+	w := postChat(p, `{"model":"gpt-4o","tools":[{"type":"function"}]}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "tools is not allowed")
+	events := policyViolations(collector)
+	require.Len(t, events, 1)
+	assert.Equal(t, "project", events[0].Details["policy"])
+
+	w = postChat(p, `{"model":"gpt-4o"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"model":"gpt-4o","store":false}`, (*bodies)[0], "provider rules run after project rules")
+
+	store.err = errors.New("db down")
+	w = postChat(p, `{"model":"gpt-4o"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "policy_unavailable")
+}
+
+func TestTransparentProxy_RequestPolicy_RewriteUpdatesLength(t *testing.T) {
+	upstreamURL, bodies := bodyRecordingUpstream(t)
+	p := newTestProxy(t, upstreamURL, withRequestPolicy(RequestPolicyRule{Path: "$.user", Default: "${project_id}"}))
+	body := bytes.Repeat([]byte("a"), 1024)
+	w := postChat(p, `{"input":"`+string(body)+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"input":"`+string(body)+`","user":"test-project-id"}`, (*bodies)[0])
+}
+
+func TestTransparentProxy_RequestPolicy_CacheKeyCoversRewrittenBody(t *testing.T) {
+	validator := &MockTokenValidator{}
+	for tok, projectID := range map[string]string{"tok-a": "project-a", "tok-b": "project-b"} {
+		validator.On("ValidateToken", mock.Anything, tok).Return(projectID, nil)
+		validator.On("ValidateTokenWithTracking", mock.Anything, tok).Return(projectID, nil)
+	}
+	var bodies []string
+	upstreamURL := startUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1"}`))
+	})
+	p := newTestProxy(t, upstreamURL, withTokenValidator(validator),
+		withRequestPolicy(RequestPolicyRule{Path: "$.user", Set: "${project_id}"}),
+		withConfig(func(cfg *ProxyConfig) { cfg.HTTPCacheEnabled = true }))
+	send := func(tok string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+		req.Header.Set("Authorization", "Bearer "+tok)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Cache-Control", "public, max-age=60")
+		w := httptest.NewRecorder()
+		p.Handler().ServeHTTP(w, req)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, send("tok-a").Code)
+	// Same client body, but the policy sends a different one upstream
+	require.Equal(t, http.StatusOK, send("tok-b").Code)
+	require.Len(t, bodies, 2)
+	assert.JSONEq(t, `{"model":"gpt-4o","user":"project-b"}`, bodies[1])
+
+	w := send("tok-a")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hit", w.Header().Get("X-PROXY-CACHE"))
+	assert.Len(t, bodies, 2)
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Response transform operations
+const (
+	TransformJSONDelete   = "json_delete"
+	TransformJSONSet      = "json_set"
+	TransformHeaderAdd    = "header_add"
+	TransformHeaderRemove = "header_remove"
+)
+
+// responseTransformer applies a provider's response transforms. Header
+// operations run on every response; JSON operations on JSON bodies and on each
+// event of a server-sent event stream.
+type responseTransformer struct {
+	steps    []transformStep
+	jsonOps  bool
+	provider string
+}
+
+type transformStep struct {
+	op     string
+	path   []pathSegment
+	header string
+	value  any
+}
+
+// newResponseTransformer validates cfgs and compiles them. It returns nil when
+// there is nothing to do.
+func newResponseTransformer(cfgs []ResponseTransformConfig, provider string) (*responseTransformer, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+	t := &responseTransformer{provider: provider}
+	for i, cfg := range cfgs {
+		step := transformStep{op: cfg.Op, header: http.CanonicalHeaderKey(cfg.Header), value: cfg.Value}
+		switch cfg.Op {
+		case TransformJSONDelete, TransformJSONSet:
+			path, err := parseJSONPath(cfg.Path)
+			if err != nil {
+				return nil, fmt.Errorf("transform %d: %w", i+1, err)
+			}
+			if cfg.Op == TransformJSONSet && cfg.Value == nil {
+				return nil, fmt.Errorf("transform %d: %s needs a value", i+1, cfg.Op)
+			}
+			step.path = path
+			t.jsonOps = true
+		case TransformHeaderAdd, TransformHeaderRemove:
+			if cfg.Header == "" {
+				return nil, fmt.Errorf("transform %d: %s needs a header", i+1, cfg.Op)
+			}
+			if cfg.Op == TransformHeaderAdd {
+				if _, ok := cfg.Value.(string); !ok {
+					return nil, fmt.Errorf("transform %d: %s needs a string value", i+1, cfg.Op)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("transform %d: unknown op '%s'", i+1, cfg.Op)
+		}
+		t.steps = append(t.steps, step)
+	}
+	return t, nil
+}
+
+// applyHeaders runs the header operations on header.
+func (t *responseTransformer) applyHeaders(header http.Header, vars *strings.Replacer) {
+	for _, step := range t.steps {
+		switch step.op {
+		case TransformHeaderAdd:
+			header.Add(step.header, vars.Replace(step.value.(string)))
+		case TransformHeaderRemove:
+			header.Del(step.header)
+		}
+	}
+}
+
+// transformBody wraps or rewrites body for the JSON operations. ok is false
+// when body is left untouched; length is -1 for streamed bodies.
+func (t *responseTransformer) transformBody(header http.Header, body io.ReadCloser, vars *strings.Replacer) (out io.ReadCloser, length int64, ok bool, err error) {
+	if !t.jsonOps || body == nil || body == http.NoBody {
+		return body, 0, false, nil
+	}
+	// Compressed bodies are passed through untouched. The director drops the
+	// client's Accept-Encoding when JSON operations are configured, so only
+	// upstreams compressing unasked get here.
+	if enc := header.Get("Content-Encoding"); enc != "" && enc != "identity" {
+		return body, 0, false, nil
+	}
+	contentType := header.Get("Content-Type")
+	switch {
+	case strings.Contains(contentType, "text/event-stream"):
+		st := newSSETranslator(body, &sseTransformConverter{t: t, vars: vars})
+		st.keepEvents = true
+		return st, -1, true, nil
+	case strings.Contains(contentType, "json"):
+		data, err := io.ReadAll(body)
+		_ = body.Close()
+		if err != nil {
+			return nil, 0, false, err
+		}
+		data = t.transformJSON(data, vars)
+		return io.NopCloser(bytes.NewReader(data)), int64(len(data)), true, nil
+	}
+	return body, 0, false, nil
+}
+
+// transformJSON applies the JSON operations to a document. Bodies that are not
+// valid JSON are returned unchanged.
+func (t *responseTransformer) transformJSON(data []byte, vars *strings.Replacer) []byte {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return data
+	}
+	for _, step := range t.steps {
+		switch step.op {
+		case TransformJSONDelete:
+			doc = deletePath(doc, step.path)
+		case TransformJSONSet:
+			doc = setPath(doc, step.path, func() any { return expandValue(step.value, vars) })
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return data
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+// transformResponse applies the proxy's response transforms to res.
+func (p *TransparentProxy) transformResponse(res *http.Response) error {
+	vars := placeholderVars(res.Request, p.transforms.provider)
+	p.transforms.applyHeaders(res.Header, vars)
+	body, length, ok, err := p.transforms.transformBody(res.Header, res.Body, vars)
+	if err != nil {
+		return fmt.Errorf("failed to transform response: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	res.Body = body
+	res.ContentLength = length
+	if length < 0 {
+		res.Header.Del("Content-Length")
+	} else {
+		res.Header.Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+	return nil
+}
+
+// transformCachedResponse applies the response transforms to a cached response
+// about to be served with header and returns the body to write.
+func (p *TransparentProxy) transformCachedResponse(r *http.Request, header http.Header, statusCode int, body []byte) []byte {
+	res := &http.Response{
+		StatusCode:    statusCode,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       r,
+	}
+	if err := p.transformResponse(res); err != nil {
+		return body
+	}
+	out, err := io.ReadAll(res.Body)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// sseTransformConverter applies the JSON operations to each event's data.
+type sseTransformConverter struct {
+	t    *responseTransformer
+	vars *strings.Replacer
+}
+
+func (c *sseTransformConverter) event(name string, data []byte) [][]byte {
+	if len(data) == 0 || string(data) == "[DONE]" {
+		return [][]byte{data}
+	}
+	return [][]byte{c.t.transformJSON(data, c.vars)}
+}
+
+func (c *sseTransformConverter) finish() [][]byte {
+	return nil
+}
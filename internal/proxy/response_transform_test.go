@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	segs, err := parseJSONPath(`$.choices[*].message['refusal']`)
+	require.NoError(t, err)
+	assert.Equal(t, []pathSegment{{key: "choices"}, {wildcard: true}, {key: "message"}, {key: "refusal"}}, segs)
+
+	segs, err = parseJSONPath(`$["a.b"][2].*`)
+	require.NoError(t, err)
+	assert.Equal(t, []pathSegment{{key: "a.b"}, {index: 2, isIndex: true}, {wildcard: true}}, segs)
+
+	for _, path := range []string{"", "$", "usage", "$..usage", "$.a[", "$.a[-1]", "$.a[x]", "$a"} {
+		_, err := parseJSONPath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestNewResponseTransformer_Validation(t *testing.T) {
+	tr, err := newResponseTransformer(nil, "openai")
+	require.NoError(t, err)
+	assert.Nil(t, tr)
+
+	_, err = newResponseTransformer([]ResponseTransformConfig{
+		{Op: TransformJSONDelete, Path: "$.system_fingerprint"},
+		{Op: TransformJSONSet, Path: "$.usage.project", Value: "${project_id}"},
+		{Op: TransformHeaderAdd, Header: "X-Served-By", Value: "llm-proxy"},
+		{Op: TransformHeaderRemove, Header: "OpenAI-Organization"},
+	}, "openai")
+	assert.NoError(t, err)
+
+	invalid := []ResponseTransformConfig{
+		{Op: "json_rename", Path: "$.a"},
+		{Op: TransformJSONDelete, Path: "a"},
+		{Op: TransformJSONSet, Path: "$.a"},
+		{Op: TransformHeaderAdd, Header: "X-A"},
+		{Op: TransformHeaderAdd, Header: "X-A", Value: 1},
+		{Op: TransformHeaderRemove},
+	}
+	for _, cfg := range invalid {
+		_, err := newResponseTransformer([]ResponseTransformConfig{cfg}, "openai")
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
+
+func TestResponseTransformer_JSON(t *testing.T) {
+	tr, err := newResponseTransformer([]ResponseTransformConfig{
+		{Op: TransformJSONDelete, Path: "$.system_fingerprint"},
+		{Op: TransformJSONDelete, Path: "$.choices[*].logprobs"},
+		{Op: TransformJSONDelete, Path: "$.data[0]"},
+		{Op: TransformJSONSet, Path: "$.x_usage", Value: map[string]any{"project": "${project_id}", "tags": []any{"${provider}"}}},
+		{Op: TransformJSONSet, Path: "$.choices[1].index", Value: 7},
+	}, "openai")
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), ctxKeyProjectID, "proj-1")
+	vars := placeholderVars(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx), "openai")
+
+	out := tr.transformJSON([]byte(`{
+		"id": "chatcmpl-1",
+		"created": 17000000000000000001,
+		"system_fingerprint": "fp_1",
+		"note": "a<b",
+		"data": [1, 2],
+		"choices": [{"index": 0, "logprobs": null}, {"index": 1, "logprobs": {}}]
+	}`), vars)
+	assert.JSONEq(t, `{
+		"id": "chatcmpl-1",
+		"created": 17000000000000000001,
+		"note": "a<b",
+		"data": [2],
+		"choices": [{"index": 0}, {"index": 7}],
+		"x_usage": {"project": "proj-1", "tags": ["openai"]}
+	}`, string(out))
+	assert.Contains(t, string(out), `17000000000000000001`, "numbers keep their precision")
+	assert.Contains(t, string(out), `"a<b"`)
+
+	assert.Equal(t, "not json", string(tr.transformJSON([]byte("not json"), vars)))
+	assert.Equal(t, `[1]`, string(tr.transformJSON([]byte(`[1]`), vars)), "paths that do not match leave the document alone")
+}
+
+// withResponseTransforms configures the proxy's response transforms.
+func withResponseTransforms(transforms ...ResponseTransformConfig) testProxyOption {
+	return withConfig(func(cfg *ProxyConfig) { cfg.ResponseTransforms = transforms })
+}
+
+func TestTransparentProxy_ResponseTransforms(t *testing.T) {
+	p := newTestProxy(t, startUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("OpenAI-Organization", "org-secret")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","system_fingerprint":"fp_1","usage":{"total_tokens":3}}`))
+	}), withResponseTransforms(
+		ResponseTransformConfig{Op: TransformJSONDelete, Path: "$.system_fingerprint"},
+		ResponseTransformConfig{Op: TransformJSONSet, Path: "$.x_usage.project", Value: "${project_id}"},
+		ResponseTransformConfig{Op: TransformHeaderRemove, Header: "openai-organization"},
+		ResponseTransformConfig{Op: TransformHeaderAdd, Header: "X-Usage-Project", Value: "${project_id}"},
+	))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"id":"chatcmpl-1","usage":{"total_tokens":3},"x_usage":{"project":"test-project-id"}}`, w.Body.String())
+	assert.Empty(t, w.Header().Get("OpenAI-Organization"))
+	assert.Equal(t, "test-project-id", w.Header().Get("X-Usage-Project"))
+	assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+}
+
+func TestTransparentProxy_ResponseTransforms_ClientAcceptEncoding(t *testing.T) {
+	p := newTestProxy(t, startUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body := `{"id":"chatcmpl-1","system_fingerprint":"fp_1"}`
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(body))
+		_ = gz.Close()
+	}), withResponseTransforms(ResponseTransformConfig{Op: TransformJSONDelete, Path: "$.system_fingerprint"}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Encoding"), "the body is decoded so it can be transformed")
+	assert.JSONEq(t, `{"id":"chatcmpl-1"}`, w.Body.String())
+}
+
+func TestTransparentProxy_ResponseTransforms_Stream(t *testing.T) {
+	p := newTestProxy(t, startUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"id\":\"1\",\"system_fingerprint\":\"fp_1\"}\n\n"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(": keep-alive\n\nevent: usage\ndata: {\"system_fingerprint\":\"fp_1\",\"n\":2}\n\ndata: [DONE]\n\n"))
+	}), withResponseTransforms(ResponseTransformConfig{Op: TransformJSONDelete, Path: "$.system_fingerprint"}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o","stream":true}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "data: {\"id\":\"1\"}\n\nevent: usage\ndata: {\"n\":2}\n\ndata: [DONE]\n\n", w.Body.String())
+	assert.Empty(t, w.Header().Get("Content-Length"))
+}
+
+func TestTransparentProxy_ResponseTransforms_CacheHit(t *testing.T) {
+	var calls atomic.Int32
+	p := newTestProxy(t, startUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		_, _ = w.Write([]byte(`{"object":"list","system_fingerprint":"fp_1"}`))
+	}), withResponseTransforms(
+		ResponseTransformConfig{Op: TransformJSONDelete, Path: "$.system_fingerprint"},
+		ResponseTransformConfig{Op: TransformHeaderAdd, Header: "X-Served-By", Value: "llm-proxy"},
+	), withConfig(func(cfg *ProxyConfig) {
+		cfg.HTTPCacheEnabled = true
+		cfg.HTTPCacheDefaultTTL = time.Minute
+	}))
+
+	var bodies []string
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer tok")
+		w := httptest.NewRecorder()
+		p.Handler().ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"llm-proxy"}, w.Header().Values("X-Served-By"), "headers are added once per response")
+		bodies = append(bodies, w.Body.String())
+		if i == 1 {
+			assert.Equal(t, "hit", w.Header().Get("X-PROXY-CACHE"))
+		}
+	}
+	assert.Equal(t, int32(1), calls.Load())
+	assert.JSONEq(t, `{"object":"list"}`, bodies[0])
+	assert.Equal(t, bodies[0], bodies[1])
+}
@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Retry defaults applied when a provider enables retries
+const (
+	defaultRetryInitialBackoff = 200 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+	// maxRetryBodyBytes is the largest request body buffered for replay;
+	// requests with bigger bodies are sent once.
+	maxRetryBodyBytes = 10 << 20
+	maxRetryAttempts  = 10
+)
+
+// defaultRetryStatuses are retried when RetryConfig.RetryOn is empty.
+var defaultRetryStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// validateRetryConfig checks the retry settings of a provider.
+func validateRetryConfig(cfg RetryConfig) error {
+	if cfg.MaxAttempts < 0 || cfg.MaxAttempts > maxRetryAttempts {
+		return fmt.Errorf("max_attempts must be between 0 and %d", maxRetryAttempts)
+	}
+	if cfg.InitialBackoff < 0 || cfg.MaxBackoff < 0 {
+		return errors.New("backoff durations must not be negative")
+	}
+	for _, status := range cfg.RetryOn {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("retry_on has invalid status code %d", status)
+		}
+	}
+	return nil
+}
+
+// retryTransport retries upstream round trips that fail with a transport
+// error or a retryable status. Retries happen before the response is handed to
+// the reverse proxy, so nothing has been written to the client yet; streaming
+// requests are only retried while they have not received response headers.
+type retryTransport struct {
+	next            http.RoundTripper
+	maxAttempts     int
+	initialBackoff  time.Duration
+	maxBackoff      time.Duration
+	retryOn         map[int]bool
+	honorRetryAfter bool
+	logger          *zap.Logger
+}
+
+// newRetryTransport wraps next with cfg's retry policy. It returns next when
+// retries are disabled.
+func newRetryTransport(next http.RoundTripper, cfg RetryConfig, logger *zap.Logger) http.RoundTripper {
+	if cfg.MaxAttempts <= 1 {
+		return next
+	}
+	t := &retryTransport{
+		next:            next,
+		maxAttempts:     cfg.MaxAttempts,
+		initialBackoff:  cfg.InitialBackoff,
+		maxBackoff:      cfg.MaxBackoff,
+		retryOn:         make(map[int]bool),
+		honorRetryAfter: !cfg.IgnoreRetryAfter,
+		logger:          logger,
+	}
+	if t.initialBackoff == 0 {
+		t.initialBackoff = defaultRetryInitialBackoff
+	}
+	if t.maxBackoff == 0 {
+		t.maxBackoff = defaultRetryMaxBackoff
+	}
+	statuses := cfg.RetryOn
+	if len(statuses) == 0 {
+		statuses = defaultRetryStatuses
+	}
+	for _, status := range statuses {
+		t.retryOn[status] = true
+	}
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, buffered, err := bufferRequestBody(req, maxRetryBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !buffered {
+		return t.next.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.Body != nil {
+			attemptReq = req.WithContext(req.Context())
+			setRequestBody(attemptReq, body)
+		}
+		res, err := t.next.RoundTrip(attemptReq)
+		if attempt >= t.maxAttempts || req.Context().Err() != nil {
+			return res, err
+		}
+
+		wait := t.backoff(attempt)
+		if err == nil {
+			if !t.retryOn[res.StatusCode] {
+				return res, nil
+			}
+			if retryAfter := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); t.honorRetryAfter && retryAfter > 0 {
+				// Waiting longer than allowed would only delay the inevitable failure
+				if retryAfter > t.maxBackoff {
+					return res, nil
+				}
+				wait = retryAfter
+			}
+		}
+
+		requestID, _ := req.Context().Value(ctxKeyRequestID).(string)
+		fields := []zap.Field{
+			zap.String("request_id", requestID),
+			zap.Int("attempt", attempt),
+			zap.Duration("wait", wait),
+		}
+		if err != nil {
+			fields = append(fields, zap.Error(err))
+		} else {
+			fields = append(fields, zap.Int("status", res.StatusCode))
+			// Drain so the connection can be reused
+			_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			_ = res.Body.Close()
+		}
+		t.logger.Warn("Retrying upstream request", fields...)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// backoff returns the jittered exponential wait after the given attempt.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.initialBackoff << (attempt - 1)
+	if d <= 0 || d > t.maxBackoff {
+		d = t.maxBackoff
+	}
+	// Equal jitter: half fixed, half random
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// bufferRequestBody reads r's body into memory and replaces it with a
+// replayable copy. With limit > 0, bodies longer than limit are not buffered:
+// buffered is false and r.Body still yields the complete body.
+func bufferRequestBody(r *http.Request, limit int64) (body []byte, buffered bool, err error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+	reader := io.Reader(r.Body)
+	if limit > 0 {
+		reader = io.LimitReader(r.Body, limit+1)
+	}
+	body, err = io.ReadAll(reader)
+	if err != nil {
+		_ = r.Body.Close()
+		return nil, false, err
+	}
+	if limit > 0 && int64(len(body)) > limit {
+		r.Body = &readerWithCloser{r: io.MultiReader(bytes.NewReader(body), r.Body), c: r.Body}
+		return nil, false, nil
+	}
+	_ = r.Body.Close()
+	setRequestBody(r, body)
+	return body, true, nil
+}
@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func statusResponse(status int, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader("body"))}
+}
+
+func TestValidateRetryConfig(t *testing.T) {
+	assert.NoError(t, validateRetryConfig(RetryConfig{}))
+	assert.NoError(t, validateRetryConfig(RetryConfig{MaxAttempts: 3, RetryOn: []int{500, 429}}))
+	assert.Error(t, validateRetryConfig(RetryConfig{MaxAttempts: -1}))
+	assert.Error(t, validateRetryConfig(RetryConfig{MaxAttempts: maxRetryAttempts + 1}))
+	assert.Error(t, validateRetryConfig(RetryConfig{MaxAttempts: 2, InitialBackoff: -time.Second}))
+	assert.Error(t, validateRetryConfig(RetryConfig{MaxAttempts: 2, RetryOn: []int{700}}))
+}
+
+func TestNewRetryTransport_Disabled(t *testing.T) {
+	base := http.DefaultTransport
+	assert.Equal(t, base, newRetryTransport(base, RetryConfig{}, zap.NewNop()))
+	assert.Equal(t, base, newRetryTransport(base, RetryConfig{MaxAttempts: 1}, zap.NewNop()))
+}
+
+func TestRetryTransport_RetriesAndReplaysBody(t *testing.T) {
+	var bodies []string
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		switch len(bodies) {
+		case 1:
+			return nil, errors.New("connection reset by peer")
+		case 2:
+			return statusResponse(http.StatusBadGateway, nil), nil
+		}
+		return statusResponse(http.StatusOK, nil), nil
+	})
+	rt := newRetryTransport(next, RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond}, zap.NewNop())
+
+	req := httptest.NewRequest(http.MethodPost, "http://upstream/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+	res, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{`{"model":"gpt-4o"}`, `{"model":"gpt-4o"}`, `{"model":"gpt-4o"}`}, bodies)
+}
+
+func TestRetryTransport_StopsOnNonRetryableAndExhaustion(t *testing.T) {
+	var calls int
+	status := http.StatusBadRequest
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return statusResponse(status, nil), nil
+	})
+	rt := newRetryTransport(next, RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, RetryOn: []int{500}}, zap.NewNop())
+
+	res, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	status = http.StatusInternalServerError
+	res, err = rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, res.StatusCode, "last failure is returned")
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	status = http.StatusServiceUnavailable
+	_, err = rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "only the configured statuses are retried")
+}
+
+func TestRetryTransport_RetryAfter(t *testing.T) {
+	var calls int
+	retryAfter := "1"
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return statusResponse(http.StatusTooManyRequests, http.Header{"Retry-After": []string{retryAfter}}), nil
+		}
+		return statusResponse(http.StatusOK, nil), nil
+	})
+
+	rt := newRetryTransport(next, RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Second}, zap.NewNop())
+	start := time.Now()
+	res, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "Retry-After is honored")
+
+	// A Retry-After beyond max_backoff is not waited for
+	calls = 0
+	retryAfter = "30"
+	res, err = rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, 1, calls)
+
+	// ignore_retry_after falls back to the exponential backoff
+	calls = 0
+	rt = newRetryTransport(next, RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond, IgnoreRetryAfter: true}, zap.NewNop())
+	start = time.Now()
+	res, err = rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryTransport_CanceledDuringBackoff(t *testing.T) {
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return statusResponse(http.StatusServiceUnavailable, nil), nil
+	})
+	rt := newRetryTransport(next, RetryConfig{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Minute}, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "http://upstream/v1/models", nil).WithContext(ctx)
+	_, err := rt.RoundTrip(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetryTransport_LargeBodySentOnce(t *testing.T) {
+	var calls int
+	var size int
+	next := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		size = len(b)
+		return statusResponse(http.StatusServiceUnavailable, nil), nil
+	})
+	rt := newRetryTransport(next, RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond}, zap.NewNop())
+
+	body := strings.Repeat("x", maxRetryBodyBytes+10)
+	res, err := rt.RoundTrip(httptest.NewRequest(http.MethodPost, "http://upstream/v1/files", strings.NewReader(body)))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, len(body), size, "the whole body is still sent")
+}
+
+func TestRetryTransport_Backoff(t *testing.T) {
+	rt := newRetryTransport(http.DefaultTransport, RetryConfig{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}, zap.NewNop()).(*retryTransport)
+	for i := 0; i < 20; i++ {
+		d := rt.backoff(1)
+		assert.True(t, d >= 50*time.Millisecond && d <= 100*time.Millisecond, "attempt 1: %v", d)
+		d = rt.backoff(2)
+		assert.True(t, d >= 100*time.Millisecond && d <= 200*time.Millisecond, "attempt 2: %v", d)
+		d = rt.backoff(4)
+		assert.True(t, d >= 150*time.Millisecond && d <= 300*time.Millisecond, "capped: %v", d)
+	}
+}
+
+func TestTransparentProxy_Retry(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(`{"error":"bad gateway"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: " + string(b) + "\n\n"))
+	}))
+	defer upstream.Close()
+
+	p, err := NewTransparentProxyWithLogger(ProxyConfig{
+		TargetBaseURL:    upstream.URL,
+		AllowedEndpoints: []string{"/v1/chat/completions"},
+		AllowedMethods:   []string{http.MethodPost},
+		ParamWhitelist:   map[string][]string{"model": {"gpt-*"}},
+		Retry:            RetryConfig{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+	}, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop())
+	require.NoError(t, err)
+
+	body := `{"model":"gpt-4o","stream":true}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer tok")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "data: "+body+"\n\n", w.Body.String(), "stream is served from the retried request")
+	assert.Equal(t, int32(2), calls.Load())
+}
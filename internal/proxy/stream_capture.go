@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"bufio"
 	"bytes"
 	"io"
 	"sync/atomic"
@@ -57,3 +58,100 @@ func (s *streamingCaptureReadCloser) finalize() {
 		}
 	}
 }
+
+// sseConverter turns upstream server-sent events into the data payloads sent
+// to the client.
+type sseConverter interface {
+	// event handles one upstream event and returns the payloads to emit.
+	event(name string, data []byte) [][]byte
+	// finish is called once when the upstream stream ends.
+	finish() [][]byte
+}
+
+// sseTranslatingReadCloser re-frames an upstream SSE body through an
+// sseConverter, emitting each converted payload as a "data:" event. Events are
+// converted as they arrive so streaming latency is preserved.
+type sseTranslatingReadCloser struct {
+	rc    io.ReadCloser
+	br    *bufio.Reader
+	conv  sseConverter
+	out   bytes.Buffer
+	event string
+	data  bytes.Buffer
+	err   error
+	// keepEvents re-emits the upstream event names and multi-line data fields
+	// with the converted payloads
+	keepEvents bool
+}
+
+func newSSETranslator(rc io.ReadCloser, conv sseConverter) *sseTranslatingReadCloser {
+	return &sseTranslatingReadCloser{rc: rc, br: bufio.NewReader(rc), conv: conv}
+}
+
+func (s *sseTranslatingReadCloser) Read(p []byte) (int, error) {
+	for s.out.Len() == 0 && s.err == nil {
+		line, err := s.br.ReadBytes('\n')
+		if len(line) > 0 {
+			s.handleLine(line)
+		}
+		if err != nil {
+			if err == io.EOF {
+				s.dispatch()
+				s.emit("", s.conv.finish())
+			}
+			s.err = err
+		}
+	}
+	if s.out.Len() > 0 {
+		return s.out.Read(p)
+	}
+	return 0, s.err
+}
+
+func (s *sseTranslatingReadCloser) Close() error {
+	return s.rc.Close()
+}
+
+func (s *sseTranslatingReadCloser) handleLine(line []byte) {
+	line = bytes.TrimRight(line, "\r\n")
+	switch {
+	case len(line) == 0:
+		s.dispatch()
+	case line[0] == ':':
+		// comment / keep-alive
+	case bytes.HasPrefix(line, []byte("event:")):
+		s.event = string(bytes.TrimSpace(line[len("event:"):]))
+	case bytes.HasPrefix(line, []byte("data:")):
+		if s.data.Len() > 0 {
+			s.data.WriteByte('\n')
+		}
+		s.data.Write(bytes.TrimPrefix(line[len("data:"):], []byte(" ")))
+	}
+}
+
+func (s *sseTranslatingReadCloser) dispatch() {
+	if s.event == "" && s.data.Len() == 0 {
+		return
+	}
+	name := ""
+	if s.keepEvents {
+		name = s.event
+	}
+	s.emit(name, s.conv.event(s.event, s.data.Bytes()))
+	s.event = ""
+	s.data.Reset()
+}
+
+func (s *sseTranslatingReadCloser) emit(event string, payloads [][]byte) {
+	for _, payload := range payloads {
+		if event != "" {
+			s.out.WriteString("event: " + event + "\n")
+		}
+		s.out.WriteString("data: ")
+		if s.keepEvents {
+			payload = bytes.ReplaceAll(payload, []byte("\n"), []byte("\ndata: "))
+		}
+		s.out.Write(payload)
+		s.out.WriteString("\n\n")
+	}
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+)
+
+// validateTokenBindings checks a provider's token binding configuration.
+func validateTokenBindings(cfg TokenBindingConfig) error {
+	for header, key := range cfg.Headers {
+		if strings.TrimSpace(header) == "" {
+			return errors.New("header name cannot be empty")
+		}
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("header '%s' has no metadata key", header)
+		}
+	}
+	return nil
+}
+
+// enforceTokenBindings rejects requests whose bound headers or client IP do
+// not match the token's metadata. Bindings whose key the token's metadata
+// lacks are skipped, so unbound tokens keep working.
+func (p *TransparentProxy) enforceTokenBindings(r *http.Request, td token.TokenData, projectID string) (int, ErrorResponse) {
+	bindings := p.config.TokenBindings
+	if len(td.Metadata) == 0 || (len(bindings.Headers) == 0 && bindings.ClientIP == "") {
+		return 0, ErrorResponse{}
+	}
+
+	headers := make([]string, 0, len(bindings.Headers))
+	for header := range bindings.Headers {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		want, ok := td.Metadata[bindings.Headers[header]]
+		if !ok {
+			continue
+		}
+		if r.Header.Get(header) != want {
+			p.auditTokenDenial(r, td, projectID, "token_bindings", "header_mismatch", map[string]any{"header": header})
+			return http.StatusForbidden, ErrorResponse{
+				Error:       "Token binding mismatch",
+				Code:        "token_binding_mismatch",
+				Description: fmt.Sprintf("header %s does not match the value this token is bound to", header),
+			}
+		}
+	}
+
+	if bindings.ClientIP != "" {
+		if want, ok := td.Metadata[bindings.ClientIP]; ok && !sameIP(getClientIP(r), want) {
+			p.auditTokenDenial(r, td, projectID, "token_bindings", "client_ip_mismatch", nil)
+			return http.StatusForbidden, ErrorResponse{
+				Error:       "Token binding mismatch",
+				Code:        "token_binding_mismatch",
+				Description: "client IP does not match the address this token is bound to",
+			}
+		}
+	}
+	return 0, ErrorResponse{}
+}
+
+// sameIP compares two IP addresses, ignoring formatting differences.
+func sameIP(a, b string) bool {
+	ipA := net.ParseIP(strings.Trim(a, "[]"))
+	ipB := net.ParseIP(strings.TrimSpace(b))
+	if ipA == nil || ipB == nil {
+		return a == b
+	}
+	return ipA.Equal(ipB)
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/middleware"
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// metadataTokenValidator is a stubTokenValidator that returns token metadata.
+type metadataTokenValidator struct {
+	stubTokenValidator
+	metadata map[string]string
+}
+
+func (v *metadataTokenValidator) GetTokenData(ctx context.Context, tokenString string) (token.TokenData, error) {
+	return token.TokenData{ID: "tok-id", Token: tokenString, ProjectID: "test-project-id", IsActive: true, Metadata: v.metadata}, nil
+}
+
+func TestValidateTokenBindings(t *testing.T) {
+	assert.NoError(t, validateTokenBindings(TokenBindingConfig{}))
+	assert.NoError(t, validateTokenBindings(TokenBindingConfig{Headers: map[string]string{"X-Proxy-User-Id": "user_id"}, ClientIP: "client_ip"}))
+	assert.Error(t, validateTokenBindings(TokenBindingConfig{Headers: map[string]string{" ": "user_id"}}))
+	assert.Error(t, validateTokenBindings(TokenBindingConfig{Headers: map[string]string{"X-Proxy-User-Id": ""}}))
+}
+
+func TestSameIP(t *testing.T) {
+	assert.True(t, sameIP("203.0.113.7", "203.0.113.7"))
+	assert.True(t, sameIP("[::1]", "0:0:0:0:0:0:0:1"))
+	assert.False(t, sameIP("203.0.113.8", "203.0.113.7"))
+}
+
+func TestTransparentProxy_TokenBindings(t *testing.T) {
+	upstream := &modelUpstream{}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+	collector := &SimpleAuditCollector{}
+	validator := &metadataTokenValidator{metadata: map[string]string{"user_id": "42", "client_ip": "203.0.113.7"}}
+	p, err := NewTransparentProxyWithAudit(ProxyConfig{
+		TargetBaseURL:    srv.URL,
+		Provider:         "openai",
+		AllowedEndpoints: []string{"/v1/"},
+		AllowedMethods:   []string{http.MethodPost},
+		TrustedProxies:   []string{"192.0.2.0/24"}, // httptest requests come from 192.0.2.1
+		TokenBindings: TokenBindingConfig{
+			Headers:  map[string]string{"X-Proxy-User-Id": "user_id", "X-Proxy-Session": "session_id"},
+			ClientIP: "client_ip",
+		},
+	}, validator, &stubProjectStore{}, zap.NewNop(), collector, middleware.ObservabilityConfig{})
+	require.NoError(t, err)
+	h := p.Handler()
+
+	// session_id is not in the token's metadata, so X-Proxy-Session is not required
+	ok := map[string]string{"X-Proxy-User-Id": "42", "X-Forwarded-For": "203.0.113.7"}
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", ok).Code)
+
+	w := sendChat(h, "gpt-4o-mini", map[string]string{"X-Proxy-User-Id": "43", "X-Forwarded-For": "203.0.113.7"})
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "token_binding_mismatch")
+	assert.Contains(t, w.Body.String(), "X-Proxy-User-Id")
+	assert.NotContains(t, w.Body.String(), "42", "bound values are not disclosed")
+
+	w = sendChat(h, "gpt-4o-mini", map[string]string{"X-Forwarded-For": "203.0.113.7"})
+	assert.Equal(t, http.StatusForbidden, w.Code, "a missing bound header is a mismatch")
+
+	w = sendChat(h, "gpt-4o-mini", map[string]string{"X-Proxy-User-Id": "42", "X-Forwarded-For": "198.51.100.1"})
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "client IP does not match")
+
+	assert.Len(t, upstream.seen(), 1)
+	events := policyViolations(collector)
+	require.Len(t, events, 3)
+	assert.Equal(t, audit.ResultDenied, events[0].Result)
+	assert.Equal(t, "token_bindings", events[0].Details["policy"])
+	assert.Equal(t, "header_mismatch", events[0].Details["reason"])
+	assert.Equal(t, "X-Proxy-User-Id", events[0].Details["header"])
+	assert.Equal(t, "client_ip_mismatch", events[2].Details["reason"])
+
+	// Tokens without metadata are not bound
+	validator.metadata = nil
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+}
@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+)
+
+// tokenLimits is the request limit of a token or its project closest to
+// exhaustion, as reported to the client
+type tokenLimits struct {
+	limit     int
+	remaining int
+	reset     time.Time // Zero for limits that never renew
+}
+
+// withTokenLimits stores the lifetime request limit of td in the request
+// context. Tokens without a request limit report none until a rate limit
+// applies.
+func withTokenLimits(r *http.Request, td token.TokenData) *http.Request {
+	remaining, limited := td.RemainingRequests()
+	if !limited {
+		return r
+	}
+	return withReportedLimit(r, tokenLimits{limit: *td.MaxRequests, remaining: remaining})
+}
+
+// withRateLimitStatus reports the window limit of status instead of the one
+// already reported when it is closer to exhaustion.
+func withRateLimitStatus(r *http.Request, status *token.RateLimitStatus) *http.Request {
+	if status == nil {
+		return r
+	}
+	return withReportedLimit(r, tokenLimits{limit: status.Limit, remaining: status.Remaining, reset: status.Reset})
+}
+
+// withReportedLimit stores limits in the request context unless the limit
+// already stored has fewer requests left.
+func withReportedLimit(r *http.Request, limits tokenLimits) *http.Request {
+	if current, ok := r.Context().Value(ctxKeyTokenLimits).(tokenLimits); ok && current.remaining <= limits.remaining {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), ctxKeyTokenLimits, limits))
+}
+
+// setRateLimitHeaders sets X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset from the limit closest to exhaustion. The reset time is
+// when the requests counted in the limit's window have aged out, as Unix
+// seconds, and is omitted for lifetime request limits, which never renew.
+func setRateLimitHeaders(ctx context.Context, h http.Header) {
+	limits, ok := ctx.Value(ctxKeyTokenLimits).(tokenLimits)
+	if !ok {
+		return
+	}
+	h.Set("X-RateLimit-Limit", strconv.Itoa(limits.limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(limits.remaining))
+	if !limits.reset.IsZero() {
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(limits.reset.Unix(), 10))
+	}
+}
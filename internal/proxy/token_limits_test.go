@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// limitedTokenValidator is a stubTokenValidator whose tokens have a request limit.
+type limitedTokenValidator struct {
+	stubTokenValidator
+	maxRequests  *int
+	requestCount int
+	expiresAt    *time.Time
+}
+
+func (v *limitedTokenValidator) GetTokenData(ctx context.Context, tokenString string) (token.TokenData, error) {
+	return token.TokenData{
+		ID:           "tok-id",
+		Token:        tokenString,
+		ProjectID:    "test-project-id",
+		IsActive:     true,
+		MaxRequests:  v.maxRequests,
+		RequestCount: v.requestCount,
+		ExpiresAt:    v.expiresAt,
+	}, nil
+}
+
+func TestTransparentProxy_RateLimitHeaders(t *testing.T) {
+	upstream := &modelUpstream{}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+
+	maxRequests := 10
+	expiresAt := time.Now().Add(time.Hour)
+	validator := &limitedTokenValidator{maxRequests: &maxRequests, requestCount: 3, expiresAt: &expiresAt}
+	h := newTestProxy(t, srv.URL, withTokenValidator(validator)).Handler()
+
+	w := sendChat(h, "gpt-4o-mini", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "7", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Empty(t, w.Header().Get("X-RateLimit-Reset"), "lifetime request limits never reset, whatever the token's expiry")
+
+	validator.maxRequests = nil
+	w = sendChat(h, "gpt-4o-mini", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("X-RateLimit-Remaining"), "unlimited tokens report no limits")
+}
+
+// windowLimitedTokenValidator is a limitedTokenValidator whose tokens also have a rate limit.
+type windowLimitedTokenValidator struct {
+	limitedTokenValidator
+	rateLimit token.RateLimit
+}
+
+func (v *windowLimitedTokenValidator) GetTokenData(ctx context.Context, tokenString string) (token.TokenData, error) {
+	td, err := v.limitedTokenValidator.GetTokenData(ctx, tokenString)
+	td.RateLimit = v.rateLimit
+	return td, err
+}
+
+func TestTransparentProxy_RateLimitHeadersReportClosestLimit(t *testing.T) {
+	upstream := &modelUpstream{}
+	srv := httptest.NewServer(upstream)
+	defer srv.Close()
+
+	maxRequests := 10
+	validator := &windowLimitedTokenValidator{
+		limitedTokenValidator: limitedTokenValidator{maxRequests: &maxRequests, requestCount: 3},
+		rateLimit:             token.RateLimit{RequestsPerWindow: 5, WindowSeconds: 3600},
+	}
+	store := &rateLimitProjectStore{}
+	p := newTestProxy(t, srv.URL, withTokenValidator(validator), withProjectStore(store))
+	p.SetRateLimiter(token.NewRedisRateLimiter(token.NewMemoryRateLimitClient(), token.RedisRateLimiterConfig{DefaultWindowDuration: time.Minute}))
+	h := p.Handler()
+
+	// The token's hourly limit has fewer requests left than its lifetime limit
+	w := sendChat(h, "gpt-4o-mini", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "5", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "4", w.Header().Get("X-RateLimit-Remaining"))
+	reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+	require.NoError(t, err)
+	windowEnd := time.Now().Truncate(time.Hour).Add(time.Hour)
+	assert.Equal(t, windowEnd.Add(time.Hour).Unix(), reset, "the request ages out of the sliding window an hour after its window ends")
+
+	// A project limit closer to exhaustion is reported instead
+	store.limit = token.RateLimit{RequestsPerWindow: 2, WindowSeconds: 60}
+	w = sendChat(h, "gpt-4o-mini", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	// Rejections report the exhausted limit
+	sendChat(h, "gpt-4o-mini", nil)
+	w = sendChat(h, "gpt-4o-mini", nil)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.NotEmpty(t, w.Header().Get("X-RateLimit-Reset"))
+}
+
+func TestCloneHeadersForCache_DropsRateLimitHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Content-Type", "application/json")
+	h.Set("X-RateLimit-Limit", "10")
+	h.Set("X-RateLimit-Remaining", "7")
+	h.Set("X-RateLimit-Reset", "1700000000")
+
+	out := cloneHeadersForCache(h)
+	assert.Equal(t, "application/json", out.Get("Content-Type"))
+	assert.Empty(t, out.Get("X-RateLimit-Limit"))
+	assert.Empty(t, out.Get("X-RateLimit-Remaining"))
+	assert.Empty(t, out.Get("X-RateLimit-Reset"))
+}
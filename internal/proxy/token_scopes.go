@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/logging"
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"go.uber.org/zap"
+)
+
+// enforceTokenRestrictions applies the scopes, bindings and client
+// restrictions of the validated token. Validators that cannot return token data leave tokens unrestricted.
+func (p *TransparentProxy) enforceTokenRestrictions(r *http.Request, tokenStr, projectID string) (*http.Request, int, ErrorResponse) {
+	provider, ok := p.tokenValidator.(token.TokenDataProvider)
+	if !ok {
+		return r, 0, ErrorResponse{}
+	}
+	td, err := provider.GetTokenData(r.Context(), tokenStr)
+	if errors.Is(err, token.ErrTokenDataUnsupported) {
+		return r, 0, ErrorResponse{}
+	}
+	if err != nil {
+		p.logger.Error("Failed to load token data", zap.String("project_id", projectID), zap.Error(err))
+		return r, http.StatusServiceUnavailable, ErrorResponse{Error: "Token restrictions unavailable", Code: "policy_unavailable"}
+	}
+
+	setCostAccountToken(r, td)
+
+	r, status, er := p.enforceTokenScopes(r, td, projectID)
+	if status != 0 {
+		return r, status, er
+	}
+	if status, er = p.enforceTokenBindings(r, td, projectID); status != 0 {
+		return r, status, er
+	}
+	if status, er = p.enforceClientRestrictions(r, td.ClientRestrictions, td, projectID, "token"); status != 0 {
+		return r, status, er
+	}
+	return withTokenRateLimit(withTokenLimits(r, td), td), 0, ErrorResponse{}
+}
+
+// enforceTokenScopes rejects requests outside the token's scopes. Model scopes
+// are stored in the request context so fallback chains skip models the token
+// may not use.
+func (p *TransparentProxy) enforceTokenScopes(r *http.Request, td token.TokenData, projectID string) (*http.Request, int, ErrorResponse) {
+	scopes := td.Scopes
+	if scopes.IsEmpty() {
+		return r, 0, ErrorResponse{}
+	}
+	if len(scopes.Models) > 0 {
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeyTokenModels, scopes.Models))
+	}
+
+	endpoint := r.URL.Path
+	model := requestModel(r)
+
+	var reason, description string
+	switch {
+	case !scopes.AllowsMethod(r.Method):
+		reason = "method_not_in_scope"
+		description = fmt.Sprintf("method %s is not allowed for this token (allowed: %s)", r.Method, strings.Join(scopes.Methods, ", "))
+	case !scopes.AllowsEndpoint(endpoint):
+		reason = "endpoint_not_in_scope"
+		description = fmt.Sprintf("endpoint %s is not allowed for this token (allowed: %s)", endpoint, strings.Join(scopes.Endpoints, ", "))
+	case model != "" && !scopes.AllowsModel(model):
+		reason = "model_not_in_scope"
+		description = fmt.Sprintf("model '%s' is not allowed for this token (allowed: %s)", model, strings.Join(scopes.Models, ", "))
+	default:
+		return r, 0, ErrorResponse{}
+	}
+
+	details := map[string]any{}
+	if model != "" {
+		details["model"] = model
+	}
+	p.auditTokenDenial(r, td, projectID, "token_scopes", reason, details)
+	return r, http.StatusForbidden, ErrorResponse{
+		Error:       "Token scope denied",
+		Code:        "token_scope_denied",
+		Description: description,
+	}
+}
+
+// auditTokenDenial logs and audits a request rejected by a token or project
+// restriction. td is the zero value for project restrictions.
+func (p *TransparentProxy) auditTokenDenial(r *http.Request, td token.TokenData, projectID, policy, reason string, details map[string]any) {
+	requestID, _ := logging.GetRequestID(r.Context())
+	p.logger.Warn("Request denied by token restriction",
+		zap.String("request_id", requestID),
+		zap.String("project_id", projectID),
+		zap.String("policy", policy),
+		zap.String("reason", reason),
+	)
+	if p.auditLogger == nil {
+		return
+	}
+	auditEvent := audit.NewEvent(audit.ActionProxyPolicyViolation, audit.ActorSystem, audit.ResultDenied).
+		WithProjectID(projectID).
+		WithRequestID(requestID).
+		WithClientIP(getClientIP(r)).
+		WithUserAgent(r.UserAgent()).
+		WithHTTPMethod(r.Method).
+		WithEndpoint(r.URL.Path).
+		WithReason(reason).
+		WithDetail("provider", p.providerName()).
+		WithDetail("policy", policy)
+	if td.ID != "" {
+		auditEvent.WithTokenID(td.ID)
+	}
+	for k, v := range details {
+		auditEvent.WithDetail(k, v)
+	}
+	if err := p.auditLogger.Log(auditEvent); err != nil {
+		p.logger.Warn("Failed to audit policy violation", zap.String("request_id", requestID), zap.Error(err))
+	}
+}
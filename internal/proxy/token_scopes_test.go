@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sofatutor/llm-proxy/internal/audit"
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scopedTokenValidator is a stubTokenValidator that returns token data.
+type scopedTokenValidator struct {
+	stubTokenValidator
+	scopes token.Scopes
+	err    error
+}
+
+func (v *scopedTokenValidator) GetTokenData(ctx context.Context, tokenString string) (token.TokenData, error) {
+	return token.TokenData{ID: "tok-id", Token: tokenString, ProjectID: "test-project-id", IsActive: true, Scopes: v.scopes}, v.err
+}
+
+func TestTransparentProxy_TokenScopes(t *testing.T) {
+	upstream := &modelUpstream{}
+	collector := &SimpleAuditCollector{}
+	validator := &scopedTokenValidator{scopes: token.Scopes{
+		Endpoints: []string{"/v1/chat/completions"},
+		Methods:   []string{http.MethodPost},
+		Models:    []string{"gpt-4o-mini"},
+	}}
+	h := newTestProxy(t, startUpstream(t, upstream.ServeHTTP), withTokenValidator(validator), withAuditLogger(collector)).Handler()
+
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o-mini", nil).Code)
+
+	w := sendChat(h, "gpt-4o", nil)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "token_scope_denied")
+	assert.Contains(t, w.Body.String(), "model 'gpt-4o' is not allowed for this token")
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "method GET is not allowed for this token")
+
+	validator.scopes.Methods = nil
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "endpoint /v1/models is not allowed for this token")
+
+	assert.Equal(t, []string{"gpt-4o-mini"}, upstream.seen())
+
+	events := policyViolations(collector)
+	require.Len(t, events, 3)
+	for _, evt := range events {
+		assert.Equal(t, audit.ResultDenied, evt.Result)
+		assert.Equal(t, "token_scopes", evt.Details["policy"])
+	}
+	assert.Equal(t, "model_not_in_scope", events[0].Details["reason"])
+	assert.Equal(t, "method_not_in_scope", events[1].Details["reason"])
+	assert.Equal(t, "endpoint_not_in_scope", events[2].Details["reason"])
+
+	validator.err = errors.New("db down")
+	assert.Equal(t, http.StatusServiceUnavailable, sendChat(h, "gpt-4o-mini", nil).Code)
+
+	validator.err = token.ErrTokenDataUnsupported
+	assert.Equal(t, http.StatusOK, sendChat(h, "gpt-4o", nil).Code, "validators without token data leave tokens unrestricted")
+}
+
+func TestTransparentProxy_TokenScopes_FallbackSkipsModelsOutOfScope(t *testing.T) {
+	upstream := &modelUpstream{status: map[string]int{"gpt-4o-mini": http.StatusServiceUnavailable}}
+	validator := &scopedTokenValidator{scopes: token.Scopes{Models: []string{"gpt-4o-mini", "gpt-4.1-*"}}}
+	h := newTestProxy(t, startUpstream(t, upstream.ServeHTTP), withTokenValidator(validator), withConfig(func(cfg *ProxyConfig) {
+		cfg.Fallback = FallbackPolicy{
+			On: []string{FallbackOn5xx},
+			Chains: map[string][]FallbackTarget{"gpt-4o-mini": {
+				{Provider: "openai", Model: "gpt-4o"},
+				{Provider: "openai", Model: "gpt-4.1-mini"},
+			}},
+		}
+	})).Handler()
+
+	w := sendChat(h, "gpt-4o-mini", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"gpt-4o-mini", "gpt-4.1-mini"}, upstream.seen())
+}
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/eventtransformer"
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"go.uber.org/zap"
+)
+
+// SetTPMLimiter sets the limiter that enforces tokens-per-minute limits.
+func (p *TransparentProxy) SetTPMLimiter(l *token.TPMLimiter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tpm = l
+}
+
+// reserveTPM reserves the estimated tokens of a request against its TPM
+// limits: the prompt plus the requested completion tokens. The reservation is
+// reconciled with actual usage once the response is complete. It writes a 429
+// and returns false when a limit is exhausted.
+func (p *TransparentProxy) reserveTPM(w http.ResponseWriter, r *http.Request) bool {
+	if p.tpm == nil {
+		return true
+	}
+	acct, ok := r.Context().Value(ctxKeyCostAccount).(*costAccount)
+	if !ok || acct.tpm != nil {
+		return true
+	}
+	// Every request counts at least one token so exhausted limits hold
+	estimate := max(int64(eventtransformer.EstimateRequestTokens(acct.requestBody)), 1)
+	res, err := p.tpm.Reserve(r.Context(), acct.projectID, acct.tokenID, estimate)
+	var limitErr *token.TPMLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		p.logger.Info("Tokens per minute limit exceeded",
+			zap.String("project_id", acct.projectID),
+			zap.String("scope", limitErr.Scope),
+			zap.Int64("limit", limitErr.Limit),
+			zap.Int64("estimated_tokens", estimate))
+		retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		writeErrorResponseForRequest(w, r, http.StatusTooManyRequests, ErrorResponse{
+			Error:       "Rate limit exceeded",
+			Code:        "tpm_limit_exceeded",
+			Description: "tokens per minute limit of the " + limitErr.Scope + " exhausted",
+		})
+		return false
+	case err != nil:
+		p.logger.Error("Failed to reserve tokens", zap.String("project_id", acct.projectID), zap.Error(err))
+		writeErrorResponseForRequest(w, r, http.StatusServiceUnavailable, ErrorResponse{
+			Error: "Rate limit unavailable",
+			Code:  "rate_limit_unavailable",
+		})
+		return false
+	}
+	acct.tpm = res
+	return true
+}
+
+// releaseTPM takes back the reservation of a request that failed.
+func releaseTPM(acct *costAccount) {
+	if acct.tpm != nil {
+		reconcileTPM(acct.tpm, 0)
+	}
+}
+
+// reconcileTPM makes a reservation count actual tokens. It runs off the
+// request context, which may already be canceled.
+func reconcileTPM(res *token.TPMReservation, actual int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_ = res.Reconcile(ctx, actual) // Lossy: the window expires within minutes
+}
@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransparentProxy_EnforcesTPMLimits(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"message":"boom"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"gpt-4o-mini","choices":[],"usage":{"prompt_tokens":20,"completion_tokens":10}}`))
+	}))
+	defer upstream.Close()
+
+	limiter := token.NewTPMLimiter(token.NewMemoryTPMStore(), token.TPMLimiterConfig{Limits: token.TPMLimits{PerToken: 1000}})
+	p := newTestProxy(t, upstream.URL, withTokenValidator(&limitedTokenValidator{}))
+	p.SetTPMLimiter(limiter)
+	h := p.Handler()
+
+	send := func(maxTokens string, fail bool) *httptest.ResponseRecorder {
+		body := `{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}],"max_tokens":` + maxTokens + `}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok")
+		req.Header.Set("Content-Type", "application/json")
+		if fail {
+			req.Header.Set("X-Fail", "1")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	remaining := func() int64 {
+		n, _, err := limiter.Remaining(context.Background(), "test-project-id", "tok-id")
+		require.NoError(t, err)
+		return n
+	}
+
+	// Counts reset each minute; keep the test within one window
+	if left := time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)); left < 5*time.Second {
+		time.Sleep(left)
+	}
+
+	// The max_tokens reservation is replaced by the reported usage
+	require.Equal(t, http.StatusOK, send("500", false).Code)
+	require.Eventually(t, func() bool { return remaining() == 970 }, 2*time.Second, 10*time.Millisecond)
+
+	// Failed requests release their reservation
+	require.Equal(t, http.StatusInternalServerError, send("500", true).Code)
+	require.Eventually(t, func() bool { return remaining() == 970 }, 2*time.Second, 10*time.Millisecond)
+
+	w := send("2000", false)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "tpm_limit_exceeded", resp.Code)
+	assert.Contains(t, resp.Description, "token")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, int64(970), remaining(), "rejected requests reserve nothing")
+}
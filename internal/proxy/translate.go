@@ -0,0 +1,417 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Provider modes that translate OpenAI chat completions (APIProviderConfig.Mode).
+const (
+	// ProviderModeAnthropic serves /v1/chat/completions from the Anthropic Messages API.
+	ProviderModeAnthropic = "anthropic"
+	// ProviderModeGemini serves /v1/chat/completions from Gemini generateContent.
+	ProviderModeGemini = "gemini"
+)
+
+// defaultAnthropicVersion is the anthropic-version header sent in anthropic mode
+// unless the auth config sets one.
+const defaultAnthropicVersion = "2023-06-01"
+
+// translatedChatPath is the OpenAI endpoint that translating modes convert.
+const translatedChatPath = "/v1/chat/completions"
+
+// defaultTranslatedMaxTokens is sent to backends that require an output limit
+// when the OpenAI request does not set one.
+const defaultTranslatedMaxTokens = 4096
+
+// errTranslation marks client requests that cannot be translated.
+var errTranslation = errors.New("cannot translate request")
+
+// isTranslatingMode reports whether mode converts OpenAI chat completions.
+func isTranslatingMode(mode string) bool {
+	return mode == ProviderModeAnthropic || mode == ProviderModeGemini
+}
+
+// chatTranslation carries the per-request state for serving an OpenAI chat
+// completion from a non-OpenAI backend.
+type chatTranslation struct {
+	mode         string
+	model        string
+	stream       bool
+	includeUsage bool
+	created      int64
+
+	// Upstream request produced by the translation
+	path  string
+	query url.Values
+	body  []byte
+}
+
+// OpenAI chat-completions request schema (the subset that is translated).
+type openAIChatRequest struct {
+	Model               string              `json:"model"`
+	Messages            []openAIChatMessage `json:"messages"`
+	MaxTokens           *int                `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int                `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64            `json:"temperature,omitempty"`
+	TopP                *float64            `json:"top_p,omitempty"`
+	N                   *int                `json:"n,omitempty"`
+	Stop                json.RawMessage     `json:"stop,omitempty"`
+	Stream              bool                `json:"stream,omitempty"`
+	StreamOptions       *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+	Tools      []openAITool    `json:"tools,omitempty"`
+	ToolChoice json.RawMessage `json:"tool_choice,omitempty"`
+	User       string          `json:"user,omitempty"`
+}
+
+type openAIChatMessage struct {
+	Role       string           `json:"role"`
+	Content    json.RawMessage  `json:"content,omitempty"`
+	Name       string           `json:"name,omitempty"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url,omitempty"`
+}
+
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters,omitempty"`
+	} `json:"function"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// newChatTranslation converts an OpenAI chat-completions body for mode.
+func newChatTranslation(mode string, body []byte) (*chatTranslation, error) {
+	var req openAIChatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON: %v", errTranslation, err)
+	}
+	if req.Model == "" {
+		return nil, fmt.Errorf("%w: model is required", errTranslation)
+	}
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("%w: messages is required", errTranslation)
+	}
+	t := &chatTranslation{
+		mode:         mode,
+		model:        req.Model,
+		stream:       req.Stream,
+		includeUsage: req.StreamOptions != nil && req.StreamOptions.IncludeUsage,
+		created:      time.Now().Unix(),
+		query:        url.Values{},
+	}
+	var err error
+	switch mode {
+	case ProviderModeAnthropic:
+		err = t.buildAnthropicRequest(&req)
+	case ProviderModeGemini:
+		err = t.buildGeminiRequest(&req)
+	default:
+		err = fmt.Errorf("%w: unsupported mode %q", errTranslation, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// prepareChatTranslation reads and translates a chat-completions request for
+// translating modes. The client body is restored so whitelisting, caching and
+// observability keep seeing the OpenAI request.
+func (p *TransparentProxy) prepareChatTranslation(r *http.Request) (*chatTranslation, error) {
+	if r.Method != http.MethodPost || r.URL.Path != translatedChatPath || r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errTranslation, err)
+	}
+	return newChatTranslation(p.config.Mode, body)
+}
+
+// applyChatTranslation replaces the outgoing request with the translated one.
+func applyChatTranslation(req *http.Request, t *chatTranslation) {
+	req.URL.Path = t.path
+	req.URL.RawPath = ""
+	if len(t.query) > 0 {
+		q := req.URL.Query()
+		for k, vs := range t.query {
+			q[k] = vs
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	req.Body = io.NopCloser(bytes.NewReader(t.body))
+	req.ContentLength = int64(len(t.body))
+	req.Header.Set("Content-Length", strconv.Itoa(len(t.body)))
+	req.Header.Set("Content-Type", "application/json")
+	// Let the transport negotiate (and transparently decode) compression so the
+	// response can be translated.
+	req.Header.Del("Accept-Encoding")
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(t.body)), nil
+	}
+}
+
+// translateResponse converts an upstream response into the OpenAI format.
+// Streaming bodies are converted incrementally as the client reads them.
+func (t *chatTranslation) translateResponse(res *http.Response) error {
+	if t.stream && res.StatusCode < 300 && isStreaming(res) {
+		res.Body = newSSETranslator(res.Body, t.newStreamConverter())
+		res.ContentLength = -1
+		res.Header.Del("Content-Length")
+		res.Header.Del("Content-Encoding")
+		res.Header.Set("Content-Type", "text/event-stream")
+		return nil
+	}
+
+	body, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return err
+	}
+	var out []byte
+	if res.StatusCode >= 300 {
+		out = t.translateError(res.StatusCode, body)
+	} else {
+		out, err = t.translateCompletion(body)
+		if err != nil {
+			out = openAIError(http.StatusBadGateway, "upstream_translation_error", err.Error())
+			res.StatusCode = http.StatusBadGateway
+			res.Status = http.StatusText(http.StatusBadGateway)
+		}
+	}
+	res.Body = io.NopCloser(bytes.NewReader(out))
+	res.ContentLength = int64(len(out))
+	res.Header.Set("Content-Length", strconv.Itoa(len(out)))
+	res.Header.Del("Content-Encoding")
+	res.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+func (t *chatTranslation) translateCompletion(body []byte) ([]byte, error) {
+	switch t.mode {
+	case ProviderModeAnthropic:
+		return t.anthropicCompletion(body)
+	default:
+		return t.geminiCompletion(body)
+	}
+}
+
+func (t *chatTranslation) newStreamConverter() sseConverter {
+	switch t.mode {
+	case ProviderModeAnthropic:
+		return &anthropicStreamConverter{t: t}
+	default:
+		return &geminiStreamConverter{t: t}
+	}
+}
+
+// translateError maps Anthropic ({"error":{"type","message"}}) and Gemini
+// ({"error":{"code","message","status"}}) errors to the OpenAI error shape.
+func (t *chatTranslation) translateError(status int, body []byte) []byte {
+	var upstream struct {
+		Error struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	msg := strings.TrimSpace(string(body))
+	errType := "upstream_error"
+	if json.Unmarshal(body, &upstream) == nil && upstream.Error.Message != "" {
+		msg = upstream.Error.Message
+		switch {
+		case upstream.Error.Type != "":
+			errType = upstream.Error.Type
+		case upstream.Error.Status != "":
+			errType = strings.ToLower(upstream.Error.Status)
+		}
+	}
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	return openAIError(status, errType, msg)
+}
+
+func openAIError(status int, errType, message string) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"error": map[string]any{
+			"message": message,
+			"type":    errType,
+			"code":    status,
+		},
+	})
+	return b
+}
+
+// openAICompletion renders a chat.completion response.
+func (t *chatTranslation) openAICompletion(id string, message map[string]any, finish string, usage *openAIUsage) ([]byte, error) {
+	if id == "" {
+		id = "chatcmpl-" + uuid.NewString()
+	}
+	resp := map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": t.created,
+		"model":   t.model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       message,
+			"finish_reason": finish,
+		}},
+	}
+	if usage != nil {
+		resp["usage"] = usage
+	}
+	return json.Marshal(resp)
+}
+
+// openAIChunk renders one chat.completion.chunk. finish is omitted (null)
+// when empty.
+func (t *chatTranslation) openAIChunk(id string, delta map[string]any, finish string) []byte {
+	var fr any
+	if finish != "" {
+		fr = finish
+	}
+	b, _ := json.Marshal(map[string]any{
+		"id":      id,
+		"object":  "chat.completion.chunk",
+		"created": t.created,
+		"model":   t.model,
+		"choices": []any{map[string]any{
+			"index":         0,
+			"delta":         delta,
+			"finish_reason": fr,
+		}},
+	})
+	return b
+}
+
+// openAIUsageChunk renders the trailing usage chunk sent when the client asked
+// for stream_options.include_usage.
+func (t *chatTranslation) openAIUsageChunk(id string, usage openAIUsage) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"id":      id,
+		"object":  "chat.completion.chunk",
+		"created": t.created,
+		"model":   t.model,
+		"choices": []any{},
+		"usage":   usage,
+	})
+	return b
+}
+
+// messageText flattens OpenAI message content (string or parts) into text.
+func messageText(raw json.RawMessage) string {
+	parts := contentParts(raw)
+	var sb strings.Builder
+	for _, p := range parts {
+		if p.Type == "text" {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String()
+}
+
+// contentParts normalizes OpenAI message content into parts.
+func contentParts(raw json.RawMessage) []openAIContentPart {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []openAIContentPart{{Type: "text", Text: s}}
+	}
+	var parts []openAIContentPart
+	_ = json.Unmarshal(raw, &parts)
+	return parts
+}
+
+// stopSequences decodes the OpenAI stop parameter (string or array).
+func stopSequences(raw json.RawMessage) []string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+	var list []string
+	_ = json.Unmarshal(raw, &list)
+	return list
+}
+
+// parseDataURL splits a base64 data URL into media type and payload.
+func parseDataURL(u string) (mediaType, data string, ok bool) {
+	rest, found := strings.CutPrefix(u, "data:")
+	if !found {
+		return "", "", false
+	}
+	meta, payload, found := strings.Cut(rest, ",")
+	if !found || !strings.HasSuffix(meta, ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(meta, ";base64"), payload, true
+}
+
+// maxOutputTokens returns the request's output token limit, if any.
+func (r *openAIChatRequest) maxOutputTokens() *int {
+	if r.MaxCompletionTokens != nil {
+		return r.MaxCompletionTokens
+	}
+	return r.MaxTokens
+}
+
+// toolArguments decodes OpenAI function-call arguments (a JSON string).
+func toolArguments(args string) any {
+	if strings.TrimSpace(args) == "" {
+		return map[string]any{}
+	}
+	var v any
+	if json.Unmarshal([]byte(args), &v) != nil {
+		return map[string]any{}
+	}
+	return v
+}
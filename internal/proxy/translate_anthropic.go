@@ -0,0 +1,348 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// buildAnthropicRequest converts an OpenAI chat request into a Messages API request.
+func (t *chatTranslation) buildAnthropicRequest(req *openAIChatRequest) error {
+	out := map[string]any{
+		"model":      req.Model,
+		"max_tokens": defaultTranslatedMaxTokens,
+	}
+	if mt := req.maxOutputTokens(); mt != nil {
+		out["max_tokens"] = *mt
+	}
+
+	var system []string
+	var messages []map[string]any
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if text := messageText(m.Content); text != "" {
+				system = append(system, text)
+			}
+		case "user":
+			messages = appendAnthropicMessage(messages, "user", anthropicContent(m.Content))
+		case "assistant":
+			blocks := anthropicContent(m.Content)
+			for _, tc := range m.ToolCalls {
+				blocks = append(blocks, map[string]any{
+					"type":  "tool_use",
+					"id":    tc.ID,
+					"name":  tc.Function.Name,
+					"input": toolArguments(tc.Function.Arguments),
+				})
+			}
+			messages = appendAnthropicMessage(messages, "assistant", blocks)
+		case "tool":
+			messages = appendAnthropicMessage(messages, "user", []map[string]any{{
+				"type":        "tool_result",
+				"tool_use_id": m.ToolCallID,
+				"content":     messageText(m.Content),
+			}})
+		default:
+			return fmt.Errorf("%w: unsupported message role %q", errTranslation, m.Role)
+		}
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("%w: at least one user message is required", errTranslation)
+	}
+	out["messages"] = messages
+	if len(system) > 0 {
+		out["system"] = strings.Join(system, "\n\n")
+	}
+	if req.Temperature != nil {
+		out["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		out["top_p"] = *req.TopP
+	}
+	if stop := stopSequences(req.Stop); len(stop) > 0 {
+		out["stop_sequences"] = stop
+	}
+	if req.Stream {
+		out["stream"] = true
+	}
+	if req.User != "" {
+		out["metadata"] = map[string]any{"user_id": req.User}
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			schema := any(map[string]any{"type": "object"})
+			if len(tool.Function.Parameters) > 0 {
+				schema = tool.Function.Parameters
+			}
+			tools = append(tools, map[string]any{
+				"name":         tool.Function.Name,
+				"description":  tool.Function.Description,
+				"input_schema": schema,
+			})
+		}
+		out["tools"] = tools
+	}
+	if choice := anthropicToolChoice(req.ToolChoice); choice != nil {
+		out["tool_choice"] = choice
+	}
+
+	body, err := json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errTranslation, err)
+	}
+	t.path = "/v1/messages"
+	t.body = body
+	return nil
+}
+
+// appendAnthropicMessage appends blocks for role, merging consecutive messages
+// of the same role since the Messages API requires alternating turns.
+func appendAnthropicMessage(messages []map[string]any, role string, blocks []map[string]any) []map[string]any {
+	if len(blocks) == 0 {
+		return messages
+	}
+	if n := len(messages); n > 0 && messages[n-1]["role"] == role {
+		prev := messages[n-1]["content"].([]map[string]any)
+		messages[n-1]["content"] = append(prev, blocks...)
+		return messages
+	}
+	return append(messages, map[string]any{"role": role, "content": blocks})
+}
+
+// anthropicContent converts OpenAI content parts into Messages content blocks.
+func anthropicContent(raw json.RawMessage) []map[string]any {
+	var blocks []map[string]any
+	for _, part := range contentParts(raw) {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": part.Text})
+			}
+		case "image_url":
+			if part.ImageURL == nil {
+				continue
+			}
+			source := map[string]any{"type": "url", "url": part.ImageURL.URL}
+			if mediaType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				source = map[string]any{"type": "base64", "media_type": mediaType, "data": data}
+			}
+			blocks = append(blocks, map[string]any{"type": "image", "source": source})
+		}
+	}
+	return blocks
+}
+
+// anthropicToolChoice maps OpenAI tool_choice to the Messages API.
+func anthropicToolChoice(raw json.RawMessage) map[string]any {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		switch s {
+		case "auto":
+			return map[string]any{"type": "auto"}
+		case "required":
+			return map[string]any{"type": "any"}
+		case "none":
+			return map[string]any{"type": "none"}
+		}
+		return nil
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if json.Unmarshal(raw, &named) == nil && named.Function.Name != "" {
+		return map[string]any{"type": "tool", "name": named.Function.Name}
+	}
+	return nil
+}
+
+// anthropicFinishReason maps Messages stop reasons to OpenAI finish reasons.
+func anthropicFinishReason(stop string) string {
+	switch stop {
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+type anthropicMessageResponse struct {
+	ID      string `json:"id"`
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// anthropicCompletion converts a Messages API response into a chat.completion.
+func (t *chatTranslation) anthropicCompletion(body []byte) ([]byte, error) {
+	var resp anthropicMessageResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid Anthropic response: %w", err)
+	}
+	var text strings.Builder
+	var toolCalls []map[string]any
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			args := string(block.Input)
+			if args == "" {
+				args = "{}"
+			}
+			toolCalls = append(toolCalls, map[string]any{
+				"id":       block.ID,
+				"type":     "function",
+				"function": map[string]any{"name": block.Name, "arguments": args},
+			})
+		}
+	}
+	message := map[string]any{"role": "assistant", "content": text.String()}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+		if text.Len() == 0 {
+			message["content"] = nil
+		}
+	}
+	usage := &openAIUsage{
+		PromptTokens:     resp.Usage.InputTokens,
+		CompletionTokens: resp.Usage.OutputTokens,
+		TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+	}
+	id := ""
+	if resp.ID != "" {
+		id = "chatcmpl-" + resp.ID
+	}
+	return t.openAICompletion(id, message, anthropicFinishReason(resp.StopReason), usage)
+}
+
+// anthropicStreamConverter converts Messages API stream events into OpenAI
+// chat.completion.chunk payloads.
+type anthropicStreamConverter struct {
+	t         *chatTranslation
+	id        string
+	usage     openAIUsage
+	toolIndex map[int]int // content block index -> tool_calls index
+	done      bool
+}
+
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		ID    string `json:"id"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *anthropicStreamConverter) event(_ string, data []byte) [][]byte {
+	var ev anthropicStreamEvent
+	if json.Unmarshal(data, &ev) != nil {
+		return nil
+	}
+	switch ev.Type {
+	case "message_start":
+		c.id = "chatcmpl-" + ev.Message.ID
+		c.usage.PromptTokens = ev.Message.Usage.InputTokens
+		c.usage.CompletionTokens = ev.Message.Usage.OutputTokens
+		return [][]byte{c.t.openAIChunk(c.id, map[string]any{"role": "assistant", "content": ""}, "")}
+	case "content_block_start":
+		if ev.ContentBlock.Type != "tool_use" {
+			return nil
+		}
+		if c.toolIndex == nil {
+			c.toolIndex = map[int]int{}
+		}
+		idx := len(c.toolIndex)
+		c.toolIndex[ev.Index] = idx
+		return [][]byte{c.t.openAIChunk(c.id, map[string]any{"tool_calls": []any{map[string]any{
+			"index":    idx,
+			"id":       ev.ContentBlock.ID,
+			"type":     "function",
+			"function": map[string]any{"name": ev.ContentBlock.Name, "arguments": ""},
+		}}}, "")}
+	case "content_block_delta":
+		switch ev.Delta.Type {
+		case "text_delta":
+			return [][]byte{c.t.openAIChunk(c.id, map[string]any{"content": ev.Delta.Text}, "")}
+		case "input_json_delta":
+			idx, ok := c.toolIndex[ev.Index]
+			if !ok {
+				return nil
+			}
+			return [][]byte{c.t.openAIChunk(c.id, map[string]any{"tool_calls": []any{map[string]any{
+				"index":    idx,
+				"function": map[string]any{"arguments": ev.Delta.PartialJSON},
+			}}}, "")}
+		}
+	case "message_delta":
+		if ev.Usage.OutputTokens > 0 {
+			c.usage.CompletionTokens = ev.Usage.OutputTokens
+		}
+		if ev.Delta.StopReason != "" {
+			return [][]byte{c.t.openAIChunk(c.id, map[string]any{}, anthropicFinishReason(ev.Delta.StopReason))}
+		}
+	case "message_stop":
+		return c.end()
+	case "error":
+		return [][]byte{openAIError(0, ev.Error.Type, ev.Error.Message)}
+	}
+	return nil
+}
+
+func (c *anthropicStreamConverter) finish() [][]byte {
+	return c.end()
+}
+
+func (c *anthropicStreamConverter) end() [][]byte {
+	if c.done {
+		return nil
+	}
+	c.done = true
+	var out [][]byte
+	if c.t.includeUsage {
+		c.usage.TotalTokens = c.usage.PromptTokens + c.usage.CompletionTokens
+		out = append(out, c.t.openAIUsageChunk(c.id, c.usage))
+	}
+	return append(out, []byte("[DONE]"))
+}
@@ -0,0 +1,405 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// buildGeminiRequest converts an OpenAI chat request into a generateContent
+// (or streamGenerateContent) request.
+func (t *chatTranslation) buildGeminiRequest(req *openAIChatRequest) error {
+	var system []string
+	var contents []map[string]any
+	// Gemini matches function responses by name, OpenAI by tool_call_id.
+	toolNames := map[string]string{}
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system", "developer":
+			if text := messageText(m.Content); text != "" {
+				system = append(system, text)
+			}
+		case "user":
+			contents = appendGeminiContent(contents, "user", geminiParts(m.Content))
+		case "assistant":
+			parts := geminiParts(m.Content)
+			for _, tc := range m.ToolCalls {
+				toolNames[tc.ID] = tc.Function.Name
+				parts = append(parts, map[string]any{"functionCall": map[string]any{
+					"name": tc.Function.Name,
+					"args": toolArguments(tc.Function.Arguments),
+				}})
+			}
+			contents = appendGeminiContent(contents, "model", parts)
+		case "tool":
+			name := toolNames[m.ToolCallID]
+			if name == "" {
+				name = m.Name
+			}
+			if name == "" {
+				return fmt.Errorf("%w: tool message %q does not match a previous tool call", errTranslation, m.ToolCallID)
+			}
+			contents = appendGeminiContent(contents, "user", []map[string]any{{"functionResponse": map[string]any{
+				"name":     name,
+				"response": geminiFunctionResponse(messageText(m.Content)),
+			}}})
+		default:
+			return fmt.Errorf("%w: unsupported message role %q", errTranslation, m.Role)
+		}
+	}
+	if len(contents) == 0 {
+		return fmt.Errorf("%w: at least one user message is required", errTranslation)
+	}
+
+	out := map[string]any{"contents": contents}
+	if len(system) > 0 {
+		out["systemInstruction"] = map[string]any{"parts": []any{map[string]any{"text": strings.Join(system, "\n\n")}}}
+	}
+	gen := map[string]any{}
+	if req.Temperature != nil {
+		gen["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		gen["topP"] = *req.TopP
+	}
+	if mt := req.maxOutputTokens(); mt != nil {
+		gen["maxOutputTokens"] = *mt
+	}
+	if stop := stopSequences(req.Stop); len(stop) > 0 {
+		gen["stopSequences"] = stop
+	}
+	if req.N != nil && *req.N > 1 {
+		gen["candidateCount"] = *req.N
+	}
+	if len(gen) > 0 {
+		out["generationConfig"] = gen
+	}
+	if len(req.Tools) > 0 {
+		decls := make([]map[string]any, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			decl := map[string]any{"name": tool.Function.Name}
+			if tool.Function.Description != "" {
+				decl["description"] = tool.Function.Description
+			}
+			if len(tool.Function.Parameters) > 0 {
+				var schema any
+				if err := json.Unmarshal(tool.Function.Parameters, &schema); err != nil {
+					return fmt.Errorf("%w: invalid parameters for tool %q", errTranslation, tool.Function.Name)
+				}
+				decl["parameters"] = geminiSchema(schema)
+			}
+			decls = append(decls, decl)
+		}
+		out["tools"] = []any{map[string]any{"functionDeclarations": decls}}
+	}
+	if cfg := geminiToolConfig(req.ToolChoice); cfg != nil {
+		out["toolConfig"] = cfg
+	}
+
+	body, err := json.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errTranslation, err)
+	}
+	t.path = "/v1beta/models/" + req.Model + ":generateContent"
+	if req.Stream {
+		t.path = "/v1beta/models/" + req.Model + ":streamGenerateContent"
+		t.query.Set("alt", "sse")
+	}
+	t.body = body
+	return nil
+}
+
+// appendGeminiContent appends parts for role, merging consecutive turns of the
+// same role (e.g. several tool results).
+func appendGeminiContent(contents []map[string]any, role string, parts []map[string]any) []map[string]any {
+	if len(parts) == 0 {
+		return contents
+	}
+	if n := len(contents); n > 0 && contents[n-1]["role"] == role {
+		prev := contents[n-1]["parts"].([]map[string]any)
+		contents[n-1]["parts"] = append(prev, parts...)
+		return contents
+	}
+	return append(contents, map[string]any{"role": role, "parts": parts})
+}
+
+// geminiParts converts OpenAI content parts into Gemini parts.
+func geminiParts(raw json.RawMessage) []map[string]any {
+	var parts []map[string]any
+	for _, part := range contentParts(raw) {
+		switch part.Type {
+		case "text":
+			if part.Text != "" {
+				parts = append(parts, map[string]any{"text": part.Text})
+			}
+		case "image_url":
+			if part.ImageURL == nil {
+				continue
+			}
+			if mediaType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				parts = append(parts, map[string]any{"inlineData": map[string]any{"mimeType": mediaType, "data": data}})
+				continue
+			}
+			file := map[string]any{"fileUri": part.ImageURL.URL}
+			if mediaType := mime.TypeByExtension(path.Ext(part.ImageURL.URL)); mediaType != "" {
+				file["mimeType"] = mediaType
+			}
+			parts = append(parts, map[string]any{"fileData": file})
+		}
+	}
+	return parts
+}
+
+// geminiFunctionResponse wraps a tool result; Gemini requires an object.
+func geminiFunctionResponse(text string) map[string]any {
+	var obj map[string]any
+	if json.Unmarshal([]byte(text), &obj) == nil && obj != nil {
+		return obj
+	}
+	return map[string]any{"content": text}
+}
+
+// geminiSchema drops JSON Schema keywords that Gemini function declarations
+// reject.
+func geminiSchema(v any) any {
+	switch s := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(s))
+		for k, val := range s {
+			if k == "$schema" || k == "additionalProperties" {
+				continue
+			}
+			out[k] = geminiSchema(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(s))
+		for i, val := range s {
+			out[i] = geminiSchema(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// geminiToolConfig maps OpenAI tool_choice to a Gemini toolConfig.
+func geminiToolConfig(raw json.RawMessage) map[string]any {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	fc := map[string]any{}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		switch s {
+		case "auto":
+			fc["mode"] = "AUTO"
+		case "required":
+			fc["mode"] = "ANY"
+		case "none":
+			fc["mode"] = "NONE"
+		default:
+			return nil
+		}
+	} else {
+		var named struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		}
+		if json.Unmarshal(raw, &named) != nil || named.Function.Name == "" {
+			return nil
+		}
+		fc["mode"] = "ANY"
+		fc["allowedFunctionNames"] = []string{named.Function.Name}
+	}
+	return map[string]any{"functionCallingConfig": fc}
+}
+
+// geminiFinishReason maps Gemini finish reasons to OpenAI finish reasons.
+func geminiFinishReason(reason string) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+type geminiResponse struct {
+	ResponseID string `json:"responseId"`
+	Candidates []struct {
+		Index   int `json:"index"`
+		Content struct {
+			Parts []struct {
+				Text         string `json:"text"`
+				Thought      bool   `json:"thought"`
+				FunctionCall *struct {
+					Name string          `json:"name"`
+					Args json.RawMessage `json:"args"`
+				} `json:"functionCall"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+func (r *geminiResponse) usage() *openAIUsage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	u := &openAIUsage{
+		PromptTokens:     r.UsageMetadata.PromptTokenCount,
+		CompletionTokens: r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      r.UsageMetadata.TotalTokenCount,
+	}
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	return u
+}
+
+// geminiCompletion converts a generateContent response into a chat.completion.
+func (t *chatTranslation) geminiCompletion(body []byte) ([]byte, error) {
+	var resp geminiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid Gemini response: %w", err)
+	}
+	finish := "stop"
+	var text strings.Builder
+	var toolCalls []map[string]any
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			finish = "content_filter"
+		}
+	} else {
+		cand := resp.Candidates[0]
+		finish = geminiFinishReason(cand.FinishReason)
+		for _, part := range cand.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				toolCalls = append(toolCalls, geminiToolCall(part.FunctionCall.Name, part.FunctionCall.Args))
+			case !part.Thought:
+				text.WriteString(part.Text)
+			}
+		}
+	}
+	message := map[string]any{"role": "assistant", "content": text.String()}
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+		if text.Len() == 0 {
+			message["content"] = nil
+		}
+		finish = "tool_calls"
+	}
+	id := ""
+	if resp.ResponseID != "" {
+		id = "chatcmpl-" + resp.ResponseID
+	}
+	return t.openAICompletion(id, message, finish, resp.usage())
+}
+
+// geminiToolCall renders a Gemini functionCall as an OpenAI tool call. Gemini
+// does not assign call IDs, so one is generated.
+func geminiToolCall(name string, args json.RawMessage) map[string]any {
+	arguments := string(args)
+	if arguments == "" || arguments == "null" {
+		arguments = "{}"
+	}
+	return map[string]any{
+		"id":       "call_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:24],
+		"type":     "function",
+		"function": map[string]any{"name": name, "arguments": arguments},
+	}
+}
+
+// geminiStreamConverter converts streamGenerateContent SSE events (each a
+// partial generateContent response) into chat.completion.chunk payloads.
+type geminiStreamConverter struct {
+	t            *chatTranslation
+	id           string
+	started      bool
+	tools        int
+	finishReason string
+	usage        *openAIUsage
+	done         bool
+}
+
+func (c *geminiStreamConverter) event(_ string, data []byte) [][]byte {
+	var resp geminiResponse
+	if json.Unmarshal(data, &resp) != nil {
+		return nil
+	}
+	var out [][]byte
+	if !c.started {
+		c.started = true
+		c.id = "chatcmpl-" + resp.ResponseID
+		if resp.ResponseID == "" {
+			c.id = "chatcmpl-" + uuid.NewString()
+		}
+		out = append(out, c.t.openAIChunk(c.id, map[string]any{"role": "assistant", "content": ""}, ""))
+	}
+	if u := resp.usage(); u != nil {
+		c.usage = u
+	}
+	if len(resp.Candidates) == 0 {
+		if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+			c.finishReason = "content_filter"
+		}
+		return out
+	}
+	cand := resp.Candidates[0]
+	for _, part := range cand.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			call := geminiToolCall(part.FunctionCall.Name, part.FunctionCall.Args)
+			call["index"] = c.tools
+			c.tools++
+			out = append(out, c.t.openAIChunk(c.id, map[string]any{"tool_calls": []any{call}}, ""))
+		case !part.Thought && part.Text != "":
+			out = append(out, c.t.openAIChunk(c.id, map[string]any{"content": part.Text}, ""))
+		}
+	}
+	if cand.FinishReason != "" {
+		c.finishReason = geminiFinishReason(cand.FinishReason)
+	}
+	return out
+}
+
+// finish emits the finish chunk once the stream ends, since Gemini reports
+// the finish reason alongside the last content rather than separately.
+func (c *geminiStreamConverter) finish() [][]byte {
+	if c.done {
+		return nil
+	}
+	c.done = true
+	var out [][]byte
+	if c.started {
+		reason := c.finishReason
+		switch {
+		case c.tools > 0 && reason != "content_filter":
+			reason = "tool_calls"
+		case reason == "":
+			reason = "stop"
+		}
+		out = append(out, c.t.openAIChunk(c.id, map[string]any{}, reason))
+		if c.t.includeUsage && c.usage != nil {
+			out = append(out, c.t.openAIUsageChunk(c.id, *c.usage))
+		}
+	}
+	return append(out, []byte("[DONE]"))
+}
@@ -0,0 +1,635 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const translateToolRequest = `{
+	"model": "m",
+	"messages": [
+		{"role": "system", "content": "be brief"},
+		{"role": "user", "content": [
+			{"type": "text", "text": "what is in this image?"},
+			{"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}}
+		]},
+		{"role": "assistant", "content": null, "tool_calls": [
+			{"id": "call_1", "type": "function", "function": {"name": "lookup", "arguments": "{\"q\":\"cat\"}"}}
+		]},
+		{"role": "tool", "tool_call_id": "call_1", "content": "{\"result\":\"a cat\"}"}
+	],
+	"max_tokens": 100,
+	"temperature": 0.5,
+	"stop": "END",
+	"tools": [{"type": "function", "function": {"name": "lookup", "description": "search", "parameters": {"type": "object", "additionalProperties": false, "properties": {"q": {"type": "string"}}}}}],
+	"tool_choice": "required",
+	"user": "u-1"
+}`
+
+func decodeJSON(t *testing.T, b []byte) map[string]any {
+	t.Helper()
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(b, &m), string(b))
+	return m
+}
+
+func TestNewChatTranslation_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		body string
+	}{
+		{name: "invalid json", mode: ProviderModeAnthropic, body: `{`},
+		{name: "missing model", mode: ProviderModeAnthropic, body: `{"messages":[{"role":"user","content":"hi"}]}`},
+		{name: "missing messages", mode: ProviderModeGemini, body: `{"model":"m"}`},
+		{name: "unknown role", mode: ProviderModeAnthropic, body: `{"model":"m","messages":[{"role":"robot","content":"hi"}]}`},
+		{name: "only system", mode: ProviderModeGemini, body: `{"model":"m","messages":[{"role":"system","content":"hi"}]}`},
+		{name: "unknown tool result", mode: ProviderModeGemini, body: `{"model":"m","messages":[{"role":"tool","tool_call_id":"x","content":"1"}]}`},
+		{name: "unknown mode", mode: "bedrock", body: `{"model":"m","messages":[{"role":"user","content":"hi"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newChatTranslation(tt.mode, []byte(tt.body))
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errTranslation))
+		})
+	}
+}
+
+func TestChatTranslation_AnthropicRequest(t *testing.T) {
+	tr, err := newChatTranslation(ProviderModeAnthropic, []byte(translateToolRequest))
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/messages", tr.path)
+	assert.Empty(t, tr.query)
+
+	got := decodeJSON(t, tr.body)
+	assert.Equal(t, "m", got["model"])
+	assert.Equal(t, float64(100), got["max_tokens"])
+	assert.Equal(t, 0.5, got["temperature"])
+	assert.Equal(t, "be brief", got["system"])
+	assert.Equal(t, []any{"END"}, got["stop_sequences"])
+	assert.Equal(t, map[string]any{"type": "any"}, got["tool_choice"])
+	assert.Equal(t, map[string]any{"user_id": "u-1"}, got["metadata"])
+	assert.NotContains(t, got, "stream")
+
+	tools := got["tools"].([]any)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "lookup", tools[0].(map[string]any)["name"])
+	assert.Contains(t, tools[0].(map[string]any), "input_schema")
+
+	messages := got["messages"].([]any)
+	require.Len(t, messages, 3)
+	user := messages[0].(map[string]any)
+	assert.Equal(t, "user", user["role"])
+	assert.Equal(t, []any{
+		map[string]any{"type": "text", "text": "what is in this image?"},
+		map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": "image/png", "data": "AAAA"}},
+	}, user["content"])
+	assistant := messages[1].(map[string]any)
+	assert.Equal(t, []any{
+		map[string]any{"type": "tool_use", "id": "call_1", "name": "lookup", "input": map[string]any{"q": "cat"}},
+	}, assistant["content"])
+	toolResult := messages[2].(map[string]any)
+	assert.Equal(t, "user", toolResult["role"])
+	assert.Equal(t, []any{
+		map[string]any{"type": "tool_result", "tool_use_id": "call_1", "content": `{"result":"a cat"}`},
+	}, toolResult["content"])
+}
+
+func TestChatTranslation_AnthropicRequestDefaults(t *testing.T) {
+	tr, err := newChatTranslation(ProviderModeAnthropic, []byte(`{"model":"m","stream":true,"messages":[{"role":"user","content":"a"},{"role":"user","content":"b"}]}`))
+	require.NoError(t, err)
+	got := decodeJSON(t, tr.body)
+	assert.Equal(t, float64(defaultTranslatedMaxTokens), got["max_tokens"])
+	assert.Equal(t, true, got["stream"])
+	messages := got["messages"].([]any)
+	require.Len(t, messages, 1, "consecutive user messages are merged")
+	assert.Len(t, messages[0].(map[string]any)["content"], 2)
+}
+
+func TestChatTranslation_GeminiRequest(t *testing.T) {
+	tr, err := newChatTranslation(ProviderModeGemini, []byte(translateToolRequest))
+	require.NoError(t, err)
+	assert.Equal(t, "/v1beta/models/m:generateContent", tr.path)
+	assert.Empty(t, tr.query)
+
+	got := decodeJSON(t, tr.body)
+	assert.Equal(t, map[string]any{"parts": []any{map[string]any{"text": "be brief"}}}, got["systemInstruction"])
+	assert.Equal(t, map[string]any{
+		"temperature":     0.5,
+		"maxOutputTokens": float64(100),
+		"stopSequences":   []any{"END"},
+	}, got["generationConfig"])
+	assert.Equal(t, map[string]any{"functionCallingConfig": map[string]any{"mode": "ANY"}}, got["toolConfig"])
+
+	decls := got["tools"].([]any)[0].(map[string]any)["functionDeclarations"].([]any)
+	require.Len(t, decls, 1)
+	params := decls[0].(map[string]any)["parameters"].(map[string]any)
+	assert.NotContains(t, params, "additionalProperties", "unsupported schema keywords are dropped")
+	assert.Contains(t, params, "properties")
+
+	contents := got["contents"].([]any)
+	require.Len(t, contents, 3)
+	assert.Equal(t, map[string]any{"role": "user", "parts": []any{
+		map[string]any{"text": "what is in this image?"},
+		map[string]any{"inlineData": map[string]any{"mimeType": "image/png", "data": "AAAA"}},
+	}}, contents[0])
+	assert.Equal(t, map[string]any{"role": "model", "parts": []any{
+		map[string]any{"functionCall": map[string]any{"name": "lookup", "args": map[string]any{"q": "cat"}}},
+	}}, contents[1])
+	assert.Equal(t, map[string]any{"role": "user", "parts": []any{
+		map[string]any{"functionResponse": map[string]any{"name": "lookup", "response": map[string]any{"result": "a cat"}}},
+	}}, contents[2])
+}
+
+func TestChatTranslation_GeminiStreamRequest(t *testing.T) {
+	tr, err := newChatTranslation(ProviderModeGemini, []byte(`{"model":"gemini-2.0-flash","stream":true,"tool_choice":{"type":"function","function":{"name":"f"}},"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"gs://bucket/cat.png"}}]}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "/v1beta/models/gemini-2.0-flash:streamGenerateContent", tr.path)
+	assert.Equal(t, "sse", tr.query.Get("alt"))
+
+	got := decodeJSON(t, tr.body)
+	assert.NotContains(t, got, "generationConfig")
+	assert.Equal(t, map[string]any{"functionCallingConfig": map[string]any{"mode": "ANY", "allowedFunctionNames": []any{"f"}}}, got["toolConfig"])
+	assert.Equal(t, []any{map[string]any{"role": "user", "parts": []any{
+		map[string]any{"fileData": map[string]any{"fileUri": "gs://bucket/cat.png", "mimeType": "image/png"}},
+	}}}, got["contents"])
+}
+
+func TestChatTranslation_AnthropicCompletion(t *testing.T) {
+	tr := &chatTranslation{mode: ProviderModeAnthropic, model: "claude", created: 42}
+	out, err := tr.translateCompletion([]byte(`{
+		"id": "msg_1",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "cat"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`))
+	require.NoError(t, err)
+
+	got := decodeJSON(t, out)
+	assert.Equal(t, "chatcmpl-msg_1", got["id"])
+	assert.Equal(t, "chat.completion", got["object"])
+	assert.Equal(t, "claude", got["model"])
+	assert.Equal(t, float64(42), got["created"])
+	assert.Equal(t, map[string]any{"prompt_tokens": float64(10), "completion_tokens": float64(5), "total_tokens": float64(15)}, got["usage"])
+	choice := got["choices"].([]any)[0].(map[string]any)
+	assert.Equal(t, "tool_calls", choice["finish_reason"])
+	message := choice["message"].(map[string]any)
+	assert.Equal(t, "Let me check.", message["content"])
+	assert.Equal(t, []any{map[string]any{
+		"id":       "toolu_1",
+		"type":     "function",
+		"function": map[string]any{"name": "lookup", "arguments": `{"q": "cat"}`},
+	}}, message["tool_calls"])
+
+	assert.Equal(t, "stop", anthropicFinishReason("end_turn"))
+	assert.Equal(t, "stop", anthropicFinishReason("stop_sequence"))
+	assert.Equal(t, "length", anthropicFinishReason("max_tokens"))
+
+	_, err = tr.translateCompletion([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestChatTranslation_GeminiCompletion(t *testing.T) {
+	tr := &chatTranslation{mode: ProviderModeGemini, model: "gemini"}
+	out, err := tr.translateCompletion([]byte(`{
+		"responseId": "r1",
+		"candidates": [{"content": {"role": "model", "parts": [
+			{"text": "thinking", "thought": true},
+			{"text": "Hello"},
+			{"text": " world"}
+		]}, "finishReason": "MAX_TOKENS"}],
+		"usageMetadata": {"promptTokenCount": 3, "candidatesTokenCount": 2, "totalTokenCount": 7}
+	}`))
+	require.NoError(t, err)
+	got := decodeJSON(t, out)
+	assert.Equal(t, "chatcmpl-r1", got["id"])
+	choice := got["choices"].([]any)[0].(map[string]any)
+	assert.Equal(t, "length", choice["finish_reason"])
+	assert.Equal(t, "Hello world", choice["message"].(map[string]any)["content"])
+	assert.Equal(t, map[string]any{"prompt_tokens": float64(3), "completion_tokens": float64(2), "total_tokens": float64(7)}, got["usage"])
+
+	out, err = tr.translateCompletion([]byte(`{"candidates": [{"content": {"parts": [{"functionCall": {"name": "lookup", "args": {"q": "cat"}}}]}, "finishReason": "STOP"}]}`))
+	require.NoError(t, err)
+	got = decodeJSON(t, out)
+	assert.True(t, strings.HasPrefix(got["id"].(string), "chatcmpl-"))
+	assert.NotContains(t, got, "usage")
+	choice = got["choices"].([]any)[0].(map[string]any)
+	assert.Equal(t, "tool_calls", choice["finish_reason"])
+	message := choice["message"].(map[string]any)
+	assert.Nil(t, message["content"])
+	call := message["tool_calls"].([]any)[0].(map[string]any)
+	assert.True(t, strings.HasPrefix(call["id"].(string), "call_"))
+	assert.Equal(t, map[string]any{"name": "lookup", "arguments": `{"q": "cat"}`}, call["function"])
+
+	out, err = tr.translateCompletion([]byte(`{"promptFeedback": {"blockReason": "SAFETY"}}`))
+	require.NoError(t, err)
+	choice = decodeJSON(t, out)["choices"].([]any)[0].(map[string]any)
+	assert.Equal(t, "content_filter", choice["finish_reason"])
+}
+
+func TestChatTranslation_TranslateError(t *testing.T) {
+	tr := &chatTranslation{mode: ProviderModeAnthropic}
+	tests := []struct {
+		name     string
+		body     string
+		wantType string
+		wantMsg  string
+	}{
+		{name: "anthropic", body: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, wantType: "overloaded_error", wantMsg: "Overloaded"},
+		{name: "gemini", body: `{"error":{"code":400,"message":"API key not valid","status":"INVALID_ARGUMENT"}}`, wantType: "invalid_argument", wantMsg: "API key not valid"},
+		{name: "plain text", body: "upstream exploded", wantType: "upstream_error", wantMsg: "upstream exploded"},
+		{name: "empty", body: "", wantType: "upstream_error", wantMsg: "Too Many Requests"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeJSON(t, tr.translateError(http.StatusTooManyRequests, []byte(tt.body)))
+			e := got["error"].(map[string]any)
+			assert.Equal(t, tt.wantType, e["type"])
+			assert.Equal(t, tt.wantMsg, e["message"])
+			assert.Equal(t, float64(http.StatusTooManyRequests), e["code"])
+		})
+	}
+}
+
+func TestChatTranslation_TranslateResponse_InvalidBody(t *testing.T) {
+	tr := &chatTranslation{mode: ProviderModeGemini}
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}},
+		Body:       io.NopCloser(strings.NewReader("<html>")),
+	}
+	require.NoError(t, tr.translateResponse(res))
+	assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+	assert.Empty(t, res.Header.Get("Content-Encoding"))
+	body, _ := io.ReadAll(res.Body)
+	assert.Equal(t, int64(len(body)), res.ContentLength)
+	assert.Equal(t, "upstream_translation_error", decodeJSON(t, body)["error"].(map[string]any)["type"])
+}
+
+// ssePayloads returns the data payloads of an OpenAI-style SSE stream.
+func ssePayloads(t *testing.T, stream string) []string {
+	t.Helper()
+	var out []string
+	for _, event := range strings.Split(strings.TrimSpace(stream), "\n\n") {
+		payload, ok := strings.CutPrefix(event, "data: ")
+		require.True(t, ok, "unexpected event %q", event)
+		out = append(out, payload)
+	}
+	return out
+}
+
+func chunkDelta(t *testing.T, payload string) (map[string]any, any) {
+	t.Helper()
+	choice := decodeJSON(t, []byte(payload))["choices"].([]any)[0].(map[string]any)
+	return choice["delta"].(map[string]any), choice["finish_reason"]
+}
+
+type recordingConverter struct {
+	events   []string
+	finished int
+}
+
+func (c *recordingConverter) event(name string, data []byte) [][]byte {
+	c.events = append(c.events, name+"|"+string(data))
+	return [][]byte{data}
+}
+
+func (c *recordingConverter) finish() [][]byte {
+	c.finished++
+	return [][]byte{[]byte("[DONE]")}
+}
+
+func TestSSETranslatingReadCloser(t *testing.T) {
+	upstream := ": keep-alive\r\nevent: a\r\ndata: 1\r\n\r\ndata: 2\ndata: 3\n\nevent: b\ndata:4"
+	conv := &recordingConverter{}
+	r := newSSETranslator(io.NopCloser(strings.NewReader(upstream)), conv)
+
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a|1", "|2\n3", "b|4"}, conv.events, "trailing event without blank line is still dispatched")
+	assert.Equal(t, 1, conv.finished)
+	assert.Equal(t, "data: 1\n\ndata: 2\n3\n\ndata: 4\n\ndata: [DONE]\n\n", string(out))
+	assert.NoError(t, r.Close())
+}
+
+func TestAnthropicStreamConverter(t *testing.T) {
+	upstream := strings.Join([]string{
+		`event: message_start`,
+		`data: {"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":10,"output_tokens":1}}}`,
+		``,
+		`event: content_block_start`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		``,
+		`event: ping`,
+		`data: {"type":"ping"}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		``,
+		`event: content_block_start`,
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"lookup","input":{}}}`,
+		``,
+		`event: content_block_delta`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"q\":"}}`,
+		``,
+		`event: message_delta`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}`,
+		``,
+		`event: message_stop`,
+		`data: {"type":"message_stop"}`,
+		``,
+		``,
+	}, "\n")
+	tr := &chatTranslation{mode: ProviderModeAnthropic, model: "claude", stream: true, includeUsage: true}
+	out, err := io.ReadAll(newSSETranslator(io.NopCloser(strings.NewReader(upstream)), tr.newStreamConverter()))
+	require.NoError(t, err)
+
+	payloads := ssePayloads(t, string(out))
+	require.Len(t, payloads, 7, string(out))
+
+	delta, finish := chunkDelta(t, payloads[0])
+	assert.Equal(t, map[string]any{"role": "assistant", "content": ""}, delta)
+	assert.Nil(t, finish)
+	assert.Equal(t, "chatcmpl-msg_1", decodeJSON(t, []byte(payloads[0]))["id"])
+
+	delta, _ = chunkDelta(t, payloads[1])
+	assert.Equal(t, map[string]any{"content": "Hi"}, delta)
+
+	delta, _ = chunkDelta(t, payloads[2])
+	assert.Equal(t, []any{map[string]any{
+		"index": float64(0), "id": "toolu_1", "type": "function",
+		"function": map[string]any{"name": "lookup", "arguments": ""},
+	}}, delta["tool_calls"])
+
+	delta, _ = chunkDelta(t, payloads[3])
+	assert.Equal(t, []any{map[string]any{"index": float64(0), "function": map[string]any{"arguments": `{"q":`}}}, delta["tool_calls"])
+
+	delta, finish = chunkDelta(t, payloads[4])
+	assert.Empty(t, delta)
+	assert.Equal(t, "tool_calls", finish)
+
+	usage := decodeJSON(t, []byte(payloads[5]))
+	assert.Empty(t, usage["choices"])
+	assert.Equal(t, map[string]any{"prompt_tokens": float64(10), "completion_tokens": float64(7), "total_tokens": float64(17)}, usage["usage"])
+
+	assert.Equal(t, "[DONE]", payloads[6])
+}
+
+func TestAnthropicStreamConverter_Error(t *testing.T) {
+	upstream := "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+	tr := &chatTranslation{mode: ProviderModeAnthropic, stream: true}
+	out, err := io.ReadAll(newSSETranslator(io.NopCloser(strings.NewReader(upstream)), tr.newStreamConverter()))
+	require.NoError(t, err)
+	payloads := ssePayloads(t, string(out))
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "overloaded_error", decodeJSON(t, []byte(payloads[0]))["error"].(map[string]any)["type"])
+	assert.Equal(t, "[DONE]", payloads[1])
+}
+
+func TestGeminiStreamConverter(t *testing.T) {
+	upstream := strings.Join([]string{
+		`data: {"responseId":"r1","candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]}}]}`,
+		``,
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":3,"candidatesTokenCount":2,"totalTokenCount":5}}`,
+		``,
+		``,
+	}, "\r\n")
+	tr := &chatTranslation{mode: ProviderModeGemini, model: "gemini", stream: true, includeUsage: true}
+	out, err := io.ReadAll(newSSETranslator(io.NopCloser(strings.NewReader(upstream)), tr.newStreamConverter()))
+	require.NoError(t, err)
+
+	payloads := ssePayloads(t, string(out))
+	require.Len(t, payloads, 6, string(out))
+	delta, _ := chunkDelta(t, payloads[0])
+	assert.Equal(t, "assistant", delta["role"])
+	assert.Equal(t, "chatcmpl-r1", decodeJSON(t, []byte(payloads[0]))["id"])
+	delta, _ = chunkDelta(t, payloads[1])
+	assert.Equal(t, "Hel", delta["content"])
+	delta, _ = chunkDelta(t, payloads[2])
+	assert.Equal(t, "lo", delta["content"])
+	assert.Equal(t, "chatcmpl-r1", decodeJSON(t, []byte(payloads[2]))["id"])
+	_, finish := chunkDelta(t, payloads[3])
+	assert.Equal(t, "stop", finish)
+	assert.Equal(t, map[string]any{"prompt_tokens": float64(3), "completion_tokens": float64(2), "total_tokens": float64(5)}, decodeJSON(t, []byte(payloads[4]))["usage"])
+	assert.Equal(t, "[DONE]", payloads[5])
+}
+
+func TestGeminiStreamConverter_ToolCall(t *testing.T) {
+	upstream := `data: {"candidates":[{"content":{"parts":[{"functionCall":{"name":"lookup","args":{"q":"cat"}}}]},"finishReason":"STOP"}]}` + "\n\n"
+	tr := &chatTranslation{mode: ProviderModeGemini, stream: true}
+	out, err := io.ReadAll(newSSETranslator(io.NopCloser(strings.NewReader(upstream)), tr.newStreamConverter()))
+	require.NoError(t, err)
+
+	payloads := ssePayloads(t, string(out))
+	require.Len(t, payloads, 4, string(out))
+	delta, _ := chunkDelta(t, payloads[1])
+	call := delta["tool_calls"].([]any)[0].(map[string]any)
+	assert.Equal(t, float64(0), call["index"])
+	assert.Equal(t, map[string]any{"name": "lookup", "arguments": `{"q":"cat"}`}, call["function"])
+	_, finish := chunkDelta(t, payloads[2])
+	assert.Equal(t, "tool_calls", finish)
+	assert.Equal(t, "[DONE]", payloads[3])
+}
+
+func TestTransparentProxy_TranslatingModes_EndToEnd(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         string
+		stream       bool
+		upstreamType string
+		upstreamBody string
+		wantPath     string
+		wantQuery    string
+		checkHeaders func(t *testing.T, h http.Header)
+		checkBody    func(t *testing.T, body string)
+	}{
+		{
+			name:         "anthropic",
+			mode:         ProviderModeAnthropic,
+			upstreamType: "application/json",
+			upstreamBody: `{"id":"msg_1","content":[{"type":"text","text":"Hello"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":2}}`,
+			wantPath:     "/v1/messages",
+			checkHeaders: func(t *testing.T, h http.Header) {
+				assert.Equal(t, "api-key", h.Get("x-api-key"))
+				assert.Equal(t, defaultAnthropicVersion, h.Get("anthropic-version"))
+				assert.Empty(t, h.Get("Authorization"))
+			},
+			checkBody: func(t *testing.T, body string) {
+				got := decodeJSON(t, []byte(body))
+				assert.Equal(t, "chat.completion", got["object"])
+				msg := got["choices"].([]any)[0].(map[string]any)["message"].(map[string]any)
+				assert.Equal(t, "Hello", msg["content"])
+			},
+		},
+		{
+			name:         "anthropic stream",
+			mode:         ProviderModeAnthropic,
+			stream:       true,
+			upstreamType: "text/event-stream",
+			upstreamBody: "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n" +
+				"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"}}\n\n" +
+				"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+			wantPath: "/v1/messages",
+			checkBody: func(t *testing.T, body string) {
+				payloads := ssePayloads(t, body)
+				require.Len(t, payloads, 4)
+				delta, _ := chunkDelta(t, payloads[1])
+				assert.Equal(t, "Hello", delta["content"])
+				assert.Equal(t, "[DONE]", payloads[3])
+			},
+		},
+		{
+			name:         "gemini",
+			mode:         ProviderModeGemini,
+			upstreamType: "application/json",
+			upstreamBody: `{"candidates":[{"content":{"parts":[{"text":"Hello"}]},"finishReason":"STOP"}]}`,
+			wantPath:     "/v1beta/models/test-model:generateContent",
+			checkHeaders: func(t *testing.T, h http.Header) {
+				assert.Equal(t, "api-key", h.Get("x-goog-api-key"))
+				assert.Empty(t, h.Get("Authorization"))
+			},
+			checkBody: func(t *testing.T, body string) {
+				msg := decodeJSON(t, []byte(body))["choices"].([]any)[0].(map[string]any)["message"].(map[string]any)
+				assert.Equal(t, "Hello", msg["content"])
+			},
+		},
+		{
+			name:         "gemini stream",
+			mode:         ProviderModeGemini,
+			stream:       true,
+			upstreamType: "text/event-stream",
+			upstreamBody: "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hello\"}]},\"finishReason\":\"STOP\"}]}\r\n\r\n",
+			wantPath:     "/v1beta/models/test-model:streamGenerateContent",
+			wantQuery:    "alt=sse",
+			checkBody: func(t *testing.T, body string) {
+				payloads := ssePayloads(t, body)
+				require.Len(t, payloads, 4)
+				delta, _ := chunkDelta(t, payloads[1])
+				assert.Equal(t, "Hello", delta["content"])
+				_, finish := chunkDelta(t, payloads[2])
+				assert.Equal(t, "stop", finish)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				seen     *http.Request
+				seenBody []byte
+			)
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.Clone(context.Background())
+				seenBody, _ = io.ReadAll(r.Body)
+				w.Header().Set("Content-Type", tt.upstreamType)
+				_, _ = w.Write([]byte(tt.upstreamBody))
+			}))
+			defer upstream.Close()
+
+			apiConfig := &APIConfig{
+				DefaultAPI: "backend",
+				APIs: map[string]*APIProviderConfig{
+					"backend": {
+						BaseURL:          upstream.URL,
+						AllowedEndpoints: []string{"/v1/chat/completions"},
+						AllowedMethods:   []string{http.MethodPost},
+						Mode:             tt.mode,
+					},
+				},
+			}
+			require.NoError(t, validateAPIConfig(apiConfig))
+			cfg, err := apiConfig.GetProxyConfigForAPI("backend")
+			require.NoError(t, err)
+			p, err := NewTransparentProxyWithLogger(*cfg, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop())
+			require.NoError(t, err)
+
+			body := `{"model":"test-model","messages":[{"role":"user","content":"hi"}]`
+			if tt.stream {
+				body += `,"stream":true`
+			}
+			body += `}`
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer tok")
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			p.Handler().ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.NotNil(t, seen)
+			assert.Equal(t, tt.wantPath, seen.URL.Path)
+			assert.Equal(t, tt.wantQuery, seen.URL.RawQuery)
+			translated := decodeJSON(t, seenBody)
+			if tt.mode == ProviderModeAnthropic {
+				assert.Equal(t, "test-model", translated["model"])
+				assert.Contains(t, translated, "messages")
+			} else {
+				assert.Contains(t, translated, "contents")
+			}
+			if tt.checkHeaders != nil {
+				tt.checkHeaders(t, seen.Header)
+			}
+			if tt.stream {
+				assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+			} else {
+				assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			}
+			tt.checkBody(t, w.Body.String())
+		})
+	}
+}
+
+func TestTransparentProxy_TranslatingMode_Errors(t *testing.T) {
+	var calls int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`))
+	}))
+	defer upstream.Close()
+
+	p, err := NewTransparentProxyWithLogger(ProxyConfig{
+		TargetBaseURL:    upstream.URL,
+		AllowedEndpoints: []string{"/v1/chat/completions"},
+		AllowedMethods:   []string{http.MethodPost},
+		Mode:             ProviderModeAnthropic,
+	}, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop())
+	require.NoError(t, err)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		p.Handler().ServeHTTP(w, req)
+		return w
+	}
+
+	// Untranslatable requests are rejected before reaching the upstream.
+	w := send(`{"messages":[{"role":"user","content":"hi"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_request")
+	assert.Equal(t, 0, calls)
+
+	// Upstream errors keep their status and are reshaped for OpenAI clients.
+	w = send(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	e := decodeJSON(t, w.Body.Bytes())["error"].(map[string]any)
+	assert.Equal(t, "rate_limit_error", e["type"])
+	assert.Equal(t, "slow down", e["message"])
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Upstream auth styles (APIProviderConfig.Auth.Style).
+const (
+	AuthStyleBearer     = "bearer"
+	AuthStyleXAPIKey    = "x-api-key"
+	AuthStyleQueryParam = "query-param"
+	AuthStyleHeader     = "header"
+)
+
+const (
+	defaultAPIKeyHeader    = "x-api-key"
+	defaultAuthQueryParam  = "key"
+	redactedQueryParameter = "REDACTED"
+)
+
+// validateUpstreamAuth checks that an auth block names a known style and has
+// the settings that style needs.
+func validateUpstreamAuth(a UpstreamAuthConfig) error {
+	switch a.style() {
+	case AuthStyleBearer, AuthStyleXAPIKey, AuthStyleQueryParam:
+	case AuthStyleHeader:
+		if strings.TrimSpace(a.Header) == "" {
+			return fmt.Errorf("style %q requires a header name", AuthStyleHeader)
+		}
+	default:
+		return fmt.Errorf("unknown style %q", a.Style)
+	}
+	return nil
+}
+
+func (a UpstreamAuthConfig) style() string {
+	if a.Style == "" {
+		return AuthStyleBearer
+	}
+	return strings.ToLower(a.Style)
+}
+
+// headerName returns the header carrying the credential for header-based
+// styles other than bearer, or "" otherwise.
+func (a UpstreamAuthConfig) headerName() string {
+	switch a.style() {
+	case AuthStyleXAPIKey:
+		if a.Header != "" {
+			return a.Header
+		}
+		return defaultAPIKeyHeader
+	case AuthStyleHeader:
+		return a.Header
+	default:
+		return ""
+	}
+}
+
+// queryParam returns the query parameter carrying the credential for the
+// query-param style, or "" otherwise.
+func (a UpstreamAuthConfig) queryParam() string {
+	if a.style() != AuthStyleQueryParam {
+		return ""
+	}
+	if a.QueryParam != "" {
+		return a.QueryParam
+	}
+	return defaultAuthQueryParam
+}
+
+// takeQueryCredential removes the credential query parameter from r and returns
+// its value, so a proxy token passed the provider-native way never ends up in
+// cache keys, logs or the upstream URL. r.URL is replaced, not mutated, because
+// it may be shared with outer middleware.
+func (a UpstreamAuthConfig) takeQueryCredential(r *http.Request) string {
+	param := a.queryParam()
+	if param == "" || r.URL == nil {
+		return ""
+	}
+	q := r.URL.Query()
+	if _, ok := q[param]; !ok {
+		return ""
+	}
+	value := q.Get(param)
+	q.Del(param)
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	r.URL = &u
+	return value
+}
+
+// clientToken returns the proxy token presented by the client. Authorization:
+// Bearer always works; in addition, clients may use the provider's native
+// credential location (e.g. x-api-key for Anthropic SDKs). queryToken is the
+// value previously returned by takeQueryCredential.
+func (a UpstreamAuthConfig) clientToken(r *http.Request, queryToken string) string {
+	if tok := extractTokenFromHeader(r.Header.Get("Authorization")); tok != "" {
+		return tok
+	}
+	if h := a.headerName(); h != "" {
+		v := strings.TrimSpace(r.Header.Get(h))
+		if a.Prefix != "" && strings.HasPrefix(v, a.Prefix) {
+			v = strings.TrimSpace(v[len(a.Prefix):])
+		}
+		if v != "" {
+			return v
+		}
+	}
+	return strings.TrimSpace(queryToken)
+}
+
+// applyUpstreamCredential replaces the client's credentials on the outgoing
+// request with the upstream key, in the location the provider expects.
+func (a UpstreamAuthConfig) applyUpstreamCredential(req *http.Request, key string) {
+	req.Header.Del("Authorization")
+	switch a.style() {
+	case AuthStyleXAPIKey, AuthStyleHeader:
+		req.Header.Set(a.headerName(), a.Prefix+key)
+	case AuthStyleQueryParam:
+		q := req.URL.Query()
+		q.Set(a.queryParam(), key)
+		req.URL.RawQuery = q.Encode()
+	default:
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	for name, value := range a.Headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+}
+
+// redactedURL returns u as a string with the credential query parameter masked.
+func (a UpstreamAuthConfig) redactedURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	param := a.queryParam()
+	if param == "" {
+		return u.String()
+	}
+	q := u.Query()
+	if _, ok := q[param]; !ok {
+		return u.String()
+	}
+	q.Set(param, redactedQueryParameter)
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestValidateUpstreamAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    UpstreamAuthConfig
+		wantErr bool
+	}{
+		{name: "default bearer", auth: UpstreamAuthConfig{}},
+		{name: "x-api-key", auth: UpstreamAuthConfig{Style: AuthStyleXAPIKey}},
+		{name: "query-param", auth: UpstreamAuthConfig{Style: AuthStyleQueryParam}},
+		{name: "custom header", auth: UpstreamAuthConfig{Style: AuthStyleHeader, Header: "api-key"}},
+		{name: "style is case-insensitive", auth: UpstreamAuthConfig{Style: "Bearer"}},
+		{name: "custom header without name", auth: UpstreamAuthConfig{Style: AuthStyleHeader}, wantErr: true},
+		{name: "unknown style", auth: UpstreamAuthConfig{Style: "basic"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateUpstreamAuth(tt.auth)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUpstreamAuthConfig_ApplyUpstreamCredential(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       UpstreamAuthConfig
+		wantHeader map[string]string
+		wantQuery  string
+	}{
+		{
+			name:       "bearer",
+			auth:       UpstreamAuthConfig{},
+			wantHeader: map[string]string{"Authorization": "Bearer sk-up", "X-Api-Key": "client"},
+		},
+		{
+			name: "x-api-key with default headers",
+			auth: UpstreamAuthConfig{
+				Style:   AuthStyleXAPIKey,
+				Headers: map[string]string{"anthropic-version": "2023-06-01", "anthropic-beta": "fixed"},
+			},
+			wantHeader: map[string]string{
+				"Authorization":     "",
+				"X-Api-Key":         "sk-up",
+				"Anthropic-Version": "2023-06-01",
+				"Anthropic-Beta":    "client-beta",
+			},
+		},
+		{
+			name:       "custom header with prefix",
+			auth:       UpstreamAuthConfig{Style: AuthStyleHeader, Header: "api-key", Prefix: "Token "},
+			wantHeader: map[string]string{"Authorization": "", "Api-Key": "Token sk-up"},
+		},
+		{
+			name:       "query param",
+			auth:       UpstreamAuthConfig{Style: AuthStyleQueryParam},
+			wantHeader: map[string]string{"Authorization": ""},
+			wantQuery:  "alt=sse&key=sk-up",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/messages?alt=sse", nil)
+			req.Header.Set("Authorization", "Bearer proxy-token")
+			req.Header.Set("X-Api-Key", "client")
+			req.Header.Set("Anthropic-Beta", "client-beta")
+
+			tt.auth.applyUpstreamCredential(req, "sk-up")
+
+			for k, v := range tt.wantHeader {
+				assert.Equal(t, v, req.Header.Get(k), k)
+			}
+			if tt.wantQuery != "" {
+				assert.Equal(t, tt.wantQuery, req.URL.RawQuery)
+			}
+		})
+	}
+}
+
+func TestUpstreamAuthConfig_ClientToken(t *testing.T) {
+	xAPIKey := UpstreamAuthConfig{Style: AuthStyleXAPIKey}
+	custom := UpstreamAuthConfig{Style: AuthStyleHeader, Header: "Authorization", Prefix: "Token "}
+	query := UpstreamAuthConfig{Style: AuthStyleQueryParam}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	req.Header.Set("Authorization", "Bearer tok-bearer")
+	req.Header.Set("x-api-key", "tok-header")
+	assert.Equal(t, "tok-bearer", xAPIKey.clientToken(req, ""), "bearer always wins")
+
+	req.Header.Del("Authorization")
+	assert.Equal(t, "tok-header", xAPIKey.clientToken(req, ""))
+	assert.Empty(t, UpstreamAuthConfig{}.clientToken(req, ""), "bearer style ignores x-api-key")
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/x", nil)
+	req.Header.Set("Authorization", "Token tok-custom")
+	assert.Equal(t, "tok-custom", custom.clientToken(req, ""))
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/x?key=tok-query&alt=sse", nil)
+	qt := query.takeQueryCredential(req)
+	assert.Equal(t, "tok-query", qt)
+	assert.Equal(t, "alt=sse", req.URL.RawQuery)
+	assert.Equal(t, "tok-query", query.clientToken(req, qt))
+}
+
+func TestUpstreamAuthConfig_TakeQueryCredential_DoesNotMutateSharedURL(t *testing.T) {
+	query := UpstreamAuthConfig{Style: AuthStyleQueryParam, QueryParam: "api_key"}
+	outer := httptest.NewRequest(http.MethodGet, "/v1/models?api_key=tok", nil)
+	inner := outer.WithContext(context.Background())
+
+	assert.Equal(t, "tok", query.takeQueryCredential(inner))
+	assert.Empty(t, inner.URL.RawQuery)
+	assert.Equal(t, "api_key=tok", outer.URL.RawQuery)
+
+	// Other styles leave the URL alone.
+	req := httptest.NewRequest(http.MethodGet, "/v1/models?key=tok", nil)
+	assert.Empty(t, UpstreamAuthConfig{}.takeQueryCredential(req))
+	assert.Equal(t, "key=tok", req.URL.RawQuery)
+}
+
+func TestUpstreamAuthConfig_RedactedURL(t *testing.T) {
+	query := UpstreamAuthConfig{Style: AuthStyleQueryParam}
+	u, err := url.Parse("https://example.com/v1/models?alt=sse&key=sk-secret")
+	require.NoError(t, err)
+
+	redacted := query.redactedURL(u)
+	assert.NotContains(t, redacted, "sk-secret")
+	assert.Contains(t, redacted, "key="+redactedQueryParameter)
+	assert.Equal(t, "alt=sse&key=sk-secret", u.RawQuery, "redaction must not modify the URL")
+	assert.Equal(t, u.String(), UpstreamAuthConfig{}.redactedURL(u))
+	assert.Empty(t, query.redactedURL(nil))
+}
+
+func TestTransparentProxy_UpstreamAuthStyles(t *testing.T) {
+	tests := []struct {
+		name      string
+		auth      UpstreamAuthConfig
+		prepare   func(r *http.Request)
+		checkSeen func(t *testing.T, r *http.Request)
+	}{
+		{
+			name: "anthropic x-api-key",
+			auth: UpstreamAuthConfig{Style: AuthStyleXAPIKey, Headers: map[string]string{"anthropic-version": "2023-06-01"}},
+			prepare: func(r *http.Request) {
+				r.Header.Set("x-api-key", "tok")
+			},
+			checkSeen: func(t *testing.T, r *http.Request) {
+				assert.Equal(t, "api-key", r.Header.Get("x-api-key"))
+				assert.Equal(t, "2023-06-01", r.Header.Get("anthropic-version"))
+				assert.Empty(t, r.Header.Get("Authorization"))
+			},
+		},
+		{
+			name: "gemini query param",
+			auth: UpstreamAuthConfig{Style: AuthStyleQueryParam},
+			prepare: func(r *http.Request) {
+				q := r.URL.Query()
+				q.Set("key", "tok")
+				r.URL.RawQuery = q.Encode()
+			},
+			checkSeen: func(t *testing.T, r *http.Request) {
+				assert.Equal(t, "api-key", r.URL.Query().Get("key"))
+				assert.Empty(t, r.Header.Get("Authorization"))
+			},
+		},
+		{
+			name: "bearer",
+			prepare: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer tok")
+			},
+			checkSeen: func(t *testing.T, r *http.Request) {
+				assert.Equal(t, "Bearer api-key", r.Header.Get("Authorization"))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen *http.Request
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.Clone(context.Background())
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"ok":true}`))
+			}))
+			defer upstream.Close()
+
+			p, err := NewTransparentProxyWithLogger(ProxyConfig{
+				TargetBaseURL:    upstream.URL,
+				AllowedEndpoints: []string{"/v1/test"},
+				AllowedMethods:   []string{"GET"},
+				UpstreamAuth:     tt.auth,
+			}, &stubTokenValidator{}, &stubProjectStore{}, zap.NewNop())
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/v1/test", nil)
+			tt.prepare(req)
+			w := httptest.NewRecorder()
+			p.Handler().ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.NotNil(t, seen)
+			tt.checkSeen(t, seen)
+		})
+	}
+}
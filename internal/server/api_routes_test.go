@@ -152,3 +152,92 @@ func TestDefaultOpenAIFallback(t *testing.T) {
 
 	assert.Equal(t, http.StatusNotFound, respDisallowed.StatusCode, "Expected 404 for disallowed endpoint")
 }
+
+// TestAPIRoutesMultiProvider verifies that every configured provider is mounted
+// under its own prefix while the default provider keeps answering bare /v1/.
+func TestAPIRoutesMultiProvider(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "api_config_*.yaml")
+	require.NoError(t, err, "Failed to create temp file")
+	defer func() {
+		if err := os.Remove(tmpFile.Name()); err != nil {
+			t.Fatalf("failed to remove temp file: %v", err)
+		}
+	}()
+
+	testConfig := `
+default_api: openai
+apis:
+  openai:
+    base_url: https://api.openai.example.com
+    allowed_endpoints:
+      - /v1/chat/completions
+    allowed_methods:
+      - POST
+  anthropic:
+    base_url: https://api.anthropic.example.com
+    allowed_endpoints:
+      - /v1/messages
+    allowed_methods:
+      - POST
+`
+	_, err = tmpFile.Write([]byte(testConfig))
+	require.NoError(t, err, "Failed to write to temp file")
+	require.NoError(t, tmpFile.Close(), "Failed to close temp file")
+
+	cfg := &config.Config{
+		ListenAddr:      ":8080",
+		RequestTimeout:  30 * time.Second,
+		APIConfigPath:   tmpFile.Name(),
+		EventBusBackend: "in-memory",
+	}
+	srv, err := New(cfg, &mockTokenStore{}, &mockProjectStore{})
+	require.NoError(t, err)
+	require.NoError(t, srv.initializeAPIRoutes(), "Failed to initialize API routes")
+
+	require.Len(t, srv.providerProxies, 2)
+	assert.Same(t, srv.providerProxies["openai"], srv.proxy, "default provider should back the bare /v1/ route")
+	assert.Len(t, srv.proxies(), 2)
+
+	testServer := httptest.NewServer(srv.server.Handler)
+	defer testServer.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		notFound bool
+	}{
+		{name: "default provider bare prefix", path: "/v1/chat/completions"},
+		{name: "default provider named prefix", path: "/openai/v1/chat/completions"},
+		{name: "secondary provider named prefix", path: "/anthropic/v1/messages"},
+		{name: "secondary endpoint not on default", path: "/v1/messages", notFound: true},
+		{name: "default endpoint not on secondary", path: "/anthropic/v1/chat/completions", notFound: true},
+		{name: "unknown provider", path: "/unknown/v1/messages", notFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(testServer.URL+tt.path, "application/json", nil)
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+			if tt.notFound {
+				assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+			} else {
+				// No valid token is supplied, so a routed request fails authentication instead of 404.
+				assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestValidateProviderRouteName(t *testing.T) {
+	assert.NoError(t, validateProviderRouteName("openai"))
+	assert.NoError(t, validateProviderRouteName("azure-eu_1.prod"))
+	assert.Error(t, validateProviderRouteName(""))
+	assert.Error(t, validateProviderRouteName("v1"))
+	assert.Error(t, validateProviderRouteName("Manage"))
+	assert.Error(t, validateProviderRouteName("open/ai"))
+}
+
+func TestProviderCacheKeyPrefix(t *testing.T) {
+	assert.Equal(t, "llmproxy:cache:anthropic:", providerCacheKeyPrefix("", "anthropic"))
+	assert.Equal(t, "custom:anthropic:", providerCacheKeyPrefix("custom:", "anthropic"))
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/database"
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createBudgetSubjects stores the project proj-1 and its token tok-1, which
+// budgets and their spend reference.
+func createBudgetSubjects(t *testing.T, db *database.DB) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	require.NoError(t, db.CreateProject(ctx, proxy.Project{ID: "proj-1", Name: "proj-1", APIKey: "key", CreatedAt: now, UpdatedAt: now}))
+	require.NoError(t, db.CreateToken(ctx, database.Token{ID: "tok-1", Token: "budget-token", ProjectID: "proj-1", IsActive: true, CreatedAt: now}))
+}
+
+func TestHandleBudget(t *testing.T) {
+	srv, db := newCostTestServer(t, "", &parentTokenStore{parent: token.TokenData{ID: "tok-1", ProjectID: "proj-1", IsActive: true}})
+	require.NotNil(t, srv.budgets)
+	createBudgetSubjects(t, db)
+	h := srv.server.Handler
+	ctx := context.Background()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "/manage/projects/proj-1/budget", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	day := time.Now().UTC().Format(time.DateOnly)
+	require.NoError(t, db.AddBudgetSpend(ctx, map[proxy.SpendKey]proxy.Spend{
+		{SubjectType: proxy.BudgetSubjectProject, SubjectID: "proj-1", Day: day}: {CostUSD: 2, Tokens: 100},
+	}))
+	w = do(http.MethodPut, "/manage/projects/proj-1/budget", `{"period":"monthly","limit_usd":5}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var status proxy.BudgetStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, proxy.BudgetPeriodMonthly, status.Budget.Period)
+	assert.InDelta(t, 2.0, status.Spend.CostUSD, 1e-9)
+	require.NotNil(t, status.RemainingUSD)
+	assert.InDelta(t, 3.0, *status.RemainingUSD, 1e-9)
+	assert.Nil(t, status.RemainingTokens)
+	assert.True(t, status.ResetAt.After(time.Now()))
+
+	stored, err := db.GetBudget(ctx, proxy.BudgetSubjectProject, "proj-1")
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+
+	w = do(http.MethodPut, "/manage/tokens/tok-1/budget", `{"period":"daily","limit_tokens":1000}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = do(http.MethodGet, "/manage/tokens/tok-1/budget", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"remaining_tokens":1000`)
+
+	w = do(http.MethodPut, "/manage/tokens/tok-1/budget", `{"period":"weekly","limit_usd":1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "period")
+	w = do(http.MethodPut, "/manage/tokens/tok-1/budget", `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(http.MethodPut, "/manage/tokens/unknown/budget", `{"period":"daily","limit_usd":1}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = do(http.MethodPost, "/manage/tokens/tok-1/budget", `{}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = do(http.MethodDelete, "/manage/tokens/tok-1/budget", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = do(http.MethodGet, "/manage/tokens/tok-1/budget", "")
+	assert.Equal(t, http.StatusNotFound, w.Code, "deleted budgets no longer apply")
+	w = do(http.MethodDelete, "/manage/tokens/tok-1/budget", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	srv.db = nil
+	w = do(http.MethodGet, "/manage/projects/proj-1/budget", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestHandleTokenIntrospection_RemainingBudget(t *testing.T) {
+	tokenString, err := token.GenerateToken()
+	require.NoError(t, err)
+	srv, db := newCostTestServer(t, "", &parentTokenStore{parent: token.TokenData{ID: "tok-1", Token: tokenString, ProjectID: "proj-1", IsActive: true}})
+	createBudgetSubjects(t, db)
+	ctx := context.Background()
+
+	introspect := func() TokenIntrospectionResponse {
+		r := httptest.NewRequest(http.MethodGet, "/v1/proxy/token", nil)
+		r.Header.Set("Authorization", "Bearer "+tokenString)
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp TokenIntrospectionResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := introspect()
+	assert.Nil(t, resp.RemainingBudget)
+	assert.Nil(t, resp.RemainingBudgetTokens)
+
+	projectLimit, tokenLimit := 5.0, 10.0
+	tokens := int64(1000)
+	require.NoError(t, db.SetBudget(ctx, proxy.BudgetSubjectProject, "proj-1", proxy.Budget{Period: proxy.BudgetPeriodMonthly, LimitUSD: &projectLimit}))
+	require.NoError(t, db.SetBudget(ctx, proxy.BudgetSubjectToken, "tok-1", proxy.Budget{Period: proxy.BudgetPeriodDaily, LimitUSD: &tokenLimit, LimitTokens: &tokens}))
+	srv.budgets.Invalidate(proxy.BudgetSubjectProject, "proj-1")
+	srv.budgets.Invalidate(proxy.BudgetSubjectToken, "tok-1")
+
+	resp = introspect()
+	require.NotNil(t, resp.RemainingBudget)
+	assert.InDelta(t, 5.0, *resp.RemainingBudget, 1e-9, "the smaller remaining budget applies")
+	require.NotNil(t, resp.RemainingBudgetTokens)
+	assert.Equal(t, int64(1000), *resp.RemainingBudgetTokens)
+}
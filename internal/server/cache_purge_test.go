@@ -151,6 +151,15 @@ func TestHandleCachePurge_Errors(t *testing.T) {
 			expectedStatus: http.StatusInternalServerError,
 			expectedError:  "proxy not available",
 		},
+		{
+			name:           "unknown_provider",
+			method:         "POST",
+			requestBody:    `{"method":"GET","url":"/v1/models","provider":"missing"}`,
+			authHeader:     "Bearer test-token",
+			setupServer:    func(s *Server) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unknown provider",
+		},
 	}
 
 	for _, tt := range tests {
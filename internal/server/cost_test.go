@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/config"
+	"github.com/sofatutor/llm-proxy/internal/database"
+	"github.com/sofatutor/llm-proxy/internal/pricing"
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/sofatutor/llm-proxy/internal/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const priceCatalogTestFile = `models:
+  - model: gpt-4o-mini*
+    input_per_1k: 0.00015
+    output_per_1k: 0.0006
+`
+
+func newCostTestServer(t *testing.T, priceCatalogPath string, tokenStore token.TokenStore) (*Server, *database.DB) {
+	t.Helper()
+	db, err := database.New(database.Config{Path: filepath.Join(t.TempDir(), "costs.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	apiConfigPath := filepath.Join(t.TempDir(), "apis.yaml")
+	require.NoError(t, os.WriteFile(apiConfigPath, []byte(modelRoutesTestConfig), 0o600))
+	cfg := &config.Config{
+		ListenAddr:       ":8080",
+		RequestTimeout:   30 * time.Second,
+		APIConfigPath:    apiConfigPath,
+		PriceCatalogPath: priceCatalogPath,
+		EventBusBackend:  "in-memory",
+		ManagementToken:  "test-token",
+	}
+	srv, err := New(cfg, tokenStore, &mockProjectStore{})
+	require.NoError(t, err)
+	srv.db = db
+	require.NoError(t, srv.initializeAPIRoutes())
+	t.Cleanup(func() {
+		_ = srv.costAgg.Stop(context.Background())
+		_ = srv.budgets.Stop(context.Background())
+	})
+	return srv, db
+}
+
+func writePriceCatalog(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prices.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPriceCatalog(t *testing.T) {
+	ctx := context.Background()
+	path := writePriceCatalog(t, priceCatalogTestFile)
+
+	// The file seeds an empty database
+	srv, db := newCostTestServer(t, path, &mockTokenStore{})
+	require.NotNil(t, srv.costAgg)
+	_, ok := srv.prices.Lookup("gpt-4o-mini-2024-07-18")
+	assert.True(t, ok)
+	stored, err := db.ListModelPrices(ctx)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+
+	// Prices in the database win over the file
+	require.NoError(t, db.SetModelPrices(ctx, []pricing.Price{{Model: "claude-*", InputPer1K: 1}}))
+	prices, err := srv.loadPriceCatalog(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []pricing.Price{{Model: "claude-*", InputPer1K: 1}}, prices.Prices())
+
+	// Without a database the file is the catalog
+	srv.db = nil
+	prices, err = srv.loadPriceCatalog(ctx)
+	require.NoError(t, err)
+	assert.Len(t, prices.Prices(), 1)
+	assert.Equal(t, "gpt-4o-mini*", prices.Prices()[0].Model)
+
+	srv.config.PriceCatalogPath = writePriceCatalog(t, "models:\n  - model: m\n    input_per_1k: -1\n")
+	_, err = srv.loadPriceCatalog(ctx)
+	assert.Error(t, err)
+}
+
+func TestHandlePrices(t *testing.T) {
+	srv, db := newCostTestServer(t, "", &mockTokenStore{})
+	h := srv.server.Handler
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/manage/prices", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"prices":[]}`, w.Body.String())
+
+	w = do(http.MethodPut, `{"prices":[{"model":"gpt-4o*","input_per_1k":0.0025,"output_per_1k":0.01,"cached_input_per_1k":0.00125}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp PricesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Prices, 1)
+
+	// The new catalog prices requests immediately and is persisted
+	price, ok := srv.prices.Lookup("gpt-4o-2024-08-06")
+	require.True(t, ok)
+	assert.Equal(t, 0.0025, price.InputPer1K)
+	stored, err := db.ListModelPrices(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, resp.Prices, stored)
+
+	w = do(http.MethodPut, `{"prices":[{"model":"m","input_per_1k":-1}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "negative")
+	assert.Len(t, srv.prices.Prices(), 1, "invalid prices leave the catalog unchanged")
+
+	w = do(http.MethodPut, `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodPost, `{}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/manage/prices", nil)
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+}
+
+func TestHandleCosts(t *testing.T) {
+	srv, db := newCostTestServer(t, "", &mockTokenStore{})
+	h := srv.server.Handler
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	require.NoError(t, db.CreateProject(ctx, proxy.Project{ID: "proj-1", Name: "One", APIKey: "key", CreatedAt: now, UpdatedAt: now}))
+	require.NoError(t, db.CreateToken(ctx, database.Token{ID: "22222222-2222-2222-2222-222222222222", Token: "tok", ProjectID: "proj-1", IsActive: true, CreatedAt: now}))
+	delta := proxy.CostDelta{Requests: 2, InputTokens: 100, OutputTokens: 10, CostUSD: 0.5}
+	require.NoError(t, db.AddCosts(ctx,
+		map[string]proxy.CostDelta{"22222222-2222-2222-2222-222222222222": delta},
+		map[string]proxy.CostDelta{"proj-1": delta}))
+
+	get := func(url string) (int, CostsResponse) {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer test-token")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		var resp CostsResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return w.Code, resp
+	}
+
+	code, resp := get("/manage/costs")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(2), resp.Total.RequestCount)
+	assert.InDelta(t, 0.5, resp.Total.CostUSD, 1e-9)
+	require.Len(t, resp.Projects, 1)
+	assert.Equal(t, "proj-1", resp.Projects[0].ProjectID)
+	assert.Empty(t, resp.Tokens)
+
+	code, resp = get("/manage/costs?projectId=proj-1")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "proj-1", resp.ProjectID)
+	assert.Equal(t, int64(110), resp.Total.InputTokens+resp.Total.OutputTokens)
+	require.Len(t, resp.Tokens, 1)
+	assert.Equal(t, "22222222-2222-2222-2222-222222222222", resp.Tokens[0].TokenID)
+
+	code, resp = get("/manage/costs?projectId=unknown")
+	require.Equal(t, http.StatusOK, code)
+	assert.Zero(t, resp.Total.RequestCount)
+	assert.Empty(t, resp.Tokens)
+
+	req := httptest.NewRequest(http.MethodPost, "/manage/costs", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	srv.db = nil
+	code, _ = get("/manage/costs")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
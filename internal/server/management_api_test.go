@@ -90,7 +90,7 @@ type MockProjectStoreExtended struct {
 	projects []proxy.Project
 }
 
-func (m *MockProjectStoreExtended) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (m *MockProjectStoreExtended) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	args := m.Called(ctx, projectID)
 	return args.String(0), args.Error(1)
 }
@@ -773,6 +773,311 @@ func TestHandleCreateProject_StoreError(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
+func TestHandleCreateProject_ProviderAPIKeys(t *testing.T) {
+	server, _, projectStore := setupServerAndMocks(t)
+	server.providerProxies = map[string]*proxy.TransparentProxy{"openai": {}, "anthropic": {}}
+	projectStore.On("CreateProject", mock.Anything, mock.MatchedBy(func(p proxy.Project) bool {
+		return p.APIKey == "" && p.APIKeys["anthropic"] == "sk-ant-123"
+	})).Return(nil)
+
+	t.Run("provider keys only", func(t *testing.T) {
+		body := `{"name":"multi","api_keys":{"anthropic":"sk-ant-123"}}`
+		req := httptest.NewRequest("POST", "/manage/projects", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.handleCreateProject(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		body := `{"name":"multi","api_keys":{"gemini":"key"}}`
+		req := httptest.NewRequest("POST", "/manage/projects", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.handleCreateProject(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "unknown API provider")
+	})
+
+	t.Run("obfuscated provider key", func(t *testing.T) {
+		body := `{"name":"multi","api_keys":{"anthropic":"sk-a...123"}}`
+		req := httptest.NewRequest("POST", "/manage/projects", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.handleCreateProject(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandleUpdateProject_ProviderAPIKeys(t *testing.T) {
+	server, _, projectStore := setupServerAndMocks(t)
+	existing := proxy.Project{
+		ID:      "id",
+		Name:    "multi",
+		APIKey:  "sk-openai-legacy",
+		APIKeys: map[string]string{"anthropic": "sk-ant-old", "gemini": "g-old"},
+	}
+	projectStore.On("GetProjectByID", mock.Anything, "id").Return(existing, nil)
+	var updated proxy.Project
+	projectStore.On("UpdateProject", mock.Anything, mock.AnythingOfType("proxy.Project")).
+		Run(func(args mock.Arguments) { updated = args.Get(1).(proxy.Project) }).
+		Return(nil)
+
+	body := `{"api_keys":{"anthropic":"sk-ant-new","gemini":null,"mistral":"m-key"}}`
+	req := httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	server.handleUpdateProject(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]string{"anthropic": "sk-ant-new", "mistral": "m-key"}, updated.APIKeys)
+	assert.Equal(t, "sk-openai-legacy", updated.APIKey)
+
+	req = httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"api_keys":{"anthropic":"sk-a****new"}}`))
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleProjects_RequestPolicy(t *testing.T) {
+	server, _, projectStore := setupServerAndMocks(t)
+	projectStore.On("CreateProject", mock.Anything, mock.MatchedBy(func(p proxy.Project) bool {
+		return len(p.RequestPolicy) == 1 && p.RequestPolicy[0].Path == "$.tools" && p.RequestPolicy[0].Forbidden
+	})).Return(nil)
+	existing := proxy.Project{ID: "id", Name: "policy", RequestPolicy: []proxy.RequestPolicyRule{{Path: "$.tools", Forbidden: true}}}
+	projectStore.On("GetProjectByID", mock.Anything, "id").Return(existing, nil)
+	var updated proxy.Project
+	projectStore.On("UpdateProject", mock.Anything, mock.AnythingOfType("proxy.Project")).
+		Run(func(args mock.Arguments) { updated = args.Get(1).(proxy.Project) }).
+		Return(nil)
+
+	body := `{"name":"policy","api_key":"sk-test","request_policy":[{"path":"$.tools","forbidden":true}]}`
+	w := httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"request_policy":[{"path":"$.tools","forbidden":true}]`)
+
+	body = `{"name":"policy","api_key":"sk-test","request_policy":[{"path":"$.tools"}]}`
+	w = httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid request_policy")
+
+	w = httptest.NewRecorder()
+	server.handleGetProject(w, httptest.NewRequest("GET", "/manage/projects/id", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ProjectResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, existing.RequestPolicy, resp.RequestPolicy)
+
+	// Omitting request_policy keeps it; an empty list removes it
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"name":"renamed"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, existing.RequestPolicy, updated.RequestPolicy)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"request_policy":[]}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, updated.RequestPolicy)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"request_policy":[{"path":"$.temperature","min":2,"max":1}]}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "min is greater than max")
+}
+
+func TestHandleProjects_AllowedModels(t *testing.T) {
+	server, _, projectStore := setupServerAndMocks(t)
+	projectStore.On("CreateProject", mock.Anything, mock.MatchedBy(func(p proxy.Project) bool {
+		return len(p.AllowedModels) == 1 && p.AllowedModels[0] == "gpt-4o*"
+	})).Return(nil)
+	existing := proxy.Project{ID: "id", Name: "models", AllowedModels: []string{"gpt-4o*"}}
+	projectStore.On("GetProjectByID", mock.Anything, "id").Return(existing, nil)
+	var updated proxy.Project
+	projectStore.On("UpdateProject", mock.Anything, mock.AnythingOfType("proxy.Project")).
+		Run(func(args mock.Arguments) { updated = args.Get(1).(proxy.Project) }).
+		Return(nil)
+
+	w := httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(`{"name":"models","api_key":"sk-test","allowed_models":["gpt-4o*"]}`)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"allowed_models":["gpt-4o*"]`)
+
+	w = httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(`{"name":"models","api_key":"sk-test","allowed_models":["gpt-[4"]}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid allowed_models")
+
+	w = httptest.NewRecorder()
+	server.handleGetProject(w, httptest.NewRequest("GET", "/manage/projects/id", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ProjectResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, []string{"gpt-4o*"}, resp.AllowedModels)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"allowed_models":["o3-mini","gpt-4.1-*"]}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"o3-mini", "gpt-4.1-*"}, updated.AllowedModels)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"allowed_models":[]}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, updated.AllowedModels)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"allowed_models":[""]}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandleProjects_APIKeyPool(t *testing.T) {
+	server, _, projectStore := setupServerAndMocks(t)
+	server.providerProxies = map[string]*proxy.TransparentProxy{"openai": {}}
+	projectStore.On("CreateProject", mock.Anything, mock.MatchedBy(func(p proxy.Project) bool {
+		return len(p.APIKeyPool) == 2 && p.APIKeyPool[0].APIKey == "sk-pool-a" && p.APIKeyPool[0].Weight == 3
+	})).Return(nil)
+	existing := proxy.Project{ID: "id", Name: "pool", APIKeyPool: []proxy.APIKeyPoolEntry{{Provider: "openai", APIKey: "sk-pool-a-1234567890", Weight: 3}}}
+	projectStore.On("GetProjectByID", mock.Anything, "id").Return(existing, nil)
+	var updated proxy.Project
+	projectStore.On("UpdateProject", mock.Anything, mock.AnythingOfType("proxy.Project")).
+		Run(func(args mock.Arguments) { updated = args.Get(1).(proxy.Project) }).
+		Return(nil)
+
+	body := `{"name":"pool","api_key":"sk-test","api_key_pool":[{"provider":"openai","api_key":"sk-pool-a","weight":3},{"provider":"openai","api_key":"sk-pool-b"}]}`
+	w := httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	for _, body := range []string{
+		`{"name":"pool","api_key":"sk-test","api_key_pool":[{"provider":"gemini","api_key":"sk-gem"}]}`,
+		`{"name":"pool","api_key":"sk-test","api_key_pool":[{"api_key":"sk-a...123"}]}`,
+		`{"name":"pool","api_key":"sk-test","api_key_pool":[{"api_key":"sk-a","weight":-1}]}`,
+	} {
+		w = httptest.NewRecorder()
+		server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), "invalid api_key_pool")
+	}
+
+	w = httptest.NewRecorder()
+	server.handleGetProject(w, httptest.NewRequest("GET", "/manage/projects/id", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ProjectResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Len(t, resp.APIKeyPool, 1)
+	assert.Equal(t, "openai", resp.APIKeyPool[0].Provider)
+	assert.Equal(t, 3, resp.APIKeyPool[0].Weight)
+	assert.NotContains(t, resp.APIKeyPool[0].APIKey, "1234567890")
+
+	// Omitting api_key_pool keeps it; an empty list removes it
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"name":"renamed"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, existing.APIKeyPool, updated.APIKeyPool)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"api_key_pool":[]}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, updated.APIKeyPool)
+}
+
+func TestHandleProjects_ClientRestrictions(t *testing.T) {
+	server, _, projectStore := setupServerAndMocks(t)
+	projectStore.On("CreateProject", mock.Anything, mock.MatchedBy(func(p proxy.Project) bool {
+		return len(p.ClientRestrictions.CIDRs) == 1 && p.ClientRestrictions.CIDRs[0] == "10.0.0.0/8"
+	})).Return(nil)
+	existing := proxy.Project{ID: "id", Name: "clients", ClientRestrictions: token.ClientRestrictions{CIDRs: []string{"10.0.0.0/8"}}}
+	projectStore.On("GetProjectByID", mock.Anything, "id").Return(existing, nil)
+	var updated proxy.Project
+	projectStore.On("UpdateProject", mock.Anything, mock.AnythingOfType("proxy.Project")).
+		Run(func(args mock.Arguments) { updated = args.Get(1).(proxy.Project) }).
+		Return(nil)
+
+	w := httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(`{"name":"clients","api_key":"sk-test","client_restrictions":{"cidrs":["10.0.0.0/8"]}}`)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"client_restrictions":{"cidrs":["10.0.0.0/8"]}`)
+
+	w = httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(`{"name":"clients","api_key":"sk-test","client_restrictions":{"origins":["app.example.com"]}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid client_restrictions")
+
+	w = httptest.NewRecorder()
+	server.handleGetProject(w, httptest.NewRequest("GET", "/manage/projects/id", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ProjectResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.NotNil(t, resp.ClientRestrictions)
+	assert.Equal(t, []string{"10.0.0.0/8"}, resp.ClientRestrictions.CIDRs)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"client_restrictions":{"origins":["https://*.example.com/"]}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, token.ClientRestrictions{Origins: []string{"https://*.example.com"}}, updated.ClientRestrictions)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"client_restrictions":{}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, updated.ClientRestrictions.IsEmpty())
+}
+
+func TestHandleProjects_RateLimit(t *testing.T) {
+	server, _, projectStore := setupServerAndMocks(t)
+	projectStore.On("CreateProject", mock.Anything, mock.MatchedBy(func(p proxy.Project) bool {
+		return p.RateLimit == token.RateLimit{RequestsPerWindow: 100, WindowSeconds: 60}
+	})).Return(nil)
+	existing := proxy.Project{ID: "id", Name: "limited", RateLimit: token.RateLimit{RequestsPerWindow: 100, WindowSeconds: 60}}
+	projectStore.On("GetProjectByID", mock.Anything, "id").Return(existing, nil)
+	var updated proxy.Project
+	projectStore.On("UpdateProject", mock.Anything, mock.AnythingOfType("proxy.Project")).
+		Run(func(args mock.Arguments) { updated = args.Get(1).(proxy.Project) }).
+		Return(nil)
+
+	w := httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(`{"name":"limited","api_key":"sk-test","rate_limit":{"requests_per_window":100}}`)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	server.handleCreateProject(w, httptest.NewRequest("POST", "/manage/projects", strings.NewReader(`{"name":"limited","api_key":"sk-test","rate_limit":{"requests_per_window":-1}}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid rate_limit")
+
+	w = httptest.NewRecorder()
+	server.handleGetProject(w, httptest.NewRequest("GET", "/manage/projects/id", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ProjectResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.NotNil(t, resp.RateLimit)
+	assert.Equal(t, 100, resp.RateLimit.RequestsPerWindow)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"rate_limit":{"requests_per_window":10,"window_seconds":1,"burst":2}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, token.RateLimit{RequestsPerWindow: 10, WindowSeconds: 1, Burst: 2}, updated.RateLimit)
+
+	w = httptest.NewRecorder()
+	server.handleUpdateProject(w, httptest.NewRequest("PATCH", "/manage/projects/id", strings.NewReader(`{"rate_limit":{}}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, updated.RateLimit.IsZero())
+}
+
+func TestHandleGetProject_ObfuscatesProviderAPIKeys(t *testing.T) {
+	server, _, projectStore := setupServerAndMocks(t)
+	projectStore.On("GetProjectByID", mock.Anything, "id").Return(proxy.Project{
+		ID:      "id",
+		APIKeys: map[string]string{"anthropic": "sk-ant-REDACTED"},
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/manage/projects/id", nil)
+	w := httptest.NewRecorder()
+	server.handleGetProject(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ProjectResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.Contains(t, resp.APIKeys, "anthropic")
+	assert.NotEqual(t, "sk-ant-REDACTED", resp.APIKeys["anthropic"])
+	assert.NotContains(t, w.Body.String(), "abcdefghijklmnop")
+}
+
 func TestHandleGetProject_InvalidID(t *testing.T) {
 	server, _, _ := setupServerAndMocks(t)
 	req := httptest.NewRequest("GET", "/manage/projects/", nil)
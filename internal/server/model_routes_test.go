@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/config"
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const modelRoutesTestConfig = `
+default_api: openai
+model_routes:
+  - model: "claude-*"
+    provider: claude
+apis:
+  openai:
+    base_url: https://api.openai.example.com
+    allowed_endpoints:
+      - /v1/chat/completions
+    allowed_methods:
+      - POST
+  claude:
+    base_url: https://api.anthropic.example.com
+    mode: anthropic
+    allowed_endpoints:
+      - /v1/chat/completions
+    allowed_methods:
+      - POST
+`
+
+func newModelRoutesTestServer(t *testing.T) *Server {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(modelRoutesTestConfig), 0o600))
+
+	cfg := &config.Config{
+		ListenAddr:      ":8080",
+		RequestTimeout:  30 * time.Second,
+		APIConfigPath:   path,
+		EventBusBackend: "in-memory",
+		ManagementToken: "test-token",
+	}
+	srv, err := New(cfg, &mockTokenStore{}, &mockProjectStore{})
+	require.NoError(t, err)
+	require.NoError(t, srv.initializeAPIRoutes())
+	return srv
+}
+
+func TestAPIRoutesModelRouting(t *testing.T) {
+	srv := newModelRoutesTestServer(t)
+	require.NotNil(t, srv.modelRouter)
+
+	tests := []struct {
+		name         string
+		path         string
+		body         string
+		wantProvider string
+		wantRoute    string
+	}{
+		{name: "routed by model", path: "/v1/chat/completions", body: `{"model":"claude-sonnet-4"}`, wantProvider: "claude", wantRoute: "claude-*"},
+		{name: "unmatched model uses default", path: "/v1/chat/completions", body: `{"model":"gpt-4o"}`, wantProvider: "openai"},
+		{name: "provider prefix bypasses routing", path: "/openai/v1/chat/completions", body: `{"model":"claude-sonnet-4"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			srv.server.Handler.ServeHTTP(w, req)
+
+			// No valid token is supplied, so the routed provider rejects the request.
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, tt.wantProvider, w.Header().Get("X-Proxy-Provider"))
+			assert.Equal(t, tt.wantRoute, w.Header().Get("X-Proxy-Model-Route"))
+		})
+	}
+}
+
+func TestAPIRoutesModelRouting_UnknownProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apis.yaml")
+	content := strings.Replace(modelRoutesTestConfig, "provider: claude", "provider: bedrock", 1)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	_, err := proxy.LoadAPIConfigFromFile(path)
+	assert.ErrorContains(t, err, "unknown provider 'bedrock'")
+}
+
+func TestHandleModelRoutes(t *testing.T) {
+	srv := newModelRoutesTestServer(t)
+	h := srv.server.Handler
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/manage/routes", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) ModelRoutesResponse {
+		var resp ModelRoutesResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+		return resp
+	}
+
+	w := do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ModelRoutesResponse{
+		DefaultProvider: "openai",
+		Routes:          []proxy.ModelRoute{{Model: "claude-*", Provider: "claude"}},
+	}, decode(w))
+
+	w = do(http.MethodPut, `{"routes":[{"model":"gpt-*","provider":"openai"},{"model":"*","provider":"claude"}]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, decode(w).Routes, 2)
+
+	// The new table applies to proxied requests immediately.
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"mistral-large"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	assert.Equal(t, "claude", rw.Header().Get("X-Proxy-Provider"))
+	assert.Equal(t, "*", rw.Header().Get("X-Proxy-Model-Route"))
+
+	w = do(http.MethodPut, `{"routes":[{"model":"x-*","provider":"unknown"}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown provider")
+
+	w = do(http.MethodPut, `not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodPost, `{}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	// Clearing the table sends everything to the default provider.
+	w = do(http.MethodPut, `{"routes":[]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []proxy.ModelRoute{}, decode(w).Routes)
+
+	// Management auth is required.
+	req = httptest.NewRequest(http.MethodGet, "/manage/routes", nil)
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusUnauthorized, rw.Code)
+}
+
+func TestHandleModelRoutes_NotInitialized(t *testing.T) {
+	srv := &Server{logger: newModelRoutesTestServer(t).logger}
+	w := httptest.NewRecorder()
+	srv.handleModelRoutes(w, httptest.NewRequest(http.MethodGet, "/manage/routes", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -5,12 +5,17 @@ package server
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -24,6 +29,7 @@ import (
 	"github.com/sofatutor/llm-proxy/internal/logging"
 	"github.com/sofatutor/llm-proxy/internal/middleware"
 	"github.com/sofatutor/llm-proxy/internal/obfuscate"
+	"github.com/sofatutor/llm-proxy/internal/pricing"
 	"github.com/sofatutor/llm-proxy/internal/proxy"
 	"github.com/sofatutor/llm-proxy/internal/token"
 	"go.uber.org/zap"
@@ -33,19 +39,33 @@ import (
 // It encapsulates the underlying http.Server along with application configuration
 // and handles request routing and server lifecycle management.
 type Server struct {
-	server        *http.Server
-	config        *config.Config
-	tokenStore    token.TokenStore
-	projectStore  proxy.ProjectStore
-	logger        *zap.Logger
-	proxy         *proxy.TransparentProxy
-	metrics       Metrics
-	eventBus      eventbus.EventBus
-	auditLogger   *audit.Logger
-	db            *database.DB
-	cacheStatsAgg *proxy.CacheStatsAggregator
-	usageStatsAgg *token.UsageStatsAggregator
-	tokenHasher   encryption.TokenHasherInterface // Optional hasher for encryption support
+	server          *http.Server
+	config          *config.Config
+	tokenStore      token.TokenStore
+	projectStore    proxy.ProjectStore
+	logger          *zap.Logger
+	proxy           *proxy.TransparentProxy            // Proxy for the default provider (also serves /v1/)
+	providerProxies map[string]*proxy.TransparentProxy // Proxies keyed by provider name
+	modelRouter     *proxy.ModelRouter                 // Dispatches bare /v1/ requests by model
+	metrics         Metrics
+	eventBus        eventbus.EventBus
+	auditLogger     *audit.Logger
+	db              *database.DB
+	cacheStatsAgg   *proxy.CacheStatsAggregator
+	usageStatsAgg   *token.UsageStatsAggregator
+	costAgg         *proxy.CostAggregator           // Records per-token and per-project cost totals (nil without a database)
+	prices          *pricing.Catalog                // Model prices shared with costAgg
+	budgets         *proxy.BudgetTracker            // Enforces project and token spend budgets (nil without a database)
+	tpm             *token.TPMLimiter               // Enforces tokens-per-minute limits (nil when none are configured)
+	rateLimiter     *token.RedisRateLimiter         // Enforces per-project and per-token request rate limits
+	concurrency     *token.ConcurrencyLimiter       // Enforces per-project and per-token concurrency limits (nil when none are configured)
+	redisClient     *redis.Client                   // Shared by budgets and the rate, TPM and concurrency limiters (nil until one needs it)
+	tokenHasher     encryption.TokenHasherInterface // Optional hasher for encryption support
+	tokenValidator  *token.CachedValidator          // Validator shared by all provider proxies
+	childTokens     *token.ChildTokenCodec          // Signs and verifies child tokens (nil when disabled)
+	trustedProxies  []*net.IPNet                    // Load balancers allowed to report the client IP of child token mints
+	tokenLimits     token.RateLimiter               // Reports remaining requests to token introspection
+	tokenRevocation *token.AutomaticRevocation      // Deactivates expired tokens, e.g. rotated tokens past their grace period
 }
 
 // ServerOption is a functional option for configuring the server.
@@ -65,6 +85,9 @@ type HealthResponse struct {
 	Status    string    `json:"status"`    // Service status, "ok" for a healthy system
 	Timestamp time.Time `json:"timestamp"` // Current server time
 	Version   string    `json:"version"`   // Application version number
+	// CircuitBreakers reports the upstream circuit breakers; an open circuit
+	// degrades a provider but does not make the service unhealthy
+	CircuitBreakers []proxy.CircuitBreakerStatus `json:"circuit_breakers,omitempty"`
 }
 
 // Metrics holds runtime metrics for the server.
@@ -208,6 +231,9 @@ func NewWithDatabase(cfg *config.Config, tokenStore token.TokenStore, projectSto
 	mux.HandleFunc("/manage/audit", s.logRequestMiddleware(s.managementAuthMiddleware(s.handleAuditEvents)))
 	mux.HandleFunc("/manage/audit/", s.logRequestMiddleware(s.managementAuthMiddleware(s.handleAuditEventByID)))
 	mux.HandleFunc("/manage/cache/purge", s.logRequestMiddleware(s.managementAuthMiddleware(s.handleCachePurge)))
+	mux.HandleFunc("/manage/routes", s.logRequestMiddleware(s.managementAuthMiddleware(s.handleModelRoutes)))
+	mux.HandleFunc("/manage/prices", s.logRequestMiddleware(s.managementAuthMiddleware(s.handlePrices)))
+	mux.HandleFunc("/manage/costs", s.logRequestMiddleware(s.managementAuthMiddleware(s.handleCosts)))
 
 	// Add catch-all handler for unmatched routes to ensure logging
 	mux.HandleFunc("/", s.logRequestMiddleware(s.handleNotFound))
@@ -247,12 +273,59 @@ func (s *Server) initializeComponents() error {
 		return fmt.Errorf("failed to initialize API routes: %w", err)
 	}
 
+	s.startTokenRevocation()
+
 	// Pending: database, logging, admin, and metrics initialization.
 	// See server_test.go for test stubs covering these responsibilities.
 
 	return nil
 }
 
+// startTokenRevocation periodically deactivates expired tokens, including
+// rotated tokens whose grace period has ended. It needs a database and is
+// disabled when TokenCleanupInterval is not positive.
+func (s *Server) startTokenRevocation() {
+	if s.db == nil || s.config.TokenCleanupInterval <= 0 || s.tokenRevocation != nil {
+		return
+	}
+	revoker := token.NewRevoker(database.NewDBTokenStoreAdapter(s.db))
+	s.tokenRevocation = token.NewAutomaticRevocation(revoker, s.config.TokenCleanupInterval, s.logger)
+	s.tokenRevocation.Start()
+	s.logger.Info("Automatic token revocation started", zap.Duration("interval", s.config.TokenCleanupInterval))
+}
+
+// newRateLimiter creates the limiter for the request rate limits stored with
+// projects and tokens. With distributed rate limiting, counters live in Redis
+// and tokens without their own limit get DISTRIBUTED_RATE_LIMIT_MAX requests
+// per DISTRIBUTED_RATE_LIMIT_WINDOW; otherwise counters are per instance and
+// only stored limits apply.
+func (s *Server) newRateLimiter() *token.RedisRateLimiter {
+	if !s.config.DistributedRateLimitEnabled {
+		return token.NewRedisRateLimiter(token.NewMemoryRateLimitClient(), token.RedisRateLimiterConfig{
+			DefaultWindowDuration: token.DefaultRateLimitWindow,
+		})
+	}
+	cfg := token.DefaultRedisRateLimiterConfig()
+	cfg.KeyPrefix = s.config.DistributedRateLimitPrefix
+	cfg.KeyHashSecret = []byte(s.config.DistributedRateLimitKeySecret)
+	cfg.DefaultWindowDuration = s.config.DistributedRateLimitWindow
+	cfg.DefaultMaxRequests = s.config.DistributedRateLimitMax
+	cfg.EnableFallback = s.config.DistributedRateLimitFallback
+	s.logger.Info("Distributed rate limiting enabled",
+		zap.Int("max_requests", cfg.DefaultMaxRequests),
+		zap.Duration("window", cfg.DefaultWindowDuration))
+	return token.NewRedisRateLimiter(token.NewRedisGoRateLimitAdapter(s.redis()), cfg)
+}
+
+// redis returns the Redis client shared by budgets and the rate, TPM and
+// concurrency limiters, connecting on first use. Shutdown closes it.
+func (s *Server) redis() *redis.Client {
+	if s.redisClient == nil {
+		s.redisClient = redis.NewClient(&redis.Options{Addr: s.config.RedisAddr, DB: s.config.RedisDB})
+	}
+	return s.redisClient
+}
+
 // initializeAPIRoutes sets up the API proxy routes based on configuration
 func (s *Server) initializeAPIRoutes() error {
 	// Load API providers configuration
@@ -297,54 +370,36 @@ func (s *Server) initializeAPIRoutes() error {
 		}
 	}
 
-	// Get proxy configuration for the default API provider
-	proxyConfig, err := apiConfig.GetProxyConfigForAPI(s.config.DefaultAPIProvider)
-	if err != nil {
-		// If specified provider doesn't exist, use the default one
-		s.logger.Warn("Specified API provider not found, using default", zap.Error(err))
-		proxyConfig, err = apiConfig.GetProxyConfigForAPI(apiConfig.DefaultAPI)
-		if err != nil {
-			return fmt.Errorf("failed to get proxy configuration: %w", err)
+	// Resolve the default provider: DEFAULT_API_PROVIDER wins when it exists in the config.
+	defaultProvider := s.config.DefaultAPIProvider
+	if _, ok := apiConfig.APIs[defaultProvider]; !ok {
+		if defaultProvider != "" {
+			s.logger.Warn("Specified API provider not found, using default",
+				zap.String("provider", defaultProvider),
+				zap.String("default_api", apiConfig.DefaultAPI))
 		}
+		defaultProvider = apiConfig.DefaultAPI
 	}
-
-	// Apply HTTP cache env overrides (simple toggle + backend selection)
-	if v := os.Getenv("HTTP_CACHE_ENABLED"); v != "" {
-		// Parse bool; default to true on invalid for safety
-		proxyConfig.HTTPCacheEnabled = strings.EqualFold(v, "true") || strings.EqualFold(v, "1") || strings.EqualFold(v, "yes")
-	} else {
-		// Default: enabled
-		proxyConfig.HTTPCacheEnabled = true
-	}
-	if v := os.Getenv("HTTP_CACHE_STREAM_RESPONSES"); v != "" {
-		proxyConfig.HTTPCacheStreamResponses = strings.EqualFold(v, "true") || strings.EqualFold(v, "1") || strings.EqualFold(v, "yes")
-	}
-	backend := strings.ToLower(os.Getenv("HTTP_CACHE_BACKEND"))
-	if backend == "redis" {
-		// Use REDIS_CACHE_URL if explicitly set, otherwise construct from REDIS_ADDR
-		url := os.Getenv("REDIS_CACHE_URL")
-		if url == "" {
-			// Construct URL from unified REDIS_ADDR config (same as event bus)
-			addr := os.Getenv("REDIS_ADDR")
-			if addr == "" {
-				addr = "localhost:6379"
-			}
-			db := os.Getenv("REDIS_DB")
-			if db == "" {
-				db = "0"
-			}
-			url = fmt.Sprintf("redis://%s/%s", addr, db)
-		}
-		proxyConfig.RedisCacheURL = url
-		if kp := os.Getenv("REDIS_CACHE_KEY_PREFIX"); kp != "" {
-			proxyConfig.RedisCacheKeyPrefix = kp
-		}
+	if _, ok := apiConfig.APIs[defaultProvider]; !ok {
+		return fmt.Errorf("failed to get proxy configuration: API provider '%s' not found in configuration", defaultProvider)
 	}
 
 	// Use the injected tokenStore and projectStore
 	// (No more creation of mock stores or test data here)
 	tokenValidator := token.NewValidator(s.tokenStore)
 
+	// Signed child tokens are verified without a DB lookup of their own
+	childTokens, err := childTokenCodec(s.config)
+	if err != nil {
+		return fmt.Errorf("invalid child token configuration: %w", err)
+	}
+	if childTokens != nil {
+		tokenValidator.EnableChildTokens(childTokens, s.config.ChildTokenParentTTL)
+		s.logger.Info("Signed child tokens enabled",
+			zap.Bool("minting", childTokens.CanSign()),
+			zap.Duration("parent_cache_ttl", s.config.ChildTokenParentTTL))
+	}
+
 	// Async usage tracking for unlimited tokens: keep DB writes off the hot path.
 	if s.db != nil {
 		usageCfg := token.DefaultUsageStatsAggregatorConfig()
@@ -365,7 +420,82 @@ func (s *Server) initializeAPIRoutes() error {
 		s.logger.Info("Usage stats aggregator started", zap.Int("buffer_size", usageCfg.BufferSize))
 	}
 
+	// Price the usage of proxied requests to keep per-token and per-project cost totals
+	s.prices, err = s.loadPriceCatalog(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to load price catalog: %w", err)
+	}
+	if s.db != nil && s.costAgg == nil {
+		s.costAgg = proxy.NewCostAggregator(proxy.DefaultCostAggregatorConfig(), s.db, s.prices, s.logger)
+		s.costAgg.Start()
+		s.logger.Info("Cost aggregator started", zap.Int("prices", len(s.prices.Prices())))
+	}
+	if s.costAgg != nil && s.budgets == nil {
+		var counter proxy.SpendCounter
+		if s.config.BudgetRedisEnabled {
+			counter = proxy.NewRedisSpendCounter(s.redis(), s.config.BudgetRedisKeyPrefix)
+		}
+		budgetCfg := proxy.DefaultBudgetTrackerConfig()
+		budgetCfg.WebhookURL = s.config.BudgetWebhookURL
+		s.budgets = proxy.NewBudgetTracker(budgetCfg, s.db, counter, s.auditLogger, s.logger)
+		s.budgets.Start()
+		s.costAgg.SetBudgetTracker(s.budgets)
+		s.logger.Info("Budget tracker started", zap.Bool("redis", s.config.BudgetRedisEnabled))
+	}
+	if s.tpm == nil && (s.config.TPMLimitGlobal > 0 || s.config.TPMLimitPerProject > 0 || s.config.TPMLimitPerToken > 0) {
+		var store token.TPMStore = token.NewMemoryTPMStore()
+		if s.config.DistributedRateLimitEnabled {
+			store = token.NewRedisTPMStore(s.redis())
+		}
+		s.tpm = token.NewTPMLimiter(store, token.TPMLimiterConfig{
+			KeyPrefix:     s.config.DistributedRateLimitPrefix + "tpm:",
+			KeyHashSecret: []byte(s.config.DistributedRateLimitKeySecret),
+			Limits: token.TPMLimits{
+				Global:     s.config.TPMLimitGlobal,
+				PerProject: s.config.TPMLimitPerProject,
+				PerToken:   s.config.TPMLimitPerToken,
+			},
+			EnableFallback: s.config.DistributedRateLimitFallback,
+		})
+		s.logger.Info("Tokens per minute limits enabled",
+			zap.Int64("global", s.config.TPMLimitGlobal),
+			zap.Int64("per_project", s.config.TPMLimitPerProject),
+			zap.Int64("per_token", s.config.TPMLimitPerToken),
+			zap.Bool("redis", s.config.DistributedRateLimitEnabled))
+	}
+	if s.concurrency == nil && (s.config.ConcurrencyLimitPerProject > 0 || s.config.ConcurrencyLimitPerToken > 0) {
+		var store token.ConcurrencyStore = token.NewMemoryConcurrencyStore()
+		if s.config.DistributedRateLimitEnabled {
+			store = token.NewRedisConcurrencyStore(s.redis())
+		}
+		s.concurrency = token.NewConcurrencyLimiter(store, token.ConcurrencyLimiterConfig{
+			KeyPrefix:     s.config.DistributedRateLimitPrefix + "concurrency:",
+			KeyHashSecret: []byte(s.config.DistributedRateLimitKeySecret),
+			Limits: token.ConcurrencyLimits{
+				PerProject: s.config.ConcurrencyLimitPerProject,
+				PerToken:   s.config.ConcurrencyLimitPerToken,
+			},
+			LeaseTTL:       s.config.ConcurrencyLeaseTTL,
+			EnableFallback: s.config.DistributedRateLimitFallback,
+		})
+		s.logger.Info("Concurrency limits enabled",
+			zap.Int64("per_project", s.config.ConcurrencyLimitPerProject),
+			zap.Int64("per_token", s.config.ConcurrencyLimitPerToken),
+			zap.Bool("redis", s.config.DistributedRateLimitEnabled))
+	}
+
+	if s.rateLimiter == nil {
+		s.rateLimiter = s.newRateLimiter()
+	}
+
 	cachedValidator := token.NewCachedValidator(tokenValidator)
+	s.tokenValidator = cachedValidator
+	s.childTokens = childTokens
+	if s.trustedProxies, err = token.ParseNetworks(s.config.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	// Introspection reads uncached token data for current request counts
+	s.tokenLimits = token.NewRateLimiter(token.NewTokenDataRateLimitStore(tokenValidator))
 
 	obsCfg := middleware.ObservabilityConfig{
 		Enabled:              s.config.ObservabilityEnabled,
@@ -385,57 +515,308 @@ func (s *Server) initializeAPIRoutes() error {
 			zap.Int("max", s.config.APIKeyCacheMax),
 		)
 	}
-	if proxyConfig.EnforceProjectActive && s.config.ActiveCacheTTL > 0 && s.config.ActiveCacheMax > 0 {
-		projectStore = proxy.NewCachedProjectActiveStore(projectStore, proxy.CachedProjectActiveStoreConfig{
-			TTL: s.config.ActiveCacheTTL,
-			Max: s.config.ActiveCacheMax,
-		})
-		s.logger.Info("Project active status cache enabled",
-			zap.Duration("ttl", s.config.ActiveCacheTTL),
-			zap.Int("max", s.config.ActiveCacheMax),
-		)
+
+	// Build one proxy per provider. Each provider gets its own transport,
+	// allowlists and cache namespace. The bare /v1/ prefix is served by the
+	// model router, which falls back to the default provider.
+	providerNames := make([]string, 0, len(apiConfig.APIs))
+	for name := range apiConfig.APIs {
+		if err := validateProviderRouteName(name); err != nil {
+			return err
+		}
+		providerNames = append(providerNames, name)
+	}
+	sort.Strings(providerNames)
+
+	mux := s.server.Handler.(*http.ServeMux)
+	s.providerProxies = make(map[string]*proxy.TransparentProxy, len(providerNames))
+	handlers := make(map[string]http.Handler, len(providerNames))
+	for _, name := range providerNames {
+		proxyConfig, err := apiConfig.GetProxyConfigForAPI(name)
+		if err != nil {
+			return fmt.Errorf("failed to get proxy configuration: %w", err)
+		}
+		applyHTTPCacheEnv(proxyConfig)
+		proxyConfig.TrustedProxies = s.config.TrustedProxies
+		// Only the default provider may fall back to the legacy project api_key;
+		// other providers require a per-provider key on the project.
+		proxyConfig.ProviderKeyFallback = name == defaultProvider
+		if name != defaultProvider {
+			// Namespace shared (Redis) cache keys so identical paths on different
+			// providers never collide. The default provider keeps the base prefix
+			// so existing cache entries and purge tooling remain valid.
+			proxyConfig.RedisCacheKeyPrefix = providerCacheKeyPrefix(proxyConfig.RedisCacheKeyPrefix, name)
+		}
+
+		// The active cache also holds project model allowlists, which are
+		// enforced whether or not project active status is.
+		providerStore := projectStore
+		if s.config.ActiveCacheTTL > 0 && s.config.ActiveCacheMax > 0 {
+			providerStore = proxy.NewCachedProjectActiveStore(providerStore, proxy.CachedProjectActiveStoreConfig{
+				TTL: s.config.ActiveCacheTTL,
+				Max: s.config.ActiveCacheMax,
+			})
+			s.logger.Info("Project active status cache enabled",
+				zap.String("provider", name),
+				zap.Duration("ttl", s.config.ActiveCacheTTL),
+				zap.Int("max", s.config.ActiveCacheMax),
+			)
+		}
+
+		proxyHandler, err := proxy.NewTransparentProxyWithAudit(*proxyConfig, cachedValidator, providerStore, s.logger, s.auditLogger, obsCfg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize proxy for provider '%s': %w", name, err)
+		}
+		s.providerProxies[name] = proxyHandler
+
+		// Initialize cache stats aggregator for per-token cache hit tracking.
+		// NOTE: Cache stats tracking is only enabled when HTTP caching is enabled (HTTPCacheEnabled=true).
+		// When caching is disabled, no cache hits occur, so tracking is not needed.
+		// The Admin UI will show CacheHitCount=0 for all tokens when caching is disabled.
+		// A single aggregator is shared by all providers.
+		if s.db != nil && proxyConfig.HTTPCacheEnabled {
+			if s.cacheStatsAgg == nil {
+				aggConfig := proxy.CacheStatsAggregatorConfig{
+					BufferSize:    s.config.CacheStatsBufferSize,
+					FlushInterval: 5 * time.Second,
+					BatchSize:     100,
+				}
+
+				// Use the raw DB store, but wrap with secure store if encryption is enabled
+				var cacheStatsStore proxy.CacheStatsStore = s.db
+				if s.tokenHasher != nil {
+					cacheStatsStore = encryption.NewSecureCacheStatsStore(s.db, s.tokenHasher)
+					s.logger.Debug("Using secure cache stats store with token hashing")
+				}
+
+				s.cacheStatsAgg = proxy.NewCacheStatsAggregator(aggConfig, cacheStatsStore, s.logger)
+				s.cacheStatsAgg.Start()
+				s.logger.Info("Cache stats aggregator started", zap.Int("buffer_size", aggConfig.BufferSize))
+			}
+			proxyHandler.SetCacheStatsAggregator(s.cacheStatsAgg)
+		}
+		if s.costAgg != nil {
+			proxyHandler.SetCostAggregator(s.costAgg)
+		}
+		if s.budgets != nil {
+			proxyHandler.SetBudgetTracker(s.budgets)
+		}
+		if s.tpm != nil {
+			proxyHandler.SetTPMLimiter(s.tpm)
+		}
+		if s.concurrency != nil {
+			proxyHandler.SetConcurrencyLimiter(s.concurrency)
+		}
+		proxyHandler.SetRateLimiter(s.rateLimiter)
+
+		// Register provider-prefixed proxy routes (e.g. /anthropic/v1/messages).
+		// The prefix is stripped so allowlists and upstream paths stay provider-native.
+		handler := proxyHandler.Handler()
+		handlers[name] = handler
+		prefix := "/" + name
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, handler))
+		if name == defaultProvider {
+			s.proxy = proxyHandler
+		}
+
+		s.logger.Info("Initialized proxy",
+			zap.String("provider", name),
+			zap.String("route_prefix", prefix+"/"),
+			zap.Bool("default", name == defaultProvider),
+			zap.String("target_base_url", proxyConfig.TargetBaseURL),
+			zap.Int("allowed_endpoints", len(proxyConfig.AllowedEndpoints)))
+	}
+
+	// Fallback chains may hand a request to any other provider's proxy
+	for _, p := range s.providerProxies {
+		p.SetFallbackProviders(s.providerProxies)
 	}
 
-	proxyHandler, err := proxy.NewTransparentProxyWithAudit(*proxyConfig, cachedValidator, projectStore, s.logger, s.auditLogger, obsCfg)
+	s.modelRouter, err = proxy.NewModelRouter(handlers, defaultProvider, apiConfig.ModelRoutes)
 	if err != nil {
-		return fmt.Errorf("failed to initialize proxy: %w", err)
+		return fmt.Errorf("failed to initialize model routes: %w", err)
+	}
+	mux.Handle("/v1/", s.modelRouter)
+	mux.HandleFunc("/v1/proxy/token", s.logRequestMiddleware(s.handleTokenIntrospection))
+	if childTokens != nil && childTokens.CanSign() {
+		mux.HandleFunc("/v1/proxy/child-tokens", s.logRequestMiddleware(s.handleMintChildToken))
+	}
+	if len(apiConfig.ModelRoutes) > 0 {
+		s.logger.Info("Model routing enabled", zap.Int("routes", len(apiConfig.ModelRoutes)))
 	}
-	s.proxy = proxyHandler
 
-	// Initialize cache stats aggregator for per-token cache hit tracking.
-	// NOTE: Cache stats tracking is only enabled when HTTP caching is enabled (HTTPCacheEnabled=true).
-	// When caching is disabled, no cache hits occur, so tracking is not needed.
-	// The Admin UI will show CacheHitCount=0 for all tokens when caching is disabled.
-	if s.db != nil && proxyConfig.HTTPCacheEnabled {
-		aggConfig := proxy.CacheStatsAggregatorConfig{
-			BufferSize:    s.config.CacheStatsBufferSize,
-			FlushInterval: 5 * time.Second,
-			BatchSize:     100,
-		}
+	return nil
+}
 
-		// Use the raw DB store, but wrap with secure store if encryption is enabled
-		var cacheStatsStore proxy.CacheStatsStore = s.db
-		if s.tokenHasher != nil {
-			cacheStatsStore = encryption.NewSecureCacheStatsStore(s.db, s.tokenHasher)
-			s.logger.Debug("Using secure cache stats store with token hashing")
+// loadPriceCatalog builds the model price catalog. The database holds the
+// catalog; the PriceCatalogPath file seeds it while it is empty and is the
+// only source without a database.
+func (s *Server) loadPriceCatalog(ctx context.Context) (*pricing.Catalog, error) {
+	path := s.config.PriceCatalogPath
+	if s.db != nil {
+		prices, err := s.db.ListModelPrices(ctx)
+		if err != nil {
+			return nil, err
 		}
+		if len(prices) > 0 || path == "" {
+			return pricing.NewCatalog(prices), nil
+		}
+	}
+	if path == "" {
+		return pricing.NewCatalog(nil), nil
+	}
 
-		s.cacheStatsAgg = proxy.NewCacheStatsAggregator(aggConfig, cacheStatsStore, s.logger)
-		s.cacheStatsAgg.Start()
-		proxyHandler.SetCacheStatsAggregator(s.cacheStatsAgg)
-		s.logger.Info("Cache stats aggregator started", zap.Int("buffer_size", aggConfig.BufferSize))
+	prices, err := pricing.LoadFile(path)
+	if err != nil {
+		return nil, err
 	}
+	if s.db != nil {
+		if err := s.db.SetModelPrices(ctx, prices); err != nil {
+			return nil, err
+		}
+		s.logger.Info("Seeded model price catalog", zap.String("path", path), zap.Int("prices", len(prices)))
+	}
+	return pricing.NewCatalog(prices), nil
+}
 
-	// Register proxy routes
-	s.server.Handler.(*http.ServeMux).Handle("/v1/", proxyHandler.Handler())
+// reservedRouteNames are top-level path segments owned by the server itself.
+// A provider with one of these names would shadow a built-in route.
+var reservedRouteNames = map[string]bool{
+	"v1":      true,
+	"health":  true,
+	"ready":   true,
+	"live":    true,
+	"manage":  true,
+	"metrics": true,
+}
 
-	s.logger.Info("Initialized proxy",
-		zap.String("target_base_url", proxyConfig.TargetBaseURL),
-		zap.Int("allowed_endpoints", len(proxyConfig.AllowedEndpoints)))
+// childTokenCodec builds the child token codec from the configuration. It
+// returns nil when no child token key is configured.
+func childTokenCodec(cfg *config.Config) (*token.ChildTokenCodec, error) {
+	hasEd25519 := cfg.ChildTokenPrivateKey != "" || cfg.ChildTokenPublicKey != ""
+	switch {
+	case cfg.ChildTokenSecret != "" && hasEd25519:
+		return nil, fmt.Errorf("set either CHILD_TOKEN_SECRET or an Ed25519 key, not both")
+	case cfg.ChildTokenSecret != "":
+		return token.NewHMACChildTokenCodec([]byte(cfg.ChildTokenSecret))
+	case !hasEd25519:
+		return nil, nil
+	}
+
+	var (
+		public  ed25519.PublicKey
+		private ed25519.PrivateKey
+		err     error
+	)
+	if cfg.ChildTokenPublicKey != "" {
+		if public, err = token.ParseEd25519PublicKey(cfg.ChildTokenPublicKey); err != nil {
+			return nil, fmt.Errorf("CHILD_TOKEN_PUBLIC_KEY: %w", err)
+		}
+	}
+	if cfg.ChildTokenPrivateKey != "" {
+		if private, err = token.ParseEd25519PrivateKey(cfg.ChildTokenPrivateKey); err != nil {
+			return nil, fmt.Errorf("CHILD_TOKEN_PRIVATE_KEY: %w", err)
+		}
+	}
+	return token.NewEd25519ChildTokenCodec(public, private)
+}
 
+// validateProviderRouteName checks that a provider name can be used as a
+// single URL path segment without clashing with built-in server routes.
+func validateProviderRouteName(name string) error {
+	if name == "" {
+		return fmt.Errorf("API provider name must not be empty")
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return fmt.Errorf("API provider name '%s' contains characters not allowed in a route prefix", name)
+		}
+	}
+	if reservedRouteNames[strings.ToLower(name)] {
+		return fmt.Errorf("API provider name '%s' is reserved", name)
+	}
 	return nil
 }
 
+// providerCacheKeyPrefix returns the Redis cache key prefix for a non-default provider.
+func providerCacheKeyPrefix(base, provider string) string {
+	if base == "" {
+		base = "llmproxy:cache:"
+	}
+	return base + provider + ":"
+}
+
+// applyHTTPCacheEnv applies the HTTP cache env overrides (simple toggle + backend selection).
+func applyHTTPCacheEnv(proxyConfig *proxy.ProxyConfig) {
+	if v := os.Getenv("HTTP_CACHE_ENABLED"); v != "" {
+		// Parse bool; default to true on invalid for safety
+		proxyConfig.HTTPCacheEnabled = strings.EqualFold(v, "true") || strings.EqualFold(v, "1") || strings.EqualFold(v, "yes")
+	} else {
+		// Default: enabled
+		proxyConfig.HTTPCacheEnabled = true
+	}
+	if v := os.Getenv("HTTP_CACHE_STREAM_RESPONSES"); v != "" {
+		proxyConfig.HTTPCacheStreamResponses = strings.EqualFold(v, "true") || strings.EqualFold(v, "1") || strings.EqualFold(v, "yes")
+	}
+	backend := strings.ToLower(os.Getenv("HTTP_CACHE_BACKEND"))
+	if backend == "redis" {
+		// Use REDIS_CACHE_URL if explicitly set, otherwise construct from REDIS_ADDR
+		url := os.Getenv("REDIS_CACHE_URL")
+		if url == "" {
+			// Construct URL from unified REDIS_ADDR config (same as event bus)
+			addr := os.Getenv("REDIS_ADDR")
+			if addr == "" {
+				addr = "localhost:6379"
+			}
+			db := os.Getenv("REDIS_DB")
+			if db == "" {
+				db = "0"
+			}
+			url = fmt.Sprintf("redis://%s/%s", addr, db)
+		}
+		proxyConfig.RedisCacheURL = url
+		if kp := os.Getenv("REDIS_CACHE_KEY_PREFIX"); kp != "" {
+			proxyConfig.RedisCacheKeyPrefix = kp
+		}
+	}
+}
+
+// proxies returns every distinct provider proxy, starting with the default one.
+func (s *Server) proxies() []*proxy.TransparentProxy {
+	var out []*proxy.TransparentProxy
+	if s.proxy != nil {
+		out = append(out, s.proxy)
+	}
+	names := make([]string, 0, len(s.providerProxies))
+	for name := range s.providerProxies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if p := s.providerProxies[name]; p != nil && p != s.proxy {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// circuitBreakers returns the circuit breaker states of all provider proxies.
+func (s *Server) circuitBreakers() []proxy.CircuitBreakerStatus {
+	var out []proxy.CircuitBreakerStatus
+	for _, p := range s.proxies() {
+		out = append(out, p.CircuitBreakers()...)
+	}
+	return out
+}
+
+// providerProxy returns the proxy for a named provider, or the default proxy when name is empty.
+func (s *Server) providerProxy(name string) *proxy.TransparentProxy {
+	if name == "" {
+		return s.proxy
+	}
+	return s.providerProxies[name]
+}
+
 // Shutdown gracefully shuts down the server without interrupting
 // active connections. It waits for all connections to complete
 // or for the provided context to be canceled, whichever comes first.
@@ -451,6 +832,26 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	if s.tokenRevocation != nil {
+		s.tokenRevocation.Stop()
+	}
+
+	// Stop cost aggregator to flush pending costs
+	if s.costAgg != nil {
+		s.logger.Info("Stopping cost aggregator")
+		if err := s.costAgg.Stop(ctx); err != nil {
+			s.logger.Error("failed to stop cost aggregator during shutdown", zap.Error(err))
+		}
+	}
+
+	// Stop budget tracker after the cost aggregator, which feeds it
+	if s.budgets != nil {
+		s.logger.Info("Stopping budget tracker")
+		if err := s.budgets.Stop(ctx); err != nil {
+			s.logger.Error("failed to stop budget tracker during shutdown", zap.Error(err))
+		}
+	}
+
 	// Stop cache stats aggregator to flush pending stats
 	if s.cacheStatsAgg != nil {
 		s.logger.Info("Stopping cache stats aggregator")
@@ -464,7 +865,15 @@ func (s *Server) Shutdown(ctx context.Context) error {
 			s.logger.Error("failed to close audit logger during shutdown", zap.Error(err))
 		}
 	}
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+
+	// Close Redis once in-flight requests no longer use it
+	if s.redisClient != nil {
+		if closeErr := s.redisClient.Close(); closeErr != nil {
+			s.logger.Error("failed to close Redis client during shutdown", zap.Error(closeErr))
+		}
+	}
+	return err
 }
 
 // handleHealth is the HTTP handler for the health check endpoint.
@@ -475,9 +884,10 @@ func (s *Server) Shutdown(ctx context.Context) error {
 // and container orchestration systems to verify service health.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
-		Status:    "ok",
-		Timestamp: time.Now(),
-		Version:   Version,
+		Status:          "ok",
+		Timestamp:       time.Now(),
+		Version:         Version,
+		CircuitBreakers: s.circuitBreakers(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -512,17 +922,30 @@ func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
 		CacheMisses int64 `json:"cache_misses"`
 		CacheBypass int64 `json:"cache_bypass"`
 		CacheStores int64 `json:"cache_stores"`
+		// Hedged request metrics
+		HedgedRequests int64 `json:"hedged_requests"`
+		HedgeWins      int64 `json:"hedge_wins"`
+		// Client restriction denials
+		ClientIPDenials int64 `json:"client_ip_denials"`
+		OriginDenials   int64 `json:"origin_denials"`
+		// Upstream circuit breaker states
+		CircuitBreakers []proxy.CircuitBreakerStatus `json:"circuit_breakers,omitempty"`
 	}{
-		UptimeSeconds: time.Since(s.metrics.StartTime).Seconds(),
-	}
-	if s.proxy != nil {
-		pm := s.proxy.Metrics()
-		m.RequestCount = pm.RequestCount
-		m.ErrorCount = pm.ErrorCount
-		m.CacheHits = pm.CacheHits
-		m.CacheMisses = pm.CacheMisses
-		m.CacheBypass = pm.CacheBypass
-		m.CacheStores = pm.CacheStores
+		UptimeSeconds:   time.Since(s.metrics.StartTime).Seconds(),
+		CircuitBreakers: s.circuitBreakers(),
+	}
+	for _, p := range s.proxies() {
+		pm := p.Metrics()
+		m.RequestCount += pm.RequestCount
+		m.ErrorCount += pm.ErrorCount
+		m.CacheHits += pm.CacheHits
+		m.CacheMisses += pm.CacheMisses
+		m.CacheBypass += pm.CacheBypass
+		m.CacheStores += pm.CacheStores
+		m.HedgedRequests += pm.HedgedRequests
+		m.HedgeWins += pm.HedgeWins
+		m.ClientIPDenials += pm.ClientIPDenials
+		m.OriginDenials += pm.OriginDenials
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -542,16 +965,20 @@ func (s *Server) handleMetricsPrometheus(w http.ResponseWriter, r *http.Request)
 	buf.WriteString("# TYPE llm_proxy_uptime_seconds gauge\n")
 	_, _ = fmt.Fprintf(&buf, "llm_proxy_uptime_seconds %g\n", uptimeSeconds)
 
-	// Get proxy metrics or use zero values
-	var requestCount, errorCount, cacheHits, cacheMisses, cacheBypass, cacheStores int64
-	if s.proxy != nil {
-		pm := s.proxy.Metrics()
-		requestCount = pm.RequestCount
-		errorCount = pm.ErrorCount
-		cacheHits = pm.CacheHits
-		cacheMisses = pm.CacheMisses
-		cacheBypass = pm.CacheBypass
-		cacheStores = pm.CacheStores
+	// Sum proxy metrics across all providers (zero values when no proxy is initialized)
+	var requestCount, errorCount, cacheHits, cacheMisses, cacheBypass, cacheStores, hedgedRequests, hedgeWins, clientIPDenials, originDenials int64
+	for _, p := range s.proxies() {
+		pm := p.Metrics()
+		requestCount += pm.RequestCount
+		errorCount += pm.ErrorCount
+		cacheHits += pm.CacheHits
+		cacheMisses += pm.CacheMisses
+		cacheBypass += pm.CacheBypass
+		cacheStores += pm.CacheStores
+		hedgedRequests += pm.HedgedRequests
+		hedgeWins += pm.HedgeWins
+		clientIPDenials += pm.ClientIPDenials
+		originDenials += pm.OriginDenials
 	}
 
 	// Write metrics in Prometheus format
@@ -579,6 +1006,39 @@ func (s *Server) handleMetricsPrometheus(w http.ResponseWriter, r *http.Request)
 	buf.WriteString("# TYPE llm_proxy_cache_stores_total counter\n")
 	_, _ = fmt.Fprintf(&buf, "llm_proxy_cache_stores_total %d\n", cacheStores)
 
+	buf.WriteString("# HELP llm_proxy_hedged_requests_total Total number of requests for which a hedge was sent\n")
+	buf.WriteString("# TYPE llm_proxy_hedged_requests_total counter\n")
+	_, _ = fmt.Fprintf(&buf, "llm_proxy_hedged_requests_total %d\n", hedgedRequests)
+
+	buf.WriteString("# HELP llm_proxy_hedge_wins_total Total number of hedged requests answered by the hedge\n")
+	buf.WriteString("# TYPE llm_proxy_hedge_wins_total counter\n")
+	_, _ = fmt.Fprintf(&buf, "llm_proxy_hedge_wins_total %d\n", hedgeWins)
+
+	buf.WriteString("# HELP llm_proxy_client_restriction_denials_total Total number of requests rejected by token or project client restrictions\n")
+	buf.WriteString("# TYPE llm_proxy_client_restriction_denials_total counter\n")
+	_, _ = fmt.Fprintf(&buf, "llm_proxy_client_restriction_denials_total{reason=\"ip_not_allowed\"} %d\n", clientIPDenials)
+	_, _ = fmt.Fprintf(&buf, "llm_proxy_client_restriction_denials_total{reason=\"origin_not_allowed\"} %d\n", originDenials)
+
+	if breakers := s.circuitBreakers(); len(breakers) > 0 {
+		buf.WriteString("# HELP llm_proxy_circuit_breaker_state Circuit breaker state (0 closed, 1 half-open, 2 open)\n")
+		buf.WriteString("# TYPE llm_proxy_circuit_breaker_state gauge\n")
+		for _, cb := range breakers {
+			state := 0
+			switch cb.State {
+			case proxy.CircuitHalfOpen:
+				state = 1
+			case proxy.CircuitOpen:
+				state = 2
+			}
+			_, _ = fmt.Fprintf(&buf, "llm_proxy_circuit_breaker_state{provider=%q,key_id=%q} %d\n", cb.Provider, cb.KeyID, state)
+		}
+		buf.WriteString("# HELP llm_proxy_circuit_breaker_opens_total Total number of times a circuit breaker opened\n")
+		buf.WriteString("# TYPE llm_proxy_circuit_breaker_opens_total counter\n")
+		for _, cb := range breakers {
+			_, _ = fmt.Fprintf(&buf, "llm_proxy_circuit_breaker_opens_total{provider=%q,key_id=%q} %d\n", cb.Provider, cb.KeyID, cb.Opens)
+		}
+	}
+
 	// Go runtime metrics
 	s.writeGoRuntimeMetrics(&buf)
 
@@ -734,13 +1194,19 @@ func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
 	sanitizedProjects := make([]ProjectResponse, len(projects))
 	for i, p := range projects {
 		sanitizedProjects[i] = ProjectResponse{
-			ID:            p.ID,
-			Name:          p.Name,
-			APIKey:        obfuscate.ObfuscateTokenGeneric(p.APIKey),
-			IsActive:      p.IsActive,
-			DeactivatedAt: p.DeactivatedAt,
-			CreatedAt:     p.CreatedAt,
-			UpdatedAt:     p.UpdatedAt,
+			ID:                 p.ID,
+			Name:               p.Name,
+			APIKey:             obfuscate.ObfuscateTokenGeneric(p.APIKey),
+			APIKeys:            obfuscateAPIKeys(p.APIKeys),
+			APIKeyPool:         obfuscateAPIKeyPool(p.APIKeyPool),
+			RequestPolicy:      p.RequestPolicy,
+			AllowedModels:      p.AllowedModels,
+			ClientRestrictions: clientRestrictionsResponse(p.ClientRestrictions),
+			RateLimit:          rateLimitResponse(p.RateLimit),
+			IsActive:           p.IsActive,
+			DeactivatedAt:      p.DeactivatedAt,
+			CreatedAt:          p.CreatedAt,
+			UpdatedAt:          p.UpdatedAt,
 		}
 	}
 
@@ -758,8 +1224,16 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	requestID := getRequestID(ctx)
 	var req struct {
-		Name   string `json:"name"`
-		APIKey string `json:"api_key"`
+		Name          string                    `json:"name"`
+		APIKey        string                    `json:"api_key"`
+		APIKeys       map[string]string         `json:"api_keys,omitempty"`
+		APIKeyPool    []proxy.APIKeyPoolEntry   `json:"api_key_pool,omitempty"`
+		RequestPolicy []proxy.RequestPolicyRule `json:"request_policy,omitempty"`
+		AllowedModels []string                  `json:"allowed_models,omitempty"`
+		// ClientRestrictions limit the networks and origins the project's tokens may be used from
+		ClientRestrictions token.ClientRestrictions `json:"client_restrictions"`
+		// RateLimit limits the project's request rate across all of its tokens
+		RateLimit token.RateLimit `json:"rate_limit"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.logger.Error("invalid request body", zap.Error(err), zap.String("request_id", requestID))
@@ -772,7 +1246,7 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
 		return
 	}
-	if req.Name == "" || req.APIKey == "" {
+	if req.Name == "" || (req.APIKey == "" && len(req.APIKeys) == 0) {
 		s.logger.Error(
 			"missing required fields",
 			zap.String("name", req.Name),
@@ -786,12 +1260,83 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 			WithDetail("name_provided", req.Name != "").
 			WithDetail("api_key_provided", req.APIKey != ""))
 
-		http.Error(w, `{"error":"name and api_key are required"}`, http.StatusBadRequest)
+		http.Error(w, `{"error":"name and api_key (or api_keys) are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	for provider, key := range req.APIKeys {
+		if err := s.validateProjectAPIKey(provider, key); err != nil {
+			s.logger.Error("invalid provider API key", zap.String("provider", provider), zap.Error(err), zap.String("request_id", requestID))
+
+			// Audit: project creation failure - invalid provider key
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithDetail("validation_error", err.Error()).
+				WithDetail("provider", provider))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.validateAPIKeyPool(req.APIKeyPool); err != nil {
+		s.logger.Error("invalid API key pool", zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: project creation failure - invalid key pool
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("validation_error", err.Error()))
+
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid api_key_pool: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if err := proxy.ValidateAllowedModels(req.AllowedModels); err != nil {
+		s.logger.Error("invalid allowed models", zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: project creation failure - invalid model allowlist
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("validation_error", err.Error()))
+
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid allowed_models: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	req.ClientRestrictions = req.ClientRestrictions.Normalize()
+	if err := req.ClientRestrictions.Validate(); err != nil {
+		s.logger.Error("invalid client restrictions", zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: project creation failure - invalid client restrictions
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("validation_error", err.Error()))
+
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid client_restrictions: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	req.RateLimit = req.RateLimit.Normalize()
+	if err := req.RateLimit.Validate(); err != nil {
+		s.logger.Error("invalid rate limit", zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: project creation failure - invalid rate limit
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("validation_error", err.Error()))
+
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid rate_limit: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if err := proxy.ValidateRequestPolicy(req.RequestPolicy); err != nil {
+		s.logger.Error("invalid request policy", zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: project creation failure - invalid request policy
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("validation_error", err.Error()))
+
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid request_policy: "+err.Error()), http.StatusBadRequest)
 		return
 	}
 
 	// Reject obfuscated keys to prevent data corruption
-	if strings.Contains(req.APIKey, "...") || strings.Contains(req.APIKey, "****") {
+	if isObfuscatedAPIKey(req.APIKey) {
 		s.logger.Error("attempted to create project with obfuscated API key", zap.String("request_id", requestID))
 
 		// Audit: project creation failure - obfuscated key
@@ -804,12 +1349,18 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 	id := uuid.NewString()
 	now := time.Now().UTC()
 	project := proxy.Project{
-		ID:        id,
-		Name:      req.Name,
-		APIKey:    req.APIKey,
-		IsActive:  true, // Projects are active by default
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:                 id,
+		Name:               req.Name,
+		APIKey:             req.APIKey,
+		APIKeys:            req.APIKeys,
+		APIKeyPool:         req.APIKeyPool,
+		RequestPolicy:      req.RequestPolicy,
+		AllowedModels:      req.AllowedModels,
+		ClientRestrictions: req.ClientRestrictions,
+		RateLimit:          req.RateLimit,
+		IsActive:           true, // Projects are active by default
+		CreatedAt:          now,
+		UpdatedAt:          now,
 	}
 	if err := s.projectStore.CreateProject(ctx, project); err != nil {
 		s.logger.Error("failed to create project", zap.Error(err), zap.String("name", req.Name), zap.String("request_id", requestID))
@@ -828,7 +1379,8 @@ func (s *Server) handleCreateProject(w http.ResponseWriter, r *http.Request) {
 	// Audit: project creation success
 	_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectCreate, audit.ActorManagement, audit.ResultSuccess, r, requestID).
 		WithProjectID(id).
-		WithDetail("project_name", req.Name))
+		WithDetail("project_name", req.Name).
+		WithDetail("providers", sortedKeys(req.APIKeys)))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -855,13 +1407,19 @@ func (s *Server) handleGetProject(w http.ResponseWriter, r *http.Request) {
 
 	// Create response with obfuscated API key
 	response := ProjectResponse{
-		ID:            project.ID,
-		Name:          project.Name,
-		APIKey:        obfuscate.ObfuscateTokenGeneric(project.APIKey),
-		IsActive:      project.IsActive,
-		DeactivatedAt: project.DeactivatedAt,
-		CreatedAt:     project.CreatedAt,
-		UpdatedAt:     project.UpdatedAt,
+		ID:                 project.ID,
+		Name:               project.Name,
+		APIKey:             obfuscate.ObfuscateTokenGeneric(project.APIKey),
+		APIKeys:            obfuscateAPIKeys(project.APIKeys),
+		APIKeyPool:         obfuscateAPIKeyPool(project.APIKeyPool),
+		RequestPolicy:      project.RequestPolicy,
+		AllowedModels:      project.AllowedModels,
+		ClientRestrictions: clientRestrictionsResponse(project.ClientRestrictions),
+		RateLimit:          rateLimitResponse(project.RateLimit),
+		IsActive:           project.IsActive,
+		DeactivatedAt:      project.DeactivatedAt,
+		CreatedAt:          project.CreatedAt,
+		UpdatedAt:          project.UpdatedAt,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -887,10 +1445,22 @@ func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		Name         *string `json:"name,omitempty"`
-		APIKey       *string `json:"api_key,omitempty"`
-		IsActive     *bool   `json:"is_active,omitempty"`
-		RevokeTokens *bool   `json:"revoke_tokens,omitempty"`
+		Name   *string `json:"name,omitempty"`
+		APIKey *string `json:"api_key,omitempty"`
+		// APIKeys adds or rotates per-provider keys; a null or empty value removes the provider's key.
+		APIKeys map[string]*string `json:"api_keys,omitempty"`
+		// APIKeyPool replaces the project's key pools; an empty list removes them.
+		APIKeyPool *[]proxy.APIKeyPoolEntry `json:"api_key_pool,omitempty"`
+		// RequestPolicy replaces the project's request policy; an empty list removes it.
+		RequestPolicy *[]proxy.RequestPolicyRule `json:"request_policy,omitempty"`
+		// AllowedModels replaces the project's model allowlist; an empty list allows every model.
+		AllowedModels *[]string `json:"allowed_models,omitempty"`
+		// ClientRestrictions replaces the project's client allowlists; an empty object removes them.
+		ClientRestrictions *token.ClientRestrictions `json:"client_restrictions,omitempty"`
+		// RateLimit replaces the project's rate limit; an empty object removes it.
+		RateLimit    *token.RateLimit `json:"rate_limit,omitempty"`
+		IsActive     *bool            `json:"is_active,omitempty"`
+		RevokeTokens *bool            `json:"revoke_tokens,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.logger.Error("invalid request body for update", zap.Error(err))
@@ -939,7 +1509,7 @@ func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 	}
 	if req.APIKey != nil && *req.APIKey != "" {
 		// Reject obfuscated keys to prevent data corruption
-		if strings.Contains(*req.APIKey, "...") || strings.Contains(*req.APIKey, "****") {
+		if isObfuscatedAPIKey(*req.APIKey) {
 			s.logger.Error("attempted to save obfuscated API key", zap.String("project_id", id))
 
 			// Audit: project update failure - obfuscated key
@@ -953,33 +1523,142 @@ func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 		project.APIKey = *req.APIKey
 		updatedFields = append(updatedFields, "api_key")
 	}
+	for _, provider := range sortedKeys(req.APIKeys) {
+		key := req.APIKeys[provider]
+		if key == nil || *key == "" {
+			if _, ok := project.APIKeys[provider]; ok {
+				delete(project.APIKeys, provider)
+				updatedFields = append(updatedFields, "api_keys."+provider)
+			}
+			continue
+		}
+		if err := s.validateProjectAPIKey(provider, *key); err != nil {
+			s.logger.Error("invalid provider API key", zap.String("project_id", id), zap.String("provider", provider), zap.Error(err))
 
-	// Handle project activation/deactivation
-	var shouldRevokeTokens bool
-	if req.IsActive != nil {
-		if *req.IsActive != project.IsActive {
-			project.IsActive = *req.IsActive
-			updatedFields = append(updatedFields, "is_active")
-
-			// If deactivating project, set deactivated timestamp
-			if !*req.IsActive {
-				now := time.Now().UTC()
-				project.DeactivatedAt = &now
-				updatedFields = append(updatedFields, "deactivated_at")
+			// Audit: project update failure - invalid provider key
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(id).
+				WithDetail("validation_error", err.Error()).
+				WithDetail("provider", provider))
 
-				// Check if tokens should be revoked when deactivating
-				if req.RevokeTokens != nil && *req.RevokeTokens {
-					shouldRevokeTokens = true
-				}
-			} else {
-				// Reactivating project, clear deactivated timestamp
-				project.DeactivatedAt = nil
-			}
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		if project.APIKeys == nil {
+			project.APIKeys = make(map[string]string)
 		}
+		project.APIKeys[provider] = *key
+		updatedFields = append(updatedFields, "api_keys."+provider)
 	}
 
-	project.UpdatedAt = time.Now().UTC()
-	if err := s.projectStore.UpdateProject(ctx, project); err != nil {
+	if req.APIKeyPool != nil {
+		if err := s.validateAPIKeyPool(*req.APIKeyPool); err != nil {
+			s.logger.Error("invalid API key pool", zap.String("project_id", id), zap.Error(err))
+
+			// Audit: project update failure - invalid key pool
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(id).
+				WithDetail("validation_error", err.Error()))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid api_key_pool: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+		project.APIKeyPool = *req.APIKeyPool
+		updatedFields = append(updatedFields, "api_key_pool")
+	}
+
+	if req.RequestPolicy != nil {
+		if err := proxy.ValidateRequestPolicy(*req.RequestPolicy); err != nil {
+			s.logger.Error("invalid request policy", zap.String("project_id", id), zap.Error(err))
+
+			// Audit: project update failure - invalid request policy
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(id).
+				WithDetail("validation_error", err.Error()))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid request_policy: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+		project.RequestPolicy = *req.RequestPolicy
+		updatedFields = append(updatedFields, "request_policy")
+	}
+
+	if req.AllowedModels != nil {
+		if err := proxy.ValidateAllowedModels(*req.AllowedModels); err != nil {
+			s.logger.Error("invalid allowed models", zap.String("project_id", id), zap.Error(err))
+
+			// Audit: project update failure - invalid model allowlist
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(id).
+				WithDetail("validation_error", err.Error()))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid allowed_models: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+		project.AllowedModels = *req.AllowedModels
+		updatedFields = append(updatedFields, "allowed_models")
+	}
+
+	if req.ClientRestrictions != nil {
+		restrictions := req.ClientRestrictions.Normalize()
+		if err := restrictions.Validate(); err != nil {
+			s.logger.Error("invalid client restrictions", zap.String("project_id", id), zap.Error(err))
+
+			// Audit: project update failure - invalid client restrictions
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(id).
+				WithDetail("validation_error", err.Error()))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid client_restrictions: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+		project.ClientRestrictions = restrictions
+		updatedFields = append(updatedFields, "client_restrictions")
+	}
+
+	if req.RateLimit != nil {
+		limit := req.RateLimit.Normalize()
+		if err := limit.Validate(); err != nil {
+			s.logger.Error("invalid rate limit", zap.String("project_id", id), zap.Error(err))
+
+			// Audit: project update failure - invalid rate limit
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionProjectUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(id).
+				WithDetail("validation_error", err.Error()))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid rate_limit: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+		project.RateLimit = limit
+		updatedFields = append(updatedFields, "rate_limit")
+	}
+
+	// Handle project activation/deactivation
+	var shouldRevokeTokens bool
+	if req.IsActive != nil {
+		if *req.IsActive != project.IsActive {
+			project.IsActive = *req.IsActive
+			updatedFields = append(updatedFields, "is_active")
+
+			// If deactivating project, set deactivated timestamp
+			if !*req.IsActive {
+				now := time.Now().UTC()
+				project.DeactivatedAt = &now
+				updatedFields = append(updatedFields, "deactivated_at")
+
+				// Check if tokens should be revoked when deactivating
+				if req.RevokeTokens != nil && *req.RevokeTokens {
+					shouldRevokeTokens = true
+				}
+			} else {
+				// Reactivating project, clear deactivated timestamp
+				project.DeactivatedAt = nil
+			}
+		}
+	}
+
+	project.UpdatedAt = time.Now().UTC()
+	if err := s.projectStore.UpdateProject(ctx, project); err != nil {
 		s.logger.Error("failed to update project", zap.String("id", id), zap.Error(err))
 
 		// Audit: project update failure - store error
@@ -1036,6 +1715,83 @@ func (s *Server) handleUpdateProject(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// isObfuscatedAPIKey reports whether key looks like an obfuscated display value
+// (as returned by the management API) rather than a real credential.
+func isObfuscatedAPIKey(key string) bool {
+	return strings.Contains(key, "...") || strings.Contains(key, "****")
+}
+
+// validateProjectAPIKey validates a per-provider project key. When API providers are
+// configured, the provider must be one of them.
+func (s *Server) validateProjectAPIKey(provider, key string) error {
+	if err := validateProviderRouteName(provider); err != nil {
+		return err
+	}
+	if len(s.providerProxies) > 0 && s.providerProxies[provider] == nil {
+		return fmt.Errorf("unknown API provider '%s'", provider)
+	}
+	if key == "" {
+		return fmt.Errorf("API key for provider '%s' must not be empty", provider)
+	}
+	if isObfuscatedAPIKey(key) {
+		return fmt.Errorf("cannot save obfuscated API key for provider '%s' - please provide the full API key", provider)
+	}
+	return nil
+}
+
+// validateAPIKeyPool validates a project's key pools. Entries with a provider must
+// name a configured API provider; entries without one pool the legacy key.
+func (s *Server) validateAPIKeyPool(pool []proxy.APIKeyPoolEntry) error {
+	if err := proxy.ValidateAPIKeyPool(pool); err != nil {
+		return err
+	}
+	for _, entry := range pool {
+		if entry.Provider != "" {
+			if err := s.validateProjectAPIKey(entry.Provider, entry.APIKey); err != nil {
+				return err
+			}
+		} else if isObfuscatedAPIKey(entry.APIKey) {
+			return errors.New("cannot save obfuscated API key - please provide the full API key")
+		}
+	}
+	return nil
+}
+
+// obfuscateAPIKeyPool returns a copy of pool with each key obfuscated.
+func obfuscateAPIKeyPool(pool []proxy.APIKeyPoolEntry) []proxy.APIKeyPoolEntry {
+	if len(pool) == 0 {
+		return nil
+	}
+	out := make([]proxy.APIKeyPoolEntry, len(pool))
+	for i, entry := range pool {
+		entry.APIKey = obfuscate.ObfuscateTokenGeneric(entry.APIKey)
+		out[i] = entry
+	}
+	return out
+}
+
+// obfuscateAPIKeys returns a copy of per-provider keys with each value obfuscated.
+func obfuscateAPIKeys(keys map[string]string) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(keys))
+	for provider, key := range keys {
+		out[provider] = obfuscate.ObfuscateTokenGeneric(key)
+	}
+	return out
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // DELETE /manage/projects/{id}
 // DELETE /manage/projects/{id} - Returns 405 Method Not Allowed
 func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
@@ -1198,6 +1954,10 @@ func (s *Server) handleProjectByID(w http.ResponseWriter, r *http.Request) {
 		s.handleBulkRevokeProjectTokens(w, r)
 		return
 	}
+	if projectID, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/manage/projects/"), "/budget"); ok {
+		s.handleBudget(w, r, proxy.BudgetSubjectProject, projectID)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
@@ -1218,9 +1978,15 @@ func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
 		var req struct {
-			ProjectID       string `json:"project_id"`
-			DurationMinutes int    `json:"duration_minutes"`
-			MaxRequests     *int   `json:"max_requests"`
+			ProjectID       string            `json:"project_id"`
+			DurationMinutes int               `json:"duration_minutes"`
+			MaxRequests     *int              `json:"max_requests"`
+			Scopes          token.Scopes      `json:"scopes"`
+			Metadata        map[string]string `json:"metadata"`
+			// ClientRestrictions limit the networks and origins the token may be used from
+			ClientRestrictions token.ClientRestrictions `json:"client_restrictions"`
+			// RateLimit overrides the default request rate limit of the token
+			RateLimit token.RateLimit `json:"rate_limit"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			s.logger.Error("invalid token create request body", zap.Error(err), zap.String("request_id", requestID))
@@ -1289,6 +2055,61 @@ func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		req.Scopes = req.Scopes.Normalize()
+		if err := req.Scopes.Validate(); err != nil {
+			s.logger.Error("invalid token scopes", zap.Error(err), zap.String("request_id", requestID))
+
+			// Audit: token creation failure - invalid scopes
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(req.ProjectID).
+				WithError(err).
+				WithDetail("validation_error", "invalid scopes"))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid scopes: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		if err := token.ValidateMetadata(req.Metadata); err != nil {
+			s.logger.Error("invalid token metadata", zap.Error(err), zap.String("request_id", requestID))
+
+			// Audit: token creation failure - invalid metadata
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(req.ProjectID).
+				WithError(err).
+				WithDetail("validation_error", "invalid metadata"))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid metadata: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		req.ClientRestrictions = req.ClientRestrictions.Normalize()
+		if err := req.ClientRestrictions.Validate(); err != nil {
+			s.logger.Error("invalid token client restrictions", zap.Error(err), zap.String("request_id", requestID))
+
+			// Audit: token creation failure - invalid client restrictions
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(req.ProjectID).
+				WithError(err).
+				WithDetail("validation_error", "invalid client_restrictions"))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid client_restrictions: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		req.RateLimit = req.RateLimit.Normalize()
+		if err := req.RateLimit.Validate(); err != nil {
+			s.logger.Error("invalid token rate limit", zap.Error(err), zap.String("request_id", requestID))
+
+			// Audit: token creation failure - invalid rate limit
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenCreate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithProjectID(req.ProjectID).
+				WithError(err).
+				WithDetail("validation_error", "invalid rate_limit"))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid rate_limit: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+
 		// Check project exists and is active
 		project, err := s.projectStore.GetProjectByID(ctx, req.ProjectID)
 		if err != nil {
@@ -1334,14 +2155,18 @@ func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
 		}
 		now := time.Now().UTC()
 		dbToken := token.TokenData{
-			ID:           tokenID,
-			Token:        tokenStr,
-			ProjectID:    req.ProjectID,
-			ExpiresAt:    expiresAt,
-			IsActive:     true,
-			RequestCount: 0,
-			MaxRequests:  req.MaxRequests,
-			CreatedAt:    now,
+			ID:                 tokenID,
+			Token:              tokenStr,
+			ProjectID:          req.ProjectID,
+			ExpiresAt:          expiresAt,
+			IsActive:           true,
+			RequestCount:       0,
+			MaxRequests:        req.MaxRequests,
+			CreatedAt:          now,
+			Scopes:             req.Scopes,
+			Metadata:           req.Metadata,
+			ClientRestrictions: req.ClientRestrictions,
+			RateLimit:          req.RateLimit,
 		}
 		if err := s.tokenStore.CreateToken(ctx, dbToken); err != nil {
 			s.logger.Error("failed to store token", zap.Error(err), zap.String("request_id", requestID))
@@ -1374,6 +2199,18 @@ func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
 		if req.MaxRequests != nil {
 			auditEvent.WithDetail("max_requests", *req.MaxRequests)
 		}
+		if !req.Scopes.IsEmpty() {
+			auditEvent.WithDetail("scopes", req.Scopes)
+		}
+		if len(req.Metadata) > 0 {
+			auditEvent.WithDetail("metadata_keys", sortedKeys(req.Metadata))
+		}
+		if !req.ClientRestrictions.IsEmpty() {
+			auditEvent.WithDetail("client_restrictions", req.ClientRestrictions)
+		}
+		if !req.RateLimit.IsZero() {
+			auditEvent.WithDetail("rate_limit", req.RateLimit)
+		}
 		_ = s.auditLogger.Log(auditEvent)
 
 		w.Header().Set("Content-Type", "application/json")
@@ -1385,6 +2222,18 @@ func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
 		if req.MaxRequests != nil {
 			response["max_requests"] = *req.MaxRequests
 		}
+		if !req.Scopes.IsEmpty() {
+			response["scopes"] = req.Scopes
+		}
+		if len(req.Metadata) > 0 {
+			response["metadata"] = req.Metadata
+		}
+		if !req.ClientRestrictions.IsEmpty() {
+			response["client_restrictions"] = req.ClientRestrictions
+		}
+		if !req.RateLimit.IsZero() {
+			response["rate_limit"] = req.RateLimit
+		}
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			s.logger.Error("failed to encode token response", zap.Error(err))
 		}
@@ -1433,16 +2282,22 @@ func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
 		sanitizedTokens := make([]TokenListResponse, len(tokens))
 		for i, t := range tokens {
 			sanitizedTokens[i] = TokenListResponse{
-				ID:            t.ID,
-				Token:         token.ObfuscateToken(t.Token),
-				ProjectID:     t.ProjectID,
-				ExpiresAt:     t.ExpiresAt,
-				IsActive:      t.IsActive,
-				RequestCount:  t.RequestCount,
-				MaxRequests:   t.MaxRequests,
-				CreatedAt:     t.CreatedAt,
-				LastUsedAt:    t.LastUsedAt,
-				CacheHitCount: t.CacheHitCount,
+				ID:                 t.ID,
+				Token:              token.ObfuscateToken(t.Token),
+				ProjectID:          t.ProjectID,
+				ExpiresAt:          t.ExpiresAt,
+				IsActive:           t.IsActive,
+				RequestCount:       t.RequestCount,
+				MaxRequests:        t.MaxRequests,
+				CreatedAt:          t.CreatedAt,
+				LastUsedAt:         t.LastUsedAt,
+				CacheHitCount:      t.CacheHitCount,
+				Scopes:             tokenScopesResponse(t.Scopes),
+				Metadata:           t.Metadata,
+				ClientRestrictions: clientRestrictionsResponse(t.ClientRestrictions),
+				RateLimit:          rateLimitResponse(t.RateLimit),
+				RotatedFromID:      t.RotatedFromID,
+				RotatedToID:        t.RotatedToID,
 			}
 		}
 
@@ -1455,6 +2310,7 @@ func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
 }
 
 // Handler for /manage/tokens/{id} (GET: retrieve, PATCH: update, DELETE: revoke)
+// and /manage/tokens/{id}/rotate (POST: rotate)
 func (s *Server) handleTokenByID(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	requestID := getRequestID(ctx)
@@ -1467,6 +2323,19 @@ func (s *Server) handleTokenByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if rotateID, ok := strings.CutSuffix(tokenID, "/rotate"); ok {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleRotateToken(w, r, rotateID)
+		return
+	}
+	if budgetID, ok := strings.CutSuffix(tokenID, "/budget"); ok {
+		s.handleBudget(w, r, proxy.BudgetSubjectToken, budgetID)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		s.handleGetToken(w, r, tokenID)
@@ -1509,15 +2378,21 @@ func (s *Server) handleGetToken(w http.ResponseWriter, r *http.Request, tokenID
 
 	// Create sanitized response with ID and obfuscated token string
 	response := TokenListResponse{
-		ID:           tokenData.ID,
-		Token:        token.ObfuscateToken(tokenData.Token),
-		ProjectID:    tokenData.ProjectID,
-		ExpiresAt:    tokenData.ExpiresAt,
-		IsActive:     tokenData.IsActive,
-		RequestCount: tokenData.RequestCount,
-		MaxRequests:  tokenData.MaxRequests,
-		CreatedAt:    tokenData.CreatedAt,
-		LastUsedAt:   tokenData.LastUsedAt,
+		ID:                 tokenData.ID,
+		Token:              token.ObfuscateToken(tokenData.Token),
+		ProjectID:          tokenData.ProjectID,
+		ExpiresAt:          tokenData.ExpiresAt,
+		IsActive:           tokenData.IsActive,
+		RequestCount:       tokenData.RequestCount,
+		MaxRequests:        tokenData.MaxRequests,
+		CreatedAt:          tokenData.CreatedAt,
+		LastUsedAt:         tokenData.LastUsedAt,
+		Scopes:             tokenScopesResponse(tokenData.Scopes),
+		Metadata:           tokenData.Metadata,
+		ClientRestrictions: clientRestrictionsResponse(tokenData.ClientRestrictions),
+		RateLimit:          rateLimitResponse(tokenData.RateLimit),
+		RotatedFromID:      tokenData.RotatedFromID,
+		RotatedToID:        tokenData.RotatedToID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1533,8 +2408,11 @@ func (s *Server) handleUpdateToken(w http.ResponseWriter, r *http.Request, token
 
 	// Parse request body
 	var req struct {
-		IsActive    *bool `json:"is_active,omitempty"`
-		MaxRequests *int  `json:"max_requests,omitempty"`
+		IsActive    *bool              `json:"is_active,omitempty"`
+		MaxRequests *int               `json:"max_requests,omitempty"`
+		Metadata    *map[string]string `json:"metadata,omitempty"`
+		// RateLimit replaces the token's rate limit; an empty object restores the default.
+		RateLimit *token.RateLimit `json:"rate_limit,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.logger.Error("invalid token update request body", zap.Error(err), zap.String("request_id", requestID))
@@ -1549,6 +2427,37 @@ func (s *Server) handleUpdateToken(w http.ResponseWriter, r *http.Request, token
 		return
 	}
 
+	if req.Metadata != nil {
+		if err := token.ValidateMetadata(*req.Metadata); err != nil {
+			s.logger.Error("invalid token metadata", zap.Error(err), zap.String("request_id", requestID))
+
+			// Audit: token update failure - invalid metadata
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithTokenID(tokenID).
+				WithError(err).
+				WithDetail("validation_error", "invalid metadata"))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid metadata: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.RateLimit != nil {
+		*req.RateLimit = req.RateLimit.Normalize()
+		if err := req.RateLimit.Validate(); err != nil {
+			s.logger.Error("invalid token rate limit", zap.Error(err), zap.String("request_id", requestID))
+
+			// Audit: token update failure - invalid rate limit
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithTokenID(tokenID).
+				WithError(err).
+				WithDetail("validation_error", "invalid rate_limit"))
+
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid rate_limit: "+err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
 	// Get existing token
 	tokenData, err := s.tokenStore.GetTokenByID(ctx, tokenID)
 	if err != nil {
@@ -1594,6 +2503,14 @@ func (s *Server) handleUpdateToken(w http.ResponseWriter, r *http.Request, token
 		tokenData.MaxRequests = normalizedMaxRequests
 		updated = true
 	}
+	if req.Metadata != nil {
+		tokenData.Metadata = *req.Metadata
+		updated = true
+	}
+	if req.RateLimit != nil {
+		tokenData.RateLimit = *req.RateLimit
+		updated = true
+	}
 
 	if !updated {
 		s.logger.Error("no fields to update", zap.String("token_id", tokenID), zap.String("request_id", requestID))
@@ -1645,19 +2562,31 @@ func (s *Server) handleUpdateToken(w http.ResponseWriter, r *http.Request, token
 	if maxRequestsProvided && normalizedMaxRequests == nil {
 		auditEvent.WithDetail("updated_max_requests", "unlimited")
 	}
+	if req.Metadata != nil {
+		auditEvent.WithDetail("updated_metadata_keys", sortedKeys(*req.Metadata))
+	}
+	if req.RateLimit != nil {
+		auditEvent.WithDetail("updated_rate_limit", *req.RateLimit)
+	}
 	_ = s.auditLogger.Log(auditEvent)
 
 	// Return updated token (sanitized with ID and obfuscated token)
 	response := TokenListResponse{
-		ID:           tokenData.ID,
-		Token:        token.ObfuscateToken(tokenData.Token),
-		ProjectID:    tokenData.ProjectID,
-		ExpiresAt:    tokenData.ExpiresAt,
-		IsActive:     tokenData.IsActive,
-		RequestCount: tokenData.RequestCount,
-		MaxRequests:  tokenData.MaxRequests,
-		CreatedAt:    tokenData.CreatedAt,
-		LastUsedAt:   tokenData.LastUsedAt,
+		ID:                 tokenData.ID,
+		Token:              token.ObfuscateToken(tokenData.Token),
+		ProjectID:          tokenData.ProjectID,
+		ExpiresAt:          tokenData.ExpiresAt,
+		IsActive:           tokenData.IsActive,
+		RequestCount:       tokenData.RequestCount,
+		MaxRequests:        tokenData.MaxRequests,
+		CreatedAt:          tokenData.CreatedAt,
+		LastUsedAt:         tokenData.LastUsedAt,
+		Scopes:             tokenScopesResponse(tokenData.Scopes),
+		Metadata:           tokenData.Metadata,
+		ClientRestrictions: clientRestrictionsResponse(tokenData.ClientRestrictions),
+		RateLimit:          rateLimitResponse(tokenData.RateLimit),
+		RotatedFromID:      tokenData.RotatedFromID,
+		RotatedToID:        tokenData.RotatedToID,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1738,6 +2667,174 @@ func (s *Server) handleRevokeToken(w http.ResponseWriter, r *http.Request, token
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// POST /manage/tokens/{id}/rotate (issue a successor and retire the token after a grace period)
+func (s *Server) handleRotateToken(w http.ResponseWriter, r *http.Request, tokenID string) {
+	ctx := r.Context()
+	requestID := getRequestID(ctx)
+
+	var req struct {
+		// GracePeriodMinutes is how long the old token stays valid; 0 deactivates it right away
+		GracePeriodMinutes *int `json:"grace_period_minutes,omitempty"`
+		// DurationMinutes gives the successor a new lifetime instead of the old token's expiry
+		DurationMinutes int `json:"duration_minutes,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.logger.Error("invalid token rotate request body", zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: token rotate failure - invalid request
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenRotate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithTokenID(tokenID).
+			WithError(err).
+			WithDetail("validation_error", "invalid request body"))
+
+		http.Error(w, `{"error":"invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	grace := s.config.TokenRotationGracePeriod
+	if req.GracePeriodMinutes != nil {
+		if *req.GracePeriodMinutes < 0 || *req.GracePeriodMinutes > maxDurationMinutes {
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenRotate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithTokenID(tokenID).
+				WithDetail("validation_error", "invalid grace_period_minutes").
+				WithDetail("requested_grace_period_minutes", *req.GracePeriodMinutes))
+
+			http.Error(w, `{"error":"grace_period_minutes must be between 0 and 525600"}`, http.StatusBadRequest)
+			return
+		}
+		grace = time.Duration(*req.GracePeriodMinutes) * time.Minute
+	}
+	if req.DurationMinutes < 0 || req.DurationMinutes > maxDurationMinutes {
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenRotate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithTokenID(tokenID).
+			WithDetail("validation_error", "invalid duration_minutes").
+			WithDetail("requested_duration_minutes", req.DurationMinutes))
+
+		http.Error(w, `{"error":"duration_minutes must be between 0 and 525600"}`, http.StatusBadRequest)
+		return
+	}
+
+	tokenData, err := s.tokenStore.GetTokenByID(ctx, tokenID)
+	if err != nil {
+		s.logger.Error("failed to get token for rotation", zap.String("token_id", tokenID), zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: token rotate failure - token not found
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenRotate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithTokenID(tokenID).
+			WithError(err).
+			WithDetail("error_type", "token not found"))
+
+		http.Error(w, `{"error":"token not found"}`, http.StatusNotFound)
+		return
+	}
+
+	now := time.Now().UTC()
+	successor, retired, err := token.Rotate(tokenData, grace, now)
+	if err != nil {
+		s.logger.Warn("token rotation rejected", zap.String("token_id", tokenID), zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: token rotate failure - token cannot be rotated
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenRotate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithTokenID(tokenID).
+			WithProjectID(tokenData.ProjectID).
+			WithError(err).
+			WithDetail("error_type", "token cannot be rotated"))
+
+		status := http.StatusInternalServerError
+		if errors.Is(err, token.ErrTokenInactive) || errors.Is(err, token.ErrTokenExpired) || errors.Is(err, token.ErrTokenRotated) {
+			status = http.StatusConflict
+		}
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), status)
+		return
+	}
+	if req.DurationMinutes > 0 {
+		expiresAt := now.Add(time.Duration(req.DurationMinutes) * time.Minute)
+		successor.ExpiresAt = &expiresAt
+	}
+
+	if err := s.tokenStore.CreateToken(ctx, successor); err != nil {
+		s.logger.Error("failed to store rotated token", zap.String("token_id", tokenID), zap.Error(err), zap.String("request_id", requestID))
+
+		// Audit: token rotate failure - storage error
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenRotate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithTokenID(tokenID).
+			WithProjectID(tokenData.ProjectID).
+			WithError(err).
+			WithDetail("error_type", "storage failed"))
+
+		http.Error(w, `{"error":"failed to store token"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := s.tokenStore.UpdateToken(ctx, retired); err != nil {
+		s.logger.Error("failed to retire rotated token", zap.String("token_id", tokenID), zap.Error(err), zap.String("request_id", requestID))
+
+		// Deactivate the successor so a failed rotation leaves a single valid token
+		successor.IsActive = false
+		successor.DeactivatedAt = &now
+		if rollbackErr := s.tokenStore.UpdateToken(ctx, successor); rollbackErr != nil {
+			s.logger.Error("failed to deactivate successor of failed rotation", zap.String("token_id", successor.ID), zap.Error(rollbackErr), zap.String("request_id", requestID))
+		}
+
+		// Audit: token rotate failure - storage error
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionTokenRotate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithTokenID(tokenID).
+			WithProjectID(tokenData.ProjectID).
+			WithError(err).
+			WithDetail("error_type", "storage failed").
+			WithDetail("rotated_to", successor.ID))
+
+		http.Error(w, `{"error":"failed to rotate token"}`, http.StatusInternalServerError)
+		return
+	}
+	// Drop the cached old token so a shortened grace period applies right away
+	if s.tokenValidator != nil {
+		s.tokenValidator.InvalidateToken(tokenData.Token)
+	}
+
+	s.logger.Info("token rotated",
+		zap.String("token_id", tokenID),
+		zap.String("successor_id", successor.ID),
+		zap.String("project_id", tokenData.ProjectID),
+		zap.Duration("grace_period", grace),
+		zap.String("request_id", requestID),
+	)
+
+	// Audit: token rotate success
+	auditEvent := s.auditEvent(audit.ActionTokenRotate, audit.ActorManagement, audit.ResultSuccess, r, requestID).
+		WithTokenID(tokenID).
+		WithProjectID(tokenData.ProjectID).
+		WithRequestID(requestID).
+		WithHTTPMethod(r.Method).
+		WithEndpoint(r.URL.Path).
+		WithDetail("rotated_from", tokenID).
+		WithDetail("rotated_to", successor.ID).
+		WithDetail("grace_period_minutes", int(grace/time.Minute))
+	if retired.IsActive && retired.ExpiresAt != nil {
+		auditEvent.WithDetail("grace_ends_at", retired.ExpiresAt.Format(time.RFC3339))
+	}
+	_ = s.auditLogger.Log(auditEvent)
+
+	response := map[string]interface{}{
+		"id":              successor.ID,
+		"token":           successor.Token,
+		"expires_at":      successor.ExpiresAt,
+		"rotated_from_id": tokenID,
+	}
+	if successor.MaxRequests != nil {
+		response["max_requests"] = *successor.MaxRequests
+	}
+	if retired.IsActive {
+		response["old_token_expires_at"] = retired.ExpiresAt
+	} else {
+		response["old_token_expires_at"] = now
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("failed to encode rotated token response", zap.Error(err))
+	}
+}
+
 func getRequestID(ctx context.Context) string {
 	if requestID, ok := logging.GetRequestID(ctx); ok && requestID != "" {
 		return requestID
@@ -2052,6 +3149,8 @@ type CachePurgeRequest struct {
 	Method string `json:"method" binding:"required"`
 	URL    string `json:"url" binding:"required"`
 	Prefix string `json:"prefix,omitempty"`
+	// Provider selects which provider's cache to purge (default provider when empty)
+	Provider string `json:"provider,omitempty"`
 }
 
 // CachePurgeResponse represents the response body for cache purge operations
@@ -2075,15 +3174,6 @@ func (s *Server) handleCachePurge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cache := s.proxy.Cache()
-	if cache == nil {
-		s.logger.Warn("cache purge attempted but caching is disabled", zap.String("request_id", requestID))
-		http.Error(w, `{"error":"caching is disabled"}`, http.StatusBadRequest)
-		_ = s.auditLogger.Log(s.auditEvent(audit.ActionCachePurge, audit.ActorManagement, audit.ResultFailure, r, requestID).
-			WithDetail("reason", "caching_disabled"))
-		return
-	}
-
 	var req CachePurgeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.logger.Warn("invalid JSON in cache purge request", zap.Error(err), zap.String("request_id", requestID))
@@ -2093,6 +3183,25 @@ func (s *Server) handleCachePurge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	p := s.providerProxy(req.Provider)
+	if p == nil {
+		s.logger.Warn("cache purge requested for unknown provider", zap.String("provider", req.Provider), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"unknown provider"}`, http.StatusBadRequest)
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionCachePurge, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("reason", "unknown_provider").
+			WithDetail("provider", req.Provider))
+		return
+	}
+
+	cache := p.Cache()
+	if cache == nil {
+		s.logger.Warn("cache purge attempted but caching is disabled", zap.String("request_id", requestID))
+		http.Error(w, `{"error":"caching is disabled"}`, http.StatusBadRequest)
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionCachePurge, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("reason", "caching_disabled"))
+		return
+	}
+
 	// Validate required fields
 	if req.Method == "" || req.URL == "" {
 		s.logger.Warn("missing required fields in cache purge request",
@@ -2164,3 +3273,588 @@ func (s *Server) handleCachePurge(w http.ResponseWriter, r *http.Request) {
 	}
 	_ = s.auditLogger.Log(auditEvent)
 }
+
+// defaultChildTokenDuration is the lifetime of child tokens minted without duration_seconds
+const defaultChildTokenDuration = 15 * time.Minute
+
+// ChildTokenRequest is the request body for POST /v1/proxy/child-tokens
+type ChildTokenRequest struct {
+	// DurationSeconds is the child token lifetime (default 15 minutes, at most 24 hours)
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+	// MaxRequests limits the child token's requests per proxy instance (0 = unlimited)
+	MaxRequests int `json:"max_requests,omitempty"`
+	// Scopes narrow the parent token's scopes; open dimensions are inherited
+	Scopes token.Scopes `json:"scopes,omitempty"`
+	// Metadata is added to the parent token's metadata for token bindings; it
+	// cannot change the parent's values
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// ChildTokenResponse is the response body for POST /v1/proxy/child-tokens
+type ChildTokenResponse struct {
+	Token       string        `json:"token"`
+	ID          string        `json:"id"`
+	ParentID    string        `json:"parent_id"`
+	ProjectID   string        `json:"project_id"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	MaxRequests int           `json:"max_requests,omitempty"`
+	Scopes      *token.Scopes `json:"scopes,omitempty"`
+}
+
+// Handler for POST /v1/proxy/child-tokens. The request is authenticated by
+// the parent token, and minting counts as one request of the parent. The
+// parent must be a stored token whose project is active, and the request must
+// pass the client restrictions of the parent and its project. Child tokens
+// never outlive their parent.
+func (s *Server) handleMintChildToken(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	parentToken, ok := token.ExtractTokenFromHeader(r.Header.Get("Authorization"))
+	if ok && token.IsChildToken(parentToken) {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, token.ErrChildTokenParent.Error()), http.StatusForbidden)
+		return
+	}
+	if !ok {
+		http.Error(w, `{"error":"missing or invalid parent token"}`, http.StatusUnauthorized)
+		return
+	}
+	if _, err := s.tokenValidator.ValidateTokenWithTracking(r.Context(), parentToken); err != nil {
+		status := http.StatusUnauthorized
+		if errors.Is(err, token.ErrTokenRateLimit) {
+			status = http.StatusTooManyRequests
+		}
+		s.logger.Warn("child token mint rejected", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), status)
+		return
+	}
+	parent, err := s.tokenValidator.GetTokenData(r.Context(), parentToken)
+	if err != nil {
+		s.logger.Error("failed to load parent token", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"failed to load parent token"}`, http.StatusInternalServerError)
+		return
+	}
+	if parent.ParentID != "" {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, token.ErrChildTokenParent.Error()), http.StatusForbidden)
+		return
+	}
+	active, err := s.projectStore.GetProjectActive(r.Context(), parent.ProjectID)
+	if err != nil {
+		s.logger.Error("failed to get project status", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"failed to get project status"}`, http.StatusServiceUnavailable)
+		return
+	}
+	if !active {
+		http.Error(w, `{"error":"project is inactive"}`, http.StatusForbidden)
+		return
+	}
+	if !s.allowsClient(w, r, parent) {
+		return
+	}
+
+	var req ChildTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+	duration := defaultChildTokenDuration
+	if req.DurationSeconds != 0 {
+		duration = time.Duration(req.DurationSeconds) * time.Second
+	}
+	if duration <= 0 || duration > token.MaxChildTokenTTL {
+		http.Error(w, fmt.Sprintf(`{"error":"duration_seconds must be between 1 and %d"}`, int(token.MaxChildTokenTTL.Seconds())), http.StatusBadRequest)
+		return
+	}
+	if req.MaxRequests < 0 {
+		http.Error(w, `{"error":"max_requests must be non-negative"}`, http.StatusBadRequest)
+		return
+	}
+	req.Scopes = req.Scopes.Normalize()
+	if err := req.Scopes.Validate(); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid scopes: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+	if !req.Scopes.Within(parent.Scopes) {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, token.ErrChildTokenScopesExceeded.Error()), http.StatusForbidden)
+		return
+	}
+	if err := token.ValidateMetadata(req.Metadata); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid metadata: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+	metadata, err := token.MergeChildMetadata(parent.Metadata, req.Metadata)
+	if err == nil {
+		err = token.ValidateMetadata(metadata)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "invalid metadata: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	expiresAt := time.Now().Add(duration)
+	if parent.ExpiresAt != nil && parent.ExpiresAt.Before(expiresAt) {
+		expiresAt = *parent.ExpiresAt
+	}
+	claims := token.ChildClaims{
+		ID:          uuid.NewString(),
+		ParentID:    parent.ID,
+		ProjectID:   parent.ProjectID,
+		ExpiresAt:   expiresAt.Unix(),
+		MaxRequests: req.MaxRequests,
+		Scopes:      req.Scopes,
+		Metadata:    req.Metadata,
+	}
+	childToken, err := s.childTokens.Sign(claims)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, "failed to sign child token: "+err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	response := ChildTokenResponse{
+		Token:       childToken,
+		ID:          claims.ID,
+		ParentID:    claims.ParentID,
+		ProjectID:   claims.ProjectID,
+		ExpiresAt:   claims.Expiry().UTC(),
+		MaxRequests: claims.MaxRequests,
+	}
+	if effective := claims.Scopes.Inherit(parent.Scopes); !effective.IsEmpty() {
+		response.Scopes = &effective
+	}
+	s.logger.Debug("child token minted",
+		zap.String("child_id", claims.ID),
+		zap.String("parent_id", claims.ParentID),
+		zap.String("request_id", requestID))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("failed to encode child token response", zap.Error(err), zap.String("request_id", requestID))
+	}
+}
+
+// allowsClient reports whether r passes the client restrictions of td and
+// its project, writing the error response when it does not.
+func (s *Server) allowsClient(w http.ResponseWriter, r *http.Request, td token.TokenData) bool {
+	projectRestrictions, err := proxy.GetClientRestrictionsForProject(r.Context(), s.projectStore, td.ProjectID)
+	if err != nil {
+		s.logger.Error("failed to get project client restrictions", zap.Error(err), zap.String("request_id", getRequestID(r.Context())))
+		http.Error(w, `{"error":"failed to get project client restrictions"}`, http.StatusServiceUnavailable)
+		return false
+	}
+	clientIP := proxy.ClientIP(r, s.trustedProxies)
+	origin, referer := r.Header.Get("Origin"), r.Header.Get("Referer")
+	for _, restrictions := range []token.ClientRestrictions{projectRestrictions, td.ClientRestrictions} {
+		if !restrictions.AllowsIP(clientIP) || !restrictions.AllowsOrigin(origin, referer) {
+			http.Error(w, `{"error":"client not allowed"}`, http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// TokenIntrospectionResponse is the response body for GET /v1/proxy/token
+type TokenIntrospectionResponse struct {
+	ID                    string        `json:"id"`
+	ProjectID             string        `json:"project_id"`
+	ProjectActive         bool          `json:"project_active"`
+	ParentID              string        `json:"parent_id,omitempty"`
+	IsActive              bool          `json:"is_active"`
+	ExpiresAt             *time.Time    `json:"expires_at"`
+	MaxRequests           *int          `json:"max_requests"`
+	RemainingRequests     *int          `json:"remaining_requests"`      // null when the token has no request limit
+	RemainingBudget       *float64      `json:"remaining_budget"`        // USD left of the token and project budgets; null when no USD budget applies
+	RemainingBudgetTokens *int64        `json:"remaining_budget_tokens"` // tokens left of the token and project budgets; null when no token budget applies
+	Scopes                *token.Scopes `json:"scopes,omitempty"`
+}
+
+// Handler for GET /v1/proxy/token. The request is authenticated by the token
+// it describes, must pass the client restrictions of the token and its
+// project, and is not counted as a request of the token. Tokens that used up
+// their requests can still introspect themselves.
+func (s *Server) handleTokenIntrospection(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokenString, ok := token.ExtractTokenFromHeader(r.Header.Get("Authorization"))
+	if !ok {
+		http.Error(w, `{"error":"missing or invalid Authorization header"}`, http.StatusUnauthorized)
+		return
+	}
+	if _, err := s.tokenValidator.ValidateToken(r.Context(), tokenString); err != nil && !errors.Is(err, token.ErrTokenRateLimit) {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusUnauthorized)
+		return
+	}
+	td, err := s.tokenValidator.GetTokenData(r.Context(), tokenString)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, token.ErrTokenRateLimit) {
+			// A child token whose parent used up its requests
+			status = http.StatusTooManyRequests
+		}
+		s.logger.Warn("token introspection failed", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), status)
+		return
+	}
+	if !s.allowsClient(w, r, td) {
+		return
+	}
+
+	response := TokenIntrospectionResponse{
+		ID:          td.ID,
+		ProjectID:   td.ProjectID,
+		ParentID:    td.ParentID,
+		IsActive:    td.IsActive,
+		ExpiresAt:   td.ExpiresAt,
+		MaxRequests: td.MaxRequests,
+	}
+	if !td.Scopes.IsEmpty() {
+		response.Scopes = &td.Scopes
+	}
+	if _, limited := td.RemainingRequests(); limited {
+		remaining, err := s.tokenLimits.GetRemainingRequests(r.Context(), tokenString)
+		if err != nil {
+			s.logger.Error("failed to get remaining requests", zap.Error(err), zap.String("request_id", requestID))
+			http.Error(w, `{"error":"failed to get remaining requests"}`, http.StatusInternalServerError)
+			return
+		}
+		response.RemainingRequests = &remaining
+	}
+	if s.budgets != nil {
+		// Child tokens spend the budget of the stored token they were derived from
+		budgetTokenID := td.ID
+		if td.ParentID != "" {
+			budgetTokenID = td.ParentID
+		}
+		response.RemainingBudget, response.RemainingBudgetTokens, err = s.budgets.Remaining(r.Context(), td.ProjectID, budgetTokenID)
+		if err != nil {
+			s.logger.Error("failed to get remaining budget", zap.Error(err), zap.String("request_id", requestID))
+			http.Error(w, `{"error":"failed to get remaining budget"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+	response.ProjectActive, err = s.projectStore.GetProjectActive(r.Context(), td.ProjectID)
+	if err != nil {
+		s.logger.Error("failed to get project status", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"failed to get project status"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("failed to encode token introspection response", zap.Error(err), zap.String("request_id", requestID))
+	}
+}
+
+// ModelRoutesRequest is the request body for PUT /manage/routes
+type ModelRoutesRequest struct {
+	Routes []proxy.ModelRoute `json:"routes"`
+}
+
+// ModelRoutesResponse describes the active model routing table
+type ModelRoutesResponse struct {
+	DefaultProvider string             `json:"default_provider"`
+	Routes          []proxy.ModelRoute `json:"routes"`
+}
+
+// Handler for GET/PUT /manage/routes
+func (s *Server) handleModelRoutes(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if s.modelRouter == nil {
+		s.logger.Error("model router not initialized", zap.String("request_id", requestID))
+		http.Error(w, `{"error":"proxy not available"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionModelRoutesRead, audit.ActorManagement, audit.ResultSuccess, r, requestID))
+		s.writeModelRoutes(w, requestID)
+		return
+	}
+
+	var req ModelRoutesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Warn("invalid JSON in model routes request", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionModelRoutesUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("reason", "invalid_json"))
+		return
+	}
+	previous := s.modelRouter.Routes()
+	if err := s.modelRouter.SetRoutes(req.Routes); err != nil {
+		s.logger.Warn("invalid model routes", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionModelRoutesUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("reason", "invalid_routes").
+			WithError(err))
+		return
+	}
+
+	s.logger.Info("model routes updated",
+		zap.Int("previous", len(previous)), zap.Int("routes", len(req.Routes)), zap.String("request_id", requestID))
+	_ = s.auditLogger.Log(s.auditEvent(audit.ActionModelRoutesUpdate, audit.ActorManagement, audit.ResultSuccess, r, requestID).
+		WithDetail("previous_routes", previous).
+		WithDetail("routes", req.Routes))
+	s.writeModelRoutes(w, requestID)
+}
+
+func (s *Server) writeModelRoutes(w http.ResponseWriter, requestID string) {
+	resp := ModelRoutesResponse{
+		DefaultProvider: s.modelRouter.DefaultProvider(),
+		Routes:          s.modelRouter.Routes(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Error("failed to encode model routes response", zap.Error(err), zap.String("request_id", requestID))
+	}
+}
+
+// PricesRequest is the request body for PUT /manage/prices
+type PricesRequest struct {
+	Prices []pricing.Price `json:"prices"`
+}
+
+// PricesResponse describes the model price catalog
+type PricesResponse struct {
+	Prices []pricing.Price `json:"prices"`
+}
+
+// Handler for GET/PUT /manage/prices
+func (s *Server) handlePrices(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r.Context())
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if s.prices == nil {
+		s.logger.Error("price catalog not initialized", zap.String("request_id", requestID))
+		http.Error(w, `{"error":"proxy not available"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionPricesRead, audit.ActorManagement, audit.ResultSuccess, r, requestID))
+		s.writePrices(w, requestID)
+		return
+	}
+
+	var req PricesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.logger.Warn("invalid JSON in prices request", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionPricesUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("reason", "invalid_json"))
+		return
+	}
+	if err := pricing.Validate(req.Prices); err != nil {
+		s.logger.Warn("invalid model prices", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		_ = s.auditLogger.Log(s.auditEvent(audit.ActionPricesUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+			WithDetail("reason", "invalid_prices").
+			WithError(err))
+		return
+	}
+	if s.db != nil {
+		if err := s.db.SetModelPrices(r.Context(), req.Prices); err != nil {
+			s.logger.Error("failed to save model prices", zap.Error(err), zap.String("request_id", requestID))
+			http.Error(w, `{"error":"failed to save prices"}`, http.StatusInternalServerError)
+			_ = s.auditLogger.Log(s.auditEvent(audit.ActionPricesUpdate, audit.ActorManagement, audit.ResultFailure, r, requestID).
+				WithDetail("reason", "database_error").
+				WithError(err))
+			return
+		}
+	}
+	previous := s.prices.Prices()
+	if err := s.prices.SetPrices(req.Prices); err != nil {
+		// Already validated above
+		s.logger.Error("failed to set model prices", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"failed to set prices"}`, http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("model prices updated",
+		zap.Int("previous", len(previous)), zap.Int("prices", len(req.Prices)), zap.String("request_id", requestID))
+	_ = s.auditLogger.Log(s.auditEvent(audit.ActionPricesUpdate, audit.ActorManagement, audit.ResultSuccess, r, requestID).
+		WithDetail("previous_prices", previous).
+		WithDetail("prices", req.Prices))
+	s.writePrices(w, requestID)
+}
+
+func (s *Server) writePrices(w http.ResponseWriter, requestID string) {
+	resp := PricesResponse{Prices: s.prices.Prices()}
+	if resp.Prices == nil {
+		resp.Prices = []pricing.Price{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Error("failed to encode prices response", zap.Error(err), zap.String("request_id", requestID))
+	}
+}
+
+// CostsResponse reports accumulated usage and cost, either over all projects
+// or, when ProjectID is set, for one project and its tokens
+type CostsResponse struct {
+	ProjectID string                 `json:"project_id,omitempty"`
+	Total     database.CostTotals    `json:"total"`
+	Projects  []database.ProjectCost `json:"projects,omitempty"`
+	Tokens    []database.TokenCost   `json:"tokens,omitempty"`
+}
+
+// Handler for GET /manage/costs
+func (s *Server) handleCosts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := getRequestID(ctx)
+
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if s.db == nil {
+		s.logger.Error("costs requested but database not available", zap.String("request_id", requestID))
+		http.Error(w, `{"error":"costs not available"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	var (
+		resp CostsResponse
+		err  error
+	)
+	if projectID := r.URL.Query().Get("projectId"); projectID != "" {
+		resp.ProjectID = projectID
+		if resp.Total, err = s.db.GetProjectCost(ctx, projectID); err == nil {
+			resp.Tokens, err = s.db.ListTokenCosts(ctx, projectID)
+		}
+	} else if resp.Total, err = s.db.GetTotalCost(ctx); err == nil {
+		resp.Projects, err = s.db.ListProjectCosts(ctx)
+	}
+	if err != nil {
+		s.logger.Error("failed to get costs", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"failed to get costs"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		s.logger.Error("failed to encode costs response", zap.Error(err), zap.String("request_id", requestID))
+	}
+}
+
+// Handler for GET/PUT/DELETE /manage/projects/{id}/budget and /manage/tokens/{id}/budget
+func (s *Server) handleBudget(w http.ResponseWriter, r *http.Request, subjectType, subjectID string) {
+	ctx := r.Context()
+	requestID := getRequestID(ctx)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if subjectID == "" || strings.Contains(subjectID, "/") {
+		http.Error(w, fmt.Sprintf(`{"error":"invalid %s id"}`, subjectType), http.StatusBadRequest)
+		return
+	}
+	if s.db == nil || s.budgets == nil {
+		s.logger.Error("budgets requested but database not available", zap.String("request_id", requestID))
+		http.Error(w, `{"error":"budgets not available"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	var err error
+	if subjectType == proxy.BudgetSubjectProject {
+		_, err = s.projectStore.GetProjectByID(ctx, subjectID)
+	} else {
+		_, err = s.tokenStore.GetTokenByID(ctx, subjectID)
+	}
+	if err != nil {
+		s.logger.Error("budget subject not found", zap.String("subject_type", subjectType), zap.String("id", subjectID), zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, fmt.Sprintf(`{"error":"%s not found"}`, subjectType), http.StatusNotFound)
+		return
+	}
+
+	auditEvent := func(action string, result audit.ResultType) *audit.Event {
+		event := s.auditEvent(action, audit.ActorManagement, result, r, requestID)
+		if subjectType == proxy.BudgetSubjectProject {
+			return event.WithProjectID(subjectID)
+		}
+		return event.WithTokenID(subjectID)
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var budget proxy.Budget
+		if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+			s.logger.Warn("invalid JSON in budget request", zap.Error(err), zap.String("request_id", requestID))
+			http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+			_ = s.auditLogger.Log(auditEvent(audit.ActionBudgetUpdate, audit.ResultFailure).
+				WithDetail("reason", "invalid_json"))
+			return
+		}
+		if err := budget.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			_ = s.auditLogger.Log(auditEvent(audit.ActionBudgetUpdate, audit.ResultFailure).
+				WithDetail("reason", "invalid_budget").
+				WithError(err))
+			return
+		}
+		if err := s.db.SetBudget(ctx, subjectType, subjectID, budget); err != nil {
+			s.logger.Error("failed to save budget", zap.Error(err), zap.String("request_id", requestID))
+			http.Error(w, `{"error":"failed to save budget"}`, http.StatusInternalServerError)
+			_ = s.auditLogger.Log(auditEvent(audit.ActionBudgetUpdate, audit.ResultFailure).
+				WithDetail("reason", "database_error").
+				WithError(err))
+			return
+		}
+		s.budgets.Invalidate(subjectType, subjectID)
+		_ = s.auditLogger.Log(auditEvent(audit.ActionBudgetUpdate, audit.ResultSuccess).
+			WithDetail("budget", budget))
+
+	case http.MethodDelete:
+		err := s.db.DeleteBudget(ctx, subjectType, subjectID)
+		if errors.Is(err, database.ErrBudgetNotFound) {
+			http.Error(w, `{"error":"budget not found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			s.logger.Error("failed to delete budget", zap.Error(err), zap.String("request_id", requestID))
+			http.Error(w, `{"error":"failed to delete budget"}`, http.StatusInternalServerError)
+			_ = s.auditLogger.Log(auditEvent(audit.ActionBudgetDelete, audit.ResultFailure).
+				WithDetail("reason", "database_error").
+				WithError(err))
+			return
+		}
+		s.budgets.Invalidate(subjectType, subjectID)
+		_ = s.auditLogger.Log(auditEvent(audit.ActionBudgetDelete, audit.ResultSuccess))
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		_ = s.auditLogger.Log(auditEvent(audit.ActionBudgetRead, audit.ResultSuccess))
+	}
+
+	status, err := s.budgets.Status(ctx, subjectType, subjectID)
+	if err != nil {
+		s.logger.Error("failed to get budget status", zap.Error(err), zap.String("request_id", requestID))
+		http.Error(w, `{"error":"failed to get budget"}`, http.StatusInternalServerError)
+		return
+	}
+	if status == nil {
+		http.Error(w, `{"error":"budget not found"}`, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		s.logger.Error("failed to encode budget response", zap.Error(err), zap.String("request_id", requestID))
+	}
+}
@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,6 +15,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 	"github.com/sofatutor/llm-proxy/internal/audit"
 	"github.com/sofatutor/llm-proxy/internal/config"
 	"github.com/sofatutor/llm-proxy/internal/database"
@@ -176,12 +180,16 @@ func TestMetricsPrometheusEndpoint(t *testing.T) {
 	require.NoError(t, err)
 	p := &proxy.TransparentProxy{}
 	p.SetMetrics(&proxy.ProxyMetrics{
-		RequestCount: 42,
-		ErrorCount:   7,
-		CacheHits:    10,
-		CacheMisses:  20,
-		CacheBypass:  5,
-		CacheStores:  15,
+		RequestCount:    42,
+		ErrorCount:      7,
+		CacheHits:       10,
+		CacheMisses:     20,
+		CacheBypass:     5,
+		CacheStores:     15,
+		HedgedRequests:  4,
+		HedgeWins:       3,
+		ClientIPDenials: 2,
+		OriginDenials:   1,
 	})
 	server.proxy = p
 
@@ -223,6 +231,14 @@ func TestMetricsPrometheusEndpoint(t *testing.T) {
 	assert.Contains(t, body, "# TYPE llm_proxy_cache_stores_total counter")
 	assert.Contains(t, body, "llm_proxy_cache_stores_total 15")
 
+	assert.Contains(t, body, "# TYPE llm_proxy_hedged_requests_total counter")
+	assert.Contains(t, body, "llm_proxy_hedged_requests_total 4")
+	assert.Contains(t, body, "# TYPE llm_proxy_hedge_wins_total counter")
+	assert.Contains(t, body, "llm_proxy_hedge_wins_total 3")
+	assert.Contains(t, body, "# TYPE llm_proxy_client_restriction_denials_total counter")
+	assert.Contains(t, body, `llm_proxy_client_restriction_denials_total{reason="ip_not_allowed"} 2`)
+	assert.Contains(t, body, `llm_proxy_client_restriction_denials_total{reason="origin_not_allowed"} 1`)
+
 	// Verify Go runtime metrics are present
 	assert.Contains(t, body, "# HELP llm_proxy_goroutines")
 	assert.Contains(t, body, "# TYPE llm_proxy_goroutines gauge")
@@ -253,6 +269,67 @@ func TestMetricsPrometheusEndpoint(t *testing.T) {
 	assert.Contains(t, body, "llm_proxy_gc_next_bytes")
 }
 
+// staticTokenValidator accepts every token for a single project.
+type staticTokenValidator struct{}
+
+func (staticTokenValidator) ValidateToken(ctx context.Context, token string) (string, error) {
+	return "project-1", nil
+}
+
+func (staticTokenValidator) ValidateTokenWithTracking(ctx context.Context, token string) (string, error) {
+	return "project-1", nil
+}
+
+func TestCircuitBreakerExposure(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		ListenAddr:      ":8080",
+		RequestTimeout:  30 * time.Second,
+		EnableMetrics:   true,
+		MetricsPath:     "/metrics",
+		EventBusBackend: "in-memory",
+	}
+	server, err := New(cfg, &mockTokenStore{}, &mockProjectStore{})
+	require.NoError(t, err)
+	p, err := proxy.NewTransparentProxyWithLogger(proxy.ProxyConfig{
+		TargetBaseURL:    upstream.URL,
+		Provider:         "openai",
+		AllowedEndpoints: []string{"/v1/models"},
+		AllowedMethods:   []string{http.MethodGet},
+		CircuitBreaker:   proxy.CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute},
+	}, staticTokenValidator{}, &mockProjectStore{}, zap.NewNop())
+	require.NoError(t, err)
+	server.proxy = p
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	p.Handler().ServeHTTP(httptest.NewRecorder(), req)
+
+	rr := httptest.NewRecorder()
+	server.handleHealth(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health HealthResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &health))
+	assert.Equal(t, "ok", health.Status)
+	require.Len(t, health.CircuitBreakers, 1)
+	assert.Equal(t, "openai", health.CircuitBreakers[0].Provider)
+	assert.Equal(t, proxy.CircuitOpen, health.CircuitBreakers[0].State)
+
+	rr = httptest.NewRecorder()
+	server.handleMetrics(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rr.Body.String(), `"circuit_breakers":[{"provider":"openai","state":"open"`)
+
+	rr = httptest.NewRecorder()
+	server.handleMetricsPrometheus(rr, httptest.NewRequest(http.MethodGet, "/metrics/prometheus", nil))
+	body := rr.Body.String()
+	assert.Contains(t, body, "# TYPE llm_proxy_circuit_breaker_state gauge")
+	assert.Contains(t, body, `llm_proxy_circuit_breaker_state{provider="openai",key_id=""} 2`)
+	assert.Contains(t, body, `llm_proxy_circuit_breaker_opens_total{provider="openai",key_id=""} 1`)
+}
+
 func TestMetricsPrometheusEndpoint_NoProxy(t *testing.T) {
 	cfg := &config.Config{
 		ListenAddr:      ":8080",
@@ -493,7 +570,7 @@ func (m *mockTokenStore) ListTokens(ctx context.Context) ([]token.TokenData, err
 
 type mockProjectStore struct{}
 
-func (m *mockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID string) (string, error) {
+func (m *mockProjectStore) GetAPIKeyForProject(ctx context.Context, projectID, provider string) (string, error) {
 	return "mock-key", nil
 }
 func (m *mockProjectStore) GetProjectActive(ctx context.Context, projectID string) (bool, error) {
@@ -1353,6 +1430,416 @@ func TestHandleTokens_Create_WithMaxRequests(t *testing.T) {
 	require.Equal(t, float64(5), val)
 }
 
+func TestHandleTokens_Create_WithScopes(t *testing.T) {
+	cfg := &config.Config{ListenAddr: ":0", RequestTimeout: time.Second, ManagementToken: "testtoken", EventBusBackend: "in-memory"}
+	ts := &recordingTokenStore{}
+	srv, err := New(cfg, ts, &activeProjectStore{})
+	require.NoError(t, err)
+
+	body := `{"project_id":"any","duration_minutes":60,"scopes":{"endpoints":["/v1/chat/completions"],"methods":["post"],"models":["gpt-4o-mini"]}}`
+	r := httptest.NewRequest(http.MethodPost, "/manage/tokens", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+	w := httptest.NewRecorder()
+	srv.handleTokens(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, token.Scopes{
+		Endpoints: []string{"/v1/chat/completions"},
+		Methods:   []string{"POST"},
+		Models:    []string{"gpt-4o-mini"},
+	}, ts.created.Scopes)
+	require.Contains(t, w.Body.String(), `"scopes":{"endpoints":["/v1/chat/completions"],"methods":["POST"],"models":["gpt-4o-mini"]}`)
+
+	body = `{"project_id":"any","duration_minutes":60,"scopes":{"endpoints":["v1/files"]}}`
+	r = httptest.NewRequest(http.MethodPost, "/manage/tokens", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+	w = httptest.NewRecorder()
+	srv.handleTokens(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "invalid scopes")
+}
+
+func TestHandleTokens_Create_WithMetadata(t *testing.T) {
+	cfg := &config.Config{ListenAddr: ":0", RequestTimeout: time.Second, ManagementToken: "testtoken", EventBusBackend: "in-memory"}
+	ts := &recordingTokenStore{}
+	srv, err := New(cfg, ts, &activeProjectStore{})
+	require.NoError(t, err)
+
+	body := `{"project_id":"any","duration_minutes":15,"metadata":{"user_id":"42"}}`
+	r := httptest.NewRequest(http.MethodPost, "/manage/tokens", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+	w := httptest.NewRecorder()
+	srv.handleTokens(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, map[string]string{"user_id": "42"}, ts.created.Metadata)
+	require.Contains(t, w.Body.String(), `"metadata":{"user_id":"42"}`)
+
+	body = `{"project_id":"any","duration_minutes":15,"metadata":{"user id":"42"}}`
+	r = httptest.NewRequest(http.MethodPost, "/manage/tokens", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+	w = httptest.NewRecorder()
+	srv.handleTokens(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "invalid metadata")
+}
+
+func TestHandleTokens_Create_WithClientRestrictions(t *testing.T) {
+	cfg := &config.Config{ListenAddr: ":0", RequestTimeout: time.Second, ManagementToken: "testtoken", EventBusBackend: "in-memory"}
+	ts := &recordingTokenStore{}
+	srv, err := New(cfg, ts, &activeProjectStore{})
+	require.NoError(t, err)
+
+	body := `{"project_id":"any","duration_minutes":15,"client_restrictions":{"cidrs":["203.0.113.0/24"],"origins":["HTTPS://App.Example.com/"]}}`
+	r := httptest.NewRequest(http.MethodPost, "/manage/tokens", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+	w := httptest.NewRecorder()
+	srv.handleTokens(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, token.ClientRestrictions{
+		CIDRs:   []string{"203.0.113.0/24"},
+		Origins: []string{"https://app.example.com"},
+	}, ts.created.ClientRestrictions)
+	require.Contains(t, w.Body.String(), `"client_restrictions":{"cidrs":["203.0.113.0/24"],"origins":["https://app.example.com"]}`)
+
+	body = `{"project_id":"any","duration_minutes":15,"client_restrictions":{"cidrs":["10.0.0.0/33"]}}`
+	r = httptest.NewRequest(http.MethodPost, "/manage/tokens", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+	w = httptest.NewRecorder()
+	srv.handleTokens(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "invalid client_restrictions")
+}
+
+// parentTokenStore holds a single parent token for child token tests.
+type parentTokenStore struct {
+	mockTokenStore
+	parent token.TokenData
+}
+
+func (p *parentTokenStore) GetTokenByID(ctx context.Context, id string) (token.TokenData, error) {
+	if id != p.parent.ID {
+		return token.TokenData{}, token.ErrTokenNotFound
+	}
+	return p.parent, nil
+}
+
+func (p *parentTokenStore) GetTokenByToken(ctx context.Context, tokenString string) (token.TokenData, error) {
+	if tokenString != p.parent.Token {
+		return token.TokenData{}, token.ErrTokenNotFound
+	}
+	return p.parent, nil
+}
+
+func (p *parentTokenStore) IncrementTokenUsage(ctx context.Context, tokenString string) error {
+	return nil
+}
+
+func TestHandleMintChildToken(t *testing.T) {
+	parentToken, err := token.GenerateToken()
+	require.NoError(t, err)
+	parentExpiry := time.Now().Add(5 * time.Minute)
+	store := &parentTokenStore{parent: token.TokenData{
+		ID:        "parent-id",
+		Token:     parentToken,
+		ProjectID: "project-id",
+		IsActive:  true,
+		ExpiresAt: &parentExpiry,
+		Scopes:    token.Scopes{Models: []string{"gpt-4o*"}},
+	}}
+	cfg := &config.Config{
+		ListenAddr:       ":0",
+		RequestTimeout:   time.Second,
+		ManagementToken:  "testtoken",
+		EventBusBackend:  "in-memory",
+		APIConfigPath:    "/nonexistent/api_providers.yaml",
+		ChildTokenSecret: "0123456789abcdef0123456789abcdef",
+	}
+	srv, err := New(cfg, store, &mockProjectStore{})
+	require.NoError(t, err)
+	require.NoError(t, srv.initializeAPIRoutes())
+
+	mint := func(auth, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/proxy/child-tokens", strings.NewReader(body))
+		if auth != "" {
+			r.Header.Set("Authorization", "Bearer "+auth)
+		}
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := mint(parentToken, `{"duration_seconds":3600,"max_requests":3,"scopes":{"models":["gpt-4o-mini"]}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp ChildTokenResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.True(t, token.IsChildToken(resp.Token))
+	assert.Equal(t, "parent-id", resp.ParentID)
+	assert.Equal(t, "project-id", resp.ProjectID)
+	assert.Equal(t, 3, resp.MaxRequests)
+	assert.WithinDuration(t, parentExpiry, resp.ExpiresAt, time.Second, "child tokens never outlive their parent")
+
+	projectID, err := srv.tokenValidator.ValidateTokenWithTracking(context.Background(), resp.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "project-id", projectID)
+
+	w = mint(parentToken, "")
+	assert.Equal(t, http.StatusOK, w.Code, "an empty body uses the defaults")
+
+	w = mint(parentToken, `{"scopes":{"models":["claude-*"]}}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = mint(parentToken, `{"duration_seconds":172800}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = mint(resp.Token, `{}`)
+	assert.Equal(t, http.StatusForbidden, w.Code, "child tokens cannot mint child tokens")
+	assert.Contains(t, w.Body.String(), token.ErrChildTokenParent.Error())
+
+	w = mint("", `{}`)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// mintProjectStore is a mockProjectStore with a configurable project status
+// and client restrictions.
+type mintProjectStore struct {
+	mockProjectStore
+	inactive     bool
+	restrictions token.ClientRestrictions
+}
+
+func (m *mintProjectStore) GetProjectActive(ctx context.Context, projectID string) (bool, error) {
+	return !m.inactive, nil
+}
+
+func (m *mintProjectStore) GetClientRestrictionsForProject(ctx context.Context, projectID string) (token.ClientRestrictions, error) {
+	return m.restrictions, nil
+}
+
+func TestHandleMintChildToken_ParentChecks(t *testing.T) {
+	parentToken, err := token.GenerateToken()
+	require.NoError(t, err)
+	store := &parentTokenStore{parent: token.TokenData{
+		ID:                 "parent-id",
+		Token:              parentToken,
+		ProjectID:          "project-id",
+		IsActive:           true,
+		Metadata:           map[string]string{"user_id": "42", "client_ip": "203.0.113.7"},
+		ClientRestrictions: token.ClientRestrictions{CIDRs: []string{"203.0.113.0/24"}},
+	}}
+	projects := &mintProjectStore{}
+	cfg := &config.Config{
+		ListenAddr:       ":0",
+		RequestTimeout:   time.Second,
+		ManagementToken:  "testtoken",
+		EventBusBackend:  "in-memory",
+		APIConfigPath:    "/nonexistent/api_providers.yaml",
+		ChildTokenSecret: "0123456789abcdef0123456789abcdef",
+		TrustedProxies:   []string{"192.0.2.0/24"}, // httptest requests come from 192.0.2.1
+	}
+	srv, err := New(cfg, store, projects)
+	require.NoError(t, err)
+	require.NoError(t, srv.initializeAPIRoutes())
+
+	mint := func(body, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/proxy/child-tokens", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+parentToken)
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("metadata is merged into the parent's", func(t *testing.T) {
+		w := mint(`{"metadata":{"session_id":"s-1"}}`, "203.0.113.7")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp ChildTokenResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		td, err := srv.tokenValidator.GetTokenData(context.Background(), resp.Token)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"user_id": "42", "client_ip": "203.0.113.7", "session_id": "s-1"}, td.Metadata)
+
+		w = mint(`{"metadata":{"client_ip":"198.51.100.1"}}`, "203.0.113.7")
+		assert.Equal(t, http.StatusBadRequest, w.Code, "a child cannot override a parent binding")
+		assert.Contains(t, w.Body.String(), "conflicts with parent metadata")
+	})
+
+	t.Run("token client restrictions", func(t *testing.T) {
+		w := mint(`{}`, "198.51.100.1")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "client not allowed")
+	})
+
+	t.Run("project client restrictions", func(t *testing.T) {
+		projects.restrictions = token.ClientRestrictions{Origins: []string{"https://app.example.com"}}
+		defer func() { projects.restrictions = token.ClientRestrictions{} }()
+		w := mint(`{}`, "203.0.113.7")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("inactive project", func(t *testing.T) {
+		projects.inactive = true
+		defer func() { projects.inactive = false }()
+		w := mint(`{}`, "203.0.113.7")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "project is inactive")
+	})
+}
+
+func TestHandleTokenIntrospection(t *testing.T) {
+	parentToken, err := token.GenerateToken()
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	maxRequests := 5
+	parent := token.TokenData{
+		ID:           "parent-id",
+		Token:        parentToken,
+		ProjectID:    "project-id",
+		IsActive:     true,
+		ExpiresAt:    &expiresAt,
+		MaxRequests:  &maxRequests,
+		RequestCount: 2,
+		Scopes:       token.Scopes{Models: []string{"gpt-4o*"}},
+	}
+	cfg := &config.Config{
+		ListenAddr:       ":0",
+		RequestTimeout:   time.Second,
+		ManagementToken:  "testtoken",
+		EventBusBackend:  "in-memory",
+		APIConfigPath:    "/nonexistent/api_providers.yaml",
+		ChildTokenSecret: "0123456789abcdef0123456789abcdef",
+	}
+	newServer := func(parent token.TokenData) *Server {
+		srv, err := New(cfg, &parentTokenStore{parent: parent}, &mockProjectStore{})
+		require.NoError(t, err)
+		require.NoError(t, srv.initializeAPIRoutes())
+		return srv
+	}
+	introspect := func(srv *Server, method, auth string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/v1/proxy/token", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", "Bearer "+auth)
+		}
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	srv := newServer(parent)
+	w := introspect(srv, http.MethodGet, parentToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "api_key")
+	var resp TokenIntrospectionResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "parent-id", resp.ID)
+	assert.Equal(t, "project-id", resp.ProjectID)
+	assert.True(t, resp.ProjectActive)
+	assert.True(t, resp.IsActive)
+	require.NotNil(t, resp.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*resp.ExpiresAt))
+	require.NotNil(t, resp.RemainingRequests)
+	assert.Equal(t, 3, *resp.RemainingRequests)
+	assert.Nil(t, resp.RemainingBudget)
+	require.NotNil(t, resp.Scopes)
+	assert.Equal(t, []string{"gpt-4o*"}, resp.Scopes.Models)
+
+	childToken, err := srv.childTokens.Sign(token.ChildClaims{
+		ParentID:  "parent-id",
+		ProjectID: "project-id",
+		ExpiresAt: time.Now().Add(time.Minute).Unix(),
+	})
+	require.NoError(t, err)
+	w = introspect(srv, http.MethodGet, childToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp = TokenIntrospectionResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, "parent-id", resp.ParentID)
+	assert.Nil(t, resp.RemainingRequests, "child tokens without max_requests are unlimited")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, introspect(srv, http.MethodPost, parentToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, introspect(srv, http.MethodGet, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, introspect(srv, http.MethodGet, "sk-unknown").Code)
+
+	// Tokens that used up their requests can still introspect themselves
+	parent.RequestCount = maxRequests
+	w = introspect(newServer(parent), http.MethodGet, parentToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp = TokenIntrospectionResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	require.NotNil(t, resp.RemainingRequests)
+	assert.Equal(t, 0, *resp.RemainingRequests)
+}
+
+func TestHandleTokenIntrospection_ClientRestrictions(t *testing.T) {
+	tokenString, err := token.GenerateToken()
+	require.NoError(t, err)
+	store := &parentTokenStore{parent: token.TokenData{
+		ID:                 "token-id",
+		Token:              tokenString,
+		ProjectID:          "project-id",
+		IsActive:           true,
+		ClientRestrictions: token.ClientRestrictions{CIDRs: []string{"203.0.113.0/24"}},
+	}}
+	projects := &mintProjectStore{}
+	cfg := &config.Config{
+		ListenAddr:      ":0",
+		RequestTimeout:  time.Second,
+		ManagementToken: "testtoken",
+		EventBusBackend: "in-memory",
+		APIConfigPath:   "/nonexistent/api_providers.yaml",
+		TrustedProxies:  []string{"192.0.2.0/24"}, // httptest requests come from 192.0.2.1
+	}
+	srv, err := New(cfg, store, projects)
+	require.NoError(t, err)
+	require.NoError(t, srv.initializeAPIRoutes())
+
+	introspect := func(forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/v1/proxy/token", nil)
+		r.Header.Set("Authorization", "Bearer "+tokenString)
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		srv.server.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, introspect("203.0.113.7").Code)
+
+	w := introspect("198.51.100.1")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "client not allowed")
+
+	projects.restrictions = token.ClientRestrictions{Origins: []string{"https://app.example.com"}}
+	assert.Equal(t, http.StatusForbidden, introspect("203.0.113.7").Code)
+}
+
+func TestChildTokenCodecConfig(t *testing.T) {
+	codec, err := childTokenCodec(&config.Config{})
+	require.NoError(t, err)
+	assert.Nil(t, codec)
+
+	_, err = childTokenCodec(&config.Config{ChildTokenSecret: "short"})
+	assert.Error(t, err)
+
+	seed := base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize))
+	_, err = childTokenCodec(&config.Config{ChildTokenSecret: "0123456789abcdef0123456789abcdef", ChildTokenPrivateKey: seed})
+	assert.Error(t, err, "HMAC and Ed25519 keys are mutually exclusive")
+
+	codec, err = childTokenCodec(&config.Config{ChildTokenPrivateKey: seed})
+	require.NoError(t, err)
+	assert.True(t, codec.CanSign())
+
+	public := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public().(ed25519.PublicKey)
+	codec, err = childTokenCodec(&config.Config{ChildTokenPublicKey: base64.StdEncoding.EncodeToString(public)})
+	require.NoError(t, err)
+	assert.False(t, codec.CanSign())
+}
+
 func TestHandleTokens_Create_InvalidMaxRequests(t *testing.T) {
 	cfg := &config.Config{ListenAddr: ":0", RequestTimeout: time.Second, ManagementToken: "testtoken", EventBusBackend: "in-memory"}
 	srv, err := New(cfg, &recordingTokenStore{}, &activeProjectStore{})
@@ -1414,6 +1901,176 @@ func TestHandleUpdateToken_MaxRequestsZeroClearsLimit(t *testing.T) {
 	}
 }
 
+func TestHandleUpdateToken_Metadata(t *testing.T) {
+	cfg := &config.Config{ListenAddr: ":0", RequestTimeout: time.Second, ManagementToken: "testtoken", EventBusBackend: "in-memory"}
+	store := &updatingTokenStore{existing: token.TokenData{ID: "tok-1", Token: "sk-test123456789", ProjectID: "any", IsActive: true, Metadata: map[string]string{"user_id": "1"}, CreatedAt: time.Now()}}
+	srv, err := New(cfg, store, &activeProjectStore{})
+	require.NoError(t, err)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPatch, "/manage/tokens/tok-1", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleUpdateToken(w, r, "tok-1")
+		return w
+	}
+
+	w := patch(`{"metadata":{"user_id":"2","client_ip":"203.0.113.7"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, map[string]string{"user_id": "2", "client_ip": "203.0.113.7"}, store.updated.Metadata)
+
+	w = patch(`{"metadata":{"bad key":"x"}}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "invalid metadata")
+}
+
+func TestHandleUpdateToken_RateLimit(t *testing.T) {
+	cfg := &config.Config{ListenAddr: ":0", RequestTimeout: time.Second, ManagementToken: "testtoken", EventBusBackend: "in-memory"}
+	store := &updatingTokenStore{existing: token.TokenData{ID: "tok-1", Token: "sk-test123456789", ProjectID: "any", IsActive: true, CreatedAt: time.Now()}}
+	srv, err := New(cfg, store, &activeProjectStore{})
+	require.NoError(t, err)
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPatch, "/manage/tokens/tok-1", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.handleUpdateToken(w, r, "tok-1")
+		return w
+	}
+
+	w := patch(`{"rate_limit":{"requests_per_window":30,"burst":5}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Equal(t, token.RateLimit{RequestsPerWindow: 30, WindowSeconds: 60, Burst: 5}, store.updated.RateLimit)
+	require.Contains(t, w.Body.String(), `"rate_limit":{"requests_per_window":30,"window_seconds":60,"burst":5}`)
+
+	w = patch(`{"rate_limit":{"requests_per_window":30,"window_seconds":100000}}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "invalid rate_limit")
+
+	w = patch(`{"rate_limit":{}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.True(t, store.updated.RateLimit.IsZero())
+	require.NotContains(t, w.Body.String(), "rate_limit")
+}
+
+// rotatingTokenStore records the token created and the token updated by a rotation.
+type rotatingTokenStore struct {
+	updatingTokenStore
+	created token.TokenData
+}
+
+func (s *rotatingTokenStore) CreateToken(ctx context.Context, td token.TokenData) error {
+	s.created = td
+	return nil
+}
+
+func (s *rotatingTokenStore) GetTokenByToken(ctx context.Context, tokenString string) (token.TokenData, error) {
+	if s.updated.ID != "" {
+		return s.updated, nil
+	}
+	return s.existing, nil
+}
+
+func TestHandleRotateToken(t *testing.T) {
+	maxRequests := 100
+	expiresAt := time.Now().Add(24 * time.Hour).UTC()
+	existing := token.TokenData{ID: "tok-1", Token: "sk-test123456789", ProjectID: "proj", IsActive: true, ExpiresAt: &expiresAt,
+		RequestCount: 7, MaxRequests: &maxRequests, Metadata: map[string]string{"user_id": "42"}, CreatedAt: time.Now()}
+	cfg := &config.Config{ListenAddr: ":0", RequestTimeout: time.Second, ManagementToken: "testtoken", EventBusBackend: "in-memory", TokenRotationGracePeriod: time.Hour}
+
+	rotate := func(store *rotatingTokenStore, method, body string) *httptest.ResponseRecorder {
+		srv, err := New(cfg, store, &activeProjectStore{})
+		require.NoError(t, err)
+		r := httptest.NewRequest(method, "/manage/tokens/tok-1/rotate", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+		w := httptest.NewRecorder()
+		srv.handleTokenByID(w, r)
+		return w
+	}
+
+	t.Run("default grace period", func(t *testing.T) {
+		store := &rotatingTokenStore{updatingTokenStore: updatingTokenStore{existing: existing}}
+		before := time.Now()
+		w := rotate(store, http.MethodPost, "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, store.created.ID, resp["id"])
+		assert.Equal(t, store.created.Token, resp["token"])
+		assert.Equal(t, "tok-1", resp["rotated_from_id"])
+
+		assert.Equal(t, "tok-1", store.created.RotatedFromID)
+		assert.Equal(t, "proj", store.created.ProjectID)
+		assert.Equal(t, &maxRequests, store.created.MaxRequests)
+		assert.Equal(t, 7, store.created.RequestCount)
+		assert.Equal(t, existing.Metadata, store.created.Metadata)
+		assert.Equal(t, &expiresAt, store.created.ExpiresAt)
+
+		assert.Equal(t, store.created.ID, store.updated.RotatedToID)
+		assert.True(t, store.updated.IsActive)
+		require.NotNil(t, store.updated.ExpiresAt)
+		assert.WithinDuration(t, before.Add(time.Hour), *store.updated.ExpiresAt, 5*time.Second)
+	})
+
+	t.Run("zero grace and new duration", func(t *testing.T) {
+		store := &rotatingTokenStore{updatingTokenStore: updatingTokenStore{existing: existing}}
+		w := rotate(store, http.MethodPost, `{"grace_period_minutes":0,"duration_minutes":60}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.False(t, store.updated.IsActive)
+		require.NotNil(t, store.created.ExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *store.created.ExpiresAt, 5*time.Second)
+	})
+
+	t.Run("zero grace rejects the cached old token", func(t *testing.T) {
+		oldToken, err := token.GenerateToken()
+		require.NoError(t, err)
+		cached := existing
+		cached.Token = oldToken
+		store := &rotatingTokenStore{updatingTokenStore: updatingTokenStore{existing: cached}}
+		apiCfg := *cfg
+		apiCfg.APIConfigPath = "/nonexistent/api_providers.yaml"
+		srv, err := New(&apiCfg, store, &activeProjectStore{})
+		require.NoError(t, err)
+		require.NoError(t, srv.initializeAPIRoutes())
+
+		_, err = srv.tokenValidator.ValidateToken(context.Background(), oldToken)
+		require.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodPost, "/manage/tokens/tok-1/rotate", strings.NewReader(`{"grace_period_minutes":0}`))
+		r.Header.Set("Authorization", "Bearer "+cfg.ManagementToken)
+		w := httptest.NewRecorder()
+		srv.handleTokenByID(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		_, err = srv.tokenValidator.ValidateToken(context.Background(), oldToken)
+		assert.ErrorIs(t, err, token.ErrTokenInactive)
+	})
+
+	t.Run("already rotated", func(t *testing.T) {
+		rotated := existing
+		rotated.RotatedToID = "tok-2"
+		store := &rotatingTokenStore{updatingTokenStore: updatingTokenStore{existing: rotated}}
+		w := rotate(store, http.MethodPost, "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Empty(t, store.created.ID)
+	})
+
+	t.Run("invalid grace period", func(t *testing.T) {
+		store := &rotatingTokenStore{updatingTokenStore: updatingTokenStore{existing: existing}}
+		w := rotate(store, http.MethodPost, `{"grace_period_minutes":-1}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		store := &rotatingTokenStore{updatingTokenStore: updatingTokenStore{existing: existing}}
+		w := rotate(store, http.MethodGet, "")
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
 func TestInitializeAPIRoutes_FallbackToDefaultWhenProviderMissing(t *testing.T) {
 	// Create a real config file where DefaultAPI is test_api
 	tmpFile, err := os.CreateTemp("", "api_config_*.yaml")
@@ -1509,6 +2166,35 @@ func TestServer_Shutdown_WithoutAggregator(t *testing.T) {
 	}
 }
 
+func TestServer_SharedRedisClient(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := &config.Config{
+		ListenAddr:                  ":0",
+		RequestTimeout:              time.Second,
+		ManagementToken:             "testtoken",
+		EventBusBackend:             "in-memory",
+		RedisAddr:                   mr.Addr(),
+		DistributedRateLimitEnabled: true,
+		TPMLimitPerToken:            1000,
+		ConcurrencyLimitPerToken:    2,
+	}
+	srv, err := New(cfg, &mockTokenStore{}, &mockProjectStore{})
+	require.NoError(t, err)
+	require.NoError(t, srv.initializeAPIRoutes())
+	require.NotNil(t, srv.tpm)
+	require.NotNil(t, srv.concurrency)
+
+	// The rate, TPM and concurrency limiters share one client
+	client := srv.redisClient
+	require.NotNil(t, client)
+	assert.Same(t, client, srv.redis())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, srv.Shutdown(ctx))
+	assert.ErrorIs(t, client.Ping(context.Background()).Err(), redis.ErrClosed, "shutdown closes the shared client")
+}
+
 func TestServer_Shutdown_WithAggregator(t *testing.T) {
 	cfg := &config.Config{
 		ListenAddr:           ":0",
@@ -1,6 +1,11 @@
 package server
 
-import "time"
+import (
+	"time"
+
+	"github.com/sofatutor/llm-proxy/internal/proxy"
+	"github.com/sofatutor/llm-proxy/internal/token"
+)
 
 // TokenListResponse matches the sanitized token response schema (shared for tests and production)
 type TokenListResponse struct {
@@ -14,15 +19,65 @@ type TokenListResponse struct {
 	CreatedAt     time.Time  `json:"created_at"`
 	LastUsedAt    *time.Time `json:"last_used_at"`
 	CacheHitCount int        `json:"cache_hit_count"`
+	// Scopes holds the token's endpoint, method and model restrictions, if any.
+	Scopes *token.Scopes `json:"scopes,omitempty"`
+	// Metadata holds the token's free-form key/value pairs.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// ClientRestrictions holds the token's network and origin allowlists, if any.
+	ClientRestrictions *token.ClientRestrictions `json:"client_restrictions,omitempty"`
+	// RateLimit holds the token's own request rate limit, if it has one.
+	RateLimit *token.RateLimit `json:"rate_limit,omitempty"`
+	// RotatedFromID is the ID of the token this token replaced by rotation, if any.
+	RotatedFromID string `json:"rotated_from_id,omitempty"`
+	// RotatedToID is the ID of the token that replaced this token by rotation, if any.
+	RotatedToID string `json:"rotated_to_id,omitempty"`
+}
+
+// tokenScopesResponse returns scopes for a token response, or nil when unrestricted.
+func tokenScopesResponse(scopes token.Scopes) *token.Scopes {
+	if scopes.IsEmpty() {
+		return nil
+	}
+	return &scopes
+}
+
+// clientRestrictionsResponse returns client restrictions for a token or project
+// response, or nil when unrestricted.
+func clientRestrictionsResponse(restrictions token.ClientRestrictions) *token.ClientRestrictions {
+	if restrictions.IsEmpty() {
+		return nil
+	}
+	return &restrictions
+}
+
+// rateLimitResponse returns the rate limit for a token or project response, or
+// nil when it has none.
+func rateLimitResponse(limit token.RateLimit) *token.RateLimit {
+	if limit.IsZero() {
+		return nil
+	}
+	return &limit
 }
 
 // ProjectResponse is the sanitized project response with obfuscated API key
 type ProjectResponse struct {
-	ID            string     `json:"id"`
-	Name          string     `json:"name"`
-	APIKey        string     `json:"api_key"` // Obfuscated for security
-	IsActive      bool       `json:"is_active"`
-	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	APIKey string `json:"api_key"` // Obfuscated for security
+	// APIKeys holds obfuscated per-provider keys, keyed by provider name.
+	APIKeys map[string]string `json:"api_keys,omitempty"`
+	// APIKeyPool holds the project's pooled upstream keys, obfuscated.
+	APIKeyPool []proxy.APIKeyPoolEntry `json:"api_key_pool,omitempty"`
+	// RequestPolicy holds the project's request policy rules.
+	RequestPolicy []proxy.RequestPolicyRule `json:"request_policy,omitempty"`
+	// AllowedModels holds the project's model allowlist patterns.
+	AllowedModels []string `json:"allowed_models,omitempty"`
+	// ClientRestrictions holds the project's network and origin allowlists, if any.
+	ClientRestrictions *token.ClientRestrictions `json:"client_restrictions,omitempty"`
+	// RateLimit holds the project's request rate limit, if it has one.
+	RateLimit     *token.RateLimit `json:"rate_limit,omitempty"`
+	IsActive      bool             `json:"is_active"`
+	DeactivatedAt *time.Time       `json:"deactivated_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
 }
@@ -120,6 +120,11 @@ func NewCachedValidator(validator TokenValidator, options ...CacheOptions) *Cach
 
 // ValidateToken validates a token using the cache when possible
 func (cv *CachedValidator) ValidateToken(ctx context.Context, tokenID string) (string, error) {
+	// Child tokens are verified by signature and never cached
+	if IsChildToken(tokenID) {
+		return cv.validator.ValidateToken(ctx, tokenID)
+	}
+
 	// Check cache first
 	projectID, found := cv.checkCache(tokenID)
 	if found {
@@ -145,8 +150,13 @@ func (cv *CachedValidator) ValidateToken(ctx context.Context, tokenID string) (s
 //
 // For cached *limited* tokens, we still want to avoid an extra DB read on every request. We do this
 // by using the cached token metadata (active/expiry/project) and performing a synchronous usage
-// increment (which is where max_requests is enforced).
+// increment (which is where max_requests is enforced). Rotated tokens, which also count against
+// their successor, go through the underlying validator.
 func (cv *CachedValidator) ValidateTokenWithTracking(ctx context.Context, tokenID string) (string, error) {
+	if IsChildToken(tokenID) {
+		return cv.validator.ValidateTokenWithTracking(ctx, tokenID)
+	}
+
 	cv.cacheMutex.RLock()
 	entry, found := cv.cache[tokenID]
 	cv.cacheMutex.RUnlock()
@@ -177,7 +187,7 @@ func (cv *CachedValidator) ValidateTokenWithTracking(ctx context.Context, tokenI
 						return entry.Data.ProjectID, nil
 					}
 				}
-			} else if sv, ok := cv.validator.(*StandardValidator); ok && sv != nil && sv.store != nil {
+			} else if sv, ok := cv.validator.(*StandardValidator); ok && sv != nil && sv.store != nil && entry.Data.RotatedToID == "" {
 				// Limited token: enforce max_requests via a synchronous increment, but avoid a DB read.
 				if err := sv.store.IncrementTokenUsage(ctx, tokenID); err != nil {
 					// If the token is no longer usable (inactive/expired/quota), invalidate the cache entry
@@ -187,6 +197,7 @@ func (cv *CachedValidator) ValidateTokenWithTracking(ctx context.Context, tokenI
 					}
 					return "", err
 				}
+				cv.recordCachedUsage(tokenID)
 				return entry.Data.ProjectID, nil
 			}
 		} else {
@@ -323,6 +334,17 @@ func (cv *CachedValidator) cacheToken(ctx context.Context, tokenID string) {
 	}
 }
 
+// recordCachedUsage counts a tracked request in the cached token data, so the
+// remaining requests reported from the cache stay current
+func (cv *CachedValidator) recordCachedUsage(tokenID string) {
+	cv.cacheMutex.Lock()
+	defer cv.cacheMutex.Unlock()
+	if entry, ok := cv.cache[tokenID]; ok {
+		entry.Data.RequestCount++
+		cv.cache[tokenID] = entry
+	}
+}
+
 // invalidateCache removes a token from the cache
 func (cv *CachedValidator) invalidateCache(tokenID string) {
 	cv.cacheMutex.Lock()
@@ -379,6 +401,12 @@ func (cv *CachedValidator) cleanup() {
 	}
 }
 
+// InvalidateToken removes a token from the cache so its next validation
+// reads the store again
+func (cv *CachedValidator) InvalidateToken(tokenString string) {
+	cv.invalidateCache(tokenString)
+}
+
 // ClearCache removes all entries from the cache
 func (cv *CachedValidator) ClearCache() {
 	cv.cacheMutex.Lock()
@@ -339,6 +339,29 @@ func TestCachedValidator_ValidateTokenWithTracking_LimitedToken_UsesCacheAndAvoi
 	}
 }
 
+func TestCachedValidator_ValidateTokenWithTracking_LimitedToken_CountsCachedUsage(t *testing.T) {
+	ctx := context.Background()
+	store := newCountingStore()
+	cv := NewCachedValidator(&StandardValidator{store: store}, CacheOptions{TTL: time.Minute, MaxSize: 10, EnableCleanup: false})
+
+	maxReq := 10
+	tok, _ := GenerateToken()
+	store.tokens[tok] = TokenData{Token: tok, ProjectID: "p1", IsActive: true, MaxRequests: &maxReq}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cv.ValidateTokenWithTracking(ctx, tok); err != nil {
+			t.Fatalf("ValidateTokenWithTracking() #%d error = %v", i+1, err)
+		}
+	}
+	td, err := cv.GetTokenData(ctx, tok)
+	if err != nil {
+		t.Fatalf("GetTokenData() error = %v", err)
+	}
+	if remaining, _ := td.RemainingRequests(); td.RequestCount != 3 || remaining != 7 {
+		t.Errorf("cached token data = %d requests, %d remaining, want 3 and 7", td.RequestCount, remaining)
+	}
+}
+
 func TestCachedValidator_ValidateTokenWithTracking_LimitedToken_InvalidatesOnRateLimit(t *testing.T) {
 	ctx := context.Background()
 	store := newCountingStore()
@@ -0,0 +1,279 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ChildTokenPrefix is the prefix of signed child tokens. The rest of the
+	// token is a compact JWT, so backends can mint child tokens with any JWT
+	// library that supports HS256 or EdDSA.
+	ChildTokenPrefix = "sk-child."
+
+	// MaxChildTokenTTL is the longest lifetime a child token may have
+	MaxChildTokenTTL = 24 * time.Hour
+
+	// MinChildTokenSecretLength is the minimum HMAC secret length in bytes
+	MinChildTokenSecretLength = 32
+
+	childAlgHMAC    = "HS256"
+	childAlgEd25519 = "EdDSA"
+)
+
+// Errors related to child tokens
+var (
+	ErrInvalidChildToken          = errors.New("invalid child token")
+	ErrChildTokensDisabled        = errors.New("child tokens are not enabled")
+	ErrChildTokenVerifyOnly       = errors.New("child token key cannot sign")
+	ErrChildTokenScopesExceeded   = errors.New("child token scopes exceed parent scopes")
+	ErrChildTokenMetadataConflict = errors.New("child token metadata conflicts with parent metadata")
+	ErrChildTokenParent           = errors.New("child tokens cannot mint child tokens")
+)
+
+// ChildClaims are the claims embedded in a signed child token
+type ChildClaims struct {
+	ID          string            `json:"jti"`
+	ParentID    string            `json:"parent_id"`
+	ProjectID   string            `json:"project_id"`
+	IssuedAt    int64             `json:"iat"`
+	ExpiresAt   int64             `json:"exp"`
+	MaxRequests int               `json:"max_requests,omitempty"`
+	Scopes      Scopes            `json:"scopes,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Expiry returns the expiration time of the child token
+func (c ChildClaims) Expiry() time.Time {
+	return time.Unix(c.ExpiresAt, 0)
+}
+
+// childHeader is the JWT header of a child token
+type childHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// ChildTokenCodec signs and verifies child tokens with a single key, either
+// an HMAC secret or an Ed25519 key pair. A codec holding only an Ed25519
+// public key can verify but not sign.
+type ChildTokenCodec struct {
+	alg     string
+	secret  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// NewHMACChildTokenCodec creates a codec that signs with HMAC-SHA256
+func NewHMACChildTokenCodec(secret []byte) (*ChildTokenCodec, error) {
+	if len(secret) < MinChildTokenSecretLength {
+		return nil, fmt.Errorf("child token secret must be at least %d bytes", MinChildTokenSecretLength)
+	}
+	return &ChildTokenCodec{alg: childAlgHMAC, secret: secret}, nil
+}
+
+// NewEd25519ChildTokenCodec creates a codec that signs with Ed25519. private
+// may be nil for verify-only deployments where child tokens are minted by a
+// backend holding the private key.
+func NewEd25519ChildTokenCodec(public ed25519.PublicKey, private ed25519.PrivateKey) (*ChildTokenCodec, error) {
+	if public == nil && private != nil {
+		public = private.Public().(ed25519.PublicKey)
+	}
+	if len(public) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519 public key must be %d bytes", ed25519.PublicKeySize)
+	}
+	if private != nil && !public.Equal(private.Public()) {
+		return nil, errors.New("ed25519 public key does not match the private key")
+	}
+	return &ChildTokenCodec{alg: childAlgEd25519, private: private, public: public}, nil
+}
+
+// ParseEd25519PublicKey decodes a base64 Ed25519 public key
+func ParseEd25519PublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("ed25519 key is not valid base64: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("ed25519 public key must be %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// ParseEd25519PrivateKey decodes a base64 Ed25519 private key given as a
+// 32-byte seed or a 64-byte key.
+func ParseEd25519PrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("ed25519 key is not valid base64: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("ed25519 private key must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+	}
+}
+
+// CanSign returns true if the codec holds a signing key
+func (c *ChildTokenCodec) CanSign() bool {
+	return c.alg == childAlgHMAC || c.private != nil
+}
+
+// Sign creates a child token from claims. A missing ID or issue time is
+// filled in; the expiry must be in the future and within MaxChildTokenTTL.
+func (c *ChildTokenCodec) Sign(claims ChildClaims) (string, error) {
+	if !c.CanSign() {
+		return "", ErrChildTokenVerifyOnly
+	}
+	if claims.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return "", fmt.Errorf("failed to generate child token ID: %w", err)
+		}
+		claims.ID = id.String()
+	}
+	if claims.IssuedAt == 0 {
+		claims.IssuedAt = time.Now().Unix()
+	}
+	if err := claims.validate(time.Now()); err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(childHeader{Alg: c.alg, Typ: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode child token header: %w", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode child token claims: %w", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return ChildTokenPrefix + signingInput + "." + base64.RawURLEncoding.EncodeToString(c.sign([]byte(signingInput))), nil
+}
+
+// Verify checks the signature and lifetime of a child token and returns its
+// claims. It returns ErrTokenExpired for expired tokens and
+// ErrInvalidChildToken for anything else that is wrong with the token.
+func (c *ChildTokenCodec) Verify(tokenString string) (ChildClaims, error) {
+	if !IsChildToken(tokenString) {
+		return ChildClaims{}, ErrInvalidChildToken
+	}
+	parts := strings.Split(strings.TrimPrefix(tokenString, ChildTokenPrefix), ".")
+	if len(parts) != 3 {
+		return ChildClaims{}, ErrInvalidChildToken
+	}
+
+	var header childHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != c.alg {
+		return ChildClaims{}, ErrInvalidChildToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !c.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return ChildClaims{}, ErrInvalidChildToken
+	}
+
+	var claims ChildClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return ChildClaims{}, ErrInvalidChildToken
+	}
+	if claims.ExpiresAt != 0 && !time.Now().Before(claims.Expiry()) {
+		return ChildClaims{}, ErrTokenExpired
+	}
+	if err := claims.validate(time.Now()); err != nil {
+		return ChildClaims{}, fmt.Errorf("%w: %v", ErrInvalidChildToken, err)
+	}
+	return claims, nil
+}
+
+// IsChildToken returns true if the token string looks like a child token
+func IsChildToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, ChildTokenPrefix)
+}
+
+// TokenData returns the token data of a child token derived from parent.
+// Scope dimensions the child leaves open are inherited from the parent;
+// client restrictions and the rate limit are always the parent's. The child's
+// metadata is merged into the parent's, with the parent's values winning.
+func (c ChildClaims) TokenData(tokenString string, parent TokenData) TokenData {
+	expiresAt := c.Expiry()
+	createdAt := time.Unix(c.IssuedAt, 0)
+	td := TokenData{
+		ID:                 c.ID,
+		Token:              tokenString,
+		ProjectID:          c.ProjectID,
+		ExpiresAt:          &expiresAt,
+		IsActive:           true,
+		CreatedAt:          createdAt,
+		Scopes:             c.Scopes.Inherit(parent.Scopes),
+		Metadata:           mergeMetadata(parent.Metadata, c.Metadata),
+		ClientRestrictions: parent.ClientRestrictions,
+		RateLimit:          parent.RateLimit,
+		ParentID:           c.ParentID,
+	}
+	if c.MaxRequests > 0 {
+		maxRequests := c.MaxRequests
+		td.MaxRequests = &maxRequests
+	}
+	return td
+}
+
+// validate checks the claims required of every child token
+func (c ChildClaims) validate(now time.Time) error {
+	switch {
+	case c.ID == "":
+		return errors.New("child token ID is required")
+	case c.ParentID == "":
+		return errors.New("parent token ID is required")
+	case c.ProjectID == "":
+		return errors.New("project ID is required")
+	case c.ExpiresAt == 0:
+		return errors.New("child token expiry is required")
+	case !now.Before(c.Expiry()):
+		return ErrTokenExpired
+	case c.Expiry().Sub(now) > MaxChildTokenTTL:
+		return fmt.Errorf("child token lifetime exceeds %s", MaxChildTokenTTL)
+	case c.MaxRequests < 0:
+		return errors.New("max requests cannot be negative")
+	}
+	if err := c.Scopes.Validate(); err != nil {
+		return err
+	}
+	return ValidateMetadata(c.Metadata)
+}
+
+func (c *ChildTokenCodec) sign(input []byte) []byte {
+	if c.alg == childAlgHMAC {
+		mac := hmac.New(sha256.New, c.secret)
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+	return ed25519.Sign(c.private, input)
+}
+
+func (c *ChildTokenCodec) verify(input, signature []byte) bool {
+	if c.alg == childAlgHMAC {
+		return hmac.Equal(c.sign(input), signature)
+	}
+	return ed25519.Verify(c.public, input, signature)
+}
+
+// decodeSegment decodes a base64url JSON segment of a child token
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,404 @@
+package token
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testChildSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testChildClaims() ChildClaims {
+	return ChildClaims{
+		ParentID:  "parent-id",
+		ProjectID: "project-id",
+		ExpiresAt: time.Now().Add(10 * time.Minute).Unix(),
+	}
+}
+
+func TestChildTokenCodec_HMAC(t *testing.T) {
+	codec, err := NewHMACChildTokenCodec(testChildSecret)
+	if err != nil {
+		t.Fatalf("NewHMACChildTokenCodec() error = %v", err)
+	}
+	claims := testChildClaims()
+	claims.MaxRequests = 5
+	claims.Scopes = Scopes{Models: []string{"gpt-4o-mini"}}
+
+	tok, err := codec.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if !IsChildToken(tok) {
+		t.Fatalf("Sign() = %q, want prefix %q", tok, ChildTokenPrefix)
+	}
+	got, err := codec.Verify(tok)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got.ID == "" || got.IssuedAt == 0 {
+		t.Errorf("Sign() should fill in ID and issue time, got %+v", got)
+	}
+	if got.ParentID != "parent-id" || got.MaxRequests != 5 || !reflect.DeepEqual(got.Scopes, claims.Scopes) {
+		t.Errorf("Verify() claims = %+v", got)
+	}
+
+	other, _ := NewHMACChildTokenCodec([]byte(strings.Repeat("x", MinChildTokenSecretLength)))
+	if _, err := other.Verify(tok); !errors.Is(err, ErrInvalidChildToken) {
+		t.Errorf("Verify() with another secret error = %v, want ErrInvalidChildToken", err)
+	}
+
+	if _, err := NewHMACChildTokenCodec([]byte("short")); err == nil {
+		t.Error("NewHMACChildTokenCodec() should reject short secrets")
+	}
+}
+
+func TestChildTokenCodec_Ed25519(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	signer, err := NewEd25519ChildTokenCodec(nil, private)
+	if err != nil {
+		t.Fatalf("NewEd25519ChildTokenCodec() error = %v", err)
+	}
+	verifier, err := NewEd25519ChildTokenCodec(public, nil)
+	if err != nil {
+		t.Fatalf("NewEd25519ChildTokenCodec() error = %v", err)
+	}
+	if verifier.CanSign() {
+		t.Error("a public-key codec should not sign")
+	}
+	if _, err := verifier.Sign(testChildClaims()); !errors.Is(err, ErrChildTokenVerifyOnly) {
+		t.Errorf("Sign() error = %v, want ErrChildTokenVerifyOnly", err)
+	}
+
+	tok, err := signer.Sign(testChildClaims())
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if _, err := verifier.Verify(tok); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// An HMAC codec must not accept EdDSA tokens and vice versa
+	hmacCodec, _ := NewHMACChildTokenCodec(testChildSecret)
+	if _, err := hmacCodec.Verify(tok); !errors.Is(err, ErrInvalidChildToken) {
+		t.Errorf("HMAC Verify() of EdDSA token error = %v", err)
+	}
+
+	otherPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := NewEd25519ChildTokenCodec(otherPublic, private); err == nil {
+		t.Error("NewEd25519ChildTokenCodec() should reject mismatched keys")
+	}
+}
+
+func TestChildTokenCodec_Verify_Rejects(t *testing.T) {
+	codec, _ := NewHMACChildTokenCodec(testChildSecret)
+	tok, _ := codec.Sign(testChildClaims())
+	parts := strings.Split(strings.TrimPrefix(tok, ChildTokenPrefix), ".")
+
+	// Swap in different claims while keeping the signature
+	forged := testChildClaims()
+	forged.ProjectID = "other-project"
+	forgedTok, _ := codec.Sign(forged)
+	forgedParts := strings.Split(strings.TrimPrefix(forgedTok, ChildTokenPrefix), ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not a child token", "sk-abc"},
+		{"missing segment", ChildTokenPrefix + parts[0] + "." + parts[1]},
+		{"tampered claims", ChildTokenPrefix + parts[0] + "." + forgedParts[1] + "." + parts[2]},
+		{"bad signature encoding", ChildTokenPrefix + parts[0] + "." + parts[1] + ".!!"},
+		{"unsigned", ChildTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + "."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codec.Verify(tt.token); !errors.Is(err, ErrInvalidChildToken) {
+				t.Errorf("Verify() error = %v, want ErrInvalidChildToken", err)
+			}
+		})
+	}
+}
+
+func TestChildTokenCodec_Sign_InvalidClaims(t *testing.T) {
+	codec, _ := NewHMACChildTokenCodec(testChildSecret)
+	tests := []struct {
+		name   string
+		modify func(*ChildClaims)
+	}{
+		{"no parent", func(c *ChildClaims) { c.ParentID = "" }},
+		{"no project", func(c *ChildClaims) { c.ProjectID = "" }},
+		{"no expiry", func(c *ChildClaims) { c.ExpiresAt = 0 }},
+		{"expired", func(c *ChildClaims) { c.ExpiresAt = time.Now().Add(-time.Minute).Unix() }},
+		{"too long", func(c *ChildClaims) { c.ExpiresAt = time.Now().Add(MaxChildTokenTTL + time.Hour).Unix() }},
+		{"negative max requests", func(c *ChildClaims) { c.MaxRequests = -1 }},
+		{"bad scopes", func(c *ChildClaims) { c.Scopes = Scopes{Endpoints: []string{"v1"}} }},
+		{"bad metadata", func(c *ChildClaims) { c.Metadata = map[string]string{"user id": "1"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := testChildClaims()
+			tt.modify(&claims)
+			if _, err := codec.Sign(claims); err == nil {
+				t.Error("Sign() should fail")
+			}
+		})
+	}
+}
+
+func TestParseEd25519Keys(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+
+	fromSeed, err := ParseEd25519PrivateKey(base64.StdEncoding.EncodeToString(private.Seed()))
+	if err != nil || !fromSeed.Equal(private) {
+		t.Errorf("ParseEd25519PrivateKey(seed) = %v, %v", fromSeed, err)
+	}
+	full, err := ParseEd25519PrivateKey(base64.StdEncoding.EncodeToString(private))
+	if err != nil || !full.Equal(private) {
+		t.Errorf("ParseEd25519PrivateKey(key) = %v, %v", full, err)
+	}
+	pub, err := ParseEd25519PublicKey(base64.StdEncoding.EncodeToString(public))
+	if err != nil || !pub.Equal(public) {
+		t.Errorf("ParseEd25519PublicKey() = %v, %v", pub, err)
+	}
+	if _, err := ParseEd25519PublicKey(base64.StdEncoding.EncodeToString(private)); err == nil {
+		t.Error("ParseEd25519PublicKey() should reject a private key")
+	}
+	if _, err := ParseEd25519PrivateKey("not base64!"); err == nil {
+		t.Error("ParseEd25519PrivateKey() should reject invalid base64")
+	}
+}
+
+func TestScopes_WithinAndInherit(t *testing.T) {
+	parent := Scopes{Endpoints: []string{"/v1/"}, Models: []string{"gpt-4o*"}}
+	tests := []struct {
+		name  string
+		child Scopes
+		want  bool
+	}{
+		{"empty", Scopes{}, true},
+		{"narrower endpoint", Scopes{Endpoints: []string{"/v1/chat/completions"}}, true},
+		{"other endpoint", Scopes{Endpoints: []string{"/admin"}}, false},
+		{"endpoint sharing a prefix", Scopes{Endpoints: []string{"/v1x/chat"}}, false},
+		{"same model pattern", Scopes{Models: []string{"gpt-4o*"}}, true},
+		{"narrower model", Scopes{Models: []string{"gpt-4o-mini"}}, true},
+		{"wider model", Scopes{Models: []string{"gpt-*"}}, false},
+		{"narrower model glob", Scopes{Models: []string{"gpt-4o-*"}}, false},
+		{"methods unrestricted by parent", Scopes{Methods: []string{"POST"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.child.Within(parent); got != tt.want {
+				t.Errorf("Within() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Globs are not widened by a pattern the parent glob matches literally
+	if (Scopes{Models: []string{"gpt-4*"}}).Within(Scopes{Models: []string{"gpt-4?"}}) {
+		t.Error("Within() should reject a child glob matched only literally by the parent")
+	}
+	if !(Scopes{Models: []string{"gpt-4o"}}).Within(Scopes{Models: []string{"gpt-4?"}}) {
+		t.Error("Within() should accept a literal model the parent glob matches")
+	}
+
+	got := Scopes{Models: []string{"gpt-4o-mini"}}.Inherit(parent)
+	want := Scopes{Endpoints: []string{"/v1/"}, Models: []string{"gpt-4o-mini"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Inherit() = %#v, want %#v", got, want)
+	}
+}
+
+// idCountingTokenStore counts parent lookups by ID
+type idCountingTokenStore struct {
+	*MockTokenStore
+	idLookups int
+}
+
+func (s *idCountingTokenStore) GetTokenByID(ctx context.Context, id string) (TokenData, error) {
+	s.idLookups++
+	return s.MockTokenStore.GetTokenByID(ctx, id)
+}
+
+func TestStandardValidator_ChildTokens(t *testing.T) {
+	ctx := context.Background()
+	store := &idCountingTokenStore{MockTokenStore: NewMockTokenStore()}
+	parent := TokenData{
+		ID:                 "parent-id",
+		Token:              "sk-parent",
+		ProjectID:          "project-id",
+		IsActive:           true,
+		Scopes:             Scopes{Endpoints: []string{"/v1/chat/completions"}},
+		Metadata:           map[string]string{"user_id": "1"},
+		ClientRestrictions: ClientRestrictions{CIDRs: []string{"10.0.0.0/8"}},
+	}
+	store.AddToken(parent.ID, parent)
+
+	codec, _ := NewHMACChildTokenCodec(testChildSecret)
+	validator := NewValidator(store)
+	claims := testChildClaims()
+	claims.MaxRequests = 2
+	claims.Metadata = map[string]string{"session_id": "s-1"}
+	child, _ := codec.Sign(claims)
+
+	if _, err := validator.ValidateToken(ctx, child); !errors.Is(err, ErrChildTokensDisabled) {
+		t.Fatalf("ValidateToken() without child tokens error = %v", err)
+	}
+	validator.EnableChildTokens(codec, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		projectID, err := validator.ValidateTokenWithTracking(ctx, child)
+		if err != nil || projectID != "project-id" {
+			t.Fatalf("ValidateTokenWithTracking() #%d = %q, %v", i+1, projectID, err)
+		}
+	}
+	if _, err := validator.ValidateTokenWithTracking(ctx, child); !errors.Is(err, ErrTokenRateLimit) {
+		t.Errorf("ValidateTokenWithTracking() over max requests error = %v, want ErrTokenRateLimit", err)
+	}
+	if store.idLookups != 1 {
+		t.Errorf("parent lookups = %d, want 1 (cached)", store.idLookups)
+	}
+
+	td, err := validator.GetTokenData(ctx, child)
+	if err != nil {
+		t.Fatalf("GetTokenData() error = %v", err)
+	}
+	if td.ParentID != "parent-id" || td.RequestCount != 2 {
+		t.Errorf("GetTokenData() = %+v", td)
+	}
+	if want := map[string]string{"user_id": "1", "session_id": "s-1"}; !reflect.DeepEqual(td.Metadata, want) {
+		t.Errorf("child metadata = %v, want it merged into the parent's %v", td.Metadata, want)
+	}
+	if !reflect.DeepEqual(td.Scopes, parent.Scopes) || !reflect.DeepEqual(td.ClientRestrictions, parent.ClientRestrictions) {
+		t.Errorf("child should inherit parent scopes and client restrictions, got %+v", td)
+	}
+
+	// Revoking the parent invalidates its children once the cache expires
+	parent.IsActive = false
+	store.AddToken(parent.ID, parent)
+	validator.EnableChildTokens(codec, time.Minute)
+	if _, err := validator.ValidateToken(ctx, child); !errors.Is(err, ErrTokenInactive) {
+		t.Errorf("ValidateToken() with revoked parent error = %v, want ErrTokenInactive", err)
+	}
+}
+
+func TestStandardValidator_ChildTokenUsageIsBounded(t *testing.T) {
+	ctx := context.Background()
+	store := NewMockTokenStore()
+	store.AddToken("parent-id", TokenData{ID: "parent-id", ProjectID: "project-id", IsActive: true})
+	codec, _ := NewHMACChildTokenCodec(testChildSecret)
+	validator := NewValidator(store)
+	validator.EnableChildTokens(codec, time.Minute)
+	validator.children.Load().maxUsage = 2
+
+	sign := func(expiresIn time.Duration) string {
+		claims := testChildClaims()
+		claims.MaxRequests = 1
+		claims.ExpiresAt = time.Now().Add(expiresIn).Unix()
+		child, err := codec.Sign(claims)
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+		return child
+	}
+	first, second, third := sign(time.Minute), sign(2*time.Minute), sign(3*time.Minute)
+
+	for _, child := range []string{first, second, third} {
+		if _, err := validator.ValidateTokenWithTracking(ctx, child); err != nil {
+			t.Fatalf("ValidateTokenWithTracking() error = %v", err)
+		}
+	}
+	c := validator.children.Load()
+	if len(c.usage) != 2 || c.expiry.Len() != 2 {
+		t.Fatalf("tracked child tokens = %d (heap %d), want 2", len(c.usage), c.expiry.Len())
+	}
+	// The child expiring first was dropped to make room; the others keep their counts
+	for _, child := range []string{second, third} {
+		if _, err := validator.ValidateTokenWithTracking(ctx, child); !errors.Is(err, ErrTokenRateLimit) {
+			t.Errorf("ValidateTokenWithTracking() error = %v, want ErrTokenRateLimit", err)
+		}
+	}
+	if _, err := validator.ValidateTokenWithTracking(ctx, first); err != nil {
+		t.Errorf("ValidateTokenWithTracking() for the dropped child error = %v", err)
+	}
+}
+
+func TestStandardValidator_ChildTokens_ParentChecks(t *testing.T) {
+	ctx := context.Background()
+	store := NewMockTokenStore()
+	past := time.Now().Add(-time.Hour)
+	maxRequests := 1
+	store.AddToken("expired", TokenData{ID: "expired", ProjectID: "project-id", IsActive: true, ExpiresAt: &past})
+	store.AddToken("exhausted", TokenData{ID: "exhausted", ProjectID: "project-id", IsActive: true, MaxRequests: &maxRequests, RequestCount: 1})
+	store.AddToken("other-project", TokenData{ID: "other-project", ProjectID: "other", IsActive: true})
+	store.AddToken("scoped", TokenData{ID: "scoped", ProjectID: "project-id", IsActive: true, Scopes: Scopes{Models: []string{"gpt-4o-mini"}}})
+
+	codec, _ := NewHMACChildTokenCodec(testChildSecret)
+	validator := NewValidator(store)
+	validator.EnableChildTokens(codec, 0)
+
+	tests := []struct {
+		parentID string
+		scopes   Scopes
+		want     error
+	}{
+		{"missing", Scopes{}, ErrTokenNotFound},
+		{"expired", Scopes{}, ErrTokenExpired},
+		{"exhausted", Scopes{}, ErrTokenRateLimit},
+		{"other-project", Scopes{}, ErrInvalidChildToken},
+		{"scoped", Scopes{Models: []string{"gpt-4o"}}, ErrInvalidChildToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.parentID, func(t *testing.T) {
+			claims := testChildClaims()
+			claims.ParentID = tt.parentID
+			claims.Scopes = tt.scopes
+			child, err := codec.Sign(claims)
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if _, err := validator.ValidateToken(ctx, child); !errors.Is(err, tt.want) {
+				t.Errorf("ValidateToken() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedValidator_ChildTokensBypassCache(t *testing.T) {
+	ctx := context.Background()
+	store := NewMockTokenStore()
+	store.AddToken("parent-id", TokenData{ID: "parent-id", ProjectID: "project-id", IsActive: true})
+	codec, _ := NewHMACChildTokenCodec(testChildSecret)
+	validator := NewValidator(store)
+	validator.EnableChildTokens(codec, time.Minute)
+	cv := NewCachedValidator(validator, CacheOptions{TTL: time.Minute, MaxSize: 10})
+
+	child, _ := codec.Sign(testChildClaims())
+	if _, err := cv.ValidateTokenWithTracking(ctx, child); err != nil {
+		t.Fatalf("ValidateTokenWithTracking() error = %v", err)
+	}
+	if _, err := cv.ValidateToken(ctx, child); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if hits, misses, _, size := cv.GetCacheStats(); hits+misses+size != 0 {
+		t.Errorf("child tokens should not touch the cache, got hits=%d misses=%d size=%d", hits, misses, size)
+	}
+}
+
+func TestObfuscateToken_ChildToken(t *testing.T) {
+	codec, _ := NewHMACChildTokenCodec(testChildSecret)
+	child, _ := codec.Sign(testChildClaims())
+	got := ObfuscateToken(child)
+	if !strings.HasPrefix(got, ChildTokenPrefix+"****") || len(got) != len(ChildTokenPrefix)+8 {
+		t.Errorf("ObfuscateToken() = %q", got)
+	}
+}
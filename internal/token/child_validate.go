@@ -0,0 +1,194 @@
+package token
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultChildTokenParentTTL is how long parent token data is cached when
+// validating child tokens
+const DefaultChildTokenParentTTL = 30 * time.Second
+
+// childPruneThreshold is the number of cached parents above which expired
+// entries are pruned
+const childPruneThreshold = 1024
+
+// maxTrackedChildTokens bounds the limited child tokens whose requests are
+// counted. Once it is reached, the count of the child token expiring first is
+// dropped, so that token starts counting again.
+const maxTrackedChildTokens = 100_000
+
+// childTokens holds the state StandardValidator needs to validate child tokens
+type childTokens struct {
+	codec     *ChildTokenCodec
+	parentTTL time.Duration
+
+	mu       sync.Mutex
+	parents  map[string]parentEntry
+	usage    map[string]*childUsage
+	expiry   childUsageHeap // usage ordered by expiry
+	maxUsage int
+}
+
+// parentEntry is a cached parent token
+type parentEntry struct {
+	data       TokenData
+	validUntil time.Time
+}
+
+// childUsage counts the requests of a limited child token
+type childUsage struct {
+	id        string
+	count     int
+	expiresAt time.Time
+}
+
+// childUsageHeap is a min-heap of child token usage by expiry
+type childUsageHeap []*childUsage
+
+func (h childUsageHeap) Len() int           { return len(h) }
+func (h childUsageHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h childUsageHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *childUsageHeap) Push(x interface{}) {
+	*h = append(*h, x.(*childUsage))
+}
+
+func (h *childUsageHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[0 : n-1]
+	return item
+}
+
+// EnableChildTokens makes the validator accept child tokens signed by codec.
+// Child tokens are validated without a database lookup of their own; their
+// parent is looked up by ID and cached for parentTTL (DefaultChildTokenParentTTL
+// when zero), so deactivating or revoking a parent invalidates its children
+// within parentTTL.
+func (v *StandardValidator) EnableChildTokens(codec *ChildTokenCodec, parentTTL time.Duration) {
+	if parentTTL <= 0 {
+		parentTTL = DefaultChildTokenParentTTL
+	}
+	v.children.Store(&childTokens{
+		codec:     codec,
+		parentTTL: parentTTL,
+		parents:   make(map[string]parentEntry),
+		usage:     make(map[string]*childUsage),
+		maxUsage:  maxTrackedChildTokens,
+	})
+}
+
+// ChildTokenCodec returns the codec configured with EnableChildTokens, or nil
+func (v *StandardValidator) ChildTokenCodec() *ChildTokenCodec {
+	if c := v.children.Load(); c != nil {
+		return c.codec
+	}
+	return nil
+}
+
+// validateChildToken verifies a child token and checks its parent. The
+// returned token data carries the child's current request count.
+func (v *StandardValidator) validateChildToken(ctx context.Context, tokenString string) (TokenData, error) {
+	c := v.children.Load()
+	if c == nil {
+		return TokenData{}, ErrChildTokensDisabled
+	}
+	claims, err := c.codec.Verify(tokenString)
+	if err != nil {
+		return TokenData{}, err
+	}
+
+	parent, err := v.parentTokenData(ctx, c, claims.ParentID)
+	if err != nil {
+		return TokenData{}, err
+	}
+	switch {
+	case !parent.IsActive:
+		return TokenData{}, fmt.Errorf("parent %w", ErrTokenInactive)
+	case IsExpired(parent.ExpiresAt):
+		return TokenData{}, fmt.Errorf("parent %w", ErrTokenExpired)
+	case parent.IsRateLimited():
+		return TokenData{}, fmt.Errorf("parent %w", ErrTokenRateLimit)
+	case parent.ProjectID != claims.ProjectID:
+		return TokenData{}, fmt.Errorf("%w: project does not match the parent token", ErrInvalidChildToken)
+	case !claims.Scopes.Within(parent.Scopes):
+		return TokenData{}, fmt.Errorf("%w: %v", ErrInvalidChildToken, ErrChildTokenScopesExceeded)
+	}
+
+	td := claims.TokenData(tokenString, parent)
+	c.mu.Lock()
+	if u, ok := c.usage[claims.ID]; ok {
+		td.RequestCount = u.count
+	}
+	c.mu.Unlock()
+	return td, nil
+}
+
+// parentTokenData returns the parent of a child token, from the cache when possible
+func (v *StandardValidator) parentTokenData(ctx context.Context, c *childTokens, parentID string) (TokenData, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.parents[parentID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.validUntil) {
+		return entry.data, nil
+	}
+
+	parent, err := v.store.GetTokenByID(ctx, parentID)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return TokenData{}, fmt.Errorf("parent %w", ErrTokenNotFound)
+		}
+		return TokenData{}, fmt.Errorf("failed to retrieve parent token: %w", err)
+	}
+
+	c.mu.Lock()
+	c.parents[parentID] = parentEntry{data: parent, validUntil: now.Add(c.parentTTL)}
+	if len(c.parents) > childPruneThreshold {
+		for id, e := range c.parents {
+			if !now.Before(e.validUntil) {
+				delete(c.parents, id)
+			}
+		}
+	}
+	c.mu.Unlock()
+	return parent, nil
+}
+
+// trackChildUsage counts a request of a limited child token in memory and
+// returns ErrTokenRateLimit once its max requests are used up. Counts are
+// per proxy instance and dropped when the child token expires.
+func (v *StandardValidator) trackChildUsage(td TokenData) error {
+	if td.MaxRequests == nil || *td.MaxRequests <= 0 {
+		return nil
+	}
+	c := v.children.Load()
+	if c == nil {
+		return ErrChildTokensDisabled
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	u, ok := c.usage[td.ID]
+	if !ok {
+		// Drop the counts of expired child tokens, then of those expiring first
+		now := time.Now()
+		for c.expiry.Len() > 0 && (len(c.usage) >= c.maxUsage || !now.Before(c.expiry[0].expiresAt)) {
+			delete(c.usage, heap.Pop(&c.expiry).(*childUsage).id)
+		}
+		u = &childUsage{id: td.ID, expiresAt: *td.ExpiresAt}
+		c.usage[td.ID] = u
+		heap.Push(&c.expiry, u)
+	}
+	if u.count >= *td.MaxRequests {
+		return ErrTokenRateLimit
+	}
+	u.count++
+	return nil
+}
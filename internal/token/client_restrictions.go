@@ -0,0 +1,137 @@
+package token
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ClientRestrictions limit where a token or project may be used from. Each
+// empty field leaves that dimension unrestricted.
+type ClientRestrictions struct {
+	// CIDRs lists allowed client networks (e.g. "203.0.113.0/24"); bare IPs are allowed
+	CIDRs []string `json:"cidrs,omitempty"`
+	// Origins lists allowed browser origins as glob patterns (e.g. "https://*.example.com")
+	Origins []string `json:"origins,omitempty"`
+}
+
+// IsEmpty returns true if the restrictions do not restrict anything
+func (c ClientRestrictions) IsEmpty() bool {
+	return len(c.CIDRs) == 0 && len(c.Origins) == 0
+}
+
+// Normalize returns a copy with trimmed entries and lower-cased origins
+// without trailing slashes
+func (c ClientRestrictions) Normalize() ClientRestrictions {
+	var out ClientRestrictions
+	for _, v := range c.CIDRs {
+		if v = strings.TrimSpace(v); v != "" {
+			out.CIDRs = append(out.CIDRs, v)
+		}
+	}
+	for _, v := range c.Origins {
+		if v = strings.TrimRight(strings.ToLower(strings.TrimSpace(v)), "/"); v != "" {
+			out.Origins = append(out.Origins, v)
+		}
+	}
+	return out
+}
+
+// Validate checks that CIDRs are valid networks or IPs and origins are valid
+// "scheme://host[:port]" glob patterns.
+func (c ClientRestrictions) Validate() error {
+	for _, cidr := range c.CIDRs {
+		if parseNetwork(cidr) == nil {
+			return fmt.Errorf("'%s' is not a valid CIDR or IP address", cidr)
+		}
+	}
+	for _, origin := range c.Origins {
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || scheme == "" || host == "" || strings.Contains(host, "/") {
+			return fmt.Errorf("origin '%s' must have the form scheme://host[:port]", origin)
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return fmt.Errorf("origin pattern '%s' is invalid: %w", origin, err)
+		}
+	}
+	return nil
+}
+
+// AllowsIP reports whether ip lies in one of the allowed networks
+func (c ClientRestrictions) AllowsIP(ip string) bool {
+	if len(c.CIDRs) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(strings.Trim(ip, "[]"))
+	if parsed == nil {
+		return false
+	}
+	for _, cidr := range c.CIDRs {
+		if network := parseNetwork(cidr); network != nil && network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin reports whether the request origin matches an origin pattern.
+// The Origin header is used when present, otherwise the origin of the
+// Referer. Requests carrying neither are not allowed.
+func (c ClientRestrictions) AllowsOrigin(origin, referer string) bool {
+	if len(c.Origins) == 0 {
+		return true
+	}
+	if origin == "" || origin == "null" {
+		origin = refererOrigin(referer)
+	}
+	origin = strings.TrimRight(strings.ToLower(origin), "/")
+	if origin == "" {
+		return false
+	}
+	for _, pattern := range c.Origins {
+		if ok, _ := path.Match(pattern, origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetwork parses a CIDR or a bare IP address (as a single-host network).
+func parseNetwork(value string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil
+	}
+	bits := 8 * net.IPv6len
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
+// refererOrigin returns the "scheme://host" part of a Referer header.
+func refererOrigin(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// ParseNetworks parses a list of CIDRs or bare IP addresses.
+func ParseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		network := parseNetwork(strings.TrimSpace(value))
+		if network == nil {
+			return nil, fmt.Errorf("'%s' is not a valid CIDR or IP address", value)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,95 @@
+package token
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClientRestrictions_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		restrictions ClientRestrictions
+		wantErr      bool
+	}{
+		{"empty", ClientRestrictions{}, false},
+		{"valid", ClientRestrictions{CIDRs: []string{"203.0.113.0/24", "2001:db8::/32", "198.51.100.7"}, Origins: []string{"https://app.example.com", "https://*.example.com:8443"}}, false},
+		{"bad cidr", ClientRestrictions{CIDRs: []string{"203.0.113.0/33"}}, true},
+		{"hostname", ClientRestrictions{CIDRs: []string{"example.com"}}, true},
+		{"origin without scheme", ClientRestrictions{Origins: []string{"app.example.com"}}, true},
+		{"origin with path", ClientRestrictions{Origins: []string{"https://app.example.com/chat"}}, true},
+		{"bad glob", ClientRestrictions{Origins: []string{"https://[app.example.com"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.restrictions.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientRestrictions_Normalize(t *testing.T) {
+	got := ClientRestrictions{CIDRs: []string{" 10.0.0.0/8 ", ""}, Origins: []string{"HTTPS://App.Example.com/"}}.Normalize()
+	want := ClientRestrictions{CIDRs: []string{"10.0.0.0/8"}, Origins: []string{"https://app.example.com"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize() = %#v, want %#v", got, want)
+	}
+}
+
+func TestClientRestrictions_AllowsIP(t *testing.T) {
+	r := ClientRestrictions{CIDRs: []string{"203.0.113.0/24", "198.51.100.7", "2001:db8::/32"}}
+	for ip, want := range map[string]bool{
+		"203.0.113.42":  true,
+		"198.51.100.7":  true,
+		"198.51.100.8":  false,
+		"2001:db8::1":   true,
+		"[2001:db8::1]": true,
+		"::1":           false,
+		"not-an-ip":     false,
+	} {
+		if got := r.AllowsIP(ip); got != want {
+			t.Errorf("AllowsIP(%q) = %v, want %v", ip, got, want)
+		}
+	}
+	if !(ClientRestrictions{}).AllowsIP("") {
+		t.Error("empty restrictions should allow every IP")
+	}
+}
+
+func TestClientRestrictions_AllowsOrigin(t *testing.T) {
+	r := ClientRestrictions{Origins: []string{"https://app.example.com", "https://*.preview.example.com"}}
+	tests := []struct {
+		origin, referer string
+		want            bool
+	}{
+		{"https://app.example.com", "", true},
+		{"https://APP.example.com", "", true},
+		{"https://pr-7.preview.example.com", "", true},
+		{"https://evil.example.org", "https://app.example.com/", false},
+		{"", "https://app.example.com/chat?x=1", true},
+		{"null", "https://app.example.com/chat", true},
+		{"", "https://evil.example.org/", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := r.AllowsOrigin(tt.origin, tt.referer); got != tt.want {
+			t.Errorf("AllowsOrigin(%q, %q) = %v, want %v", tt.origin, tt.referer, got, tt.want)
+		}
+	}
+	if !(ClientRestrictions{}).AllowsOrigin("", "") {
+		t.Error("empty restrictions should allow requests without origin")
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks([]string{"10.0.0.0/8", " 192.0.2.1 "})
+	if err != nil {
+		t.Fatalf("ParseNetworks() error = %v", err)
+	}
+	if len(networks) != 2 || networks[1].String() != "192.0.2.1/32" {
+		t.Errorf("ParseNetworks() = %v", networks)
+	}
+	if _, err := ParseNetworks([]string{"10.0.0.0/8", "proxy.internal"}); err == nil {
+		t.Error("ParseNetworks() should reject hostnames")
+	}
+}
@@ -0,0 +1,325 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrConcurrencyLimitExceeded is returned when a request would exceed a concurrency limit
+var ErrConcurrencyLimitExceeded = errors.New("concurrency limit exceeded")
+
+// Concurrency limit scopes
+const (
+	ConcurrencyScopeProject = "project"
+	ConcurrencyScopeToken   = "token"
+)
+
+// DefaultConcurrencyLeaseTTL is how long a lease outlives its last renewal,
+// bounding how long a crashed instance holds on to its slots.
+const DefaultConcurrencyLeaseTTL = 30 * time.Second
+
+// concurrencyRetryAfter is the Retry-After suggested when a limit is exhausted.
+// Slots free up whenever a request completes, so there is no reset time.
+const concurrencyRetryAfter = time.Second
+
+// ConcurrencyLimitError reports which concurrency limit a request would exceed.
+type ConcurrencyLimitError struct {
+	Scope      string        // ConcurrencyScopeProject or ConcurrencyScopeToken
+	Limit      int64         // In-flight requests allowed in the scope
+	RetryAfter time.Duration // Suggested wait before retrying
+}
+
+func (e *ConcurrencyLimitError) Error() string {
+	return fmt.Sprintf("%s concurrency limit of %d exceeded", e.Scope, e.Limit)
+}
+
+// Unwrap makes ConcurrencyLimitError match ErrConcurrencyLimitExceeded.
+func (e *ConcurrencyLimitError) Unwrap() error {
+	return ErrConcurrencyLimitExceeded
+}
+
+// ConcurrencyStore holds semaphores of leases for the concurrency limiter.
+// Leases expire unless renewed, so slots held by a crashed instance free up.
+type ConcurrencyStore interface {
+	// Acquire adds lease to the semaphore key unless it holds limit
+	// unexpired leases already. The lease expires ttl after now.
+	Acquire(ctx context.Context, key, lease string, limit int64, now time.Time, ttl time.Duration) (bool, error)
+	// Renew makes a held lease expire ttl after now.
+	Renew(ctx context.Context, key, lease string, now time.Time, ttl time.Duration) error
+	// Release removes lease from the semaphore key.
+	Release(ctx context.Context, key, lease string) error
+}
+
+// ConcurrencyLimits are the in-flight requests allowed in each scope; 0 means unlimited.
+type ConcurrencyLimits struct {
+	PerProject int64 // Per project
+	PerToken   int64 // Per token
+}
+
+// ConcurrencyLimiterConfig contains configuration for the concurrency limiter
+type ConcurrencyLimiterConfig struct {
+	// KeyPrefix is the prefix for all keys used by the limiter
+	KeyPrefix string
+	// KeyHashSecret is the HMAC secret for hashing token IDs in keys (optional)
+	KeyHashSecret []byte
+	// Limits are the limits per project and token
+	Limits ConcurrencyLimits
+	// LeaseTTL is how long leases live without renewal (DefaultConcurrencyLeaseTTL when 0)
+	LeaseTTL time.Duration
+	// EnableFallback holds leases in memory while the store is failing instead
+	// of returning its errors
+	EnableFallback bool
+}
+
+// ConcurrencyLimiter caps the requests in flight at the same time per project
+// and per token, so long-running streams of one client cannot take all
+// upstream capacity. Each request acquires a lease in a ConcurrencyStore,
+// which is shared between instances when backed by Redis. Leases are renewed
+// while the request runs and released when it completes.
+type ConcurrencyLimiter struct {
+	store    ConcurrencyStore
+	fallback *MemoryConcurrencyStore
+	config   ConcurrencyLimiterConfig
+
+	now func() time.Time
+}
+
+// NewConcurrencyLimiter creates a concurrency limiter holding leases in store.
+func NewConcurrencyLimiter(store ConcurrencyStore, config ConcurrencyLimiterConfig) *ConcurrencyLimiter {
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "concurrency:"
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = DefaultConcurrencyLeaseTTL
+	}
+	l := &ConcurrencyLimiter{store: store, config: config, now: time.Now}
+	if config.EnableFallback {
+		l.fallback = NewMemoryConcurrencyStore()
+	}
+	return l
+}
+
+type concurrencyScope struct {
+	scope string
+	id    string
+	limit int64
+}
+
+// scopes returns the limited scopes of a request.
+func (l *ConcurrencyLimiter) scopes(projectID, tokenID string) []concurrencyScope {
+	var scopes []concurrencyScope
+	if projectID != "" && l.config.Limits.PerProject > 0 {
+		scopes = append(scopes, concurrencyScope{scope: ConcurrencyScopeProject, id: projectID, limit: l.config.Limits.PerProject})
+	}
+	if tokenID != "" && l.config.Limits.PerToken > 0 {
+		scopes = append(scopes, concurrencyScope{scope: ConcurrencyScopeToken, id: tokenID, limit: l.config.Limits.PerToken})
+	}
+	return scopes
+}
+
+// buildKey constructs the key of a scope's semaphore.
+func (l *ConcurrencyLimiter) buildKey(s concurrencyScope) string {
+	id := s.id
+	if s.scope == ConcurrencyScopeToken && len(l.config.KeyHashSecret) > 0 {
+		id = hashTokenID(id, l.config.KeyHashSecret)
+	}
+	return fmt.Sprintf("%s%s:%s", l.config.KeyPrefix, s.scope, id)
+}
+
+// Acquire takes a slot for a request of a project and token, either of which
+// may be empty. It returns a *ConcurrencyLimitError when a limit is
+// exhausted, and a nil lease when no limit applies. The lease must be
+// released once the request completes.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, projectID, tokenID string) (*ConcurrencyLease, error) {
+	scopes := l.scopes(projectID, tokenID)
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+
+	lease := &ConcurrencyLease{limiter: l, id: uuid.NewString(), done: make(chan struct{})}
+	for _, s := range scopes {
+		key := l.buildKey(s)
+		store := ConcurrencyStore(l.store)
+		ok, err := store.Acquire(ctx, key, lease.id, s.limit, l.now(), l.config.LeaseTTL)
+		if err != nil && l.fallback != nil {
+			store = l.fallback
+			ok, err = store.Acquire(ctx, key, lease.id, s.limit, l.now(), l.config.LeaseTTL)
+		}
+		if err != nil {
+			lease.release()
+			return nil, fmt.Errorf("failed to acquire concurrency lease: %w", err)
+		}
+		if !ok {
+			lease.release()
+			return nil, &ConcurrencyLimitError{Scope: s.scope, Limit: s.limit, RetryAfter: concurrencyRetryAfter}
+		}
+		lease.held = append(lease.held, heldLease{key: key, store: store})
+	}
+	go lease.renew()
+	return lease, nil
+}
+
+// heldLease is a lease acquired in one semaphore
+type heldLease struct {
+	key   string
+	store ConcurrencyStore
+}
+
+// ConcurrencyLease holds the slots of a request in flight.
+type ConcurrencyLease struct {
+	limiter *ConcurrencyLimiter
+	id      string
+	held    []heldLease
+
+	once sync.Once
+	done chan struct{}
+}
+
+// renew keeps the lease alive until it is released.
+func (le *ConcurrencyLease) renew() {
+	ticker := time.NewTicker(le.limiter.config.LeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-le.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			for _, h := range le.held {
+				_ = h.store.Renew(ctx, h.key, le.id, le.limiter.now(), le.limiter.config.LeaseTTL) // Lossy: missed renewals only free the slot early
+			}
+			cancel()
+		}
+	}
+}
+
+// Release frees the slots of the lease. It may be called more than once and
+// on a nil lease. It runs off the request context, which may already be
+// canceled.
+func (le *ConcurrencyLease) Release() {
+	if le == nil {
+		return
+	}
+	le.once.Do(func() {
+		close(le.done)
+		le.release()
+	})
+}
+
+// release removes the lease from the semaphores it was acquired in.
+func (le *ConcurrencyLease) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	for _, h := range le.held {
+		_ = h.store.Release(ctx, h.key, le.id) // Lossy: the lease expires on its own
+	}
+}
+
+// MemoryConcurrencyStore holds leases in process memory.
+type MemoryConcurrencyStore struct {
+	mu     sync.Mutex
+	leases map[string]map[string]time.Time // key -> lease -> expiry
+}
+
+// NewMemoryConcurrencyStore creates an in-memory concurrency store.
+func NewMemoryConcurrencyStore() *MemoryConcurrencyStore {
+	return &MemoryConcurrencyStore{leases: make(map[string]map[string]time.Time)}
+}
+
+// Acquire implements ConcurrencyStore. Expired leases of key are dropped first.
+func (s *MemoryConcurrencyStore) Acquire(ctx context.Context, key, lease string, limit int64, now time.Time, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leases := s.leases[key]
+	for id, expiresAt := range leases {
+		if !now.Before(expiresAt) {
+			delete(leases, id)
+		}
+	}
+	if int64(len(leases)) >= limit {
+		return false, nil
+	}
+	if leases == nil {
+		leases = make(map[string]time.Time)
+		s.leases[key] = leases
+	}
+	leases[lease] = now.Add(ttl)
+	return true, nil
+}
+
+// Renew implements ConcurrencyStore.
+func (s *MemoryConcurrencyStore) Renew(ctx context.Context, key, lease string, now time.Time, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.leases[key][lease]; ok {
+		s.leases[key][lease] = now.Add(ttl)
+	}
+	return nil
+}
+
+// Release implements ConcurrencyStore.
+func (s *MemoryConcurrencyStore) Release(ctx context.Context, key, lease string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.leases[key], lease)
+	if len(s.leases[key]) == 0 {
+		delete(s.leases, key)
+	}
+	return nil
+}
+
+// acquireConcurrencyScript drops expired leases from the sorted set KEYS[1],
+// scored by expiry in Unix milliseconds, and adds lease ARGV[4] expiring at
+// ARGV[3] unless ARGV[2] leases remain. ARGV[1] is now.
+var acquireConcurrencyScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return 1
+`)
+
+// RedisConcurrencyStore holds leases in Redis sorted sets so that all proxy
+// instances share concurrency limits.
+type RedisConcurrencyStore struct {
+	client *redis.Client
+}
+
+// NewRedisConcurrencyStore creates a concurrency store on client.
+func NewRedisConcurrencyStore(client *redis.Client) *RedisConcurrencyStore {
+	return &RedisConcurrencyStore{client: client}
+}
+
+// Acquire implements ConcurrencyStore.
+func (s *RedisConcurrencyStore) Acquire(ctx context.Context, key, lease string, limit int64, now time.Time, ttl time.Duration) (bool, error) {
+	expiresAt := now.Add(ttl)
+	ok, err := acquireConcurrencyScript.Run(ctx, s.client, []string{key},
+		now.UnixMilli(), limit, expiresAt.UnixMilli(), lease, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return ok == 1, nil
+}
+
+// Renew implements ConcurrencyStore.
+func (s *RedisConcurrencyStore) Renew(ctx context.Context, key, lease string, now time.Time, ttl time.Duration) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAddXX(ctx, key, redis.Z{Score: float64(now.Add(ttl).UnixMilli()), Member: lease})
+		pipe.PExpire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+// Release implements ConcurrencyStore.
+func (s *RedisConcurrencyStore) Release(ctx context.Context, key, lease string) error {
+	return s.client.ZRem(ctx, key, lease).Err()
+}
@@ -0,0 +1,179 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// failingConcurrencyStore fails every operation
+type failingConcurrencyStore struct{}
+
+func (failingConcurrencyStore) Acquire(ctx context.Context, key, lease string, limit int64, now time.Time, ttl time.Duration) (bool, error) {
+	return false, errors.New("store down")
+}
+
+func (failingConcurrencyStore) Renew(ctx context.Context, key, lease string, now time.Time, ttl time.Duration) error {
+	return errors.New("store down")
+}
+
+func (failingConcurrencyStore) Release(ctx context.Context, key, lease string) error {
+	return errors.New("store down")
+}
+
+func TestConcurrencyLimiter_Acquire(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stores := map[string]ConcurrencyStore{
+		"memory": NewMemoryConcurrencyStore(),
+		"redis":  NewRedisConcurrencyStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			l := NewConcurrencyLimiter(store, ConcurrencyLimiterConfig{Limits: ConcurrencyLimits{PerProject: 3, PerToken: 2}})
+
+			first, err := l.Acquire(ctx, "proj-1", "tok-1")
+			if err != nil || first == nil {
+				t.Fatalf("Acquire() = %v, %v; want lease", first, err)
+			}
+			defer first.Release()
+			second, err := l.Acquire(ctx, "proj-1", "tok-1")
+			if err != nil {
+				t.Fatalf("Acquire() error = %v", err)
+			}
+
+			_, err = l.Acquire(ctx, "proj-1", "tok-1")
+			var limitErr *ConcurrencyLimitError
+			if !errors.As(err, &limitErr) || !errors.Is(err, ErrConcurrencyLimitExceeded) {
+				t.Fatalf("Acquire() error = %v, want concurrency limit error", err)
+			}
+			if limitErr.Scope != ConcurrencyScopeToken || limitErr.Limit != 2 || limitErr.RetryAfter <= 0 {
+				t.Errorf("limit error = %+v, want token scope with limit 2", limitErr)
+			}
+
+			// The rejected request must not hold a project slot
+			third, err := l.Acquire(ctx, "proj-1", "tok-2")
+			if err != nil {
+				t.Fatalf("Acquire() for another token error = %v", err)
+			}
+			defer third.Release()
+			if _, err := l.Acquire(ctx, "proj-1", "tok-3"); !errors.As(err, &limitErr) || limitErr.Scope != ConcurrencyScopeProject {
+				t.Fatalf("Acquire() error = %v, want project limit error", err)
+			}
+
+			// Releasing frees the slot; releasing again is harmless
+			second.Release()
+			second.Release()
+			lease, err := l.Acquire(ctx, "proj-1", "tok-1")
+			if err != nil {
+				t.Fatalf("Acquire() after release error = %v", err)
+			}
+			lease.Release()
+		})
+	}
+}
+
+func TestConcurrencyLimiter_LeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewConcurrencyLimiter(NewMemoryConcurrencyStore(), ConcurrencyLimiterConfig{
+		Limits:   ConcurrencyLimits{PerToken: 1},
+		LeaseTTL: time.Minute,
+	})
+	l.now = func() time.Time { return now }
+
+	// A lease that is never released, as on a crashed instance
+	abandoned, err := l.Acquire(ctx, "", "tok-1")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	close(abandoned.done)
+	if _, err := l.Acquire(ctx, "", "tok-1"); !errors.Is(err, ErrConcurrencyLimitExceeded) {
+		t.Fatalf("Acquire() error = %v, want limit exceeded", err)
+	}
+
+	now = now.Add(time.Minute)
+	lease, err := l.Acquire(ctx, "", "tok-1")
+	if err != nil {
+		t.Errorf("Acquire() after lease expiry error = %v", err)
+	}
+	lease.Release()
+}
+
+func TestConcurrencyLimiter_Renewal(t *testing.T) {
+	mr := miniredis.RunT(t)
+	l := NewConcurrencyLimiter(NewRedisConcurrencyStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})), ConcurrencyLimiterConfig{
+		Limits:   ConcurrencyLimits{PerToken: 1},
+		LeaseTTL: 300 * time.Millisecond,
+	})
+	ctx := context.Background()
+	lease, err := l.Acquire(ctx, "", "tok-1")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer lease.Release()
+
+	// The lease is renewed while held, so it outlives its TTL
+	time.Sleep(600 * time.Millisecond)
+	if _, err := l.Acquire(ctx, "", "tok-1"); !errors.Is(err, ErrConcurrencyLimitExceeded) {
+		t.Errorf("Acquire() error = %v, want limit exceeded while the lease is held", err)
+	}
+}
+
+func TestConcurrencyLimiter_NoLimits(t *testing.T) {
+	l := NewConcurrencyLimiter(NewMemoryConcurrencyStore(), ConcurrencyLimiterConfig{})
+	lease, err := l.Acquire(context.Background(), "proj-1", "tok-1")
+	if lease != nil || err != nil {
+		t.Errorf("Acquire() = %v, %v; want nil, nil", lease, err)
+	}
+	lease.Release()
+}
+
+func TestConcurrencyLimiter_KeyHashing(t *testing.T) {
+	mr := miniredis.RunT(t)
+	l := NewConcurrencyLimiter(NewRedisConcurrencyStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})), ConcurrencyLimiterConfig{
+		KeyPrefix:     "llm:concurrency:",
+		KeyHashSecret: []byte("secret"),
+		Limits:        ConcurrencyLimits{PerToken: 1},
+	})
+	lease, err := l.Acquire(context.Background(), "", "sk-secret-token")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer lease.Release()
+	keys := mr.Keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], "llm:concurrency:token:") || strings.Contains(keys[0], "sk-secret-token") {
+		t.Errorf("keys = %v, want one hashed token key", keys)
+	}
+	if ttl := mr.TTL(keys[0]); ttl <= 0 {
+		t.Errorf("TTL = %v, want keys to expire", ttl)
+	}
+}
+
+func TestConcurrencyLimiter_StoreFailure(t *testing.T) {
+	ctx := context.Background()
+	l := NewConcurrencyLimiter(failingConcurrencyStore{}, ConcurrencyLimiterConfig{Limits: ConcurrencyLimits{PerToken: 1}})
+	if _, err := l.Acquire(ctx, "", "tok-1"); err == nil || errors.Is(err, ErrConcurrencyLimitExceeded) {
+		t.Errorf("Acquire() error = %v, want store error", err)
+	}
+
+	l = NewConcurrencyLimiter(failingConcurrencyStore{}, ConcurrencyLimiterConfig{Limits: ConcurrencyLimits{PerToken: 1}, EnableFallback: true})
+	lease, err := l.Acquire(ctx, "", "tok-1")
+	if err != nil {
+		t.Fatalf("Acquire() with fallback error = %v", err)
+	}
+	if _, err := l.Acquire(ctx, "", "tok-1"); !errors.Is(err, ErrConcurrencyLimitExceeded) {
+		t.Errorf("Acquire() with fallback error = %v, want limit exceeded", err)
+	}
+	lease.Release()
+	if lease, err := l.Acquire(ctx, "", "tok-1"); err != nil {
+		t.Errorf("Acquire() after release with fallback error = %v", err)
+	} else {
+		lease.Release()
+	}
+}
@@ -0,0 +1,67 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Limits for token metadata
+const (
+	MaxMetadataEntries     = 32
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 512
+)
+
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ValidateMetadata checks the keys and values of token metadata. Keys may
+// contain letters, digits, '_', '.' and '-'.
+func ValidateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataEntries {
+		return fmt.Errorf("metadata has %d entries, at most %d are allowed", len(metadata), MaxMetadataEntries)
+	}
+	for key, value := range metadata {
+		if key == "" {
+			return errors.New("metadata key cannot be empty")
+		}
+		if len(key) > MaxMetadataKeyLength {
+			return fmt.Errorf("metadata key '%s' is longer than %d characters", key, MaxMetadataKeyLength)
+		}
+		if !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("metadata key '%s' contains invalid characters", key)
+		}
+		if len(value) > MaxMetadataValueLength {
+			return fmt.Errorf("metadata value of '%s' is longer than %d characters", key, MaxMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// MergeChildMetadata returns the metadata of a child token: the parent's
+// metadata plus the child's own keys. Parent keys are authoritative, so a
+// child setting one of them to a different value is rejected with
+// ErrChildTokenMetadataConflict rather than escaping a binding.
+func MergeChildMetadata(parent, child map[string]string) (map[string]string, error) {
+	for key, value := range child {
+		if parentValue, ok := parent[key]; ok && parentValue != value {
+			return nil, fmt.Errorf("%w: '%s'", ErrChildTokenMetadataConflict, key)
+		}
+	}
+	return mergeMetadata(parent, child), nil
+}
+
+// mergeMetadata overlays parent onto child, so parent values win
+func mergeMetadata(parent, child map[string]string) map[string]string {
+	if len(child) == 0 {
+		return parent
+	}
+	merged := make(map[string]string, len(parent)+len(child))
+	for key, value := range child {
+		merged[key] = value
+	}
+	for key, value := range parent {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,59 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := 0; i <= MaxMetadataEntries; i++ {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		wantErr  bool
+	}{
+		{"nil", nil, false},
+		{"valid", map[string]string{"user_id": "42", "client.ip": "203.0.113.7", "tenant-id": ""}, false},
+		{"empty key", map[string]string{"": "x"}, true},
+		{"invalid key", map[string]string{"user id": "42"}, true},
+		{"long key", map[string]string{strings.Repeat("k", MaxMetadataKeyLength+1): "x"}, true},
+		{"long value", map[string]string{"k": strings.Repeat("v", MaxMetadataValueLength+1)}, true},
+		{"too many entries", tooMany, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateMetadata(tt.metadata); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeChildMetadata(t *testing.T) {
+	parent := map[string]string{"user_id": "1", "client_ip": "203.0.113.7"}
+
+	merged, err := MergeChildMetadata(parent, map[string]string{"session_id": "s-1", "user_id": "1"})
+	if err != nil {
+		t.Fatalf("MergeChildMetadata() error = %v", err)
+	}
+	want := map[string]string{"user_id": "1", "client_ip": "203.0.113.7", "session_id": "s-1"}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("MergeChildMetadata() = %v, want %v", merged, want)
+	}
+
+	if _, err := MergeChildMetadata(parent, map[string]string{"client_ip": "198.51.100.1"}); !errors.Is(err, ErrChildTokenMetadataConflict) {
+		t.Errorf("MergeChildMetadata() error = %v, want ErrChildTokenMetadataConflict", err)
+	}
+
+	// Parent values win when a child token is derived anyway
+	claims := ChildClaims{Metadata: map[string]string{"client_ip": "198.51.100.1"}}
+	if got := claims.TokenData("", TokenData{Metadata: parent}).Metadata["client_ip"]; got != "203.0.113.7" {
+		t.Errorf("TokenData() client_ip = %q, want the parent's", got)
+	}
+}
@@ -0,0 +1,67 @@
+package token
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultRateLimitWindow is the window of rate limits that do not set one
+const DefaultRateLimitWindow = time.Minute
+
+// maxRateLimitWindowSeconds caps rate limit windows at one day
+const maxRateLimitWindowSeconds = 24 * 60 * 60
+
+// RateLimit is the request rate limit of a token or project: at most
+// RequestsPerWindow requests per window of WindowSeconds, of which at most
+// Burst may arrive within one second. The zero value is unlimited.
+type RateLimit struct {
+	// RequestsPerWindow is the number of requests allowed per window (0 is unlimited)
+	RequestsPerWindow int `json:"requests_per_window"`
+	// WindowSeconds is the window length (defaults to 60)
+	WindowSeconds int `json:"window_seconds,omitempty"`
+	// Burst caps the requests within one second (0 leaves them uncapped)
+	Burst int `json:"burst,omitempty"`
+}
+
+// IsZero returns true if the limit does not limit anything
+func (l RateLimit) IsZero() bool {
+	return l.RequestsPerWindow <= 0
+}
+
+// Normalize returns a copy with the default window filled in, or the zero
+// value when nothing is limited
+func (l RateLimit) Normalize() RateLimit {
+	if l.RequestsPerWindow == 0 {
+		return RateLimit{}
+	}
+	if l.WindowSeconds == 0 {
+		l.WindowSeconds = int(DefaultRateLimitWindow / time.Second)
+	}
+	return l
+}
+
+// Validate checks that the limit's values are in range
+func (l RateLimit) Validate() error {
+	switch {
+	case l.RequestsPerWindow < 0:
+		return errors.New("requests_per_window must be >= 0")
+	case l.WindowSeconds < 0 || l.WindowSeconds > maxRateLimitWindowSeconds:
+		return errors.New("window_seconds must be between 1 and 86400")
+	case l.Burst < 0:
+		return errors.New("burst must be >= 0")
+	}
+	return nil
+}
+
+// Window returns the window length, or DefaultRateLimitWindow when unset
+func (l RateLimit) Window() time.Duration {
+	if l.WindowSeconds <= 0 {
+		return DefaultRateLimitWindow
+	}
+	return time.Duration(l.WindowSeconds) * time.Second
+}
+
+// TokenRateLimit returns the limit as enforced by a RedisRateLimiter
+func (l RateLimit) TokenRateLimit() TokenRateLimit {
+	return TokenRateLimit{MaxRequests: l.RequestsPerWindow, WindowDuration: l.Window(), Burst: l.Burst}
+}
@@ -0,0 +1,44 @@
+package token
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimit_Normalize(t *testing.T) {
+	if got := (RateLimit{Burst: 5}).Normalize(); got != (RateLimit{}) {
+		t.Errorf("Normalize() of unlimited = %+v, want zero value", got)
+	}
+	if got := (RateLimit{RequestsPerWindow: 10}).Normalize(); got.WindowSeconds != 60 {
+		t.Errorf("Normalize() window = %d, want 60", got.WindowSeconds)
+	}
+}
+
+func TestRateLimit_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   RateLimit
+		wantErr bool
+	}{
+		{"zero", RateLimit{}, false},
+		{"valid", RateLimit{RequestsPerWindow: 10, WindowSeconds: 60, Burst: 2}, false},
+		{"negative requests", RateLimit{RequestsPerWindow: -1}, true},
+		{"window too long", RateLimit{RequestsPerWindow: 1, WindowSeconds: 86401}, true},
+		{"negative burst", RateLimit{RequestsPerWindow: 1, Burst: -1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.limit.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateLimit_TokenRateLimit(t *testing.T) {
+	got := RateLimit{RequestsPerWindow: 10, Burst: 3}.TokenRateLimit()
+	want := TokenRateLimit{MaxRequests: 10, WindowDuration: time.Minute, Burst: 3}
+	if got != want {
+		t.Errorf("TokenRateLimit() = %+v, want %+v", got, want)
+	}
+}
@@ -16,6 +16,10 @@ var (
 	ErrLimitOperation = errors.New("limit operation failed")
 )
 
+// UnlimitedRequests is the number of remaining requests reported for tokens
+// without a request limit
+const UnlimitedRequests = 1000000000
+
 // RateLimiter defines the interface for rate limiting
 type RateLimiter interface {
 	// AllowRequest checks if a token is within its rate limits and updates usage
@@ -61,8 +65,8 @@ func NewRateLimiter(store RateLimitStore) *StandardRateLimiter {
 // AllowRequest checks if a token is within its rate limits and updates usage
 func (r *StandardRateLimiter) AllowRequest(ctx context.Context, tokenID string) error {
 	// Validate token format first
-	if err := ValidateTokenFormat(tokenID); err != nil {
-		return fmt.Errorf("invalid token format: %w", err)
+	if err := validateLimiterToken(tokenID); err != nil {
+		return err
 	}
 
 	// Get current token data
@@ -93,8 +97,8 @@ func (r *StandardRateLimiter) AllowRequest(ctx context.Context, tokenID string)
 // GetRemainingRequests returns the number of remaining requests for a token
 func (r *StandardRateLimiter) GetRemainingRequests(ctx context.Context, tokenID string) (int, error) {
 	// Validate token format first
-	if err := ValidateTokenFormat(tokenID); err != nil {
-		return 0, fmt.Errorf("invalid token format: %w", err)
+	if err := validateLimiterToken(tokenID); err != nil {
+		return 0, err
 	}
 
 	// Get current token data
@@ -108,7 +112,7 @@ func (r *StandardRateLimiter) GetRemainingRequests(ctx context.Context, tokenID
 
 	// If token has no limit, return a high number
 	if token.MaxRequests == nil {
-		return 1000000000, nil // Unlimited
+		return UnlimitedRequests, nil
 	}
 
 	// Calculate remaining requests
@@ -123,8 +127,8 @@ func (r *StandardRateLimiter) GetRemainingRequests(ctx context.Context, tokenID
 // ResetUsage resets the usage counter for a token
 func (r *StandardRateLimiter) ResetUsage(ctx context.Context, tokenID string) error {
 	// Validate token format first
-	if err := ValidateTokenFormat(tokenID); err != nil {
-		return fmt.Errorf("invalid token format: %w", err)
+	if err := validateLimiterToken(tokenID); err != nil {
+		return err
 	}
 
 	// Reset token usage
@@ -141,8 +145,8 @@ func (r *StandardRateLimiter) ResetUsage(ctx context.Context, tokenID string) er
 // UpdateLimit updates the maximum allowed requests for a token
 func (r *StandardRateLimiter) UpdateLimit(ctx context.Context, tokenID string, maxRequests *int) error {
 	// Validate token format first
-	if err := ValidateTokenFormat(tokenID); err != nil {
-		return fmt.Errorf("invalid token format: %w", err)
+	if err := validateLimiterToken(tokenID); err != nil {
+		return err
 	}
 
 	// Update token limit
@@ -156,6 +160,51 @@ func (r *StandardRateLimiter) UpdateLimit(ctx context.Context, tokenID string, m
 	return nil
 }
 
+// validateLimiterToken checks the format of a token passed to the rate
+// limiter. Child tokens are passed through; the store verifies them.
+func validateLimiterToken(tokenID string) error {
+	if IsChildToken(tokenID) {
+		return nil
+	}
+	if err := ValidateTokenFormat(tokenID); err != nil {
+		return fmt.Errorf("invalid token format: %w", err)
+	}
+	return nil
+}
+
+// TokenDataRateLimitStore is a read-only RateLimitStore that looks tokens up
+// through a TokenDataProvider, so a RateLimiter can report the remaining
+// requests of any token the validator accepts, including child tokens.
+// Usage is tracked by the validator; the write methods fail.
+type TokenDataRateLimitStore struct {
+	provider TokenDataProvider
+}
+
+// NewTokenDataRateLimitStore creates a read-only RateLimitStore backed by provider
+func NewTokenDataRateLimitStore(provider TokenDataProvider) *TokenDataRateLimitStore {
+	return &TokenDataRateLimitStore{provider: provider}
+}
+
+// GetTokenByID returns the token data of a token string
+func (s *TokenDataRateLimitStore) GetTokenByID(ctx context.Context, tokenID string) (TokenData, error) {
+	return s.provider.GetTokenData(ctx, tokenID)
+}
+
+// IncrementTokenUsage is not supported; usage is tracked by the validator
+func (s *TokenDataRateLimitStore) IncrementTokenUsage(ctx context.Context, tokenID string) error {
+	return fmt.Errorf("%w: token data store is read-only", ErrLimitOperation)
+}
+
+// ResetTokenUsage is not supported; usage is tracked by the validator
+func (s *TokenDataRateLimitStore) ResetTokenUsage(ctx context.Context, tokenID string) error {
+	return fmt.Errorf("%w: token data store is read-only", ErrLimitOperation)
+}
+
+// UpdateTokenLimit is not supported; limits are managed by the token store
+func (s *TokenDataRateLimitStore) UpdateTokenLimit(ctx context.Context, tokenID string, maxRequests *int) error {
+	return fmt.Errorf("%w: token data store is read-only", ErrLimitOperation)
+}
+
 // MemoryRateLimiter implements in-memory rate limiting with token bucket algorithm
 type MemoryRateLimiter struct {
 	// Mapping from token ID to rate limit data
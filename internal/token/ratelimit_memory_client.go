@@ -0,0 +1,118 @@
+package token
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryRateLimitSweepInterval is how often expired keys are dropped
+const memoryRateLimitSweepInterval = time.Minute
+
+type memoryRateLimitEntry struct {
+	value     string
+	expiresAt time.Time // Zero for keys without expiry
+}
+
+// MemoryRateLimitClient implements RedisRateLimitClient in process memory, so
+// a RedisRateLimiter can enforce limits on a single instance without Redis.
+type MemoryRateLimitClient struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryRateLimitEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimitClient creates an in-memory rate limit client
+func NewMemoryRateLimitClient() *MemoryRateLimitClient {
+	return &MemoryRateLimitClient{entries: make(map[string]*memoryRateLimitEntry), now: time.Now}
+}
+
+// get returns the live entry of key. The caller holds mu.
+func (c *MemoryRateLimitClient) get(key string) (*memoryRateLimitEntry, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expiresAt.IsZero() && !c.now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e, true
+}
+
+// Incr implements RedisRateLimitClient. Expired keys are swept once a minute.
+func (c *MemoryRateLimitClient) Incr(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if now.Sub(c.lastSweep) >= memoryRateLimitSweepInterval {
+		for k, e := range c.entries {
+			if !e.expiresAt.IsZero() && !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+	e, ok := c.get(key)
+	if !ok {
+		e = &memoryRateLimitEntry{}
+		c.entries[key] = e
+	}
+	n, _ := strconv.ParseInt(e.value, 10, 64)
+	n++
+	e.value = strconv.FormatInt(n, 10)
+	return n, nil
+}
+
+// Get implements RedisRateLimitClient. Missing keys return an empty string.
+func (c *MemoryRateLimitClient) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.get(key); ok {
+		return e.value, nil
+	}
+	return "", nil
+}
+
+// Set implements RedisRateLimitClient
+func (c *MemoryRateLimitClient) Set(ctx context.Context, key, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &memoryRateLimitEntry{value: value}
+	return nil
+}
+
+// Expire implements RedisRateLimitClient
+func (c *MemoryRateLimitClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.get(key); ok {
+		e.expiresAt = c.now().Add(expiration)
+	}
+	return nil
+}
+
+// SetNX implements RedisRateLimitClient
+func (c *MemoryRateLimitClient) SetNX(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.get(key); ok {
+		return false, nil
+	}
+	e := &memoryRateLimitEntry{value: value}
+	if expiration > 0 {
+		e.expiresAt = c.now().Add(expiration)
+	}
+	c.entries[key] = e
+	return true, nil
+}
+
+// Del implements RedisRateLimitClient
+func (c *MemoryRateLimitClient) Del(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return nil
+}
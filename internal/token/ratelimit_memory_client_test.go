@@ -0,0 +1,60 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimitClient(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryRateLimitClient()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	for want := int64(1); want <= 3; want++ {
+		if got, err := c.Incr(ctx, "k"); err != nil || got != want {
+			t.Fatalf("Incr() = %d, %v; want %d", got, err, want)
+		}
+	}
+	if err := c.Expire(ctx, "k", time.Minute); err != nil {
+		t.Fatalf("Expire() error = %v", err)
+	}
+	if v, _ := c.Get(ctx, "k"); v != "3" {
+		t.Errorf("Get() = %q, want 3", v)
+	}
+
+	now = now.Add(time.Minute)
+	if v, _ := c.Get(ctx, "k"); v != "" {
+		t.Errorf("Get() after expiry = %q, want empty", v)
+	}
+	if got, _ := c.Incr(ctx, "k"); got != 1 {
+		t.Errorf("Incr() after expiry = %d, want 1", got)
+	}
+
+	if ok, _ := c.SetNX(ctx, "n", "a", time.Second); !ok {
+		t.Error("SetNX() on missing key = false")
+	}
+	if ok, _ := c.SetNX(ctx, "n", "b", time.Second); ok {
+		t.Error("SetNX() on existing key = true")
+	}
+	if err := c.Del(ctx, "n"); err != nil {
+		t.Fatalf("Del() error = %v", err)
+	}
+	if v, _ := c.Get(ctx, "n"); v != "" {
+		t.Errorf("Get() after Del = %q, want empty", v)
+	}
+}
+
+func TestMemoryRateLimitClient_WithLimiter(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRedisRateLimiter(NewMemoryRateLimitClient(), RedisRateLimiterConfig{DefaultWindowDuration: time.Minute})
+	limiter.SetTokenRateLimit("token1", RateLimit{RequestsPerWindow: 1, WindowSeconds: 3600}.TokenRateLimit())
+
+	if allowed, err := limiter.Allow(ctx, "token1"); err != nil || !allowed {
+		t.Fatalf("Allow() = %v, %v; want allowed", allowed, err)
+	}
+	if allowed, err := limiter.Allow(ctx, "token1"); err != nil || allowed {
+		t.Errorf("Allow() over limit = %v, %v; want denied", allowed, err)
+	}
+}
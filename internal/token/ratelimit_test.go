@@ -515,3 +515,39 @@ func TestMemoryRateLimiter_ResetAndSetLimit(t *testing.T) {
 		}
 	})
 }
+
+func TestTokenDataRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMockTokenStore()
+	tok, _ := GenerateToken()
+	limit := 10
+	store.AddToken(tok, TokenData{Token: tok, ProjectID: "project1", IsActive: true, RequestCount: 4, MaxRequests: &limit})
+	store.AddToken("parent-id", TokenData{ID: "parent-id", ProjectID: "project-id", IsActive: true})
+
+	validator := NewValidator(store)
+	codec, _ := NewHMACChildTokenCodec(testChildSecret)
+	validator.EnableChildTokens(codec, time.Minute)
+	claims := testChildClaims()
+	claims.MaxRequests = 3
+	child, _ := codec.Sign(claims)
+	if _, err := validator.ValidateTokenWithTracking(ctx, child); err != nil {
+		t.Fatalf("ValidateTokenWithTracking() error = %v", err)
+	}
+
+	limiter := NewRateLimiter(NewTokenDataRateLimitStore(validator))
+	if remaining, err := limiter.GetRemainingRequests(ctx, tok); err != nil || remaining != 6 {
+		t.Errorf("GetRemainingRequests() = %d, %v, want 6", remaining, err)
+	}
+	if remaining, err := limiter.GetRemainingRequests(ctx, child); err != nil || remaining != 2 {
+		t.Errorf("GetRemainingRequests() for child token = %d, %v, want 2", remaining, err)
+	}
+	if err := limiter.AllowRequest(ctx, tok); !errors.Is(err, ErrLimitOperation) {
+		t.Errorf("AllowRequest() error = %v, want ErrLimitOperation", err)
+	}
+	if err := limiter.ResetUsage(ctx, tok); !errors.Is(err, ErrLimitOperation) {
+		t.Errorf("ResetUsage() error = %v, want ErrLimitOperation", err)
+	}
+	if err := limiter.UpdateLimit(ctx, tok, nil); !errors.Is(err, ErrLimitOperation) {
+		t.Errorf("UpdateLimit() error = %v, want ErrLimitOperation", err)
+	}
+}
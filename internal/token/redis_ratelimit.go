@@ -66,25 +66,41 @@ func DefaultRedisRateLimiterConfig() RedisRateLimiterConfig {
 }
 
 // RedisRateLimiter implements distributed rate limiting using Redis.
-// It uses a sliding window counter algorithm with Redis INCR for atomic operations.
+// It uses a sliding window counter algorithm: requests are counted per fixed
+// window with Redis INCR, and the sliding window ending now is estimated from
+// the current window's count plus the overlapping share of the previous
+// window's, so a client cannot double its limit across a window boundary.
+// Tokens are limited by DefaultMaxRequests unless they have their own limit;
+// projects are only limited when they have one.
 type RedisRateLimiter struct {
 	client   RedisRateLimitClient
 	config   RedisRateLimiterConfig
 	fallback *MemoryRateLimiter
+	now      func() time.Time
 
 	// Track Redis availability
 	redisAvailable   bool
 	redisAvailableMu sync.RWMutex
 
-	// Per-token limits (optional override of defaults)
+	// Per-token limits (optional override of defaults) and per-project limits
 	tokenLimits   map[string]*TokenRateLimit
+	projectLimits map[string]*TokenRateLimit
 	tokenLimitsMu sync.RWMutex
 }
 
-// TokenRateLimit holds rate limit configuration for a specific token
+// TokenRateLimit holds rate limit configuration for a specific token or project
 type TokenRateLimit struct {
 	MaxRequests    int
 	WindowDuration time.Duration
+	// Burst caps the requests within one second (0 leaves them uncapped)
+	Burst int
+}
+
+// RateLimitStatus reports a request limit after a request was checked against it
+type RateLimitStatus struct {
+	Limit     int       // Requests allowed per window
+	Remaining int       // Requests left in the sliding window
+	Reset     time.Time // When the requests counted so far have left the sliding window
 }
 
 // NewRedisRateLimiter creates a new distributed rate limiter using Redis
@@ -94,6 +110,8 @@ func NewRedisRateLimiter(client RedisRateLimitClient, config RedisRateLimiterCon
 		config:         config,
 		redisAvailable: true,
 		tokenLimits:    make(map[string]*TokenRateLimit),
+		projectLimits:  make(map[string]*TokenRateLimit),
+		now:            time.Now,
 	}
 
 	// Create fallback in-memory limiter if enabled
@@ -123,30 +141,193 @@ func hashTokenID(tokenID string, secret []byte) string {
 	return hex.EncodeToString(h.Sum(nil))[:16]
 }
 
-// getWindowStart returns the start timestamp for the current window
-func (r *RedisRateLimiter) getWindowStart(windowDuration time.Duration) int64 {
-	now := time.Now()
-	return now.Truncate(windowDuration).Unix()
+// slidingWindow holds the counts of the fixed window containing a point in
+// time and of the window before it.
+type slidingWindow struct {
+	window   time.Duration
+	elapsed  time.Duration // Time into the current window
+	previous int64
+	current  int64
+}
+
+// count returns the estimated requests in the sliding window: the current
+// window's plus the share of the previous window's that the sliding window
+// still overlaps.
+func (s slidingWindow) count() float64 {
+	overlap := 1 - float64(s.elapsed)/float64(s.window)
+	return float64(s.previous)*overlap + float64(s.current)
+}
+
+// retryAfter returns how long until one more request fits under limit.
+func (s slidingWindow) retryAfter(limit int) time.Duration {
+	var wait time.Duration
+	if s.current+1 <= int64(limit) && s.previous > 0 {
+		// The previous window's share has to decay far enough
+		free := float64(int64(limit)-s.current-1) / float64(s.previous)
+		wait = time.Duration(float64(s.window)*(1-free)) - s.elapsed
+	} else {
+		// The current window becomes the previous one, whose share has to decay
+		free := 0.0
+		if s.current > 0 {
+			free = float64(limit-1) / float64(s.current)
+		}
+		wait = s.window - s.elapsed + time.Duration(float64(s.window)*(1-free))
+	}
+	// Round up so the request fits at the returned time despite float rounding
+	return max(wait, 0).Truncate(time.Millisecond) + time.Millisecond
+}
+
+// status reports the window against limit at now.
+func (s slidingWindow) status(limit int, now time.Time) *RateLimitStatus {
+	start := now.Add(-s.elapsed)
+	reset := now
+	switch {
+	case s.current > 0:
+		reset = start.Add(2 * s.window)
+	case s.previous > 0:
+		reset = start.Add(s.window)
+	}
+	return &RateLimitStatus{Limit: limit, Remaining: max(int(float64(limit)-s.count()), 0), Reset: reset}
+}
+
+// readWindow reads the counts of the sliding window of length window ending at now.
+func (r *RedisRateLimiter) readWindow(ctx context.Context, id, suffix string, window time.Duration, now time.Time) (slidingWindow, error) {
+	start := now.Truncate(window)
+	s := slidingWindow{window: window, elapsed: now.Sub(start)}
+	var err error
+	if s.previous, err = r.getCount(ctx, r.buildKey(id, start.Add(-window).Unix())+suffix); err != nil {
+		return s, err
+	}
+	if s.current, err = r.getCount(ctx, r.buildKey(id, start.Unix())+suffix); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// getCount returns the counter at key; missing keys count 0
+func (r *RedisRateLimiter) getCount(ctx context.Context, key string) (int64, error) {
+	value, err := r.client.Get(ctx, key)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n, nil
+}
+
+// countRequest adds a request to the current window of s and returns the
+// updated window. Counters live for two windows, as long as the sliding
+// window can reach back.
+func (r *RedisRateLimiter) countRequest(ctx context.Context, id, suffix string, s slidingWindow, now time.Time) (slidingWindow, error) {
+	key := r.buildKey(id, now.Truncate(s.window).Unix()) + suffix
+	count, err := r.client.Incr(ctx, key)
+	if err != nil {
+		return s, err
+	}
+	if count == 1 {
+		// Ignore expire errors for graceful degradation; orphaned keys will be cleaned up by Redis eventually.
+		_ = r.client.Expire(ctx, key, 2*s.window+time.Second)
+	}
+	s.current = count
+	return s, nil
+}
+
+// admit counts a request in the sliding window of length window unless that
+// would exceed limit. Rejected requests are not counted; requests that lose a
+// race for the last slot are counted but rejected. It returns the window as
+// counted, and 0 when the request is admitted, otherwise how long until it
+// would be.
+func (r *RedisRateLimiter) admit(ctx context.Context, id, suffix string, limit int, window time.Duration, now time.Time) (slidingWindow, time.Duration, error) {
+	s, err := r.readWindow(ctx, id, suffix, window, now)
+	if err != nil {
+		return s, 0, err
+	}
+	if s.count()+1 > float64(limit) {
+		return s, s.retryAfter(limit), nil
+	}
+	if s, err = r.countRequest(ctx, id, suffix, s, now); err != nil {
+		return s, 0, err
+	}
+	if s.count() > float64(limit) {
+		s.current--
+		return s, s.retryAfter(limit), nil
+	}
+	return s, 0, nil
 }
 
 // getTokenLimit returns the rate limit for a token, using defaults if not set
-func (r *RedisRateLimiter) getTokenLimit(tokenID string) (int, time.Duration) {
+func (r *RedisRateLimiter) getTokenLimit(tokenID string) TokenRateLimit {
 	r.tokenLimitsMu.RLock()
 	limit, exists := r.tokenLimits[tokenID]
 	r.tokenLimitsMu.RUnlock()
 
 	if exists && limit != nil {
-		return limit.MaxRequests, limit.WindowDuration
+		return *limit
 	}
-	return r.config.DefaultMaxRequests, r.config.DefaultWindowDuration
+	return r.defaultLimit()
+}
+
+// defaultLimit returns the limit of tokens without their own
+func (r *RedisRateLimiter) defaultLimit() TokenRateLimit {
+	return TokenRateLimit{MaxRequests: r.config.DefaultMaxRequests, WindowDuration: r.config.DefaultWindowDuration}
+}
+
+// projectKeyID returns the ID project counters are keyed by, kept apart from token IDs
+func projectKeyID(projectID string) string {
+	return "project:" + projectID
 }
 
 // Allow checks if a request from the given token should be allowed.
 // Returns true if the request is within rate limits, false otherwise.
 func (r *RedisRateLimiter) Allow(ctx context.Context, tokenID string) (bool, error) {
-	maxRequests, windowDuration := r.getTokenLimit(tokenID)
-	windowStart := r.getWindowStart(windowDuration)
-	key := r.buildKey(tokenID, windowStart)
+	retryAfter, err := r.CheckToken(ctx, tokenID)
+	if err != nil {
+		return false, err
+	}
+	return retryAfter == 0, nil
+}
+
+// CheckToken counts a request of a token against its limit. It returns 0 if
+// the request is allowed, and otherwise how long until the limit admits
+// requests again.
+func (r *RedisRateLimiter) CheckToken(ctx context.Context, tokenID string) (time.Duration, error) {
+	retryAfter, _, err := r.CheckTokenStatus(ctx, tokenID)
+	return retryAfter, err
+}
+
+// CheckTokenStatus is CheckToken, also reporting the state of the token's
+// limit. The status is nil for unlimited tokens, when a burst is exceeded and
+// while in fallback mode.
+func (r *RedisRateLimiter) CheckTokenStatus(ctx context.Context, tokenID string) (time.Duration, *RateLimitStatus, error) {
+	return r.check(ctx, tokenID, r.getTokenLimit(tokenID))
+}
+
+// CheckProject counts a request of a project against its limit like
+// CheckToken. Projects without a limit are not counted.
+func (r *RedisRateLimiter) CheckProject(ctx context.Context, projectID string) (time.Duration, error) {
+	retryAfter, _, err := r.CheckProjectStatus(ctx, projectID)
+	return retryAfter, err
+}
+
+// CheckProjectStatus is CheckProject, also reporting the state of the
+// project's limit like CheckTokenStatus.
+func (r *RedisRateLimiter) CheckProjectStatus(ctx context.Context, projectID string) (time.Duration, *RateLimitStatus, error) {
+	r.tokenLimitsMu.RLock()
+	limit, exists := r.projectLimits[projectID]
+	r.tokenLimitsMu.RUnlock()
+
+	if !exists || limit == nil {
+		return 0, nil, nil
+	}
+	return r.check(ctx, projectKeyID(projectID), *limit)
+}
+
+// check counts a request against limit under id. A MaxRequests of 0 or less
+// is unlimited.
+func (r *RedisRateLimiter) check(ctx context.Context, id string, limit TokenRateLimit) (time.Duration, *RateLimitStatus, error) {
+	if limit.MaxRequests <= 0 || limit.WindowDuration <= 0 {
+		return 0, nil, nil
+	}
+	now := r.now()
 
 	// Check Redis availability
 	r.redisAvailableMu.RLock()
@@ -154,38 +335,56 @@ func (r *RedisRateLimiter) Allow(ctx context.Context, tokenID string) (bool, err
 	r.redisAvailableMu.RUnlock()
 
 	if !available {
-		return r.handleFallback(tokenID)
+		retryAfter, err := r.handleFallback(id, limit)
+		return retryAfter, nil, err
 	}
 
-	// Try to increment counter atomically in Redis
-	count, err := r.client.Incr(ctx, key)
+	// Bursts are checked first, over a sliding second, so a burst rejection
+	// does not use up the window. A failing burst count does not reject the request.
+	if limit.Burst > 0 {
+		if _, retryAfter, err := r.admit(ctx, id, ":burst", limit.Burst, time.Second, now); err == nil && retryAfter > 0 {
+			return retryAfter, nil, nil
+		}
+	}
+
+	window, retryAfter, err := r.admit(ctx, id, "", limit.MaxRequests, limit.WindowDuration, now)
 	if err != nil {
 		// Redis operation failed
 		r.markRedisUnavailable()
-		return r.handleFallback(tokenID)
+		retryAfter, err := r.handleFallback(id, limit)
+		return retryAfter, nil, err
 	}
 
 	// Mark Redis as available (successful operation)
 	r.markRedisAvailable()
-
-	// Set expiration on first increment (count == 1)
-	if count == 1 {
-		// Set TTL slightly longer than window to handle edge cases
-		ttl := windowDuration + time.Second
-		// Ignore expire errors for graceful degradation; orphaned keys will be cleaned up by Redis eventually.
-		_ = r.client.Expire(ctx, key, ttl)
-	}
-
-	// Check if request is within limit
-	return count <= int64(maxRequests), nil
+	return retryAfter, window.status(limit.MaxRequests, now), nil
 }
 
-// handleFallback handles rate limiting when Redis is unavailable
-func (r *RedisRateLimiter) handleFallback(tokenID string) (bool, error) {
+// handleFallback handles rate limiting when Redis is unavailable. Tokens and
+// projects with their own limit get a token bucket refilling at that limit,
+// holding Burst requests (or the whole window's).
+func (r *RedisRateLimiter) handleFallback(id string, limit TokenRateLimit) (time.Duration, error) {
 	if !r.config.EnableFallback || r.fallback == nil {
-		return false, ErrRedisUnavailable
+		return 0, ErrRedisUnavailable
+	}
+	if limit != r.defaultLimit() {
+		rate := float64(limit.MaxRequests) / limit.WindowDuration.Seconds()
+		capacity := limit.MaxRequests
+		if limit.Burst > 0 {
+			capacity = limit.Burst
+		}
+		if curRate, curCapacity, ok := r.fallback.GetLimit(id); !ok || curRate != rate || curCapacity != capacity {
+			r.fallback.SetLimit(id, rate, capacity)
+		}
+	}
+	if r.fallback.Allow(id) {
+		return 0, nil
 	}
-	return r.fallback.Allow(tokenID), nil
+	rate, _, _ := r.fallback.GetLimit(id)
+	if rate <= 0 {
+		return time.Second, nil
+	}
+	return time.Duration(float64(time.Second) / rate), nil
 }
 
 // markRedisUnavailable marks Redis as unavailable
@@ -202,11 +401,13 @@ func (r *RedisRateLimiter) markRedisAvailable() {
 	r.redisAvailableMu.Unlock()
 }
 
-// GetRemainingRequests returns the number of remaining requests for a token in the current window
+// GetRemainingRequests returns the number of remaining requests for a token in the sliding window
 func (r *RedisRateLimiter) GetRemainingRequests(ctx context.Context, tokenID string) (int, error) {
-	maxRequests, windowDuration := r.getTokenLimit(tokenID)
-	windowStart := r.getWindowStart(windowDuration)
-	key := r.buildKey(tokenID, windowStart)
+	limit := r.getTokenLimit(tokenID)
+	maxRequests := limit.MaxRequests
+	if maxRequests <= 0 {
+		return UnlimitedRequests, nil
+	}
 
 	// Check Redis availability
 	r.redisAvailableMu.RLock()
@@ -221,8 +422,8 @@ func (r *RedisRateLimiter) GetRemainingRequests(ctx context.Context, tokenID str
 		return 0, ErrRedisUnavailable
 	}
 
-	// Get current count from Redis
-	countStr, err := r.client.Get(ctx, key)
+	// Get current counts from Redis
+	window, err := r.readWindow(ctx, tokenID, "", limit.WindowDuration, r.now())
 	if err != nil {
 		// Redis error - mark unavailable and use fallback
 		r.markRedisUnavailable()
@@ -232,18 +433,7 @@ func (r *RedisRateLimiter) GetRemainingRequests(ctx context.Context, tokenID str
 		return 0, fmt.Errorf("failed to get rate limit counter: %w", err)
 	}
 
-	// Key doesn't exist (empty string returned, no error) - all requests remain
-	if countStr == "" {
-		return maxRequests, nil
-	}
-
-	// Parse count using strconv.Atoi for idiomatic integer parsing
-	count, parseErr := strconv.Atoi(countStr)
-	if parseErr != nil {
-		count = 0
-	}
-
-	remaining := maxRequests - count
+	remaining := int(float64(maxRequests) - window.count())
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -252,26 +442,61 @@ func (r *RedisRateLimiter) GetRemainingRequests(ctx context.Context, tokenID str
 
 // SetTokenLimit sets a custom rate limit for a specific token
 func (r *RedisRateLimiter) SetTokenLimit(tokenID string, maxRequests int, windowDuration time.Duration) {
-	r.tokenLimitsMu.Lock()
-	r.tokenLimits[tokenID] = &TokenRateLimit{
+	r.SetTokenRateLimit(tokenID, TokenRateLimit{
 		MaxRequests:    maxRequests,
 		WindowDuration: windowDuration,
-	}
-	r.tokenLimitsMu.Unlock()
+	})
+}
+
+// SetTokenRateLimit sets a custom rate limit, including its burst, for a specific token
+func (r *RedisRateLimiter) SetTokenRateLimit(tokenID string, limit TokenRateLimit) {
+	r.storeLimit(r.tokenLimits, tokenID, &limit)
 }
 
 // RemoveTokenLimit removes the custom rate limit for a token (falls back to defaults)
 func (r *RedisRateLimiter) RemoveTokenLimit(tokenID string) {
+	r.storeLimit(r.tokenLimits, tokenID, nil)
+}
+
+// SetProjectLimit sets the rate limit shared by all requests of a project
+func (r *RedisRateLimiter) SetProjectLimit(projectID string, limit TokenRateLimit) {
+	r.storeLimit(r.projectLimits, projectID, &limit)
+}
+
+// RemoveProjectLimit removes the rate limit of a project, leaving it unlimited
+func (r *RedisRateLimiter) RemoveProjectLimit(projectID string) {
+	r.storeLimit(r.projectLimits, projectID, nil)
+}
+
+// storeLimit sets the limit of id in limits, or removes it when limit is nil.
+// Limits are set on every request, so the write lock is only taken when the
+// limit actually changes.
+func (r *RedisRateLimiter) storeLimit(limits map[string]*TokenRateLimit, id string, limit *TokenRateLimit) {
+	r.tokenLimitsMu.RLock()
+	current, ok := limits[id]
+	unchanged := (limit == nil && !ok) || (limit != nil && ok && *current == *limit)
+	r.tokenLimitsMu.RUnlock()
+	if unchanged {
+		return
+	}
+
 	r.tokenLimitsMu.Lock()
-	delete(r.tokenLimits, tokenID)
-	r.tokenLimitsMu.Unlock()
+	defer r.tokenLimitsMu.Unlock()
+	if limit == nil {
+		delete(limits, id)
+	} else {
+		limits[id] = limit
+	}
 }
 
-// ResetTokenUsage resets the rate limit counter for a token
+// ResetTokenUsage resets the rate limit counters of the sliding window for a token
 func (r *RedisRateLimiter) ResetTokenUsage(ctx context.Context, tokenID string) error {
-	_, windowDuration := r.getTokenLimit(tokenID)
-	windowStart := r.getWindowStart(windowDuration)
-	key := r.buildKey(tokenID, windowStart)
+	window := r.getTokenLimit(tokenID).WindowDuration
+	windowStart := r.now().Truncate(window)
+	keys := []string{
+		r.buildKey(tokenID, windowStart.Add(-window).Unix()),
+		r.buildKey(tokenID, windowStart.Unix()),
+	}
 
 	// Check Redis availability
 	r.redisAvailableMu.RLock()
@@ -286,13 +511,15 @@ func (r *RedisRateLimiter) ResetTokenUsage(ctx context.Context, tokenID string)
 		return ErrRedisUnavailable
 	}
 
-	if err := r.client.Del(ctx, key); err != nil {
-		r.markRedisUnavailable()
-		if r.config.EnableFallback && r.fallback != nil {
-			r.fallback.Reset(tokenID)
-			return nil
+	for _, key := range keys {
+		if err := r.client.Del(ctx, key); err != nil {
+			r.markRedisUnavailable()
+			if r.config.EnableFallback && r.fallback != nil {
+				r.fallback.Reset(tokenID)
+				return nil
+			}
+			return fmt.Errorf("failed to reset rate limit counter: %w", err)
 		}
-		return fmt.Errorf("failed to reset rate limit counter: %w", err)
 	}
 
 	r.markRedisAvailable()
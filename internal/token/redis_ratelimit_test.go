@@ -294,21 +294,20 @@ func TestRedisRateLimiter_RemoveTokenLimit(t *testing.T) {
 	limiter.SetTokenLimit(tokenID, 5, time.Minute)
 
 	// Verify custom limit is set
-	maxReqs, windowDur := limiter.getTokenLimit(tokenID)
-	if maxReqs != 5 {
-		t.Fatalf("expected maxRequests 5, got %d", maxReqs)
+	limit := limiter.getTokenLimit(tokenID)
+	if limit.MaxRequests != 5 {
+		t.Fatalf("expected maxRequests 5, got %d", limit.MaxRequests)
 	}
-	if windowDur != time.Minute {
-		t.Fatalf("expected windowDuration 1m, got %v", windowDur)
+	if limit.WindowDuration != time.Minute {
+		t.Fatalf("expected windowDuration 1m, got %v", limit.WindowDuration)
 	}
 
 	// Remove the custom limit
 	limiter.RemoveTokenLimit(tokenID)
 
 	// Should now use defaults
-	maxReqs, _ = limiter.getTokenLimit(tokenID)
-	if maxReqs != 100 {
-		t.Fatalf("expected default maxRequests 100, got %d", maxReqs)
+	if limit = limiter.getTokenLimit(tokenID); limit.MaxRequests != 100 {
+		t.Fatalf("expected default maxRequests 100, got %d", limit.MaxRequests)
 	}
 }
 
@@ -476,8 +475,8 @@ func TestRedisRateLimiter_ExpireOnFirstIncrement(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	// Verify TTL was set (should be window duration + 1 second)
-	expectedTTL := time.Minute + time.Second
+	// Verify TTL was set (should be two windows + 1 second, as far back as the sliding window reaches)
+	expectedTTL := 2*time.Minute + time.Second
 	for key, ttl := range client.ttls {
 		if ttl != expectedTTL {
 			t.Errorf("key %s has TTL %v, expected %v", key, ttl, expectedTTL)
@@ -904,3 +903,206 @@ func TestRedisRateLimiter_Allow_WithHashedKeys(t *testing.T) {
 		}
 	}
 }
+
+func TestRedisRateLimiter_CheckProject(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRedisRateLimiter(newMockRedisRateLimitClient(), RedisRateLimiterConfig{
+		KeyPrefix:             "test:",
+		DefaultWindowDuration: time.Minute,
+		DefaultMaxRequests:    1,
+	})
+
+	// Projects without a limit are not counted
+	for i := 0; i < 3; i++ {
+		if retryAfter, err := limiter.CheckProject(ctx, "proj-1"); err != nil || retryAfter != 0 {
+			t.Fatalf("CheckProject() without limit = %v, %v; want 0, nil", retryAfter, err)
+		}
+	}
+
+	limiter.SetProjectLimit("proj-1", TokenRateLimit{MaxRequests: 2, WindowDuration: time.Hour})
+	for i := 0; i < 2; i++ {
+		if retryAfter, err := limiter.CheckProject(ctx, "proj-1"); err != nil || retryAfter != 0 {
+			t.Fatalf("CheckProject() request %d = %v, %v; want allowed", i+1, retryAfter, err)
+		}
+	}
+	retryAfter, err := limiter.CheckProject(ctx, "proj-1")
+	if err != nil || retryAfter <= 0 || retryAfter > 2*time.Hour {
+		t.Fatalf("CheckProject() over limit = %v, %v; want retry within the sliding window", retryAfter, err)
+	}
+
+	// Project counters are kept apart from a token with the same ID
+	if retryAfter, err := limiter.CheckToken(ctx, "proj-1"); err != nil || retryAfter != 0 {
+		t.Errorf("CheckToken() = %v, %v; want allowed", retryAfter, err)
+	}
+
+	limiter.RemoveProjectLimit("proj-1")
+	if retryAfter, err := limiter.CheckProject(ctx, "proj-1"); err != nil || retryAfter != 0 {
+		t.Errorf("CheckProject() after RemoveProjectLimit = %v, %v; want allowed", retryAfter, err)
+	}
+}
+
+func TestRedisRateLimiter_Burst(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRedisRateLimiter(newMockRedisRateLimitClient(), RedisRateLimiterConfig{KeyPrefix: "test:"})
+	limiter.SetTokenRateLimit("token1", TokenRateLimit{MaxRequests: 100, WindowDuration: time.Minute, Burst: 2})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if retryAfter, err := limiter.CheckToken(ctx, "token1"); err != nil || retryAfter != 0 {
+			t.Fatalf("CheckToken() request %d = %v, %v; want allowed", i+1, retryAfter, err)
+		}
+	}
+	retryAfter, err := limiter.CheckToken(ctx, "token1")
+	if err != nil || retryAfter < 1500*time.Millisecond || retryAfter > 2*time.Second {
+		t.Errorf("CheckToken() over burst = %v, %v; want retry after about 1.5s", retryAfter, err)
+	}
+
+	// Burst rejections do not use up the window
+	remaining, err := limiter.GetRemainingRequests(ctx, "token1")
+	if err != nil || remaining != 98 {
+		t.Errorf("GetRemainingRequests() = %d, %v; want 98", remaining, err)
+	}
+
+	now = now.Add(retryAfter)
+	if retryAfter, err := limiter.CheckToken(ctx, "token1"); err != nil || retryAfter != 0 {
+		t.Errorf("CheckToken() after retry = %v, %v; want allowed", retryAfter, err)
+	}
+}
+
+func TestRedisRateLimiter_SlidingWindowBoundary(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRedisRateLimiter(newMockRedisRateLimitClient(), RedisRateLimiterConfig{
+		KeyPrefix:             "test:",
+		DefaultWindowDuration: time.Minute,
+		DefaultMaxRequests:    10,
+	})
+	windowStart := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := windowStart.Add(59 * time.Second)
+	limiter.now = func() time.Time { return now }
+
+	// The whole limit is used at the end of one window...
+	for i := 0; i < 10; i++ {
+		if retryAfter, err := limiter.CheckToken(ctx, "token1"); err != nil || retryAfter != 0 {
+			t.Fatalf("CheckToken() request %d = %v, %v; want allowed", i+1, retryAfter, err)
+		}
+	}
+
+	// ...so a fixed window reset must not allow it again right after the boundary
+	now = windowStart.Add(61 * time.Second)
+	retryAfter, err := limiter.CheckToken(ctx, "token1")
+	if err != nil || retryAfter <= 0 {
+		t.Fatalf("CheckToken() after boundary = %v, %v; want rejected", retryAfter, err)
+	}
+	if remaining, err := limiter.GetRemainingRequests(ctx, "token1"); err != nil || remaining != 0 {
+		t.Errorf("GetRemainingRequests() after boundary = %d, %v; want 0", remaining, err)
+	}
+
+	// The previous window's requests age out as the window slides
+	now = now.Add(retryAfter)
+	if retryAfter, err := limiter.CheckToken(ctx, "token1"); err != nil || retryAfter != 0 {
+		t.Fatalf("CheckToken() at retry time = %v, %v; want allowed", retryAfter, err)
+	}
+	if retryAfter, err := limiter.CheckToken(ctx, "token1"); err != nil || retryAfter <= 0 {
+		t.Errorf("CheckToken() = %v, %v; want rejected until more requests age out", retryAfter, err)
+	}
+
+	// A window later the previous window no longer counts
+	now = windowStart.Add(2 * time.Minute)
+	if remaining, err := limiter.GetRemainingRequests(ctx, "token1"); err != nil || remaining != 9 {
+		t.Errorf("GetRemainingRequests() two windows later = %d, %v; want 9", remaining, err)
+	}
+}
+
+func TestRedisRateLimiter_Unlimited(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRedisRateLimiter(newMockRedisRateLimitClient(), RedisRateLimiterConfig{DefaultWindowDuration: time.Minute})
+
+	for i := 0; i < 5; i++ {
+		if retryAfter, err := limiter.CheckToken(ctx, "token1"); err != nil || retryAfter != 0 {
+			t.Fatalf("CheckToken() = %v, %v; want allowed", retryAfter, err)
+		}
+	}
+	remaining, err := limiter.GetRemainingRequests(ctx, "token1")
+	if err != nil || remaining != UnlimitedRequests {
+		t.Errorf("GetRemainingRequests() = %d, %v; want unlimited", remaining, err)
+	}
+}
+
+func TestRedisRateLimiter_FallbackUsesStoredLimit(t *testing.T) {
+	ctx := context.Background()
+	client := newMockRedisRateLimitClient()
+	client.failIncr = true
+	limiter := NewRedisRateLimiter(client, RedisRateLimiterConfig{
+		DefaultWindowDuration: time.Minute,
+		DefaultMaxRequests:    100,
+		EnableFallback:        true,
+		FallbackRate:          100,
+		FallbackCapacity:      100,
+	})
+	limiter.SetProjectLimit("proj-1", TokenRateLimit{MaxRequests: 60, WindowDuration: time.Minute, Burst: 1})
+
+	if retryAfter, err := limiter.CheckProject(ctx, "proj-1"); err != nil || retryAfter != 0 {
+		t.Fatalf("CheckProject() = %v, %v; want allowed", retryAfter, err)
+	}
+	retryAfter, err := limiter.CheckProject(ctx, "proj-1")
+	if err != nil || retryAfter != time.Second {
+		t.Errorf("CheckProject() over fallback burst = %v, %v; want 1s", retryAfter, err)
+	}
+}
+
+func TestRedisRateLimiter_SetUnchangedLimit(t *testing.T) {
+	limiter := NewRedisRateLimiter(newMockRedisRateLimitClient(), RedisRateLimiterConfig{DefaultWindowDuration: time.Minute})
+	limit := TokenRateLimit{MaxRequests: 10, WindowDuration: time.Minute}
+
+	limiter.SetProjectLimit("proj-1", limit)
+	stored := limiter.projectLimits["proj-1"]
+	limiter.SetProjectLimit("proj-1", limit)
+	if limiter.projectLimits["proj-1"] != stored {
+		t.Error("SetProjectLimit() with an unchanged limit replaced the stored limit")
+	}
+
+	limit.Burst = 2
+	limiter.SetProjectLimit("proj-1", limit)
+	if got := *limiter.projectLimits["proj-1"]; got != limit {
+		t.Errorf("project limit = %+v, want %+v", got, limit)
+	}
+
+	limiter.RemoveTokenLimit("tok-1")
+	limiter.RemoveProjectLimit("proj-1")
+	if _, ok := limiter.projectLimits["proj-1"]; ok {
+		t.Error("RemoveProjectLimit() kept the limit")
+	}
+}
+
+func TestRedisRateLimiter_CheckTokenStatus(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewRedisRateLimiter(newMockRedisRateLimitClient(), RedisRateLimiterConfig{
+		KeyPrefix:             "test:",
+		DefaultWindowDuration: time.Minute,
+		DefaultMaxRequests:    3,
+	})
+	windowStart := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := windowStart.Add(30 * time.Second)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		retryAfter, status, err := limiter.CheckTokenStatus(ctx, "token1")
+		if err != nil || retryAfter != 0 {
+			t.Fatalf("CheckTokenStatus() request %d = %v, %v; want allowed", i+1, retryAfter, err)
+		}
+		want := RateLimitStatus{Limit: 3, Remaining: 2 - i, Reset: windowStart.Add(2 * time.Minute)}
+		if status == nil || *status != want {
+			t.Errorf("CheckTokenStatus() request %d status = %+v, want %+v", i+1, status, want)
+		}
+	}
+	retryAfter, status, err := limiter.CheckTokenStatus(ctx, "token1")
+	if err != nil || retryAfter <= 0 || status == nil || status.Remaining != 0 {
+		t.Errorf("CheckTokenStatus() over limit = %v, %+v, %v; want rejected with none remaining", retryAfter, status, err)
+	}
+
+	// Unlimited projects report no status
+	if _, status, err := limiter.CheckProjectStatus(ctx, "proj-1"); status != nil || err != nil {
+		t.Errorf("CheckProjectStatus() = %+v, %v; want no status", status, err)
+	}
+}
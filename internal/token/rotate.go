@@ -0,0 +1,61 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrTokenRotated is returned when rotating a token that already has a successor
+var ErrTokenRotated = errors.New("token has already been rotated")
+
+// Rotate issues a successor for old and returns it together with old as it
+// should be stored after the rotation. The successor inherits the project,
+// expiry, request limit and count, scopes, metadata and client restrictions
+// of old. Old stays valid for grace and is deactivated right away when grace
+// is zero; it never outlives its original expiry. During grace, requests of
+// old also count against the successor, so the two share one request limit.
+func Rotate(old TokenData, grace time.Duration, now time.Time) (successor TokenData, retired TokenData, err error) {
+	switch {
+	case !old.IsActive:
+		return TokenData{}, TokenData{}, ErrTokenInactive
+	case IsExpired(old.ExpiresAt):
+		return TokenData{}, TokenData{}, ErrTokenExpired
+	case old.RotatedToID != "":
+		return TokenData{}, TokenData{}, ErrTokenRotated
+	case grace < 0:
+		return TokenData{}, TokenData{}, errors.New("grace period cannot be negative")
+	}
+
+	tokenString, err := GenerateToken()
+	if err != nil {
+		return TokenData{}, TokenData{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	successor = TokenData{
+		ID:                 uuid.New().String(),
+		Token:              tokenString,
+		ProjectID:          old.ProjectID,
+		ExpiresAt:          old.ExpiresAt,
+		IsActive:           true,
+		RequestCount:       old.RequestCount,
+		MaxRequests:        old.MaxRequests,
+		CreatedAt:          now,
+		Scopes:             old.Scopes,
+		Metadata:           old.Metadata,
+		ClientRestrictions: old.ClientRestrictions,
+		RateLimit:          old.RateLimit,
+		RotatedFromID:      old.ID,
+	}
+
+	retired = old
+	retired.RotatedToID = successor.ID
+	if grace == 0 {
+		retired.IsActive = false
+		retired.DeactivatedAt = &now
+	} else if graceEnd := now.Add(grace); old.ExpiresAt == nil || graceEnd.Before(*old.ExpiresAt) {
+		retired.ExpiresAt = &graceEnd
+	}
+	return successor, retired, nil
+}
@@ -0,0 +1,177 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRotate(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(24 * time.Hour)
+	maxRequests := 100
+	old := TokenData{
+		ID:                 "old-id",
+		Token:              "sk-old",
+		ProjectID:          "project",
+		ExpiresAt:          &expiresAt,
+		IsActive:           true,
+		RequestCount:       40,
+		MaxRequests:        &maxRequests,
+		Scopes:             Scopes{Models: []string{"gpt-4o*"}},
+		Metadata:           map[string]string{"user_id": "42"},
+		ClientRestrictions: ClientRestrictions{CIDRs: []string{"203.0.113.0/24"}},
+	}
+
+	successor, retired, err := Rotate(old, time.Hour, now)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if err := ValidateTokenFormat(successor.Token); err != nil {
+		t.Errorf("successor token has invalid format: %v", err)
+	}
+	if successor.ID == "" || successor.ID == old.ID {
+		t.Errorf("successor ID = %q, want a new ID", successor.ID)
+	}
+	if successor.RotatedFromID != old.ID || retired.RotatedToID != successor.ID {
+		t.Errorf("rotation links = %q -> %q, want %q -> %q", successor.RotatedFromID, retired.RotatedToID, old.ID, successor.ID)
+	}
+	if successor.ProjectID != old.ProjectID || successor.ExpiresAt != old.ExpiresAt || successor.MaxRequests != old.MaxRequests ||
+		successor.RequestCount != old.RequestCount || !successor.IsActive {
+		t.Errorf("successor = %+v, want project, expiry and limits of %+v", successor, old)
+	}
+	if !reflect.DeepEqual(successor.Scopes, old.Scopes) || !reflect.DeepEqual(successor.Metadata, old.Metadata) ||
+		!reflect.DeepEqual(successor.ClientRestrictions, old.ClientRestrictions) {
+		t.Errorf("successor does not inherit scopes, metadata and client restrictions: %+v", successor)
+	}
+	if !retired.IsActive || retired.ExpiresAt == nil || !retired.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("retired token = %+v, want active until the end of the grace period", retired)
+	}
+
+	// The grace period never extends the original expiry
+	_, retired, err = Rotate(old, 48*time.Hour, now)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if !retired.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("retired expiry = %v, want %v", retired.ExpiresAt, expiresAt)
+	}
+
+	// Tokens without expiry get one
+	noExpiry := old
+	noExpiry.ExpiresAt = nil
+	successor, retired, err = Rotate(noExpiry, time.Hour, now)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if successor.ExpiresAt != nil || retired.ExpiresAt == nil {
+		t.Errorf("expiries = %v, %v, want nil and the end of the grace period", successor.ExpiresAt, retired.ExpiresAt)
+	}
+
+	// Zero grace deactivates the old token right away
+	_, retired, err = Rotate(old, 0, now)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+	if retired.IsActive || retired.DeactivatedAt == nil {
+		t.Errorf("retired token = %+v, want deactivated", retired)
+	}
+}
+
+func TestRotate_Errors(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	tests := []struct {
+		name  string
+		old   TokenData
+		grace time.Duration
+		want  error
+	}{
+		{"inactive", TokenData{ID: "a"}, time.Hour, ErrTokenInactive},
+		{"expired", TokenData{ID: "a", IsActive: true, ExpiresAt: &past}, time.Hour, ErrTokenExpired},
+		{"already rotated", TokenData{ID: "a", IsActive: true, RotatedToID: "b"}, time.Hour, ErrTokenRotated},
+		{"negative grace", TokenData{ID: "a", IsActive: true}, -time.Minute, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Rotate(tt.old, tt.grace, now)
+			if err == nil {
+				t.Fatal("Rotate() expected error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Rotate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// limitedTokenStore is a MockTokenStore that enforces request limits and
+// counts usage by token ID.
+type limitedTokenStore struct {
+	*MockTokenStore
+}
+
+func (s *limitedTokenStore) IncrementTokenUsage(ctx context.Context, tokenString string) error {
+	return s.increment(func(td TokenData) bool { return td.Token == tokenString })
+}
+
+func (s *limitedTokenStore) IncrementTokenUsageByID(ctx context.Context, id string) error {
+	return s.increment(func(td TokenData) bool { return td.ID == id })
+}
+
+func (s *limitedTokenStore) increment(match func(TokenData) bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, td := range s.tokens {
+		if !match(td) {
+			continue
+		}
+		if td.IsRateLimited() {
+			return ErrTokenRateLimit
+		}
+		td.RequestCount++
+		s.tokens[key] = td
+		return nil
+	}
+	return ErrTokenNotFound
+}
+
+func TestRotate_SharesRequestLimitDuringGrace(t *testing.T) {
+	ctx := context.Background()
+	tokenString, err := GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	maxRequests := 3
+	old := TokenData{ID: "old-id", Token: tokenString, ProjectID: "project", IsActive: true, RequestCount: 1, MaxRequests: &maxRequests}
+
+	for name, newValidator := range map[string]func(TokenStore) TokenValidator{
+		"standard": func(s TokenStore) TokenValidator { return NewValidator(s) },
+		"cached":   func(s TokenStore) TokenValidator { return NewCachedValidator(NewValidator(s)) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			successor, retired, err := Rotate(old, time.Hour, time.Now())
+			if err != nil {
+				t.Fatalf("Rotate() error = %v", err)
+			}
+			store := &limitedTokenStore{MockTokenStore: NewMockTokenStore()}
+			store.AddToken(retired.Token, retired)
+			store.AddToken(successor.Token, successor)
+			validator := newValidator(store)
+
+			// Two requests are left, whichever token spends them
+			for _, tok := range []string{retired.Token, retired.Token} {
+				if _, err := validator.ValidateTokenWithTracking(ctx, tok); err != nil {
+					t.Fatalf("ValidateTokenWithTracking() error = %v", err)
+				}
+			}
+			for _, tok := range []string{successor.Token, retired.Token} {
+				if _, err := validator.ValidateTokenWithTracking(ctx, tok); !errors.Is(err, ErrTokenRateLimit) {
+					t.Errorf("ValidateTokenWithTracking() error = %v, want ErrTokenRateLimit", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrTokenDataUnsupported is returned by TokenDataProvider implementations
+// that wrap a validator without access to stored token data.
+var ErrTokenDataUnsupported = errors.New("token data lookup not supported")
+
+// Scopes restrict what a token may do within its project. Each empty field
+// leaves that dimension unrestricted, so the zero value grants full access.
+type Scopes struct {
+	// Endpoints lists allowed request path prefixes (e.g. "/v1/chat/completions")
+	Endpoints []string `json:"endpoints,omitempty"`
+	// Methods lists allowed HTTP methods
+	Methods []string `json:"methods,omitempty"`
+	// Models lists allowed model glob patterns (e.g. "gpt-4o-*")
+	Models []string `json:"models,omitempty"`
+}
+
+// IsEmpty returns true if the scopes do not restrict anything
+func (s Scopes) IsEmpty() bool {
+	return len(s.Endpoints) == 0 && len(s.Methods) == 0 && len(s.Models) == 0
+}
+
+// Normalize returns a copy with trimmed entries and upper-cased methods
+func (s Scopes) Normalize() Scopes {
+	trim := func(in []string, fn func(string) string) []string {
+		var out []string
+		for _, v := range in {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, fn(v))
+			}
+		}
+		return out
+	}
+	same := func(v string) string { return v }
+	return Scopes{
+		Endpoints: trim(s.Endpoints, same),
+		Methods:   trim(s.Methods, strings.ToUpper),
+		Models:    trim(s.Models, same),
+	}
+}
+
+// Validate checks that endpoints are absolute paths, methods are known HTTP
+// methods and model patterns are valid globs.
+func (s Scopes) Validate() error {
+	for _, endpoint := range s.Endpoints {
+		if !strings.HasPrefix(endpoint, "/") {
+			return fmt.Errorf("endpoint '%s' must start with '/'", endpoint)
+		}
+	}
+	for _, method := range s.Methods {
+		switch strings.ToUpper(method) {
+		case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+			http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		default:
+			return fmt.Errorf("method '%s' is not supported", method)
+		}
+	}
+	for _, model := range s.Models {
+		if strings.TrimSpace(model) == "" {
+			return errors.New("model pattern cannot be empty")
+		}
+		if _, err := path.Match(model, ""); err != nil {
+			return fmt.Errorf("model pattern '%s' is invalid: %w", model, err)
+		}
+	}
+	return nil
+}
+
+// AllowsEndpoint reports whether the request path matches an endpoint prefix.
+// Prefixes match whole path segments only, so "/v1/chat" allows
+// "/v1/chat/completions" but not "/v1/chatx".
+func (s Scopes) AllowsEndpoint(requestPath string) bool {
+	if len(s.Endpoints) == 0 {
+		return true
+	}
+	for _, endpoint := range s.Endpoints {
+		if hasPathPrefix(requestPath, endpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether prefix matches p up to a path segment boundary
+func hasPathPrefix(p, prefix string) bool {
+	if !strings.HasPrefix(p, prefix) {
+		return false
+	}
+	return len(p) == len(prefix) || strings.HasSuffix(prefix, "/") || p[len(prefix)] == '/'
+}
+
+// AllowsMethod reports whether the HTTP method is allowed
+func (s Scopes) AllowsMethod(method string) bool {
+	if len(s.Methods) == 0 {
+		return true
+	}
+	for _, allowed := range s.Methods {
+		if strings.EqualFold(method, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsModel reports whether the model matches one of the model patterns
+func (s Scopes) AllowsModel(model string) bool {
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, pattern := range s.Models {
+		if ok, _ := path.Match(pattern, model); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Within reports whether every restriction of s is allowed by parent, so a
+// token with scopes s cannot reach anything a token with parent cannot.
+// Dimensions s leaves open are inherited from parent (see Inherit).
+func (s Scopes) Within(parent Scopes) bool {
+	for _, endpoint := range s.Endpoints {
+		if !parent.AllowsEndpoint(endpoint) {
+			return false
+		}
+	}
+	for _, method := range s.Methods {
+		if !parent.AllowsMethod(method) {
+			return false
+		}
+	}
+	for _, model := range s.Models {
+		if !parent.allowsModelPattern(model) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsModelPattern reports whether every model matched by pattern is
+// allowed. Patterns are not compared as globs: a pattern is allowed when it
+// is one of s's own patterns, or a literal model name s allows.
+func (s Scopes) allowsModelPattern(pattern string) bool {
+	if len(s.Models) == 0 {
+		return true
+	}
+	for _, own := range s.Models {
+		if own == pattern {
+			return true
+		}
+	}
+	return !strings.ContainsAny(pattern, `*?[\`) && s.AllowsModel(pattern)
+}
+
+// Inherit returns s with each open dimension taken from parent
+func (s Scopes) Inherit(parent Scopes) Scopes {
+	if len(s.Endpoints) == 0 {
+		s.Endpoints = parent.Endpoints
+	}
+	if len(s.Methods) == 0 {
+		s.Methods = parent.Methods
+	}
+	if len(s.Models) == 0 {
+		s.Models = parent.Models
+	}
+	return s
+}
+
+// TokenDataProvider is implemented by validators that can return the stored
+// data of a token, e.g. to enforce its scopes after validation.
+type TokenDataProvider interface {
+	// GetTokenData returns the data of a token by its token string
+	GetTokenData(ctx context.Context, tokenString string) (TokenData, error)
+}
+
+// GetTokenData returns the stored data of a token by its token string. Child
+// tokens are verified and derived from their parent instead.
+func (v *StandardValidator) GetTokenData(ctx context.Context, tokenString string) (TokenData, error) {
+	if IsChildToken(tokenString) {
+		return v.validateChildToken(ctx, tokenString)
+	}
+	tokenData, err := v.store.GetTokenByToken(ctx, tokenString)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return TokenData{}, ErrTokenNotFound
+		}
+		return TokenData{}, fmt.Errorf("failed to retrieve token: %w", err)
+	}
+	return tokenData, nil
+}
+
+// GetTokenData returns the token data from the cache, falling back to the
+// underlying validator. Lookups do not count towards cache statistics.
+func (cv *CachedValidator) GetTokenData(ctx context.Context, tokenString string) (TokenData, error) {
+	cv.cacheMutex.RLock()
+	entry, found := cv.cache[tokenString]
+	cv.cacheMutex.RUnlock()
+	if found && !time.Now().After(entry.ValidUntil) {
+		return entry.Data, nil
+	}
+
+	provider, ok := cv.validator.(TokenDataProvider)
+	if !ok {
+		return TokenData{}, ErrTokenDataUnsupported
+	}
+	return provider.GetTokenData(ctx, tokenString)
+}
@@ -0,0 +1,104 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScopes_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  Scopes
+		wantErr bool
+	}{
+		{"empty", Scopes{}, false},
+		{"valid", Scopes{Endpoints: []string{"/v1/chat/completions"}, Methods: []string{"post"}, Models: []string{"gpt-4o-*"}}, false},
+		{"relative endpoint", Scopes{Endpoints: []string{"v1/files"}}, true},
+		{"unknown method", Scopes{Methods: []string{"FETCH"}}, true},
+		{"blank model", Scopes{Models: []string{" "}}, true},
+		{"bad glob", Scopes{Models: []string{"gpt-[4"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.scopes.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScopes_Normalize(t *testing.T) {
+	got := Scopes{Endpoints: []string{" /v1/chat ", ""}, Methods: []string{"post"}}.Normalize()
+	want := Scopes{Endpoints: []string{"/v1/chat"}, Methods: []string{"POST"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Normalize() = %#v, want %#v", got, want)
+	}
+	if !(Scopes{Models: []string{" "}}).Normalize().IsEmpty() {
+		t.Error("Normalize() should drop blank entries")
+	}
+}
+
+func TestScopes_Allows(t *testing.T) {
+	var unrestricted Scopes
+	if !unrestricted.AllowsEndpoint("/v1/files") || !unrestricted.AllowsMethod("DELETE") || !unrestricted.AllowsModel("gpt-4o") {
+		t.Error("empty scopes should allow everything")
+	}
+
+	s := Scopes{
+		Endpoints: []string{"/v1/chat/completions"},
+		Methods:   []string{"POST"},
+		Models:    []string{"gpt-4o-mini", "claude-3-5-*"},
+	}
+	if !s.AllowsEndpoint("/v1/chat/completions") || s.AllowsEndpoint("/v1/files") {
+		t.Error("AllowsEndpoint() should match path prefixes only")
+	}
+	prefix := Scopes{Endpoints: []string{"/v1/chat"}}
+	if !prefix.AllowsEndpoint("/v1/chat") || !prefix.AllowsEndpoint("/v1/chat/completions") || prefix.AllowsEndpoint("/v1/chatx") {
+		t.Error("AllowsEndpoint() should match at path segment boundaries")
+	}
+	if !s.AllowsMethod("post") || s.AllowsMethod("GET") {
+		t.Error("AllowsMethod() should match methods case-insensitively")
+	}
+	if !s.AllowsModel("claude-3-5-haiku-latest") || s.AllowsModel("gpt-4o") {
+		t.Error("AllowsModel() should match model globs")
+	}
+}
+
+func TestCachedValidator_GetTokenData(t *testing.T) {
+	ctx := context.Background()
+	store := newCountingStore()
+	tok, _ := GenerateToken()
+	future := time.Now().Add(time.Hour)
+	scopes := Scopes{Methods: []string{"POST"}}
+	store.tokens[tok] = TokenData{Token: tok, ProjectID: "p1", IsActive: true, ExpiresAt: &future, Scopes: scopes}
+
+	cv := NewCachedValidator(NewValidator(store), CacheOptions{TTL: time.Minute, MaxSize: 10})
+	if _, err := cv.ValidateToken(ctx, tok); err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	calls := store.getByTokenCalls
+
+	td, err := cv.GetTokenData(ctx, tok)
+	if err != nil {
+		t.Fatalf("GetTokenData() error = %v", err)
+	}
+	if !reflect.DeepEqual(td.Scopes, scopes) {
+		t.Errorf("GetTokenData() scopes = %#v, want %#v", td.Scopes, scopes)
+	}
+	if store.getByTokenCalls != calls {
+		t.Errorf("GetTokenData() should be served from the cache, store calls = %d, want %d", store.getByTokenCalls, calls)
+	}
+
+	other, _ := GenerateToken()
+	if _, err := cv.GetTokenData(ctx, other); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("GetTokenData() error = %v, want ErrTokenNotFound", err)
+	}
+
+	wrapped := NewCachedValidator(NewMockValidator(), CacheOptions{TTL: time.Minute, MaxSize: 10})
+	if _, err := wrapped.GetTokenData(ctx, tok); !errors.Is(err, ErrTokenDataUnsupported) {
+		t.Errorf("GetTokenData() error = %v, want ErrTokenDataUnsupported", err)
+	}
+}
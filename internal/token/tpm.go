@@ -0,0 +1,333 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrTPMLimitExceeded is returned when a request would exceed a tokens per minute limit
+var ErrTPMLimitExceeded = errors.New("tokens per minute limit exceeded")
+
+// TPM limit scopes
+const (
+	TPMScopeGlobal  = "global"
+	TPMScopeProject = "project"
+	TPMScopeToken   = "token"
+)
+
+// tpmWindow is the fixed window TPM limits are counted in.
+const tpmWindow = time.Minute
+
+// TPMLimitError reports which tokens per minute limit a request would exceed.
+type TPMLimitError struct {
+	Scope      string        // TPMScopeGlobal, TPMScopeProject or TPMScopeToken
+	Limit      int64         // Tokens per minute allowed in the scope
+	RetryAfter time.Duration // Time until the current window ends
+}
+
+func (e *TPMLimitError) Error() string {
+	return fmt.Sprintf("%s tokens per minute limit of %d exceeded", e.Scope, e.Limit)
+}
+
+// Unwrap makes TPMLimitError match ErrTPMLimitExceeded.
+func (e *TPMLimitError) Unwrap() error {
+	return ErrTPMLimitExceeded
+}
+
+// TPMStore counts LLM tokens per key for the TPM limiter.
+type TPMStore interface {
+	// IncrBy adds n to the count of key and returns the new count. Keys
+	// expire ttl after they were last changed.
+	IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error)
+	// Get returns the count of key, or 0 when it does not exist.
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+// TPMLimits are the LLM tokens per minute allowed in each scope; 0 means unlimited.
+type TPMLimits struct {
+	Global     int64 // Over all requests
+	PerProject int64 // Per project
+	PerToken   int64 // Per token
+}
+
+// TPMLimiterConfig contains configuration for the TPM limiter
+type TPMLimiterConfig struct {
+	// KeyPrefix is the prefix for all keys used by the limiter
+	KeyPrefix string
+	// KeyHashSecret is the HMAC secret for hashing token IDs in keys (optional)
+	KeyHashSecret []byte
+	// Limits are the default limits
+	Limits TPMLimits
+	// EnableFallback counts in memory while the store is failing instead of
+	// returning its errors
+	EnableFallback bool
+}
+
+// TPMLimiter limits the LLM tokens (input plus output) spent per minute,
+// globally, per project and per token. Requests reserve an estimate of their
+// tokens up front; the reservation is reconciled with the actual usage once
+// the response is complete. Counts use fixed one-minute windows in a
+// TPMStore, which is shared between instances when backed by Redis.
+type TPMLimiter struct {
+	store    TPMStore
+	fallback *MemoryTPMStore
+	config   TPMLimiterConfig
+
+	now func() time.Time
+}
+
+// NewTPMLimiter creates a TPM limiter counting in store.
+func NewTPMLimiter(store TPMStore, config TPMLimiterConfig) *TPMLimiter {
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "tpm:"
+	}
+	l := &TPMLimiter{
+		store:  store,
+		config: config,
+		now:    time.Now,
+	}
+	if config.EnableFallback {
+		l.fallback = NewMemoryTPMStore()
+	}
+	return l
+}
+
+type tpmScope struct {
+	scope string
+	id    string
+	limit int64
+}
+
+// scopes returns the limited scopes of a request.
+func (l *TPMLimiter) scopes(projectID, tokenID string) []tpmScope {
+	var scopes []tpmScope
+	if l.config.Limits.Global > 0 {
+		scopes = append(scopes, tpmScope{scope: TPMScopeGlobal, limit: l.config.Limits.Global})
+	}
+	if projectID != "" && l.config.Limits.PerProject > 0 {
+		scopes = append(scopes, tpmScope{scope: TPMScopeProject, id: projectID, limit: l.config.Limits.PerProject})
+	}
+	if tokenID != "" && l.config.Limits.PerToken > 0 {
+		scopes = append(scopes, tpmScope{scope: TPMScopeToken, id: tokenID, limit: l.config.Limits.PerToken})
+	}
+	return scopes
+}
+
+// buildKey constructs the key of a scope's count in the window starting at windowStart.
+func (l *TPMLimiter) buildKey(s tpmScope, windowStart int64) string {
+	id := s.id
+	if s.scope == TPMScopeToken && len(l.config.KeyHashSecret) > 0 {
+		id = hashTokenID(id, l.config.KeyHashSecret)
+	}
+	return fmt.Sprintf("%s%s:%s:%d", l.config.KeyPrefix, s.scope, id, windowStart)
+}
+
+// incrBy adds n to key, counting in the fallback store when the store fails.
+func (l *TPMLimiter) incrBy(ctx context.Context, key string, n int64) (int64, error) {
+	// Keys outlive their window so late reconciliations still find them
+	ttl := 2 * tpmWindow
+	count, err := l.store.IncrBy(ctx, key, n, ttl)
+	if err != nil && l.fallback != nil {
+		return l.fallback.IncrBy(ctx, key, n, ttl)
+	}
+	return count, err
+}
+
+// Reserve reserves tokens for a request of a project and token, either of
+// which may be empty. It returns a *TPMLimitError when a limit would be
+// exceeded, and a nil reservation when no limit applies.
+func (l *TPMLimiter) Reserve(ctx context.Context, projectID, tokenID string, tokens int64) (*TPMReservation, error) {
+	scopes := l.scopes(projectID, tokenID)
+	if len(scopes) == 0 {
+		return nil, nil
+	}
+	if tokens < 0 {
+		tokens = 0
+	}
+
+	now := l.now()
+	windowStart := now.Truncate(tpmWindow)
+	res := &TPMReservation{limiter: l, counted: tokens}
+	for _, s := range scopes {
+		key := l.buildKey(s, windowStart.Unix())
+		count, err := l.incrBy(ctx, key, tokens)
+		if err != nil {
+			l.rollback(res.keys, tokens)
+			return nil, fmt.Errorf("failed to reserve tokens: %w", err)
+		}
+		res.keys = append(res.keys, key)
+		if count > s.limit {
+			l.rollback(res.keys, tokens)
+			return nil, &TPMLimitError{Scope: s.scope, Limit: s.limit, RetryAfter: windowStart.Add(tpmWindow).Sub(now)}
+		}
+	}
+	return res, nil
+}
+
+// rollback takes back tokens reserved on keys.
+func (l *TPMLimiter) rollback(keys []string, tokens int64) {
+	if tokens == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	for _, key := range keys {
+		_, _ = l.incrBy(ctx, key, -tokens)
+	}
+}
+
+// Remaining returns the tokens left in the current window of the most
+// constrained limit of a project and token, and false when no limit applies.
+func (l *TPMLimiter) Remaining(ctx context.Context, projectID, tokenID string) (int64, bool, error) {
+	scopes := l.scopes(projectID, tokenID)
+	if len(scopes) == 0 {
+		return 0, false, nil
+	}
+	windowStart := l.now().Truncate(tpmWindow).Unix()
+	var remaining int64 = -1
+	for _, s := range scopes {
+		key := l.buildKey(s, windowStart)
+		count, err := l.store.Get(ctx, key)
+		if err != nil {
+			if l.fallback == nil {
+				return 0, false, fmt.Errorf("failed to get token count: %w", err)
+			}
+			count, _ = l.fallback.Get(ctx, key)
+		}
+		left := max(s.limit-count, 0)
+		if remaining < 0 || left < remaining {
+			remaining = left
+		}
+	}
+	return remaining, true, nil
+}
+
+// TPMReservation holds the tokens a request counts against TPM limits.
+type TPMReservation struct {
+	limiter *TPMLimiter
+	keys    []string
+
+	mu      sync.Mutex
+	counted int64
+}
+
+// Reserved returns the tokens the request currently counts.
+func (r *TPMReservation) Reserved() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.counted
+}
+
+// Reconcile makes the request count actual tokens instead of its reservation,
+// e.g. its usage once the response is complete, or 0 when it failed. It may be
+// called more than once; the last call wins.
+func (r *TPMReservation) Reconcile(ctx context.Context, actual int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delta := actual - r.counted
+	if delta == 0 {
+		return nil
+	}
+	for _, key := range r.keys {
+		if _, err := r.limiter.incrBy(ctx, key, delta); err != nil {
+			return fmt.Errorf("failed to reconcile tokens: %w", err)
+		}
+	}
+	r.counted = actual
+	return nil
+}
+
+type memoryTPMEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// MemoryTPMStore counts tokens in process memory.
+type MemoryTPMStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryTPMEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryTPMStore creates an in-memory TPM store.
+func NewMemoryTPMStore() *MemoryTPMStore {
+	return &MemoryTPMStore{entries: make(map[string]*memoryTPMEntry), now: time.Now}
+}
+
+// IncrBy implements TPMStore. Expired keys are swept once per window.
+func (s *MemoryTPMStore) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastSweep) >= tpmWindow {
+		for k, e := range s.entries {
+			if !now.Before(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expiresAt) {
+		e = &memoryTPMEntry{}
+		s.entries[key] = e
+	}
+	e.count += n
+	e.expiresAt = now.Add(ttl)
+	return e.count, nil
+}
+
+// Get implements TPMStore.
+func (s *MemoryTPMStore) Get(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || !s.now().Before(e.expiresAt) {
+		return 0, nil
+	}
+	return e.count, nil
+}
+
+// RedisTPMStore counts tokens in Redis so that all proxy instances share
+// TPM limits.
+type RedisTPMStore struct {
+	client *redis.Client
+}
+
+// NewRedisTPMStore creates a TPM store on client.
+func NewRedisTPMStore(client *redis.Client) *RedisTPMStore {
+	return &RedisTPMStore{client: client}
+}
+
+// IncrBy implements TPMStore.
+func (s *RedisTPMStore) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	var count *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.IncrBy(ctx, key, n)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// Get implements TPMStore.
+func (s *RedisTPMStore) Get(ctx context.Context, key string) (int64, error) {
+	val, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
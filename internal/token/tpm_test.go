@@ -0,0 +1,148 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// failingTPMStore fails every operation
+type failingTPMStore struct{}
+
+func (failingTPMStore) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	return 0, errors.New("store down")
+}
+
+func (failingTPMStore) Get(ctx context.Context, key string) (int64, error) {
+	return 0, errors.New("store down")
+}
+
+func newTestTPMLimiter(store TPMStore, limits TPMLimits) *TPMLimiter {
+	l := NewTPMLimiter(store, TPMLimiterConfig{Limits: limits})
+	now := time.Date(2026, 1, 1, 12, 0, 15, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	return l
+}
+
+func TestTPMLimiter_Reserve(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stores := map[string]TPMStore{
+		"memory": NewMemoryTPMStore(),
+		"redis":  NewRedisTPMStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})),
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			l := newTestTPMLimiter(store, TPMLimits{PerProject: 1000, PerToken: 600})
+
+			res, err := l.Reserve(ctx, "proj-1", "tok-1", 500)
+			if err != nil || res == nil {
+				t.Fatalf("Reserve() = %v, %v; want reservation", res, err)
+			}
+			_, err = l.Reserve(ctx, "proj-1", "tok-1", 200)
+			var limitErr *TPMLimitError
+			if !errors.As(err, &limitErr) || !errors.Is(err, ErrTPMLimitExceeded) {
+				t.Fatalf("Reserve() error = %v, want TPM limit error", err)
+			}
+			if limitErr.Scope != TPMScopeToken || limitErr.Limit != 600 {
+				t.Errorf("limit error = %+v, want token scope with limit 600", limitErr)
+			}
+			if limitErr.RetryAfter != 45*time.Second {
+				t.Errorf("RetryAfter = %v, want 45s", limitErr.RetryAfter)
+			}
+
+			// The rejected request must not count against the project
+			if _, err := l.Reserve(ctx, "proj-1", "tok-2", 500); err != nil {
+				t.Fatalf("Reserve() for another token error = %v", err)
+			}
+			if _, err := l.Reserve(ctx, "proj-1", "tok-3", 1); !errors.As(err, &limitErr) || limitErr.Scope != TPMScopeProject {
+				t.Fatalf("Reserve() error = %v, want project limit error", err)
+			}
+
+			// Actual usage below the estimate frees tokens for the next request
+			if err := res.Reconcile(ctx, 100); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if got := res.Reserved(); got != 100 {
+				t.Errorf("Reserved() = %d, want 100", got)
+			}
+			remaining, limited, err := l.Remaining(ctx, "proj-1", "tok-1")
+			if err != nil || !limited || remaining != 400 {
+				t.Errorf("Remaining() = %d, %v, %v; want 400, true, nil", remaining, limited, err)
+			}
+			if _, err := l.Reserve(ctx, "proj-1", "tok-1", 300); err != nil {
+				t.Errorf("Reserve() after reconcile error = %v", err)
+			}
+		})
+	}
+}
+
+func TestTPMLimiter_Windows(t *testing.T) {
+	ctx := context.Background()
+	l := newTestTPMLimiter(NewMemoryTPMStore(), TPMLimits{Global: 100})
+	if _, err := l.Reserve(ctx, "", "", 100); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	if _, err := l.Reserve(ctx, "", "", 1); !errors.Is(err, ErrTPMLimitExceeded) {
+		t.Fatalf("Reserve() error = %v, want limit exceeded", err)
+	}
+	next := l.now().Add(time.Minute)
+	l.now = func() time.Time { return next }
+	if _, err := l.Reserve(ctx, "", "", 100); err != nil {
+		t.Errorf("Reserve() in next window error = %v", err)
+	}
+}
+
+func TestTPMLimiter_NoLimits(t *testing.T) {
+	l := newTestTPMLimiter(NewMemoryTPMStore(), TPMLimits{})
+	res, err := l.Reserve(context.Background(), "proj-1", "tok-1", 1_000_000)
+	if res != nil || err != nil {
+		t.Errorf("Reserve() = %v, %v; want nil, nil", res, err)
+	}
+	if _, limited, _ := l.Remaining(context.Background(), "proj-1", "tok-1"); limited {
+		t.Error("Remaining() reports a limit")
+	}
+}
+
+func TestTPMLimiter_KeyHashing(t *testing.T) {
+	mr := miniredis.RunT(t)
+	l := NewTPMLimiter(NewRedisTPMStore(redis.NewClient(&redis.Options{Addr: mr.Addr()})), TPMLimiterConfig{
+		KeyPrefix:     "llm:tpm:",
+		KeyHashSecret: []byte("secret"),
+		Limits:        TPMLimits{PerToken: 100},
+	})
+	if _, err := l.Reserve(context.Background(), "", "sk-secret-token", 10); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+	keys := mr.Keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], "llm:tpm:token:") || strings.Contains(keys[0], "sk-secret-token") {
+		t.Errorf("keys = %v, want one hashed token key", keys)
+	}
+	if ttl := mr.TTL(keys[0]); ttl <= 0 {
+		t.Errorf("TTL = %v, want keys to expire", ttl)
+	}
+}
+
+func TestTPMLimiter_StoreFailure(t *testing.T) {
+	ctx := context.Background()
+	l := NewTPMLimiter(failingTPMStore{}, TPMLimiterConfig{Limits: TPMLimits{Global: 100}})
+	if _, err := l.Reserve(ctx, "", "", 10); err == nil || errors.Is(err, ErrTPMLimitExceeded) {
+		t.Errorf("Reserve() error = %v, want store error", err)
+	}
+
+	l = NewTPMLimiter(failingTPMStore{}, TPMLimiterConfig{Limits: TPMLimits{Global: 100}, EnableFallback: true})
+	if _, err := l.Reserve(ctx, "", "", 80); err != nil {
+		t.Fatalf("Reserve() with fallback error = %v", err)
+	}
+	if _, err := l.Reserve(ctx, "", "", 30); !errors.Is(err, ErrTPMLimitExceeded) {
+		t.Errorf("Reserve() with fallback error = %v, want limit exceeded", err)
+	}
+	if remaining, _, err := l.Remaining(ctx, "", ""); err != nil || remaining != 20 {
+		t.Errorf("Remaining() = %d, %v; want 20, nil", remaining, err)
+	}
+}
@@ -103,7 +103,10 @@ func ExtractTokenFromHeader(header string) (string, bool) {
 		return "", false
 	}
 
-	// Validate the token format
+	// Validate the token format; child tokens are checked when they are verified
+	if IsChildToken(token) {
+		return token, true
+	}
 	if err := ValidateTokenFormat(token); err != nil {
 		return "", false
 	}
@@ -173,8 +176,14 @@ func TruncateToken(token string, showChars int) string {
 	return prefix + "..." + suffix
 }
 
-// ObfuscateToken partially obfuscates a token for display purposes
-func ObfuscateToken(token string) string { return obfuscate.ObfuscateTokenByPrefix(token, TokenPrefix) }
+// ObfuscateToken partially obfuscates a token for display purposes. Child
+// tokens keep only their prefix and the last characters of the signature.
+func ObfuscateToken(token string) string {
+	if IsChildToken(token) && len(token) > len(ChildTokenPrefix)+8 {
+		return ChildTokenPrefix + "****" + token[len(token)-4:]
+	}
+	return obfuscate.ObfuscateTokenByPrefix(token, TokenPrefix)
+}
 
 // TokenInfo represents information about a token for display purposes
 type TokenInfo struct {
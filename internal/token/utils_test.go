@@ -84,6 +84,12 @@ func TestExtractTokenFromHeader(t *testing.T) {
 			wantToken:   validToken,
 			wantSuccess: true,
 		},
+		{
+			name:        "Child token",
+			header:      "Bearer sk-child.eyJhbGciOiJIUzI1NiJ9.e30.c2ln",
+			wantToken:   "sk-child.eyJhbGciOiJIUzI1NiJ9.e30.c2ln",
+			wantSuccess: true,
+		},
 		{
 			name:        "Invalid token format",
 			header:      "Bearer invalidtoken",
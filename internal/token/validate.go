@@ -49,19 +49,39 @@ type TokenStore interface {
 	GetTokensByProjectID(ctx context.Context, projectID string) ([]TokenData, error)
 }
 
+// UsageByIDStore is implemented by token stores that can count a request
+// against a token by its ID. Requests of rotated tokens are counted against
+// their successor this way, so the two share one request limit.
+type UsageByIDStore interface {
+	// IncrementTokenUsageByID increments the usage count of the token with the given ID
+	IncrementTokenUsageByID(ctx context.Context, id string) error
+}
+
 // TokenData represents the data associated with a token
 type TokenData struct {
-	ID            string     // The token ID (UUID) - used for management operations
-	Token         string     // The token string (sk-...) - used for authentication
-	ProjectID     string     // The associated project ID
-	ExpiresAt     *time.Time // When the token expires (nil for no expiration)
-	IsActive      bool       // Whether the token is active
-	DeactivatedAt *time.Time // When the token was deactivated (nil if not deactivated)
-	RequestCount  int        // Number of requests made with this token
-	MaxRequests   *int       // Maximum number of requests allowed (nil for unlimited)
-	CreatedAt     time.Time  // When the token was created
-	LastUsedAt    *time.Time // When the token was last used (nil if never used)
-	CacheHitCount int        // Number of cache hits for this token
+	ID            string            // The token ID (UUID) - used for management operations
+	Token         string            // The token string (sk-...) - used for authentication
+	ProjectID     string            // The associated project ID
+	ExpiresAt     *time.Time        // When the token expires (nil for no expiration)
+	IsActive      bool              // Whether the token is active
+	DeactivatedAt *time.Time        // When the token was deactivated (nil if not deactivated)
+	RequestCount  int               // Number of requests made with this token
+	MaxRequests   *int              // Maximum number of requests allowed (nil for unlimited)
+	CreatedAt     time.Time         // When the token was created
+	LastUsedAt    *time.Time        // When the token was last used (nil if never used)
+	CacheHitCount int               // Number of cache hits for this token
+	Scopes        Scopes            // Endpoint, method and model restrictions (zero value is unrestricted)
+	Metadata      map[string]string // Free-form key/value pairs, e.g. the end user the token was issued for
+	// ClientRestrictions limit the networks and origins the token may be used from
+	ClientRestrictions ClientRestrictions
+	// RateLimit limits the token's request rate (zero value uses the limiter's default)
+	RateLimit RateLimit
+	// ParentID is the ID of the token a signed child token was derived from (empty for stored tokens)
+	ParentID string
+	// RotatedFromID is the ID of the token this token replaced by rotation (empty if none)
+	RotatedFromID string
+	// RotatedToID is the ID of the token that replaced this token by rotation (empty if not rotated)
+	RotatedToID string
 }
 
 // IsValid returns true if the token is active, not expired, and not rate limited
@@ -80,6 +100,15 @@ func (t *TokenData) IsRateLimited() bool {
 	return t.RequestCount >= *t.MaxRequests
 }
 
+// RemainingRequests returns the number of requests the token has left and
+// false if the token has no request limit
+func (t *TokenData) RemainingRequests() (int, bool) {
+	if t.MaxRequests == nil || *t.MaxRequests <= 0 {
+		return 0, false
+	}
+	return max(*t.MaxRequests-t.RequestCount, 0), true
+}
+
 // ValidateTokenFormat checks if a token has the correct format
 func (t *TokenData) ValidateFormat() error {
 	return ValidateTokenFormat(t.Token)
@@ -89,6 +118,7 @@ func (t *TokenData) ValidateFormat() error {
 type StandardValidator struct {
 	store         TokenStore
 	usageStatsAgg atomic.Pointer[UsageStatsAggregator]
+	children      atomic.Pointer[childTokens]
 }
 
 type usageStatsAggregatorGetter interface {
@@ -115,6 +145,10 @@ func (v *StandardValidator) usageStatsAggregator() *UsageStatsAggregator {
 }
 
 func (v *StandardValidator) validateTokenData(ctx context.Context, tokenString string) (TokenData, error) {
+	if IsChildToken(tokenString) {
+		return v.validateChildToken(ctx, tokenString)
+	}
+
 	// First validate the token format
 	if err := ValidateTokenFormat(tokenString); err != nil {
 		return TokenData{}, fmt.Errorf("invalid token format: %w", err)
@@ -171,6 +205,14 @@ func (v *StandardValidator) ValidateTokenWithTracking(ctx context.Context, token
 		return "", ErrTokenRateLimit
 	}
 
+	// Child tokens are counted in memory, never in the store.
+	if IsChildToken(tokenString) {
+		if err := v.trackChildUsage(tokenData); err != nil {
+			return "", err
+		}
+		return tokenData.ProjectID, nil
+	}
+
 	// For unlimited tokens, move request_count/last_used_at updates off the hot path.
 	if tokenData.MaxRequests == nil || *tokenData.MaxRequests <= 0 {
 		if agg := v.usageStatsAgg.Load(); agg != nil {
@@ -179,6 +221,14 @@ func (v *StandardValidator) ValidateTokenWithTracking(ctx context.Context, token
 		}
 	}
 
+	// A rotated token shares the request limit of its successor during the
+	// grace period, so each request also counts against the successor.
+	if tokenData.RotatedToID != "" && tokenData.MaxRequests != nil && *tokenData.MaxRequests > 0 {
+		if err := v.trackSuccessorUsage(ctx, tokenData.RotatedToID); err != nil {
+			return "", err
+		}
+	}
+
 	// Limited tokens (or no async aggregator configured): do synchronous tracking.
 	if err := v.store.IncrementTokenUsage(ctx, tokenString); err != nil {
 		if errors.Is(err, ErrTokenRateLimit) {
@@ -190,6 +240,22 @@ func (v *StandardValidator) ValidateTokenWithTracking(ctx context.Context, token
 	return tokenData.ProjectID, nil
 }
 
+// trackSuccessorUsage counts a request of a rotated token against its
+// successor, rejecting it once the successor has used up its requests.
+func (v *StandardValidator) trackSuccessorUsage(ctx context.Context, successorID string) error {
+	store, ok := v.store.(UsageByIDStore)
+	if !ok {
+		return nil
+	}
+	if err := store.IncrementTokenUsageByID(ctx, successorID); err != nil {
+		if errors.Is(err, ErrTokenRateLimit) || errors.Is(err, ErrTokenInactive) || errors.Is(err, ErrTokenExpired) {
+			return err
+		}
+		return fmt.Errorf("failed to track successor token usage: %w", err)
+	}
+	return nil
+}
+
 // ValidateTokenFormat checks if a token string has the correct format
 func ValidateToken(ctx context.Context, validator TokenValidator, tokenID string) (string, error) {
 	return validator.ValidateToken(ctx, tokenID)
@@ -215,6 +215,29 @@ func TestTokenDataIsValid(t *testing.T) {
 	}
 }
 
+func TestTokenData_RemainingRequests(t *testing.T) {
+	zero, ten := 0, 10
+	tests := []struct {
+		name          string
+		data          TokenData
+		wantRemaining int
+		wantLimited   bool
+	}{
+		{"no limit", TokenData{RequestCount: 5}, 0, false},
+		{"zero limit is unlimited", TokenData{MaxRequests: &zero, RequestCount: 5}, 0, false},
+		{"within limit", TokenData{MaxRequests: &ten, RequestCount: 4}, 6, true},
+		{"over limit", TokenData{MaxRequests: &ten, RequestCount: 12}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, limited := tt.data.RemainingRequests()
+			if remaining != tt.wantRemaining || limited != tt.wantLimited {
+				t.Errorf("RemainingRequests() = %d, %v, want %d, %v", remaining, limited, tt.wantRemaining, tt.wantLimited)
+			}
+		})
+	}
+}
+
 func TestStandardValidator_ValidateToken(t *testing.T) {
 	ctx := context.Background()
 	store := NewMockTokenStore()
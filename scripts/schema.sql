@@ -17,6 +17,68 @@ CREATE TABLE IF NOT EXISTS projects (
 -- Create index on project name
 CREATE INDEX IF NOT EXISTS idx_projects_name ON projects(name);
 
+-- Per-provider upstream API keys (one per provider in APIConfig).
+-- projects.api_key remains the default-provider fallback.
+CREATE TABLE IF NOT EXISTS project_api_keys (
+    project_id TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    api_key TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, provider),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- Upstream key pools; a provider's pool replaces its single key ('' pools projects.api_key).
+CREATE TABLE IF NOT EXISTS project_api_key_pools (
+    project_id TEXT NOT NULL,
+    provider TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL,
+    api_key TEXT NOT NULL,
+    weight INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, provider, position),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- Per-project request policy rules, stored as a JSON array.
+CREATE TABLE IF NOT EXISTS project_request_policies (
+    project_id TEXT PRIMARY KEY,
+    rules TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- Per-project model allowlists; each row is a glob pattern.
+CREATE TABLE IF NOT EXISTS project_allowed_models (
+    project_id TEXT NOT NULL,
+    model TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, model),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- Per-project client allowlists; kind is 'cidr' or 'origin'.
+CREATE TABLE IF NOT EXISTS project_client_restrictions (
+    project_id TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    value TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, kind, value),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- Request rate limit of a project (absent rows are unlimited)
+CREATE TABLE IF NOT EXISTS project_rate_limits (
+    project_id TEXT PRIMARY KEY,
+    requests_per_window INTEGER NOT NULL,
+    window_seconds INTEGER NOT NULL,
+    burst INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
 -- Tokens table
 CREATE TABLE IF NOT EXISTS tokens (
     id TEXT PRIMARY KEY,
@@ -30,6 +92,12 @@ CREATE TABLE IF NOT EXISTS tokens (
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     last_used_at DATETIME,
     cache_hit_count INTEGER NOT NULL DEFAULT 0,
+    scopes TEXT,
+    metadata TEXT,
+    client_restrictions TEXT,
+    rate_limit TEXT,
+    rotated_from_id TEXT,
+    rotated_to_id TEXT,
     FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
 );
 
@@ -39,6 +107,79 @@ CREATE INDEX IF NOT EXISTS idx_tokens_project_id ON tokens(project_id);
 CREATE INDEX IF NOT EXISTS idx_tokens_expires_at ON tokens(expires_at);
 CREATE INDEX IF NOT EXISTS idx_tokens_is_active ON tokens(is_active);
 
+-- Model price catalog; prices are USD per 1,000 tokens and model may be a glob pattern.
+-- A NULL cached_input_per_1k bills cached input tokens at input_per_1k.
+CREATE TABLE IF NOT EXISTS model_prices (
+    model TEXT PRIMARY KEY,
+    input_per_1k REAL NOT NULL DEFAULT 0,
+    output_per_1k REAL NOT NULL DEFAULT 0,
+    cached_input_per_1k REAL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Cost totals per token and per project
+CREATE TABLE IF NOT EXISTS token_costs (
+    token_id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL,
+    request_count INTEGER NOT NULL DEFAULT 0,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cached_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (token_id) REFERENCES tokens(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_costs_project_id ON token_costs(project_id);
+
+CREATE TABLE IF NOT EXISTS project_costs (
+    project_id TEXT PRIMARY KEY,
+    request_count INTEGER NOT NULL DEFAULT 0,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cached_input_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+-- Spend budgets of projects and tokens; subject_type is 'project' or 'token'.
+-- project_id or token_id repeats subject_id so that budgets are deleted with their subject.
+CREATE TABLE IF NOT EXISTS budgets (
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    project_id TEXT,
+    token_id TEXT,
+    period TEXT NOT NULL,
+    limit_usd REAL,
+    limit_tokens INTEGER,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (subject_type, subject_id),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (token_id) REFERENCES tokens(id) ON DELETE CASCADE
+);
+
+-- Daily spend per project and token (day is YYYY-MM-DD in UTC)
+CREATE TABLE IF NOT EXISTS budget_spend (
+    subject_type TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    project_id TEXT,
+    token_id TEXT,
+    day TEXT NOT NULL,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    tokens INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (subject_type, subject_id, day),
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+    FOREIGN KEY (token_id) REFERENCES tokens(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_project_id ON budgets(project_id);
+CREATE INDEX IF NOT EXISTS idx_budgets_token_id ON budgets(token_id);
+CREATE INDEX IF NOT EXISTS idx_budget_spend_project_id ON budget_spend(project_id);
+CREATE INDEX IF NOT EXISTS idx_budget_spend_token_id ON budget_spend(token_id);
+
 -- Audit events table for security logging
 CREATE TABLE IF NOT EXISTS audit_events (
     id TEXT PRIMARY KEY,